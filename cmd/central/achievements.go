@@ -0,0 +1,113 @@
+package main
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Achievements =====================
+
+// Achievements are rules over the stat totals central keeps per player (see
+// leaderboard.go): a player earns one the first time their total of its
+// Stat reaches its Goal. They are read from the JSON list in -achievements,
+// or the one built in (achievements.json) without it, and checked whenever a
+// game server's report adds to a player's totals. What a player earned is
+// kept in their profile; the report's reply lists the new awards so the
+// server can push them to its players. GET /achievements lists the rules,
+// and with ?player_id= how far that player is towards each.
+
+//go:embed achievements.json
+var builtinAchievements []byte
+
+// read-only once loaded
+var achievements []types.Achievement
+
+var achievementsTotal = metrics.NewCounterVec("central_achievements_total",
+	"Achievements awarded to players, by achievement ID.", "achievement")
+
+// loadAchievements reads the rules from path, or the built-in ones if path
+// is empty, refusing stats no leaderboard ranks by.
+func loadAchievements(path string) error {
+	data := builtinAchievements
+	if path != "" {
+		var err error
+		if data, err = os.ReadFile(path); err != nil {
+			return err
+		}
+	}
+	var list []types.Achievement
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	seen := make(map[string]bool, len(list))
+	for _, rule := range list {
+		if rule.ID == "" || seen[rule.ID] {
+			return fmt.Errorf("achievement IDs must be set and unique (%q)", rule.ID)
+		}
+		seen[rule.ID] = true
+		if _, ok := leaderboard_stats[rule.Stat]; !ok {
+			return fmt.Errorf("achievement %q: unknown stat %q", rule.ID, rule.Stat)
+		}
+		if rule.Goal <= 0 {
+			return fmt.Errorf("achievement %q: goal must be positive", rule.ID)
+		}
+	}
+	achievements = list
+	return nil
+}
+
+// checkAchievements awards profile every achievement its totals have newly
+// reached. Must be called with profilesMu held.
+func checkAchievements(profile *types.PlayerProfile, now time.Time) []types.Award {
+	var awards []types.Award
+	for _, rule := range achievements {
+		if _, earned := profile.Achievements[rule.ID]; earned {
+			continue
+		}
+		if leaderboard_stats[rule.Stat](profile.Stats) < rule.Goal {
+			continue
+		}
+		if profile.Achievements == nil {
+			profile.Achievements = make(map[string]time.Time)
+		}
+		profile.Achievements[rule.ID] = now
+		awards = append(awards, types.Award{PlayerID: profile.PlayerID, Achievement: rule, EarnedMs: now.UnixMilli()})
+		achievementsTotal.Inc(rule.ID)
+	}
+	return awards
+}
+
+// handleAchievements serves GET /achievements: the rules, or with
+// ?player_id= that player's progress towards each.
+func handleAchievements(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	player_id := r.URL.Query().Get("player_id")
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	if player_id == "" {
+		json.NewEncoder(w).Encode(achievements)
+		return
+	}
+	profile := profiles[player_id]
+	list := make([]types.AchievementProgress, 0, len(achievements))
+	for _, rule := range achievements {
+		progress := types.AchievementProgress{Achievement: rule, Value: leaderboard_stats[rule.Stat](profile.Stats)}
+		if at, earned := profile.Achievements[rule.ID]; earned {
+			progress.EarnedAt = &at
+		}
+		list = append(list, progress)
+	}
+	sort.SliceStable(list, func(i, j int) bool { return list[i].EarnedAt != nil && list[j].EarnedAt == nil })
+	json.NewEncoder(w).Encode(list)
+}
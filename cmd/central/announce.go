@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Announcements =====================
+
+// POST /announce (admin) sends a message to every player: each live game
+// server gets ANNOUNCE and pushes it to the players connected to it as a
+// PUSH_ANNOUNCE message. GET /announce lists the last maxAnnouncements, for
+// players who cannot take pushes, such as the HTTP gateway's, and for those
+// who join after one was sent.
+
+const maxAnnouncements = 20
+
+type AnnounceRequest struct {
+	Kind string `json:"kind"` // "info" if empty
+	Text string `json:"text"`
+}
+
+var (
+	announcementsMu sync.Mutex
+	announcements   []types.Announcement
+)
+
+func handleAnnounce(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		announcementsMu.Lock()
+		list := append([]types.Announcement{}, announcements...)
+		announcementsMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	case http.MethodPost:
+		requireAdmin(postAnnounce)(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func postAnnounce(w http.ResponseWriter, r *http.Request) {
+	var req AnnounceRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if req.Text == "" {
+		http.Error(w, "Missing text", http.StatusBadRequest)
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = "info"
+	}
+
+	announcement := types.Announcement{ID: netproto.NewTraceID(), Kind: req.Kind, Text: req.Text, SentMs: time.Now().UnixMilli()}
+	announcementsMu.Lock()
+	announcements = append(announcements, announcement)
+	if len(announcements) > maxAnnouncements {
+		announcements = announcements[len(announcements)-maxAnnouncements:]
+	}
+	announcementsMu.Unlock()
+
+	servers, players := announceEverywhere(announcement)
+	log.Printf("📢 Announced %s (%s) on %v to %d players: %q", announcement.ID, announcement.Kind, servers, players, announcement.Text)
+	json.NewEncoder(w).Encode(types.Response{Success: len(servers) > 0, PlayerCount: players,
+		Message: fmt.Sprintf("Announced on %d server(s) to %d player(s)", len(servers), players)})
+}
+
+// announceEverywhere sends ANNOUNCE to every live game server and returns
+// the servers that confirmed it with the players they pushed it to.
+func announceEverywhere(announcement types.Announcement) ([]string, int) {
+	zoneMu.Lock()
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+
+	req := types.Request{Type: types.ReqAnnounce, Announcement: &announcement, TraceID: announcement.ID}
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		confirmed []string
+		players   int
+	)
+	for _, server := range live {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			res, err := netproto.RoundTrip(network, server, req, 2*time.Second)
+			if err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  ANNOUNCE on %s failed: %v", server, err)
+				return
+			}
+			if res.Success {
+				mu.Lock()
+				confirmed = append(confirmed, server)
+				players += res.PlayerCount
+				mu.Unlock()
+			}
+		}(server)
+	}
+	wg.Wait()
+	sort.Strings(confirmed)
+	return confirmed, players
+}
@@ -0,0 +1,921 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+var (
+	zone        map[types.ChunkID]string
+	zoneMu      sync.Mutex
+	network     = netproto.UDP // how FROM_CENTRAL and KICK_PLAYER reach game servers
+	serversList = []string{"172.16.118.72:9000", "172.16.118.120:9000", "172.16.118.112:9000"}
+
+	// latest experiment report per game server, keyed by server IP
+	worldReports   = make(map[string]types.WorldMetrics)
+	worldReportsMu sync.Mutex
+)
+
+// Prometheus metrics served on /metrics (see pkg/metrics)
+var (
+	httpRequestsTotal = metrics.NewCounterVec("central_requests_total",
+		"HTTP requests handled, by path.", "path")
+	httpRequestSeconds = metrics.NewHistogramVec("central_request_duration_seconds",
+		"Time spent handling an HTTP request, by path.", "path", metrics.DefaultBuckets)
+	peerCallSeconds = metrics.NewHistogramVec("central_peer_call_duration_seconds",
+		"Latency of FROM_CENTRAL round trips to the owning game server.", "", metrics.DefaultBuckets)
+	migrationsTotal = metrics.NewCounterVec("central_chunk_migrations_total",
+		"Chunk ownership changes between game servers.", "")
+	sentChunksTotal = metrics.NewCounterVec("central_sentchunk_reports_total",
+		"Chunk owners reported at /sentchunk, by result: assigned, unchanged or conflict.", "result")
+	_ = metrics.NewGaugeFunc("central_chunks_assigned",
+		"Chunks assigned to each game server.", "server", func() map[string]float64 {
+			zoneMu.Lock()
+			defer zoneMu.Unlock()
+			values := make(map[string]float64)
+			for _, owner := range zone {
+				values[owner]++
+			}
+			return values
+		})
+)
+
+// instrument records request count and latency for a handler.
+func instrument(path string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		next(w, r)
+		httpRequestsTotal.Inc(path)
+		httpRequestSeconds.Observe(path, time.Since(start).Seconds())
+	}
+}
+
+// WorldComparison aggregates the latest reports of every server in a world.
+type WorldComparison struct {
+	World             types.WorldConfig `json:"world"`
+	Servers           []string          `json:"servers"`
+	Requests          int64             `json:"requests"`
+	AvgHandleUs       int64             `json:"avg_handle_us"`
+	MaxHandleUs       int64             `json:"max_handle_us"`
+	ChunksOwned       int               `json:"chunks_owned"`
+	Players           int               `json:"players"`
+	PlayersPerChunk   float64           `json:"players_per_chunk"`
+	Migrations        int64             `json:"migrations"`
+	MigrationsPerKReq float64           `json:"migrations_per_1k_requests"`
+	// ClientRTTMs and ClientLoss are the servers' client telemetry averages
+	// weighted by their players.
+	ClientRTTMs float64 `json:"client_rtt_ms"`
+	ClientLoss  float64 `json:"client_loss"`
+}
+
+// chunkSize is the cluster's chunk edge length. Game servers started without
+// -chunk-size read it from /config and players get it at /join, so every
+// component derives chunk IDs from positions the same way.
+var chunkSize = types.DefaultChunkSize
+
+// chunkSizeFor returns the chunk size of the world the given server reported,
+// falling back to the cluster's chunkSize for servers that have not reported
+// yet. Only experiment worlds run with a different size.
+func chunkSizeFor(server string) int {
+	worldReportsMu.Lock()
+	defer worldReportsMu.Unlock()
+	if report, ok := worldReports[server]; ok && report.World.ChunkSize > 0 {
+		return report.World.ChunkSize
+	}
+	return chunkSize
+}
+
+func handleConfig(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	json.NewEncoder(w).Encode(types.WorldConfig{Name: "default", ChunkSize: chunkSize})
+}
+
+func handleJoin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req types.PlayerJoinRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if ban, ok := activeBan(req.PlayerID); ok {
+		log.Printf("⛔ Rejected banned player %s at join", req.PlayerID)
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Banned: " + ban.Reason, Code: types.CodeBanned})
+		return
+	}
+	if req.SessionToken != "" && validSession(req.PlayerID, req.SessionToken) {
+		if player, server, ok := locatePlayer(req.PlayerID, req.SessionToken); ok {
+			log.Printf("🔁 Player %s resuming on %s at (%d,%d)", req.PlayerID, server, player.PosX, player.PosY)
+			sessionsTotal.Inc("resumed")
+			res := types.Response{Success: true, Message: server, Code: types.CodeRedirect, RedirectIP: server, ChunkSize: chunkSizeFor(server),
+				SessionToken: req.SessionToken, Player: &player}
+			zoneMu.Lock()
+			res.Splits = splitList()
+			zoneMu.Unlock()
+			issueSeal(req, &res)
+			json.NewEncoder(w).Encode(res)
+			return
+		}
+		sessionsTotal.Inc("lost")
+	} else {
+		sessionsTotal.Inc("new")
+	}
+
+	profile, returning := startSession(req.PlayerID)
+	zoneMu.Lock()
+	if returning && !profile.SavedAt.IsZero() && knownWorld(profile.Player.World) {
+		// pick up where they left off
+		req.PosX, req.PosY, req.World = profile.Player.PosX, profile.Player.PosY, profile.Player.World
+	} else {
+		returning = false
+	}
+	known := knownWorld(req.World)
+	zoneMu.Unlock()
+	if !known {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "No such world: " + req.World, Code: types.CodeNotFound})
+		return
+	}
+	if err := types.ChunkOf(req.World, req.PosX, req.PosY, chunkSize).Validate(); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Position out of range: " + err.Error(), Code: types.CodeBadRequest})
+		return
+	}
+
+	log.Printf("Player %s joined at (%d,%d) !", req.PlayerID, req.PosX, req.PosY)
+	assigned := placePlayer(req)
+	//res := PlayerJoinResponse{AssignedServer: "127.0.0.1" + assigned, Message: fmt.Sprintf("Player %s assigned to %s", req.PlayerID, assigned)}
+	res := types.Response{Success: true, Message: assigned, Code: types.CodeRedirect, RedirectIP: assigned, ChunkSize: chunkSizeFor(assigned),
+		SessionToken: newSession(req.PlayerID)}
+	if returning {
+		res.Profile = &profile
+	}
+	zoneMu.Lock()
+	res.Splits = splitList()
+	zoneMu.Unlock()
+	issueSeal(req, &res)
+	//log.Println("Assigned:", req.PlayerID, "->", assigned)
+	json.NewEncoder(w).Encode(res)
+}
+
+// handleSentChunk takes a game server's report that a chunk has a new
+// owner: the owner handing it to another server (CallerIP the owner, Owner
+// the new one), or a server claiming it (Owner empty or CallerIP). Central
+// records it unless a live server other than the caller holds the chunk,
+// that is, one whose lease has not run out (see leaseExpiry); a claim on it
+// is refused with 409 and ERR_NOT_OWNER, naming the owner in redirect_ip.
+func handleSentChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req types.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeSentChunk(w, http.StatusBadRequest, types.Response{Success: false, Message: err.Error(), Code: types.CodeBadRequest})
+		return
+	}
+	chunk_id := req.ChunkID
+	owner := req.Owner
+	if owner == "" {
+		owner = req.CallerIP
+	}
+	var invalid string
+	switch err := chunk_id.Validate(); {
+	case !slices.Contains(serversList, req.CallerIP):
+		invalid = "caller_ip is not a game server of this cluster"
+	case !slices.Contains(serversList, owner):
+		invalid = "owner is not a game server of this cluster"
+	case err != nil:
+		invalid = err.Error()
+	}
+	if invalid != "" {
+		writeSentChunk(w, http.StatusBadRequest, types.Response{Success: false, Message: invalid, Code: types.CodeBadRequest, TraceID: req.TraceID})
+		return
+	}
+
+	zoneMu.Lock()
+	res, status := reportOwner(req, owner)
+	mark := markZone()
+	zoneMu.Unlock()
+	if status == http.StatusOK && !awaitZone(w, mark) {
+		return
+	}
+	writeSentChunk(w, status, res)
+}
+
+// reportOwner records owner as chunk_id's owner on req.CallerIP's word, if
+// it may. Must be called with zoneMu held.
+func reportOwner(req types.Request, owner string) (types.Response, int) {
+	chunk_id := req.ChunkID
+	if !knownWorld(chunk_id.World) {
+		return types.Response{Success: false, Message: "No such world: " + chunk_id.World, Code: types.CodeNotFound, TraceID: req.TraceID}, http.StatusNotFound
+	}
+	if splits[chunk_id] {
+		return types.Response{Success: false, Message: "Chunk is split", Code: types.CodeChunkSplit,
+			Splits: splitList(), TraceID: req.TraceID}, http.StatusConflict
+	}
+	if dead[req.CallerIP] {
+		return types.Response{Success: false, Message: "Declared dead; rejoin first", Code: types.CodeFenced, TraceID: req.TraceID}, http.StatusConflict
+	}
+	if dead[owner] {
+		return types.Response{Success: false, Message: "Owner " + owner + " is declared dead", Code: types.CodeBadRequest, TraceID: req.TraceID}, http.StatusConflict
+	}
+	current, held := zone[chunk_id]
+	if held && current != owner && current != req.CallerIP && !dead[current] {
+		worldReportsMu.Lock()
+		expiry, leased := leaseExpiry(current)
+		worldReportsMu.Unlock()
+		if !leased || time.Now().Before(expiry) {
+			netproto.Tracef(req.TraceID, "/sentchunk [%d,%d] from %s refused, owned by %s", chunk_id.IDX, chunk_id.IDY, req.CallerIP, current)
+			sentChunksTotal.Inc("conflict")
+			return types.Response{Success: false, Message: "Chunk is owned by " + current, Code: types.CodeNotOwner,
+				RedirectIP: current, TraceID: req.TraceID}, http.StatusConflict
+		}
+	}
+
+	if current == owner {
+		sentChunksTotal.Inc("unchanged")
+	} else {
+		assignChunk(chunk_id, owner)
+		sentChunksTotal.Inc("assigned")
+	}
+	netproto.Tracef(req.TraceID, "/sentchunk [%d,%d] from %s: owner %s (was %q)", chunk_id.IDX, chunk_id.IDY, req.CallerIP, owner, current)
+	return types.Response{Success: true, Message: owner, NewIP: owner, ChunkID: &chunk_id, TraceID: req.TraceID}, http.StatusOK
+}
+
+func writeSentChunk(w http.ResponseWriter, status int, res types.Response) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(res)
+}
+
+func handlePeerChunk(w http.ResponseWriter, r *http.Request) {
+	// Check if zone map is initialized
+	if zone == nil {
+		log.Println("ERROR: zone map is nil")
+		http.Error(w, "Server not properly initialized", http.StatusInternalServerError)
+		return
+	}
+
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	// Check if request body is nil
+	if r.Body == nil {
+		http.Error(w, "Request body is empty", http.StatusBadRequest)
+		return
+	}
+	defer r.Body.Close()
+
+	var req types.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+
+	// Validate required fields
+	if err := req.ChunkID.Validate(); err != nil {
+		http.Error(w, "Invalid chunk_id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if req.CallerIP == "" {
+		http.Error(w, "Missing caller_ip", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(serversList, req.CallerIP) {
+		http.Error(w, "caller_ip is not a game server of this cluster", http.StatusForbidden)
+		return
+	}
+
+	res, mark := assignPeerChunk(req)
+	if !awaitZone(w, mark) {
+		return
+	}
+	if err := json.NewEncoder(w).Encode(res); err != nil {
+		log.Printf("ERROR: Failed to encode response: %v", err)
+	}
+}
+
+// assignPeerChunk decides who owns the chunk a game server asked for at
+// /chunk, asking the current owner for its load, and returns the mark of
+// the zone change to wait for. zoneMu is released for the FROM_CENTRAL
+// round trip, so that a slow owner does not hold up /join, /heartbeat and
+// the rest; what the owner said is only acted on if the chunk is still its.
+func assignPeerChunk(req types.Request) (types.Response, zoneMark) {
+	chunk_id := req.ChunkID
+	caller_load := req.PlayerCount
+
+	zoneMu.Lock()
+	res, owner, need := decidePeerChunk(req)
+	mark := markZone()
+	zoneMu.Unlock()
+	if owner == "" {
+		return res, mark
+	}
+
+	req_from_central := types.Request{
+		Type:        types.ReqFromCentral,
+		ChunkID:     chunk_id,
+		CallerIP:    req.CallerIP,
+		PlayerCount: caller_load,
+		MinLead:     need,
+		TraceID:     req.TraceID,
+	}
+
+	netproto.Tracef(req.TraceID, "→ FROM_CENTRAL to owner %s", owner)
+	callStart := time.Now()
+	res, err := netproto.RoundTrip(network, owner, req_from_central, 3*time.Second)
+	peerCallSeconds.Observe("", time.Since(callStart).Seconds())
+
+	zoneMu.Lock()
+	defer zoneMu.Unlock()
+	if again, current, _ := decidePeerChunk(req); current != owner {
+		// the chunk moved, split or lost its owner during the call
+		netproto.Tracef(req.TraceID, "Chunk [%d,%d] changed while asking %s, now %q", chunk_id.IDX, chunk_id.IDY, owner, current)
+		if current != "" {
+			again = types.Response{Success: true, Message: current, NewIP: current, TraceID: req.TraceID}
+		}
+		return again, markZone()
+	}
+	return settlePeerChunk(req, owner, need, res, err), markZone()
+}
+
+// decidePeerChunk answers a /chunk request if it can without the owner's
+// word, returning an empty owner; otherwise it returns the owner to ask and
+// the lead the caller needs over it. Must be called with zoneMu held.
+func decidePeerChunk(req types.Request) (types.Response, string, int) {
+	chunk_id := req.ChunkID
+	caller_load := req.PlayerCount
+
+	if !knownWorld(chunk_id.World) {
+		return types.Response{Success: false, Message: "No such world: " + chunk_id.World, Code: types.CodeNotFound, TraceID: req.TraceID}, "", 0
+	}
+	if splits[chunk_id] {
+		return types.Response{Success: false, Message: "Chunk is split", Code: types.CodeChunkSplit,
+			Splits: splitList(), TraceID: req.TraceID}, "", 0
+	}
+
+	if dead[req.CallerIP] {
+		return types.Response{Success: false, Message: "Declared dead; rejoin first", Code: types.CodeFenced, TraceID: req.TraceID}, "", 0
+	}
+
+	owner, ok := zone[chunk_id]
+	netproto.Tracef(req.TraceID, "/chunk [%d,%d] from %s (load %d), owner %q",
+		chunk_id.IDX, chunk_id.IDY, req.CallerIP, caller_load, owner)
+
+	if !ok {
+		if owner, adopted := adoptInRegion(chunk_id, req.CallerIP, req.TraceID); adopted {
+			return types.Response{Success: true, Message: owner, NewIP: owner, TraceID: req.TraceID}, "", 0
+		}
+		assignChunk(chunk_id, req.CallerIP)
+		netproto.Tracef(req.TraceID, "Chunk [%d,%d] had no owner, assigned to %s", chunk_id.IDX, chunk_id.IDY, req.CallerIP)
+		return types.Response{Success: false, TraceID: req.TraceID}, "", 0
+	}
+
+	if dead[owner] && owner != req.CallerIP {
+		// nobody was left to fail it over to; the caller can have it
+		netproto.Tracef(req.TraceID, "Owner %s is dead, chunk goes to %s", owner, req.CallerIP)
+		assignChunk(chunk_id, req.CallerIP)
+		migrationsTotal.Inc("")
+		return types.Response{Success: true, Message: req.CallerIP, NewIP: req.CallerIP, TraceID: req.TraceID}, "", 0
+	}
+
+	if coolingDown(chunk_id) {
+		netproto.Tracef(req.TraceID, "Chunk [%d,%d] changed owner less than %s ago, stays on %s", chunk_id.IDX, chunk_id.IDY, migrateCooldown, owner)
+		migrationsHeldTotal.Inc("cooldown")
+		return types.Response{Success: true, Message: owner, NewIP: owner, TraceID: req.TraceID}, "", 0
+	}
+	return types.Response{}, owner, migrationLead(chunk_id, owner)
+}
+
+// settlePeerChunk decides a /chunk request on the owner's reply res (or
+// the error asking it). Must be called with zoneMu held.
+func settlePeerChunk(req types.Request, owner string, need int, res types.Response, err error) types.Response {
+	chunk_id := req.ChunkID
+	caller_load := req.PlayerCount
+
+	if err != nil {
+		netproto.Tracef(req.TraceID, "ERROR: FROM_CENTRAL to %s failed: %v", owner, err)
+		// Continue processing even if the owner did not answer, but with default values
+		var final_res types.Response
+		if caller_load > 0 { // If we have caller load, assume we should take ownership
+			assignChunk(chunk_id, req.CallerIP)
+			migrationsTotal.Inc("")
+			final_res = types.Response{Success: true, Message: req.CallerIP, NewIP: req.CallerIP}
+		} else {
+			final_res = types.Response{Success: true, Message: owner, NewIP: owner}
+		}
+		final_res.TraceID = req.TraceID
+		return final_res
+	}
+	if res.Code == types.CodeChunkLocked {
+		netproto.Tracef(req.TraceID, "Chunk [%d,%d] is locked by a transaction on %s, stays there", chunk_id.IDX, chunk_id.IDY, owner)
+		migrationsHeldTotal.Inc("locked")
+		return types.Response{Success: true, Message: owner, NewIP: owner, TraceID: req.TraceID}
+	}
+
+	var final_res types.Response
+	callee_load := res.PlayerCount
+	peer_chunk := res.Chunk
+
+	netproto.Tracef(req.TraceID, "Processing chunk transfer decision: owner load %d, caller load %d, lead needed %d", callee_load, caller_load, need)
+
+	if lead := caller_load - callee_load; lead > 0 && lead < need {
+		migrationsHeldTotal.Inc(heldReason(lead))
+	}
+	if caller_load-callee_load >= need {
+		assignChunk(chunk_id, req.CallerIP)
+		migrationsTotal.Inc("")
+		final_res = types.Response{Success: true, Message: req.CallerIP, NewIP: req.CallerIP, Chunk: peer_chunk}
+	} else {
+		final_res = types.Response{Success: true, Message: owner, NewIP: owner}
+	}
+
+	netproto.Tracef(req.TraceID, "Owner is : %s", final_res.Message)
+	final_res.TraceID = req.TraceID
+	return final_res
+}
+
+func handleExperimentReport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var report types.WorldMetrics
+	if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if report.ServerIP == "" {
+		http.Error(w, "Missing server_ip", http.StatusBadRequest)
+		return
+	}
+	if !slices.Contains(serversList, report.ServerIP) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Unknown server " + report.ServerIP, Code: types.CodeForbidden})
+		return
+	}
+
+	// a report is not a heartbeat (see handleHeartbeat): it says nothing of
+	// whether its server still holds its chunks
+	worldReportsMu.Lock()
+	worldReports[report.ServerIP] = report
+	delete(joinedSince, report.ServerIP)
+	worldReportsMu.Unlock()
+	zoneMu.Lock()
+	gone := dead[report.ServerIP]
+	strict := strictWorlds()
+	zoneMu.Unlock()
+	if !gone {
+		planReplicas(report)
+	}
+	awards := addStats(report.PlayerStats)
+
+	json.NewEncoder(w).Encode(types.Response{Success: true, Awards: awards, Worlds: strict})
+}
+
+func handleExperimentCompare(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+
+	worldReportsMu.Lock()
+	byWorld := make(map[string]*WorldComparison)
+	weighted := make(map[string]int64)
+	reporting := make(map[string]int)
+	for _, report := range worldReports {
+		cmp, ok := byWorld[report.World.Name]
+		if !ok {
+			cmp = &WorldComparison{World: report.World}
+			byWorld[report.World.Name] = cmp
+		}
+		cmp.Servers = append(cmp.Servers, report.ServerIP)
+		cmp.Requests += report.Requests
+		cmp.ChunksOwned += report.ChunksOwned
+		cmp.Players += report.Players
+		cmp.Migrations += report.Migrations
+		if report.MaxHandleUs > cmp.MaxHandleUs {
+			cmp.MaxHandleUs = report.MaxHandleUs
+		}
+		weighted[report.World.Name] += report.AvgHandleUs * report.Requests
+		if report.ClientRTTMs > 0 {
+			cmp.ClientRTTMs += report.ClientRTTMs * float64(report.Players)
+			cmp.ClientLoss += report.ClientLoss * float64(report.Players)
+			reporting[report.World.Name] += report.Players
+		}
+	}
+	worldReportsMu.Unlock()
+
+	result := make([]WorldComparison, 0, len(byWorld))
+	for name, cmp := range byWorld {
+		if cmp.Requests > 0 {
+			cmp.AvgHandleUs = weighted[name] / cmp.Requests
+			cmp.MigrationsPerKReq = float64(cmp.Migrations) * 1000 / float64(cmp.Requests)
+		}
+		if n := reporting[name]; n > 0 {
+			cmp.ClientRTTMs /= float64(n)
+			cmp.ClientLoss /= float64(n)
+		}
+		if cmp.ChunksOwned > 0 {
+			cmp.PlayersPerChunk = float64(cmp.Players) / float64(cmp.ChunksOwned)
+		}
+		sort.Strings(cmp.Servers)
+		result = append(result, *cmp)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].World.Name < result[j].World.Name })
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// ===================== Kick / ban =====================
+
+type BanRequest struct {
+	PlayerID        string `json:"player_id"`
+	Reason          string `json:"reason"`
+	DurationSeconds int    `json:"duration_seconds"` // 0 bans permanently
+}
+
+var (
+	bans        = make(map[string]types.Ban)
+	bansMu      sync.Mutex
+	banlistPath string
+)
+
+func activeBan(player_id string) (types.Ban, bool) {
+	bansMu.Lock()
+	defer bansMu.Unlock()
+	ban, ok := bans[player_id]
+	if !ok {
+		return types.Ban{}, false
+	}
+	if !ban.Active(time.Now()) {
+		delete(bans, player_id)
+		return types.Ban{}, false
+	}
+	return ban, true
+}
+
+func loadBans() error {
+	data, err := os.ReadFile(banlistPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []types.Ban
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	for _, ban := range list {
+		bans[ban.PlayerID] = ban
+	}
+	return nil
+}
+
+// saveBans must be called with bansMu held.
+func saveBans() error {
+	if banlistPath == "" {
+		return nil
+	}
+	list := make([]types.Ban, 0, len(bans))
+	for _, ban := range bans {
+		list = append(list, ban)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PlayerID < list[j].PlayerID })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := banlistPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, banlistPath)
+}
+
+// kickEverywhere sends KICK_PLAYER to every game server and returns the
+// servers that confirmed the kick.
+func kickEverywhere(player_id, reason string) []string {
+	req := types.Request{Type: types.ReqKickPlayer, PlayerID: player_id, Reason: reason, TraceID: netproto.NewTraceID()}
+
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		confirmed []string
+	)
+	for _, server := range serversList {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			res, err := netproto.RoundTrip(network, server, req, 2*time.Second)
+			if err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  KICK_PLAYER %s on %s failed: %v", player_id, server, err)
+				return
+			}
+			if res.Success {
+				mu.Lock()
+				confirmed = append(confirmed, server)
+				mu.Unlock()
+			}
+		}(server)
+	}
+	wg.Wait()
+	sort.Strings(confirmed)
+	return confirmed
+}
+
+// adminToken guards /bans, /kick, /replicas and POST /announce; an empty
+// token disables them.
+var adminToken string
+
+func requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return netproto.RequireAdmin(adminToken, next)
+}
+
+// serverToken is what game servers must show on the endpoints only they
+// call (see requireServer): the seal secret's SealKeys.ServerToken, or
+// empty without -seal-secret, when a call from a game server's host will do.
+var serverToken string
+
+func requireServer(next http.HandlerFunc) http.HandlerFunc {
+	return netproto.RequireServer(serverToken, knownServerHost, next)
+}
+
+// knownServerHost reports whether host is that of a game server in
+// serversList.
+func knownServerHost(host string) bool {
+	for _, server := range serversList {
+		if h, _, err := net.SplitHostPort(server); err == nil && h == host {
+			return true
+		}
+	}
+	return false
+}
+
+// handleBans serves GET (list), POST (ban + kick) and DELETE ?player_id= (unban).
+func handleBans(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		now := time.Now()
+		bansMu.Lock()
+		list := make([]types.Ban, 0, len(bans))
+		for _, ban := range bans {
+			if ban.Active(now) {
+				list = append(list, ban)
+			}
+		}
+		bansMu.Unlock()
+		sort.Slice(list, func(i, j int) bool { return list[i].PlayerID < list[j].PlayerID })
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var req BanRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if req.PlayerID == "" || req.DurationSeconds < 0 {
+			http.Error(w, "player_id required and duration_seconds must not be negative", http.StatusBadRequest)
+			return
+		}
+		ban := types.Ban{PlayerID: req.PlayerID, Reason: req.Reason, BannedAt: time.Now().UTC()}
+		if req.DurationSeconds > 0 {
+			ban.Until = ban.BannedAt.Add(time.Duration(req.DurationSeconds) * time.Second)
+		}
+
+		bansMu.Lock()
+		bans[req.PlayerID] = ban
+		err := saveBans()
+		bansMu.Unlock()
+		if err != nil {
+			log.Printf("ERROR: Failed to save banlist: %v", err)
+		}
+
+		kicked := kickEverywhere(req.PlayerID, "Banned: "+req.Reason)
+		log.Printf("⛔ Banned player %s (%s), kicked on %v", req.PlayerID, req.Reason, kicked)
+		json.NewEncoder(w).Encode(types.Response{Success: true, Message: fmt.Sprintf("Banned, kicked on %d server(s)", len(kicked))})
+
+	case http.MethodDelete:
+		player_id := r.URL.Query().Get("player_id")
+		if player_id == "" {
+			http.Error(w, "Missing player_id", http.StatusBadRequest)
+			return
+		}
+		bansMu.Lock()
+		_, ok := bans[player_id]
+		delete(bans, player_id)
+		err := saveBans()
+		bansMu.Unlock()
+		if err != nil {
+			log.Printf("ERROR: Failed to save banlist: %v", err)
+		}
+		if !ok {
+			http.Error(w, "Player is not banned", http.StatusNotFound)
+			return
+		}
+		log.Printf("✅ Unbanned player %s", player_id)
+		json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Unbanned"})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func handleKick(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req BanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if req.PlayerID == "" {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	kicked := kickEverywhere(req.PlayerID, req.Reason)
+	log.Printf("👢 Kicked player %s (%s) on %v", req.PlayerID, req.Reason, kicked)
+	json.NewEncoder(w).Encode(types.Response{Success: len(kicked) > 0, Message: fmt.Sprintf("Kicked on %d server(s)", len(kicked))})
+}
+
+// handle registers h at pattern behind the CORS policy, so every central
+// endpoint answers browsers the same way.
+func handle(pattern string, h http.HandlerFunc) {
+	http.HandleFunc(pattern, netproto.EnableCORS(h))
+}
+
+func main() {
+	listenAddr := flag.String("listen", ":8080", "HTTP listen address")
+	servers := flag.String("servers", strings.Join(serversList, ","), "comma-separated UDP addresses of the game servers")
+	flag.IntVar(&chunkSize, "chunk-size", chunkSize, "chunk edge length used by the cluster")
+	flag.StringVar(&banlistPath, "banlist", "bans.json", "file the banlist is persisted to (empty keeps it in memory)")
+	flag.StringVar(&webhooksPath, "webhooks", "webhooks.json", "file webhook registrations are persisted to (empty keeps them in memory)")
+	flag.StringVar(&profilesPath, "profiles", "profiles.json", "file player profiles are persisted to (empty keeps them in memory)")
+	achievementsPath := flag.String("achievements", "", "JSON file of the achievements players can earn (the built-in ones if empty)")
+	flag.StringVar(&adminToken, "admin-token", os.Getenv("ADMIN_TOKEN"), "token required by /bans, /kick, /replicas, /webhooks, GET /profile, POST /announce and POST /worlds (disabled if empty)")
+	flag.IntVar(&replicaThreshold, "replica-threshold", replicaThreshold, "players in one chunk that earn it read replicas (0 disables automatic replicas)")
+	flag.IntVar(&replicaCount, "replicas", replicaCount, "read replicas given to a crowded chunk, or to every chunk of a quorum world")
+	flag.StringVar(&sharedConsistency, "shared-consistency", sharedConsistency, `consistency mode of the shared world: empty (edits acknowledged by the owner) or "quorum" (also by a replica)`)
+	flag.IntVar(&regionSize, "region-size", regionSize, "chunks per edge of the regions whose chunks go to one server (0 assigns chunks one by one)")
+	flag.IntVar(&regionHysteresis, "region-hysteresis", regionHysteresis, "player lead a server needs to take a chunk from its region's owner")
+	flag.IntVar(&migrateMinLead, "migrate-lead", migrateMinLead, "more players than the owner a server needs in a chunk to take it over")
+	flag.DurationVar(&migrateCooldown, "migrate-cooldown", migrateCooldown, "time after a chunk changes owner before it may migrate again")
+	flag.DurationVar(&sessionTTL, "session-ttl", sessionTTL, "how long after joining a player's client can resume their session")
+	flag.DurationVar(&deadAfter, "dead-after", deadAfter, "silence after which a game server is declared dead and its chunks fail over (0 disables)")
+	flag.Uint64Var(&raftID, "raft-id", 0, "this node's ID in -raft-peers (0 runs a single central without Raft)")
+	peerList := flag.String("raft-peers", "", "Raft group of central nodes replicating the zone map, as id=url,... (e.g. 1=http://10.0.0.1:8080)")
+	flag.StringVar(&raftDir, "raft-dir", raftDir, "directory this node's raft log is kept in")
+	modes := flag.String("match-modes", strings.Join(modeNames(), ","), "game modes players can queue for, as name=TEAMSxSIZE,...")
+	flag.IntVar(&matchCapacity, "match-capacity", matchCapacity, "most players a server may hold for a match to be placed on it (0 is unlimited)")
+	flag.IntVar(&arenaChunks, "match-arena", arenaChunks, "chunks per edge of the arena each match gets")
+	debugEndpoints := flag.Bool("debug", false, "serve pprof profiles, goroutine dumps and heap snapshots under /debug (needs -admin-token)")
+	sealSecret := flag.String("seal-secret", os.Getenv("SEAL_SECRET"), "secret shared with the game servers and gateways to seal all UDP traffic with (unsealed if empty)")
+	netproto.CORS.RegisterFlags(flag.CommandLine)
+	var certs netproto.TLSFiles
+	certs.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+
+	serversList = strings.Split(*servers, ",")
+	if *debugEndpoints && adminToken == "" {
+		log.Fatalf("-debug needs -admin-token")
+	}
+	if err := netproto.CORS.Validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *sealSecret != "" {
+		sealKeys = netproto.NewSealKeys(*sealSecret)
+		serverToken = sealKeys.ServerToken()
+		network = netproto.NewSealNetwork(network, sealKeys)
+	}
+	if chunkSize <= 0 {
+		log.Fatalf("invalid -chunk-size %d", chunkSize)
+	}
+	parsed, err := parseModes(*modes)
+	if err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	matchModes = parsed
+	if arenaChunks <= 0 {
+		log.Fatalf("invalid -match-arena %d", arenaChunks)
+	}
+	if !types.ValidConsistency(sharedConsistency) {
+		log.Fatalf("invalid -shared-consistency %q", sharedConsistency)
+	}
+
+	if banlistPath != "" {
+		if err := loadBans(); err != nil {
+			log.Fatal("Loading banlist failed:", err)
+		}
+	}
+	if webhooksPath != "" {
+		if err := loadWebhooks(); err != nil {
+			log.Fatal("Loading webhooks failed:", err)
+		}
+	}
+	if profilesPath != "" {
+		if err := loadProfiles(); err != nil {
+			log.Fatal("Loading profiles failed:", err)
+		}
+	}
+	if err := loadAchievements(*achievementsPath); err != nil {
+		log.Fatal("Loading achievements failed:", err)
+	}
+
+	rand.Seed(time.Now().UnixNano())
+	zone = make(map[types.ChunkID]string)
+	if raftID != 0 {
+		var err error
+		if raftPeers, err = parsePeers(*peerList); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if raftHosts, err = raftPeerHosts(raftPeers); err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		if err := startRaft(); err != nil {
+			log.Fatalf("❌ Starting raft failed: %v", err)
+		}
+	}
+
+	go watchServers()
+
+	handle("/config", instrument("/config", handleConfig))
+	handle("/join", instrument("/join", leaderOnly(handleJoin)))
+	handle("/chunk", instrument("/chunk", leaderOnly(requireServer(handlePeerChunk))))
+	handle("/sentchunk", instrument("/sentchunk", leaderOnly(requireServer(handleSentChunk))))
+	handle("/split", instrument("/split", leaderOnly(requireServer(handleSplit))))
+	handle("/locate", instrument("/locate", leaderOnly(handleLocate)))
+	handle("/profile", instrument("/profile", leaderOnly(handleProfile)))
+	handle("/peer_chunk", instrument("/peer_chunk", leaderOnly(requireServer(handlePeerChunk))))
+	handle("/experiment/report", instrument("/experiment/report", leaderOnly(requireServer(handleExperimentReport))))
+	handle("/heartbeat", instrument("/heartbeat", leaderOnly(requireServer(handleHeartbeat))))
+	handle("/rejoin", instrument("/rejoin", leaderOnly(requireServer(handleRejoin))))
+	handle("/experiment/compare", leaderOnly(handleExperimentCompare))
+	handle("/metrics", metrics.Handler)
+	handle("/bans", leaderOnly(requireAdmin(handleBans)))
+	handle("/kick", leaderOnly(requireAdmin(handleKick)))
+	handle("/replicas", leaderOnly(requireAdmin(handleReplicas)))
+	handle("/webhooks", leaderOnly(requireAdmin(handleWebhooks)))
+	handle("/announce", leaderOnly(handleAnnounce))
+	handle("/party", leaderOnly(handlePartyGet))
+	handle("/party/create", leaderOnly(handlePartyCreate))
+	handle("/party/join", leaderOnly(handlePartyJoin))
+	handle("/party/leave", leaderOnly(handlePartyLeave))
+	handle("/worlds", leaderOnly(handleWorlds))
+	handle("/map", leaderOnly(handleMap))
+	handle("/chunks", leaderOnly(handleChunks))
+	handle("/chunks/", leaderOnly(handleChunk))
+	handle("/servers", leaderOnly(handleServers))
+	handle("/leaderboard", leaderOnly(handleLeaderboard))
+	handle("/achievements", leaderOnly(handleAchievements))
+	handle("/match", leaderOnly(handleMatchGet))
+	handle("/match/queue", leaderOnly(handleMatchQueue))
+	handle("/match/leave", leaderOnly(handleMatchLeave))
+	if raftID != 0 {
+		// between central nodes only, so not behind CORS
+		http.HandleFunc("/raft", requireRaftPeer(handleRaft))
+	}
+	handle("/leader", handleLeader)
+	if *debugEndpoints {
+		metrics.HandleDebug(func(pattern string, h http.HandlerFunc) { handle(pattern, requireAdmin(h)) })
+	}
+	scheme := "HTTP"
+	if certs.Enabled() {
+		scheme = "HTTPS"
+	}
+	log.Printf("Central Server running on %s over %s (game servers: %s)", *listenAddr, scheme, *servers)
+	log.Fatal(netproto.ListenAndServe(*listenAddr, nil, certs))
+}
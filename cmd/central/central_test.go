@@ -0,0 +1,195 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+const (
+	ownerA = "10.0.0.1:8000" // holds the chunk
+	ownerB = "10.0.0.2:8000" // reports a new owner for it
+	ownerC = "10.0.0.3:8000" // the new owner
+)
+
+var leasedChunk = types.ChunkID{IDX: 4, IDY: -2}
+
+// leaseState sets up central's maps for a reportOwner case: chunk held by
+// holder ("" for nobody), with the given servers dead and last seen ago.
+type leaseState struct {
+	holder   string
+	dead     []string
+	seen     map[string]time.Duration
+	split    bool
+	noLeases bool // failover off
+}
+
+func (s leaseState) apply(t *testing.T) {
+	t.Helper()
+	zoneMu.Lock()
+	restoreZone(nil)
+	if s.holder != "" {
+		zone[leasedChunk] = s.holder
+	}
+	if s.split {
+		splits[leasedChunk] = true
+	}
+	for _, server := range s.dead {
+		dead[server] = true
+	}
+	zoneMu.Unlock()
+
+	worldReportsMu.Lock()
+	lastSeen = make(map[string]time.Time)
+	for server, ago := range s.seen {
+		lastSeen[server] = time.Now().Add(-ago)
+	}
+	worldReportsMu.Unlock()
+
+	// nobody to announce new owners to
+	oldDeadAfter, oldServers := deadAfter, serversList
+	serversList = nil
+	if s.noLeases {
+		deadAfter = 0
+	}
+	t.Cleanup(func() {
+		deadAfter, serversList = oldDeadAfter, oldServers
+		zoneMu.Lock()
+		restoreZone(nil)
+		zoneMu.Unlock()
+		worldReportsMu.Lock()
+		lastSeen = make(map[string]time.Time)
+		worldReportsMu.Unlock()
+	})
+}
+
+func TestReportOwner(t *testing.T) {
+	expired := deadAfter + time.Second
+	tests := []struct {
+		name   string
+		state  leaseState
+		caller string
+		world  string
+		status int
+		code   string
+		owner  string // the chunk's owner afterwards
+	}{
+		{"unowned chunk", leaseState{}, ownerB, "", http.StatusOK, types.CodeOK, ownerC},
+		{"already the owner", leaseState{holder: ownerC}, ownerB, "", http.StatusOK, types.CodeOK, ownerC},
+		{"holder hands it off", leaseState{holder: ownerB, seen: map[string]time.Duration{ownerB: 0}},
+			ownerB, "", http.StatusOK, types.CodeOK, ownerC},
+		{"holder's lease live", leaseState{holder: ownerA, seen: map[string]time.Duration{ownerA: time.Second}},
+			ownerB, "", http.StatusConflict, types.CodeNotOwner, ownerA},
+		{"holder's lease expired", leaseState{holder: ownerA, seen: map[string]time.Duration{ownerA: expired}},
+			ownerB, "", http.StatusOK, types.CodeOK, ownerC},
+		{"holder never reported", leaseState{holder: ownerA},
+			ownerB, "", http.StatusConflict, types.CodeNotOwner, ownerA},
+		{"failover off", leaseState{holder: ownerA, seen: map[string]time.Duration{ownerA: expired}, noLeases: true},
+			ownerB, "", http.StatusConflict, types.CodeNotOwner, ownerA},
+		{"holder declared dead", leaseState{holder: ownerA, dead: []string{ownerA}},
+			ownerB, "", http.StatusOK, types.CodeOK, ownerC},
+		{"caller declared dead", leaseState{holder: ownerA, dead: []string{ownerB}, seen: map[string]time.Duration{ownerA: expired}},
+			ownerB, "", http.StatusConflict, types.CodeFenced, ownerA},
+		{"new owner declared dead", leaseState{dead: []string{ownerC}},
+			ownerB, "", http.StatusConflict, types.CodeBadRequest, ""},
+		{"split chunk", leaseState{split: true}, ownerB, "", http.StatusConflict, types.CodeChunkSplit, ""},
+		{"unknown world", leaseState{}, ownerB, "nowhere", http.StatusNotFound, types.CodeNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.state.apply(t)
+			chunk_id := leasedChunk
+			chunk_id.World = tt.world
+			req := types.Request{ChunkID: chunk_id, CallerIP: tt.caller, TraceID: "t1"}
+
+			zoneMu.Lock()
+			res, status := reportOwner(req, ownerC)
+			owner := zone[chunk_id]
+			zoneMu.Unlock()
+
+			code := res.Code
+			if res.Success {
+				code = types.CodeOK
+			}
+			if status != tt.status || code != tt.code {
+				t.Errorf("reportOwner = %d %s (%s), want %d %s", status, code, res.Message, tt.status, tt.code)
+			}
+			if owner != tt.owner {
+				t.Errorf("owner afterwards %q, want %q", owner, tt.owner)
+			}
+			if tt.code == types.CodeNotOwner && res.RedirectIP != tt.owner {
+				t.Errorf("redirected to %q, want the holder %q", res.RedirectIP, tt.owner)
+			}
+		})
+	}
+}
+
+func TestAssignPeerChunkUnlocked(t *testing.T) {
+	tests := []struct {
+		name  string
+		moved bool // another server takes the chunk during the call
+		owner string
+	}{
+		{"caller takes it", false, ownerB},
+		{"chunk moved meanwhile", true, ownerC},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			leaseState{holder: ownerA}.apply(t)
+			mem := netproto.NewMemNetwork(1)
+			oldNetwork := network
+			network = mem
+			t.Cleanup(func() { network = oldNetwork })
+
+			// the owner answers FROM_CENTRAL only once let go
+			conn, err := mem.Listen(ownerA)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer conn.Close()
+			asked, answer := make(chan struct{}), make(chan struct{})
+			go func() {
+				from, _, err := conn.Recv()
+				if err != nil {
+					return
+				}
+				close(asked)
+				<-answer
+				netproto.SendJSON(conn, from, types.Response{Success: true, PlayerCount: 0})
+			}()
+
+			done := make(chan types.Response)
+			go func() {
+				res, _ := assignPeerChunk(types.Request{ChunkID: leasedChunk, CallerIP: ownerB, PlayerCount: 5, TraceID: "t1"})
+				done <- res
+			}()
+			<-asked
+			locked := make(chan struct{})
+			go func() {
+				zoneMu.Lock()
+				if tt.moved {
+					zone[leasedChunk] = ownerC
+				}
+				zoneMu.Unlock()
+				close(locked)
+			}()
+			select {
+			case <-locked:
+			case <-time.After(time.Second):
+				t.Fatal("zoneMu held during the FROM_CENTRAL round trip")
+			}
+			close(answer)
+
+			res := <-done
+			zoneMu.Lock()
+			owner := zone[leasedChunk]
+			zoneMu.Unlock()
+			if !res.Success || res.NewIP != tt.owner || owner != tt.owner {
+				t.Errorf("assignPeerChunk = %s (%s), owner afterwards %s, want %s", res.NewIP, res.Message, owner, tt.owner)
+			}
+		})
+	}
+}
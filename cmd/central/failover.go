@@ -0,0 +1,315 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Failure detection =====================
+
+// Game servers POST /heartbeat every second or so. Only those calls, from a
+// game server (see requireServer) for itself, keep it alive: the metrics it
+// reports to /experiment/report do not. A server not heard from for
+// deadAfter is declared dead and its chunks fail over: each goes to one of
+// its read replicas if it has a live one, else to the least loaded live
+// server of the same world, which is told with ADOPT_CHUNK. /chunk lookups
+// then name the new owner, and chunks still owned by a dead server are
+// handed to the caller rather than asking the dead owner. Only the leader
+// watches; a new leader gives every server a full deadAfter before judging
+// it.
+//
+// A dead server is fenced off rather than trusted again when it next calls:
+// its heartbeats, /chunk and /sentchunk are answered ERR_FENCED until it
+// POSTs /rejoin, which it does only after dropping every chunk it held. Each
+// /rejoin starts a new incarnation of the server, numbered by its epoch;
+// heartbeats carry the epoch, so an older incarnation still running is
+// fenced too. A heartbeat's reply grants a lease of deadAfter, which the
+// server times from before it called: it stops writing to its chunks once
+// the lease runs out without another, which is before central can have
+// declared it dead and handed them to someone else. Epochs and deaths are
+// part of the replicated zone map.
+
+// deadAfter is how long a silent game server is given (0 disables).
+var deadAfter = 5 * time.Second
+
+var (
+	// guarded by worldReportsMu, like worldReports
+	lastSeen = make(map[string]time.Time)
+	// guarded by zoneMu
+	dead   = make(map[string]bool)
+	epochs = make(map[string]uint64) // servers that have joined, by incarnation
+)
+
+var (
+	failoversTotal = metrics.NewCounterVec("central_chunk_failovers_total",
+		"Chunks reassigned away from a dead game server, by how the new owner was picked.", "via")
+	_ = metrics.NewGaugeFunc("central_servers_dead",
+		"Game servers currently declared dead.", "", func() map[string]float64 {
+			zoneMu.Lock()
+			defer zoneMu.Unlock()
+			return map[string]float64{"": float64(len(dead))}
+		})
+)
+
+// leaseExpiry is when server's hold on its chunks runs out: central fails
+// them over unless it reports again by then. There is none while failover
+// is off or before server first reports. Must be called with
+// worldReportsMu held.
+func leaseExpiry(server string) (time.Time, bool) {
+	seen, ok := lastSeen[server]
+	if !ok || deadAfter <= 0 {
+		return time.Time{}, false
+	}
+	return seen.Add(deadAfter), true
+}
+
+// handleHeartbeat serves POST /heartbeat: a game server (CallerIP, in its
+// incarnation Epoch) telling central it is alive.
+func handleHeartbeat(w http.ResponseWriter, r *http.Request) {
+	req, ok := serverCall(w, r)
+	if !ok {
+		return
+	}
+	zoneMu.Lock()
+	epoch, joined := epochs[req.CallerIP]
+	gone := dead[req.CallerIP]
+	zoneMu.Unlock()
+	switch {
+	case gone || joined && req.Epoch != epoch:
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Fenced off; drop your chunks and rejoin", Code: types.CodeFenced})
+		return
+	case !joined:
+		// this central has no record of the server, so cannot have
+		// failed over its chunks: it may rejoin keeping them
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Not joined; rejoin", Code: types.CodeNotFound})
+		return
+	}
+	markAlive(req.CallerIP)
+	json.NewEncoder(w).Encode(types.Response{Success: true, Epoch: epoch, LeaseMs: deadAfter.Milliseconds()})
+}
+
+// handleRejoin serves POST /rejoin: a game server (CallerIP) starting a new
+// incarnation, after it started or was fenced off. It answers with the new
+// epoch and the lease its heartbeats grant.
+func handleRejoin(w http.ResponseWriter, r *http.Request) {
+	req, ok := serverCall(w, r)
+	if !ok {
+		return
+	}
+	server := req.CallerIP
+	zoneMu.Lock()
+	was_dead := dead[server]
+	commitZone(ZoneCommand{Op: "rejoin", Owner: server})
+	epoch := epochs[server]
+	mark := markZone()
+	zoneMu.Unlock()
+	if !awaitZone(w, mark) {
+		return
+	}
+
+	markAlive(server)
+	if was_dead {
+		worldReportsMu.Lock()
+		world := worldReports[server].World.Name
+		worldReportsMu.Unlock()
+		log.Printf("💚 Game server %s rejoined after being declared dead (epoch %d)", server, epoch)
+		notifyWebhooks(eventServerRecovered, ServerEvent{Server: server, World: world})
+	} else {
+		log.Printf("👋 Game server %s joined (epoch %d)", server, epoch)
+	}
+	json.NewEncoder(w).Encode(types.Response{Success: true, Epoch: epoch, LeaseMs: deadAfter.Milliseconds()})
+}
+
+// serverCall decodes the body of a game server's POST about itself, and
+// refuses it unless its CallerIP is in serversList.
+func serverCall(w http.ResponseWriter, r *http.Request) (types.Request, bool) {
+	var req types.Request
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.CallerIP == "" {
+		http.Error(w, "Missing caller_ip", http.StatusBadRequest)
+		return req, false
+	}
+	if !slices.Contains(serversList, req.CallerIP) {
+		w.WriteHeader(http.StatusForbidden)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Unknown server " + req.CallerIP, Code: types.CodeForbidden})
+		return req, false
+	}
+	return req, true
+}
+
+// markAlive records a heartbeat from server. It does not bring a dead
+// server back: that takes a /rejoin.
+func markAlive(server string) {
+	worldReportsMu.Lock()
+	_, seen := lastSeen[server]
+	lastSeen[server] = time.Now()
+	world := worldReports[server].World.Name
+	worldReportsMu.Unlock()
+	if !seen {
+		notifyWebhooks(eventServerJoined, ServerEvent{Server: server, World: world})
+	}
+}
+
+// watchServers declares silent servers dead while this node leads.
+func watchServers() {
+	if deadAfter <= 0 {
+		return
+	}
+	ticker := time.NewTicker(deadAfter / 5)
+	defer ticker.Stop()
+
+	var since time.Time // when this node started watching
+	for now := range ticker.C {
+		if raftNode != nil {
+			if lead, ready := raftLeader(); lead != raftID || !ready {
+				since = time.Time{}
+				continue
+			}
+		}
+		if since.IsZero() {
+			since = now
+		}
+
+		var silent []string
+		worldReportsMu.Lock()
+		for _, server := range serversList {
+			seen := lastSeen[server]
+			if seen.Before(since) {
+				seen = since
+			}
+			if now.Sub(seen) > deadAfter {
+				silent = append(silent, server)
+			}
+		}
+		worldReportsMu.Unlock()
+
+		for _, server := range silent {
+			zoneMu.Lock()
+			known := dead[server]
+			zoneMu.Unlock()
+			if !known {
+				failover(server)
+			}
+		}
+	}
+}
+
+// failover declares server dead and reassigns its chunks.
+func failover(server string) {
+	trace := netproto.NewTraceID()
+	candidates := failoverCandidates(server)
+
+	zoneMu.Lock()
+	commitZone(ZoneCommand{Op: "fence", Owner: server})
+	var owned []types.ChunkID
+	for chunk_id, owner := range zone {
+		if owner == server {
+			owned = append(owned, chunk_id)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		a, b := owned[i], owned[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.IDX != b.IDX {
+			return a.IDX < b.IDX
+		}
+		return a.IDY < b.IDY
+	})
+
+	moved := make(map[types.ChunkID]string, len(owned))
+	target_epochs := make(map[string]uint64)
+	for _, chunk_id := range owned {
+		target, via := "", "replica"
+		for _, replica := range replicas[chunk_id] {
+			if !dead[replica] && replica != server {
+				target = replica
+				break
+			}
+		}
+		if target == "" {
+			target, via = leastLoaded(candidates), "load"
+		}
+		if target == "" {
+			continue // nobody left to take it
+		}
+		candidates[target]++
+		assignChunk(chunk_id, target)
+		migrationsTotal.Inc("")
+		failoversTotal.Inc(via)
+		moved[chunk_id] = target
+		target_epochs[target] = epochs[target]
+	}
+	mark := markZone()
+	zoneMu.Unlock()
+
+	log.Printf("💀 Game server %s declared dead after %s of silence; failing over %d of its %d chunks", server, deadAfter, len(moved), len(owned))
+	worldReportsMu.Lock()
+	world := worldReports[server].World.Name
+	worldReportsMu.Unlock()
+	notifyWebhooks(eventServerDead, ServerEvent{Server: server, World: world, Chunks: len(owned), FailedOver: len(moved)})
+	if err := waitZone(mark); err != nil {
+		log.Printf("⚠️  Failover of %s not committed: %v", server, err)
+		return
+	}
+
+	for chunk_id, target := range moved {
+		go func(chunk_id types.ChunkID, target string) {
+			req := types.Request{Type: types.ReqAdoptChunk, ChunkID: chunk_id, Reason: "owner " + server + " is dead", Epoch: target_epochs[target], TraceID: trace}
+			res, err := netproto.RoundTrip(network, target, req, 2*time.Second)
+			if err != nil || !res.Success {
+				netproto.Tracef(trace, "⚠️  ADOPT_CHUNK [%d,%d] on %s failed: %v %s", chunk_id.IDX, chunk_id.IDY, target, err, res.Message)
+				return
+			}
+			netproto.Tracef(trace, "🩹 Chunk [%d,%d] failed over from %s to %s", chunk_id.IDX, chunk_id.IDY, server, target)
+		}(chunk_id, target)
+	}
+}
+
+// failoverCandidates returns the live servers of the dead server's world
+// with their reported player counts. Must not be called with zoneMu held.
+func failoverCandidates(server string) map[string]int {
+	worldReportsMu.Lock()
+	world, known := worldReports[server]
+	load := make(map[string]int, len(serversList))
+	for _, candidate := range serversList {
+		report, ok := worldReports[candidate]
+		if candidate == server || known && (!ok || report.World.Name != world.World.Name) {
+			continue
+		}
+		load[candidate] = report.Players
+	}
+	worldReportsMu.Unlock()
+
+	zoneMu.Lock()
+	for candidate := range load {
+		if dead[candidate] {
+			delete(load, candidate)
+		}
+	}
+	zoneMu.Unlock()
+	return load
+}
+
+// leastLoaded picks the server with the fewest players, by address on ties.
+func leastLoaded(load map[string]int) string {
+	best := ""
+	for server, players := range load {
+		if best == "" || players < load[best] || players == load[best] && server < best {
+			best = server
+		}
+	}
+	return best
+}
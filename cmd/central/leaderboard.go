@@ -0,0 +1,127 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Leaderboards =====================
+
+// Game servers count what their players do and send the counts since their
+// last report with it (WorldMetrics.PlayerStats). Central adds them to the
+// players' profiles, so the totals last across sessions and servers, and
+// GET /leaderboard?stat=&limit= ranks the players by one of them.
+
+const (
+	defaultLeaderboardSize = 10
+	maxLeaderboardSize     = 100
+)
+
+// leaderboard_stats maps each stat a leaderboard can rank by to its value.
+var leaderboard_stats = map[string]func(types.PlayerStats) float64{
+	"cubes_placed":   func(s types.PlayerStats) float64 { return float64(s.CubesPlaced) },
+	"cubes_removed":  func(s types.PlayerStats) float64 { return float64(s.CubesRemoved) },
+	"distance":       func(s types.PlayerStats) float64 { return s.Distance },
+	"chunks_visited": func(s types.PlayerStats) float64 { return float64(s.ChunksVisited) },
+	"kills":          func(s types.PlayerStats) float64 { return float64(s.Kills) },
+	"deaths":         func(s types.PlayerStats) float64 { return float64(s.Deaths) },
+}
+
+// addStats adds counts from a game server's report to the profiles and
+// returns the achievements they earned by it.
+func addStats(list []types.PlayerStats) []types.Award {
+	if len(list) == 0 {
+		return nil
+	}
+	now := time.Now().UTC()
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	var awards []types.Award
+	var changed []string
+	for _, stats := range list {
+		if stats.PlayerID == "" {
+			continue
+		}
+		profile, ok := profiles[stats.PlayerID]
+		if !ok {
+			profile = types.PlayerProfile{PlayerID: stats.PlayerID}
+		}
+		profile.Stats.Add(stats)
+		profile.Stats.PlayerID = ""
+		awards = append(awards, checkAchievements(&profile, now)...)
+		profiles[stats.PlayerID] = profile
+		changed = append(changed, stats.PlayerID)
+	}
+	if err := saveProfiles(changed...); err != nil {
+		log.Printf("ERROR: Failed to save profiles: %v", err)
+	}
+	return awards
+}
+
+// handleLeaderboard serves GET /leaderboard: the players with the highest
+// ?stat= (kills by default), at most ?limit= of them.
+func handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	stat := r.URL.Query().Get("stat")
+	if stat == "" {
+		stat = "kills"
+	}
+	value, ok := leaderboard_stats[stat]
+	if !ok {
+		names := make([]string, 0, len(leaderboard_stats))
+		for name := range leaderboard_stats {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		http.Error(w, "Unknown stat; one of "+strings.Join(names, ", "), http.StatusBadRequest)
+		return
+	}
+	limit := defaultLeaderboardSize
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxLeaderboardSize)
+	}
+
+	board := types.Leaderboard{Stat: stat, Entries: []types.LeaderboardEntry{}}
+	profilesMu.Lock()
+	for player_id, profile := range profiles {
+		if v := value(profile.Stats); v > 0 {
+			board.Entries = append(board.Entries, types.LeaderboardEntry{PlayerID: player_id, Value: v})
+		}
+	}
+	profilesMu.Unlock()
+
+	sort.Slice(board.Entries, func(i, j int) bool {
+		a, b := board.Entries[i], board.Entries[j]
+		if a.Value != b.Value {
+			return a.Value > b.Value
+		}
+		return a.PlayerID < b.PlayerID
+	})
+	if len(board.Entries) > limit {
+		board.Entries = board.Entries[:limit]
+	}
+	for i := range board.Entries {
+		// players tied on the value share a rank
+		if i > 0 && board.Entries[i].Value == board.Entries[i-1].Value {
+			board.Entries[i].Rank = board.Entries[i-1].Rank
+		} else {
+			board.Entries[i].Rank = i + 1
+		}
+	}
+	json.NewEncoder(w).Encode(board)
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Matchmaking =====================
+
+// Players queue for a game mode with POST /match/queue. Each mode (-match-
+// modes) has a number of teams of a fixed size; once enough players are
+// queued for one, the longest waiting form a match. It is played on the
+// least loaded live server that has room for it (-match-capacity), in a
+// world of its own whose arena is arenaChunks x arenaChunks chunks from the
+// origin. Central registers the world, hands the arena to the server, which
+// seeds it freshly with MATCH, and then sends the match to every other live
+// server, which push it to the players they hold. GET /match?player_id= shows a player's match or place in the
+// queue, for players who cannot take pushes; POST /match/leave takes them
+// out of either. Queues and matches live in the memory of the central node
+// that keeps them.
+
+type MatchMode struct {
+	Teams    int
+	TeamSize int
+}
+
+func (m MatchMode) players() int { return m.Teams * m.TeamSize }
+
+var (
+	// matchModes are the modes players can queue for, from -match-modes.
+	matchModes = map[string]MatchMode{"duel": {2, 1}, "squads": {2, 4}}
+	// matchCapacity is the most players a server takes before matches go
+	// elsewhere (0 is unlimited).
+	matchCapacity = 0
+	arenaChunks   = 2
+)
+
+const (
+	// queueIdle is how long a queued player keeps their place without
+	// queueing again or asking GET /match.
+	queueIdle = 5 * time.Minute
+	// matchTTL is how long a match is remembered for its players.
+	matchTTL = time.Hour
+)
+
+type MatchRequest struct {
+	PlayerID string `json:"player_id"`
+	Mode     string `json:"mode,omitempty"` // for /match/queue
+}
+
+type queuedPlayer struct {
+	PlayerID string
+	Polled   time.Time
+}
+
+var (
+	matchMu     sync.Mutex
+	matchQueues = make(map[string][]queuedPlayer) // by mode, longest waiting first
+	matches     = make(map[string]types.Match)
+	playerMatch = make(map[string]string)
+)
+
+var matchesTotal = metrics.NewCounterVec("central_matches_total",
+	"Matches by mode and outcome: formed, or failed (no server with room, or seeding failed).", "result")
+
+// parseModes reads -match-modes, a list like "duel=2x1,squads=2x4" of
+// modes with their teams x players per team.
+func parseModes(list string) (map[string]MatchMode, error) {
+	modes := make(map[string]MatchMode)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, shape, ok := strings.Cut(entry, "=")
+		teams, size, ok2 := strings.Cut(shape, "x")
+		t, err1 := strconv.Atoi(teams)
+		s, err2 := strconv.Atoi(size)
+		if !ok || !ok2 || name == "" || err1 != nil || err2 != nil || t < 1 || s < 1 {
+			return nil, fmt.Errorf("bad match mode %q (want name=TEAMSxSIZE)", entry)
+		}
+		modes[name] = MatchMode{Teams: t, TeamSize: s}
+	}
+	return modes, nil
+}
+
+func handleMatchGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	player_id := r.URL.Query().Get("player_id")
+
+	matchMu.Lock()
+	res := matchStatus(player_id)
+	matchMu.Unlock()
+	json.NewEncoder(w).Encode(res)
+}
+
+func handleMatchQueue(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMatchRequest(w, r)
+	if !ok {
+		return
+	}
+	mode, known := matchModes[req.Mode]
+	if !known {
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: fmt.Sprintf("Unknown mode %q", req.Mode), Code: types.CodeBadRequest})
+		return
+	}
+
+	matchMu.Lock()
+	if _, playing := playerMatch[req.PlayerID]; playing {
+		res := matchStatus(req.PlayerID)
+		matchMu.Unlock()
+		json.NewEncoder(w).Encode(res)
+		return
+	}
+	dequeue(req.PlayerID)
+	matchQueues[req.Mode] = append(matchQueues[req.Mode], queuedPlayer{PlayerID: req.PlayerID, Polled: time.Now()})
+	pruneQueue(req.Mode)
+	var picked []string
+	if queue := matchQueues[req.Mode]; len(queue) >= mode.players() {
+		for _, queued := range queue[:mode.players()] {
+			picked = append(picked, queued.PlayerID)
+		}
+		matchQueues[req.Mode] = append([]queuedPlayer(nil), queue[mode.players():]...)
+	}
+	matchMu.Unlock()
+
+	if picked != nil {
+		if _, err := startMatch(req.Mode, mode, picked); err != nil {
+			log.Printf("⚠️  %s match for %v not started: %v", req.Mode, picked, err)
+			matchMu.Lock()
+			requeue(req.Mode, picked)
+			matchMu.Unlock()
+		}
+	}
+
+	matchMu.Lock()
+	res := matchStatus(req.PlayerID)
+	matchMu.Unlock()
+	json.NewEncoder(w).Encode(res)
+}
+
+func handleMatchLeave(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodeMatchRequest(w, r)
+	if !ok {
+		return
+	}
+
+	matchMu.Lock()
+	queued := dequeue(req.PlayerID)
+	_, playing := playerMatch[req.PlayerID]
+	delete(playerMatch, req.PlayerID)
+	matchMu.Unlock()
+	if !queued && !playing {
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Not queued or in a match", Code: types.CodeNotFound})
+		return
+	}
+	json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Left matchmaking"})
+}
+
+func decodeMatchRequest(w http.ResponseWriter, r *http.Request) (MatchRequest, bool) {
+	var req MatchRequest
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// matchStatus answers for player_id's match, or their place in a queue,
+// which it keeps for them. Must be called with matchMu held.
+func matchStatus(player_id string) types.Response {
+	now := time.Now()
+	for id, match := range matches {
+		if now.Sub(time.UnixMilli(match.FormedMs)) > matchTTL {
+			delete(matches, id)
+		}
+	}
+	if match, ok := matches[playerMatch[player_id]]; ok {
+		return types.Response{Success: true, Message: "Match ready", Code: types.CodeRedirect, RedirectIP: match.Server, Match: &match}
+	}
+	delete(playerMatch, player_id)
+
+	for mode, queue := range matchQueues {
+		for i := range queue {
+			if queue[i].PlayerID == player_id {
+				queue[i].Polled = now
+				return types.Response{Success: true, Message: "Queued for " + mode, QueuePosition: i + 1,
+					PlayerCount: matchModes[mode].players()}
+			}
+		}
+	}
+	return types.Response{Success: false, Message: "Not queued or in a match", Code: types.CodeNotFound}
+}
+
+// dequeue takes player_id out of any queue. Must be called with matchMu
+// held.
+func dequeue(player_id string) bool {
+	for mode, queue := range matchQueues {
+		for i, queued := range queue {
+			if queued.PlayerID == player_id {
+				matchQueues[mode] = append(queue[:i:i], queue[i+1:]...)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// pruneQueue drops the players queued for mode who stopped asking. Must be
+// called with matchMu held.
+func pruneQueue(mode string) {
+	now := time.Now()
+	kept := matchQueues[mode][:0]
+	for _, queued := range matchQueues[mode] {
+		if now.Sub(queued.Polled) <= queueIdle {
+			kept = append(kept, queued)
+		}
+	}
+	matchQueues[mode] = kept
+}
+
+// requeue puts the players of a match that could not start back at the
+// head of mode's queue. Must be called with matchMu held.
+func requeue(mode string, picked []string) {
+	now := time.Now()
+	var head []queuedPlayer
+	for _, player_id := range picked {
+		head = append(head, queuedPlayer{PlayerID: player_id, Polled: now})
+	}
+	matchQueues[mode] = append(head, matchQueues[mode]...)
+}
+
+// startMatch forms a match of picked, who fill mode's teams in queue order,
+// hands its arena to a server with room and tells every server about it.
+func startMatch(name string, mode MatchMode, picked []string) (types.Match, error) {
+	server, err := matchServer(len(picked))
+	if err != nil {
+		matchesTotal.Inc("failed")
+		return types.Match{}, err
+	}
+
+	match := types.Match{ID: netproto.NewTraceID(), Mode: name, Server: server, FormedMs: time.Now().UnixMilli()}
+	match.World = "match-" + match.ID
+	for t := 0; t < mode.Teams; t++ {
+		match.Teams = append(match.Teams, picked[t*mode.TeamSize:(t+1)*mode.TeamSize])
+	}
+	for x := 0; x < arenaChunks; x++ {
+		for y := 0; y < arenaChunks; y++ {
+			match.Chunks = append(match.Chunks, types.ChunkID{World: match.World, IDX: x, IDY: y})
+		}
+	}
+	// teams spawn spread across the arena, halfway up
+	edge := arenaChunks * chunkSize
+	for t := 0; t < mode.Teams; t++ {
+		match.Spawns = append(match.Spawns, types.SpawnPoint{PosX: (2*t + 1) * edge / (2 * mode.Teams), PosY: edge / 2})
+	}
+
+	zoneMu.Lock()
+	addWorld(types.World{ID: match.World, Kind: "match", CreatedMs: match.FormedMs})
+	for _, chunk_id := range match.Chunks {
+		assignChunk(chunk_id, server)
+	}
+	mark := markZone()
+	zoneMu.Unlock()
+	if err := waitZone(mark); err != nil {
+		matchesTotal.Inc("failed")
+		return types.Match{}, err
+	}
+
+	req := types.Request{Type: types.ReqMatch, Match: &match, TraceID: match.ID}
+	res, err := netproto.RoundTrip(network, server, req, 2*time.Second)
+	if err == nil && !res.Success {
+		err = fmt.Errorf("%s: %s", res.Code, res.Message)
+	}
+	if err != nil {
+		matchesTotal.Inc("failed")
+		return types.Match{}, fmt.Errorf("seeding on %s: %w", server, err)
+	}
+
+	matchMu.Lock()
+	matches[match.ID] = match
+	for _, team := range match.Teams {
+		for _, player_id := range team {
+			playerMatch[player_id] = match.ID
+		}
+	}
+	matchMu.Unlock()
+
+	pushed := res.PlayerCount + notifyMatch(match)
+	matchesTotal.Inc("formed")
+	log.Printf("🏟️  %s match %s on %s in world %s: teams %v, pushed to %d of %d players",
+		name, match.ID, server, match.World, match.Teams, pushed, len(picked))
+	return match, nil
+}
+
+// matchServer picks the least loaded live server with room for a match of
+// size players, counting them as joined there.
+func matchServer(size int) (string, error) {
+	zoneMu.Lock()
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+
+	worldReportsMu.Lock()
+	defer worldReportsMu.Unlock()
+	load := make(map[string]int, len(live))
+	for _, server := range live {
+		players := worldReports[server].Players + joinedSince[server]
+		if matchCapacity == 0 || players+size <= matchCapacity {
+			load[server] = players
+		}
+	}
+	server := leastLoaded(load)
+	if server == "" {
+		return "", fmt.Errorf("no live server has room for %d more players", size)
+	}
+	joinedSince[server] += size
+	return server, nil
+}
+
+// notifyMatch sends match to every live server but its host, which had it
+// already, and returns how many players they pushed it to.
+func notifyMatch(match types.Match) int {
+	zoneMu.Lock()
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] && server != match.Server {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+
+	req := types.Request{Type: types.ReqMatch, Match: &match, TraceID: match.ID}
+	var (
+		wg     sync.WaitGroup
+		mu     sync.Mutex
+		pushed int
+	)
+	for _, server := range live {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			res, err := netproto.RoundTrip(network, server, req, 2*time.Second)
+			if err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  MATCH on %s failed: %v", server, err)
+				return
+			}
+			mu.Lock()
+			pushed += res.PlayerCount
+			mu.Unlock()
+		}(server)
+	}
+	wg.Wait()
+	return pushed
+}
+
+// modeNames lists matchModes for logs.
+func modeNames() []string {
+	var names []string
+	for name, mode := range matchModes {
+		names = append(names, fmt.Sprintf("%s=%dx%d", name, mode.Teams, mode.TeamSize))
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Migration hysteresis =====================
+
+// A chunk migrates to a server asking for it at /chunk only if that server
+// has at least migrateMinLead more players in it than the owner (more inside
+// the owner's region, see regionHysteresis), and not within migrateCooldown
+// of its last change of owner. Central tells the owner the lead it requires
+// in FROM_CENTRAL, so both sides reach the same decision and two servers of
+// similar load stop passing a chunk back and forth.
+
+var (
+	migrateMinLead  = 1
+	migrateCooldown = 10 * time.Second
+
+	// when each chunk last changed owner; guarded by zoneMu, like zone
+	migratedAt = make(map[types.ChunkID]time.Time)
+)
+
+var migrationsHeldTotal = metrics.NewCounterVec("central_migrations_held_total",
+	"Chunk migrations asked for at /chunk and held back, by reason (cooldown, lead, region, locked).", "reason")
+
+// coolingDown reports whether chunk_id changed owner too recently to move
+// again. Must be called with zoneMu held.
+func coolingDown(chunk_id types.ChunkID) bool {
+	at, ok := migratedAt[chunk_id]
+	if !ok {
+		return false
+	}
+	if time.Since(at) >= migrateCooldown {
+		delete(migratedAt, chunk_id)
+		return false
+	}
+	return true
+}
+
+// migrationLead returns how many more players than owner a caller needs in
+// chunk_id to take it over. Must be called with zoneMu held.
+func migrationLead(chunk_id types.ChunkID, owner string) int {
+	lead := max(migrateMinLead, 1)
+	if region_owner, ok := regionOwner(chunk_id); ok && region_owner == owner {
+		lead = max(lead, regionHysteresis+1)
+	}
+	return lead
+}
+
+// heldReason names why a caller with lead more players than the owner did
+// not get the chunk, for migrationsHeldTotal. Must be called with zoneMu
+// held.
+func heldReason(lead int) string {
+	if lead < max(migrateMinLead, 1) {
+		return "lead"
+	}
+	return "region"
+}
+
+// announceOwner tells every live game server that chunk_id is now owner's,
+// for servers caching owners (-owner-cache) to stop routing to the old one.
+// It does not wait for them. Must be called with zoneMu held.
+func announceOwner(chunk_id types.ChunkID, owner string) {
+	req := types.Request{Type: types.ReqOwnerChanged, ChunkID: chunk_id, Owner: owner, TraceID: netproto.NewTraceID()}
+	for _, server := range serversList {
+		if dead[server] {
+			continue
+		}
+		go func(server string) {
+			if _, err := netproto.RoundTrip(network, server, req, 2*time.Second); err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  OWNER_CHANGED [%d,%d] on %s failed: %v", chunk_id.IDX, chunk_id.IDY, server, err)
+			}
+		}(server)
+	}
+}
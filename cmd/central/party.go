@@ -0,0 +1,206 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Parties =====================
+
+// Players group up with POST /party/create, /party/join and /party/leave;
+// GET /party?player_id= shows a player's party. A player is in at most one
+// party of up to maxPartySize. Every change is sent to all live game servers
+// with PARTY_UPDATE, and they put the party members in each member's
+// GET_UPDATES replies wherever in the world they are, sharing the positions
+// of members held elsewhere through gossip. Parties live in the memory of
+// the central node that formed them.
+
+const maxPartySize = 8
+
+type PartyRequest struct {
+	PlayerID string `json:"player_id"`
+	PartyID  string `json:"party_id,omitempty"` // for /party/join
+}
+
+var (
+	partiesMu   sync.Mutex
+	parties     = make(map[string]*types.Party)
+	playerParty = make(map[string]string)
+)
+
+var partyChangesTotal = metrics.NewCounterVec("central_party_changes_total",
+	"Party changes by action: create, join or leave.", "action")
+
+func handlePartyGet(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	partiesMu.Lock()
+	party, ok := parties[playerParty[r.URL.Query().Get("player_id")]]
+	var res types.Response
+	if ok {
+		res = types.Response{Success: true, Message: party.ID, Party: cloneParty(party)}
+	} else {
+		res = types.Response{Success: false, Message: "Not in a party", Code: types.CodeNotFound}
+	}
+	partiesMu.Unlock()
+	json.NewEncoder(w).Encode(res)
+}
+
+func handlePartyCreate(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePartyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	partiesMu.Lock()
+	old, left := leaveParty(req.PlayerID)
+	party := &types.Party{ID: netproto.NewTraceID(), Leader: req.PlayerID, Members: []string{req.PlayerID}}
+	parties[party.ID] = party
+	playerParty[req.PlayerID] = party.ID
+	created := *cloneParty(party)
+	partiesMu.Unlock()
+
+	partyChangesTotal.Inc("create")
+	if left {
+		broadcastParty(old)
+	}
+	broadcastParty(created)
+	log.Printf("🎉 %s created party %s", req.PlayerID, created.ID)
+	json.NewEncoder(w).Encode(types.Response{Success: true, Message: created.ID, Party: &created})
+}
+
+func handlePartyJoin(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePartyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	partiesMu.Lock()
+	party, exists := parties[req.PartyID]
+	switch {
+	case !exists:
+		partiesMu.Unlock()
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "No such party", Code: types.CodeNotFound})
+		return
+	case playerParty[req.PlayerID] == req.PartyID:
+		joined := *cloneParty(party)
+		partiesMu.Unlock()
+		json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Already in the party", Party: &joined})
+		return
+	case len(party.Members) >= maxPartySize:
+		partiesMu.Unlock()
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: fmt.Sprintf("Party is full (%d)", maxPartySize), Code: types.CodeBadRequest})
+		return
+	}
+	old, left := leaveParty(req.PlayerID)
+	party.Members = append(party.Members, req.PlayerID)
+	playerParty[req.PlayerID] = party.ID
+	joined := *cloneParty(party)
+	partiesMu.Unlock()
+
+	partyChangesTotal.Inc("join")
+	if left {
+		broadcastParty(old)
+	}
+	broadcastParty(joined)
+	log.Printf("🎉 %s joined party %s (%d members)", req.PlayerID, joined.ID, len(joined.Members))
+	json.NewEncoder(w).Encode(types.Response{Success: true, Message: joined.ID, Party: &joined})
+}
+
+func handlePartyLeave(w http.ResponseWriter, r *http.Request) {
+	req, ok := decodePartyRequest(w, r)
+	if !ok {
+		return
+	}
+
+	partiesMu.Lock()
+	old, left := leaveParty(req.PlayerID)
+	partiesMu.Unlock()
+	if !left {
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Not in a party", Code: types.CodeNotFound})
+		return
+	}
+
+	partyChangesTotal.Inc("leave")
+	broadcastParty(old)
+	log.Printf("👋 %s left party %s", req.PlayerID, old.ID)
+	json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Left the party"})
+}
+
+func decodePartyRequest(w http.ResponseWriter, r *http.Request) (PartyRequest, bool) {
+	var req PartyRequest
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return req, false
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return req, false
+	}
+	return req, true
+}
+
+// leaveParty takes player_id out of their party, handing the lead on or
+// disbanding it, and returns the party as it is left. Must be called with
+// partiesMu held.
+func leaveParty(player_id string) (types.Party, bool) {
+	party, ok := parties[playerParty[player_id]]
+	if !ok {
+		return types.Party{}, false
+	}
+	delete(playerParty, player_id)
+	kept := party.Members[:0]
+	for _, member := range party.Members {
+		if member != player_id {
+			kept = append(kept, member)
+		}
+	}
+	party.Members = kept
+	if len(kept) == 0 {
+		delete(parties, party.ID)
+	} else if party.Leader == player_id {
+		party.Leader = kept[0]
+	}
+	return *cloneParty(party), true
+}
+
+func cloneParty(party *types.Party) *types.Party {
+	clone := *party
+	clone.Members = append([]string(nil), party.Members...)
+	return &clone
+}
+
+// broadcastParty sends party's member list to every live game server.
+func broadcastParty(party types.Party) {
+	zoneMu.Lock()
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+
+	req := types.Request{Type: types.ReqPartyUpdate, Party: &party, TraceID: netproto.NewTraceID()}
+	var wg sync.WaitGroup
+	for _, server := range live {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			if _, err := netproto.RoundTrip(network, server, req, 2*time.Second); err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  PARTY_UPDATE %s on %s failed: %v", party.ID, server, err)
+			}
+		}(server)
+	}
+	wg.Wait()
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Player placement =====================
+
+// /join sends a player to the live game server owning the chunk they spawn
+// in, or its region, so players joining near a hotspot land where it already
+// is and nothing has to migrate. Elsewhere they go to the least loaded live
+// server, counting the players it last reported plus those sent to it since.
+
+// guarded by worldReportsMu; each report resets its server's count
+var joinedSince = make(map[string]int)
+
+var placementsTotal = metrics.NewCounterVec("central_join_placements_total",
+	"Players placed at /join, by reason (owner of the spawn chunk, or load).", "reason")
+
+// placePlayer picks the game server a joining player should use.
+func placePlayer(req types.PlayerJoinRequest) string {
+	zoneMu.Lock()
+	spawn := types.ResolveChunk(req.World, req.PosX, req.PosY, chunkSize, splits)
+	owner, owned := zone[spawn]
+	if !owned {
+		owner, owned = regionOwner(spawn)
+	}
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+	if len(live) == 0 {
+		// better a server that may come back than no answer
+		live = serversList
+	}
+
+	worldReportsMu.Lock()
+	defer worldReportsMu.Unlock()
+	for _, server := range live {
+		if owned && server == owner {
+			joinedSince[owner]++
+			placementsTotal.Inc("owner")
+			return owner
+		}
+	}
+	load := make(map[string]int, len(live))
+	for _, server := range live {
+		load[server] = worldReports[server].Players + joinedSince[server]
+	}
+	server := leastLoaded(load)
+	joinedSince[server]++
+	placementsTotal.Inc("load")
+	return server
+}
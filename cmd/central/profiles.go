@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Player profiles =====================
+
+// Central keeps a profile of every player who has left a game server (see
+// types.PlayerProfile). Game servers POST the player to /profile whenever
+// one leaves them: DLT_PLAYER, an idle timeout, a kick or an eviction. A
+// player starting a new session at /join gets their profile back and is
+// placed where it left them, in its world if that still exists, and the
+// client enters there with the profile's health and inventory. A resumed
+// session needs none of this: the server still holding the player has them.
+//
+// A player who walked on to another server may still time out on the one
+// they left, so a save older than the profile (by Player.UpdatedMs, the
+// server time of their last move) is ignored. Only game servers may save
+// one (see requireServer), and only for a server in serversList.
+//
+// Profiles are kept like the banlist: in memory, and in -profiles when set.
+// The file is a log of profiles, one JSON object a line, the last line for a
+// player winning: every change appends the profiles it touched, synced
+// before it is acknowledged, and the file is compacted to one line per
+// player, through a temporary file and a rename, once it holds more than
+// twice as many lines as there are profiles. A line torn by a crash is
+// dropped at the next start.
+
+var (
+	profilesMu    sync.Mutex
+	profiles      = make(map[string]types.PlayerProfile)
+	profilesPath  string
+	profilesFile  *os.File // profilesPath, open for appending
+	profilesLines int      // lines in profilesFile
+)
+
+// profilesSlack is how many lines past twice the profiles the file may grow
+// to before it is compacted, so small files are not rewritten all the time.
+const profilesSlack = 1024
+
+var profileSavesTotal = metrics.NewCounterVec("central_profile_saves_total",
+	"Profile saves from game servers: saved, or stale (older than the profile).", "result")
+
+// loadProfiles reads -profiles, in the current format or the JSON array it
+// used to be, and compacts it.
+func loadProfiles() error {
+	data, err := os.ReadFile(profilesPath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '[' {
+		var list []types.PlayerProfile
+		if err := json.Unmarshal(trimmed, &list); err != nil {
+			return err
+		}
+		for _, profile := range list {
+			profiles[profile.PlayerID] = profile
+		}
+	} else {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		for {
+			var profile types.PlayerProfile
+			if err := dec.Decode(&profile); err == io.EOF {
+				break
+			} else if err != nil {
+				log.Printf("⚠️  Dropping the rest of %s from offset %d: %v", profilesPath, dec.InputOffset(), err)
+				break
+			}
+			profiles[profile.PlayerID] = profile
+		}
+	}
+	return compactProfiles()
+}
+
+// saveProfiles appends the profiles of player_ids to -profiles, compacting
+// it if it has grown too long. Must be called with profilesMu held.
+func saveProfiles(player_ids ...string) error {
+	if profilesPath == "" || len(player_ids) == 0 {
+		return nil
+	}
+	if profilesFile == nil {
+		return compactProfiles()
+	}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, player_id := range player_ids {
+		if profile, ok := profiles[player_id]; ok {
+			if err := enc.Encode(profile); err != nil {
+				return err
+			}
+			profilesLines++
+		}
+	}
+	if _, err := profilesFile.Write(buf.Bytes()); err != nil {
+		return err
+	}
+	if err := profilesFile.Sync(); err != nil {
+		return err
+	}
+	if profilesLines > 2*len(profiles)+profilesSlack {
+		return compactProfiles()
+	}
+	return nil
+}
+
+// compactProfiles rewrites -profiles with one line per profile and reopens
+// it for appending. Must be called with profilesMu held, or before central
+// serves.
+func compactProfiles() error {
+	list := make([]types.PlayerProfile, 0, len(profiles))
+	for _, profile := range profiles {
+		list = append(list, profile)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PlayerID < list[j].PlayerID })
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	for _, profile := range list {
+		if err := enc.Encode(profile); err != nil {
+			return err
+		}
+	}
+
+	tmp := profilesPath + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	_, err = f.Write(buf.Bytes())
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return err
+	}
+	if err := os.Rename(tmp, profilesPath); err != nil {
+		return err
+	}
+	f, err = os.OpenFile(profilesPath, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	if profilesFile != nil {
+		profilesFile.Close()
+	}
+	profilesFile, profilesLines = f, len(list)
+	return nil
+}
+
+// startSession returns player_id's profile, if they have one, and counts
+// the session they are starting.
+func startSession(player_id string) (types.PlayerProfile, bool) {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profile, ok := profiles[player_id]
+	if !ok {
+		profile = types.PlayerProfile{PlayerID: player_id}
+	}
+	profile.Sessions++
+	profile.JoinedAt = time.Now().UTC()
+	profiles[player_id] = profile
+	if err := saveProfiles(player_id); err != nil {
+		log.Printf("ERROR: Failed to save profiles: %v", err)
+	}
+	return profile, ok
+}
+
+// handleProfile serves /profile: POST saves the player of a types.Request
+// from the game server they left (CallerIP), GET ?player_id= returns a
+// profile.
+func handleProfile(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		requireAdmin(func(w http.ResponseWriter, r *http.Request) {
+			profilesMu.Lock()
+			profile, ok := profiles[r.URL.Query().Get("player_id")]
+			profilesMu.Unlock()
+			if !ok {
+				http.Error(w, "No such profile", http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(profile)
+		})(w, r)
+
+	case http.MethodPost:
+		requireServer(func(w http.ResponseWriter, r *http.Request) {
+			var req types.Request
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Player.ID == "" {
+				http.Error(w, "Missing player", http.StatusBadRequest)
+				return
+			}
+			if !slices.Contains(serversList, req.CallerIP) {
+				w.WriteHeader(http.StatusForbidden)
+				json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Unknown server " + req.CallerIP, Code: types.CodeForbidden})
+				return
+			}
+			if !saveProfile(req.Player, req.CallerIP, time.Now().UTC()) {
+				json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Profile is newer"})
+				return
+			}
+			json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Profile saved"})
+		})(w, r)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// saveProfile records player as they left server, unless the profile has a
+// later state of them.
+func saveProfile(player types.Player, server string, now time.Time) bool {
+	profilesMu.Lock()
+	defer profilesMu.Unlock()
+	profile, ok := profiles[player.ID]
+	if ok && player.UpdatedMs < profile.Player.UpdatedMs {
+		profileSavesTotal.Inc("stale")
+		return false
+	}
+	if !ok {
+		profile = types.PlayerProfile{PlayerID: player.ID}
+	}
+	if !profile.JoinedAt.IsZero() {
+		profile.PlaySeconds += int64(now.Sub(profile.JoinedAt).Seconds())
+		profile.JoinedAt = time.Time{}
+	}
+	player.ServerIP, player.ChunkID = "", types.ChunkID{}
+	player.VelX, player.VelY = 0, 0
+	profile.Player, profile.LastServer, profile.SavedAt = player, server, now
+	profiles[player.ID] = profile
+	if err := saveProfiles(player.ID); err != nil {
+		log.Printf("ERROR: Failed to save profiles: %v", err)
+	}
+	profileSavesTotal.Inc("saved")
+	return true
+}
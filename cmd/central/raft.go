@@ -0,0 +1,950 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.etcd.io/raft/v3"
+	"go.etcd.io/raft/v3/raftpb"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Raft =====================
+
+// Started with -raft-id and -raft-peers, several central servers form a Raft
+// group (go.etcd.io/raft) replicating the zone map: chunk owners, splits,
+// read replica sets, the worlds registry, and the game servers' epochs and
+// which of them are declared dead (see failover.go). Only the leader serves game servers and players;
+// followers answer 307 with the leader's URL, which Go HTTP clients follow on
+// their own, and 503 while no leader is elected. The leader changes its maps
+// at once and proposes the change; handlers answer once it is committed. A
+// leader that steps down rebuilds its maps from the committed log. Bans and
+// experiment reports stay on the node that received them.
+
+const (
+	raftTick          = 100 * time.Millisecond
+	raftCommitTimeout = 3 * time.Second
+	raftSnapshotEvery = 1000 // applied entries between snapshots
+	raftCatchUp       = 100  // entries kept after a snapshot for slow followers
+)
+
+var (
+	raftID    uint64            // this node; 0 runs central alone
+	raftPeers map[uint64]string // node ID → base URL of that central
+	raftHosts map[string]bool   // the peers' addresses, for requireRaftPeer
+	raftDir   = "raft"
+
+	raftNode    raft.Node
+	raftStorage *raft.MemoryStorage
+	raftWAL     *raftLog
+	raftClient  = &http.Client{Timeout: 2 * time.Second}
+	raftQueues  = make(map[uint64]chan raftpb.Message)
+
+	// leadership as seen by the raft loop
+	raftMu    sync.Mutex
+	raftLead  uint64
+	raftReady bool // leading and caught up with the log it inherited
+
+	// owned by the raft loop
+	raftApplied    uint64
+	raftSnapIndex  uint64
+	raftReadyIndex uint64
+	raftConfState  raftpb.ConfState
+	raftRebuild    atomic.Bool
+
+	// guarded by zoneMu: the leader tags its changes with zoneOrigin and
+	// numbers them with zoneSeq; the origin changes whenever the maps are
+	// rebuilt from the log, so stale changes are never mistaken for ours
+	zoneOrigin string
+	zoneSeq    uint64
+
+	// how far our own changes are committed
+	commitMu     sync.Mutex
+	commitOrigin string
+	committedSeq uint64
+	commitCh     = make(chan struct{})
+)
+
+var _ = metrics.NewGaugeFunc("central_raft_leader",
+	"1 on the central node leading the Raft group and serving requests.", "", func() map[string]float64 {
+		lead, ready := raftLeader()
+		if raftNode != nil && lead == raftID && ready {
+			return map[string]float64{"": 1}
+		}
+		return map[string]float64{"": 0}
+	})
+
+// ZoneCommand is one change to the replicated zone map.
+type ZoneCommand struct {
+	Origin   string        `json:"origin,omitempty"`
+	Seq      uint64        `json:"seq,omitempty"`
+	Op       string        `json:"op"` // assign, split, replicas, world, fence or rejoin
+	ChunkID  types.ChunkID `json:"chunk_id"`
+	Owner    string        `json:"owner,omitempty"`
+	Replicas []string      `json:"replicas,omitempty"`
+	Pinned   bool          `json:"pinned,omitempty"`
+	World    *types.World  `json:"world,omitempty"`
+}
+
+// ZoneState is the zone map as stored in a Raft snapshot.
+type ZoneState struct {
+	Chunks []ChunkReplicas `json:"chunks"`
+	Splits []types.ChunkID `json:"splits,omitempty"`
+	Worlds []types.World   `json:"worlds,omitempty"`
+	// Servers are the game servers that have joined, with their epochs.
+	Servers []ServerEpoch `json:"servers,omitempty"`
+}
+
+// ServerEpoch is a game server's incarnation in a ZoneState.
+type ServerEpoch struct {
+	Server string `json:"server"`
+	Epoch  uint64 `json:"epoch"`
+	Dead   bool   `json:"dead,omitempty"`
+}
+
+// RaftStatus is the body of GET /leader.
+type RaftStatus struct {
+	ID        uint64 `json:"id"`
+	Leader    uint64 `json:"leader"`
+	LeaderURL string `json:"leader_url,omitempty"`
+	Term      uint64 `json:"term"`
+	State     string `json:"state"`
+	Applied   uint64 `json:"applied"`
+}
+
+// zoneMark identifies the last change a handler may depend on.
+type zoneMark struct {
+	origin string
+	seq    uint64
+}
+
+// assignChunk makes owner the owner of chunk_id, dropping its replicas if the
+// owner changed, and tells the game servers. Must be called with zoneMu held.
+func assignChunk(chunk_id types.ChunkID, owner string) {
+	from := zone[chunk_id]
+	commitZone(ZoneCommand{Op: "assign", ChunkID: chunk_id, Owner: owner})
+	if from != "" && from != owner {
+		notifyWebhooks(eventChunkMigrated, ChunkMigratedEvent{ChunkID: chunk_id, From: from, To: owner})
+		announceOwner(chunk_id, owner)
+	}
+}
+
+// splitChunk replaces chunk_id by its sub-chunks, all owned by owner. Must be
+// called with zoneMu held.
+func splitChunk(chunk_id types.ChunkID, owner string) {
+	commitZone(ZoneCommand{Op: "split", ChunkID: chunk_id, Owner: owner})
+}
+
+// setReplicas designates chunk_id's read replicas; an empty list drops them.
+// Must be called with zoneMu held.
+func setReplicas(chunk_id types.ChunkID, list []string, pinned bool) {
+	commitZone(ZoneCommand{Op: "replicas", ChunkID: chunk_id, Replicas: list, Pinned: pinned})
+}
+
+// addWorld registers a new world. Must be called with zoneMu held.
+func addWorld(world types.World) {
+	commitZone(ZoneCommand{Op: "world", World: &world})
+	notifyWebhooks(eventWorldCreated, world)
+}
+
+// applyZone applies cmd to the zone maps. Must be called with zoneMu held.
+func applyZone(cmd ZoneCommand) {
+	switch cmd.Op {
+	case "assign":
+		if zone[cmd.ChunkID] != cmd.Owner {
+			dropReplicas(cmd.ChunkID)
+			migratedAt[cmd.ChunkID] = time.Now()
+			assignedAt[cmd.ChunkID] = time.Now()
+			recordMigration(cmd.ChunkID, zone[cmd.ChunkID], cmd.Owner)
+		}
+		zone[cmd.ChunkID] = cmd.Owner
+	case "split":
+		splits[cmd.ChunkID] = true
+		delete(zone, cmd.ChunkID)
+		delete(migratedAt, cmd.ChunkID)
+		delete(assignedAt, cmd.ChunkID)
+		dropReplicas(cmd.ChunkID)
+		for _, child := range cmd.ChunkID.Children() {
+			zone[child] = cmd.Owner
+			assignedAt[child] = time.Now()
+		}
+	case "replicas":
+		if len(cmd.Replicas) == 0 {
+			dropReplicas(cmd.ChunkID)
+			return
+		}
+		replicas[cmd.ChunkID] = cmd.Replicas
+		if cmd.Pinned {
+			replicaPinned[cmd.ChunkID] = true
+		}
+	case "world":
+		if cmd.World != nil {
+			worlds[cmd.World.ID] = *cmd.World
+		}
+	case "fence":
+		dead[cmd.Owner] = true
+	case "rejoin":
+		delete(dead, cmd.Owner)
+		epochs[cmd.Owner]++
+	default:
+		log.Printf("⚠️  Unknown zone command %q", cmd.Op)
+	}
+}
+
+// commitZone applies cmd and, in a Raft group, proposes it to the followers.
+// Must be called with zoneMu held, which keeps proposals in the order the
+// changes were applied.
+func commitZone(cmd ZoneCommand) {
+	applyZone(cmd)
+	if raftNode == nil {
+		return
+	}
+	zoneSeq++
+	cmd.Origin, cmd.Seq = zoneOrigin, zoneSeq
+	data, err := json.Marshal(cmd)
+	if err == nil {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		err = raftNode.Propose(ctx, data)
+		cancel()
+	}
+	if err != nil {
+		// our maps are now ahead of the log; start over from what it holds
+		log.Printf("⚠️  Proposing zone change %s [%d,%d] failed: %v", cmd.Op, cmd.ChunkID.IDX, cmd.ChunkID.IDY, err)
+		raftRebuild.Store(true)
+	}
+}
+
+// markZone returns the mark of the latest change. Must be called with zoneMu
+// held.
+func markZone() zoneMark {
+	return zoneMark{origin: zoneOrigin, seq: zoneSeq}
+}
+
+// waitZone waits until every change up to mark is committed.
+func waitZone(mark zoneMark) error {
+	if raftNode == nil {
+		return nil
+	}
+	deadline := time.After(raftCommitTimeout)
+	for {
+		commitMu.Lock()
+		lost := mark.origin != commitOrigin
+		done := committedSeq >= mark.seq
+		ch := commitCh
+		commitMu.Unlock()
+		switch {
+		case lost:
+			return fmt.Errorf("lost leadership before the zone change was committed")
+		case done:
+			return nil
+		}
+		select {
+		case <-ch:
+		case <-deadline:
+			return fmt.Errorf("zone change not committed within %v", raftCommitTimeout)
+		}
+	}
+}
+
+// awaitZone is waitZone for handlers: it answers 503 and returns false if the
+// change did not commit.
+func awaitZone(w http.ResponseWriter, mark zoneMark) bool {
+	if err := waitZone(mark); err != nil {
+		log.Printf("⚠️  %v", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
+
+func markCommitted(origin string, seq uint64) {
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	if origin != commitOrigin || seq <= committedSeq {
+		return
+	}
+	committedSeq = seq
+	close(commitCh)
+	commitCh = make(chan struct{})
+}
+
+// resetCommits starts tracking a new origin with nothing in flight.
+func resetCommits(origin string, seq uint64) {
+	commitMu.Lock()
+	defer commitMu.Unlock()
+	commitOrigin, committedSeq = origin, seq
+	close(commitCh)
+	commitCh = make(chan struct{})
+}
+
+func newOrigin() string {
+	return fmt.Sprintf("%d-%x", raftID, rand.Uint64())
+}
+
+// zoneState captures the zone maps. Must be called with zoneMu held.
+func zoneState() ZoneState {
+	state := ZoneState{Chunks: make([]ChunkReplicas, 0, len(zone)), Splits: splitList(), Worlds: worldList()}
+	for chunk_id, owner := range zone {
+		state.Chunks = append(state.Chunks, ChunkReplicas{ChunkID: chunk_id, Owner: owner,
+			Replicas: replicas[chunk_id], Pinned: replicaPinned[chunk_id]})
+	}
+	for server, epoch := range epochs {
+		state.Servers = append(state.Servers, ServerEpoch{Server: server, Epoch: epoch, Dead: dead[server]})
+	}
+	return state
+}
+
+// restoreZone replaces the zone maps with a snapshot. Must be called with
+// zoneMu held.
+func restoreZone(data []byte) error {
+	zone = make(map[types.ChunkID]string)
+	splits = make(map[types.ChunkID]bool)
+	replicas = make(map[types.ChunkID][]string)
+	replicaPinned = make(map[types.ChunkID]bool)
+	migratedAt = make(map[types.ChunkID]time.Time)
+	assignedAt = make(map[types.ChunkID]time.Time)
+	worlds = make(map[string]types.World)
+	epochs = make(map[string]uint64)
+	dead = make(map[string]bool)
+	if len(data) == 0 {
+		return nil
+	}
+	var state ZoneState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return err
+	}
+	for _, chunk := range state.Chunks {
+		zone[chunk.ChunkID] = chunk.Owner
+		if len(chunk.Replicas) > 0 {
+			replicas[chunk.ChunkID] = chunk.Replicas
+		}
+		if chunk.Pinned {
+			replicaPinned[chunk.ChunkID] = true
+		}
+	}
+	for _, chunk_id := range state.Splits {
+		splits[chunk_id] = true
+	}
+	for _, world := range state.Worlds {
+		worlds[world.ID] = world
+	}
+	for _, server := range state.Servers {
+		epochs[server.Server] = server.Epoch
+		if server.Dead {
+			dead[server.Server] = true
+		}
+	}
+	return nil
+}
+
+// parsePeers parses -raft-peers, "1=http://host:8080,2=http://host:8081".
+func parsePeers(s string) (map[uint64]string, error) {
+	peers := make(map[uint64]string)
+	for _, part := range strings.Split(s, ",") {
+		id, url, ok := strings.Cut(strings.TrimSpace(part), "=")
+		n, err := strconv.ParseUint(id, 10, 64)
+		if !ok || err != nil || n == 0 || url == "" {
+			return nil, fmt.Errorf("invalid raft peer %q (want id=url, id > 0)", part)
+		}
+		peers[n] = strings.TrimRight(url, "/")
+	}
+	return peers, nil
+}
+
+// startRaft starts this node, restarting from its raft log if it has one.
+func startRaft() error {
+	if _, ok := raftPeers[raftID]; !ok {
+		return fmt.Errorf("-raft-id %d is not in -raft-peers", raftID)
+	}
+	if err := os.MkdirAll(raftDir, 0o755); err != nil {
+		return err
+	}
+	raftStorage = raft.NewMemoryStorage()
+	wal, existing, err := openRaftLog(filepath.Join(raftDir, fmt.Sprintf("central-%d.raftlog", raftID)), raftStorage)
+	if err != nil {
+		return err
+	}
+	raftWAL = wal
+
+	snap, err := raftStorage.Snapshot()
+	if err != nil {
+		return err
+	}
+	zoneMu.Lock()
+	err = restoreZone(snap.Data)
+	zoneOrigin = newOrigin()
+	resetCommits(zoneOrigin, zoneSeq)
+	zoneMu.Unlock()
+	if err != nil {
+		return fmt.Errorf("restoring zone snapshot: %w", err)
+	}
+	raftApplied, raftSnapIndex = snap.Metadata.Index, snap.Metadata.Index
+	raftConfState = snap.Metadata.ConfState
+
+	cfg := &raft.Config{
+		ID:                        raftID,
+		ElectionTick:              10,
+		HeartbeatTick:             1,
+		Storage:                   raftStorage,
+		Applied:                   raftApplied,
+		MaxSizePerMsg:             1 << 20,
+		MaxInflightMsgs:           256,
+		MaxUncommittedEntriesSize: 1 << 30,
+		CheckQuorum:               true,
+		PreVote:                   true,
+		DisableProposalForwarding: true, // only the leader writes
+		Logger:                    &raft.DefaultLogger{Logger: log.New(os.Stderr, "raft: ", log.LstdFlags)},
+	}
+	if existing {
+		raftNode = raft.RestartNode(cfg)
+	} else {
+		// every node must bootstrap the same log, so list peers in ID order
+		peers := make([]raft.Peer, 0, len(raftPeers))
+		for id := range raftPeers {
+			peers = append(peers, raft.Peer{ID: id})
+		}
+		sort.Slice(peers, func(i, j int) bool { return peers[i].ID < peers[j].ID })
+		raftNode = raft.StartNode(cfg, peers)
+	}
+
+	for id, url := range raftPeers {
+		if id == raftID {
+			continue
+		}
+		queue := make(chan raftpb.Message, 1024)
+		raftQueues[id] = queue
+		go sendRaftLoop(id, url, queue)
+	}
+	go raftLoop()
+	log.Printf("🗳️  Raft node %d started with peers %v (restarted: %v)", raftID, raftPeers, existing)
+	return nil
+}
+
+func raftLoop() {
+	ticker := time.NewTicker(raftTick)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			raftNode.Tick()
+			if raftRebuild.CompareAndSwap(true, false) {
+				rebuildZone()
+			}
+		case rd := <-raftNode.Ready():
+			handleReady(rd)
+		}
+	}
+}
+
+// handleReady persists, sends and applies one batch of raft output.
+func handleReady(rd raft.Ready) {
+	if err := raftWAL.save(rd.HardState, rd.Entries, rd.Snapshot, rd.MustSync); err != nil {
+		log.Fatalf("❌ Writing the raft log failed: %v", err)
+	}
+	if !raft.IsEmptySnap(rd.Snapshot) {
+		if err := raftStorage.ApplySnapshot(rd.Snapshot); err != nil {
+			log.Printf("⚠️  Applying raft snapshot failed: %v", err)
+		}
+	}
+	raftStorage.Append(rd.Entries)
+	if !raft.IsEmptyHardState(rd.HardState) {
+		raftStorage.SetHardState(rd.HardState)
+	}
+
+	if rd.SoftState != nil {
+		leading := rd.SoftState.RaftState == raft.StateLeader
+		raftMu.Lock()
+		wasLeading := raftLead == raftID
+		raftLead = rd.SoftState.Lead
+		raftReady = false
+		raftMu.Unlock()
+		switch {
+		case leading && !wasLeading:
+			// serve once everything logged before our term is applied
+			raftReadyIndex, _ = raftStorage.LastIndex()
+			log.Printf("👑 Central node %d is the Raft leader", raftID)
+		case wasLeading && !leading:
+			log.Printf("🗳️  Central node %d stepped down (leader now %d)", raftID, rd.SoftState.Lead)
+			rebuildZone()
+		}
+	}
+
+	sendRaft(rd.Messages)
+
+	if !raft.IsEmptySnap(rd.Snapshot) {
+		zoneMu.Lock()
+		err := restoreZone(rd.Snapshot.Data)
+		zoneMu.Unlock()
+		if err != nil {
+			log.Printf("⚠️  Restoring zone snapshot failed: %v", err)
+		}
+		raftApplied, raftSnapIndex = rd.Snapshot.Metadata.Index, rd.Snapshot.Metadata.Index
+		raftConfState = rd.Snapshot.Metadata.ConfState
+	}
+	applyEntries(rd.CommittedEntries)
+
+	raftMu.Lock()
+	if raftLead == raftID && !raftReady && raftApplied >= raftReadyIndex {
+		raftReady = true
+		log.Printf("👑 Central node %d caught up, serving requests", raftID)
+	}
+	raftMu.Unlock()
+
+	maybeSnapshot()
+	raftNode.Advance()
+}
+
+// applyEntries applies committed entries, skipping our own changes, which
+// the maps already hold.
+func applyEntries(ents []raftpb.Entry) {
+	var own uint64
+	zoneMu.Lock()
+	for _, ent := range ents {
+		if ent.Index <= raftApplied {
+			continue
+		}
+		switch ent.Type {
+		case raftpb.EntryNormal:
+			if len(ent.Data) == 0 {
+				break // a new leader's empty entry
+			}
+			var cmd ZoneCommand
+			if err := json.Unmarshal(ent.Data, &cmd); err != nil {
+				log.Printf("⚠️  Skipping undecodable raft entry %d: %v", ent.Index, err)
+				break
+			}
+			if cmd.Origin == zoneOrigin {
+				own = cmd.Seq
+			} else {
+				applyZone(cmd)
+			}
+		case raftpb.EntryConfChange:
+			var cc raftpb.ConfChange
+			if err := cc.Unmarshal(ent.Data); err != nil {
+				log.Printf("⚠️  Skipping undecodable conf change %d: %v", ent.Index, err)
+				break
+			}
+			raftConfState = *raftNode.ApplyConfChange(cc)
+		}
+		raftApplied = ent.Index
+	}
+	origin := zoneOrigin
+	zoneMu.Unlock()
+	if own > 0 {
+		markCommitted(origin, own)
+	}
+}
+
+// rebuildZone resets the zone maps to what the log has committed, dropping
+// changes this node made as leader that never got committed. Runs on the
+// raft loop.
+func rebuildZone() {
+	snap, err := raftStorage.Snapshot()
+	if err != nil {
+		log.Printf("⚠️  Rebuilding zone map failed: %v", err)
+		return
+	}
+	var ents []raftpb.Entry
+	if raftApplied > snap.Metadata.Index {
+		ents, err = raftStorage.Entries(snap.Metadata.Index+1, raftApplied+1, math.MaxUint64)
+		if err != nil {
+			log.Printf("⚠️  Rebuilding zone map failed: %v", err)
+			return
+		}
+	}
+
+	zoneMu.Lock()
+	if err := restoreZone(snap.Data); err != nil {
+		log.Printf("⚠️  Restoring zone snapshot failed: %v", err)
+	}
+	for _, ent := range ents {
+		var cmd ZoneCommand
+		if ent.Type == raftpb.EntryNormal && len(ent.Data) > 0 && json.Unmarshal(ent.Data, &cmd) == nil {
+			applyZone(cmd)
+		}
+	}
+	zoneOrigin = newOrigin()
+	resetCommits(zoneOrigin, zoneSeq)
+	zoneMu.Unlock()
+
+	raftMu.Lock()
+	raftReady = false
+	raftMu.Unlock()
+	raftReadyIndex, _ = raftStorage.LastIndex()
+	log.Printf("🔁 Rebuilt zone map from the raft log up to entry %d", raftApplied)
+}
+
+// maybeSnapshot compacts the raft log every raftSnapshotEvery entries. The
+// leader waits until none of its changes are in flight, so the maps match
+// the applied index.
+func maybeSnapshot() {
+	if raftApplied-raftSnapIndex < raftSnapshotEvery {
+		return
+	}
+	zoneMu.Lock()
+	mark := markZone()
+	commitMu.Lock()
+	settled := mark.origin == commitOrigin && committedSeq >= mark.seq
+	commitMu.Unlock()
+	var data []byte
+	var err error
+	if settled {
+		data, err = json.Marshal(zoneState())
+	}
+	zoneMu.Unlock()
+	if !settled || err != nil {
+		return
+	}
+
+	if _, err := raftStorage.CreateSnapshot(raftApplied, &raftConfState, data); err != nil {
+		log.Printf("⚠️  Raft snapshot failed: %v", err)
+		return
+	}
+	raftSnapIndex = raftApplied
+	if raftApplied > raftCatchUp {
+		if err := raftStorage.Compact(raftApplied - raftCatchUp); err != nil && err != raft.ErrCompacted {
+			log.Printf("⚠️  Raft log compaction failed: %v", err)
+		}
+	}
+	if err := raftWAL.rewrite(raftStorage); err != nil {
+		log.Printf("⚠️  Rewriting the raft log failed: %v", err)
+	}
+}
+
+// ===================== Raft transport =====================
+
+// Raft messages travel as protobuf over POST /raft, one queue per peer so a
+// slow peer does not hold up the others. /raft only exists on a node started
+// with -raft-id, and only takes messages from the other nodes: those
+// carrying the server token given -seal-secret (which all central nodes
+// then share), or else those from a peer's host.
+
+func sendRaft(msgs []raftpb.Message) {
+	for _, m := range msgs {
+		queue, ok := raftQueues[m.To]
+		if !ok {
+			continue
+		}
+		select {
+		case queue <- m:
+		default:
+			raftNode.ReportUnreachable(m.To)
+		}
+	}
+}
+
+func sendRaftLoop(id uint64, url string, queue chan raftpb.Message) {
+	for m := range queue {
+		data, err := m.Marshal()
+		if err == nil {
+			var res *http.Response
+			var req *http.Request
+			req, err = http.NewRequest(http.MethodPost, url+"/raft", bytes.NewReader(data))
+			if err == nil {
+				req.Header.Set("Content-Type", "application/x-protobuf")
+				if serverToken != "" {
+					req.Header.Set(netproto.ServerTokenHeader, serverToken)
+				}
+				res, err = raftClient.Do(req)
+			}
+			if err == nil {
+				io.Copy(io.Discard, res.Body)
+				res.Body.Close()
+				if res.StatusCode >= 300 {
+					err = fmt.Errorf("%s", res.Status)
+				}
+			}
+		}
+		if err != nil {
+			raftNode.ReportUnreachable(id)
+		}
+		if m.Type == raftpb.MsgSnap {
+			status := raft.SnapshotFinish
+			if err != nil {
+				status = raft.SnapshotFailure
+			}
+			raftNode.ReportSnapshot(id, status)
+		}
+	}
+}
+
+// requireRaftPeer lets only the other nodes of the Raft group through to
+// next.
+func requireRaftPeer(next http.HandlerFunc) http.HandlerFunc {
+	return netproto.RequireServer(serverToken, func(host string) bool { return raftHosts[host] }, next)
+}
+
+// raftPeerHosts resolves the hosts of the peers' URLs to their addresses.
+func raftPeerHosts(peers map[uint64]string) (map[string]bool, error) {
+	hosts := make(map[string]bool)
+	for _, peer := range peers {
+		u, err := url.Parse(peer)
+		if err != nil {
+			return nil, fmt.Errorf("invalid raft peer URL %q: %v", peer, err)
+		}
+		addrs, err := net.LookupHost(u.Hostname())
+		if err != nil {
+			return nil, fmt.Errorf("resolving raft peer %s: %v", peer, err)
+		}
+		for _, addr := range addrs {
+			hosts[addr] = true
+		}
+	}
+	return hosts, nil
+}
+
+func handleRaft(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	data, err := io.ReadAll(r.Body)
+	var m raftpb.Message
+	if err == nil {
+		err = m.Unmarshal(data)
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := raftNode.Step(r.Context(), m); err != nil {
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// raftLeader returns the current leader and whether it is ready to serve,
+// as far as this node knows.
+func raftLeader() (uint64, bool) {
+	raftMu.Lock()
+	defer raftMu.Unlock()
+	return raftLead, raftReady
+}
+
+// leaderOnly passes requests to next on the leader and sends them to the
+// leader elsewhere.
+func leaderOnly(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if raftNode == nil {
+			next(w, r)
+			return
+		}
+		lead, ready := raftLeader()
+		switch {
+		case lead == raftID && ready:
+			next(w, r)
+		case lead != raft.None && lead != raftID:
+			w.Header().Set("X-Central-Leader", raftPeers[lead])
+			http.Redirect(w, r, raftPeers[lead]+r.URL.RequestURI(), http.StatusTemporaryRedirect)
+		default:
+			http.Error(w, "No central leader yet", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+func handleLeader(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if raftNode == nil {
+		json.NewEncoder(w).Encode(RaftStatus{State: "standalone"})
+		return
+	}
+	st := raftNode.Status()
+	json.NewEncoder(w).Encode(RaftStatus{ID: raftID, Leader: st.Lead, LeaderURL: raftPeers[st.Lead],
+		Term: st.Term, State: st.RaftState.String(), Applied: st.Applied})
+}
+
+// ===================== Raft log =====================
+
+// raftLog persists a node's raft state as JSON lines, each holding a
+// snapshot, hard state and entries in their protobuf encoding. Replaying
+// the lines in order into a MemoryStorage restores it; rewrite starts a
+// fresh file after a snapshot.
+type raftLog struct {
+	path string
+	f    *os.File
+}
+
+type raftRecord struct {
+	Snapshot  []byte   `json:"snapshot,omitempty"`
+	HardState []byte   `json:"hard_state,omitempty"`
+	Entries   [][]byte `json:"entries,omitempty"`
+}
+
+// openRaftLog replays path into storage and reports whether it held any
+// state. A torn last line is cut off.
+func openRaftLog(path string, storage *raft.MemoryStorage) (*raftLog, bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, false, err
+	}
+	existing := false
+	good := 0
+	for good < len(data) {
+		end := bytes.IndexByte(data[good:], '\n')
+		if end < 0 {
+			break
+		}
+		var rec raftRecord
+		if err := json.Unmarshal(data[good:good+end], &rec); err != nil {
+			break
+		}
+		if err := rec.replay(storage); err != nil {
+			return nil, false, fmt.Errorf("replaying %s: %w", path, err)
+		}
+		existing = true
+		good += end + 1
+	}
+	if good < len(data) {
+		log.Printf("⚠️  Cutting %d torn bytes off %s", len(data)-good, path)
+		if err := os.Truncate(path, int64(good)); err != nil {
+			return nil, false, err
+		}
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, false, err
+	}
+	return &raftLog{path: path, f: f}, existing, nil
+}
+
+func (rec raftRecord) replay(storage *raft.MemoryStorage) error {
+	if rec.Snapshot != nil {
+		var snap raftpb.Snapshot
+		if err := snap.Unmarshal(rec.Snapshot); err != nil {
+			return err
+		}
+		if err := storage.ApplySnapshot(snap); err != nil && err != raft.ErrSnapOutOfDate {
+			return err
+		}
+	}
+	if rec.HardState != nil {
+		var hs raftpb.HardState
+		if err := hs.Unmarshal(rec.HardState); err != nil {
+			return err
+		}
+		storage.SetHardState(hs)
+	}
+	if len(rec.Entries) > 0 {
+		ents := make([]raftpb.Entry, len(rec.Entries))
+		for i, b := range rec.Entries {
+			if err := ents[i].Unmarshal(b); err != nil {
+				return err
+			}
+		}
+		return storage.Append(ents)
+	}
+	return nil
+}
+
+func newRaftRecord(hs raftpb.HardState, ents []raftpb.Entry, snap raftpb.Snapshot) (raftRecord, error) {
+	var rec raftRecord
+	var err error
+	if !raft.IsEmptySnap(snap) {
+		if rec.Snapshot, err = snap.Marshal(); err != nil {
+			return rec, err
+		}
+	}
+	if !raft.IsEmptyHardState(hs) {
+		if rec.HardState, err = hs.Marshal(); err != nil {
+			return rec, err
+		}
+	}
+	for _, ent := range ents {
+		b, err := ent.Marshal()
+		if err != nil {
+			return rec, err
+		}
+		rec.Entries = append(rec.Entries, b)
+	}
+	return rec, nil
+}
+
+func (l *raftLog) save(hs raftpb.HardState, ents []raftpb.Entry, snap raftpb.Snapshot, sync bool) error {
+	rec, err := newRaftRecord(hs, ents, snap)
+	if err != nil || rec.Snapshot == nil && rec.HardState == nil && rec.Entries == nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if _, err := l.f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	if sync {
+		return l.f.Sync()
+	}
+	return nil
+}
+
+// rewrite replaces the file with storage's snapshot, hard state and
+// remaining entries.
+func (l *raftLog) rewrite(storage *raft.MemoryStorage) error {
+	snap, err := storage.Snapshot()
+	if err != nil {
+		return err
+	}
+	hs, _, err := storage.InitialState()
+	if err != nil {
+		return err
+	}
+	first, _ := storage.FirstIndex()
+	last, _ := storage.LastIndex()
+	var ents []raftpb.Entry
+	if last >= first {
+		if ents, err = storage.Entries(first, last+1, math.MaxUint64); err != nil {
+			return err
+		}
+	}
+	rec, err := newRaftRecord(hs, ents, snap)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	tmp := l.path + ".tmp"
+	if err := os.WriteFile(tmp, append(line, '\n'), 0o644); err != nil {
+		return err
+	}
+	if f, err := os.Open(tmp); err == nil {
+		f.Sync()
+		f.Close()
+	}
+	if err := os.Rename(tmp, l.path); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return err
+	}
+	l.f.Close()
+	l.f = f
+	return nil
+}
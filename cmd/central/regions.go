@@ -0,0 +1,87 @@
+package main
+
+import (
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Regions =====================
+
+// Each world is tiled into regions of regionSize x regionSize chunks, and the
+// live server owning most of a region's chunks owns the region. A new chunk
+// in someone's region goes to that server (ADOPT_CHUNK) rather than to the
+// caller, and a chunk in its owner's region only migrates to a server with
+// more than regionHysteresis more players in it than the owner (see
+// migrationLead). Players
+// walking in a line then change servers at region borders, not at every
+// chunk border. Sub-chunks belong to the region of their whole chunk.
+
+var (
+	regionSize       = 8 // chunks per region edge (0 assigns chunks one by one)
+	regionHysteresis = 4
+)
+
+type Region struct {
+	World string
+	X, Y  int
+}
+
+var regionDecisionsTotal = metrics.NewCounterVec("central_region_decisions_total",
+	"Chunk assignments decided by region: new chunks given to the region owner (adopt).", "decision")
+
+func regionOf(chunk_id types.ChunkID) Region {
+	x, y := chunk_id.IDX>>chunk_id.Level, chunk_id.IDY>>chunk_id.Level
+	return Region{World: chunk_id.World, X: floorDiv(x, regionSize), Y: floorDiv(y, regionSize)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// regionOwner returns the live server owning most chunks of chunk_id's
+// region, by address on ties. Must be called with zoneMu held.
+func regionOwner(chunk_id types.ChunkID) (string, bool) {
+	if regionSize <= 0 {
+		return "", false
+	}
+	region := regionOf(chunk_id)
+	counts := make(map[string]int)
+	for id, owner := range zone {
+		if !dead[owner] && regionOf(id) == region {
+			counts[owner]++
+		}
+	}
+	best := ""
+	for server, count := range counts {
+		if best == "" || count > counts[best] || count == counts[best] && server < best {
+			best = server
+		}
+	}
+	return best, best != ""
+}
+
+// adoptInRegion has the owner of chunk_id's region take the new chunk
+// instead of caller. Must be called with zoneMu held.
+func adoptInRegion(chunk_id types.ChunkID, caller, trace string) (string, bool) {
+	owner, ok := regionOwner(chunk_id)
+	if !ok || owner == caller {
+		return "", false
+	}
+	req := types.Request{Type: types.ReqAdoptChunk, ChunkID: chunk_id, Reason: "new chunk in its region", Epoch: epochs[owner], TraceID: trace}
+	res, err := netproto.RoundTrip(network, owner, req, 2*time.Second)
+	if err != nil || !res.Success {
+		netproto.Tracef(trace, "⚠️  ADOPT_CHUNK [%d,%d] on region owner %s failed: %v %s", chunk_id.IDX, chunk_id.IDY, owner, err, res.Message)
+		return "", false
+	}
+	assignChunk(chunk_id, owner)
+	regionDecisionsTotal.Inc("adopt")
+	netproto.Tracef(trace, "🗺️  New chunk [%d,%d] goes to region owner %s", chunk_id.IDX, chunk_id.IDY, owner)
+	return owner, true
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Read replicas =====================
+
+// Chunks whose owner reports at least replicaThreshold players get
+// replicaCount read replicas, picked among the least loaded other servers.
+// The designation is lifted when the chunk drops below half the threshold,
+// stops being reported, or changes owner. Every owned chunk of a quorum
+// world (see types.ConsistencyQuorum) has replicaCount replicas whatever its
+// load, replaced when one of them dies, since its edits are not
+// acknowledged without one. Admins can pin a replica set with POST
+// /replicas; pinned sets are left alone until cleared.
+var (
+	replicaThreshold = 50
+	replicaCount     = 1
+
+	// guarded by zoneMu, like zone
+	replicas      = make(map[types.ChunkID][]string)
+	replicaPinned = make(map[types.ChunkID]bool)
+)
+
+// ChunkReplicas is one entry of GET /replicas and the body of POST /replicas.
+type ChunkReplicas struct {
+	ChunkID  types.ChunkID `json:"chunk_id"`
+	Owner    string        `json:"owner,omitempty"`
+	Replicas []string      `json:"replicas"`
+	Pinned   bool          `json:"pinned,omitempty"`
+}
+
+// dropReplicas forgets a chunk's replicas when it changes owner; the old
+// owner stops streaming on its own. Must be called with zoneMu held.
+func dropReplicas(chunk_id types.ChunkID) {
+	delete(replicas, chunk_id)
+	delete(replicaPinned, chunk_id)
+}
+
+// pickReplicas returns up to replicaCount live servers other than owner,
+// least loaded first by their latest report. Must be called with zoneMu held.
+func pickReplicas(owner string) []string {
+	worldReportsMu.Lock()
+	load := make(map[string]int, len(worldReports))
+	for server, report := range worldReports {
+		load[server] = report.Players
+	}
+	worldReportsMu.Unlock()
+
+	var candidates []string
+	for _, server := range serversList {
+		if server != owner && !dead[server] {
+			candidates = append(candidates, server)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool { return load[candidates[i]] < load[candidates[j]] })
+	if len(candidates) > replicaCount {
+		candidates = candidates[:replicaCount]
+	}
+	return candidates
+}
+
+// anyDead reports whether any of servers is declared dead. Must be called
+// with zoneMu held.
+func anyDead(servers []string) bool {
+	for _, server := range servers {
+		if dead[server] {
+			return true
+		}
+	}
+	return false
+}
+
+// planReplicas updates the replica sets of the chunks a server owns from its
+// report and tells it about the changes.
+func planReplicas(report types.WorldMetrics) {
+	changed := make(map[types.ChunkID][]string)
+	reported := make(map[types.ChunkID]bool, len(report.HotChunks))
+
+	zoneMu.Lock()
+	if len(strictWorlds()) > 0 {
+		for chunk_id, owner := range zone {
+			if owner != report.ServerIP || replicaPinned[chunk_id] || worldConsistency(chunk_id.World) != types.ConsistencyQuorum {
+				continue
+			}
+			if list, has := replicas[chunk_id]; has && !anyDead(list) {
+				continue
+			}
+			if picked := pickReplicas(report.ServerIP); len(picked) > 0 {
+				setReplicas(chunk_id, picked, false)
+				changed[chunk_id] = picked
+			}
+		}
+	}
+	for _, load := range report.HotChunks {
+		chunk_id := load.ChunkID
+		reported[chunk_id] = true
+		if replicaThreshold <= 0 || zone[chunk_id] != report.ServerIP || replicaPinned[chunk_id] || worldConsistency(chunk_id.World) == types.ConsistencyQuorum {
+			continue
+		}
+		_, has := replicas[chunk_id]
+		switch {
+		case !has && load.Players >= replicaThreshold:
+			if picked := pickReplicas(report.ServerIP); len(picked) > 0 {
+				setReplicas(chunk_id, picked, false)
+				changed[chunk_id] = picked
+			}
+		case has && load.Players < replicaThreshold/2:
+			setReplicas(chunk_id, nil, false)
+			changed[chunk_id] = nil
+		}
+	}
+	for chunk_id := range replicas {
+		if zone[chunk_id] == report.ServerIP && !reported[chunk_id] && !replicaPinned[chunk_id] && worldConsistency(chunk_id.World) != types.ConsistencyQuorum {
+			setReplicas(chunk_id, nil, false)
+			changed[chunk_id] = nil
+		}
+	}
+	mark := markZone()
+	zoneMu.Unlock()
+	if err := waitZone(mark); err != nil {
+		log.Printf("⚠️  Replica changes for %s not committed: %v", report.ServerIP, err)
+		return
+	}
+
+	for chunk_id, list := range changed {
+		go sendReplicas(report.ServerIP, chunk_id, list, netproto.NewTraceID())
+	}
+}
+
+// sendReplicas tells owner which replicas to stream chunk_id to.
+func sendReplicas(owner string, chunk_id types.ChunkID, list []string, trace string) error {
+	req := types.Request{Type: types.ReqSetReplicas, ChunkID: chunk_id, Replicas: list, TraceID: trace}
+	res, err := netproto.RoundTrip(network, owner, req, 2*time.Second)
+	if err == nil && !res.Success {
+		err = fmt.Errorf("%s: %s", res.Code, res.Message)
+	}
+	if err != nil {
+		netproto.Tracef(trace, "⚠️  SET_REPLICAS [%d,%d] on %s failed: %v", chunk_id.IDX, chunk_id.IDY, owner, err)
+		return err
+	}
+	log.Printf("🪞 Chunk [%d,%d] on %s now has replicas %v", chunk_id.IDX, chunk_id.IDY, owner, list)
+	return nil
+}
+
+// handleReplicas serves GET (list) and POST (pin a replica set; an empty
+// list clears it and returns the chunk to automatic placement).
+func handleReplicas(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		zoneMu.Lock()
+		list := make([]ChunkReplicas, 0, len(replicas))
+		for chunk_id, servers := range replicas {
+			list = append(list, ChunkReplicas{ChunkID: chunk_id, Owner: zone[chunk_id], Replicas: servers, Pinned: replicaPinned[chunk_id]})
+		}
+		zoneMu.Unlock()
+		sort.Slice(list, func(i, j int) bool {
+			if list[i].ChunkID.IDX != list[j].ChunkID.IDX {
+				return list[i].ChunkID.IDX < list[j].ChunkID.IDX
+			}
+			return list[i].ChunkID.IDY < list[j].ChunkID.IDY
+		})
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var req ChunkReplicas
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if err := req.ChunkID.Validate(); err != nil {
+			http.Error(w, "Invalid chunk_id: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		zoneMu.Lock()
+		owner, ok := zone[req.ChunkID]
+		if ok {
+			for _, server := range req.Replicas {
+				if server == owner {
+					ok = false
+				}
+			}
+		}
+		if !ok {
+			zoneMu.Unlock()
+			http.Error(w, "Chunk has no owner, or the owner is listed as a replica", http.StatusConflict)
+			return
+		}
+		setReplicas(req.ChunkID, req.Replicas, true)
+		mark := markZone()
+		zoneMu.Unlock()
+		if !awaitZone(w, mark) {
+			return
+		}
+
+		if err := sendReplicas(owner, req.ChunkID, req.Replicas, netproto.NewTraceID()); err != nil {
+			http.Error(w, "Owner did not accept the replicas: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		json.NewEncoder(w).Encode(types.Response{Success: true, Message: owner, Replicas: req.Replicas})
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Sessions =====================
+
+// /join hands every player a session token. A client that restarts sends it
+// back to /join (session_token) to resume its player instead of joining as
+// a new one: central asks every live game server with LOCATE_PLAYER, the
+// one still holding the player arms the token and answers with their state,
+// and the client is redirected there with that state to send RESUME. A
+// token that is unknown, expired or wrong gets a fresh session, as does a
+// good one whose player no server holds any more. Sessions are kept in the
+// memory of the central node that issued them; after a restart or a leader
+// change clients just join afresh.
+
+// sessionTTL is how long a session can be resumed after its last /join.
+var sessionTTL = 24 * time.Hour
+
+type Session struct {
+	Token  string
+	Joined time.Time
+}
+
+var (
+	sessionsMu sync.Mutex
+	sessions   = make(map[string]Session)
+)
+
+// sealKeys issues players' seals at /join; nil without -seal-secret, and
+// then they play unsealed.
+var sealKeys *netproto.SealKeys
+
+// issueSeal gives res a seal if the cluster seals, whether or not the
+// player asked for one: its game servers drop anything unsealed.
+func issueSeal(req types.PlayerJoinRequest, res *types.Response) {
+	if sealKeys == nil {
+		return
+	}
+	seal, err := sealKeys.Issue()
+	if err != nil {
+		return
+	}
+	res.Seal = &seal
+}
+
+var sessionsTotal = metrics.NewCounterVec("central_sessions_total",
+	"Joins by session outcome: new, resumed, or lost (valid token, player gone).", "result")
+
+// newSession issues player_id a fresh token, replacing any earlier one.
+func newSession(player_id string) string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	token := hex.EncodeToString(b)
+
+	sessionsMu.Lock()
+	sessions[player_id] = Session{Token: token, Joined: time.Now()}
+	sessionsMu.Unlock()
+	return token
+}
+
+// validSession reports whether token is player_id's live session, and
+// renews it if so.
+func validSession(player_id, token string) bool {
+	sessionsMu.Lock()
+	defer sessionsMu.Unlock()
+
+	now := time.Now()
+	for id, session := range sessions {
+		if now.Sub(session.Joined) > sessionTTL {
+			delete(sessions, id)
+		}
+	}
+	session, ok := sessions[player_id]
+	if !ok || subtle.ConstantTimeCompare([]byte(session.Token), []byte(token)) != 1 {
+		return false
+	}
+	session.Joined = now
+	sessions[player_id] = session
+	return true
+}
+
+// locatePlayer asks the live game servers which one holds player_id, arming
+// token there if set, and returns it with the player's state.
+func locatePlayer(player_id, token string) (types.Player, string, bool) {
+	zoneMu.Lock()
+	var live []string
+	for _, server := range serversList {
+		if !dead[server] {
+			live = append(live, server)
+		}
+	}
+	zoneMu.Unlock()
+
+	req := types.Request{Type: types.ReqLocatePlayer, PlayerID: player_id, SessionToken: token, TraceID: netproto.NewTraceID()}
+	type found struct {
+		player types.Player
+		server string
+	}
+	results := make(chan found, len(live))
+	var wg sync.WaitGroup
+	for _, server := range live {
+		wg.Add(1)
+		go func(server string) {
+			defer wg.Done()
+			res, err := netproto.RoundTrip(network, server, req, 2*time.Second)
+			if err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  LOCATE_PLAYER %s on %s failed: %v", player_id, server, err)
+				return
+			}
+			if res.Success && res.Player != nil {
+				results <- found{*res.Player, server}
+			}
+		}(server)
+	}
+	wg.Wait()
+	close(results)
+
+	// a player caught mid-transfer may be on two servers; either will do
+	for result := range results {
+		return result.player, result.server, true
+	}
+	return types.Player{}, "", false
+}
+
+// handleLocate tells a game server which server holds a player, for
+// relaying a WHISPER when it knows no gossip members to ask.
+func handleLocate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req types.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.PlayerID == "" {
+		http.Error(w, "Missing player_id", http.StatusBadRequest)
+		return
+	}
+
+	player, server, ok := locatePlayer(req.PlayerID, "")
+	if !ok {
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Player is not online", Code: types.CodeNotFound})
+		return
+	}
+	json.NewEncoder(w).Encode(types.Response{Success: true, Message: "Player located", RedirectIP: server, Player: &player})
+}
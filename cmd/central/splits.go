@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk splits =====================
+
+// An owner asks to split an overcrowded chunk at /split. The split chunk
+// leaves the zone map and its four sub-chunks (types.ChunkID.Children) are
+// assigned to the same owner; the other game servers hear about it with
+// SPLIT_CHUNK. A /chunk lookup of a split chunk answers ERR_CHUNK_SPLIT with
+// the split list so the caller can resolve the position again.
+
+// guarded by zoneMu, like zone
+var splits = make(map[types.ChunkID]bool)
+
+var splitsTotal = metrics.NewCounterVec("central_chunk_splits_total",
+	"Chunks split into sub-chunks.", "")
+
+// splitList returns every split chunk, sorted. Must be called with zoneMu
+// held.
+func splitList() []types.ChunkID {
+	if len(splits) == 0 {
+		return nil
+	}
+	list := make([]types.ChunkID, 0, len(splits))
+	for chunk_id := range splits {
+		list = append(list, chunk_id)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.IDX != b.IDX {
+			return a.IDX < b.IDX
+		}
+		return a.IDY < b.IDY
+	})
+	return list
+}
+
+func handleSplit(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	var req types.Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	chunk_id := req.ChunkID
+	if err := chunk_id.Validate(); err != nil {
+		http.Error(w, "Invalid chunk_id: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	zoneMu.Lock()
+	owner, ok := zone[chunk_id]
+	var res types.Response
+	switch {
+	case !ok || owner != req.CallerIP:
+		res = types.Response{Success: false, Message: "Chunk not owned by caller", Code: types.CodeNotOwner, RedirectIP: owner}
+	case !chunk_id.CanSplit(chunkSizeFor(owner)):
+		res = types.Response{Success: false, Message: "Chunk cannot be split further", Code: types.CodeBadRequest}
+	default:
+		splitChunk(chunk_id, owner)
+		splitsTotal.Inc("")
+		res = types.Response{Success: true, Message: owner, Splits: splitList()}
+	}
+	mark := markZone()
+	zoneMu.Unlock()
+	if !awaitZone(w, mark) {
+		return
+	}
+	res.TraceID = req.TraceID
+	json.NewEncoder(w).Encode(res)
+	if !res.Success {
+		return
+	}
+
+	log.Printf("🔪 Chunk [%d,%d] (level %d) on %s split (%d players)", chunk_id.IDX, chunk_id.IDY, chunk_id.Level, owner, req.PlayerCount)
+	announce := types.Request{Type: types.ReqSplitChunk, ChunkID: chunk_id, TraceID: req.TraceID}
+	for _, server := range serversList {
+		if server == owner {
+			continue // the owner splits as soon as we answer
+		}
+		go func(server string) {
+			if _, err := netproto.RoundTrip(network, server, announce, 2*time.Second); err != nil {
+				netproto.Tracef(req.TraceID, "⚠️  SPLIT_CHUNK to %s failed: %v", server, err)
+			}
+		}(server)
+	}
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Webhooks =====================
+
+// Operators register webhooks with POST /webhooks (admin) to hear about the
+// cluster as it changes: game servers joining, dying and recovering, chunks
+// changing owner, worlds being created. Each event is POSTed to every
+// webhook that wants it as a WebhookEvent; a webhook with a secret gets the
+// body's HMAC-SHA256 in X-Webhook-Signature ("sha256=" and hex). A delivery
+// that fails is retried with backoff, keeping the webhook's events in order,
+// up to webhookAttempts; the event ID stays the same, so a receiver can drop
+// repeats. Events a webhook falls webhookQueue behind on are dropped.
+//
+// Only the leader sees the changes, so only the leader sends. Like bans,
+// registrations stay on the node that received them, persisted to the
+// -webhooks file.
+
+// The events a webhook can ask for.
+const (
+	eventServerJoined    = "server-joined"    // first report this node has had from a game server
+	eventServerDead      = "server-dead"      // a game server declared dead, its chunks failed over
+	eventServerRecovered = "server-recovered" // a dead game server reporting again
+	eventChunkMigrated   = "chunk-migrated"   // a chunk assigned to a different game server
+	eventWorldCreated    = "world-created"    // a world created by an operator or for a match
+)
+
+var webhookEvents = []string{eventServerJoined, eventServerDead, eventServerRecovered, eventChunkMigrated, eventWorldCreated}
+
+const (
+	webhookQueue    = 256
+	webhookAttempts = 5
+	webhookBackoff  = time.Second // doubling after each failed attempt
+	webhookTimeout  = 5 * time.Second
+)
+
+// Webhook is one registration. Events empty means every event.
+type Webhook struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events,omitempty"`
+	Secret    string   `json:"secret,omitempty"`
+	CreatedMs int64    `json:"created_ms"`
+}
+
+// WebhookEvent is the body of a delivery.
+type WebhookEvent struct {
+	ID     string `json:"id"`
+	Event  string `json:"event"`
+	TimeMs int64  `json:"time_ms"`
+	Data   any    `json:"data"`
+}
+
+// The data of each event.
+type (
+	ServerEvent struct {
+		Server string `json:"server"`
+		World  string `json:"world,omitempty"`
+		// server-dead: chunks it owned, and how many found a new owner
+		Chunks     int `json:"chunks,omitempty"`
+		FailedOver int `json:"failed_over,omitempty"`
+	}
+	ChunkMigratedEvent struct {
+		ChunkID types.ChunkID `json:"chunk_id"`
+		From    string        `json:"from"`
+		To      string        `json:"to"`
+	}
+)
+
+// webhookSender delivers one webhook's events in order.
+type webhookSender struct {
+	hook  Webhook
+	queue chan WebhookEvent
+	done  chan struct{}
+}
+
+var (
+	webhooks     = make(map[string]*webhookSender)
+	webhooksMu   sync.Mutex
+	webhooksPath string
+	webhookHTTP  = &http.Client{Timeout: webhookTimeout}
+)
+
+var webhookDeliveriesTotal = metrics.NewCounterVec("central_webhook_deliveries_total",
+	"Webhook events by outcome: delivered, retried (a failed attempt), failed (out of attempts) or dropped (queue full).", "result")
+
+// notifyWebhooks queues event for every webhook that wants it. It does not
+// block, and may be called with zoneMu held.
+func notifyWebhooks(event string, data any) {
+	ev := WebhookEvent{ID: netproto.NewTraceID(), Event: event, TimeMs: time.Now().UnixMilli(), Data: data}
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+	for _, s := range webhooks {
+		if !s.hook.wants(event) {
+			continue
+		}
+		select {
+		case s.queue <- ev:
+		default:
+			webhookDeliveriesTotal.Inc("dropped")
+		}
+	}
+}
+
+func (h Webhook) wants(event string) bool {
+	if len(h.Events) == 0 {
+		return true
+	}
+	for _, e := range h.Events {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// startWebhook registers hook and starts its sender. Must be called with
+// webhooksMu held.
+func startWebhook(hook Webhook) {
+	s := &webhookSender{hook: hook, queue: make(chan WebhookEvent, webhookQueue), done: make(chan struct{})}
+	webhooks[hook.ID] = s
+	go s.run()
+}
+
+func (s *webhookSender) run() {
+	for {
+		select {
+		case ev := <-s.queue:
+			s.deliver(ev)
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// deliver POSTs ev until it is taken, refused for good or out of attempts.
+func (s *webhookSender) deliver(ev WebhookEvent) {
+	body, err := json.Marshal(ev)
+	if err != nil {
+		log.Printf("⚠️  Webhook event %s not encoded: %v", ev.Event, err)
+		return
+	}
+	backoff := webhookBackoff
+	for attempt := 1; ; attempt++ {
+		retry, err := s.post(ev, body)
+		if err == nil {
+			webhookDeliveriesTotal.Inc("delivered")
+			return
+		}
+		if !retry || attempt == webhookAttempts {
+			webhookDeliveriesTotal.Inc("failed")
+			log.Printf("⚠️  Webhook %s (%s) gave up on %s %s after %d attempt(s): %v", s.hook.ID, s.hook.URL, ev.Event, ev.ID, attempt, err)
+			return
+		}
+		webhookDeliveriesTotal.Inc("retried")
+		select {
+		case <-time.After(backoff):
+		case <-s.done:
+			return
+		}
+		backoff *= 2
+	}
+}
+
+// post makes one attempt, saying whether a failure is worth retrying.
+func (s *webhookSender) post(ev WebhookEvent, body []byte) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), webhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Webhook-Event", ev.Event)
+	req.Header.Set("X-Webhook-ID", ev.ID)
+	if s.hook.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(s.hook.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Webhook-Signature", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	res, err := webhookHTTP.Do(req)
+	if err != nil {
+		return true, err
+	}
+	res.Body.Close()
+	switch {
+	case res.StatusCode < 300:
+		return false, nil
+	case res.StatusCode >= 500, res.StatusCode == http.StatusRequestTimeout, res.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("%s", res.Status)
+	}
+	return false, fmt.Errorf("%s", res.Status)
+}
+
+func loadWebhooks() error {
+	data, err := os.ReadFile(webhooksPath)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var list []Webhook
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	webhooksMu.Lock()
+	defer webhooksMu.Unlock()
+	for _, hook := range list {
+		startWebhook(hook)
+	}
+	return nil
+}
+
+// saveWebhooks must be called with webhooksMu held.
+func saveWebhooks() error {
+	if webhooksPath == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(webhookList(false), "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := webhooksPath + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, webhooksPath)
+}
+
+// webhookList returns the webhooks by ID, with their secrets masked if
+// redact. Must be called with webhooksMu held.
+func webhookList(redact bool) []Webhook {
+	list := make([]Webhook, 0, len(webhooks))
+	for _, s := range webhooks {
+		hook := s.hook
+		if redact && hook.Secret != "" {
+			hook.Secret = "********"
+		}
+		list = append(list, hook)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+// validWebhook checks what POST /webhooks was given.
+func validWebhook(hook Webhook) error {
+	u, err := url.Parse(hook.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("url must be an http:// or https:// URL")
+	}
+	for _, e := range hook.Events {
+		known := false
+		for _, k := range webhookEvents {
+			known = known || e == k
+		}
+		if !known {
+			return fmt.Errorf("unknown event %q (one of %v)", e, webhookEvents)
+		}
+	}
+	return nil
+}
+
+// handleWebhooks serves GET (list, secrets masked), POST (register) and
+// DELETE ?id= (unregister).
+func handleWebhooks(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		webhooksMu.Lock()
+		list := webhookList(true)
+		webhooksMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+
+	case http.MethodPost:
+		var hook Webhook
+		if err := json.NewDecoder(r.Body).Decode(&hook); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+			return
+		}
+		if err := validWebhook(hook); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		hook.ID = netproto.NewTraceID()
+		hook.CreatedMs = time.Now().UnixMilli()
+
+		webhooksMu.Lock()
+		startWebhook(hook)
+		err := saveWebhooks()
+		webhooksMu.Unlock()
+		if err != nil {
+			log.Printf("ERROR: Failed to save webhooks: %v", err)
+		}
+		log.Printf("🪝 Webhook %s registered for %v at %s", hook.ID, hook.Events, hook.URL)
+		if hook.Secret != "" {
+			hook.Secret = "********"
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(hook)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		webhooksMu.Lock()
+		s, ok := webhooks[id]
+		if ok {
+			close(s.done)
+			delete(webhooks, id)
+		}
+		err := saveWebhooks()
+		webhooksMu.Unlock()
+		if !ok {
+			http.Error(w, "No such webhook", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("ERROR: Failed to save webhooks: %v", err)
+		}
+		log.Printf("🪝 Webhook %s removed", id)
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,247 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== World map =====================
+
+// GET /map?world= describes a world for dashboards: every assigned chunk
+// with its owner and the players the owner last reported in it, the game
+// servers, and the last maxMigrations changes of owner across all worlds,
+// newest first. GET /servers lists the game servers, whether they are
+// alive, and the chunks and players they have. GET /chunks pages through
+// the chunk assignments of every world, with ?server=, ?world=, ?offset=
+// and ?limit=, and GET /chunks/{x}/{y}?world=&level= looks one up: its
+// owner and replicas, the players the owner last reported in it, when the
+// owner's lease runs out (it is failed over unless it reports by then) and
+// when the chunk last changed owner. All of these are read-only and public,
+// like /worlds.
+
+const (
+	maxMigrations = 50
+
+	defaultChunkPage = 100
+	maxChunkPage     = 1000
+)
+
+var (
+	// guarded by zoneMu; oldest first
+	migrations []types.Migration
+	// when each chunk got its current owner; guarded by zoneMu, like zone.
+	// Unlike migratedAt it is kept after the cooldown, but it is not in
+	// snapshots, so a node that restored one knows only later changes.
+	assignedAt = make(map[types.ChunkID]time.Time)
+)
+
+// recordMigration notes chunk_id moving from one owner to another. Must be
+// called with zoneMu held.
+func recordMigration(chunk_id types.ChunkID, from, to string) {
+	migrations = append(migrations, types.Migration{ChunkID: chunk_id, From: from, To: to, AtMs: time.Now().UnixMilli()})
+	if len(migrations) > maxMigrations {
+		migrations = append([]types.Migration(nil), migrations[len(migrations)-maxMigrations:]...)
+	}
+}
+
+func handleMap(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	world := r.URL.Query().Get("world")
+
+	players := make(map[types.ChunkID]map[string]int)
+	worldReportsMu.Lock()
+	for server, report := range worldReports {
+		for _, load := range report.ChunkPlayers {
+			if players[load.ChunkID] == nil {
+				players[load.ChunkID] = make(map[string]int)
+			}
+			players[load.ChunkID][server] = load.Players
+		}
+	}
+	worldReportsMu.Unlock()
+
+	zoneMu.Lock()
+	if !knownWorld(world) {
+		zoneMu.Unlock()
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "No such world", Code: types.CodeNotFound})
+		return
+	}
+	view := types.WorldMap{World: world, ChunkSize: chunkSize, Servers: append([]string(nil), serversList...),
+		Chunks: []types.MapChunk{}, Migrations: make([]types.Migration, 0, len(migrations))}
+	for chunk_id, owner := range zone {
+		if chunk_id.World == world {
+			view.Chunks = append(view.Chunks, types.MapChunk{ChunkID: chunk_id, Owner: owner, Players: players[chunk_id][owner]})
+		}
+	}
+	for i := len(migrations) - 1; i >= 0; i-- {
+		view.Migrations = append(view.Migrations, migrations[i])
+	}
+	zoneMu.Unlock()
+
+	sort.Slice(view.Chunks, func(i, j int) bool { return chunkBefore(view.Chunks[i].ChunkID, view.Chunks[j].ChunkID) })
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(view)
+}
+
+func handleServers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	list := make([]types.ServerStatus, len(serversList))
+	index := make(map[string]int, len(serversList))
+	for i, server := range serversList {
+		list[i] = types.ServerStatus{Server: server}
+		index[server] = i
+	}
+
+	zoneMu.Lock()
+	for _, owner := range zone {
+		if i, ok := index[owner]; ok {
+			list[i].Chunks++
+		}
+	}
+	for i := range list {
+		list[i].Alive = !dead[list[i].Server]
+	}
+	zoneMu.Unlock()
+
+	worldReportsMu.Lock()
+	for i := range list {
+		if report, ok := worldReports[list[i].Server]; ok {
+			list[i].Players, list[i].ReportedAt = report.Players, report.ReportedAt
+		}
+	}
+	worldReportsMu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(list)
+}
+
+func handleChunks(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	q := r.URL.Query()
+	server := q.Get("server")
+	world, by_world := q.Get("world"), q.Has("world")
+	offset, limit := 0, defaultChunkPage
+	if s := q.Get("offset"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "offset must be a non-negative integer", http.StatusBadRequest)
+			return
+		}
+		offset = n
+	}
+	if s := q.Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		limit = min(n, maxChunkPage)
+	}
+
+	zoneMu.Lock()
+	matched := make([]types.ChunkID, 0, len(zone))
+	for chunk_id, owner := range zone {
+		if (server == "" || owner == server) && (!by_world || chunk_id.World == world) {
+			matched = append(matched, chunk_id)
+		}
+	}
+	zoneMu.Unlock()
+	sort.Slice(matched, func(i, j int) bool { return chunkBefore(matched[i], matched[j]) })
+
+	page := types.ChunkPage{Chunks: []types.ChunkAssignment{}, Total: len(matched)}
+	if offset < len(matched) {
+		end := min(offset+limit, len(matched))
+		page.Chunks = chunkAssignments(matched[offset:end])
+		if end < len(matched) {
+			page.NextOffset = end
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(page)
+}
+
+func handleChunk(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/chunks/"), "/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	chunk_id, err := types.ParseChunkID(parts[0], parts[1], r.URL.Query().Get("world"), r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	found := chunkAssignments([]types.ChunkID{chunk_id})
+	w.Header().Set("Content-Type", "application/json")
+	if len(found) == 0 {
+		w.WriteHeader(http.StatusNotFound)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "Chunk is not assigned", Code: types.CodeNotFound})
+		return
+	}
+	json.NewEncoder(w).Encode(found[0])
+}
+
+// chunkAssignments describes the chunks of ids that are assigned, in order.
+func chunkAssignments(ids []types.ChunkID) []types.ChunkAssignment {
+	list := make([]types.ChunkAssignment, 0, len(ids))
+	zoneMu.Lock()
+	for _, chunk_id := range ids {
+		owner, ok := zone[chunk_id]
+		if !ok {
+			continue
+		}
+		a := types.ChunkAssignment{ChunkID: chunk_id, Owner: owner, Replicas: append([]string(nil), replicas[chunk_id]...)}
+		if at, ok := assignedAt[chunk_id]; ok {
+			a.MigratedMs = at.UnixMilli()
+		}
+		list = append(list, a)
+	}
+	zoneMu.Unlock()
+
+	worldReportsMu.Lock()
+	for i := range list {
+		a := &list[i]
+		for _, load := range worldReports[a.Owner].ChunkPlayers {
+			if load.ChunkID == a.ChunkID {
+				a.Players = load.Players
+			}
+		}
+		if expiry, ok := leaseExpiry(a.Owner); ok {
+			a.LeaseExpiresMs = expiry.UnixMilli()
+		}
+	}
+	worldReportsMu.Unlock()
+	return list
+}
+
+// chunkBefore orders chunk IDs by world, level, x and y.
+func chunkBefore(a, b types.ChunkID) bool {
+	if a.World != b.World {
+		return a.World < b.World
+	}
+	if a.Level != b.Level {
+		return a.Level < b.Level
+	}
+	return a.IDX < b.IDX || (a.IDX == b.IDX && a.IDY < b.IDY)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Worlds =====================
+
+// Besides the shared world, central keeps a registry of worlds created with
+// POST /worlds (admin), or by matchmaking for each match. GET /worlds lists
+// them. The registry is part of the zone map and replicated with it. Every
+// chunk ID names its world, so chunks of different worlds are owned, split
+// and migrated independently; a player joins a world by naming it at /join,
+// and central turns away /join and /chunk for worlds it does not know.
+//
+// A world is created with a consistency mode (see types.ConsistencyQuorum);
+// -shared-consistency sets the shared world's. Game servers learn the modes
+// from the reply to their metrics reports.
+
+type WorldRequest struct {
+	ID          string `json:"id"`
+	Kind        string `json:"kind"`                  // "lobby" if empty
+	Consistency string `json:"consistency,omitempty"` // owner-acknowledged if empty
+}
+
+var (
+	// guarded by zoneMu; the shared world is implicit
+	worlds = make(map[string]types.World)
+	// set by -shared-consistency
+	sharedConsistency = types.ConsistencyOwner
+)
+
+// knownWorld reports whether id is the shared world or a created one. Must
+// be called with zoneMu held.
+func knownWorld(id string) bool {
+	if id == types.DefaultWorld {
+		return true
+	}
+	_, ok := worlds[id]
+	return ok
+}
+
+// worldConsistency returns the consistency mode of world id. Must be called
+// with zoneMu held.
+func worldConsistency(id string) string {
+	if id == types.DefaultWorld {
+		return sharedConsistency
+	}
+	return worlds[id].Consistency
+}
+
+// strictWorlds returns the worlds, the shared one included, whose edits are
+// not owner-acknowledged, for game servers. Must be called with zoneMu held.
+func strictWorlds() []types.World {
+	var list []types.World
+	if sharedConsistency != types.ConsistencyOwner {
+		list = append(list, types.World{ID: types.DefaultWorld, Kind: "shared", Consistency: sharedConsistency})
+	}
+	for _, world := range worlds {
+		if world.Consistency != types.ConsistencyOwner {
+			list = append(list, world)
+		}
+	}
+	return list
+}
+
+// worldList returns the created worlds by ID. Must be called with zoneMu
+// held.
+func worldList() []types.World {
+	list := make([]types.World, 0, len(worlds))
+	for _, world := range worlds {
+		list = append(list, world)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func handleWorlds(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		zoneMu.Lock()
+		list := append([]types.World{{ID: types.DefaultWorld, Kind: "shared", Consistency: sharedConsistency}}, worldList()...)
+		zoneMu.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(list)
+	case http.MethodPost:
+		requireAdmin(postWorld)(w, r)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func postWorld(w http.ResponseWriter, r *http.Request) {
+	var req WorldRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+		return
+	}
+	if !types.ValidWorldID(req.ID) {
+		http.Error(w, "World id must be 1-64 of a-z, 0-9 and -", http.StatusBadRequest)
+		return
+	}
+	if !types.ValidConsistency(req.Consistency) {
+		http.Error(w, "Consistency must be empty or "+types.ConsistencyQuorum, http.StatusBadRequest)
+		return
+	}
+	if req.Kind == "" {
+		req.Kind = "lobby"
+	}
+
+	world, created, mark := createWorld(req.ID, req.Kind, req.Consistency)
+	if !awaitZone(w, mark) {
+		return
+	}
+	if !created {
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(types.Response{Success: false, Message: "World already exists", Code: types.CodeBadRequest})
+		return
+	}
+	if world.Consistency == types.ConsistencyQuorum {
+		log.Printf("🌍 Created %s world %s with quorum writes", world.Kind, world.ID)
+	} else {
+		log.Printf("🌍 Created %s world %s", world.Kind, world.ID)
+	}
+	json.NewEncoder(w).Encode(world)
+}
+
+// createWorld registers world id unless it exists, returning it with the
+// mark to wait for before using it.
+func createWorld(id, kind, consistency string) (types.World, bool, zoneMark) {
+	zoneMu.Lock()
+	defer zoneMu.Unlock()
+	if world, ok := worlds[id]; ok {
+		return world, false, markZone()
+	}
+	world := types.World{ID: id, Kind: kind, CreatedMs: time.Now().UnixMilli(), Consistency: consistency}
+	addWorld(world)
+	return world, true, markZone()
+}
@@ -0,0 +1,301 @@
+// Command cluster builds the central server and game server binaries and
+// runs a local cluster of them on free ports, so nobody has to edit the
+// hardcoded addresses to try the system on one machine. Stop it with Ctrl-C.
+//
+//	go run ./cmd/cluster -n 3
+//	go run ./cmd/cluster -n 3 -centrals 3   # central as a Raft group
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/hex"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+)
+
+type process struct {
+	name string
+	cmd  *exec.Cmd
+}
+
+func main() {
+	n := flag.Int("n", 3, "number of game servers")
+	centrals := flag.Int("centrals", 1, "number of central servers; more than one forms a Raft group")
+	host := flag.String("host", "127.0.0.1", "address every process binds to")
+	dir := flag.String("dir", "", "working directory for binaries, journals and banlist (temporary if empty)")
+	chunkSize := flag.Int("chunk-size", 0, "cluster chunk size, set on central and read from it by every game server (default if 0)")
+	replicaThreshold := flag.Int("replica-threshold", 0, "players per chunk that earn read replicas, passed to central (its default if 0)")
+	splitPlayers := flag.Int("split-players", 0, "players per chunk above which game servers split it (disabled if 0)")
+	chunkCapacity := flag.Int("chunk-capacity", 0, "most players a chunk admits before queueing the rest (unlimited if 0)")
+	gossip := flag.Bool("gossip", true, "have game servers gossip membership and ownership hints with each other")
+	listeners := flag.Int("listeners", 1, "SO_REUSEPORT sockets per game server")
+	chaosLoss := flag.Float64("chaos-loss", 0, "packet loss passed to every game server's chaos mode")
+	chaosDup := flag.Float64("chaos-dup", 0, "duplication rate passed to every game server's chaos mode")
+	chaosDelay := flag.Duration("chaos-delay", 0, "max send delay passed to every game server's chaos mode")
+	flag.Parse()
+
+	if *n <= 0 {
+		log.Fatalf("invalid -n %d", *n)
+	}
+	if *centrals <= 0 {
+		log.Fatalf("invalid -centrals %d", *centrals)
+	}
+
+	workDir := *dir
+	if workDir == "" {
+		tmp, err := os.MkdirTemp("", "cluster-")
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer os.RemoveAll(tmp)
+		workDir = tmp
+	} else if err := os.MkdirAll(workDir, 0o755); err != nil {
+		log.Fatal(err)
+	}
+
+	bin := filepath.Join(workDir, "bin")
+	for _, name := range []string{"central", "gameserver"} {
+		build := exec.Command("go", "build", "-o", filepath.Join(bin, name), "./cmd/"+name)
+		build.Stdout, build.Stderr = os.Stdout, os.Stderr
+		if err := build.Run(); err != nil {
+			log.Fatalf("building %s failed (run from the module root): %v", name, err)
+		}
+	}
+
+	// ports are picked up front so central knows every game server at start
+	centralAddrs := make([]string, *centrals)
+	centralURLs := make([]string, *centrals)
+	raftPeers := make([]string, *centrals)
+	for i := range centralAddrs {
+		centralAddrs[i] = net.JoinHostPort(*host, freePort("tcp", *host))
+		centralURLs[i] = "http://" + centralAddrs[i]
+		raftPeers[i] = fmt.Sprintf("%d=%s", i+1, centralURLs[i])
+	}
+	centralURL := centralURLs[0]
+	udpAddrs := make([]string, *n)
+	httpAddrs := make([]string, *n)
+	for i := range udpAddrs {
+		udpAddrs[i] = net.JoinHostPort(*host, freePort("udp", *host))
+		httpAddrs[i] = net.JoinHostPort(*host, freePort("tcp", *host))
+	}
+
+	// one seal secret for the whole cluster, whose servers then take only
+	// sealed traffic
+	secret := make([]byte, 16)
+	if _, err := rand.Read(secret); err != nil {
+		log.Fatal(err)
+	}
+	sealSecret := hex.EncodeToString(secret)
+
+	var procs []*process
+	stopAll := func() {
+		for i := len(procs) - 1; i >= 0; i-- {
+			procs[i].cmd.Process.Signal(syscall.SIGTERM)
+		}
+		for _, p := range procs {
+			p.cmd.Wait()
+		}
+	}
+
+	isCentral := make(map[string]bool)
+	for i := range centralAddrs {
+		name, banlist := "central", filepath.Join(workDir, "bans.json")
+		centralArgs := []string{
+			"-listen", centralAddrs[i],
+			"-servers", strings.Join(udpAddrs, ","),
+			"-seal-secret", sealSecret,
+		}
+		if *centrals > 1 {
+			name, banlist = fmt.Sprintf("central-%d", i+1), filepath.Join(workDir, fmt.Sprintf("bans-%d.json", i+1))
+			centralArgs = append(centralArgs, "-raft-id", fmt.Sprint(i+1), "-raft-peers", strings.Join(raftPeers, ","),
+				"-raft-dir", filepath.Join(workDir, "raft"))
+		}
+		centralArgs = append(centralArgs, "-banlist", banlist)
+		if *chunkSize > 0 {
+			centralArgs = append(centralArgs, "-chunk-size", fmt.Sprint(*chunkSize))
+		}
+		if *replicaThreshold > 0 {
+			centralArgs = append(centralArgs, "-replica-threshold", fmt.Sprint(*replicaThreshold))
+		}
+		isCentral[name] = true
+		procs = append(procs, start(name, filepath.Join(bin, "central"), centralArgs...))
+		if err := waitHTTP(centralURLs[i] + "/metrics"); err != nil {
+			stopAll()
+			log.Fatalf("%s did not come up: %v", name, err)
+		}
+	}
+
+	for i := range udpAddrs {
+		name := fmt.Sprintf("game-%d", i+1)
+		args := []string{
+			"-addr", udpAddrs[i],
+			"-central", strings.Join(centralURLs, ","),
+			"-http", httpAddrs[i],
+			"-seal-secret", sealSecret,
+			"-journal", filepath.Join(workDir, name+".events.jsonl"),
+		}
+		if *gossip && *n > 1 {
+			var peers []string
+			for j, peer := range udpAddrs {
+				if j != i {
+					peers = append(peers, peer)
+				}
+			}
+			args = append(args, "-peers", strings.Join(peers, ","))
+		}
+		if *listeners > 1 {
+			args = append(args, "-listeners", fmt.Sprint(*listeners))
+		}
+		if *splitPlayers > 0 {
+			args = append(args, "-split-players", fmt.Sprint(*splitPlayers))
+		}
+		if *chunkCapacity > 0 {
+			args = append(args, "-chunk-capacity", fmt.Sprint(*chunkCapacity))
+		}
+		if *chaosLoss > 0 || *chaosDup > 0 || *chaosDelay > 0 {
+			args = append(args, "-chaos-loss", fmt.Sprint(*chaosLoss), "-chaos-dup", fmt.Sprint(*chaosDup),
+				"-chaos-delay", chaosDelay.String(), "-chaos-seed", fmt.Sprint(i+1))
+		}
+		procs = append(procs, start(name, filepath.Join(bin, "gameserver"), args...))
+		if err := waitHTTP("http://" + httpAddrs[i] + "/metrics"); err != nil {
+			stopAll()
+			log.Fatalf("%s did not come up: %v", name, err)
+		}
+	}
+
+	fmt.Printf("\n🚀 Local cluster is up (working dir %s)\n\n", workDir)
+	for i, url := range centralURLs {
+		if *centrals > 1 {
+			fmt.Printf("  central-%-6d %s\n", i+1, url)
+		} else {
+			fmt.Printf("  central       %s\n", url)
+		}
+	}
+	for i := range udpAddrs {
+		fmt.Printf("  game-%-8d udp %s   metrics/admin http://%s\n", i+1, udpAddrs[i], httpAddrs[i])
+	}
+	fmt.Printf("\nConnect with:\n")
+	fmt.Printf("  go run ./cmd/gateway -server %s -central %s -seal-secret %s\n", udpAddrs[0], centralURL, sealSecret)
+	fmt.Printf("  go run ./cmd/simclient -central %s -id 1\n\n", centralURL)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+	exited := make(chan string, len(procs))
+	for _, p := range procs {
+		go func(p *process) {
+			p.cmd.Wait()
+			exited <- p.name
+		}(p)
+	}
+
+	running, games := len(procs), *n
+wait:
+	for {
+		select {
+		case <-sig:
+			log.Println("🛑 Stopping cluster")
+			break wait
+		case name := <-exited:
+			running--
+			if *centrals > 1 && isCentral[name] {
+				// the Raft group carries on without it, which is the point
+				log.Printf("⚠️  %s exited, the other central nodes take over", name)
+				continue
+			}
+			if !isCentral[name] && games > 1 {
+				// central fails its chunks over to the others
+				games--
+				log.Printf("⚠️  %s exited, central fails its chunks over", name)
+				continue
+			}
+			log.Printf("❌ %s exited, stopping cluster", name)
+			break wait
+		}
+	}
+	for i := len(procs) - 1; i >= 0; i-- {
+		procs[i].cmd.Process.Signal(syscall.SIGTERM)
+	}
+	deadline := time.After(5 * time.Second)
+	for remaining := running; remaining > 0; remaining-- {
+		select {
+		case <-exited:
+		case <-deadline:
+			for _, p := range procs {
+				p.cmd.Process.Kill()
+			}
+			return
+		}
+	}
+}
+
+// start launches a child process with its output prefixed by name.
+func start(name, path string, args ...string) *process {
+	cmd := exec.Command(path, args...)
+	out, err := cmd.StdoutPipe()
+	if err != nil {
+		log.Fatal(err)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		log.Fatalf("starting %s failed: %v", name, err)
+	}
+	go prefixLines(name, out)
+	return &process{name: name, cmd: cmd}
+}
+
+func prefixLines(name string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		fmt.Printf("[%s] %s\n", name, scanner.Text())
+	}
+}
+
+// freePort asks the kernel for an unused port on host.
+func freePort(network, host string) string {
+	var addr net.Addr
+	switch network {
+	case "udp":
+		conn, err := net.ListenPacket("udp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer conn.Close()
+		addr = conn.LocalAddr()
+	default:
+		ln, err := net.Listen("tcp", net.JoinHostPort(host, "0"))
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer ln.Close()
+		addr = ln.Addr()
+	}
+	_, port, _ := net.SplitHostPort(addr.String())
+	return port
+}
+
+func waitHTTP(url string) error {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		res, err := http.Get(url)
+		if err == nil {
+			res.Body.Close()
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return err
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
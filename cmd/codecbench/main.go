@@ -0,0 +1,181 @@
+// Command codecbench checks that the generated JSON codecs write and read
+// the same JSON as encoding/json, then benchmarks both on typical messages.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"reflect"
+	"strconv"
+	"testing"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// sampleChunk is a chunk with cubes, players, NPCs and items enough to look
+// like a busy GET_UPDATES reply.
+func sampleChunk(cubes, players int) types.Chunk {
+	c := types.Chunk{IDX: 3, IDY: -2, ServerIP: "127.0.0.1:8001", Data: "grass <&> \"quoted\"\n\ttab é ✓", IsDirty: true}
+	for i := 0; i < cubes; i++ {
+		c.Cells = append(c.Cells, types.Cube{ID: "cube-" + strconv.Itoa(i), X: i % 16, Z: i / 16, Height: i % 7, Color: "#a0b0c0"})
+	}
+	for i := 0; i < players; i++ {
+		c.PlayerList = append(c.PlayerList, types.Player{
+			ID: "player-" + strconv.Itoa(i), PosX: i * 3, PosY: -i, ServerIP: "127.0.0.1:8001", AOIRadius: 1,
+			ChunkID: types.ChunkID{IDX: 3, IDY: -2},
+			VelX:    1.5, VelY: -0.25 * float64(i), Heading: math.Pi / float64(i+1), UpdatedMs: 1700000000000 + int64(i),
+			Health:    100 - i,
+			Inventory: []types.ItemStack{{Kind: "gem", Count: i}},
+		})
+	}
+	c.NPCs = []types.NPC{{ID: "npc-1", Kind: "wanderer", PosX: 4, PosY: 5}, {ID: "npc-2", Kind: "mob", Target: "player-0"}}
+	c.Items = []types.Item{{ID: "item-1", Kind: "gem", Count: 2, PosX: 1, PosY: 1}}
+	return c
+}
+
+func sampleRequest() types.Request {
+	return types.Request{
+		Type:           types.ReqGetUpdates,
+		ChunkID:        types.ChunkID{IDX: 3, IDY: -2},
+		CallerIP:       "127.0.0.1:50000",
+		Player:         types.Player{ID: "player-7", PosX: 21, PosY: -7, AOIRadius: 1, ChunkID: types.ChunkID{IDX: 3, IDY: -2}, VelX: 0.1, UpdatedMs: 1700000000123},
+		PlayerID:       "player-7",
+		TraceID:        "7f3a",
+		Since:          &types.ChunkVersion{Epoch: 2, Seq: 41},
+		AcceptEncoding: netproto.AcceptEncodings,
+		SessionToken:   "tok en",
+		InputSeq:       99,
+		CallID:         1 << 40,
+	}
+}
+
+func sampleResponse(cubes, players int) types.Response {
+	c := sampleChunk(cubes, players)
+	return types.Response{
+		Success:      true,
+		Chunk:        c,
+		Message:      "ok",
+		GameData:     types.GameData{Chunk: c},
+		PlayerCount:  players,
+		Code:         types.CodeOK,
+		Version:      &types.ChunkVersion{Epoch: 2, Seq: 42},
+		Chat:         []types.ChatMessage{{Seq: 1, From: "player-1", Text: "hi <b>there</b>", SentMs: 1700000000000}},
+		ServerTimeMs: 1700000000456,
+		Tick:         12345,
+		TickMs:       50,
+		Payload:      []byte{0, 1, 2, 0xff},
+	}
+}
+
+// sameJSON marshals v with both codecs, requiring the same bytes, and reads
+// the JSON back with both into fresh values of v's type, requiring equal
+// values.
+func sameJSON(name string, v any) error {
+	want, err := netproto.Reflect.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: encoding/json: %v", name, err)
+	}
+	got, err := netproto.Generated.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%s: generated: %v", name, err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("%s: generated wrote\n%s\nencoding/json wrote\n%s", name, got, want)
+	}
+	return sameDecode(name, want, v)
+}
+
+// sameDecode reads data with both codecs into fresh values of v's type.
+func sameDecode(name string, data []byte, v any) error {
+	t := reflect.TypeOf(v)
+	want, got := reflect.New(t), reflect.New(t)
+	wantErr := netproto.Reflect.Unmarshal(data, want.Interface())
+	gotErr := netproto.Generated.Unmarshal(data, got.Interface())
+	if (wantErr == nil) != (gotErr == nil) {
+		return fmt.Errorf("%s: generated error %v, encoding/json error %v", name, gotErr, wantErr)
+	}
+	if wantErr == nil && !reflect.DeepEqual(got.Elem().Interface(), want.Elem().Interface()) {
+		return fmt.Errorf("%s: generated read\n%+v\nencoding/json read\n%+v", name, got.Elem().Interface(), want.Elem().Interface())
+	}
+	return nil
+}
+
+// check compares the codecs on the samples and on hand-written JSON that
+// encoding/json accepts but never writes.
+func check() error {
+	invalid := types.Request{PlayerID: "bad\xffutf8", Text: "a\x00b\u2029c\\d/e"}
+	for name, v := range map[string]any{
+		"request":       sampleRequest(),
+		"empty request": types.Request{},
+		"invalid utf-8": invalid,
+		"response":      sampleResponse(64, 8),
+		"pushes":        types.Response{Pushes: []types.Response{sampleResponse(1, 1)}},
+		"chunk":         sampleChunk(4, 2),
+	} {
+		if err := sameJSON(name, v); err != nil {
+			return err
+		}
+	}
+	for name, data := range map[string]string{
+		"case-insensitive keys": `{"TYPE":"GET_UPDATES","Player_ID":"p","chunk_ID":{"ID_X":1}}`,
+		"nulls":                 `{"type":null,"since":null,"cubes":null,"player":null,"stats":{"rtt_last_ms":null}}`,
+		"unknown keys":          `{"zzz":[1,{"a":[true,null]}],"player_id":"p","zz":-1.5e3}`,
+		"escapes":               `{"player_id":"é😀\n\"\/","text":"\ud800x"}`,
+		"duplicate keys":        `{"player_id":"a","player_id":"b","since":{"seq":1},"since":{"epoch":2}}`,
+		"wrong type":            `{"player_id":5}`,
+		"overflow":              `{"player_count":1e400}`,
+		"trailing data":         `{"player_id":"p"} x`,
+		"truncated":             `{"player_id":"p"`,
+	} {
+		if err := sameDecode(name, []byte(data), types.Request{}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func main() {
+	cubes := flag.Int("cubes", 256, "cubes in the benchmarked chunk")
+	players := flag.Int("players", 16, "players in the benchmarked chunk")
+	flag.Parse()
+
+	if err := check(); err != nil {
+		log.Fatalf("❌ codecs differ: %v", err)
+	}
+	fmt.Println("✅ generated codecs match encoding/json")
+
+	req := sampleRequest()
+	res := sampleResponse(*cubes, *players)
+	reqData, _ := netproto.Reflect.Marshal(req)
+	resData, _ := netproto.Reflect.Marshal(res)
+	fmt.Printf("request %d bytes, response %d bytes (%d cubes, %d players)\n", len(reqData), len(resData), *cubes, *players)
+
+	for _, c := range []netproto.Codec{netproto.Reflect, netproto.Generated} {
+		c := c
+		bench(c.Name()+" marshal request", func() error { _, err := c.Marshal(req); return err })
+		bench(c.Name()+" unmarshal request", func() error { var r types.Request; return c.Unmarshal(reqData, &r) })
+		bench(c.Name()+" marshal response", func() error { _, err := c.Marshal(res); return err })
+		bench(c.Name()+" unmarshal response", func() error { var r types.Response; return c.Unmarshal(resData, &r) })
+	}
+}
+
+func bench(name string, f func() error) {
+	r := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			if err := f(); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+	if r.N == 0 {
+		fmt.Fprintf(os.Stderr, "%s: failed\n", name)
+		return
+	}
+	fmt.Printf("%-28s %10d ns/op %8d B/op %6d allocs/op\n", name, r.NsPerOp(), r.AllocedBytesPerOp(), r.AllocsPerOp())
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk activity =====================
+
+// How often a chunk's changes are pushed follows how often it changes. Each
+// new version chunkVersion sees counts as an event; a chunk's activity is
+// its event rate, decayed with activityHalfLife so it tracks the last few
+// seconds. Spectated chunks are observed every tick so their rate is
+// measured even between pushes. A chunk changing every tick is pushed every
+// minUpdateEvery ticks, a quiet one every maxUpdateEvery, and in between
+// about as often as it changes. The interval goes out as UpdateMs with
+// SPECTATE, PUSH_SPECTATE and GET_UPDATES so clients can poll and size
+// their interpolation windows to match.
+
+const (
+	activityHalfLife = 5 * time.Second
+	minUpdateEvery   = 2  // ticks between pushes of the busiest chunks
+	maxUpdateEvery   = 20 // ticks between pushes of quiet chunks
+)
+
+type chunkActivity struct {
+	Rate    float64 // events per second, as of At
+	At      time.Time
+	Version types.ChunkVersion // last one seen
+}
+
+// guarded by zone_map_Mu; dropped with chunk_versions
+var chunk_activity = make(map[types.ChunkID]*chunkActivity)
+
+var _ = metrics.NewGaugeFunc("game_chunk_update_ms",
+	"Interval pushes of each spectated chunk are sent at, following its activity.", "chunk", func() map[string]float64 {
+		zone_map_Mu.Lock()
+		defer zone_map_Mu.Unlock()
+		values := make(map[string]float64, len(spectators))
+		for chunk_id := range spectators {
+			values[chunkLabel(chunk_id)] = float64(updateEvery(chunk_id, time.Now()) * world.TickMs)
+		}
+		return values
+	})
+
+// decayed returns rate as of now.
+func (a *chunkActivity) decayed(now time.Time) float64 {
+	dt := now.Sub(a.At).Seconds()
+	if dt <= 0 {
+		return a.Rate
+	}
+	return a.Rate * math.Exp2(-dt/activityHalfLife.Seconds())
+}
+
+// noteVersion counts an event if chunk_id has reached a version not seen
+// before. Must be called with zone_map_Mu held.
+func noteVersion(chunk_id types.ChunkID, version types.ChunkVersion, now time.Time) {
+	a, ok := chunk_activity[chunk_id]
+	if !ok {
+		chunk_activity[chunk_id] = &chunkActivity{At: now, Version: version}
+		return
+	}
+	if version == a.Version {
+		return
+	}
+	// each event adds the weight that makes a steady rate r settle at r
+	a.Rate = a.decayed(now) + math.Ln2/activityHalfLife.Seconds()
+	a.At, a.Version = now, version
+}
+
+// updateEvery returns the ticks between pushes of chunk_id. Must be called
+// with zone_map_Mu held.
+func updateEvery(chunk_id types.ChunkID, now time.Time) int {
+	a, ok := chunk_activity[chunk_id]
+	if !ok {
+		return maxUpdateEvery
+	}
+	rate := a.decayed(now)
+	if rate <= 0 {
+		return maxUpdateEvery
+	}
+	ticks := math.Round(1000 / (rate * float64(world.TickMs)))
+	return int(math.Max(minUpdateEvery, math.Min(maxUpdateEvery, ticks)))
+}
+
+// updateMs is updateEvery in milliseconds, as clients are told it. Must be
+// called with zone_map_Mu held.
+func updateMs(chunk_id types.ChunkID, now time.Time) int64 {
+	return int64(updateEvery(chunk_id, now) * world.TickMs)
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// handleAnnounce pushes central's announcement to every player connected
+// to this server and tells central how many it went to.
+func handleAnnounce(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if req.Announcement == nil || req.Announcement.Text == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing announcement", Code: types.CodeBadRequest})
+		return
+	}
+
+	notice := types.Response{Success: true, Code: types.CodeAnnounce, Message: req.Announcement.Text, Announcement: req.Announcement}
+	stampClock(&notice)
+	pushed := 0
+	for player_id := range players {
+		if player_addr, ok := player_addrs[player_id]; ok {
+			push(conn, player_addr, notice)
+			pushed++
+		}
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Announced", PlayerCount: pushed})
+	log.Printf("📢 Announcement %s (%s) pushed to %d players: %q", req.Announcement.ID, req.Announcement.Kind, pushed, req.Announcement.Text)
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/s3"
+)
+
+// ===================== Backups =====================
+
+// With -backup s3://BUCKET/PREFIX a server uploads a snapshot of the chunks
+// it owns every -backup-every to S3-compatible storage (-backup-endpoint,
+// credentials from AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+// AWS_SESSION_TOKEN). A snapshot is the world file GET /admin/world/export
+// returns, gzipped, at PREFIX/WORLD/SERVER/20060102T150405Z.world.json.gz,
+// with its SHA-256 in the object's sha256 metadata; the store checks the
+// upload against a checksum sent with it. With -backup-keep the oldest of this
+// server's snapshots beyond that many are deleted after each upload.
+//
+// GET /admin/backups lists the snapshots of this world, POST takes one now,
+// and POST /admin/backups/restore?key=KEY downloads one, refuses it unless
+// its checksum matches, and imports it as POST /admin/world/import would
+// (overwrite=1 and range= included): the chunks are claimed at central and
+// land in the chunk store on the next save pass.
+
+var (
+	backups      *s3.Client // nil without -backup
+	backupPrefix string     // PREFIX/WORLD/
+	backupEvery  = time.Hour
+	backupKeep   int
+)
+
+const backupTimeout = 5 * time.Minute
+
+var backupsTotal = metrics.NewCounterVec("game_backups_total",
+	"World snapshots by outcome: uploaded, failed, pruned (deleted past -backup-keep), restored or corrupt (checksum mismatch on restore).", "result")
+
+// BackupResult is the reply to POST /admin/backups.
+type BackupResult struct {
+	Key    string `json:"key"`
+	Chunks int    `json:"chunks"`
+	Bytes  int    `json:"bytes"`
+	SHA256 string `json:"sha256"`
+}
+
+// openBackups sets up the client for target, s3://BUCKET/PREFIX.
+func openBackups(target, endpoint, region string) error {
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme != "s3" || u.Host == "" {
+		return fmt.Errorf("-backup must be s3://BUCKET/PREFIX, got %q", target)
+	}
+	client, err := s3.New(s3.Config{
+		Endpoint:     endpoint,
+		Region:       region,
+		Bucket:       u.Host,
+		AccessKey:    os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretKey:    os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken: os.Getenv("AWS_SESSION_TOKEN"),
+	})
+	if err != nil {
+		return err
+	}
+	backups = client
+	backupPrefix = strings.Trim(u.Path, "/")
+	if backupPrefix != "" {
+		backupPrefix += "/"
+	}
+	backupPrefix += world.Name + "/"
+	return nil
+}
+
+// serverBackupPrefix is where this server's snapshots go.
+func serverBackupPrefix() string {
+	return backupPrefix + strings.ReplaceAll(serverIP, ":", "_") + "/"
+}
+
+// runBackups takes a snapshot every backupEvery.
+func runBackups() {
+	ticker := time.NewTicker(backupEvery)
+	defer ticker.Stop()
+	for range ticker.C {
+		ctx, cancel := context.WithTimeout(context.Background(), backupTimeout)
+		result, err := takeBackup(ctx)
+		cancel()
+		if err != nil {
+			log.Printf("❌ Backup failed: %v", err)
+			continue
+		}
+		log.Printf("🗄️  Backed up %d chunks (%d bytes) to %s", result.Chunks, result.Bytes, result.Key)
+	}
+}
+
+// takeBackup uploads a snapshot of every chunk this server owns, then
+// prunes old ones.
+func takeBackup(ctx context.Context) (BackupResult, error) {
+	file, err := exportWorld(nil)
+	if err != nil {
+		backupsTotal.Inc("failed")
+		return BackupResult{}, err
+	}
+	var buf bytes.Buffer
+	zw, _ := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err := file.Write(zw); err != nil {
+		backupsTotal.Inc("failed")
+		return BackupResult{}, err
+	}
+	if err := zw.Close(); err != nil {
+		backupsTotal.Inc("failed")
+		return BackupResult{}, err
+	}
+
+	sum := sha256.Sum256(buf.Bytes())
+	result := BackupResult{
+		Key:    serverBackupPrefix() + time.Now().UTC().Format("20060102T150405Z") + ".world.json.gz",
+		Chunks: len(file.Chunks),
+		Bytes:  buf.Len(),
+		SHA256: hex.EncodeToString(sum[:]),
+	}
+	meta := map[string]string{"sha256": result.SHA256, "source": serverIP, "chunks": fmt.Sprint(result.Chunks)}
+	if err := backups.Put(ctx, result.Key, buf.Bytes(), "application/gzip", meta); err != nil {
+		backupsTotal.Inc("failed")
+		return BackupResult{}, err
+	}
+	backupsTotal.Inc("uploaded")
+	journal.Record(WorldEvent{Type: "BACKUP", Detail: fmt.Sprintf("%d chunks to %s", result.Chunks, result.Key)})
+
+	if backupKeep > 0 {
+		pruneBackups(ctx)
+	}
+	return result, nil
+}
+
+// pruneBackups deletes this server's snapshots beyond the newest
+// backupKeep; keys sort by time.
+func pruneBackups(ctx context.Context) {
+	objects, err := backups.List(ctx, serverBackupPrefix())
+	if err != nil {
+		log.Printf("⚠️  Listing backups to prune failed: %v", err)
+		return
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key > objects[j].Key })
+	for i := backupKeep; i < len(objects); i++ {
+		if err := backups.Delete(ctx, objects[i].Key); err != nil {
+			log.Printf("⚠️  Deleting old backup %s failed: %v", objects[i].Key, err)
+			continue
+		}
+		backupsTotal.Inc("pruned")
+	}
+}
+
+// restoreBackup downloads the snapshot at key, checks it against its
+// checksum, and imports it.
+func restoreBackup(ctx context.Context, key string, chunk_range *chunkstore.ChunkRange, overwrite bool) (WorldImportResult, error) {
+	data, meta, err := backups.Get(ctx, key)
+	if err != nil {
+		return WorldImportResult{}, err
+	}
+	sum := sha256.Sum256(data)
+	if want := meta["sha256"]; want == "" || want != hex.EncodeToString(sum[:]) {
+		backupsTotal.Inc("corrupt")
+		return WorldImportResult{}, fmt.Errorf("backup %s does not match its checksum", key)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return WorldImportResult{}, err
+	}
+	file, err := chunkstore.ReadWorldFile(zr)
+	if err != nil {
+		return WorldImportResult{}, err
+	}
+	result, err := importWorld(ctx, file, chunk_range, overwrite)
+	if err != nil {
+		return WorldImportResult{}, err
+	}
+	backupsTotal.Inc("restored")
+	journal.Record(WorldEvent{Type: "RESTORE", Detail: fmt.Sprintf("%d chunks from %s", result.Imported, key), TraceID: result.TraceID})
+	return result, nil
+}
+
+// handleAdminBackups lists this world's snapshots (GET) or takes one now
+// (POST).
+func handleAdminBackups(w http.ResponseWriter, r *http.Request) {
+	if backups == nil {
+		http.Error(w, "Backups are not configured (-backup)", http.StatusNotFound)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		objects, err := backups.List(r.Context(), backupPrefix)
+		if err != nil {
+			http.Error(w, "Listing backups failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		if objects == nil {
+			objects = []s3.Object{}
+		}
+		writeAdminJSON(w, objects)
+	case http.MethodPost:
+		result, err := takeBackup(r.Context())
+		if err != nil {
+			http.Error(w, "Backup failed: "+err.Error(), http.StatusBadGateway)
+			return
+		}
+		log.Printf("🗄️  Backed up %d chunks (%d bytes) to %s for %s", result.Chunks, result.Bytes, result.Key, r.RemoteAddr)
+		writeAdminJSON(w, result)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleAdminBackupRestore serves POST /admin/backups/restore?key=KEY.
+func handleAdminBackupRestore(w http.ResponseWriter, r *http.Request) {
+	if backups == nil {
+		http.Error(w, "Backups are not configured (-backup)", http.StatusNotFound)
+		return
+	}
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	key := r.URL.Query().Get("key")
+	if key == "" {
+		http.Error(w, "key required", http.StatusBadRequest)
+		return
+	}
+	chunk_range, err := parseChunkRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := restoreBackup(r.Context(), key, chunk_range, r.URL.Query().Get("overwrite") == "1")
+	if s3.IsNotFound(err) {
+		http.Error(w, "No such backup", http.StatusNotFound)
+		return
+	} else if err != nil {
+		http.Error(w, "Restore failed: "+err.Error(), http.StatusConflict)
+		return
+	}
+	log.Printf("🗄️  Restored %d chunks from %s for %s", result.Imported, key, r.RemoteAddr)
+	writeAdminJSON(w, result)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk capacity =====================
+
+// With -chunk-capacity set, a player entering a full owned chunk gets
+// ERR_CHUNK_FULL instead of joining. They are queued first come, first
+// served and keep their place as long as they ask again (GET_DATA) within
+// queueTimeout; the reply carries their position, an estimated wait from
+// how fast players have been leaving the chunk, and a neighbouring chunk
+// with room. Reads of a chunk the player is not standing in are never
+// queued.
+
+// chunkCapacity is the most players one chunk admits (0 is unlimited).
+var chunkCapacity = 0
+
+const (
+	queueTimeout     = 30 * time.Second
+	departureWindow  = time.Minute
+	defaultQueueWait = 5 * time.Second
+)
+
+type QueuedPlayer struct {
+	PlayerID string
+	Since    time.Time
+	Polled   time.Time
+}
+
+var (
+	chunk_queues     = make(map[types.ChunkID][]QueuedPlayer)
+	chunk_departures = make(map[types.ChunkID][]time.Time) // within departureWindow
+)
+
+var (
+	chunkFullTotal = metrics.NewCounterVec("game_chunk_full_total",
+		"GET_DATA requests refused because the chunk was at capacity.", "")
+	_ = metrics.NewGaugeFunc("game_chunk_queue_length",
+		"Players waiting to enter each full chunk.", "chunk", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			values := make(map[string]float64, len(chunk_queues))
+			for chunk_id, queue := range chunk_queues {
+				values[chunkLabel(chunk_id)] = float64(len(queue))
+			}
+			return values
+		})
+)
+
+// chunkPlayerCount counts the players this server has in chunk_id. Must be
+// called with zone_map_Mu held.
+func chunkPlayerCount(chunk_id types.ChunkID) int {
+	count := 0
+	for _, in := range players {
+		if in == chunk_id {
+			count++
+		}
+	}
+	return count
+}
+
+// admitPlayer reports whether player may enter the owned chunk_id and, if
+// not, returns the ERR_CHUNK_FULL reply. Must be called with zone_map_Mu
+// held.
+func admitPlayer(chunk_id types.ChunkID, player types.Player) (types.Response, bool) {
+	in, known := players[player.ID]
+	if chunkCapacity <= 0 || player.ID == "" || known && in == chunk_id ||
+		chunkAt(player.World, player.PosX, player.PosY) != chunk_id {
+		return types.Response{}, true
+	}
+
+	now := time.Now()
+	free := chunkCapacity - chunkPlayerCount(chunk_id)
+	queue := chunk_queues[chunk_id]
+	pos := -1
+	for i, queued := range queue {
+		if queued.PlayerID == player.ID {
+			pos = i
+			break
+		}
+	}
+	if pos < 0 && len(queue) < free || pos >= 0 && pos < free {
+		if pos >= 0 {
+			queue = append(queue[:pos:pos], queue[pos+1:]...)
+		}
+		setQueue(chunk_id, queue)
+		return types.Response{}, true
+	}
+
+	if pos < 0 {
+		queue = append(queue, QueuedPlayer{PlayerID: player.ID, Since: now, Polled: now})
+		pos = len(queue) - 1
+	} else {
+		queue[pos].Polled = now
+	}
+	setQueue(chunk_id, queue)
+	chunkFullTotal.Inc("")
+
+	position := pos + 1
+	wait := estimateWait(chunk_id, position-max(free, 0), now)
+	return types.Response{
+		Success:       false,
+		Message:       fmt.Sprintf("Chunk is full, you are number %d in the queue", position),
+		Code:          types.CodeChunkFull,
+		QueuePosition: position,
+		RetryAfterMs:  wait.Milliseconds(),
+		Alternative:   alternativeChunk(chunk_id),
+	}, false
+}
+
+func setQueue(chunk_id types.ChunkID, queue []QueuedPlayer) {
+	if len(queue) == 0 {
+		delete(chunk_queues, chunk_id)
+		return
+	}
+	chunk_queues[chunk_id] = queue
+}
+
+// estimateWait guesses how long until ahead more players have left chunk_id,
+// from the departures seen in the last departureWindow. Must be called with
+// zone_map_Mu held.
+func estimateWait(chunk_id types.ChunkID, ahead int, now time.Time) time.Duration {
+	if ahead < 1 {
+		ahead = 1
+	}
+	recent := 0
+	for _, at := range chunk_departures[chunk_id] {
+		if now.Sub(at) <= departureWindow {
+			recent++
+		}
+	}
+	if recent == 0 {
+		return time.Duration(ahead) * defaultQueueWait
+	}
+	return time.Duration(ahead) * departureWindow / time.Duration(recent)
+}
+
+// recordDeparture notes a player leaving chunk_id, for wait estimates. Must
+// be called with zone_map_Mu held.
+func recordDeparture(chunk_id types.ChunkID) {
+	if chunkCapacity <= 0 {
+		return
+	}
+	chunk_departures[chunk_id] = append(chunk_departures[chunk_id], time.Now())
+}
+
+// alternativeChunk suggests a neighbour of chunk_id a queued player could go
+// to instead: an owned one with room, else one nobody here holds yet. Must
+// be called with zone_map_Mu held.
+func alternativeChunk(chunk_id types.ChunkID) *types.ChunkID {
+	var unclaimed *types.ChunkID
+	for dx := -1; dx <= 1; dx++ {
+		for dy := -1; dy <= 1; dy++ {
+			id := types.ChunkID{World: chunk_id.World, IDX: chunk_id.IDX + dx, IDY: chunk_id.IDY + dy, Level: chunk_id.Level}
+			if id == chunk_id || splits[id] {
+				continue
+			}
+			chunk, ok := zone_map[id]
+			if !ok {
+				if unclaimed == nil {
+					unclaimed = &id
+				}
+				continue
+			}
+			if chunk.ServerIP == serverIP && chunkPlayerCount(id) < chunkCapacity {
+				return &id
+			}
+		}
+	}
+	return unclaimed
+}
+
+// sweepQueues drops queued players who stopped asking and departures older
+// than departureWindow. Must be called with zone_map_Mu held.
+func sweepQueues(now time.Time) {
+	for chunk_id, queue := range chunk_queues {
+		kept := queue[:0]
+		for _, queued := range queue {
+			if now.Sub(queued.Polled) <= queueTimeout {
+				kept = append(kept, queued)
+			}
+		}
+		setQueue(chunk_id, kept)
+	}
+	for chunk_id, departures := range chunk_departures {
+		kept := departures[:0]
+		for _, at := range departures {
+			if now.Sub(at) <= departureWindow {
+				kept = append(kept, at)
+			}
+		}
+		if len(kept) == 0 {
+			delete(chunk_departures, chunk_id)
+		} else {
+			chunk_departures[chunk_id] = kept
+		}
+	}
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"log"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Central outages =====================
+
+// callCentral retries a call central did not answer (every node
+// unreachable, or a 5xx) up to centralRetries more times, backing off
+// exponentially from centralBackoff with jitter, all within centralTimeout.
+// A call central answered, even with a 4xx, is not retried.
+//
+// Calls that fail centralBreakAfter times in a row open centralBreaker:
+// calls then fail at once with errCentralOpen rather than each waiting out
+// centralTimeout, until centralCooldown has passed. The next call after that
+// is let through as a probe (half-open); if it succeeds the breaker closes,
+// otherwise it opens again. The metrics report every reportEvery ticks keeps
+// probing on its own.
+//
+// While central is out the server is degraded, not down: chunks it owns are
+// served as usual (GET_DATA only asks central about chunks it does not own),
+// gossiped ownership hints still route players to peers, and a GET_DATA for
+// a chunk nobody here knows the owner of is answered ERR_CENTRAL_UNAVAILABLE
+// with a RetryAfterMs and its chunk queued. Once central answers again the
+// queued chunks are claimed from it (resolveClaims), so the players' retries
+// find them here or are sent on to their owners as usual. With failover on
+// in central, though, writes to owned chunks stop once the heartbeat lease
+// runs out (see failover.go), since central may hand them on meanwhile.
+
+var (
+	centralRetries    = 2
+	centralBackoff    = 100 * time.Millisecond
+	centralBreakAfter = 5
+	centralCooldown   = 5 * time.Second
+)
+
+const (
+	maxCentralBackoff = time.Second
+	maxPendingClaims  = 256
+	claimKeep         = time.Minute // a queued chunk nobody has asked about since is dropped
+)
+
+var errCentralOpen = errors.New("central server unreachable, not calling it for now")
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+func (s breakerState) String() string {
+	switch s {
+	case breakerOpen:
+		return "open"
+	case breakerHalfOpen:
+		return "half_open"
+	}
+	return "closed"
+}
+
+// Breaker counts the calls to central that failed in a row.
+type Breaker struct {
+	sync.Mutex
+	State    breakerState
+	Failures int
+	OpenedAt time.Time
+	Probing  bool // a half-open probe is in flight
+}
+
+var centralBreaker = &Breaker{}
+
+var (
+	centralRetriesTotal = metrics.NewCounterVec("game_central_retries_total",
+		"Calls to central tried again after a failure, by endpoint.", "endpoint")
+	centralRejectedTotal = metrics.NewCounterVec("game_central_rejected_total",
+		"Calls to central failed at once by the open breaker, by endpoint.", "endpoint")
+	_ = metrics.NewGaugeFunc("game_central_breaker",
+		"State of the breaker on calls to central: 1 for the current one.", "state", func() map[string]float64 {
+			centralBreaker.Lock()
+			defer centralBreaker.Unlock()
+			values := map[string]float64{}
+			for _, s := range []breakerState{breakerClosed, breakerOpen, breakerHalfOpen} {
+				values[s.String()] = 0
+			}
+			values[centralBreaker.State.String()] = 1
+			return values
+		})
+	claimsTotal = metrics.NewCounterVec("game_pending_claims_total",
+		"Chunks queued while central was out, by what became of them.", "result")
+	_ = metrics.NewGaugeFunc("game_pending_claims",
+		"Chunks waiting for central to come back to be claimed.", "", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			return map[string]float64{"": float64(len(pending_claims))}
+		})
+)
+
+// Allow reports whether a call may go to central now, or else how long
+// until it may.
+func (b *Breaker) Allow(now time.Time) (bool, time.Duration) {
+	b.Lock()
+	defer b.Unlock()
+	switch b.State {
+	case breakerOpen:
+		if wait := b.OpenedAt.Add(centralCooldown).Sub(now); wait > 0 {
+			return false, wait
+		}
+		b.State, b.Probing = breakerHalfOpen, true
+		log.Printf("🔌 Central breaker half-open, probing")
+		return true, 0
+	case breakerHalfOpen:
+		if b.Probing {
+			return false, centralBackoff
+		}
+		b.Probing = true
+	}
+	return true, 0
+}
+
+// Succeeded closes the breaker.
+func (b *Breaker) Succeeded() {
+	b.Lock()
+	defer b.Unlock()
+	if b.State != breakerClosed {
+		log.Printf("🔌 Central breaker closed, central is back")
+	}
+	b.State, b.Failures, b.Probing = breakerClosed, 0, false
+}
+
+// Failed counts a failed call, opening the breaker after centralBreakAfter
+// in a row or a failed probe.
+func (b *Breaker) Failed(now time.Time) {
+	b.Lock()
+	defer b.Unlock()
+	b.Failures++
+	if b.State == breakerHalfOpen || (b.State == breakerClosed && b.Failures >= centralBreakAfter) {
+		log.Printf("🔌 Central breaker open after %d failed calls, retrying in %v", b.Failures, centralCooldown)
+		b.State, b.OpenedAt = breakerOpen, now
+	}
+	b.Probing = false
+}
+
+// RetryAfter is how long a player refused for a central outage should wait.
+func (b *Breaker) RetryAfter(now time.Time) time.Duration {
+	b.Lock()
+	defer b.Unlock()
+	if b.State == breakerOpen {
+		return max(centralBackoff, b.OpenedAt.Add(centralCooldown).Sub(now))
+	}
+	return centralTimeout
+}
+
+// backoff returns how long to wait before retry n (from 0): centralBackoff
+// doubled n times, at most maxCentralBackoff, with full jitter.
+func backoff(n int) time.Duration {
+	d := centralBackoff << n
+	if d <= 0 || d > maxCentralBackoff {
+		d = maxCentralBackoff
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+// sleepCtx waits d, or less if ctx is done first, and reports whether it
+// waited it all.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return false
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// guarded by zone_map_Mu; chunks asked for while central was out, by when
+// they were last asked for
+var pending_claims = make(map[types.ChunkID]time.Time)
+
+// queueClaim queues chunk_id to be claimed once central is back. Must be
+// called with zone_map_Mu held.
+func queueClaim(chunk_id types.ChunkID, now time.Time) {
+	if _, ok := pending_claims[chunk_id]; !ok && len(pending_claims) >= maxPendingClaims {
+		claimsTotal.Inc("dropped")
+		return
+	}
+	pending_claims[chunk_id] = now
+}
+
+// resolveClaims asks central about the chunks queued while it was out,
+// while it answers. A chunk nobody owns becomes this server's, as if a
+// player had asked for it; a chunk owned elsewhere is left to the players'
+// retries, which are sent on to the owner.
+func resolveClaims() {
+	zone_map_Mu.Lock()
+	now := time.Now()
+	queued := make([]types.ChunkID, 0, len(pending_claims))
+	for chunk_id, at := range pending_claims {
+		if _, held := zone_map[chunk_id]; held {
+			delete(pending_claims, chunk_id)
+			claimsTotal.Inc("held")
+			continue
+		}
+		if now.Sub(at) > claimKeep {
+			delete(pending_claims, chunk_id)
+			claimsTotal.Inc("expired")
+			continue
+		}
+		queued = append(queued, chunk_id)
+	}
+	zone_map_Mu.Unlock()
+
+	for _, chunk_id := range queued {
+		res, err := callCentral(context.Background(), "/chunk", types.Request{Type: types.ReqGetChunk, ChunkID: chunk_id, CallerIP: serverIP})
+		if errors.Is(err, errCentralOpen) {
+			return
+		}
+		if err != nil {
+			log.Printf("Claiming chunk [%d,%d] from central failed: %v", chunk_id.IDX, chunk_id.IDY, err)
+			return
+		}
+		zone_map_Mu.Lock()
+		delete(pending_claims, chunk_id)
+		_, held := zone_map[chunk_id]
+		switch {
+		case res.Code == types.CodeChunkSplit:
+			learnSplits(res.Splits)
+			claimsTotal.Inc("split")
+		case held:
+			claimsTotal.Inc("held")
+		case res.Code == types.CodeNotFound || (res.Success && res.Message != serverIP):
+			claimsTotal.Inc("elsewhere")
+		case !res.Success:
+			zone_map[chunk_id] = types.Chunk{World: chunk_id.World, IDX: chunk_id.IDX, IDY: chunk_id.IDY, Level: chunk_id.Level, Data: "new chunk",
+				ServerIP: serverIP, Cells: generator.Generate(chunk_id, chunk_id.Edge(world.ChunkSize))}
+			journal.Record(WorldEvent{Type: "CHUNK_CREATE", ChunkID: chunk_id, Detail: "claimed after a central outage"})
+			claimsTotal.Inc("claimed")
+		default:
+			chunk := res.Chunk
+			chunk.World, chunk.IDX, chunk.IDY, chunk.Level = chunk_id.World, chunk_id.IDX, chunk_id.IDY, chunk_id.Level
+			chunk.ServerIP = serverIP
+			zone_map[chunk_id] = chunk
+			publish(ChunkMigrated{ChunkID: chunk_id, Direction: "in", Detail: "claimed after a central outage"})
+			claimsTotal.Inc("claimed")
+		}
+		zone_map_Mu.Unlock()
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"math"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chat =====================
+
+// CHAT with Text sends a message to the players around the sender: everyone
+// on this server in the same chunk, and, if the sender has an AOIRadius,
+// everyone here within it. Each gets it pushed at once as a PUSH_CHAT
+// message; the sender gets it in the reply. Messages are numbered per chunk
+// and the last chatHistory are kept, so a CHAT can also ask for those after
+// ChatSince, which is how players without a steady UDP address (the HTTP
+// gateway's) and players whose push was lost catch up; a CHAT without Text
+// only reads. Every player may send chatRate messages a second with bursts
+// of chatBurst, of at most chatMaxLen bytes, and words from the -chat-filter
+// list are masked.
+
+const chatHistory = 50
+
+var (
+	chatRate   = 1.0
+	chatBurst  = 5.0
+	chatMaxLen = 200
+	// words masked in messages, from -chat-filter; nil filters nothing
+	chatFilter *regexp.Regexp
+)
+
+type ChatLog struct {
+	Seq      uint64
+	Messages []types.ChatMessage
+}
+
+type ChatBucket struct {
+	Tokens float64
+	At     time.Time
+}
+
+// guarded by zone_map_Mu
+var (
+	chat_logs    = make(map[types.ChunkID]*ChatLog)
+	chat_buckets = make(map[string]ChatBucket)
+)
+
+var chatMessagesTotal = metrics.NewCounterVec("game_chat_messages_total",
+	"CHAT messages by outcome: sent, rate_limited or too_long.", "result")
+
+// loadChatFilter reads the words to mask, one per line, from path.
+func loadChatFilter(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if word := strings.TrimSpace(scanner.Text()); word != "" && !strings.HasPrefix(word, "#") {
+			words = append(words, regexp.QuoteMeta(word))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	if len(words) > 0 {
+		chatFilter = regexp.MustCompile(`(?i)\b(` + strings.Join(words, "|") + `)\b`)
+	}
+	return nil
+}
+
+// filterChat masks the filtered words in text.
+func filterChat(text string) string {
+	if chatFilter == nil {
+		return text
+	}
+	return chatFilter.ReplaceAllStringFunc(text, func(word string) string {
+		return strings.Repeat("*", len([]rune(word)))
+	})
+}
+
+// allowChat takes a token from player_id's bucket, if there is one. Must be
+// called with zone_map_Mu held.
+func allowChat(player_id string, now time.Time) bool {
+	bucket, ok := chat_buckets[player_id]
+	if !ok {
+		bucket = ChatBucket{Tokens: chatBurst, At: now}
+	}
+	bucket.Tokens = math.Min(chatBurst, bucket.Tokens+now.Sub(bucket.At).Seconds()*chatRate)
+	bucket.At = now
+	allowed := bucket.Tokens >= 1
+	if allowed {
+		bucket.Tokens--
+	}
+	chat_buckets[player_id] = bucket
+	return allowed
+}
+
+func handleChat(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player_id := req.Player.ID
+	chunk_id, held := players[player_id]
+	if !held {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+	if req.Text == "" {
+		reply(conn, addr, req, types.Response{Success: true, Message: "Chat history", ChunkID: &chunk_id, Chat: chatSince(chunk_id, req.ChatSince)})
+		return
+	}
+	if len(req.Text) > chatMaxLen {
+		chatMessagesTotal.Inc("too_long")
+		reply(conn, addr, req, types.Response{Success: false, Message: "Message too long", Code: types.CodeBadRequest})
+		return
+	}
+	now := time.Now()
+	if !allowChat(player_id, now) {
+		chatMessagesTotal.Inc("rate_limited")
+		reply(conn, addr, req, types.Response{Success: false, Message: "Too many messages, slow down", Code: types.CodeRateLimited,
+			RetryAfterMs: int64(1000 / chatRate)})
+		return
+	}
+
+	history, ok := chat_logs[chunk_id]
+	if !ok {
+		history = &ChatLog{}
+		chat_logs[chunk_id] = history
+	}
+	history.Seq++
+	msg := types.ChatMessage{Seq: history.Seq, From: player_id, ChunkID: chunk_id, Text: filterChat(req.Text), SentMs: now.UnixMilli()}
+	history.Messages = append(history.Messages, msg)
+	if len(history.Messages) > chatHistory {
+		history.Messages = history.Messages[len(history.Messages)-chatHistory:]
+	}
+	chatMessagesTotal.Inc("sent")
+
+	pushed := pushChat(conn, msg, player_id)
+	reply(conn, addr, req, types.Response{Success: true, Message: "Message sent", ChunkID: &chunk_id, Chat: chatSince(chunk_id, req.ChatSince)})
+	netproto.Tracef(req.TraceID, "💬 %s in chunk [%d,%d] to %d players: %q", player_id, chunk_id.IDX, chunk_id.IDY, pushed, msg.Text)
+}
+
+// chatSince returns chunk_id's kept messages after seq. Must be called with
+// zone_map_Mu held.
+func chatSince(chunk_id types.ChunkID, seq uint64) []types.ChatMessage {
+	history, ok := chat_logs[chunk_id]
+	if !ok {
+		return nil
+	}
+	var list []types.ChatMessage
+	for _, msg := range history.Messages {
+		if msg.Seq > seq {
+			list = append(list, msg)
+		}
+	}
+	return list
+}
+
+// pushChat sends msg to the players around its sender and returns how many
+// it went to. Must be called with zone_map_Mu held.
+func pushChat(conn netproto.Transport, msg types.ChatMessage, sender_id string) int {
+	sender := player_map[sender_id]
+	radius := float64(sender.AOIRadius)
+
+	notice := types.Response{Success: true, Code: types.CodeChat, Message: "Chat from " + sender_id, Chat: []types.ChatMessage{msg}}
+	stampClock(&notice)
+	pushed := 0
+	for player_id, chunk_id := range players {
+		if player_id == sender_id {
+			continue
+		}
+		if chunk_id != msg.ChunkID {
+			other := player_map[player_id]
+			if radius <= 0 || math.Hypot(float64(other.PosX-sender.PosX), float64(other.PosY-sender.PosY)) > radius {
+				continue
+			}
+		}
+		if player_addr, ok := player_addrs[player_id]; ok {
+			push(conn, player_addr, notice)
+			pushed++
+		}
+	}
+	return pushed
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/hlc"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Hybrid logical clock =====================
+
+// Every cube edit and player update is stamped from this server's hybrid
+// logical clock, which follows wall time but is moved past every stamp
+// that arrives from a peer (in a MERGE, a replica sync, a player handoff
+// or a forwarded move), so a write made after seeing another is stamped
+// later even when this server's clock runs behind. Where two copies of a
+// chunk meet, the later stamp wins each cube and player; removed cubes
+// leave tombstones, so a stale copy cannot bring them back. Stamps further
+// ahead than -hlc-max-offset are not followed.
+
+var clock = hlc.New(time.Second)
+
+var hlcUpdatesTotal = metrics.NewCounterVec("game_hlc_updates_total",
+	"Peer timestamps merged into this server's hybrid logical clock, by result: ok, or ahead (beyond -hlc-max-offset, ignored).", "result")
+
+// observeHLC moves the clock past a stamp from a peer.
+func observeHLC(remote types.HLC, from string) {
+	if remote == 0 {
+		return
+	}
+	if _, err := clock.Update(remote); err != nil {
+		hlcUpdatesTotal.Inc("ahead")
+		log.Printf("⏱️  Ignoring clock of %s: %v", from, err)
+		return
+	}
+	hlcUpdatesTotal.Inc("ok")
+}
+
+// observeChunk moves the clock past the latest stamp in a peer's chunk.
+func observeChunk(chunk types.Chunk, from string) {
+	observeHLC(chunkstore.LastHLC(chunk), from)
+}
+
+// stampPlayer stamps an update of player, unless a peer forwarded it
+// already stamped.
+func stampPlayer(player *types.Player, from_peer bool) {
+	if from_peer && player.HLC != 0 {
+		observeHLC(player.HLC, player.ServerIP)
+		return
+	}
+	player.HLC = clock.Now()
+}
+
+// addCube stamps cube and adds it to chunk, returning it as stored. Must
+// be called with zone_map_Mu held.
+func addCube(chunk_id types.ChunkID, chunk *types.Chunk, cube types.Cube) types.Cube {
+	cube.HLC = clock.Now()
+	cubeIndex(chunk_id, *chunk).Add(chunk, cube)
+	return cube
+}
+
+// removeCube removes the cube with the given ID from chunk and leaves a
+// tombstone. Must be called with zone_map_Mu held.
+func removeCube(chunk_id types.ChunkID, chunk *types.Chunk, cube_id string) (types.Cube, bool) {
+	cube, ok := cubeIndex(chunk_id, *chunk).Remove(chunk, cube_id)
+	if ok {
+		chunkstore.Bury(chunk, cube_id, clock.Now())
+	}
+	return cube, ok
+}
@@ -0,0 +1,347 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/eventstream"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Event bus =====================
+
+// Handlers publish what happened to the world as typed events: a player
+// moved or left, a cube was added or removed, a chunk migrated in or out.
+// What follows from it (the edit history, the audit journal, player stats,
+// republishing chunk views and warning spectators, the external sinks)
+// is done by subscribers, so a handler states the fact once and a new side
+// effect is one more subscriber rather than a change to every handler.
+//
+// publish calls the subscribers in the order they subscribed, synchronously
+// and with zone_map_Mu held: they see the world as the handler left it and
+// must not block. Anything slow (the external sinks) is queued and sent
+// from its own goroutine.
+
+// A GameEvent is one fact published on the bus.
+type GameEvent interface {
+	EventType() string
+}
+
+// PlayerMoved is a MOVE applied here. From is where the player was held here
+// before, nil for a player new to this server.
+type PlayerMoved struct {
+	Player  types.Player  `json:"player"`
+	From    *types.Player `json:"from,omitempty"`
+	Addr    string        `json:"addr,omitempty"`
+	TraceID string        `json:"trace_id,omitempty"`
+}
+
+// CubeAdded is a cube placed in an owned chunk. Edits made by one request
+// share Batch.
+type CubeAdded struct {
+	ChunkID  types.ChunkID `json:"chunk_id"`
+	Cube     types.Cube    `json:"cube"`
+	PlayerID string        `json:"player_id,omitempty"`
+	Batch    int64         `json:"batch"`
+	Addr     string        `json:"addr,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	TraceID  string        `json:"trace_id,omitempty"`
+}
+
+// CubeRemoved is a cube taken out of an owned chunk.
+type CubeRemoved struct {
+	ChunkID  types.ChunkID `json:"chunk_id"`
+	Cube     types.Cube    `json:"cube"`
+	PlayerID string        `json:"player_id,omitempty"`
+	Batch    int64         `json:"batch"`
+	Addr     string        `json:"addr,omitempty"`
+	Detail   string        `json:"detail,omitempty"`
+	TraceID  string        `json:"trace_id,omitempty"`
+}
+
+// ChunkMigrated is a chunk this server took over ("in") or handed to
+// another ("out").
+type ChunkMigrated struct {
+	ChunkID   types.ChunkID `json:"chunk_id"`
+	Direction string        `json:"direction"`
+	PlayerID  string        `json:"player_id,omitempty"` // whose entering set it off, if anyone's
+	Peer      string        `json:"peer,omitempty"`      // the server it came from or went to, if known
+	Addr      string        `json:"addr,omitempty"`
+	Detail    string        `json:"detail,omitempty"`
+	TraceID   string        `json:"trace_id,omitempty"`
+}
+
+// PlayerLeft is a player dropped from this server, for Reason. Player is
+// their last known state.
+type PlayerLeft struct {
+	Player  types.Player  `json:"player"`
+	ChunkID types.ChunkID `json:"chunk_id"`
+	Reason  string        `json:"reason"`
+}
+
+func (PlayerMoved) EventType() string   { return "PlayerMoved" }
+func (CubeAdded) EventType() string     { return "CubeAdded" }
+func (CubeRemoved) EventType() string   { return "CubeRemoved" }
+func (ChunkMigrated) EventType() string { return "ChunkMigrated" }
+func (PlayerLeft) EventType() string    { return "PlayerLeft" }
+
+type subscriber struct {
+	name string
+	fn   func(GameEvent)
+}
+
+// subscribers are set up before the server starts serving; see init below
+// and the external sinks.
+var subscribers []subscriber
+
+var eventsTotal = metrics.NewCounterVec("game_events_total",
+	"Events published on the game server's event bus, by type.", "type")
+
+// Subscribe has fn called with every event published from now on. Must be
+// called before the server starts serving.
+func Subscribe(name string, fn func(GameEvent)) {
+	subscribers = append(subscribers, subscriber{name: name, fn: fn})
+}
+
+// publish hands ev to every subscriber in turn. Must be called with
+// zone_map_Mu held.
+func publish(ev GameEvent) {
+	eventsTotal.Inc(ev.EventType())
+	for _, s := range subscribers {
+		s.fn(ev)
+	}
+}
+
+func init() {
+	Subscribe("history", historySubscriber)
+	Subscribe("stats", statsSubscriber)
+	Subscribe("journal", journalSubscriber)
+	Subscribe("views", viewsSubscriber)
+}
+
+// historySubscriber keeps the undo history of cube edits.
+func historySubscriber(ev GameEvent) {
+	switch ev := ev.(type) {
+	case CubeAdded:
+		recordEdit(ev.ChunkID, ev.Batch, ev.PlayerID, "add", ev.Cube)
+	case CubeRemoved:
+		recordEdit(ev.ChunkID, ev.Batch, ev.PlayerID, "remove", ev.Cube)
+	}
+}
+
+// statsSubscriber counts what players did, and the migrations the
+// experiment reports, and the departures capacity estimates waits from.
+func statsSubscriber(ev GameEvent) {
+	switch ev := ev.(type) {
+	case PlayerMoved:
+		countMove(ev.Player, ev.From)
+	case CubeAdded:
+		countStats(ev.PlayerID, types.PlayerStats{CubesPlaced: 1})
+	case CubeRemoved:
+		countStats(ev.PlayerID, types.PlayerStats{CubesRemoved: 1})
+	case ChunkMigrated:
+		migrationsTotal.Inc(ev.Direction)
+		if ev.Direction == "out" {
+			expMigrations++
+		}
+	case PlayerLeft:
+		recordDeparture(ev.ChunkID)
+	}
+}
+
+// journalSubscriber writes the world-changing events to the audit journal.
+// Moves are too frequent to journal.
+func journalSubscriber(ev GameEvent) {
+	switch ev := ev.(type) {
+	case CubeAdded:
+		cube := ev.Cube
+		journal.Record(WorldEvent{Type: "ADD_CUBE", PlayerID: ev.PlayerID, Addr: ev.Addr, ChunkID: ev.ChunkID,
+			CubeID: cube.ID, Cube: &cube, Detail: ev.Detail, TraceID: ev.TraceID})
+	case CubeRemoved:
+		cube := ev.Cube
+		journal.Record(WorldEvent{Type: "DLT_CUBE", PlayerID: ev.PlayerID, Addr: ev.Addr, ChunkID: ev.ChunkID,
+			CubeID: cube.ID, Cube: &cube, Detail: ev.Detail, TraceID: ev.TraceID})
+	case ChunkMigrated:
+		journal.Record(WorldEvent{Type: "MIGRATE_" + strings.ToUpper(ev.Direction), PlayerID: ev.PlayerID, Addr: ev.Addr,
+			ChunkID: ev.ChunkID, Detail: ev.Detail, TraceID: ev.TraceID})
+	case PlayerLeft:
+		journal.Record(WorldEvent{Type: "LEAVE", PlayerID: ev.Player.ID, ChunkID: ev.ChunkID, Detail: ev.Reason})
+	}
+}
+
+// viewsSubscriber flags the chunks whose published view, replicas and saved
+// copy the event changed, and has spectators of a chunk that moved away told
+// on the next tick rather than at their chunk's usual pace.
+func viewsSubscriber(ev GameEvent) {
+	switch ev := ev.(type) {
+	case PlayerMoved:
+		if ev.From != nil {
+			view_dirty[ev.From.ChunkID] = true
+		}
+		view_dirty[ev.Player.ChunkID] = true
+	case CubeAdded:
+		markUnsaved(ev.ChunkID)
+	case CubeRemoved:
+		markUnsaved(ev.ChunkID)
+	case ChunkMigrated:
+		view_dirty[ev.ChunkID] = true
+		if ev.Direction == "out" {
+			delete(spectate_next, ev.ChunkID)
+		}
+	case PlayerLeft:
+		view_dirty[ev.ChunkID] = true
+	}
+}
+
+// ===================== External sinks =====================
+
+// Events can also leave the server, for scripts and pipelines outside it:
+// with -event-hook they are POSTed to a URL as JSON arrays of HookEvents,
+// and with -event-stream each is published to NATS or Kafka on its own
+// topic, PREFIX.WORLD.TYPE (-event-stream-prefix), keyed by the chunk or
+// player it is about. Either way events are encoded when published and
+// queued, and sent from the sink's own goroutine up to eventSinkBatch at a
+// time, in order; if a sink falls eventSinkQueue behind, newer events are
+// dropped (and counted) rather than holding up the server.
+
+// HookEvent is one event as the sinks send it.
+type HookEvent struct {
+	Type   string          `json:"type"`
+	Server string          `json:"server"`
+	TimeMs int64           `json:"time_ms"`
+	Event  json.RawMessage `json:"event"`
+
+	key string // the stream's message key
+}
+
+const (
+	eventSinkQueue   = 4096
+	eventSinkBatch   = 256
+	eventSinkTimeout = 2 * time.Second
+)
+
+var eventSinkTotal = metrics.NewCounterVec("game_event_sink_events_total",
+	"Events for the external sinks, by sink and outcome: sent, dropped (queue full) or failed, e.g. hook_sent.", "result")
+
+// eventKey is what ev is about: its chunk, or its player.
+func eventKey(ev GameEvent) string {
+	chunkKey := func(id types.ChunkID) string {
+		return fmt.Sprintf("%s:%d:%d:%d", id.World, id.Level, id.IDX, id.IDY)
+	}
+	switch ev := ev.(type) {
+	case PlayerMoved:
+		return ev.Player.ID
+	case PlayerLeft:
+		return ev.Player.ID
+	case CubeAdded:
+		return chunkKey(ev.ChunkID)
+	case CubeRemoved:
+		return chunkKey(ev.ChunkID)
+	case ChunkMigrated:
+		return chunkKey(ev.ChunkID)
+	}
+	return ""
+}
+
+// startEventSink subscribes a queue for the sink called name and starts
+// sending it with send.
+func startEventSink(name, target string, send func(batch []HookEvent) error) {
+	queue := make(chan HookEvent, eventSinkQueue)
+	Subscribe(name, func(ev GameEvent) {
+		data, err := json.Marshal(ev)
+		if err != nil {
+			return
+		}
+		select {
+		case queue <- HookEvent{Type: ev.EventType(), Server: serverIP, TimeMs: time.Now().UnixMilli(), Event: data, key: eventKey(ev)}:
+		default:
+			eventSinkTotal.Inc(name + "_dropped")
+		}
+	})
+	go runEventSink(name, target, queue, send)
+}
+
+func runEventSink(name, target string, queue chan HookEvent, send func(batch []HookEvent) error) {
+	failing := false
+	for ev := range queue {
+		batch := []HookEvent{ev}
+	fill:
+		for len(batch) < eventSinkBatch {
+			select {
+			case ev := <-queue:
+				batch = append(batch, ev)
+			default:
+				break fill
+			}
+		}
+
+		err := send(batch)
+		if err != nil {
+			eventSinkTotal.Add(name+"_failed", float64(len(batch)))
+			if !failing {
+				log.Printf("⚠️  Event %s %s failed, dropping events until it answers: %v", name, target, err)
+			}
+		} else {
+			eventSinkTotal.Add(name+"_sent", float64(len(batch)))
+			if failing {
+				log.Printf("✅ Event %s %s answering again", name, target)
+			}
+		}
+		failing = err != nil
+	}
+}
+
+// startEventHook POSTs events to url.
+func startEventHook(url string) {
+	client := &http.Client{}
+	startEventSink("hook", url, func(batch []HookEvent) error {
+		return postEvents(client, url, batch)
+	})
+}
+
+func postEvents(client *http.Client, url string, batch []HookEvent) error {
+	body, err := json.Marshal(batch)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), eventSinkTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	res.Body.Close()
+	if res.StatusCode >= 300 {
+		return fmt.Errorf("%s: %s", url, res.Status)
+	}
+	return nil
+}
+
+// startEventStream publishes events with pub, on topics under prefix.
+func startEventStream(pub eventstream.Publisher, target, prefix string) {
+	startEventSink("stream", target, func(batch []HookEvent) error {
+		msgs := make([]eventstream.Message, 0, len(batch))
+		for _, ev := range batch {
+			value, err := json.Marshal(ev)
+			if err != nil {
+				continue
+			}
+			msgs = append(msgs, eventstream.Message{Topic: prefix + "." + eventstream.Topic(world.Name, ev.Type), Key: []byte(ev.key), Value: value})
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), eventSinkTimeout)
+		defer cancel()
+		return pub.Publish(ctx, msgs)
+	})
+}
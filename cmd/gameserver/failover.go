@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Failover =====================
+
+// When central declares a game server dead it hands each of its chunks to a
+// live server with ADOPT_CHUNK; it also uses it to give a new chunk to the
+// owner of its region. The new owner starts from the best copy it
+// has: its read replica copy, else whatever copy it last saw, else a freshly
+// generated chunk. Edits the dead owner made since are lost. Players listed
+// in the copy who are not on this server are dropped; they rejoin through
+// GET_DATA like anyone else.
+
+func handleAdoptChunk(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	if epoch := lease_epoch.Load(); req.Epoch != 0 && epoch != 0 && req.Epoch != epoch {
+		// meant for an earlier incarnation of this server
+		reply(conn, addr, req, types.Response{Success: false, Message: "Adoption is for another epoch", Code: types.CodeFenced, Epoch: epoch})
+		return
+	}
+	chunk, ok := zone_map[chunk_id]
+	source := "its last copy"
+	if ok && chunk.ServerIP == serverIP {
+		reply(conn, addr, req, types.Response{Success: true, Message: "Chunk already owned"})
+		return
+	}
+	if replica, has := replica_copies[chunk_id]; has {
+		// expired copies are kept for this; see replicaKeep
+		chunk, ok, source = replica.Chunk, true, "its replica copy"
+	}
+	if !ok {
+		chunk = types.Chunk{World: chunk_id.World, IDX: chunk_id.IDX, IDY: chunk_id.IDY, Level: chunk_id.Level, Data: "new chunk",
+			Cells: generator.Generate(chunk_id, chunk_id.Edge(world.ChunkSize))}
+		source = "a generated chunk"
+	}
+
+	kept := chunk.PlayerList[:0:0]
+	for _, player := range chunk.PlayerList {
+		if in, known := players[player.ID]; known && in == chunk_id {
+			kept = append(kept, player)
+		}
+	}
+	chunk.PlayerList = kept
+	chunk.ServerIP = serverIP
+	chunk.IsDirty = true
+	zone_map[chunk_id] = chunk
+	delete(replica_copies, chunk_id)
+	delete(cube_indexes, chunk_id)
+
+	publish(ChunkMigrated{ChunkID: chunk_id, Direction: "in", Addr: addr, Detail: "adopted, " + req.Reason, TraceID: req.TraceID})
+	reply(conn, addr, req, types.Response{Success: true, Message: "Chunk adopted"})
+	log.Printf("🩹 Adopted chunk [%d,%d], starting from %s (%s)", chunk_id.IDX, chunk_id.IDY, source, req.Reason)
+}
+
+// ===================== Leases =====================
+
+// Central only trusts this server with its chunks while it hears from it
+// (see failover on central). Every heartbeat (reportAlive) carries the
+// epoch this server joined central as, and a reply grants a lease of
+// LeaseMs, timed from before the call. Heartbeats go out heartbeatsPerLease
+// times a lease from a goroutine of their own (heartbeatLoop), so that
+// neither a slow tick rate nor a handler holding zone_map_Mu can let the
+// lease lapse. Once the lease runs out without
+// another, central may be about to hand the chunks to someone else, so
+// writes to them are refused (refuseExpired) and nothing is saved until a
+// heartbeat gets through. If central has already declared this server dead
+// it answers ERR_FENCED: the server drops every chunk it owns, since they
+// now belong to others, and rejoins as a new epoch. A central with no record
+// of it (a fresh one) is answered with a rejoin that keeps the chunks.
+// LeaseMs 0 (failover off on central) turns leases off.
+
+var (
+	lease_epoch atomic.Uint64 // 0 until joined
+	lease_until atomic.Int64  // unix nanos; 0 while leases are off
+	lease_ms    atomic.Int64  // the last lease granted; 0 while leases are off
+)
+
+const (
+	// leaseMargin is the part of a lease used, for clock rate drift.
+	leaseMargin = 0.9
+	// heartbeatsPerLease is how many heartbeats go out in one lease, so
+	// that a lost one or two do not let it lapse.
+	heartbeatsPerLease = 4
+)
+
+// heartbeatLoop sends heartbeats for as long as the server runs: every
+// lease/heartbeatsPerLease while leases are on, otherwise every idle.
+func heartbeatLoop(idle time.Duration) {
+	for {
+		every := idle
+		if lease := lease_ms.Load(); lease > 0 {
+			every = time.Duration(lease) * time.Millisecond / heartbeatsPerLease
+		}
+		time.Sleep(every)
+		reportAlive()
+	}
+}
+
+// reportAlive tells central this server is alive, renewing its lease.
+func reportAlive() {
+	sent := time.Now()
+	res, err := callCentral(context.Background(), "/heartbeat", types.Request{CallerIP: serverIP, Epoch: lease_epoch.Load()})
+	if err != nil {
+		log.Printf("⚠️  Heartbeat to central failed: %v", err)
+		return
+	}
+	switch res.Code {
+	case types.CodeFenced:
+		zone_map_Mu.Lock()
+		dropped := dropOwnedChunks()
+		zone_map_Mu.Unlock()
+		log.Printf("⛔ Fenced off by central (%s), dropped %d chunks", res.Message, dropped)
+		rejoin()
+	case types.CodeNotFound:
+		rejoin()
+	default:
+		if res.Success {
+			grantLease(sent, res.LeaseMs)
+		}
+	}
+}
+
+// rejoin starts a new incarnation of this server on central.
+func rejoin() {
+	sent := time.Now()
+	res, err := callCentral(context.Background(), "/rejoin", types.Request{CallerIP: serverIP})
+	if err != nil || !res.Success {
+		log.Printf("⚠️  Joining central failed: %v %s", err, res.Message)
+		return
+	}
+	lease_epoch.Store(res.Epoch)
+	grantLease(sent, res.LeaseMs)
+	log.Printf("🪪 Joined central as epoch %d", res.Epoch)
+}
+
+func grantLease(sent time.Time, granted_ms int64) {
+	lease_ms.Store(max(granted_ms, 0))
+	if granted_ms <= 0 {
+		lease_until.Store(0)
+		return
+	}
+	lease := time.Duration(float64(granted_ms) * leaseMargin * float64(time.Millisecond))
+	lease_until.Store(sent.Add(lease).UnixNano())
+}
+
+// leaseExpired reports whether this server's lease on its chunks has run
+// out.
+func leaseExpired(now time.Time) bool {
+	until := lease_until.Load()
+	return until != 0 && now.UnixNano() > until
+}
+
+// dropOwnedChunks gives up every chunk this server owns, unsaved edits
+// included, and returns how many. Must be called with zone_map_Mu held.
+func dropOwnedChunks() int {
+	dropped := 0
+	for chunk_id, chunk := range zone_map {
+		if chunk.ServerIP != serverIP {
+			continue
+		}
+		chunk.ServerIP = ""
+		chunk.IsDirty = false
+		zone_map[chunk_id] = chunk
+		delete(unsaved, chunk_id)
+		view_dirty[chunk_id] = true
+		dropped++
+	}
+	return dropped
+}
+
+// refuseExpired turns away writes to owned chunks while the lease is out.
+func refuseExpired(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if txGuarded[req.Type] && zone_map[req.ChunkID].ServerIP == serverIP {
+			if now := time.Now(); leaseExpired(now) {
+				requestsRefusedTotal.Inc("lease")
+				reply(conn, addr, req, types.Response{Success: false, Message: "Lease from central ran out, retry shortly", Code: types.CodeCentralUnavailable,
+					RetryAfterMs: centralBreaker.RetryAfter(now).Milliseconds()})
+				return
+			}
+		}
+		next(ctx, req, conn, addr)
+	}
+}
@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Gossip =====================
+
+// Game servers started with -peers gossip with each other (GOSSIP). Every
+// gossipInterval a server bumps its own heartbeat and swaps member lists with
+// up to gossipFanout random live peers, or the seeds while it knows too few,
+// so one seed is enough to learn the whole cluster. Each entry carries the
+// server's load and the chunks it owns in memory. A member whose heartbeat
+// stops moving for memberDeadAfter is dead, and forgotten after
+// memberForgetAfter.
+//
+// The owned chunks double as ownership hints. A player asking for a chunk
+// nobody here is in, and which a live member claims, is merged straight into
+// that owner (MERGE with OwnerHint) instead of asking central at /chunk;
+// central would only have named the owner. A refused or failed merge, or two
+// members claiming the same chunk, falls back to central.
+
+// gossipSeeds are the -peers addresses; gossip is off without them.
+var (
+	gossipSeeds    []string
+	gossipInterval = time.Second
+)
+
+const (
+	gossipFanout      = 2
+	memberDeadAfter   = 10 * time.Second
+	memberForgetAfter = time.Minute
+	gossipMaxChunks   = 256 // owned chunks advertised, to keep GOSSIP within a datagram
+	gossipMaxParty    = 64  // party members advertised, likewise
+)
+
+type Member struct {
+	State   types.MemberState
+	Updated time.Time // when Heartbeat last went up
+}
+
+// guarded by zone_map_Mu
+var (
+	members     = make(map[string]*Member)
+	owner_hints = make(map[types.ChunkID]string) // "" where live members disagree
+	// starts at the clock so a restarted server outranks what peers remember
+	heartbeat   = uint64(time.Now().UnixMilli())
+	gossip_last time.Time
+)
+
+var (
+	gossipRoundsTotal = metrics.NewCounterVec("game_gossip_rounds_total",
+		"GOSSIP exchanges started with peers, by result.", "result")
+	gossipRoutesTotal = metrics.NewCounterVec("game_gossip_routes_total",
+		"GET_DATA requests routed on a gossiped ownership hint instead of central, by result.", "result")
+	_ = metrics.NewGaugeFunc("game_gossip_members",
+		"Game servers known through gossip, by state.", "state", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			values := map[string]float64{"alive": 0, "dead": 0}
+			now := time.Now()
+			for _, member := range members {
+				if member.alive(now) {
+					values["alive"]++
+				} else {
+					values["dead"]++
+				}
+			}
+			return values
+		})
+)
+
+func (m *Member) alive(now time.Time) bool {
+	return now.Sub(m.Updated) < memberDeadAfter
+}
+
+// selfState describes this server for gossip. Must be called with
+// zone_map_Mu held.
+func selfState() types.MemberState {
+	var chunks []types.ChunkID
+	for chunk_id, chunk := range zone_map {
+		if chunk.ServerIP == serverIP {
+			chunks = append(chunks, chunk_id)
+		}
+	}
+	sort.Slice(chunks, func(i, j int) bool {
+		a, b := chunks[i], chunks[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.IDX != b.IDX {
+			return a.IDX < b.IDX
+		}
+		return a.IDY < b.IDY
+	})
+	if len(chunks) > gossipMaxChunks {
+		chunks = chunks[:gossipMaxChunks]
+	}
+	return types.MemberState{Addr: serverIP, Heartbeat: heartbeat, Players: len(players), Chunks: chunks, PartyPlayers: partyPlayers()}
+}
+
+// memberList is this server's view of the cluster: itself and every live
+// member. Must be called with zone_map_Mu held.
+func memberList(now time.Time) []types.MemberState {
+	list := []types.MemberState{selfState()}
+	for _, member := range members {
+		if member.alive(now) {
+			list = append(list, member.State)
+		}
+	}
+	return list
+}
+
+// mergeMembers folds a peer's member list into ours. Must be called with
+// zone_map_Mu held.
+func mergeMembers(list []types.MemberState, now time.Time) {
+	changed := false
+	for _, state := range list {
+		if state.Addr == "" || state.Addr == serverIP {
+			continue
+		}
+		member, ok := members[state.Addr]
+		if !ok {
+			log.Printf("🤝 Learned about game server %s through gossip", state.Addr)
+			members[state.Addr] = &Member{State: state, Updated: now}
+			changed = true
+			continue
+		}
+		if state.Heartbeat > member.State.Heartbeat {
+			if !member.alive(now) {
+				log.Printf("🤝 Game server %s is back", state.Addr)
+			}
+			member.State = state
+			member.Updated = now
+			changed = true
+		}
+	}
+	if changed {
+		rebuildHints(now)
+	}
+}
+
+// rebuildHints recomputes owner_hints from the live members. Must be called
+// with zone_map_Mu held.
+func rebuildHints(now time.Time) {
+	owner_hints = make(map[types.ChunkID]string)
+	for addr, member := range members {
+		if !member.alive(now) {
+			continue
+		}
+		for _, chunk_id := range member.State.Chunks {
+			if other, ok := owner_hints[chunk_id]; ok && other != addr {
+				owner_hints[chunk_id] = ""
+			} else {
+				owner_hints[chunk_id] = addr
+			}
+		}
+	}
+}
+
+// gossipOwner returns the live member gossip says owns chunk_id. Must be
+// called with zone_map_Mu held.
+func gossipOwner(chunk_id types.ChunkID) (string, bool) {
+	owner := owner_hints[chunk_id]
+	return owner, owner != "" && owner != serverIP
+}
+
+// gossipTick runs a gossip round once per gossipInterval. Must be called with
+// zone_map_Mu held.
+func gossipTick(now time.Time) {
+	if len(gossipSeeds) == 0 && len(members) == 0 || now.Sub(gossip_last) < gossipInterval {
+		return
+	}
+	gossip_last = now
+	heartbeat++
+
+	for addr, member := range members {
+		if now.Sub(member.Updated) > memberForgetAfter {
+			log.Printf("👋 Forgot game server %s, silent since %s", addr, member.Updated.Format(time.TimeOnly))
+			delete(members, addr)
+		}
+	}
+	rebuildHints(now)
+
+	req := types.Request{Type: types.ReqGossip, CallerIP: serverIP, Members: memberList(now)}
+	for _, target := range gossipTargets(now) {
+		go func(target string) {
+			res, err := netproto.RoundTrip(network, target, req, peerTimeout)
+			if err != nil {
+				gossipRoundsTotal.Inc("error")
+				return
+			}
+			gossipRoundsTotal.Inc("ok")
+			zone_map_Mu.Lock()
+			mergeMembers(res.Members, time.Now())
+			zone_map_Mu.Unlock()
+		}(target)
+	}
+}
+
+// gossipTargets picks up to gossipFanout peers for a round: live members,
+// topped up with seeds while too few are known. Must be called with
+// zone_map_Mu held.
+func gossipTargets(now time.Time) []string {
+	var live []string
+	for addr, member := range members {
+		if member.alive(now) {
+			live = append(live, addr)
+		}
+	}
+	rand.Shuffle(len(live), func(i, j int) { live[i], live[j] = live[j], live[i] })
+	targets := live[:min(len(live), gossipFanout)]
+
+	seeds := append([]string(nil), gossipSeeds...)
+	rand.Shuffle(len(seeds), func(i, j int) { seeds[i], seeds[j] = seeds[j], seeds[i] })
+	for _, seed := range seeds {
+		if len(targets) >= gossipFanout {
+			break
+		}
+		if member, ok := members[seed]; seed == serverIP || ok && member.alive(now) {
+			continue
+		}
+		targets = append(targets, seed)
+	}
+	return targets
+}
+
+func handleGossip(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	now := time.Now()
+	mergeMembers(req.Members, now)
+	reply(conn, addr, req, types.Response{Success: true, Members: memberList(now)})
+}
+
+// routeByGossip merges player into the owner gossip names for chunk_id,
+// sparing central the /chunk lookup. It only applies when nobody here is in
+// the chunk, since central would otherwise weigh migrating it to us. Must be
+// called with zone_map_Mu held.
+func routeByGossip(ctx context.Context, chunk_id types.ChunkID, local types.Chunk, player types.Player, trace string) (types.Response, bool) {
+	owner, ok := gossipOwner(chunk_id)
+	if !ok || len(local.PlayerList) > 0 {
+		return types.Response{}, false
+	}
+
+	res, ok := routeOnHint(ctx, chunk_id, owner, player, trace)
+	if !ok {
+		// stale hint; central has the last word
+		delete(owner_hints, chunk_id)
+		gossipRoutesTotal.Inc("stale")
+		netproto.Tracef(trace, "🗣️  Gossip hint %s for chunk [%d,%d] was stale", owner, chunk_id.IDX, chunk_id.IDY)
+		return types.Response{}, false
+	}
+	gossipRoutesTotal.Inc("hit")
+	netproto.Tracef(trace, "🗣️  Chunk [%d,%d] routed to %s on a gossip hint", chunk_id.IDX, chunk_id.IDY, owner)
+	return res, true
+}
+
+// routeOnHint merges player into owner, which a hint rather than central
+// says owns chunk_id, and redirects them there. It fails if owner refuses,
+// not owning the chunk. Must be called with zone_map_Mu held.
+func routeOnHint(ctx context.Context, chunk_id types.ChunkID, owner string, player types.Player, trace string) (types.Response, bool) {
+	temp_chunk := types.Chunk{PlayerList: []types.Player{player}}
+	merge_req := types.Request{Type: types.ReqMerge, ChunkID: chunk_id, Chunk: temp_chunk, BareChunk: true, OwnerHint: true, TraceID: trace}
+	merge_res, err := merge(ctx, merge_req, owner)
+	if err != nil || !merge_res.Success {
+		return types.Response{}, false
+	}
+
+	transferPlayers(ctx, chunk_id, owner, player, trace)
+	if chunk, ok := zone_map[chunk_id]; ok {
+		chunk.ServerIP = owner
+		zone_map[chunk_id] = chunk
+	}
+	return redirectAfterMerge(owner, merge_res, nil), true
+}
+
+// AdminMember is one entry of GET /admin/members.
+type AdminMember struct {
+	types.MemberState
+	Alive     bool      `json:"alive"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+func handleAdminMembers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	zone_map_Mu.Lock()
+	now := time.Now()
+	list := make([]AdminMember, 0, len(members))
+	for _, member := range members {
+		list = append(list, AdminMember{MemberState: member.State, Alive: member.alive(now), UpdatedAt: member.Updated})
+	}
+	zone_map_Mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool { return list[i].Addr < list[j].Addr })
+	writeAdminJSON(w, list)
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Edit history =====================
+
+// CubeEdit is one cube placed or removed by a player. Edits made by one
+// request share a Batch, so UNDO reverts a whole ADD_CUBES at once.
+type CubeEdit struct {
+	Batch    int64      `json:"batch"`
+	Time     time.Time  `json:"time"`
+	PlayerID string     `json:"player_id"`
+	Op       string     `json:"op"` // "add" or "remove"
+	Cube     types.Cube `json:"cube"`
+}
+
+// maxChunkHistory bounds the edits kept per chunk; older ones can only be
+// restored from a backup. History lives in memory on the owner and does not
+// follow a chunk when it migrates.
+const maxChunkHistory = 500
+
+var (
+	chunk_history = make(map[types.ChunkID][]CubeEdit)
+	edit_batch    int64
+)
+
+// newEditBatch starts the batch for one request's edits. Must be called with
+// zone_map_Mu held.
+func newEditBatch() int64 {
+	edit_batch++
+	return edit_batch
+}
+
+// recordEdit appends to chunk_id's history, dropping the oldest edits past
+// maxChunkHistory. Must be called with zone_map_Mu held.
+func recordEdit(chunk_id types.ChunkID, batch int64, player_id, op string, cube types.Cube) {
+	history := append(chunk_history[chunk_id], CubeEdit{Batch: batch, Time: time.Now(), PlayerID: player_id, Op: op, Cube: cube})
+	if len(history) > maxChunkHistory {
+		history = append([]CubeEdit(nil), history[len(history)-maxChunkHistory:]...)
+	}
+	chunk_history[chunk_id] = history
+}
+
+// revertEdits undoes edits newest first on chunk. Cubes that changed again
+// since (already gone, or re-placed) are skipped. Must be called with
+// zone_map_Mu held.
+func revertEdits(chunk_id types.ChunkID, chunk *types.Chunk, edits []CubeEdit) int {
+	reverted := 0
+	for i := len(edits) - 1; i >= 0; i-- {
+		edit := edits[i]
+		switch edit.Op {
+		case "add":
+			if _, ok := removeCube(chunk_id, chunk, edit.Cube.ID); !ok {
+				continue
+			}
+		case "remove":
+			if _, ok := cubeIndex(chunk_id, *chunk).Find(chunk.Cells, edit.Cube.ID); ok {
+				continue
+			}
+			addCube(chunk_id, chunk, edit.Cube)
+		}
+		reverted++
+	}
+	if reverted > 0 {
+		chunk.IsDirty = true
+	}
+	return reverted
+}
+
+// handleUndo reverts the requesting player's latest edit batch in a chunk.
+func handleUndo(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	chunk, ok := zone_map[chunk_id]
+	if !ok || chunk.ServerIP != serverIP {
+		replyNotOwner(conn, addr, req, chunk)
+		return
+	}
+
+	history := chunk_history[chunk_id]
+	end := len(history) - 1
+	for end >= 0 && history[end].PlayerID != req.PlayerID {
+		end--
+	}
+	if req.PlayerID == "" || end < 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Nothing to undo", Code: types.CodeNotFound})
+		return
+	}
+	batch := history[end].Batch
+	var undone, kept []CubeEdit
+	for _, edit := range history {
+		if edit.Batch == batch {
+			undone = append(undone, edit)
+		} else {
+			kept = append(kept, edit)
+		}
+	}
+
+	reverted := revertEdits(chunk_id, &chunk, undone)
+	chunk_history[chunk_id] = kept
+	zone_map[chunk_id] = chunk
+
+	journal.Record(WorldEvent{Type: "UNDO", PlayerID: req.PlayerID, Addr: addr, ChunkID: chunk_id,
+		Detail: fmt.Sprintf("%d of %d edits reverted", reverted, len(undone)), TraceID: req.TraceID})
+	replyEdit(conn, addr, req, types.Response{Success: true, Message: fmt.Sprintf("Undid %d edits", reverted)})
+	netproto.Tracef(req.TraceID, "↩️  Player %s undid %d edits in chunk [%d,%d]", req.PlayerID, reverted, chunk_id.IDX, chunk_id.IDY)
+}
+
+// AdminRollbackRequest is the body of POST /admin/chunks/{x}/{y}/rollback.
+type AdminRollbackRequest struct {
+	To time.Time `json:"to"`
+}
+
+// adminRollbackChunk reverts every edit made to an owned chunk after to.
+func adminRollbackChunk(w http.ResponseWriter, chunk_id types.ChunkID, to time.Time) {
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+
+	touchChunk(chunk_id)
+	chunk, ok := zone_map[chunk_id]
+	if !ok || chunk.ServerIP != serverIP {
+		http.Error(w, "Chunk not owned by this server", http.StatusConflict)
+		return
+	}
+
+	history := chunk_history[chunk_id]
+	cut := len(history)
+	for cut > 0 && history[cut-1].Time.After(to) {
+		cut--
+	}
+	reverted := revertEdits(chunk_id, &chunk, history[cut:])
+	chunk_history[chunk_id] = history[:cut]
+	zone_map[chunk_id] = chunk
+	markUnsaved(chunk_id)
+
+	trace := netproto.NewTraceID()
+	journal.Record(WorldEvent{Type: "ROLLBACK", ChunkID: chunk_id,
+		Detail: fmt.Sprintf("to %s, %d edits reverted", to.Format(time.RFC3339), reverted), TraceID: trace})
+	netproto.Tracef(trace, "⏪ Admin rolled chunk [%d,%d] back to %s (%d edits)", chunk_id.IDX, chunk_id.IDY, to.Format(time.RFC3339), reverted)
+	writeAdminJSON(w, types.Response{Success: true, Message: fmt.Sprintf("Reverted %d edits", reverted), TraceID: trace})
+}
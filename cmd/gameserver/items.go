@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Items =====================
+
+// Players carry items in Player.Inventory, stacks of up to maxStack per
+// kind, which like Health is held by the player's server and persists with
+// the player. DROP leaves items from it in the player's chunk as a
+// types.Item at their feet; PICKUP takes one lying within pickupRange back,
+// as much of it as fits. Items in chunks go wherever the chunk does, like
+// NPCs. USE uses up one item of a kind that has an itemUse; kinds without
+// one can only be carried and traded by dropping them. Operators hand out
+// items with POST /admin/players/{id}/items.
+//
+// The inventory only ever changes here, so it is replaced rather than
+// edited in place: a copy of the player elsewhere (a chunk list, a handoff
+// in flight) must not see it change.
+
+const (
+	pickupRange       = 2 // cells
+	maxStack          = 999
+	maxInventoryKinds = 32
+	maxChunkItems     = 256
+)
+
+// An itemUse applies one item of a kind to the player using it, and reports
+// whether it was used up; a refusal says why.
+type itemUse func(player_id string, now time.Time) (bool, string)
+
+// item_uses maps a kind to what using it does.
+var item_uses = map[string]itemUse{
+	"medkit": func(player_id string, now time.Time) (bool, string) {
+		if player := player_map[player_id]; player.Health >= maxHealth {
+			return false, "Health already full"
+		}
+		_, ok := heal(player_id, player_id, "medkit", 50, now)
+		return ok, "Dead"
+	},
+}
+
+var itemsTotal = metrics.NewCounterVec("game_items_total",
+	"Item actions by players: pickup, drop or use.", "action")
+
+// keepInventory gives player the inventory this server holds for them, or,
+// for a player new here, the one they bring, within the limits. Must be
+// called with zone_map_Mu held.
+func keepInventory(player *types.Player) {
+	if held, ok := player_map[player.ID]; ok {
+		player.Inventory = held.Inventory
+		return
+	}
+	var inventory []types.ItemStack
+	for _, stack := range player.Inventory {
+		inventory = addItems(inventory, stack.Kind, stack.Count)
+	}
+	player.Inventory = inventory
+}
+
+// addItems returns inventory with count more of kind, as many as fit, in a
+// new slice.
+func addItems(inventory []types.ItemStack, kind string, count int) []types.ItemStack {
+	out := append([]types.ItemStack(nil), inventory...)
+	if kind == "" || count <= 0 {
+		return out
+	}
+	for i := range out {
+		if out[i].Kind == kind {
+			out[i].Count = min(maxStack, out[i].Count+count)
+			return out
+		}
+	}
+	if len(out) >= maxInventoryKinds {
+		return out
+	}
+	out = append(out, types.ItemStack{Kind: kind, Count: min(maxStack, count)})
+	sort.Slice(out, func(i, j int) bool { return out[i].Kind < out[j].Kind })
+	return out
+}
+
+// takeItems returns inventory with count fewer of kind, in a new slice, or
+// false if it does not hold that many.
+func takeItems(inventory []types.ItemStack, kind string, count int) ([]types.ItemStack, bool) {
+	out := make([]types.ItemStack, 0, len(inventory))
+	taken := false
+	for _, stack := range inventory {
+		if stack.Kind == kind {
+			if stack.Count < count {
+				return inventory, false
+			}
+			taken = true
+			if stack.Count -= count; stack.Count == 0 {
+				continue
+			}
+		}
+		out = append(out, stack)
+	}
+	return out, taken
+}
+
+// room returns how many of kind fit in inventory.
+func room(inventory []types.ItemStack, kind string) int {
+	for _, stack := range inventory {
+		if stack.Kind == kind {
+			return maxStack - stack.Count
+		}
+	}
+	if len(inventory) >= maxInventoryKinds {
+		return 0
+	}
+	return maxStack
+}
+
+// itemPlayer returns the player req acts for, if they are held here in a
+// chunk this server owns and alive; otherwise it has replied. Must be
+// called with zone_map_Mu held.
+func itemPlayer(req types.Request, conn netproto.Transport, addr string) (types.Player, bool) {
+	player, held := heldPlayer(req.Player.ID)
+	if !held {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return player, false
+	}
+	if chunk, ok := zone_map[player.ChunkID]; !ok || chunk.ServerIP != serverIP {
+		replyNotOwner(conn, addr, req, chunk)
+		return player, false
+	}
+	if dead, ok := lifeGate(player.ID, player.PosX, player.PosY, time.Now()); !ok && dead.Code == types.CodeDead {
+		reply(conn, addr, req, dead)
+		return player, false
+	}
+	return player, true
+}
+
+func handlePickup(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player, ok := itemPlayer(req, conn, addr)
+	if !ok {
+		return
+	}
+	chunk := zone_map[player.ChunkID]
+	i := 0
+	for i < len(chunk.Items) && chunk.Items[i].ID != req.ItemID {
+		i++
+	}
+	if i == len(chunk.Items) {
+		reply(conn, addr, req, types.Response{Success: false, Message: "No such item here", Code: types.CodeNotFound})
+		return
+	}
+	item := chunk.Items[i]
+	if abs(item.PosX-player.PosX) > pickupRange || abs(item.PosY-player.PosY) > pickupRange {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Item out of reach", Code: types.CodeBadRequest})
+		return
+	}
+	taken := min(item.Count, room(player.Inventory, item.Kind))
+	if taken == 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Inventory full", Code: types.CodeBadRequest})
+		return
+	}
+
+	items := append([]types.Item(nil), chunk.Items...)
+	if item.Count -= taken; item.Count == 0 {
+		items = append(items[:i], items[i+1:]...)
+	} else {
+		items[i] = item
+	}
+	chunk.Items = items
+	zone_map[player.ChunkID] = chunk
+	player.Inventory = addItems(player.Inventory, item.Kind, taken)
+	setVitals(player)
+	itemsTotal.Inc("pickup")
+	journal.Record(WorldEvent{Type: "PICKUP", PlayerID: player.ID, ChunkID: player.ChunkID, TraceID: req.TraceID,
+		Detail: fmt.Sprintf("%d %s (%s)", taken, item.Kind, item.ID)})
+	reply(conn, addr, req, types.Response{Success: true, Message: fmt.Sprintf("Picked up %d %s", taken, item.Kind), Player: &player})
+}
+
+func handleDrop(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player, ok := itemPlayer(req, conn, addr)
+	if !ok {
+		return
+	}
+	if req.Item == nil || req.Item.Kind == "" || req.Item.Count <= 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing item kind or count", Code: types.CodeBadRequest})
+		return
+	}
+	chunk := zone_map[player.ChunkID]
+	if len(chunk.Items) >= maxChunkItems {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Too many items lying here", Code: types.CodeBadRequest})
+		return
+	}
+	inventory, ok := takeItems(player.Inventory, req.Item.Kind, req.Item.Count)
+	if !ok {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Not carrying that many", Code: types.CodeBadRequest})
+		return
+	}
+
+	item := types.Item{ID: fmt.Sprintf("item-%016x", rand.Uint64()), Kind: req.Item.Kind, Count: req.Item.Count,
+		PosX: player.PosX, PosY: player.PosY}
+	chunk.Items = append(append([]types.Item(nil), chunk.Items...), item)
+	zone_map[player.ChunkID] = chunk
+	player.Inventory = inventory
+	setVitals(player)
+	itemsTotal.Inc("drop")
+	journal.Record(WorldEvent{Type: "DROP", PlayerID: player.ID, ChunkID: player.ChunkID, TraceID: req.TraceID,
+		Detail: fmt.Sprintf("%d %s (%s)", item.Count, item.Kind, item.ID)})
+	reply(conn, addr, req, types.Response{Success: true, Message: fmt.Sprintf("Dropped %d %s", item.Count, item.Kind),
+		Player: &player, ItemID: item.ID})
+}
+
+func handleUse(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player, ok := itemPlayer(req, conn, addr)
+	if !ok {
+		return
+	}
+	if req.Item == nil || req.Item.Kind == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing item kind", Code: types.CodeBadRequest})
+		return
+	}
+	kind := req.Item.Kind
+	use, usable := item_uses[kind]
+	if !usable {
+		reply(conn, addr, req, types.Response{Success: false, Message: fmt.Sprintf("%s cannot be used", kind), Code: types.CodeUnsupported})
+		return
+	}
+	if _, ok := takeItems(player.Inventory, kind, 1); !ok {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Not carrying any", Code: types.CodeBadRequest})
+		return
+	}
+	if used, why := use(player.ID, time.Now()); !used {
+		reply(conn, addr, req, types.Response{Success: false, Message: why, Code: types.CodeBadRequest})
+		return
+	}
+
+	// the use may have changed the player
+	player, _ = heldPlayer(player.ID)
+	player.Inventory, _ = takeItems(player.Inventory, kind, 1)
+	setVitals(player)
+	itemsTotal.Inc("use")
+	reply(conn, addr, req, types.Response{Success: true, Message: "Used " + kind, Player: &player})
+}
+
+// AdminItemsRequest is the body of POST /admin/players/{id}/items.
+type AdminItemsRequest struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+// adminGiveItems serves POST /admin/players/{id}/items, adding items to a
+// held player's inventory. Must be called with zone_map_Mu held.
+func adminGiveItems(w http.ResponseWriter, r *http.Request, player_id string) {
+	var body AdminItemsRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Kind == "" || body.Count <= 0 {
+		http.Error(w, "Body must be {\"kind\": \"...\", \"count\": <positive>}", http.StatusBadRequest)
+		return
+	}
+	player, held := heldPlayer(player_id)
+	if !held {
+		http.Error(w, "Player not in a chunk here", http.StatusConflict)
+		return
+	}
+	if room(player.Inventory, body.Kind) < body.Count {
+		http.Error(w, "Inventory full", http.StatusConflict)
+		return
+	}
+	player.Inventory = addItems(player.Inventory, body.Kind, body.Count)
+	setVitals(player)
+	journal.Record(WorldEvent{Type: "GIVE", PlayerID: player_id, ChunkID: player.ChunkID,
+		Detail: fmt.Sprintf("%d %s by admin", body.Count, body.Kind)})
+	writeAdminJSON(w, adminPlayer(player_id))
+}
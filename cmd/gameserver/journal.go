@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== World event journal =====================
+
+// WorldEvent is one line of the append-only journal (JSON Lines) that records
+// who changed what in the world, so moderators can answer questions after
+// the fact without grepping server logs. It doubles as the audit log of
+// world-mutating requests: every cube added or removed, every MERGE and
+// UPDATE_DATA, and every change of a chunk's owner carries the address the
+// request came from, so griefing and migration bugs can be traced to a
+// client or peer.
+type WorldEvent struct {
+	Seq      int64         `json:"seq"`
+	Time     time.Time     `json:"time"`
+	Type     string        `json:"type"`
+	PlayerID string        `json:"player_id,omitempty"`
+	Addr     string        `json:"addr,omitempty"` // where the request that caused it came from
+	ChunkID  types.ChunkID `json:"chunk_id"`
+	CubeID   string        `json:"cube_id,omitempty"`
+	Cube     *types.Cube   `json:"cube,omitempty"`
+	ServerIP string        `json:"server_ip"`
+	Detail   string        `json:"detail,omitempty"`
+	TraceID  string        `json:"trace_id,omitempty"`
+}
+
+// EventFilter selects journal entries. Zero values match everything.
+type EventFilter struct {
+	PlayerID string
+	Addr     string
+	ChunkID  *types.ChunkID
+	Type     string
+	Since    time.Time
+	Until    time.Time
+	After    int64
+}
+
+type EventPage struct {
+	Events     []WorldEvent `json:"events"`
+	NextCursor int64        `json:"next_cursor,omitempty"`
+}
+
+type Journal struct {
+	sync.Mutex
+	path string
+	file *os.File
+	seq  int64
+}
+
+const (
+	defaultEventLimit = 100
+	maxEventLimit     = 1000
+	maxJournalLine    = 1 << 20
+)
+
+var journal *Journal
+
+// OpenJournal opens (or creates) the journal at path and resumes its
+// sequence numbering from the last entry.
+func OpenJournal(path string) (*Journal, error) {
+	j := &Journal{path: path}
+	err := j.scan(func(ev WorldEvent) bool {
+		j.seq = ev.Seq
+		return true
+	})
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	j.file, err = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// Record appends ev to the journal, stamping its sequence number and time.
+func (j *Journal) Record(ev WorldEvent) {
+	if j == nil {
+		return
+	}
+	j.Lock()
+	defer j.Unlock()
+
+	j.seq++
+	ev.Seq = j.seq
+	if ev.Time.IsZero() {
+		ev.Time = time.Now().UTC()
+	}
+	if ev.ServerIP == "" {
+		ev.ServerIP = serverIP
+	}
+
+	data, err := json.Marshal(ev)
+	if err != nil {
+		log.Println("JSON marshal error:", err)
+		return
+	}
+	if _, err := j.file.Write(append(data, '\n')); err != nil {
+		log.Printf("❌ Journal write failed: %v", err)
+	}
+}
+
+// Query returns up to limit events matching f in sequence order.
+func (j *Journal) Query(f EventFilter, limit int) (EventPage, error) {
+	page := EventPage{Events: make([]WorldEvent, 0)}
+	err := j.scan(func(ev WorldEvent) bool {
+		if !f.Match(ev) {
+			return true
+		}
+		if limit > 0 && len(page.Events) == limit {
+			page.NextCursor = page.Events[len(page.Events)-1].Seq
+			return false
+		}
+		page.Events = append(page.Events, ev)
+		return true
+	})
+	return page, err
+}
+
+// scan feeds every journal entry to fn until it returns false.
+func (j *Journal) scan(fn func(WorldEvent) bool) error {
+	f, err := os.Open(j.path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxJournalLine)
+	for scanner.Scan() {
+		var ev WorldEvent
+		if err := json.Unmarshal(scanner.Bytes(), &ev); err != nil {
+			log.Printf("⚠️  Skipping corrupt journal line: %v", err)
+			continue
+		}
+		if !fn(ev) {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+func (f EventFilter) Match(ev WorldEvent) bool {
+	if ev.Seq <= f.After {
+		return false
+	}
+	if f.PlayerID != "" && ev.PlayerID != f.PlayerID {
+		return false
+	}
+	if f.Addr != "" && ev.Addr != f.Addr {
+		return false
+	}
+	if f.ChunkID != nil && ev.ChunkID != *f.ChunkID {
+		return false
+	}
+	if f.Type != "" && ev.Type != f.Type {
+		return false
+	}
+	if !f.Since.IsZero() && ev.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && ev.Time.After(f.Until) {
+		return false
+	}
+	return true
+}
+
+// parseEventFilter reads ?player=&addr=&chunk=x,y&world=&type=&since=&until=&cursor=
+// (times in RFC 3339).
+func parseEventFilter(r *http.Request) (EventFilter, error) {
+	q := r.URL.Query()
+	f := EventFilter{PlayerID: q.Get("player"), Addr: q.Get("addr"), Type: q.Get("type")}
+
+	if chunk := q.Get("chunk"); chunk != "" {
+		xy := strings.Split(chunk, ",")
+		if len(xy) != 2 {
+			return f, fmt.Errorf("chunk must be x,y")
+		}
+		x, errX := strconv.Atoi(xy[0])
+		y, errY := strconv.Atoi(xy[1])
+		if errX != nil || errY != nil {
+			return f, fmt.Errorf("chunk must be x,y")
+		}
+		f.ChunkID = &types.ChunkID{World: q.Get("world"), IDX: x, IDY: y}
+	}
+
+	var err error
+	if v := q.Get("since"); v != "" {
+		if f.Since, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("since: %v", err)
+		}
+	}
+	if v := q.Get("until"); v != "" {
+		if f.Until, err = time.Parse(time.RFC3339, v); err != nil {
+			return f, fmt.Errorf("until: %v", err)
+		}
+	}
+	if v := q.Get("cursor"); v != "" {
+		if f.After, err = strconv.ParseInt(v, 10, 64); err != nil {
+			return f, fmt.Errorf("cursor: %v", err)
+		}
+	}
+	return f, nil
+}
+
+// handleAdminEvents serves GET /admin/events with pagination via ?limit= and
+// the next_cursor of the previous page.
+func handleAdminEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if journal == nil {
+		http.Error(w, "Event journal disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	f, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	limit := defaultEventLimit
+	if v := r.URL.Query().Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 {
+			http.Error(w, "limit must be a positive integer", http.StatusBadRequest)
+			return
+		}
+		if limit > maxEventLimit {
+			limit = maxEventLimit
+		}
+	}
+
+	page, err := journal.Query(f, limit)
+	if err != nil {
+		log.Printf("❌ Journal query failed: %v", err)
+		http.Error(w, "Journal read failed", http.StatusInternalServerError)
+		return
+	}
+	writeAdminJSON(w, page)
+}
+
+// handleAdminEventsExport serves GET /admin/events/export: every matching
+// event as a downloadable JSON array.
+func handleAdminEventsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if journal == nil {
+		http.Error(w, "Event journal disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	f, err := parseEventFilter(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=\"events-%s.json\"", time.Now().UTC().Format("20060102T150405Z")))
+
+	enc := json.NewEncoder(w)
+	first := true
+	w.Write([]byte("["))
+	err = journal.scan(func(ev WorldEvent) bool {
+		if !f.Match(ev) {
+			return true
+		}
+		if !first {
+			w.Write([]byte(","))
+		}
+		first = false
+		enc.Encode(ev)
+		return true
+	})
+	w.Write([]byte("]\n"))
+	if err != nil {
+		log.Printf("❌ Journal export failed: %v", err)
+	}
+}
@@ -0,0 +1,344 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Health and respawn =====================
+
+// A player's health lives in their Player (Health, RespawnMs), so it goes
+// wherever the player does: into chunk player lists and saved chunks, to a
+// chunk's new owner with PLAYER_TRANSFER, and back to a resuming client with
+// RESUME and LOCATE_PLAYER. The server holding a player is the authority on
+// it; what a client claims is ignored once the player is known here.
+//
+// Health drops with projectile hits and DAMAGE (from a peer, or the admin
+// API), and comes back with HEAL. DAMAGE and HEAL are taken only from other
+// servers (see authenticatePeers): from a player they would heal anyone or
+// damage anyone. At 0 the player dies: their moves, chunk
+// changes and shots are refused with ERR_DEAD until respawnDelay has passed.
+// The player then respawns with full health at the spawn point of their
+// world that has the fewest players within spawnClearance, preferring those
+// in chunks owned here, and is answered ERR_RESPAWNED, with where they are
+// now, until they move from there. Every step is pushed as PUSH_LIFE to the
+// players and spectators of the chunk and to whoever caused it.
+
+const (
+	maxHealth      = 100
+	respawnDelay   = 5 * time.Second
+	spawnClearance = 8 // cells around a spawn point counted as crowding it
+	respawnSlack   = 2 // cells from the spawn point a first move may land
+)
+
+type spawnPoint struct {
+	X, Y int
+}
+
+// spawn_points are the -spawn-points, by world; a world with none respawns
+// players at (0, 0).
+var spawn_points = make(map[string][]spawnPoint)
+
+// guarded by zone_map_Mu; players who respawned but have not moved since
+var respawned = make(map[string]bool)
+
+var lifeEventsTotal = metrics.NewCounterVec("game_life_events_total",
+	"Changes to players' health: damage, heal, death or respawn.", "kind")
+
+// parseSpawnPoints sets spawn_points from "x,y" entries separated by
+// semicolons, each optionally prefixed with "world:" for a world other than
+// the shared one.
+func parseSpawnPoints(list string) error {
+	for _, entry := range strings.Split(list, ";") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		world_id := ""
+		if i := strings.LastIndex(entry, ":"); i >= 0 {
+			world_id, entry = entry[:i], entry[i+1:]
+		}
+		coords := strings.Split(entry, ",")
+		if len(coords) != 2 {
+			return fmt.Errorf("spawn point %q is not x,y", entry)
+		}
+		x, errX := strconv.Atoi(strings.TrimSpace(coords[0]))
+		y, errY := strconv.Atoi(strings.TrimSpace(coords[1]))
+		if errX != nil || errY != nil {
+			return fmt.Errorf("spawn point %q is not x,y", entry)
+		}
+		spawn_points[world_id] = append(spawn_points[world_id], spawnPoint{X: x, Y: y})
+	}
+	return nil
+}
+
+// keepVitals gives player the health this server holds for them, or, for a
+// player new here, the health they bring, full if they bring none. Must be
+// called with zone_map_Mu held.
+func keepVitals(player *types.Player) {
+	if held, ok := player_map[player.ID]; ok {
+		player.Health, player.RespawnMs = held.Health, held.RespawnMs
+	}
+	if player.RespawnMs > 0 {
+		player.Health = 0
+	} else if player.Health <= 0 || player.Health > maxHealth {
+		player.Health = maxHealth
+	}
+}
+
+// lifeGate refuses a move to (x, y), or a GET_DATA there, by a player who
+// is dead or has respawned somewhere else. Must be called with zone_map_Mu
+// held.
+func lifeGate(player_id string, x, y int, now time.Time) (types.Response, bool) {
+	held, ok := player_map[player_id]
+	if !ok {
+		return types.Response{}, true
+	}
+	if held.RespawnMs > 0 {
+		return types.Response{Success: false, Message: "Dead", Code: types.CodeDead,
+			RetryAfterMs: max(1, held.RespawnMs-now.UnixMilli())}, false
+	}
+	if respawned[player_id] {
+		if abs(x-held.PosX) > respawnSlack || abs(y-held.PosY) > respawnSlack {
+			return types.Response{Success: false, Message: "Respawned", Code: types.CodeRespawned, Player: &held}, false
+		}
+		delete(respawned, player_id)
+	}
+	return types.Response{}, true
+}
+
+// damage takes amount off a living player's health, killing them at 0, and
+// returns them as they now are. Must be called with zone_map_Mu held.
+func damage(player_id, source, reason string, amount int, now time.Time) (types.Player, bool) {
+	player, ok := heldPlayer(player_id)
+	if !ok || player.RespawnMs > 0 {
+		return player, false
+	}
+	keepVitals(&player)
+	player.Health = max(0, player.Health-amount)
+	event := types.LifeEvent{Kind: types.LifeDamage, PlayerID: player_id, SourceID: source, Reason: reason,
+		Amount: amount, Health: player.Health, PosX: player.PosX, PosY: player.PosY, ChunkID: player.ChunkID}
+	pushLife(event, source)
+	if player.Health == 0 {
+		player.RespawnMs = now.Add(respawnDelay).UnixMilli()
+		event.Kind, event.RespawnMs = types.LifeDeath, player.RespawnMs
+		pushLife(event, source)
+		journal.Record(WorldEvent{Type: "DEATH", PlayerID: player_id, ChunkID: player.ChunkID,
+			Detail: fmt.Sprintf("%s by %s", reason, source)})
+		countStats(player_id, types.PlayerStats{Deaths: 1})
+		if _, by_player := player_map[source]; by_player && source != player_id {
+			countStats(source, types.PlayerStats{Kills: 1})
+		}
+	}
+	setVitals(player)
+	return player, true
+}
+
+// heal gives a living player back amount of health, up to maxHealth, and
+// returns them as they now are. It takes the time only to be a lifeChange,
+// like damage. Must be called with zone_map_Mu held.
+func heal(player_id, source, reason string, amount int, _ time.Time) (types.Player, bool) {
+	player, ok := heldPlayer(player_id)
+	if !ok || player.RespawnMs > 0 {
+		return player, false
+	}
+	keepVitals(&player)
+	player.Health = min(maxHealth, player.Health+amount)
+	setVitals(player)
+	pushLife(types.LifeEvent{Kind: types.LifeHeal, PlayerID: player_id, SourceID: source, Reason: reason,
+		Amount: amount, Health: player.Health, PosX: player.PosX, PosY: player.PosY, ChunkID: player.ChunkID}, source)
+	return player, true
+}
+
+// setVitals stores a held player's new health or inventory where it is
+// kept.
+func setVitals(player types.Player) {
+	player.HLC = clock.Now()
+	player_map[player.ID] = player
+	updateListed(player.ChunkID, player)
+	markUnsaved(player.ChunkID)
+}
+
+// pushLife pushes event around its chunk and to source.
+func pushLife(event types.LifeEvent, source string) {
+	lifeEventsTotal.Inc(event.Kind)
+	notice := types.Response{Success: true, Code: types.CodeLife, Message: fmt.Sprintf("%s: %s", event.PlayerID, event.Kind), Life: &event}
+	stampClock(&notice)
+	pushAround(notice, []types.ChunkID{event.ChunkID}, event.PlayerID, source)
+}
+
+// pushAround pushes notice to the players and spectators of chunks, and to
+// the players also, each once. Must be called with zone_map_Mu held.
+func pushAround(notice types.Response, chunks []types.ChunkID, also ...string) {
+	in_chunks := make(map[types.ChunkID]bool, len(chunks))
+	for _, chunk_id := range chunks {
+		in_chunks[chunk_id] = true
+	}
+	wanted := make(map[string]bool, len(also))
+	for _, player_id := range also {
+		wanted[player_id] = true
+	}
+	sent := make(map[string]bool)
+	for player_id, in := range players {
+		if !in_chunks[in] && !wanted[player_id] {
+			continue
+		}
+		if addr, ok := player_addrs[player_id]; ok && !sent[addr] {
+			sent[addr] = true
+			push(pushConn, addr, notice)
+		}
+	}
+	for _, chunk_id := range chunks {
+		for addr, spectator := range spectators[chunk_id] {
+			if !sent[addr] {
+				sent[addr] = true
+				push(spectator.Conn, addr, notice)
+			}
+		}
+	}
+}
+
+// respawnTick respawns the dead players held here whose time has come. Must
+// be called with zone_map_Mu held.
+func respawnTick(now time.Time) {
+	for player_id, chunk_id := range players {
+		if player := player_map[player_id]; player.RespawnMs > 0 && player.RespawnMs <= now.UnixMilli() {
+			respawn(player_id, chunk_id, now)
+		}
+	}
+}
+
+// respawn brings a dead player back at a spawn point of their world. When
+// its chunk is owned here the player is moved into it; otherwise they only
+// leave the chunk they died in, and enter the spawn point's with their next
+// move, routed like any other. Must be called with zone_map_Mu held.
+func respawn(player_id string, chunk_id types.ChunkID, now time.Time) {
+	player := player_map[player_id]
+	point := pickSpawnPoint(player.World)
+	spawn_id := chunkAt(player.World, point.X, point.Y)
+	player.Health, player.RespawnMs = maxHealth, 0
+	player.PosX, player.PosY, player.ChunkID = point.X, point.Y, spawn_id
+	player.VelX, player.VelY, player.UpdatedMs = 0, 0, now.UnixMilli()
+	player.HLC = clock.Now()
+
+	if spawn_id != chunk_id {
+		leaveChunk(chunk_id, player_id)
+		markUnsaved(chunk_id)
+	}
+	if chunk, ok := zone_map[spawn_id]; ok && chunk.ServerIP == serverIP {
+		if spawn_id != chunk_id {
+			player.ServerIP = serverIP
+			chunk.PlayerList = append(chunk.PlayerList, player)
+			zone_map[spawn_id] = chunk
+			players[player_id] = spawn_id
+		}
+		updateListed(spawn_id, player)
+		markUnsaved(spawn_id)
+	}
+	player_map[player_id] = player
+	respawned[player_id] = true
+
+	event := types.LifeEvent{Kind: types.LifeRespawn, PlayerID: player_id, Health: player.Health,
+		PosX: player.PosX, PosY: player.PosY, ChunkID: spawn_id}
+	lifeEventsTotal.Inc(event.Kind)
+	notice := types.Response{Success: true, Code: types.CodeLife, Message: player_id + ": respawn", Life: &event}
+	stampClock(&notice)
+	pushAround(notice, []types.ChunkID{chunk_id, spawn_id}, player_id)
+	journal.Record(WorldEvent{Type: "RESPAWN", PlayerID: player_id, ChunkID: spawn_id,
+		Detail: fmt.Sprintf("at (%d, %d)", point.X, point.Y)})
+}
+
+// pickSpawnPoint returns the least crowded spawn point of world_id, those in
+// chunks owned here first. Must be called with zone_map_Mu held.
+func pickSpawnPoint(world_id string) spawnPoint {
+	points := spawn_points[world_id]
+	if len(points) == 0 {
+		return spawnPoint{}
+	}
+	best, best_owned, best_crowd := points[0], false, -1
+	for _, point := range points {
+		chunk, ok := zone_map[chunkAt(world_id, point.X, point.Y)]
+		owned := ok && chunk.ServerIP == serverIP
+		crowd := 0
+		for player_id := range players {
+			other := player_map[player_id]
+			if other.World == world_id && abs(other.PosX-point.X) <= spawnClearance && abs(other.PosY-point.Y) <= spawnClearance {
+				crowd++
+			}
+		}
+		if best_crowd < 0 || (owned && !best_owned) || (owned == best_owned && crowd < best_crowd) {
+			best, best_owned, best_crowd = point, owned, crowd
+		}
+	}
+	return best
+}
+
+// handleDamage applies DAMAGE to req.PlayerID, dealt by req.Player.ID if
+// set. Dealing damage to the dead does nothing.
+func handleDamage(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	handleLifeChange(ctx, req, conn, addr, damage)
+}
+
+// handleHeal applies HEAL to req.PlayerID, given by req.Player.ID if set.
+func handleHeal(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	handleLifeChange(ctx, req, conn, addr, heal)
+}
+
+// A lifeChange is damage or heal.
+type lifeChange func(player_id, source, reason string, amount int, now time.Time) (types.Player, bool)
+
+func handleLifeChange(ctx context.Context, req types.Request, conn netproto.Transport, addr string, change lifeChange) {
+	if !isPeer(ctx) {
+		requestsRefusedTotal.Inc("forbidden")
+		reply(conn, addr, req, types.Response{Success: false, Message: "Only servers may send " + string(req.Type), Code: types.CodeForbidden})
+		return
+	}
+	if req.Amount <= 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Amount must be positive", Code: types.CodeBadRequest})
+		return
+	}
+	if _, held := players[req.PlayerID]; !held {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+	player, ok := change(req.PlayerID, req.Player.ID, req.Reason, req.Amount, time.Now())
+	if !ok {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player is dead", Code: types.CodeDead, Player: &player})
+		return
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: fmt.Sprintf("%s %s", req.Type, req.PlayerID), Player: &player})
+	netproto.Tracef(req.TraceID, "❤️ %s %d to %s (%s): health %d", req.Type, req.Amount, req.PlayerID, req.Reason, player.Health)
+}
+
+// AdminLifeRequest is the body of POST /admin/players/{id}/damage and
+// /heal.
+type AdminLifeRequest struct {
+	Amount int    `json:"amount"`
+	Reason string `json:"reason"`
+}
+
+// adminLifeChange serves POST /admin/players/{id}/damage and /heal.
+func adminLifeChange(w http.ResponseWriter, r *http.Request, player_id string, change lifeChange) {
+	var body AdminLifeRequest
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil || body.Amount <= 0 {
+		http.Error(w, "Body must be {\"amount\": <positive>, \"reason\": \"...\"}", http.StatusBadRequest)
+		return
+	}
+	if body.Reason == "" {
+		body.Reason = "admin"
+	}
+	player, ok := change(player_id, "", body.Reason, body.Amount, time.Now())
+	if !ok {
+		http.Error(w, "Player is dead", http.StatusConflict)
+		return
+	}
+	writeAdminJSON(w, adminPlayer(player.ID))
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"log"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Matches =====================
+
+// Central's matchmaking gives each match a world of its own and sends the
+// match with MATCH: first to the server it picked to host it, which takes
+// the match's chunks freshly generated, then to every other live server.
+// Each server pushes the match to the players in it that it holds, as a
+// PUSH_MATCH message; the clients leave their server and enter the match's
+// world at their team's spawn.
+
+func handleMatch(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	match := req.Match
+	if match == nil || match.ID == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing match", Code: types.CodeBadRequest})
+		return
+	}
+
+	if match.Server == serverIP {
+		for _, chunk_id := range match.Chunks {
+			if old, ok := zone_map[chunk_id]; ok && len(old.PlayerList) > 0 {
+				log.Printf("⚠️  Match %s reseeds chunk [%d,%d] with %d players in it", match.ID, chunk_id.IDX, chunk_id.IDY, len(old.PlayerList))
+			}
+			zone_map[chunk_id] = types.Chunk{World: chunk_id.World, IDX: chunk_id.IDX, IDY: chunk_id.IDY, Level: chunk_id.Level, Data: "new chunk", ServerIP: serverIP,
+				Cells: generator.Generate(chunk_id, chunk_id.Edge(world.ChunkSize)), IsDirty: true}
+			delete(cube_indexes, chunk_id)
+			journal.Record(WorldEvent{Type: "CHUNK_CREATE", ChunkID: chunk_id, Detail: "match " + match.ID, TraceID: req.TraceID})
+		}
+		log.Printf("🏟️  Seeded %d chunks for %s match %s", len(match.Chunks), match.Mode, match.ID)
+	}
+
+	notice := types.Response{Success: true, Code: types.CodeMatch, Message: match.ID, Match: match}
+	stampClock(&notice)
+	pushed := 0
+	for _, team := range match.Teams {
+		for _, player_id := range team {
+			if _, held := players[player_id]; !held {
+				continue
+			}
+			if player_addr, ok := player_addrs[player_id]; ok {
+				push(conn, player_addr, notice)
+				pushed++
+			}
+		}
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Match noted", PlayerCount: pushed})
+	netproto.Tracef(req.TraceID, "🏟️  Match %s pushed to %d players", match.ID, pushed)
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Request middleware =====================
+
+// Every decoded request goes to its handler (the handlers table) through
+// the middleware in chain, outermost first. A middleware sees the request
+// before its handler, may answer it itself instead, and sees it again once
+// the handler is done; all of it runs with zone_map_Mu held. Concerns every
+// request type shares belong here rather than in serve or the handlers: a
+// new one is a middleware added to chain (or, from another file, with use
+// before the server starts serving). A GET_UPDATES answered from a chunk
+// view skips the chain (see views.go).
+
+// A middleware wraps next with one concern.
+type middleware func(next handlerFunc) handlerFunc
+
+// chain is the middleware every request passes through, outermost first.
+var chain = []middleware{
+	traceRequests, // first, so the slow-request log has the trace ID
+	instrumentRequests,
+	authenticatePeers, // before anything that trusts a peer
+	refuseKicked,
+	limitRequests,
+	validateRequests,
+	refuseLocked,
+	refuseExpired,
+	notePlayers,
+	publishChunks,
+	touchChunks,
+}
+
+// routes is handlers with chain applied, and unsupported the handler for
+// request types not in it; built by buildRoutes.
+var (
+	routes      map[types.RequestType]handlerFunc
+	unsupported handlerFunc
+)
+
+// use adds middleware to the inside of chain. It must be called before
+// buildRoutes.
+func use(mw ...middleware) {
+	chain = append(chain, mw...)
+}
+
+// buildRoutes wraps every handler in chain.
+func buildRoutes() {
+	routes = make(map[types.RequestType]handlerFunc, len(handlers))
+	for t, h := range handlers {
+		routes[t] = wrap(h)
+	}
+	unsupported = wrap(replyUnsupported)
+}
+
+func wrap(h handlerFunc) handlerFunc {
+	for i := len(chain) - 1; i >= 0; i-- {
+		h = chain[i](h)
+	}
+	return h
+}
+
+var requestsRefusedTotal = metrics.NewCounterVec("game_requests_refused_total",
+	"Requests the middleware answered without a handler: kicked, limited, invalid, locked or out of lease.", "reason")
+
+// instrumentRequests counts and times every request, and the calls it makes
+// (see slo.go).
+func instrumentRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		start := time.Now()
+		ctx, timing := withTiming(ctx)
+		next(ctx, req, conn, addr)
+		countRequest(req, addr, time.Since(start), timing)
+	}
+}
+
+// countRequest counts a request from addr handled in elapsed, checking it
+// against its SLO. timing is nil for requests that cannot call out.
+func countRequest(req types.Request, addr string, elapsed time.Duration, timing *requestTiming) {
+	// unknown types share one label so clients can't grow the series
+	reqType := string(req.Type)
+	if _, ok := handlers[req.Type]; !ok {
+		reqType = "unknown"
+	}
+	requestsTotal.Inc(reqType)
+	handlerSeconds.Observe(reqType, elapsed.Seconds())
+	checkSLO(reqType, req, addr, elapsed, timing)
+	expMu.Lock()
+	defer expMu.Unlock()
+	expRequests++
+	expHandleTotal += elapsed
+	if elapsed > expHandleMax {
+		expHandleMax = elapsed
+	}
+}
+
+// traceRequests gives a request without a trace ID one, and traces it in.
+func traceRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if req.TraceID == "" {
+			req.TraceID = netproto.NewTraceID()
+		}
+		netproto.Tracef(req.TraceID, "📩 Received request from %s of type : %s", req.Player.ID, req.Type)
+		next(ctx, req, conn, addr)
+	}
+}
+
+// Whether a request comes from another server (a game server or central) is
+// decided by who sent it, never by the request: with -seal-secret, by the
+// seal it came with (netproto.SealNetwork.FromPeer); without, by its host
+// being that of a server this one knows of (knownServerHost). The latter
+// only holds where players cannot share a host with, or spoof the address
+// of, a server; a cluster open to players should set -seal-secret.
+//
+// authenticatePeers refuses the request types only servers send from
+// anyone else, and clears IsPeerReq on requests from players. The rest of
+// the chain reads the verdict with isPeer.
+func authenticatePeers(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if !req.Type.IsPeer() && !req.IsPeerReq {
+			next(ctx, req, conn, addr)
+			return
+		}
+		peer := fromPeer(addr)
+		if !peer && peerOnly(req.Type) {
+			requestsRefusedTotal.Inc("forbidden")
+			netproto.Tracef(req.TraceID, "🚫 %s from %s, which is not a server", req.Type, addr)
+			reply(conn, addr, req, types.Response{Success: false, Message: "Only servers may send " + string(req.Type), Code: types.CodeForbidden})
+			return
+		}
+		req.IsPeerReq = req.IsPeerReq && peer
+		next(context.WithValue(ctx, peerKey{}, peer), req, conn, addr)
+	}
+}
+
+type peerKey struct{}
+
+// isPeer reports whether authenticatePeers found ctx's request to come from
+// another server.
+func isPeer(ctx context.Context) bool {
+	peer, _ := ctx.Value(peerKey{}).(bool)
+	return peer
+}
+
+// peerOnly reports whether only servers send requests of type t. Players
+// send READ_ONLY too, to prefetch the chunks around them.
+func peerOnly(t types.RequestType) bool {
+	return t.IsPeer() && t != types.ReqReadOnly
+}
+
+// fromPeer reports whether addr is another server's. Must be called with
+// zone_map_Mu held.
+func fromPeer(addr string) bool {
+	if sealNet != nil {
+		return sealNet.FromPeer(addr)
+	}
+	host, _, err := net.SplitHostPort(addr)
+	return err == nil && knownServerHost(host)
+}
+
+// fromCentral reports whether addr is central's: a server, on the host of
+// one of the -central nodes. Must be called with zone_map_Mu held.
+func fromCentral(addr string) bool {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil || !fromPeer(addr) {
+		return false
+	}
+	for _, node := range centralURLs {
+		if hostOf(node) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// knownServerHost reports whether host is that of central, a -peers seed, a
+// gossip member, or a server owning or replicating a chunk this one knows
+// of. Must be called with zone_map_Mu held.
+func knownServerHost(host string) bool {
+	for _, node := range centralURLs {
+		if hostOf(node) == host {
+			return true
+		}
+	}
+	for _, seed := range gossipSeeds {
+		if hostOf(seed) == host {
+			return true
+		}
+	}
+	for member := range members {
+		if hostOf(member) == host {
+			return true
+		}
+	}
+	for _, replicas := range chunk_replicas {
+		for _, replica := range replicas {
+			if hostOf(replica) == host {
+				return true
+			}
+		}
+	}
+	for _, chunk := range zone_map {
+		if hostOf(chunk.ServerIP) == host {
+			return true
+		}
+	}
+	return false
+}
+
+// hostOf returns the host of a host:port address or a URL.
+func hostOf(addr string) string {
+	if u, err := url.Parse(addr); err == nil && u.Host != "" {
+		addr = u.Host
+	}
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// refuseKicked turns away requests from players kicked from this server.
+func refuseKicked(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if kick, ok := kicked[playerOf(req)]; ok && time.Now().Before(kick.Until) {
+			requestsRefusedTotal.Inc("kicked")
+			reply(conn, addr, req, types.Response{Success: false, Message: "Kicked: " + kick.Reason, Code: types.CodeKicked})
+			return
+		}
+		next(ctx, req, conn, addr)
+	}
+}
+
+// Players' requests are limited to requestRate a second with bursts of
+// requestBurst, per player (or per address, for requests naming none).
+// Requests from other servers (see authenticatePeers) are not limited.
+var (
+	requestRate  = 200.0
+	requestBurst = 400.0
+)
+
+// guarded by bucketsMu, as GET_UPDATES served from a chunk view is limited
+// without zone_map_Mu
+var (
+	bucketsMu       sync.Mutex
+	request_buckets = make(map[string]ChatBucket)
+)
+
+func limitRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if requestRate <= 0 || isPeer(ctx) {
+			next(ctx, req, conn, addr)
+			return
+		}
+		key := playerOf(req)
+		if key == "" {
+			key = addr
+		}
+		if wait, ok := takeRequest(key, time.Now()); !ok {
+			replyLimited(conn, addr, req, wait)
+			return
+		}
+		next(ctx, req, conn, addr)
+	}
+}
+
+// takeRequest takes a request from key's bucket, or reports how long until
+// there is one to take.
+func takeRequest(key string, now time.Time) (time.Duration, bool) {
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+	bucket, ok := request_buckets[key]
+	if !ok {
+		bucket = ChatBucket{Tokens: requestBurst, At: now}
+	}
+	bucket.Tokens = math.Min(requestBurst, bucket.Tokens+now.Sub(bucket.At).Seconds()*requestRate)
+	bucket.At = now
+	if bucket.Tokens < 1 {
+		request_buckets[key] = bucket
+		return time.Duration((1 - bucket.Tokens) / requestRate * float64(time.Second)), false
+	}
+	bucket.Tokens--
+	request_buckets[key] = bucket
+	return 0, true
+}
+
+func replyLimited(conn netproto.Transport, addr string, req types.Request, wait time.Duration) {
+	requestsRefusedTotal.Inc("limited")
+	reply(conn, addr, req, types.Response{Success: false, Message: "Too many requests, slow down", Code: types.CodeRateLimited,
+		RetryAfterMs: max(1, wait.Milliseconds())})
+}
+
+// sweepRequestBuckets forgets the buckets that have filled up again.
+func sweepRequestBuckets(now time.Time) {
+	if requestRate <= 0 {
+		return
+	}
+	bucketsMu.Lock()
+	defer bucketsMu.Unlock()
+	full := time.Duration(requestBurst / requestRate * float64(time.Second))
+	for key, bucket := range request_buckets {
+		if now.Sub(bucket.At) > full {
+			delete(request_buckets, key)
+		}
+	}
+}
+
+// maxIDLen bounds the player IDs a request may carry.
+const maxIDLen = 128
+
+// validateRequests refuses requests no handler should see: chunk IDs out
+// of range, a chunk sent for another chunk ID, a move in a world other than
+// its chunk's, or player IDs past maxIDLen. A bare chunk is given the
+// request's ID (see types.NormalizeChunk).
+func validateRequests(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if why := invalidRequest(&req); why != "" {
+			requestsRefusedTotal.Inc("invalid")
+			reply(conn, addr, req, types.Response{Success: false, Message: "Invalid request: " + why, Code: types.CodeBadRequest})
+			return
+		}
+		next(ctx, req, conn, addr)
+	}
+}
+
+// carriesChunk lists the requests whose Chunk is the chunk of their ChunkID.
+var carriesChunk = map[types.RequestType]bool{
+	types.ReqMerge:       true,
+	types.ReqReplicaSync: true,
+	types.ReqUpdateData:  true,
+}
+
+// invalidRequest says what is wrong with req, if anything, normalizing the
+// chunk it carries.
+func invalidRequest(req *types.Request) string {
+	if err := req.ChunkID.Validate(); err != nil {
+		return err.Error()
+	}
+	if carriesChunk[req.Type] {
+		if err := types.NormalizeChunk(req.ChunkID, &req.Chunk, req.BareChunk); err != nil {
+			return err.Error()
+		}
+	}
+	if peerOnly(req.Type) {
+		return ""
+	}
+	switch {
+	case len(req.Player.ID) > maxIDLen || len(req.PlayerID) > maxIDLen:
+		return fmt.Sprintf("Player IDs are at most %d bytes", maxIDLen)
+	case req.Type == types.ReqMovePlayer && req.Player.World != req.ChunkID.World:
+		return "player and chunk are in different worlds"
+	}
+	return ""
+}
+
+// refuseLocked turns away writes to chunks a transaction has locked.
+func refuseLocked(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if chunkLocked(req) {
+			requestsRefusedTotal.Inc("locked")
+			reply(conn, addr, req, types.Response{Success: false, Message: "Chunk is locked by a transaction, retry shortly", Code: types.CodeChunkLocked})
+			return
+		}
+		next(ctx, req, conn, addr)
+	}
+}
+
+// notePlayers records when a player was last heard from, and where.
+func notePlayers(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		if req.Player.ID != "" {
+			player_seen[req.Player.ID] = time.Now()
+			// a move forwarded by a peer comes from the peer, not the player
+			if !req.IsPeerReq {
+				player_addrs[req.Player.ID] = addr
+			}
+		}
+		next(ctx, req, conn, addr)
+	}
+}
+
+// publishChunks publishes the views of the chunks the request may have
+// changed: those flagged by markUnsaved (its own among them, by
+// touchChunks), and those its player was in before and after it.
+func publishChunks(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		was_in, was_held := players[req.Player.ID]
+		next(ctx, req, conn, addr)
+		if was_held {
+			view_dirty[was_in] = true
+		}
+		if is_in, held := players[req.Player.ID]; held {
+			view_dirty[is_in] = true
+		}
+		publishDirty()
+	}
+}
+
+// touchChunks keeps the request's chunk loaded while it is handled, and
+// saves it later if it changed.
+func touchChunks(next handlerFunc) handlerFunc {
+	return func(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+		touchChunk(req.ChunkID)
+		next(ctx, req, conn, addr)
+		markUnsaved(req.ChunkID)
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== NPCs =====================
+
+// NPCs (types.NPC) live in a chunk's NPCs and are simulated by its owner:
+// every npcEvery ticks each one takes a step chosen by the NPCBehavior
+// registered for its Kind. Only chunks with players or spectators are
+// stepped; the others stay frozen until someone comes back. With -npcs an
+// owned chunk with players is topped up to that many NPCs, one per step, of
+// the -npc-kinds in turn.
+//
+// Being part of the chunk, NPCs go wherever it does: into GET_DATA and
+// GET_UPDATES replies and spectate pushes (diffed like players), to the new
+// owner on migration, to read replicas, and into saved chunks. World files
+// leave them out, like players.
+
+// An NPCBehavior moves one NPC a step. It may change anything but the ID,
+// and must keep the NPC inside the chunk.
+type NPCBehavior interface {
+	Step(npc *types.NPC, around NPCView)
+}
+
+// NPCView is what an NPC's step can see: its chunk, and the chunk's extent
+// in world coordinates.
+type NPCView struct {
+	Chunk                  types.Chunk
+	MinX, MinY, MaxX, MaxY int // inclusive
+}
+
+func (v NPCView) clamp(x, y int) (int, int) {
+	return max(v.MinX, min(v.MaxX, x)), max(v.MinY, min(v.MaxY, y))
+}
+
+// npc_behaviors maps a Kind to what moves it. An NPC of a kind with no
+// behavior here (one a newer server registered) stands still.
+var npc_behaviors = map[string]NPCBehavior{
+	"wanderer": wanderer{},
+	"mob":      mob{Aggro: 5},
+}
+
+const npcEvery = 5 // ticks between NPC steps
+
+var (
+	npcsPerChunk = 0 // NPCs an owned chunk with players is topped up to
+	npcKinds     = []string{"wanderer", "mob"}
+)
+
+var _ = metrics.NewGaugeFunc("game_npcs",
+	"NPCs in chunks this server owns, by kind.", "kind", func() map[string]float64 {
+		zone_map_Mu.Lock()
+		defer zone_map_Mu.Unlock()
+		values := make(map[string]float64)
+		for _, chunk := range zone_map {
+			if chunk.ServerIP != serverIP {
+				continue
+			}
+			for _, npc := range chunk.NPCs {
+				values[npc.Kind]++
+			}
+		}
+		return values
+	})
+
+// parseNPCKinds sets npcKinds from a comma-separated list, refusing kinds
+// with no behavior.
+func parseNPCKinds(list string) error {
+	var kinds []string
+	for _, kind := range strings.Split(list, ",") {
+		if kind = strings.TrimSpace(kind); kind == "" {
+			continue
+		}
+		if _, ok := npc_behaviors[kind]; !ok {
+			return fmt.Errorf("unknown NPC kind %q", kind)
+		}
+		kinds = append(kinds, kind)
+	}
+	if len(kinds) == 0 {
+		return fmt.Errorf("no NPC kinds in %q", list)
+	}
+	npcKinds = kinds
+	return nil
+}
+
+// npcTick steps the NPCs of every owned chunk someone is watching. Must be
+// called with zone_map_Mu held.
+func npcTick(tick int64) {
+	if tick%npcEvery != 0 {
+		return
+	}
+	for chunk_id, chunk := range zone_map {
+		if chunk.ServerIP != serverIP || (len(chunk.PlayerList) == 0 && len(spectators[chunk_id]) == 0) {
+			continue
+		}
+		if len(chunk.NPCs) == 0 && (npcsPerChunk == 0 || len(chunk.PlayerList) == 0) {
+			continue
+		}
+		view := npcView(chunk_id, chunk)
+		npcs := append([]types.NPC(nil), chunk.NPCs...)
+		if len(npcs) < npcsPerChunk && len(chunk.PlayerList) > 0 {
+			npcs = append(npcs, spawnNPC(view, npcKinds[len(npcs)%len(npcKinds)]))
+		}
+		for i := range npcs {
+			if behavior, ok := npc_behaviors[npcs[i].Kind]; ok {
+				id := npcs[i].ID
+				behavior.Step(&npcs[i], view)
+				npcs[i].ID = id
+				npcs[i].PosX, npcs[i].PosY = view.clamp(npcs[i].PosX, npcs[i].PosY)
+			}
+		}
+		chunk.NPCs = npcs
+		zone_map[chunk_id] = chunk
+		markUnsaved(chunk_id)
+	}
+}
+
+func npcView(chunk_id types.ChunkID, chunk types.Chunk) NPCView {
+	edge := chunk_id.Edge(world.ChunkSize)
+	x, y := chunk_id.IDX*edge, chunk_id.IDY*edge
+	return NPCView{Chunk: chunk, MinX: x, MinY: y, MaxX: x + edge - 1, MaxY: y + edge - 1}
+}
+
+func spawnNPC(view NPCView, kind string) types.NPC {
+	return types.NPC{
+		ID:   fmt.Sprintf("npc-%016x", rand.Uint64()),
+		Kind: kind,
+		PosX: view.MinX + rand.Intn(view.MaxX-view.MinX+1),
+		PosY: view.MinY + rand.Intn(view.MaxY-view.MinY+1),
+	}
+}
+
+// wanderer takes a random step, or stays put.
+type wanderer struct{}
+
+func (wanderer) Step(npc *types.NPC, around NPCView) {
+	npc.PosX += rand.Intn(3) - 1
+	npc.PosY += rand.Intn(3) - 1
+}
+
+// mob steps towards the nearest player within Aggro cells, and wanders when
+// there is none.
+type mob struct {
+	Aggro int
+}
+
+func (m mob) Step(npc *types.NPC, around NPCView) {
+	npc.Target = ""
+	best := m.Aggro + 1
+	var prey types.Player
+	for _, player := range around.Chunk.PlayerList {
+		if d := max(abs(player.PosX-npc.PosX), abs(player.PosY-npc.PosY)); d < best {
+			best, prey, npc.Target = d, player, player.ID
+		}
+	}
+	if npc.Target == "" {
+		wanderer{}.Step(npc, around)
+		return
+	}
+	npc.PosX += sign(prey.PosX - npc.PosX)
+	npc.PosY += sign(prey.PosY - npc.PosY)
+}
+
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
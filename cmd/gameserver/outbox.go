@@ -0,0 +1,300 @@
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Outbox =====================
+
+// Pushes (the PUSH_* messages a client did not ask for) are not sent as they
+// are made but queued per destination and flushed once a tick, so a client
+// gets one datagram per tick however many chats, notices and spectated
+// chunks changed: a single push goes out as it is, several as one
+// PUSH_BATCH. Each destination may be sent pushBudget bytes a tick, most
+// urgent first:
+//
+//   - notices: chat, whispers, announcements, matches
+//   - position updates: spectate deltas that only move players
+//   - bulk: whole chunks and deltas with cube changes
+//
+// What does not fit waits for the next tick, in order, so a busy chunk's
+// cube syncs fall behind before anyone's position updates do; a chunk's own
+// updates always stay in order. A queue
+// longer than maxQueuedPushes drops its oldest bulk first; pushes are as
+// lossy as UDP anyway and spectators catch up from their next update.
+// Replies and kicks are never queued.
+//
+// With -client-bandwidth each client also has a bytes/sec allowance, a
+// second's worth of burst. A client over it degrades rather than having its
+// link saturated: its spectated chunks are not diffed again while an update
+// is still waiting, so it gets fewer, larger updates, and a position update
+// that does not fit loses the players farthest from the client (from the
+// chunk's centre, for a spectator who is not playing) until it does.
+// Notices always go.
+
+type pushPriority int
+
+const (
+	pushNotice pushPriority = iota
+	pushPosition
+	pushBulk
+)
+
+func (p pushPriority) String() string {
+	return [...]string{"notice", "position", "bulk"}[p]
+}
+
+// pushBudget is the bytes of pushes one destination may be sent a tick; the
+// first push always goes, however large.
+var pushBudget = 16 << 10
+
+// clientBandwidth is the bytes per second of pushes one client may be sent;
+// 0 is unlimited.
+var clientBandwidth = 0
+
+const maxQueuedPushes = 256
+
+type queuedPush struct {
+	Res  types.Response
+	Prio pushPriority
+	Size int
+}
+
+type pushQueue struct {
+	Conn    netproto.Transport // of the latest push
+	Pending []queuedPush
+	// Tokens are the bytes the client may be sent now, refilled at
+	// clientBandwidth since Filled.
+	Tokens float64
+	Filled time.Time
+}
+
+// pushConn is the socket pushes not made in answer to a request leave on:
+// those of the tick and of the admin API.
+var pushConn netproto.Transport
+
+var (
+	outboxMu sync.Mutex
+	outbox   = make(map[string]*pushQueue) // by destination address
+)
+
+var (
+	pushesTotal = metrics.NewCounterVec("game_pushes_total",
+		"Pushes queued for clients, by priority.", "priority")
+	pushesDroppedTotal = metrics.NewCounterVec("game_pushes_dropped_total",
+		"Pushes dropped from a full outbox queue, by priority.", "priority")
+	pushDatagramsTotal = metrics.NewCounterVec("game_push_datagrams_total",
+		"Datagrams the outbox sent: single pushes or batches.", "kind")
+	pushThrottledTotal = metrics.NewCounterVec("game_pushes_throttled_total",
+		"Push updates held back or thinned for clients over their bandwidth: skipped (a spectate round) or trimmed (distant players dropped).", "action")
+)
+
+// push queues res for addr, to go out with the next flush.
+func push(conn netproto.Transport, addr string, res types.Response) {
+	data, err := json.Marshal(res)
+	if err != nil {
+		return
+	}
+	prio := priorityOf(res)
+
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	q := outbox[addr]
+	if q == nil {
+		q = &pushQueue{Tokens: float64(clientBandwidth), Filled: time.Now()}
+		outbox[addr] = q
+	}
+	q.Conn = conn
+	// a spectated chunk's updates chain version to version, so one must not
+	// overtake an earlier one still waiting
+	for _, p := range q.Pending {
+		if res.Code == types.CodeSpectate && p.Res.Code == types.CodeSpectate && *p.Res.ChunkID == *res.ChunkID {
+			prio = max(prio, p.Prio)
+		}
+	}
+	pushesTotal.Inc(prio.String())
+	q.Pending = append(q.Pending, queuedPush{Res: res, Prio: prio, Size: len(data)})
+	if len(q.Pending) > maxQueuedPushes {
+		dropped := dropOne(q.Pending)
+		pushesDroppedTotal.Inc(q.Pending[dropped].Prio.String())
+		q.Pending = append(q.Pending[:dropped], q.Pending[dropped+1:]...)
+	}
+}
+
+func priorityOf(res types.Response) pushPriority {
+	switch {
+	case res.Code != types.CodeSpectate, !res.Success: // notices, and a spectated chunk moving away
+		return pushNotice
+	case res.Delta != nil && len(res.Delta.Cubes) == 0 && len(res.Delta.RemovedCubes) == 0:
+		return pushPosition
+	}
+	return pushBulk
+}
+
+// dropOne picks the oldest of the least urgent pushes in pending.
+func dropOne(pending []queuedPush) int {
+	worst := 0
+	for i, p := range pending {
+		if p.Prio > pending[worst].Prio {
+			worst = i
+		}
+	}
+	return worst
+}
+
+// behind reports whether addr still has pushes waiting, or has used up its
+// bandwidth, so no new spectate update should be made for it yet.
+func behind(addr string) bool {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	q := outbox[addr]
+	if q == nil || (len(q.Pending) == 0 && (clientBandwidth <= 0 || q.Tokens > 0)) {
+		return false
+	}
+	pushThrottledTotal.Inc("skipped")
+	return true
+}
+
+// refill adds the allowance earned since q was last filled.
+func (q *pushQueue) refill(now time.Time) {
+	if clientBandwidth <= 0 {
+		return
+	}
+	q.Tokens = math.Min(float64(clientBandwidth), q.Tokens+now.Sub(q.Filled).Seconds()*float64(clientBandwidth))
+	q.Filled = now
+}
+
+// flushOutbox sends every destination what fits in its budget this tick.
+// Must be called with zone_map_Mu held, for stampClock and trimDistant.
+func flushOutbox() {
+	outboxMu.Lock()
+	defer outboxMu.Unlock()
+	now := time.Now()
+	// every destination's datagram goes out in one batch per socket
+	sends := make(map[netproto.Transport][]netproto.Message)
+	send := func(conn netproto.Transport, addr string, res types.Response) {
+		data, err := netproto.Wire.Marshal(res)
+		if err != nil {
+			log.Println("JSON marshal error:", err)
+			return
+		}
+		sends[conn] = append(sends[conn], netproto.Message{To: addr, Data: data})
+	}
+	defer func() {
+		for conn, msgs := range sends {
+			if err := netproto.SendBatch(conn, msgs); err != nil {
+				log.Printf("❌ Error sending pushes: %v", err)
+			}
+		}
+	}()
+
+	for addr, q := range outbox {
+		q.refill(now)
+		budget := pushBudget
+		if clientBandwidth > 0 {
+			budget = min(budget, int(q.Tokens))
+		}
+		sort.SliceStable(q.Pending, func(i, j int) bool { return q.Pending[i].Prio < q.Pending[j].Prio })
+
+		n, size := 0, 0
+		for ; n < len(q.Pending); n++ {
+			p := &q.Pending[n]
+			if p.Prio == pushNotice || size+p.Size <= budget {
+				size += p.Size
+				continue
+			}
+			if p.Prio == pushPosition && trimDistant(p, addr, budget-size) {
+				pushThrottledTotal.Inc("trimmed")
+				size += p.Size
+				continue
+			}
+			// an idle client gets its first push, however large
+			if n == 0 && (clientBandwidth <= 0 || q.Tokens >= float64(clientBandwidth)) {
+				size += p.Size
+				n++
+			}
+			break
+		}
+		if clientBandwidth > 0 {
+			q.Tokens -= float64(size)
+		}
+
+		sending := q.Pending[:n]
+		switch {
+		case len(sending) == 1:
+			res := sending[0].Res
+			stampClock(&res)
+			send(q.Conn, addr, res)
+			pushDatagramsTotal.Inc("single")
+		case len(sending) > 1:
+			batch := types.Response{Success: true, Code: types.CodeBatch, Pushes: make([]types.Response, len(sending))}
+			for i, p := range sending {
+				batch.Pushes[i] = p.Res
+				stampClock(&batch.Pushes[i])
+			}
+			stampClock(&batch)
+			send(q.Conn, addr, batch)
+			pushDatagramsTotal.Inc("batch")
+		}
+
+		q.Pending = append([]queuedPush(nil), q.Pending[n:]...)
+		// keep an emptied queue until its allowance is whole again
+		if len(q.Pending) == 0 && (clientBandwidth <= 0 || q.Tokens >= float64(clientBandwidth)) {
+			delete(outbox, addr)
+		}
+	}
+}
+
+// trimDistant drops the players farthest from addr's viewpoint out of p's
+// delta until p fits in room bytes, and reports whether it does. Removals
+// are never dropped. Must be called with zone_map_Mu held.
+func trimDistant(p *queuedPush, addr string, room int) bool {
+	delta := p.Res.Delta
+	if delta == nil || len(delta.Players) == 0 {
+		return false
+	}
+	x, y := viewpoint(addr, *p.Res.ChunkID)
+	players := append([]types.Player(nil), delta.Players...)
+	sort.Slice(players, func(i, j int) bool {
+		return math.Hypot(float64(players[i].PosX-x), float64(players[i].PosY-y)) <
+			math.Hypot(float64(players[j].PosX-x), float64(players[j].PosY-y))
+	})
+
+	size := p.Size
+	for len(players) > 0 && size > room {
+		data, _ := json.Marshal(players[len(players)-1])
+		size -= len(data) + 1
+		players = players[:len(players)-1]
+	}
+	if size > room {
+		return false
+	}
+	trimmed := *delta
+	trimmed.Players = players
+	p.Res.Delta = &trimmed
+	p.Size = size
+	return true
+}
+
+// viewpoint is where the client at addr looks from: its player, or the
+// centre of chunk_id for a spectator. Must be called with zone_map_Mu held.
+func viewpoint(addr string, chunk_id types.ChunkID) (int, int) {
+	for player_id, player_addr := range player_addrs {
+		if player_addr == addr {
+			if player, ok := player_map[player_id]; ok {
+				return player.PosX, player.PosY
+			}
+		}
+	}
+	edge := chunk_id.Edge(world.ChunkSize)
+	return chunk_id.IDX*edge + edge/2, chunk_id.IDY*edge + edge/2
+}
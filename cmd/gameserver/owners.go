@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/ownercache"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Ownership cache =====================
+
+// With -owner-cache a server remembers the owners central names at /chunk,
+// in process or in a Redis the servers share, and routes a player entering
+// a chunk nobody here is in straight to its cached owner, the way it does
+// on a gossip hint (MERGE with OwnerHint), before asking central. Central
+// sends every server OWNER_CHANGED when a chunk changes owner, and a
+// server's own migrations update the cache too; an entry missed by both
+// is refused by the server it names and dropped, or expires after
+// -owner-cache-ttl. A cache that fails or is slower than ownerCacheTimeout
+// counts as a miss.
+
+const ownerCacheTimeout = 100 * time.Millisecond
+
+// ownerCache is nil without -owner-cache.
+var ownerCache ownercache.Cache
+
+var ownerCacheTotal = metrics.NewCounterVec("game_owner_cache_total",
+	"Ownership cache lookups and updates by outcome: hit, miss, stale (refused by the cached owner), error, set or invalidated.", "result")
+
+// startOwnerCache turns the cache on. Must be called before the server
+// starts serving.
+func startOwnerCache(cache ownercache.Cache) {
+	ownerCache = cache
+	Subscribe("owners", ownersSubscriber)
+}
+
+// ownersSubscriber caches where this server's chunks went, and the chunks
+// it took over as its own, for the servers sharing the cache.
+func ownersSubscriber(ev GameEvent) {
+	if ev, ok := ev.(ChunkMigrated); ok {
+		if ev.Direction == "in" {
+			cacheOwner(ev.ChunkID, serverIP)
+		} else if ev.Peer != "" {
+			cacheOwner(ev.ChunkID, ev.Peer)
+		}
+	}
+}
+
+// cachedOwner returns the cached owner of chunk_id.
+func cachedOwner(chunk_id types.ChunkID) (string, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), ownerCacheTimeout)
+	defer cancel()
+	owner, ok, err := ownerCache.Get(ctx, chunk_id)
+	switch {
+	case err != nil:
+		ownerCacheTotal.Inc("error")
+		return "", false
+	case !ok:
+		ownerCacheTotal.Inc("miss")
+		return "", false
+	}
+	return owner, true
+}
+
+// cacheOwner records owner as chunk_id's.
+func cacheOwner(chunk_id types.ChunkID, owner string) {
+	if ownerCache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ownerCacheTimeout)
+	defer cancel()
+	if err := ownerCache.Set(ctx, chunk_id, owner); err != nil {
+		ownerCacheTotal.Inc("error")
+		return
+	}
+	ownerCacheTotal.Inc("set")
+}
+
+func forgetOwner(chunk_id types.ChunkID) {
+	if ownerCache == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), ownerCacheTimeout)
+	defer cancel()
+	if err := ownerCache.Delete(ctx, chunk_id); err != nil {
+		ownerCacheTotal.Inc("error")
+		return
+	}
+	ownerCacheTotal.Inc("invalidated")
+}
+
+// routeByCache merges player into the cached owner of chunk_id, like
+// routeByGossip. Must be called with zone_map_Mu held.
+func routeByCache(ctx context.Context, chunk_id types.ChunkID, local types.Chunk, player types.Player, trace string) (types.Response, bool) {
+	if ownerCache == nil || len(local.PlayerList) > 0 {
+		return types.Response{}, false
+	}
+	owner, ok := cachedOwner(chunk_id)
+	if !ok || owner == serverIP {
+		// this server's own entry is stale, or it would hold the chunk
+		return types.Response{}, false
+	}
+
+	res, ok := routeOnHint(ctx, chunk_id, owner, player, trace)
+	if !ok {
+		ownerCacheTotal.Inc("stale")
+		forgetOwner(chunk_id)
+		netproto.Tracef(trace, "🗃️  Cached owner %s of chunk [%d,%d] was stale", owner, chunk_id.IDX, chunk_id.IDY)
+		return types.Response{}, false
+	}
+	ownerCacheTotal.Inc("hit")
+	netproto.Tracef(trace, "🗃️  Chunk [%d,%d] routed to cached owner %s", chunk_id.IDX, chunk_id.IDY, owner)
+	return res, true
+}
+
+// handleOwnerChanged updates the cache with central's word on a migration.
+func handleOwnerChanged(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if req.Owner == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing owner", Code: types.CodeBadRequest})
+		return
+	}
+	cacheOwner(req.ChunkID, req.Owner)
+	reply(conn, addr, req, types.Response{Success: true})
+}
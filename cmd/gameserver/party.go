@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Parties =====================
+
+// Central sends every party's member list with PARTY_UPDATE. A player in a
+// party gets their party members with every GET_UPDATES, whichever chunk
+// they are in: the ones held here from player_map, the ones held elsewhere
+// from the PartyPlayers the other servers gossip. Members no server
+// advertises (offline, or on a server this one does not gossip with) are
+// left out.
+
+// guarded by zone_map_Mu
+var (
+	parties      = make(map[string]types.Party)
+	player_party = make(map[string]string) // player ID -> party ID
+)
+
+func handlePartyUpdate(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if req.Party == nil || req.Party.ID == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing party", Code: types.CodeBadRequest})
+		return
+	}
+
+	party := *req.Party
+	if old, ok := parties[party.ID]; ok {
+		for _, member := range old.Members {
+			if player_party[member] == party.ID {
+				delete(player_party, member)
+			}
+		}
+	}
+	if len(party.Members) == 0 {
+		delete(parties, party.ID)
+	} else {
+		parties[party.ID] = party
+		for _, member := range party.Members {
+			player_party[member] = party.ID
+		}
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Party updated"})
+	log.Printf("🎉 Party %s now %v", party.ID, party.Members)
+}
+
+// partyPlayers returns the players held here who are in a party, up to
+// gossipMaxParty, for gossip. Must be called with zone_map_Mu held.
+func partyPlayers() []types.Player {
+	var list []types.Player
+	for player_id := range player_party {
+		if len(list) == gossipMaxParty {
+			break
+		}
+		if player, held := heldPlayer(player_id); held {
+			list = append(list, player)
+		}
+	}
+	return list
+}
+
+// partyView returns where player_id's party members are. Must be called
+// with zone_map_Mu held.
+func partyView(player_id string) []types.Player {
+	party, ok := parties[player_party[player_id]]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	var list []types.Player
+	for _, member := range party.Members {
+		if member == player_id {
+			continue
+		}
+		if player, held := heldPlayer(member); held {
+			list = append(list, player)
+			continue
+		}
+		if player, found := gossipedPlayer(member, now); found {
+			list = append(list, player)
+		}
+	}
+	return list
+}
+
+// gossipedPlayer looks player_id up in the live members' PartyPlayers. Must
+// be called with zone_map_Mu held.
+func gossipedPlayer(player_id string, now time.Time) (types.Player, bool) {
+	for _, member := range members {
+		if !member.alive(now) {
+			continue
+		}
+		for _, player := range member.State.PartyPlayers {
+			if player.ID == player_id {
+				return player, true
+			}
+		}
+	}
+	return types.Player{}, false
+}
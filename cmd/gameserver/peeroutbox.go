@@ -0,0 +1,295 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Peer outbox =====================
+
+// A chunk given away on central's word (FROM_CENTRAL) is sent to its new
+// owner with MERGE, and the players in it follow with PLAYER_TRANSFER. If
+// the new owner does not answer, the message is queued here rather than
+// lost, and sent again when it is due (checked every reportEvery ticks),
+// backing off exponentially (with jitter) from deliveryBackoff to
+// maxDeliveryBackoff. A delivery that has not gone through after
+// deliveryGiveUp is dropped. With -data-dir the
+// queue is kept in peer_outbox.json there, so it survives a restart.
+//
+// A queued MERGE carries the chunk as it was given away; it is dropped if
+// the chunk is owned here again by the time it is due. A queued transfer
+// carries only the chunk: the players still held here in it are collected
+// again when it is sent, so a player who has since left is not sent, and
+// the receiver ignores a player it has a later state of. Merges made for a
+// player's GET_DATA are not queued; the player asks again.
+//
+// GET /admin/outbox lists what is waiting, DELETE /admin/outbox?id= drops
+// one delivery.
+
+var (
+	deliveryBackoff    = time.Second
+	maxDeliveryBackoff = time.Minute
+	deliveryGiveUp     = 10 * time.Minute
+)
+
+const (
+	maxDeliveries      = 1024
+	deliveryStuckAfter = 5 // attempts, after which GET /admin/outbox flags a delivery stuck
+	peerOutboxFile     = "peer_outbox.json"
+)
+
+// Delivery is a message to a peer waiting to be sent again.
+type Delivery struct {
+	ID        string        `json:"id"`
+	Peer      string        `json:"peer"`
+	Req       types.Request `json:"request"`
+	Attempts  int           `json:"attempts"`
+	QueuedAt  time.Time     `json:"queued_at"`
+	NextAt    time.Time     `json:"next_at"`
+	LastError string        `json:"last_error,omitempty"`
+}
+
+// guarded by zone_map_Mu; oldest first
+var peer_outbox []*Delivery
+
+var (
+	deliveriesTotal = metrics.NewCounterVec("game_peer_deliveries_total",
+		"Messages to peers queued after a failed send, by what became of them.", "result")
+	_ = metrics.NewGaugeFunc("game_peer_outbox",
+		"Messages to peers waiting to be sent again, by type.", "type", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			values := map[string]float64{string(types.ReqMerge): 0, string(types.ReqPlayerTransfer): 0}
+			for _, d := range peer_outbox {
+				values[string(d.Req.Type)]++
+			}
+			return values
+		})
+	_ = metrics.NewGaugeFunc("game_peer_outbox_oldest_seconds",
+		"Age of the oldest message to a peer waiting to be sent again.", "", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			age := 0.0
+			if len(peer_outbox) > 0 {
+				age = time.Since(peer_outbox[0].QueuedAt).Seconds()
+			}
+			return map[string]float64{"": age}
+		})
+)
+
+// queueDelivery queues req to be sent to peer again after it failed with
+// err. A transfer is queued without its players, and replaces one already
+// queued for the same chunk and peer, as a MERGE does. Must be called with
+// zone_map_Mu held.
+func queueDelivery(req types.Request, peer string, err error) {
+	if req.Type == types.ReqPlayerTransfer {
+		req.Handoffs = nil
+	}
+	now := time.Now()
+	for _, d := range peer_outbox {
+		if d.Peer == peer && d.Req.Type == req.Type && d.Req.ChunkID == req.ChunkID {
+			d.Req, d.LastError = req, err.Error()
+			saveOutbox()
+			return
+		}
+	}
+	if len(peer_outbox) >= maxDeliveries {
+		log.Printf("❌ Peer outbox full, dropping %s of chunk [%d,%d] to %s", peer_outbox[0].Req.Type,
+			peer_outbox[0].Req.ChunkID.IDX, peer_outbox[0].Req.ChunkID.IDY, peer_outbox[0].Peer)
+		peer_outbox = peer_outbox[1:]
+		deliveriesTotal.Inc("dropped")
+	}
+	peer_outbox = append(peer_outbox, &Delivery{ID: fmt.Sprintf("%016x", rand.Uint64()), Peer: peer, Req: req,
+		Attempts: 1, QueuedAt: now, NextAt: now.Add(deliveryWait(1)), LastError: err.Error()})
+	deliveriesTotal.Inc("queued")
+	saveOutbox()
+}
+
+// deliveryWait is how long to wait after the attempts-th failed send:
+// deliveryBackoff doubled for each one before, at most maxDeliveryBackoff,
+// half of it jittered.
+func deliveryWait(attempts int) time.Duration {
+	d := deliveryBackoff << (attempts - 1)
+	if d <= 0 || d > maxDeliveryBackoff {
+		d = maxDeliveryBackoff
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// retryDeliveries sends the queued messages that are due. It must be called
+// without zone_map_Mu held, as it waits on the peers.
+func retryDeliveries() {
+	type sending struct {
+		d   *Delivery
+		req types.Request
+	}
+	zone_map_Mu.Lock()
+	now := time.Now()
+	var due []sending
+	kept := peer_outbox[:0]
+	for _, d := range peer_outbox {
+		if now.Sub(d.QueuedAt) > deliveryGiveUp {
+			log.Printf("❌ Giving up on %s of chunk [%d,%d] to %s after %d attempts: %s", d.Req.Type,
+				d.Req.ChunkID.IDX, d.Req.ChunkID.IDY, d.Peer, d.Attempts, d.LastError)
+			deliveriesTotal.Inc("expired")
+			continue
+		}
+		if chunk, ok := zone_map[d.Req.ChunkID]; ok && chunk.ServerIP == serverIP {
+			// taken back since; nothing to hand over
+			deliveriesTotal.Inc("stale")
+			continue
+		}
+		kept = append(kept, d)
+		if now.Before(d.NextAt) {
+			continue
+		}
+		req := d.Req
+		if req.Type == types.ReqPlayerTransfer {
+			req.Handoffs = collectHandoffs(req.ChunkID, types.Player{})
+		}
+		due = append(due, sending{d, req})
+	}
+	if len(kept) < len(peer_outbox) {
+		peer_outbox = kept
+		saveOutbox()
+	}
+	zone_map_Mu.Unlock()
+
+	results := make([]error, len(due))
+	for i, s := range due {
+		if s.req.Type == types.ReqPlayerTransfer && len(s.req.Handoffs) == 0 {
+			continue
+		}
+		_, results[i] = p2p(context.Background(), s.req, s.d.Peer)
+	}
+
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	delivered := make(map[*Delivery]bool)
+	for i, s := range due {
+		d := s.d
+		if s.req.Type == types.ReqPlayerTransfer && len(s.req.Handoffs) == 0 {
+			// everyone left before it went through
+			delivered[d] = true
+			deliveriesTotal.Inc("stale")
+			continue
+		}
+		if err := results[i]; err != nil {
+			d.Attempts++
+			d.NextAt = time.Now().Add(deliveryWait(d.Attempts))
+			d.LastError = err.Error()
+			continue
+		}
+		if len(s.req.Handoffs) > 0 {
+			finishTransfer(s.req.Handoffs, d.Peer)
+		}
+		delivered[d] = true
+		deliveriesTotal.Inc("delivered")
+		log.Printf("📬 %s of chunk [%d,%d] delivered to %s after %d attempts", d.Req.Type,
+			d.Req.ChunkID.IDX, d.Req.ChunkID.IDY, d.Peer, d.Attempts+1)
+	}
+	kept = peer_outbox[:0]
+	for _, d := range peer_outbox {
+		if !delivered[d] {
+			kept = append(kept, d)
+		}
+	}
+	peer_outbox = kept
+	if len(due) > 0 {
+		saveOutbox()
+	}
+}
+
+// saveOutbox writes the queue to -data-dir, if set. Must be called with
+// zone_map_Mu held.
+func saveOutbox() {
+	if dataDir == "" {
+		return
+	}
+	data, err := json.MarshalIndent(peer_outbox, "", "  ")
+	if err == nil {
+		path := filepath.Join(dataDir, peerOutboxFile)
+		if err = os.WriteFile(path+".tmp", data, 0o644); err == nil {
+			err = os.Rename(path+".tmp", path)
+		}
+	}
+	if err != nil {
+		log.Printf("❌ Saving the peer outbox failed: %v", err)
+	}
+}
+
+// loadOutbox reads back the queue saved in -data-dir.
+func loadOutbox() error {
+	data, err := os.ReadFile(filepath.Join(dataDir, peerOutboxFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, &peer_outbox); err != nil {
+		return err
+	}
+	if len(peer_outbox) > 0 {
+		log.Printf("📬 %d messages to peers waiting in the outbox", len(peer_outbox))
+	}
+	return nil
+}
+
+// AdminDelivery is one entry of GET /admin/outbox.
+type AdminDelivery struct {
+	ID         string        `json:"id"`
+	Type       string        `json:"type"`
+	ChunkID    types.ChunkID `json:"chunk_id"`
+	Peer       string        `json:"peer"`
+	Attempts   int           `json:"attempts"`
+	AgeSeconds float64       `json:"age_seconds"`
+	NextAt     time.Time     `json:"next_at"`
+	LastError  string        `json:"last_error,omitempty"`
+	Stuck      bool          `json:"stuck"`
+}
+
+// handleAdminOutbox serves /admin/outbox: GET lists the queued deliveries,
+// oldest first; DELETE ?id= drops one.
+func handleAdminOutbox(w http.ResponseWriter, r *http.Request) {
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	switch r.Method {
+	case http.MethodGet:
+		now := time.Now()
+		list := make([]AdminDelivery, 0, len(peer_outbox))
+		for _, d := range peer_outbox {
+			list = append(list, AdminDelivery{ID: d.ID, Type: string(d.Req.Type), ChunkID: d.Req.ChunkID, Peer: d.Peer,
+				Attempts: d.Attempts, AgeSeconds: now.Sub(d.QueuedAt).Seconds(), NextAt: d.NextAt, LastError: d.LastError,
+				Stuck: d.Attempts >= deliveryStuckAfter})
+		}
+		writeAdminJSON(w, list)
+
+	case http.MethodDelete:
+		id := r.URL.Query().Get("id")
+		for i, d := range peer_outbox {
+			if d.ID == id {
+				peer_outbox = append(peer_outbox[:i], peer_outbox[i+1:]...)
+				deliveriesTotal.Inc("discarded")
+				journal.Record(WorldEvent{Type: "OUTBOX_DISCARD", ChunkID: d.Req.ChunkID, Detail: fmt.Sprintf("%s to %s (admin)", d.Req.Type, d.Peer)})
+				saveOutbox()
+				writeAdminJSON(w, types.Response{Success: true, Message: "Delivery dropped"})
+				return
+			}
+		}
+		http.Error(w, "No such delivery", http.StatusNotFound)
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"log"
+	"slices"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk persistence =====================
+
+// Owned chunks are written to a chunkstore under dataDir, or with
+// -postgres to a database the servers share. At startup only the names of
+// the chunks are indexed; a chunk is read back the first time a request
+// touches it. A save pass copies the changed chunks under zone_map_Mu and
+// writes them saveBatch at a time from a goroutine of its own, without the
+// lock, so the tick and requests do not wait on the disk or the database.
+// A pass starts only once the last one is done, so writes of a chunk land in
+// order, and chunks being written are not evicted. An admin flush writes
+// its chunk the same way (saveOne), in place of a pass.
+//
+// Every reportEvery ticks, after the save pass, chunks are evicted from
+// zone_map, least recently used first: as many as it takes to get down to
+// maxHotChunks, and any idle for longer than chunkTTL. Only chunks nobody is
+// in, queued for, locked by a transaction or spectating are evicted, and
+// what becomes of one depends on what it is:
+//
+//   - an owned chunk, with a store: only once its last change is saved,
+//     and it is read back from the store the next time a request touches it
+//   - an owned chunk, without a store: only if it is still as the
+//     generator made it (no edits, NPCs or items), and it is generated
+//     again the next time a request touches it
+//   - a copy of a chunk another server owns: dropped; the next GET_DATA
+//     for it asks central as if it had never been here
+//
+// Owned chunks that were changed are never evicted without a store, since
+// the change would be lost.
+
+var (
+	dataDir      string
+	maxHotChunks int
+	chunkTTL     = 10 * time.Minute
+	postgresURL  string
+	store        chunkstore.Backend // nil when persistence is disabled
+
+	cold_chunks  = make(map[types.ChunkID]string)    // persisted but not read back
+	fresh_chunks = make(map[types.ChunkID]bool)      // owned and evicted, generated again when touched
+	chunk_used   = make(map[types.ChunkID]time.Time) // last request per hot chunk
+	unsaved      = make(map[types.ChunkID]bool)      // hot chunks changed since their last save
+	saving       = make(map[types.ChunkID]bool)      // chunks the running save pass or an admin flush is writing
+)
+
+var (
+	chunksEvictedTotal = metrics.NewCounterVec("game_chunks_evicted_total",
+		"Chunks dropped from memory: over the hot cap (cap) or idle past the TTL (ttl).", "reason")
+	chunksReloadedTotal = metrics.NewCounterVec("game_chunks_reloaded_total",
+		"Evicted owned chunks brought back by a request, by where from.", "source")
+)
+
+const saveBatch = 128
+
+// openChunkStore opens the database, or else dataDir, and records which
+// chunks were persisted without reading them.
+func openChunkStore() error {
+	var s chunkstore.Backend
+	where := dataDir
+	if postgresURL != "" {
+		pg, err := chunkstore.OpenPostgres(postgresURL, serverIP, world)
+		if err != nil {
+			return err
+		}
+		s, where = pg, pg.Name()
+	} else {
+		dir, err := chunkstore.Open(dataDir)
+		if err != nil {
+			return err
+		}
+		s = dir
+	}
+	index, err := s.Index()
+	if err != nil {
+		return err
+	}
+	store = s
+	cold_chunks = index
+	log.Printf("💾 %d persisted chunks indexed in %s", len(cold_chunks), where)
+	return nil
+}
+
+// touchChunk makes sure an evicted owned chunk is back in zone_map before a
+// handler reads it, and notes it as used. Must be called with zone_map_Mu
+// held.
+func touchChunk(chunk_id types.ChunkID) {
+	if _, hot := zone_map[chunk_id]; !hot {
+		if path, ok := cold_chunks[chunk_id]; ok {
+			chunk, err := store.Load(path)
+			if err != nil {
+				log.Printf("❌ Loading chunk [%d,%d] failed: %v", chunk_id.IDX, chunk_id.IDY, err)
+				return
+			}
+			zone_map[chunk_id] = chunk
+			chunksReloadedTotal.Inc("store")
+			log.Printf("💾 Chunk [%d,%d] loaded from the store", chunk_id.IDX, chunk_id.IDY)
+		} else if fresh_chunks[chunk_id] {
+			zone_map[chunk_id] = generatedChunk(chunk_id)
+			chunksReloadedTotal.Inc("generator")
+		}
+	}
+	delete(cold_chunks, chunk_id)
+	delete(fresh_chunks, chunk_id)
+	chunk_used[chunk_id] = time.Now()
+}
+
+// generatedChunk is chunk_id as the generator makes it, owned here.
+func generatedChunk(chunk_id types.ChunkID) types.Chunk {
+	return types.Chunk{World: chunk_id.World, IDX: chunk_id.IDX, IDY: chunk_id.IDY, Level: chunk_id.Level, Data: "new chunk",
+		ServerIP: serverIP, Cells: generator.Generate(chunk_id, chunk_id.Edge(world.ChunkSize))}
+}
+
+// markUnsaved flags a chunk for the next save pass, the next sync to its
+// read replicas and the next publication of its view. Must be called with
+// zone_map_Mu held.
+func markUnsaved(chunk_id types.ChunkID) {
+	markReplicaDirty(chunk_id)
+	view_dirty[chunk_id] = true
+	if store == nil {
+		return
+	}
+	if _, ok := zone_map[chunk_id]; ok {
+		unsaved[chunk_id] = true
+	}
+}
+
+// saveOne copies one hot chunk for saving at once, without zone_map_Mu (an
+// admin flush): the chunk if it is owned here, or else the removal of its
+// saved copy. It is flagged saving until endSave, so that no save pass
+// writes it meanwhile. It returns false if a save pass is writing it now.
+// Must be called with zone_map_Mu held.
+func saveOne(chunk_id types.ChunkID) (chunkBatch, bool) {
+	if saving[chunk_id] {
+		return chunkBatch{}, false
+	}
+	batch := chunkBatch{chunks: make(map[types.ChunkID]types.Chunk)}
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		batch.chunks[chunk_id] = chunkstore.Clone(chunk)
+	} else {
+		batch.gone = []types.ChunkID{chunk_id}
+	}
+	delete(unsaved, chunk_id)
+	saving[chunk_id] = true
+	return batch, true
+}
+
+// endSave records how a saveOne write went: a chunk that failed is flagged
+// unsaved again, for the next pass. Must be called with zone_map_Mu held.
+func endSave(chunk_id types.ChunkID, err error) {
+	delete(saving, chunk_id)
+	if err != nil {
+		unsaved[chunk_id] = true
+	}
+}
+
+// persistDirtyChunks starts a save pass: every changed owned chunk is
+// saved, and the saved copies of those that moved away removed, saveBatch
+// at a time by flushChunks. Nothing is started while the last pass or an
+// admin flush is still writing, or the lease from central is out (see
+// leaseExpired). Must be called with zone_map_Mu held.
+func persistDirtyChunks() {
+	if store == nil || len(saving) > 0 || len(unsaved) == 0 || leaseExpired(time.Now()) {
+		return
+	}
+	var batches []chunkBatch
+	batch := chunkBatch{chunks: make(map[types.ChunkID]types.Chunk)}
+	for chunk_id := range unsaved {
+		if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+			batch.chunks[chunk_id] = chunkstore.Clone(chunk)
+		} else {
+			batch.gone = append(batch.gone, chunk_id)
+		}
+		saving[chunk_id] = true
+		if len(batch.chunks)+len(batch.gone) >= saveBatch {
+			batches = append(batches, batch)
+			batch = chunkBatch{chunks: make(map[types.ChunkID]types.Chunk)}
+		}
+	}
+	if len(batch.chunks)+len(batch.gone) > 0 {
+		batches = append(batches, batch)
+	}
+	clear(unsaved)
+	go flushChunks(batches)
+}
+
+// A chunkBatch is saved with one SaveBatch.
+type chunkBatch struct {
+	chunks map[types.ChunkID]types.Chunk
+	gone   []types.ChunkID
+}
+
+// flushChunks writes a save pass without zone_map_Mu. A batch that fails is
+// flagged unsaved again, for the next pass.
+func flushChunks(batches []chunkBatch) {
+	var failed []types.ChunkID
+	for _, batch := range batches {
+		if err := store.SaveBatch(batch.chunks, batch.gone); err != nil {
+			log.Printf("❌ Saving %d chunks failed: %v", len(batch.chunks)+len(batch.gone), err)
+			for chunk_id := range batch.chunks {
+				failed = append(failed, chunk_id)
+			}
+			failed = append(failed, batch.gone...)
+		}
+	}
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	for _, chunk_id := range failed {
+		unsaved[chunk_id] = true
+	}
+	for _, batch := range batches {
+		for chunk_id := range batch.chunks {
+			delete(saving, chunk_id)
+		}
+		for _, chunk_id := range batch.gone {
+			delete(saving, chunk_id)
+		}
+	}
+}
+
+// evictChunks drops the least recently used chunks that may be evicted
+// while more than maxHotChunks are in zone_map, and those unused for longer
+// than chunkTTL. Must be called with zone_map_Mu held, after a save pass
+// starts.
+func evictChunks(now time.Time) {
+	held := make(map[types.ChunkID]bool, len(players))
+	for _, chunk_id := range players {
+		held[chunk_id] = true
+	}
+	var candidates []types.ChunkID
+	for chunk_id, chunk := range zone_map {
+		if _, ok := chunk_used[chunk_id]; !ok {
+			// here by a merge, an adoption or a claim; its idle clock starts now
+			chunk_used[chunk_id] = now
+		}
+		if !held[chunk_id] && evictable(chunk_id, chunk) {
+			candidates = append(candidates, chunk_id)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		return chunk_used[candidates[i]].Before(chunk_used[candidates[j]])
+	})
+
+	for _, chunk_id := range candidates {
+		reason := "cap"
+		if maxHotChunks <= 0 || len(zone_map) <= maxHotChunks {
+			if chunkTTL <= 0 || now.Sub(chunk_used[chunk_id]) <= chunkTTL {
+				// the rest were used more recently still
+				break
+			}
+			reason = "ttl"
+		}
+		if chunk := zone_map[chunk_id]; chunk.ServerIP == serverIP {
+			if store != nil {
+				cold_chunks[chunk_id] = store.Path(chunk_id)
+			} else if pristine(chunk_id, chunk) {
+				fresh_chunks[chunk_id] = true
+			} else {
+				continue
+			}
+		}
+		delete(zone_map, chunk_id)
+		chunk_views.Delete(chunk_id)
+		delete(chunk_used, chunk_id)
+		delete(cube_indexes, chunk_id)
+		delete(chunk_versions, chunk_id)
+		delete(chunk_activity, chunk_id)
+		delete(chat_logs, chunk_id)
+		chunksEvictedTotal.Inc(reason)
+	}
+}
+
+// evictable reports whether nothing keeps chunk in memory: nobody is in it,
+// queued for it, spectating it or replicating it, no transaction has it
+// locked, and it has no unsaved changes or a save still being written. Must
+// be called with zone_map_Mu held.
+func evictable(chunk_id types.ChunkID, chunk types.Chunk) bool {
+	if len(chunk.PlayerList) > 0 || len(chunk_queues[chunk_id]) > 0 || len(spectators[chunk_id]) > 0 ||
+		len(chunk_replicas[chunk_id]) > 0 || unsaved[chunk_id] || saving[chunk_id] {
+		return false
+	}
+	_, locked := tx_locks[chunk_id]
+	return !locked
+}
+
+// pristine reports whether chunk is as the generator made it, with nothing
+// added.
+func pristine(chunk_id types.ChunkID, chunk types.Chunk) bool {
+	return len(chunk.NPCs) == 0 && len(chunk.Items) == 0 &&
+		slices.Equal(chunk.Cells, generator.Generate(chunk_id, chunk_id.Edge(world.ChunkSize)))
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Input acknowledgement =====================
+
+// Clients that predict their own movement number each MOVE_PLAYER with
+// InputSeq. Whatever becomes of a numbered move here, applied, refused, or
+// turned into entering a new chunk, its reply carries AckSeq, the last input
+// processed for the player, and the player's authoritative state, so the
+// client can drop the acknowledged inputs and replay the rest on top. A
+// resent input this server already processed is acknowledged again but not
+// applied a second time. Redirects are not acknowledgements: the client
+// sends the input on to the owner.
+
+// guarded by zone_map_Mu
+var input_seqs = make(map[string]uint64) // last input processed per player
+
+// staleInput reports whether req is a numbered input this server has
+// already processed. Must be called with zone_map_Mu held.
+func staleInput(req types.Request) bool {
+	if req.InputSeq == 0 || req.IsPeerReq {
+		return false
+	}
+	_, held := players[req.Player.ID]
+	return held && req.InputSeq <= input_seqs[req.Player.ID]
+}
+
+// ackInput adds the acknowledgement for req's input to res. Must be called
+// with zone_map_Mu held.
+func ackInput(req types.Request, res *types.Response) {
+	switch res.Code {
+	case types.CodeRedirect, types.CodeNotOwner, types.CodeChunkMigrating:
+		return
+	}
+	player_id := req.Player.ID
+	res.AckSeq = req.InputSeq
+	player, held := heldPlayer(player_id)
+	if !held {
+		// refused before the player got in; they are where they were
+		return
+	}
+	if req.InputSeq > input_seqs[player_id] {
+		input_seqs[player_id] = req.InputSeq
+	}
+	res.AckSeq = input_seqs[player_id]
+	res.Player = &player
+}
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Projectiles =====================
+
+// SHOOT fires a projectile from the shooter's cell along Shot's direction,
+// at the height they stand at. The server flies it projectileSpeed cells a
+// second, in steps of at most half a cell, until it meets a cube at its
+// height, hits a player, has flown projectileRange, or leaves the chunks
+// this server owns (projectiles do not cross servers).
+//
+// Hits are lag compensated: the shooter aimed at players as they were at
+// Shot.SeenMs, so every player is tested where position_history says they
+// were that long before the present, rewinding at most maxRewind so a
+// laggy or lying client cannot shoot far into the past. A hit takes
+// hitDamage off the target's health (see damage). Each hit is pushed as
+// PUSH_HIT to the players and spectators of the chunk it happened in, and to
+// the shooter. The dead neither shoot nor are hit.
+
+const (
+	projectileSpeed = 20.0 // cells per second
+	projectileRange = 40.0 // cells
+	hitRadius       = 0.5  // cells from a player's centre
+	maxRewind       = 300 * time.Millisecond
+	maxProjectiles  = 1024
+	shotCooldown    = 100 * time.Millisecond
+	hitDamage       = 25
+)
+
+type projectile struct {
+	ID      string
+	Shooter string
+	World   string
+	X, Y    float64 // in cells; a player at (x, y) is centred on (x+0.5, y+0.5)
+	DX, DY  float64 // unit direction
+	Level   int     // cubes at this height or above stop it
+	Flown   float64
+	Rewind  time.Duration
+}
+
+type positionSample struct {
+	Ms   int64
+	X, Y int
+}
+
+// guarded by zone_map_Mu; the last shot per player, and the positions each
+// player held here had over the last maxRewind
+var (
+	projectiles      = make(map[string]*projectile)
+	last_shot        = make(map[string]time.Time)
+	position_history = make(map[string][]positionSample)
+)
+
+var (
+	shotsTotal = metrics.NewCounterVec("game_shots_total",
+		"Projectiles fired, and how they ended: hit, cube, range or left (flew out of this server's chunks).", "outcome")
+	_ = metrics.NewGaugeFunc("game_projectiles",
+		"Projectiles in flight on this server.", "", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			return map[string]float64{"": float64(len(projectiles))}
+		})
+)
+
+func handleShoot(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player_id := req.Player.ID
+	chunk_id, held := players[player_id]
+	if !held {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+	if chunk, ok := zone_map[chunk_id]; !ok || chunk.ServerIP != serverIP {
+		replyNotOwner(conn, addr, req, chunk)
+		return
+	}
+	shot := req.Shot
+	if shot == nil || math.IsNaN(shot.DirX) || math.IsNaN(shot.DirY) || math.Hypot(shot.DirX, shot.DirY) == 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing shot direction", Code: types.CodeBadRequest})
+		return
+	}
+	now := time.Now()
+	if dead, ok := lifeGate(player_id, req.Player.PosX, req.Player.PosY, now); !ok && dead.Code == types.CodeDead {
+		reply(conn, addr, req, dead)
+		return
+	}
+	if wait := shotCooldown - now.Sub(last_shot[player_id]); wait > 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Firing too fast", Code: types.CodeRateLimited,
+			RetryAfterMs: max(1, wait.Milliseconds())})
+		return
+	}
+	if len(projectiles) >= maxProjectiles {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Too many projectiles in flight", Code: types.CodeRateLimited,
+			RetryAfterMs: shotCooldown.Milliseconds()})
+		return
+	}
+	last_shot[player_id] = now
+
+	var rewind time.Duration
+	if shot.SeenMs > 0 {
+		rewind = max(0, min(maxRewind, time.Duration(now.UnixMilli()-shot.SeenMs)*time.Millisecond))
+	}
+	shooter := player_map[player_id]
+	length := math.Hypot(shot.DirX, shot.DirY)
+	p := &projectile{
+		ID:      fmt.Sprintf("shot-%016x", rand.Uint64()),
+		Shooter: player_id,
+		World:   shooter.World,
+		X:       float64(shooter.PosX) + 0.5,
+		Y:       float64(shooter.PosY) + 0.5,
+		DX:      shot.DirX / length,
+		DY:      shot.DirY / length,
+		Level:   columnHeight(chunk_id, shooter.PosX, shooter.PosY),
+		Rewind:  rewind,
+	}
+	projectiles[p.ID] = p
+	reply(conn, addr, req, types.Response{Success: true, Message: "Fired", ProjectileID: p.ID})
+	netproto.Tracef(req.TraceID, "🔫 %s fired %s from (%d, %d), rewinding %v", player_id, p.ID, shooter.PosX, shooter.PosY, rewind)
+}
+
+// projectileTick records where every player is and moves every projectile
+// one tick on. Must be called with zone_map_Mu held.
+func projectileTick(now time.Time) {
+	recordPositions(now)
+	if len(projectiles) == 0 {
+		return
+	}
+	flight := projectileSpeed * float64(world.TickMs) / 1000
+	for id, p := range projectiles {
+		if outcome, done := p.fly(flight, now); done {
+			delete(projectiles, id)
+			shotsTotal.Inc(outcome)
+		}
+	}
+}
+
+// fly moves p up to dist cells and reports how its flight ended, if it did.
+func (p *projectile) fly(dist float64, now time.Time) (string, bool) {
+	for dist > 0 {
+		step := math.Min(0.5, dist)
+		dist -= step
+		p.X += p.DX * step
+		p.Y += p.DY * step
+		p.Flown += step
+		if p.Flown > projectileRange {
+			return "range", true
+		}
+
+		x, y := int(math.Floor(p.X)), int(math.Floor(p.Y))
+		chunk_id := chunkAt(p.World, x, y)
+		if chunk, ok := zone_map[chunk_id]; !ok || chunk.ServerIP != serverIP {
+			return "left", true
+		}
+		if columnHeight(chunk_id, x, y) > p.Level {
+			return "cube", true
+		}
+		if target, ok := p.hitTest(now); ok {
+			hit(p, target, chunk_id, now)
+			return "hit", true
+		}
+	}
+	return "", false
+}
+
+// hitTest returns the player p is over, as of p.Rewind ago.
+func (p *projectile) hitTest(now time.Time) (string, bool) {
+	at_ms := now.Add(-p.Rewind).UnixMilli()
+	for player_id := range players {
+		if target := player_map[player_id]; player_id == p.Shooter || target.World != p.World || target.RespawnMs > 0 {
+			continue
+		}
+		x, y := positionAt(player_id, at_ms)
+		if math.Hypot(float64(x)+0.5-p.X, float64(y)+0.5-p.Y) <= hitRadius {
+			return player_id, true
+		}
+	}
+	return "", false
+}
+
+// hit applies p's damage to target and pushes the hit around chunk_id.
+func hit(p *projectile, target string, chunk_id types.ChunkID, now time.Time) {
+	hurt, _ := damage(target, p.Shooter, "shot", hitDamage, now)
+	event := types.HitEvent{ProjectileID: p.ID, ShooterID: p.Shooter, TargetID: target, ChunkID: chunk_id,
+		X: p.X, Y: p.Y, Damage: hitDamage, Health: hurt.Health}
+	notice := types.Response{Success: true, Code: types.CodeHit, Message: fmt.Sprintf("%s hit %s", p.Shooter, target), Hit: &event}
+	stampClock(&notice)
+	pushAround(notice, []types.ChunkID{chunk_id}, p.Shooter, target)
+	journal.Record(WorldEvent{Type: "HIT", PlayerID: target, ChunkID: chunk_id,
+		Detail: fmt.Sprintf("by %s, health %d", p.Shooter, hurt.Health)})
+}
+
+// columnHeight returns how many cubes high the column at (x, y) of chunk_id
+// stands. Must be called with zone_map_Mu held.
+func columnHeight(chunk_id types.ChunkID, x, y int) int {
+	chunk := zone_map[chunk_id]
+	height := 0
+	for _, cube := range cubeIndex(chunk_id, chunk).InRegion(chunk.Cells, x, y, x, y) {
+		height = max(height, cube.Height+1)
+	}
+	return height
+}
+
+// recordPositions adds a sample for every player held here who moved, and
+// drops samples older than maxRewind except the one in force then. Must be
+// called with zone_map_Mu held.
+func recordPositions(now time.Time) {
+	now_ms := now.UnixMilli()
+	horizon := now.Add(-maxRewind).UnixMilli()
+	for player_id := range players {
+		player := player_map[player_id]
+		samples := position_history[player_id]
+		if n := len(samples); n == 0 || samples[n-1].X != player.PosX || samples[n-1].Y != player.PosY {
+			samples = append(samples, positionSample{Ms: now_ms, X: player.PosX, Y: player.PosY})
+		}
+		drop := 0
+		for drop+1 < len(samples) && samples[drop+1].Ms <= horizon {
+			drop++
+		}
+		position_history[player_id] = samples[drop:]
+	}
+	for player_id := range position_history {
+		if _, held := players[player_id]; !held {
+			delete(position_history, player_id)
+			delete(last_shot, player_id)
+		}
+	}
+}
+
+// positionAt returns where player_id was at at_ms, as far as the history
+// goes back. Must be called with zone_map_Mu held.
+func positionAt(player_id string, at_ms int64) (int, int) {
+	samples := position_history[player_id]
+	if len(samples) == 0 {
+		player := player_map[player_id]
+		return player.PosX, player.PosY
+	}
+	at := samples[0]
+	for _, sample := range samples[1:] {
+		if sample.Ms > at_ms {
+			break
+		}
+		at = sample
+	}
+	return at.X, at.Y
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"errors"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Quorum writes =====================
+
+// In a world created with consistency "quorum" (types.ConsistencyQuorum),
+// central gives every owned chunk a read replica, and a cube edit
+// (ADD_CUBE, ADD_CUBES, DLT_CUBE, DLT_CUBES, UNDO) is acknowledged only
+// once a replica has applied it: the owner applies the edit, releases the
+// lock and runs an anti-entropy round with the chunk's replicas, and
+// replies when the first of them holds a copy at least as new. If none
+// does within quorumRounds rounds, or the chunk has no replica yet, the
+// reply is ERR_UNREPLICATED: the edit stands on the owner and reaches the
+// replicas with the next sync, but it would not survive the owner dying
+// before then. Transactions commit as before.
+//
+// Servers learn the worlds' modes from central's reply to their metrics
+// report, so edits in a world created a moment ago are owner-acknowledged
+// until the next report.
+
+// quorumRounds bounds the anti-entropy rounds an edit waits for. A round
+// with a later snapshot of the chunk follows one that failed, in case a
+// concurrent sync of a newer copy got to the replica first.
+const quorumRounds = 2
+
+var (
+	// world ID -> consistency mode, for the worlds not owner-acknowledged;
+	// guarded by zone_map_Mu and replaced by every report reply
+	world_consistency = make(map[string]string)
+
+	quorumWritesTotal = metrics.NewCounterVec("game_quorum_writes_total",
+		"Cube edits in quorum worlds, by result: acked (a replica applied it) or unreplicated.", "result")
+	quorumAckSeconds = metrics.NewHistogramVec("game_quorum_ack_duration_seconds",
+		"Time from applying a cube edit in a quorum world to a replica acknowledging it.", "", metrics.DefaultBuckets)
+)
+
+// learnConsistency records the worlds' consistency modes from central.
+func learnConsistency(worlds []types.World) {
+	modes := make(map[string]string, len(worlds))
+	for _, w := range worlds {
+		if w.Consistency != types.ConsistencyOwner {
+			modes[w.ID] = w.Consistency
+		}
+	}
+	zone_map_Mu.Lock()
+	world_consistency = modes
+	zone_map_Mu.Unlock()
+}
+
+// replyEdit replies to a cube edit of req.ChunkID once the world's
+// consistency mode allows: at once, or in a quorum world from a goroutine
+// once a replica has applied the edit. Must be called with zone_map_Mu
+// held, after the edit is in zone_map.
+func replyEdit(conn netproto.Transport, addr string, req types.Request, res types.Response) {
+	chunk_id := req.ChunkID
+	if world_consistency[chunk_id.World] != types.ConsistencyQuorum {
+		reply(conn, addr, req, res)
+		return
+	}
+	start := time.Now()
+	go func() {
+		err := awaitReplica(chunk_id)
+		zone_map_Mu.Lock()
+		defer zone_map_Mu.Unlock()
+		if err != nil {
+			quorumWritesTotal.Inc("unreplicated")
+			netproto.Tracef(req.TraceID, "⚠️  Edit of chunk [%d,%d] not acknowledged by a replica: %v", chunk_id.IDX, chunk_id.IDY, err)
+			reply(conn, addr, req, types.Response{Success: false, Code: types.CodeUnreplicated,
+				Message: "Applied by the owner but not by a replica: " + err.Error()})
+			return
+		}
+		quorumWritesTotal.Inc("acked")
+		quorumAckSeconds.Observe("", time.Since(start).Seconds())
+		reply(conn, addr, req, res)
+	}()
+}
+
+// awaitReplica syncs the owned chunk to its replicas and returns once one
+// of them holds the current copy.
+func awaitReplica(chunk_id types.ChunkID) error {
+	var err error
+	for round := 0; round < quorumRounds; round++ {
+		zone_map_Mu.Lock()
+		chunk, ok := zone_map[chunk_id]
+		owned := ok && chunk.ServerIP == serverIP
+		targets := chunk_replicas[chunk_id]
+		var snapshot types.Chunk
+		if owned {
+			snapshot = chunkstore.Clone(chunk)
+		}
+		zone_map_Mu.Unlock()
+		if !owned {
+			return errors.New("chunk moved to another server")
+		}
+		if len(targets) == 0 {
+			return errors.New("chunk has no replica yet")
+		}
+
+		acks := make(chan error, len(targets))
+		for _, target := range targets {
+			go func(target string) {
+				acks <- syncReplica(target, chunk_id, snapshot)
+			}(target)
+		}
+		for range targets {
+			if err = <-acks; err == nil {
+				return nil
+			}
+		}
+	}
+	return err
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// How a fake replica answers an anti-entropy round.
+const (
+	replicaInSync  = "in sync"  // its digest matches
+	replicaPatched = "patched"  // it asks for a patch, then takes it
+	replicaRefuses = "refuses"  // ERR_BAD_REQUEST, as a server owning the chunk
+	replicaSilent  = "silent"   // never answers
+	replicaBadSync = "bad sync" // asks for a patch, then refuses it
+)
+
+var quorumChunk = types.ChunkID{World: "q", IDX: 2, IDY: 3}
+
+// fakeReplica answers owner's REPLICA_DIGEST and REPLICA_SYNC on addr as
+// behavior says, counting the requests of each type.
+type fakeReplica struct {
+	mu    sync.Mutex
+	calls map[types.RequestType]int
+}
+
+func startReplica(t *testing.T, mem *netproto.MemNetwork, owner, addr, behavior string) *fakeReplica {
+	t.Helper()
+	conn, err := mem.Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	r := &fakeReplica{calls: make(map[types.RequestType]int)}
+	go func() {
+		for {
+			from, data, err := conn.Recv()
+			if err != nil {
+				return
+			}
+			var req types.Request
+			if err := netproto.Wire.Unmarshal(data, &req); err != nil {
+				continue
+			}
+			r.mu.Lock()
+			r.calls[req.Type]++
+			r.mu.Unlock()
+			if req.ChunkID != quorumChunk || req.CallerIP != owner {
+				netproto.SendJSON(conn, from, types.Response{Success: false, Code: types.CodeBadRequest})
+				continue
+			}
+			res := types.Response{Success: true}
+			switch {
+			case behavior == replicaSilent:
+				continue
+			case behavior == replicaRefuses:
+				res = types.Response{Success: false, Code: types.CodeBadRequest, Message: "Chunk is owned by this server"}
+			case req.Type == types.ReqReplicaDigest && behavior != replicaInSync:
+				res.Digest = &types.ChunkDigest{Root: req.Digest.Root + 1}
+			case req.Type == types.ReqReplicaSync && behavior == replicaBadSync:
+				res = types.Response{Success: false, Code: types.CodeBadRequest, Message: "stale patch"}
+			}
+			netproto.SendJSON(conn, from, res)
+		}
+	}()
+	return r
+}
+
+func (r *fakeReplica) count(t types.RequestType) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.calls[t]
+}
+
+// awaitCount waits up to a second for the replica to have seen want
+// requests of type t, returning how many it saw. A replica that lost the
+// race to acknowledge is still being synced when awaitReplica returns.
+func (r *fakeReplica) awaitCount(t types.RequestType, want int) int {
+	deadline := time.Now().Add(time.Second)
+	for r.count(t) < want && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	return r.count(t)
+}
+
+// quorumServer makes this process a game server owning quorumChunk, in a
+// quorum world, on an in-memory network with the given replicas.
+func quorumServer(t *testing.T, replicas ...string) (*netproto.MemNetwork, []*fakeReplica) {
+	t.Helper()
+	mem := netproto.NewMemNetwork(1)
+	oldNetwork, oldServerIP, oldTimeout := network, serverIP, peerTimeout
+	network, serverIP, peerTimeout = mem, "mem:owner", 100*time.Millisecond
+
+	zone_map_Mu.Lock()
+	oldZone, oldReplicas, oldConsistency := zone_map, chunk_replicas, world_consistency
+	zone_map = map[types.ChunkID]types.Chunk{quorumChunk: {World: "q", IDX: 2, IDY: 3, ServerIP: serverIP,
+		Cells: []types.Cube{{ID: "c1", X: 1, Z: 1}}}}
+	chunk_replicas = make(map[types.ChunkID][]string)
+	world_consistency = map[string]string{"q": types.ConsistencyQuorum}
+	zone_map_Mu.Unlock()
+	t.Cleanup(func() {
+		zone_map_Mu.Lock()
+		zone_map, chunk_replicas, world_consistency = oldZone, oldReplicas, oldConsistency
+		zone_map_Mu.Unlock()
+		network, serverIP, peerTimeout = oldNetwork, oldServerIP, oldTimeout
+	})
+
+	var fakes []*fakeReplica
+	var addrs []string
+	for i, behavior := range replicas {
+		addr := "mem:replica" + string(rune('a'+i))
+		fakes = append(fakes, startReplica(t, mem, serverIP, addr, behavior))
+		addrs = append(addrs, addr)
+	}
+	zone_map_Mu.Lock()
+	if len(addrs) > 0 {
+		chunk_replicas[quorumChunk] = addrs
+	}
+	zone_map_Mu.Unlock()
+	return mem, fakes
+}
+
+// errTimeout stands for a replica not answering before peerTimeout.
+const errTimeout = "timeout"
+
+// wantErr reports whether err is the one wanted: contains want, or timed
+// out for errTimeout.
+func wantErr(err error, want string) bool {
+	if err == nil {
+		return false
+	}
+	if want == errTimeout {
+		return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, netproto.ErrTimeout)
+	}
+	return strings.Contains(err.Error(), want)
+}
+
+func TestAwaitReplica(t *testing.T) {
+	tests := []struct {
+		name     string
+		replicas []string
+		moved    bool
+		err      string // part of the error wanted, "" for an ack, errTimeout for no answer
+		digests  int    // REPLICA_DIGEST rounds each replica saw
+	}{
+		{"one in sync", []string{replicaInSync}, false, "", 1},
+		{"one patched", []string{replicaPatched}, false, "", 1},
+		{"first ack wins", []string{replicaRefuses, replicaInSync}, false, "", 1},
+		{"ack from a slow quorum", []string{replicaSilent, replicaPatched}, false, "", 1},
+		{"every replica refuses", []string{replicaRefuses, replicaRefuses}, false, "owned by this server", quorumRounds},
+		{"patch refused", []string{replicaBadSync}, false, "stale patch", quorumRounds},
+		{"no answer", []string{replicaSilent}, false, errTimeout, quorumRounds},
+		{"no replica yet", nil, false, "no replica yet", 0},
+		{"moved away", []string{replicaInSync}, true, "moved", 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, fakes := quorumServer(t, tt.replicas...)
+			if tt.moved {
+				zone_map_Mu.Lock()
+				chunk := zone_map[quorumChunk]
+				chunk.ServerIP = "mem:elsewhere"
+				zone_map[quorumChunk] = chunk
+				zone_map_Mu.Unlock()
+			}
+
+			err := awaitReplica(quorumChunk)
+			if tt.err == "" && err != nil {
+				t.Fatalf("awaitReplica: %v", err)
+			}
+			if tt.err != "" && !wantErr(err, tt.err) {
+				t.Fatalf("awaitReplica: err = %v, want one with %q", err, tt.err)
+			}
+			for i, fake := range fakes {
+				if got := fake.awaitCount(types.ReqReplicaDigest, tt.digests); got != tt.digests {
+					t.Errorf("replica %d saw %d digest rounds, want %d", i, got, tt.digests)
+				}
+			}
+		})
+	}
+}
+
+func TestReplyEdit(t *testing.T) {
+	tests := []struct {
+		name        string
+		consistency string
+		replicas    []string
+		code        string
+	}{
+		{"owner world replies at once", types.ConsistencyOwner, []string{replicaSilent}, types.CodeOK},
+		{"quorum acked", types.ConsistencyQuorum, []string{replicaPatched}, types.CodeOK},
+		{"quorum unreplicated", types.ConsistencyQuorum, []string{replicaRefuses}, types.CodeUnreplicated},
+		{"quorum without replicas", types.ConsistencyQuorum, nil, types.CodeUnreplicated},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mem, fakes := quorumServer(t, tt.replicas...)
+			zone_map_Mu.Lock()
+			world_consistency = map[string]string{"q": tt.consistency}
+			zone_map_Mu.Unlock()
+			server, _ := mem.Listen("mem:owner")
+			player, _ := mem.Listen("mem:player")
+			defer server.Close()
+			defer player.Close()
+
+			req := types.Request{Type: types.ReqAddCube, ChunkID: quorumChunk, TraceID: "t1"}
+			zone_map_Mu.Lock()
+			replyEdit(server, "mem:player", req, types.Response{Success: true, Message: "Cube added"})
+			zone_map_Mu.Unlock()
+
+			player.SetReadDeadline(time.Now().Add(time.Second))
+			_, data, err := player.Recv()
+			if err != nil {
+				t.Fatalf("no reply: %v", err)
+			}
+			res, err := netproto.DecodeResponse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if res.Code != tt.code || res.TraceID != "t1" {
+				t.Errorf("reply %s (trace %q): %s, want %s", res.Code, res.TraceID, res.Message, tt.code)
+			}
+			if tt.consistency == types.ConsistencyOwner {
+				for _, fake := range fakes {
+					if n := fake.count(types.ReqReplicaDigest); n != 0 {
+						t.Errorf("owner-acknowledged edit waited on a replica (%d rounds)", n)
+					}
+				}
+			}
+		})
+	}
+}
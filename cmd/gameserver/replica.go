@@ -0,0 +1,273 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Read replicas =====================
+
+// A crowded chunk can be given read replicas by the central server
+// (SET_REPLICAS). The owner syncs them whenever the chunk changed, at most
+// every replicaSyncEvery, and at least every replicaHeartbeat, by anti-
+// entropy rather than by sending the whole chunk: it asks each replica
+// whether its copy has the same Merkle root (REPLICA_DIGEST), and only if
+// not compares the replica's leaf digests with its own and sends the cubes
+// of the leaves that differ, with the chunk's players, NPCs and items
+// (REPLICA_SYNC). A replica that missed syncs during a partition thus gets
+// back just what it missed. Replicas answer GET_UPDATES and READ_ONLY from
+// their copy. Writes still go to the owner. A copy that stops being refreshed for
+// replicaTTL stops serving, so replicas go quiet once the designation is
+// lifted or the owner goes away; it is kept until replicaKeep in case central
+// fails the chunk over here (ADOPT_CHUNK).
+
+const (
+	replicaHeartbeat = 2 * time.Second
+	replicaTTL       = 3 * replicaHeartbeat
+	replicaKeep      = time.Minute
+	// owned chunks reported to central as candidates for replicas
+	hotChunkReports = 8
+)
+
+// replicaSyncEvery bounds how often one chunk is streamed to its replicas.
+var replicaSyncEvery = 200 * time.Millisecond
+
+// ReplicaCopy is a chunk held for reads on behalf of its owner.
+type ReplicaCopy struct {
+	Chunk    types.Chunk
+	Digest   types.ChunkDigest
+	Owner    string
+	SyncedAt time.Time
+}
+
+var (
+	// owner side: replicas of each owned chunk, and what they have been sent
+	chunk_replicas = make(map[types.ChunkID][]string)
+	replica_dirty  = make(map[types.ChunkID]bool)
+	replica_synced = make(map[types.ChunkID]time.Time)
+
+	// replica side
+	replica_copies = make(map[types.ChunkID]ReplicaCopy)
+)
+
+var (
+	replicaSyncsTotal = metrics.NewCounterVec("game_replica_syncs_total",
+		"Anti-entropy rounds with read replicas, by result: in_sync (Merkle roots matched), patched or error.", "result")
+	replicaPatchCubes = metrics.NewCounterVec("game_replica_patch_cubes_total",
+		"Cubes of chunks being synced to read replicas, by whether they were sent (in a leaf that differed) or skipped.", "result")
+	_ = metrics.NewGaugeFunc("game_replica_copies",
+		"Chunks this server holds as a read replica.", "", func() map[string]float64 {
+			zone_map_Mu.Lock()
+			defer zone_map_Mu.Unlock()
+			return map[string]float64{"": float64(len(replica_copies))}
+		})
+)
+
+// markReplicaDirty queues a replicated chunk for the next sync. Must be
+// called with zone_map_Mu held.
+func markReplicaDirty(chunk_id types.ChunkID) {
+	if _, ok := chunk_replicas[chunk_id]; ok {
+		replica_dirty[chunk_id] = true
+	}
+}
+
+// replicaCopy returns the fresh replica copy of a chunk this server does not
+// own. Must be called with zone_map_Mu held.
+func replicaCopy(chunk_id types.ChunkID) (ReplicaCopy, bool) {
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		return ReplicaCopy{}, false
+	}
+	replica, ok := replica_copies[chunk_id]
+	if !ok || time.Since(replica.SyncedAt) > replicaTTL {
+		return ReplicaCopy{}, false
+	}
+	return replica, true
+}
+
+// handleSetReplicas records the replicas central picked for an owned chunk;
+// an empty list lifts the designation.
+func handleSetReplicas(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	chunk, ok := zone_map[chunk_id]
+	if !ok || chunk.ServerIP != serverIP {
+		replyNotOwner(conn, addr, req, chunk)
+		return
+	}
+	if len(req.Replicas) == 0 {
+		delete(chunk_replicas, chunk_id)
+		delete(replica_dirty, chunk_id)
+		delete(replica_synced, chunk_id)
+	} else {
+		chunk_replicas[chunk_id] = req.Replicas
+		replica_dirty[chunk_id] = true
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Replicas set", Replicas: req.Replicas})
+	netproto.Tracef(req.TraceID, "🪞 Chunk [%d,%d] replicas: %v", chunk_id.IDX, chunk_id.IDY, req.Replicas)
+}
+
+// handleReplicaDigest tells the owner whether this server's copy of a
+// chunk matches the Merkle root it sent, and if not, the copy's leaves.
+func handleReplicaDigest(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Chunk is owned by this server", Code: types.CodeBadRequest})
+		return
+	}
+	if req.Digest == nil {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing digest", Code: types.CodeBadRequest})
+		return
+	}
+	replica, ok := replica_copies[chunk_id]
+	if ok && replica.Digest.Root == req.Digest.Root {
+		replica.Owner = req.CallerIP
+		replica.SyncedAt = time.Now()
+		replica_copies[chunk_id] = replica
+		reply(conn, addr, req, types.Response{Success: true, Message: "In sync"})
+		return
+	}
+	// no leaves at all if there is no copy, so the owner sends everything
+	digest := replica.Digest
+	reply(conn, addr, req, types.Response{Success: true, Digest: &digest})
+}
+
+// handleReplicaSync stores the owner's latest copy of a chunk, or patches
+// this server's copy with the cubes of the leaves it differed in.
+func handleReplicaSync(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		// ownership moved here after the sync was sent
+		reply(conn, addr, req, types.Response{Success: false, Message: "Chunk is owned by this server", Code: types.CodeBadRequest})
+		return
+	}
+	observeChunk(req.Chunk, req.CallerIP)
+	chunk := req.Chunk
+	replica, ok := replica_copies[chunk_id]
+	if req.Digest != nil {
+		if !ok && len(req.Buckets) < chunkstore.MerkleBuckets {
+			// expired since the digest was compared
+			reply(conn, addr, req, types.Response{Success: false, Message: "No copy to patch", Code: types.CodeNotFound})
+			return
+		}
+		chunk.Cells = replica.Chunk.Cells
+		chunkstore.PatchCubes(&chunk, req.Buckets, req.Chunk.Cells)
+	}
+	if ok && chunkstore.Regresses(replica.Chunk, chunk) {
+		// a previous owner still syncing, or a sync overtaken by a later one
+		reply(conn, addr, req, types.Response{Success: false, Message: "Sync is older than this copy", Code: types.CodeBadRequest})
+		return
+	}
+	digest := chunkstore.Digest(chunk)
+	if req.Digest != nil && digest.Root != req.Digest.Root {
+		// the copy changed between the digest and the patch; the next
+		// round starts over
+		reply(conn, addr, req, types.Response{Success: false, Message: "Patched copy does not match the owner's digest", Code: types.CodeBadRequest})
+		return
+	}
+	replica_copies[chunk_id] = ReplicaCopy{Chunk: chunk, Digest: digest, Owner: req.CallerIP, SyncedAt: time.Now()}
+	reply(conn, addr, req, types.Response{Success: true})
+}
+
+// syncReplicas streams changed replicated chunks to their replicas and drops
+// expired replica copies. Must be called with zone_map_Mu held.
+func syncReplicas(now time.Time) {
+	for chunk_id, targets := range chunk_replicas {
+		chunk, ok := zone_map[chunk_id]
+		if !ok || chunk.ServerIP != serverIP {
+			// migrated away; the new owner's replicas are central's call
+			delete(chunk_replicas, chunk_id)
+			delete(replica_dirty, chunk_id)
+			delete(replica_synced, chunk_id)
+			continue
+		}
+		since := now.Sub(replica_synced[chunk_id])
+		if since < replicaSyncEvery || (!replica_dirty[chunk_id] && since < replicaHeartbeat) {
+			continue
+		}
+		delete(replica_dirty, chunk_id)
+		replica_synced[chunk_id] = now
+
+		snapshot := chunkstore.Clone(chunk)
+		for _, target := range targets {
+			go syncReplica(target, chunk_id, snapshot)
+		}
+	}
+
+	for chunk_id, replica := range replica_copies {
+		if now.Sub(replica.SyncedAt) > replicaKeep {
+			delete(replica_copies, chunk_id)
+		}
+	}
+}
+
+// syncReplica runs one anti-entropy round of chunk with target, returning
+// once target holds chunk or why it does not. chunk is a snapshot, not to
+// be modified.
+func syncReplica(target string, chunk_id types.ChunkID, chunk types.Chunk) error {
+	digest := chunkstore.Digest(chunk)
+	req := types.Request{Type: types.ReqReplicaDigest, ChunkID: chunk_id, CallerIP: serverIP, Digest: &types.ChunkDigest{Root: digest.Root}}
+	res, err := roundTripReplica(target, req)
+	if err != nil {
+		replicaSyncsTotal.Inc("error")
+		return err
+	}
+	if res.Digest == nil {
+		replicaSyncsTotal.Inc("in_sync")
+		return nil
+	}
+
+	buckets := chunkstore.DivergentBuckets(*res.Digest, digest)
+	patch := chunk
+	patch.Cells = chunkstore.CubesIn(chunk, buckets)
+	replicaPatchCubes.Add("sent", float64(len(patch.Cells)))
+	replicaPatchCubes.Add("skipped", float64(len(chunk.Cells)-len(patch.Cells)))
+	req = types.Request{Type: types.ReqReplicaSync, ChunkID: chunk_id, CallerIP: serverIP, Chunk: patch, Digest: &digest, Buckets: buckets}
+	if _, err := roundTripReplica(target, req); err != nil {
+		replicaSyncsTotal.Inc("error")
+		return err
+	}
+	replicaSyncsTotal.Inc("patched")
+	return nil
+}
+
+// roundTripReplica sends req to target, turning a refusal into an error.
+func roundTripReplica(target string, req types.Request) (types.Response, error) {
+	res, err := netproto.RoundTrip(network, target, req, peerTimeout)
+	if err == nil && !res.Success {
+		err = fmt.Errorf("%s: %s", res.Code, res.Message)
+	}
+	return res, err
+}
+
+// hotChunks lists the owned chunks with the most players, for central to
+// consider for replicas. Must be called with zone_map_Mu held.
+func hotChunks() []types.ChunkLoad {
+	loads := chunkLoads()
+	if len(loads) > hotChunkReports {
+		loads = loads[:hotChunkReports]
+	}
+	return loads
+}
+
+// chunkLoads counts the players in each owned chunk that has any, busiest
+// first. Must be called with zone_map_Mu held.
+func chunkLoads() []types.ChunkLoad {
+	counts := make(map[types.ChunkID]int)
+	for _, chunk_id := range players {
+		if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+			counts[chunk_id]++
+		}
+	}
+	loads := make([]types.ChunkLoad, 0, len(counts))
+	for chunk_id, count := range counts {
+		loads = append(loads, types.ChunkLoad{ChunkID: chunk_id, Players: count})
+	}
+	sort.Slice(loads, func(i, j int) bool { return loads[i].Players > loads[j].Players })
+	return loads
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"crypto/subtle"
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Session resume =====================
+
+// A client that restarts resumes its player rather than joining as a new
+// one. Central, given the player's session token at /join, asks every
+// server with LOCATE_PLAYER; the one holding the player answers with their
+// state and arms the token for resumeWindow. The client then sends RESUME
+// with the token from its new address, which replaces the stale one in the
+// same step, and carries on from where the player was. A token is good for
+// one RESUME.
+//
+// Tokens are only ever issued by central, at /join: a LOCATE_PLAYER that
+// carries one is refused unless it comes from central (see fromCentral), so
+// nobody else can arm a token of their choosing and take over a player.
+
+const resumeWindow = 30 * time.Second
+
+type ResumeToken struct {
+	Token string
+	Until time.Time
+}
+
+// guarded by zone_map_Mu
+var resume_tokens = make(map[string]ResumeToken)
+
+// heldPlayer returns the state of a player on this server. Must be called
+// with zone_map_Mu held.
+func heldPlayer(player_id string) (types.Player, bool) {
+	chunk_id, ok := players[player_id]
+	if !ok {
+		return types.Player{}, false
+	}
+	player := player_map[player_id]
+	player.ID, player.ChunkID = player_id, chunk_id
+	return player, true
+}
+
+func handleLocatePlayer(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	now := time.Now()
+	for player_id, token := range resume_tokens {
+		if now.After(token.Until) {
+			delete(resume_tokens, player_id)
+		}
+	}
+
+	if req.SessionToken != "" && !fromCentral(addr) {
+		requestsRefusedTotal.Inc("forbidden")
+		reply(conn, addr, req, types.Response{Success: false, Message: "Only central may arm a session token", Code: types.CodeForbidden})
+		return
+	}
+	player, ok := heldPlayer(req.PlayerID)
+	if !ok {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+	// without a token this is only a lookup, as for WHISPER
+	if req.SessionToken != "" {
+		resume_tokens[req.PlayerID] = ResumeToken{Token: req.SessionToken, Until: now.Add(resumeWindow)}
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Player is here", Player: &player})
+	netproto.Tracef(req.TraceID, "🔎 Player %s located here", req.PlayerID)
+}
+
+func handleResume(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player_id := req.PlayerID
+	token, armed := resume_tokens[player_id]
+	player, held := heldPlayer(player_id)
+	if !armed || !sameToken(token.Token, req.SessionToken) || time.Now().After(token.Until) || !held {
+		reply(conn, addr, req, types.Response{Success: false, Message: "No session to resume; join again", Code: types.CodeBadSession})
+		return
+	}
+	delete(resume_tokens, player_id)
+
+	stale := player_addrs[player_id]
+	player_addrs[player_id] = addr
+	player_seen[player_id] = time.Now()
+	journal.Record(WorldEvent{Type: "RESUME", PlayerID: player_id, ChunkID: player.ChunkID, Detail: "from " + addr, TraceID: req.TraceID})
+
+	chunk_id := player.ChunkID
+	reply(conn, addr, req, types.Response{Success: true, Message: "Session resumed", Player: &player, ChunkID: &chunk_id,
+		ChunkSize: world.ChunkSize, Replicas: chunk_replicas[chunk_id], Splits: splitList()})
+	log.Printf("🔁 Player %s resumed in chunk [%d,%d] from %s (was %s)", player_id, chunk_id.IDX, chunk_id.IDY, addr, stale)
+}
+
+// sameToken compares session tokens in constant time.
+func sameToken(armed, given string) bool {
+	return armed != "" && subtle.ConstantTimeCompare([]byte(armed), []byte(given)) == 1
+}
+
+// saveProfile hands player, who has left this server, to central's profile
+// store so their next session starts where this one ended. It calls central
+// without zone_map_Mu; a lost save only costs the player this session's
+// progress.
+func saveProfile(player types.Player) {
+	req := types.Request{Player: player, CallerIP: serverIP, TraceID: netproto.NewTraceID()}
+	if _, err := callCentral(context.Background(), "/profile", req); err != nil {
+		log.Printf("⚠️  Saving the profile of %s failed: %v", player.ID, err)
+	}
+}
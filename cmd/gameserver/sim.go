@@ -0,0 +1,256 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== External simulation =====================
+
+// A SimAdapter lets an external service (pathfinding, heavier physics, NPC
+// AI) take part in a chunk's tick. Calls run outside zone_map_Mu with a
+// deadline, so a slow or dead service never stalls the server: the chunk
+// simply ticks without it (the fallback) until the service answers again.
+//
+// The service is reached over gRPC (grpcSimAdapter) or HTTP
+// (httpSimAdapter); either way it takes a ChunkStep and answers a
+// ChunkStepResult, encoded as JSON.
+type SimAdapter interface {
+	Name() string
+	Step(ctx context.Context, step ChunkStep) (ChunkStepResult, error)
+}
+
+// ChunkStep is the snapshot of one owned chunk sent to the adapter.
+type ChunkStep struct {
+	World   string        `json:"world"`
+	Tick    int64         `json:"tick"`
+	ChunkID types.ChunkID `json:"chunk_id"`
+	Chunk   types.Chunk   `json:"chunk"`
+}
+
+// ChunkStepResult is applied as a delta, so edits players made while the
+// call was in flight are kept.
+type ChunkStepResult struct {
+	Upsert []types.Cube `json:"upsert,omitempty"`
+	Remove []string     `json:"remove,omitempty"`
+}
+
+const (
+	simFailureLimit = 3               // consecutive failures before backing off
+	simBackoff      = 5 * time.Second // how long a failing adapter is skipped
+)
+
+var (
+	simAdapter SimAdapter
+	simTimeout       = 50 * time.Millisecond
+	simEvery   int64 = 1 // run the adapter every simEvery ticks
+
+	simMu       sync.Mutex
+	simInFlight = make(map[types.ChunkID]bool)
+	simFailures int
+	simSkipTill time.Time
+
+	simCallSeconds = metrics.NewHistogramVec("game_sim_call_duration_seconds",
+		"Latency of external simulation calls, by adapter.", "adapter", metrics.DefaultBuckets)
+	simFallbacksTotal = metrics.NewCounterVec("game_sim_fallbacks_total",
+		"Chunk ticks that ran without the external simulation, by reason.", "reason")
+)
+
+// newSimAdapter picks the adapter for target: grpc://host:port, or an
+// http(s) URL.
+func newSimAdapter(target string) (SimAdapter, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	switch u.Scheme {
+	case "grpc":
+		if u.Host == "" || u.Path != "" {
+			return nil, fmt.Errorf("%s: want grpc://host:port", target)
+		}
+		return newGRPCSimAdapter(u.Host)
+	case "http", "https":
+		return newHTTPSimAdapter(target), nil
+	}
+	return nil, fmt.Errorf("%s: want grpc://host:port or an http(s) URL", target)
+}
+
+// grpcSimAdapter calls the unary method Step of the service
+// gameserver.Simulation, with JSON messages (content type
+// application/grpc+json) rather than protobuf ones, so a service needs no
+// generated code to take part.
+type grpcSimAdapter struct {
+	conn *grpc.ClientConn
+}
+
+const simStepMethod = "/gameserver.Simulation/Step"
+
+// jsonCodec encodes gRPC messages as JSON.
+type jsonCodec struct{}
+
+func (jsonCodec) Name() string                       { return "json" }
+func (jsonCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (jsonCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+func init() {
+	encoding.RegisterCodec(jsonCodec{})
+}
+
+func newGRPCSimAdapter(addr string) (*grpcSimAdapter, error) {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithDefaultCallOptions(grpc.CallContentSubtype(jsonCodec{}.Name())))
+	if err != nil {
+		return nil, err
+	}
+	return &grpcSimAdapter{conn: conn}, nil
+}
+
+func (a *grpcSimAdapter) Name() string { return "grpc" }
+
+func (a *grpcSimAdapter) Step(ctx context.Context, step ChunkStep) (ChunkStepResult, error) {
+	var result ChunkStepResult
+	err := a.conn.Invoke(ctx, simStepMethod, &step, &result)
+	return result, err
+}
+
+type httpSimAdapter struct {
+	url    string
+	client *http.Client
+}
+
+func newHTTPSimAdapter(url string) *httpSimAdapter {
+	return &httpSimAdapter{url: url, client: &http.Client{}}
+}
+
+func (a *httpSimAdapter) Name() string { return "http" }
+
+func (a *httpSimAdapter) Step(ctx context.Context, step ChunkStep) (ChunkStepResult, error) {
+	var result ChunkStepResult
+	body, err := json.Marshal(step)
+	if err != nil {
+		return result, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return result, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := a.client.Do(req)
+	if err != nil {
+		return result, err
+	}
+	defer res.Body.Close()
+	if res.StatusCode >= 300 {
+		return result, fmt.Errorf("%s: %s", a.url, res.Status)
+	}
+	err = json.NewDecoder(res.Body).Decode(&result)
+	return result, err
+}
+
+// simTick starts an adapter call for every owned chunk with players that has
+// no call in flight. Must be called with zone_map_Mu held.
+func simTick(tick int64) {
+	if simAdapter == nil || tick%simEvery != 0 {
+		return
+	}
+
+	simMu.Lock()
+	defer simMu.Unlock()
+	if time.Now().Before(simSkipTill) {
+		simFallbacksTotal.Inc("backoff")
+		return
+	}
+	for chunk_id, chunk := range zone_map {
+		if chunk.ServerIP != serverIP || len(chunk.PlayerList) == 0 {
+			continue
+		}
+		if simInFlight[chunk_id] {
+			simFallbacksTotal.Inc("busy")
+			continue
+		}
+		simInFlight[chunk_id] = true
+		go runSimStep(ChunkStep{World: world.Name, Tick: tick, ChunkID: chunk_id, Chunk: chunkstore.Clone(chunk)})
+	}
+}
+
+func runSimStep(step ChunkStep) {
+	ctx, cancel := context.WithTimeout(context.Background(), simTimeout)
+	defer cancel()
+
+	start := time.Now()
+	result, err := simAdapter.Step(ctx, step)
+	simCallSeconds.Observe(simAdapter.Name(), time.Since(start).Seconds())
+
+	simMu.Lock()
+	delete(simInFlight, step.ChunkID)
+	if err != nil {
+		simFailures++
+		if simFailures >= simFailureLimit {
+			simSkipTill = time.Now().Add(simBackoff)
+			simFailures = 0
+			log.Printf("⚠️  Simulation adapter %s failing (%v), skipping it for %v", simAdapter.Name(), err, simBackoff)
+		}
+		simMu.Unlock()
+		simFallbacksTotal.Inc("error")
+		return
+	}
+	simFailures = 0
+	simMu.Unlock()
+
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	applySimResult(step.ChunkID, result)
+}
+
+// applySimResult merges an adapter's delta into the chunk if it is still
+// owned here. Must be called with zone_map_Mu held.
+func applySimResult(chunk_id types.ChunkID, result ChunkStepResult) {
+	chunk, ok := zone_map[chunk_id]
+	if !ok || chunk.ServerIP != serverIP || (len(result.Upsert) == 0 && len(result.Remove) == 0) {
+		return
+	}
+
+	remove := make(map[string]bool, len(result.Remove))
+	for _, id := range result.Remove {
+		remove[id] = true
+	}
+	at := clock.Now()
+	upsert := make([]types.Cube, len(result.Upsert))
+	replaced := make(map[string]bool, len(result.Upsert))
+	for i, cube := range result.Upsert {
+		cube.HLC = at
+		upsert[i] = cube
+		replaced[cube.ID] = true
+	}
+	cells := chunk.Cells[:0:0]
+	for _, cube := range chunk.Cells {
+		switch {
+		case replaced[cube.ID]:
+		case remove[cube.ID]:
+			chunkstore.Bury(&chunk, cube.ID, at)
+		default:
+			cells = append(cells, cube)
+		}
+	}
+	chunk.Cells = append(cells, upsert...)
+	chunk.IsDirty = true
+	zone_map[chunk_id] = chunk
+	markUnsaved(chunk_id)
+}
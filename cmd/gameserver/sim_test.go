@@ -0,0 +1,99 @@
+package main
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// startSimService serves gameserver.Simulation/Step on a local port with
+// step, returning the grpc:// URL of the service.
+func startSimService(t *testing.T, step func(ChunkStep) (ChunkStepResult, error)) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	srv.RegisterService(&grpc.ServiceDesc{
+		ServiceName: "gameserver.Simulation",
+		HandlerType: (*any)(nil),
+		Methods: []grpc.MethodDesc{{
+			MethodName: "Step",
+			Handler: func(_ any, ctx context.Context, dec func(any) error, _ grpc.UnaryServerInterceptor) (any, error) {
+				var req ChunkStep
+				if err := dec(&req); err != nil {
+					return nil, err
+				}
+				return step(req)
+			},
+		}},
+	}, struct{}{})
+	go srv.Serve(ln)
+	t.Cleanup(srv.Stop)
+	return "grpc://" + ln.Addr().String()
+}
+
+func TestGRPCSimAdapter(t *testing.T) {
+	chunk_id := types.ChunkID{IDX: 1, IDY: 2}
+	tests := []struct {
+		name   string
+		step   func(ChunkStep) (ChunkStepResult, error)
+		upsert string // ID of the cube wanted back, "" for an error
+		code   codes.Code
+	}{
+		{"answers", func(step ChunkStep) (ChunkStepResult, error) {
+			if step.ChunkID != chunk_id || step.Tick != 7 {
+				return ChunkStepResult{}, status.Errorf(codes.InvalidArgument, "step %+v", step)
+			}
+			return ChunkStepResult{Upsert: []types.Cube{{ID: "npc-path"}}}, nil
+		}, "npc-path", codes.OK},
+		{"fails", func(ChunkStep) (ChunkStepResult, error) {
+			return ChunkStepResult{}, status.Error(codes.Unavailable, "busy")
+		}, "", codes.Unavailable},
+		{"too slow", func(ChunkStep) (ChunkStepResult, error) {
+			time.Sleep(200 * time.Millisecond)
+			return ChunkStepResult{}, nil
+		}, "", codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			adapter, err := newSimAdapter(startSimService(t, tt.step))
+			if err != nil {
+				t.Fatal(err)
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+			defer cancel()
+			result, err := adapter.Step(ctx, ChunkStep{Tick: 7, ChunkID: chunk_id})
+			if tt.upsert == "" {
+				if status.Code(err) != tt.code {
+					t.Errorf("Step = %v, want %v", err, tt.code)
+				}
+				return
+			}
+			if err != nil || len(result.Upsert) != 1 || result.Upsert[0].ID != tt.upsert {
+				t.Errorf("Step = %+v, %v", result, err)
+			}
+		})
+	}
+}
+
+func TestNewSimAdapter(t *testing.T) {
+	for _, target := range []string{"grpc://sim:7000", "http://sim/step", "https://sim/step"} {
+		if _, err := newSimAdapter(target); err != nil {
+			t.Errorf("newSimAdapter(%q): %v", target, err)
+		}
+	}
+	for _, target := range []string{"sim:7000", "grpc://sim:7000/step", "grpc://", "ftp://sim"} {
+		if _, err := newSimAdapter(target); err == nil {
+			t.Errorf("newSimAdapter(%q) took it", target)
+		}
+	}
+}
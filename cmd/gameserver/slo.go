@@ -0,0 +1,180 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Latency SLOs =====================
+
+// Every request is timed by instrumentRequests, and so is what it waited on:
+// its calls to central (callCentral) and to peers (peerRoundTrip) add their
+// time to the requestTiming in its context. Calls made in parallel each add
+// their own, so central and peer time can exceed the total.
+//
+// A request slower than its type's SLO (-request-slo, else -slow-request)
+// is counted and logged with who sent it, which chunk, its trace ID and
+// where the time went. Logging is capped at one line per type a second; the
+// line says how many were left out since.
+
+var (
+	slowRequest = 250 * time.Millisecond // SLO of types without their own; 0 logs none
+	requestSLOs = make(map[types.RequestType]time.Duration)
+)
+
+const slowLogEvery = time.Second
+
+var (
+	requestCentralSeconds = metrics.NewHistogramVec("game_request_central_seconds",
+		"Time a request spent in calls to central, by request type (requests that made any).", "type", metrics.DefaultBuckets)
+	requestPeerSeconds = metrics.NewHistogramVec("game_request_peer_seconds",
+		"Time a request spent in calls to peers, by request type (requests that made any).", "type", metrics.DefaultBuckets)
+	slowRequestsTotal = metrics.NewCounterVec("game_slow_requests_total",
+		"Requests that took longer than their type's SLO, by request type.", "type")
+	_ = metrics.NewGaugeFunc("game_request_slo_seconds",
+		"Latency SLO of each request type, past which a request is logged as slow.", "type", func() map[string]float64 {
+			values := make(map[string]float64, len(handlers))
+			for t := range handlers {
+				values[string(t)] = requestSLO(t).Seconds()
+			}
+			return values
+		})
+)
+
+// requestTiming is the time a request has spent waiting on others.
+type requestTiming struct {
+	central      atomic.Int64 // nanoseconds
+	centralCalls atomic.Int32
+	peer         atomic.Int64
+	peerCalls    atomic.Int32
+}
+
+type timingKey struct{}
+
+func withTiming(ctx context.Context) (context.Context, *requestTiming) {
+	timing := &requestTiming{}
+	return context.WithValue(ctx, timingKey{}, timing), timing
+}
+
+func timingOf(ctx context.Context) *requestTiming {
+	timing, _ := ctx.Value(timingKey{}).(*requestTiming)
+	return timing
+}
+
+// noteCentralCall adds a central call of elapsed to ctx's request, if any.
+func noteCentralCall(ctx context.Context, elapsed time.Duration) {
+	if timing := timingOf(ctx); timing != nil {
+		timing.central.Add(int64(elapsed))
+		timing.centralCalls.Add(1)
+	}
+}
+
+// notePeerCall adds a peer call of elapsed to ctx's request, if any.
+func notePeerCall(ctx context.Context, elapsed time.Duration) {
+	if timing := timingOf(ctx); timing != nil {
+		timing.peer.Add(int64(elapsed))
+		timing.peerCalls.Add(1)
+	}
+}
+
+// peerRoundTrip sends req to peer and waits for its response, within ctx,
+// counting the time against ctx's request.
+func peerRoundTrip(ctx context.Context, peer string, req types.Request) (types.Response, error) {
+	start := time.Now()
+	res, err := netproto.RoundTripContext(ctx, network, peer, req)
+	notePeerCall(ctx, time.Since(start))
+	return res, err
+}
+
+// requestSLO returns how long a request of type t may take.
+func requestSLO(t types.RequestType) time.Duration {
+	if slo, ok := requestSLOs[t]; ok {
+		return slo
+	}
+	return slowRequest
+}
+
+// parseRequestSLOs sets requestSLOs from a comma-separated list of
+// TYPE=duration, refusing unknown types.
+func parseRequestSLOs(list string) error {
+	slos := make(map[types.RequestType]time.Duration)
+	for _, entry := range strings.Split(list, ",") {
+		if entry = strings.TrimSpace(entry); entry == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			return fmt.Errorf("%q is not TYPE=duration", entry)
+		}
+		t := types.RequestType(strings.TrimSpace(name))
+		if _, ok := handlers[t]; !ok {
+			return fmt.Errorf("unknown request type %q", t)
+		}
+		slo, err := time.ParseDuration(strings.TrimSpace(value))
+		if err != nil || slo < 0 {
+			return fmt.Errorf("invalid SLO %q for %s", value, t)
+		}
+		slos[t] = slo
+	}
+	requestSLOs = slos
+	return nil
+}
+
+var (
+	slowLogMu      sync.Mutex
+	slowLogged     = make(map[string]time.Time) // by request type label
+	slowSuppressed = make(map[string]int)
+)
+
+// checkSLO records where a request of elapsed spent its time and reports it
+// if it was slow. timing is nil for requests that cannot call out.
+func checkSLO(reqType string, req types.Request, addr string, elapsed time.Duration, timing *requestTiming) {
+	var central, peer time.Duration
+	var centralCalls, peerCalls int32
+	if timing != nil {
+		central, centralCalls = time.Duration(timing.central.Load()), timing.centralCalls.Load()
+		peer, peerCalls = time.Duration(timing.peer.Load()), timing.peerCalls.Load()
+	}
+	if centralCalls > 0 {
+		requestCentralSeconds.Observe(reqType, central.Seconds())
+	}
+	if peerCalls > 0 {
+		requestPeerSeconds.Observe(reqType, peer.Seconds())
+	}
+
+	slo := requestSLO(req.Type)
+	if slo <= 0 || elapsed <= slo {
+		return
+	}
+	slowRequestsTotal.Inc(reqType)
+
+	slowLogMu.Lock()
+	now := time.Now()
+	if now.Sub(slowLogged[reqType]) < slowLogEvery {
+		slowSuppressed[reqType]++
+		slowLogMu.Unlock()
+		return
+	}
+	slowLogged[reqType] = now
+	suppressed := slowSuppressed[reqType]
+	delete(slowSuppressed, reqType)
+	slowLogMu.Unlock()
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "🐢 Slow %s from player %q at %s, chunk [%d,%d], trace %s: %v (SLO %v); central %v in %d call(s), peers %v in %d call(s)",
+		reqType, playerOf(req), addr, req.ChunkID.IDX, req.ChunkID.IDY, req.TraceID,
+		elapsed.Round(time.Microsecond), slo, central.Round(time.Microsecond), centralCalls, peer.Round(time.Microsecond), peerCalls)
+	if suppressed > 0 {
+		fmt.Fprintf(&sb, "; %d more slow %s since the last", suppressed, reqType)
+	}
+	log.Print(sb.String())
+}
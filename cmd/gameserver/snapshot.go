@@ -0,0 +1,173 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Snapshots =====================
+
+// State sync works as in Quake: the server diffs each client's updates from
+// the newest chunk version that client has acknowledged, not from the last
+// one it was sent, so a lost update costs nothing but a larger next delta.
+// Clients acknowledge a version by sending it as Since: with GET_UPDATES and
+// SPECTATE, or on its own with ACK after applying a PUSH_SPECTATE. The server
+// keeps each client's last maxAcked acknowledgements per chunk, as acks
+// arrive out of order and a chunk's versions restart under a new epoch when
+// it moves or is reloaded; the newest it can still diff from is the
+// baseline. A client with no usable ack, or more than maxDeltaSpan versions
+// behind it, gets a full snapshot instead, as does one whose delta would be
+// no smaller than the chunk.
+//
+// A pushed update not acknowledged within ackTimeout is sent again, diffed
+// from the same baseline, so a spectator of a chunk that stopped changing
+// still catches up.
+
+const (
+	maxAcked     = 8
+	maxDeltaSpan = 64 // versions
+	ackTimeout   = time.Second
+	snapshotTTL  = time.Minute // a client's acks are forgotten after this
+)
+
+type snapshotKey struct {
+	Client  string // player ID, or the address of a spectator
+	ChunkID types.ChunkID
+}
+
+type clientSnapshots struct {
+	Acked []types.ChunkVersion // oldest first
+	Seen  time.Time
+}
+
+// guarded by snapshotsMu, not zone_map_Mu, as GET_UPDATES served from a
+// chunk view (views.go) acks and diffs without it
+var (
+	snapshotsMu      sync.Mutex
+	client_snapshots = make(map[snapshotKey]*clientSnapshots)
+	snapshots_swept  time.Time
+)
+
+var snapshotsSentTotal = metrics.NewCounterVec("game_snapshots_sent_total",
+	"Chunk updates sent to clients: delta (from an acked version) or full (no usable ack, too far behind, or the delta was no smaller).", "kind")
+
+// handleAck records that a client holds version req.Since of req.ChunkID.
+// It is not answered: a lost ack only makes the next update larger.
+func handleAck(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if req.Since == nil {
+		return
+	}
+	noteAck(snapshotKey{Client: addr, ChunkID: req.ChunkID}, *req.Since, time.Now())
+}
+
+// noteAck adds version to key's acknowledgements.
+func noteAck(key snapshotKey, version types.ChunkVersion, now time.Time) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	s, ok := client_snapshots[key]
+	if !ok {
+		s = &clientSnapshots{}
+		client_snapshots[key] = s
+	}
+	s.Seen = now
+	for _, v := range s.Acked {
+		if v == version {
+			return
+		}
+	}
+	s.Acked = append(s.Acked, version)
+	if len(s.Acked) > maxAcked {
+		s.Acked = s.Acked[len(s.Acked)-maxAcked:]
+	}
+}
+
+// forgetAcks drops key's acknowledgements, for a client that holds nothing
+// of the chunk.
+func forgetAcks(key snapshotKey) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	delete(client_snapshots, key)
+}
+
+// syncUpdate returns chunk_id's current version and, when key has
+// acknowledged one it can be diffed from, the changes since then; a nil
+// delta means the whole chunk is to be sent. Must be called with
+// zone_map_Mu held.
+func syncUpdate(key snapshotKey, chunk types.Chunk, now time.Time) (types.ChunkVersion, *types.ChunkDelta) {
+	version, _ := chunkVersion(key.ChunkID, chunk, nil)
+	return syncVersion(key, chunk, version, chunk_versions[key.ChunkID], now)
+}
+
+// syncVersion is syncUpdate for a chunk already observed at version by
+// versions. A delta runs to the newest version versions has observed, which
+// may be past version; the version returned is the one the reply brings
+// the client to.
+func syncVersion(key snapshotKey, chunk types.Chunk, version types.ChunkVersion, versions *chunkstore.Versions, now time.Time) (types.ChunkVersion, *types.ChunkDelta) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	s, ok := client_snapshots[key]
+	if !ok {
+		snapshotsSentTotal.Inc("full")
+		return version, nil
+	}
+	s.Seen = now
+
+	var baseline *types.ChunkVersion
+	for i := range s.Acked {
+		v := &s.Acked[i]
+		if v.Epoch != version.Epoch || v.Seq > version.Seq || version.Seq-v.Seq > maxDeltaSpan {
+			continue
+		}
+		if baseline == nil || v.Seq > baseline.Seq {
+			baseline = v
+		}
+	}
+	if baseline == nil {
+		snapshotsSentTotal.Inc("full")
+		return version, nil
+	}
+	delta, ok := versions.Delta(*baseline)
+	changes := len(delta.Cubes) + len(delta.RemovedCubes) + len(delta.Players) + len(delta.RemovedPlayers) + len(delta.NPCs) + len(delta.RemovedNPCs) +
+		len(delta.Items) + len(delta.RemovedItems)
+	if !ok || (changes > 0 && changes >= len(chunk.Cells)+len(chunk.PlayerList)+len(chunk.NPCs)+len(chunk.Items)) {
+		snapshotsSentTotal.Inc("full")
+		return version, nil
+	}
+	snapshotsSentTotal.Inc("delta")
+	return delta.To, &delta
+}
+
+// acked reports whether key has acknowledged version.
+func acked(key snapshotKey, version types.ChunkVersion) bool {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if s, ok := client_snapshots[key]; ok {
+		for _, v := range s.Acked {
+			if v == version {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// sweepSnapshots forgets the acks of clients not heard from in snapshotTTL.
+func sweepSnapshots(now time.Time) {
+	snapshotsMu.Lock()
+	defer snapshotsMu.Unlock()
+	if now.Sub(snapshots_swept) < snapshotTTL/4 {
+		return
+	}
+	snapshots_swept = now
+	for key, s := range client_snapshots {
+		if now.Sub(s.Seen) > snapshotTTL {
+			delete(client_snapshots, key)
+		}
+	}
+}
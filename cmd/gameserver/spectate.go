@@ -0,0 +1,174 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Spectators =====================
+
+// A client sends SPECTATE for a chunk this server owns or holds a read
+// replica of, and gets the chunk in the reply. From then on, as often as
+// the chunk's activity calls for (see updateEvery), it is pushed whatever
+// changed since the last version it acknowledged (see syncUpdate), as a
+// PUSH_SPECTATE message carrying a Delta when the server can diff and the
+// whole chunk otherwise; it acknowledges each one it applies with ACK.
+// Spectators are not players: they
+// are not in PlayerList, take no room in a full chunk and weigh nothing in
+// migration decisions. A subscription lapses after spectateTTL unless
+// SPECTATE is sent again; SPECTATE with Unsubscribe ends it. When the chunk
+// moves away the spectator is pushed one last PUSH_SPECTATE with Success
+// false and the new owner in RedirectIP, where they can subscribe again.
+
+const (
+	spectateTTL   = 30 * time.Second
+	maxSpectators = 64
+)
+
+type Spectator struct {
+	Conn    netproto.Transport
+	Until   time.Time
+	Version *types.ChunkVersion // last one pushed
+	Pushed  time.Time
+}
+
+// guarded by zone_map_Mu; chunk -> spectator address -> subscription, and
+// the tick each spectated chunk is next pushed at
+var (
+	spectators    = make(map[types.ChunkID]map[string]*Spectator)
+	spectate_next = make(map[types.ChunkID]int64)
+)
+
+var _ = metrics.NewGaugeFunc("game_spectators",
+	"Clients spectating chunks on this server.", "", func() map[string]float64 {
+		zone_map_Mu.Lock()
+		defer zone_map_Mu.Unlock()
+		count := 0
+		for _, subs := range spectators {
+			count += len(subs)
+		}
+		return map[string]float64{"": float64(count)}
+	})
+
+func handleSpectate(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	chunk_id := req.ChunkID
+	subs := spectators[chunk_id]
+	if req.Unsubscribe {
+		delete(subs, addr)
+		if len(subs) == 0 {
+			delete(spectators, chunk_id)
+		}
+		reply(conn, addr, req, types.Response{Success: true, Message: "Stopped spectating"})
+		return
+	}
+
+	chunk, ok := spectatedChunk(chunk_id)
+	if !ok {
+		replyNotOwner(conn, addr, req, zone_map[chunk_id])
+		return
+	}
+	if _, renewing := subs[addr]; !renewing && len(subs) >= maxSpectators {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Too many spectators in this chunk", Code: types.CodeRateLimited,
+			RetryAfterMs: spectateTTL.Milliseconds()})
+		return
+	}
+
+	if key := (snapshotKey{Client: addr, ChunkID: chunk_id}); req.Since != nil {
+		noteAck(key, *req.Since, time.Now())
+	} else {
+		forgetAcks(key)
+	}
+	version, _ := chunkVersion(chunk_id, chunk, nil)
+	if subs == nil {
+		subs = make(map[string]*Spectator)
+		spectators[chunk_id] = subs
+	}
+	subs[addr] = &Spectator{Conn: conn, Until: time.Now().Add(spectateTTL), Version: &version, Pushed: time.Now()}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Spectating", ChunkID: &chunk_id, Version: &version,
+		GameData: types.GameData{Chunk: chunk}, ChunkSize: world.ChunkSize, UpdateMs: updateMs(chunk_id, time.Now())})
+	netproto.Tracef(req.TraceID, "👀 %s spectating chunk [%d,%d]", addr, chunk_id.IDX, chunk_id.IDY)
+}
+
+// spectatedChunk returns the state of chunk_id spectators see here: the
+// chunk if owned, else a live replica copy. Must be called with zone_map_Mu
+// held.
+func spectatedChunk(chunk_id types.ChunkID) (types.Chunk, bool) {
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		return chunk, true
+	}
+	if replica, ok := replicaCopy(chunk_id); ok {
+		return replica.Chunk, true
+	}
+	return types.Chunk{}, false
+}
+
+// streamSpectators pushes the changes of every spectated chunk that is due
+// to its spectators, and observes the others so their activity is known.
+// Must be called with zone_map_Mu held.
+func streamSpectators(now time.Time) {
+	for chunk_id, subs := range spectators {
+		chunk, ok := spectatedChunk(chunk_id)
+		if ok && expTicks < spectate_next[chunk_id] {
+			chunkVersion(chunk_id, chunk, nil)
+			continue
+		}
+		spectate_next[chunk_id] = expTicks + int64(updateEvery(chunk_id, now))
+
+		for addr, spectator := range subs {
+			if now.After(spectator.Until) {
+				delete(subs, addr)
+			}
+		}
+		if !ok {
+			moved := types.Response{Success: false, Code: types.CodeSpectate, Message: "Chunk moved", ChunkID: &chunk_id}
+			if owner := zone_map[chunk_id].ServerIP; owner != "" && owner != serverIP {
+				moved.RedirectIP = owner
+			} else if owner, ok := gossipOwner(chunk_id); ok {
+				moved.RedirectIP = owner
+			}
+			stampClock(&moved)
+			for addr, spectator := range subs {
+				push(spectator.Conn, addr, moved)
+			}
+			delete(spectators, chunk_id)
+			delete(spectate_next, chunk_id)
+			continue
+		}
+		if len(subs) == 0 {
+			delete(spectators, chunk_id)
+			delete(spectate_next, chunk_id)
+			continue
+		}
+
+		current, _ := chunkVersion(chunk_id, chunk, nil)
+		for addr, spectator := range subs {
+			key := snapshotKey{Client: addr, ChunkID: chunk_id}
+			if acked(key, current) {
+				continue
+			}
+			if spectator.Version != nil && *spectator.Version == current && now.Sub(spectator.Pushed) < ackTimeout {
+				// sent, and not yet overdue for an ack
+				continue
+			}
+			if behind(addr) {
+				// the next update covers this one's changes too
+				continue
+			}
+			version, delta := syncUpdate(key, chunk, now)
+			update := types.Response{Success: true, Code: types.CodeSpectate, ChunkID: &chunk_id, Version: &version,
+				UpdateMs: updateMs(chunk_id, now)}
+			if delta != nil {
+				update.Delta = delta
+			} else {
+				update.GameData = types.GameData{Chunk: chunk}
+			}
+			stampClock(&update)
+			push(spectator.Conn, addr, update)
+			spectator.Version, spectator.Pushed = &version, now
+		}
+	}
+}
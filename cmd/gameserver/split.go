@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk splits =====================
+
+// An owned chunk with more than splitPlayers players is split into four
+// sub-chunks (see types.ResolveChunk). The owner asks the central server at
+// /split; central records the split, gives the owner the sub-chunks and
+// tells the other servers with SPLIT_CHUNK. From then on the sub-chunks
+// migrate, replicate and persist independently. Edit history of the parent
+// is dropped. Sub-chunks are not merged back.
+
+// splitPlayers is the player count above which an owned chunk is split (0
+// disables splitting).
+var splitPlayers = 0
+
+// chunks this server knows to be split; learned from central
+var splits = make(map[types.ChunkID]bool)
+
+var chunkSplitsTotal = metrics.NewCounterVec("game_chunk_splits_total",
+	"Owned chunks this server split into sub-chunks.", "")
+
+// chunkAt returns the chunk containing position (x, y) of world_id. Must be
+// called with zone_map_Mu held.
+func chunkAt(world_id string, x, y int) types.ChunkID {
+	return types.ResolveChunk(world_id, x, y, world.ChunkSize, splits)
+}
+
+// chunkLabel names chunk_id in metric labels: "x,y", with ",level" for a
+// sub-chunk, after "world/" outside the shared world.
+func chunkLabel(chunk_id types.ChunkID) string {
+	label := fmt.Sprintf("%d,%d", chunk_id.IDX, chunk_id.IDY)
+	if chunk_id.Level > 0 {
+		label += fmt.Sprintf(",%d", chunk_id.Level)
+	}
+	if chunk_id.World != types.DefaultWorld {
+		label = chunk_id.World + "/" + label
+	}
+	return label
+}
+
+// splitList returns the known splits for clients, sorted. Must be called with
+// zone_map_Mu held.
+func splitList() []types.ChunkID {
+	if len(splits) == 0 {
+		return nil
+	}
+	list := make([]types.ChunkID, 0, len(splits))
+	for chunk_id := range splits {
+		list = append(list, chunk_id)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		a, b := list[i], list[j]
+		if a.Level != b.Level {
+			return a.Level < b.Level
+		}
+		if a.IDX != b.IDX {
+			return a.IDX < b.IDX
+		}
+		return a.IDY < b.IDY
+	})
+	return list
+}
+
+// learnSplits records splits reported by central. Must be called with
+// zone_map_Mu held.
+func learnSplits(list []types.ChunkID) {
+	for _, chunk_id := range list {
+		if !splits[chunk_id] {
+			splitLocal(chunk_id, "")
+		}
+	}
+}
+
+// checkSplits asks central to split owned chunks that grew too crowded.
+// Must be called with zone_map_Mu held.
+func checkSplits() {
+	if splitPlayers <= 0 {
+		return
+	}
+	counts := make(map[types.ChunkID]int)
+	for _, chunk_id := range players {
+		counts[chunk_id]++
+	}
+	for chunk_id, count := range counts {
+		chunk, ok := zone_map[chunk_id]
+		if count <= splitPlayers || !ok || chunk.ServerIP != serverIP || !chunk_id.CanSplit(world.ChunkSize) {
+			continue
+		}
+		trace := netproto.NewTraceID()
+		res, err := callCentral(context.Background(), "/split", types.Request{ChunkID: chunk_id, CallerIP: serverIP, PlayerCount: count, TraceID: trace})
+		if err != nil || !res.Success {
+			netproto.Tracef(trace, "⚠️  Split of chunk [%d,%d] refused: %v %s", chunk_id.IDX, chunk_id.IDY, err, res.Message)
+			continue
+		}
+		splitLocal(chunk_id, trace)
+		learnSplits(res.Splits)
+	}
+}
+
+// splitLocal divides chunk_id into its sub-chunks if this server owns it,
+// and otherwise forgets any copy of it. Players, NPCs and items in it are
+// moved to the sub-chunk they stand in. Must be called with zone_map_Mu held.
+func splitLocal(chunk_id types.ChunkID, trace string) {
+	splits[chunk_id] = true
+	touchChunk(chunk_id)
+	chunk, ok := zone_map[chunk_id]
+
+	if ok && chunk.ServerIP == serverIP {
+		// which sub-chunk a removed cube was in is not known, so each
+		// treats every removal as forgotten
+		floor := chunk.TombstoneFloor
+		for _, t := range chunk.Tombstones {
+			floor = max(floor, t.HLC)
+		}
+		children := make(map[types.ChunkID]types.Chunk, 4)
+		for _, child_id := range chunk_id.Children() {
+			children[child_id] = types.Chunk{World: child_id.World, IDX: child_id.IDX, IDY: child_id.IDY, Level: child_id.Level,
+				Data: chunk.Data, ServerIP: serverIP, IsDirty: true, TombstoneFloor: floor}
+		}
+		for _, cube := range chunk.Cells {
+			child_id := chunk_id.Child(cube.X, cube.Z, world.ChunkSize)
+			child := children[child_id]
+			child.Cells = append(child.Cells, cube)
+			children[child_id] = child
+		}
+		for _, player := range chunk.PlayerList {
+			child_id := chunk_id.Child(player.PosX, player.PosY, world.ChunkSize)
+			player.ChunkID = child_id
+			child := children[child_id]
+			child.PlayerList = append(child.PlayerList, player)
+			children[child_id] = child
+		}
+		for _, npc := range chunk.NPCs {
+			child_id := chunk_id.Child(npc.PosX, npc.PosY, world.ChunkSize)
+			child := children[child_id]
+			child.NPCs = append(child.NPCs, npc)
+			children[child_id] = child
+		}
+		for _, item := range chunk.Items {
+			child_id := chunk_id.Child(item.PosX, item.PosY, world.ChunkSize)
+			child := children[child_id]
+			child.Items = append(child.Items, item)
+			children[child_id] = child
+		}
+		for child_id, child := range children {
+			zone_map[child_id] = child
+			markUnsaved(child_id)
+		}
+		chunkSplitsTotal.Inc("")
+		journal.Record(WorldEvent{Type: "SPLIT", ChunkID: chunk_id, TraceID: trace})
+		log.Printf("🔪 Split chunk [%d,%d] (level %d) into 4 sub-chunks", chunk_id.IDX, chunk_id.IDY, chunk_id.Level)
+	}
+
+	for player_id, in := range players {
+		if in != chunk_id {
+			continue
+		}
+		player := player_map[player_id]
+		player.ChunkID = chunk_id.Child(player.PosX, player.PosY, world.ChunkSize)
+		players[player_id] = player.ChunkID
+		player_map[player_id] = player
+	}
+
+	delete(zone_map, chunk_id)
+	chunk_views.Delete(chunk_id)
+	delete(cube_indexes, chunk_id)
+	delete(chunk_versions, chunk_id)
+	delete(chunk_activity, chunk_id)
+	delete(chat_logs, chunk_id)
+	delete(chunk_history, chunk_id)
+	delete(chunk_replicas, chunk_id)
+	delete(replica_dirty, chunk_id)
+	delete(replica_synced, chunk_id)
+	delete(replica_copies, chunk_id)
+	delete(unsaved, chunk_id)
+	delete(chunk_used, chunk_id)
+	if store != nil {
+		if err := store.Remove(chunk_id); err != nil {
+			log.Printf("⚠️  Removing persisted chunk [%d,%d] after split failed: %v", chunk_id.IDX, chunk_id.IDY, err)
+		}
+	}
+}
+
+// handleSplitChunk applies a split central announced.
+func handleSplitChunk(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if !splits[req.ChunkID] {
+		splitLocal(req.ChunkID, req.TraceID)
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Split applied"})
+	netproto.Tracef(req.TraceID, "🔪 Chunk [%d,%d] (level %d) is split", req.ChunkID.IDX, req.ChunkID.IDY, req.ChunkID.Level)
+}
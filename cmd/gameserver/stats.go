@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Player stats =====================
+
+// The server counts what each player does here (types.PlayerStats): every
+// cube edit recorded in a chunk's history, the cells walked by each move it
+// applies and the chunks they enter, and kills and deaths at the hands
+// of other players. The counts go to central with the metrics report every
+// reportEvery ticks and start again; central keeps the totals in the
+// players' profiles, ranks them on /leaderboard and awards the achievements
+// they reach. The report's reply names those, and each is pushed to its
+// player as PUSH_ACHIEVEMENT if they are still here. Counts of a report
+// central did not take are kept for the next one.
+
+// guarded by zone_map_Mu; counts since the last report, by player
+var play_counts = make(map[string]types.PlayerStats)
+
+// countStats adds to player_id's counts. Must be called with zone_map_Mu
+// held.
+func countStats(player_id string, add types.PlayerStats) {
+	if player_id == "" {
+		return
+	}
+	stats := play_counts[player_id]
+	stats.Add(add)
+	play_counts[player_id] = stats
+}
+
+// countMove counts the cells player walked from prev, where they were held
+// here before. A player new here (prev nil: a move forwarded by the server
+// they left) has only entered a chunk; other chunks entered are counted by
+// GET_DATA. Must be called with zone_map_Mu held.
+func countMove(player types.Player, prev *types.Player) {
+	if prev == nil {
+		countStats(player.ID, types.PlayerStats{ChunksVisited: 1})
+		return
+	}
+	if prev.World != player.World || prev.RespawnMs > 0 {
+		return
+	}
+	if d := math.Hypot(float64(player.PosX-prev.PosX), float64(player.PosY-prev.PosY)); d > 0 {
+		countStats(player.ID, types.PlayerStats{Distance: d})
+	}
+}
+
+// takeStats returns the counts since the last report, by player ID, and
+// starts them again. Must be called with zone_map_Mu held.
+func takeStats() []types.PlayerStats {
+	if len(play_counts) == 0 {
+		return nil
+	}
+	list := make([]types.PlayerStats, 0, len(play_counts))
+	for player_id, stats := range play_counts {
+		stats.PlayerID = player_id
+		list = append(list, stats)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].PlayerID < list[j].PlayerID })
+	play_counts = make(map[string]types.PlayerStats)
+	return list
+}
+
+// keepStats puts back counts central did not take.
+func keepStats(list []types.PlayerStats) {
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	for _, stats := range list {
+		player_id := stats.PlayerID
+		stats.PlayerID = ""
+		countStats(player_id, stats)
+	}
+}
+
+var awardsTotal = metrics.NewCounterVec("game_achievements_pushed_total",
+	"Achievements central awarded this server's players, by whether they were still here to be told.", "result")
+
+// pushAwards tells each player central awarded an achievement to, if they
+// are still held here.
+func pushAwards(awards []types.Award) {
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	for _, award := range awards {
+		chunk_id, held := players[award.PlayerID]
+		addr, reachable := player_addrs[award.PlayerID]
+		if !held || !reachable {
+			awardsTotal.Inc("gone")
+			continue
+		}
+		notice := types.Response{Success: true, Code: types.CodeAchievement,
+			Message: fmt.Sprintf("Achievement earned: %s", award.Achievement.Name), Awards: []types.Award{award}}
+		stampClock(&notice)
+		push(pushConn, addr, notice)
+		awardsTotal.Inc("pushed")
+		journal.Record(WorldEvent{Type: "ACHIEVEMENT", PlayerID: award.PlayerID, ChunkID: chunk_id, Detail: award.Achievement.ID})
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Client telemetry =====================
+
+// Clients report what they measure of their connection (round trips, lost
+// requests, redirects) with TELEMETRY. The latest report per player shows
+// on /admin/players, feeds the client RTT histogram, and is averaged into
+// the metrics report to central, which sees from it how well each server's
+// players are served.
+
+type PlayerStats struct {
+	Stats types.ClientStats
+	At    time.Time
+}
+
+// guarded by zone_map_Mu
+var player_stats = make(map[string]PlayerStats)
+
+var clientRTTSeconds = metrics.NewHistogramVec("game_client_rtt_seconds",
+	"Average round trip players reported with TELEMETRY.", "", metrics.DefaultBuckets)
+
+func handleTelemetry(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	player_id := req.Player.ID
+	if _, held := players[player_id]; !held || req.Stats == nil {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+	player_stats[player_id] = PlayerStats{Stats: *req.Stats, At: time.Now()}
+	clientRTTSeconds.Observe("", req.Stats.RTTAvgMs/1000)
+	reply(conn, addr, req, types.Response{Success: true, Message: "Telemetry recorded"})
+}
+
+// clientQuality averages the players' latest reports. Must be called with
+// zone_map_Mu held.
+func clientQuality() (rtt_ms, loss float64) {
+	n := 0
+	for player_id, stats := range player_stats {
+		if _, held := players[player_id]; !held {
+			continue
+		}
+		rtt_ms += stats.Stats.RTTAvgMs
+		loss += stats.Stats.LossRate
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return rtt_ms / float64(n), loss / float64(n)
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Player transfer =====================
+
+// When a chunk, or a player, moves to another server the player's state is
+// sent ahead with PLAYER_TRANSFER before the client is redirected, so the new
+// owner already knows their position, AOI radius, address and health when
+// the first request arrives. Until the client has switched over, moves that
+// still reach the old server are forwarded to the new one instead of being
+// lost. A transfer the new server does not answer waits in the peer outbox
+// (peeroutbox.go) and is sent again.
+
+// TransferredPlayer remembers where a player was handed to.
+type TransferredPlayer struct {
+	Target string
+	At     time.Time
+}
+
+// players handed to another server, forwarded there until the entry expires
+var transferred = make(map[string]TransferredPlayer)
+
+// collectHandoffs gathers the state of the players this server has in
+// chunk_id, plus extra (the player whose request caused the move, who may
+// not be in the chunk yet) if their position is inside it; clients also read
+// neighbouring chunks, which must not move them. Must be called with
+// zone_map_Mu held.
+func collectHandoffs(chunk_id types.ChunkID, extra types.Player) []types.PlayerHandoff {
+	var handoffs []types.PlayerHandoff
+	add := func(player types.Player) {
+		player.ChunkID = chunk_id
+		handoffs = append(handoffs, types.PlayerHandoff{Player: player, ChunkID: chunk_id,
+			LastSeen: player_seen[player.ID], Addr: player_addrs[player.ID]})
+	}
+	for player_id, in := range players {
+		if in != chunk_id || player_id == extra.ID {
+			continue
+		}
+		if player, ok := player_map[player_id]; ok {
+			add(player)
+		}
+	}
+	if extra.ID != "" && chunkAt(extra.World, extra.PosX, extra.PosY) == chunk_id {
+		add(extra)
+	}
+	return handoffs
+}
+
+// transferPlayers hands the players of chunk_id (and extra) to target and
+// forgets them here, keeping only the forwarding entry. Must be called with
+// zone_map_Mu held.
+func transferPlayers(ctx context.Context, chunk_id types.ChunkID, target string, extra types.Player, trace string) {
+	if target == serverIP {
+		return
+	}
+	handoffs := collectHandoffs(chunk_id, extra)
+	if len(handoffs) == 0 {
+		return
+	}
+	req := types.Request{Type: types.ReqPlayerTransfer, ChunkID: chunk_id, CallerIP: serverIP, Handoffs: handoffs, TraceID: trace}
+	if _, err := p2p(ctx, req, target); err != nil {
+		netproto.Tracef(trace, "⚠️  Transfer of %d players to %s failed, queued: %v", len(handoffs), target, err)
+		queueDelivery(req, target, err)
+		return
+	}
+	finishTransfer(handoffs, target)
+	netproto.Tracef(trace, "🧳 Transferred %d players in chunk [%d,%d] to %s", len(handoffs), chunk_id.IDX, chunk_id.IDY, target)
+}
+
+// transferPlayersAsync is transferPlayers for callers that must not wait on
+// target, such as a FROM_CENTRAL reply the target is blocked on.
+func transferPlayersAsync(chunk_id types.ChunkID, target string, trace string) {
+	if target == serverIP {
+		return
+	}
+	handoffs := collectHandoffs(chunk_id, types.Player{})
+	if len(handoffs) == 0 {
+		return
+	}
+	go func() {
+		req := types.Request{Type: types.ReqPlayerTransfer, ChunkID: chunk_id, CallerIP: serverIP, Handoffs: handoffs, TraceID: trace}
+		if _, err := p2p(context.Background(), req, target); err != nil {
+			netproto.Tracef(trace, "⚠️  Transfer of %d players to %s failed, queued: %v", len(handoffs), target, err)
+			zone_map_Mu.Lock()
+			queueDelivery(req, target, err)
+			zone_map_Mu.Unlock()
+			return
+		}
+		zone_map_Mu.Lock()
+		finishTransfer(handoffs, target)
+		zone_map_Mu.Unlock()
+		netproto.Tracef(trace, "🧳 Transferred %d players in chunk [%d,%d] to %s", len(handoffs), chunk_id.IDX, chunk_id.IDY, target)
+	}()
+}
+
+// finishTransfer drops handed-off players from this server's indexes. Must
+// be called with zone_map_Mu held.
+func finishTransfer(handoffs []types.PlayerHandoff, target string) {
+	now := time.Now()
+	for _, handoff := range handoffs {
+		player_id := handoff.Player.ID
+		delete(players, player_id)
+		delete(player_map, player_id)
+		delete(player_seen, player_id)
+		delete(player_addrs, player_id)
+		delete(input_seqs, player_id)
+		delete(player_stats, player_id)
+		delete(chat_buckets, player_id)
+		delete(respawned, player_id)
+		transferred[player_id] = TransferredPlayer{Target: target, At: now}
+		playerTransfersTotal.Inc("out")
+	}
+}
+
+// handlePlayerTransfer installs players handed over by a peer.
+func handlePlayerTransfer(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	for _, handoff := range req.Handoffs {
+		player := handoff.Player
+		if player.ID == "" {
+			continue
+		}
+		observeHLC(player.HLC, addr)
+		if held, ok := player_map[player.ID]; ok && held.HLC > player.HLC {
+			// a handoff sent again after the player got here another way
+			continue
+		}
+		player.ServerIP = serverIP
+		players[player.ID] = handoff.ChunkID
+		player_map[player.ID] = player
+		// the idle clock carries over; a player never seen here starts now
+		if seen, ok := player_seen[player.ID]; !ok || handoff.LastSeen.After(seen) {
+			player_seen[player.ID] = handoff.LastSeen
+		}
+		if player_seen[player.ID].IsZero() {
+			player_seen[player.ID] = time.Now()
+		}
+		if handoff.Addr != "" {
+			player_addrs[player.ID] = handoff.Addr
+		}
+		// the player may be coming back
+		delete(transferred, player.ID)
+		playerTransfersTotal.Inc("in")
+		journal.Record(WorldEvent{Type: "TRANSFER_IN", PlayerID: player.ID, Addr: addr, ChunkID: handoff.ChunkID, Detail: "from " + req.CallerIP, TraceID: req.TraceID})
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: fmt.Sprintf("Accepted %d players", len(req.Handoffs))})
+	netproto.Tracef(req.TraceID, "🧳 Accepted %d players from %s", len(req.Handoffs), req.CallerIP)
+}
+
+// forwardMove passes a move from a player who was transferred away on to
+// their new server and tells the client to follow. It reports false if the
+// player was not transferred. Must be called with zone_map_Mu held.
+func forwardMove(req types.Request, chunk_id types.ChunkID, conn netproto.Transport, addr string) bool {
+	moved, ok := transferred[req.Player.ID]
+	if !ok {
+		return false
+	}
+	if chunk, held := zone_map[chunk_id]; held && chunk.ServerIP == serverIP {
+		// walked back into a chunk we own
+		delete(transferred, req.Player.ID)
+		return false
+	}
+	// the serve loop noted the player as seen; they are not ours any more
+	delete(player_seen, req.Player.ID)
+	delete(player_addrs, req.Player.ID)
+
+	fwd := req
+	fwd.IsPeerReq = true
+	go func() {
+		if _, err := p2p(context.Background(), fwd, moved.Target); err != nil {
+			netproto.Tracef(req.TraceID, "⚠️  Forwarding move of %s to %s failed: %v", req.Player.ID, moved.Target, err)
+		}
+	}()
+	reply(conn, addr, req, types.Response{Success: true, Message: moved.Target, Code: types.CodeRedirect,
+		RedirectIP: moved.Target, ChunkID: &chunk_id})
+	netproto.Tracef(req.TraceID, "↪️  Forwarded move of transferred player %s to %s", req.Player.ID, moved.Target)
+	return true
+}
+
+// sweepTransferred forgets forwarding entries once a client has had
+// playerTimeout to switch over. Must be called with zone_map_Mu held.
+func sweepTransferred(now time.Time) {
+	for player_id, moved := range transferred {
+		if now.Sub(moved.At) > playerTimeout {
+			delete(transferred, player_id)
+		}
+	}
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Transactions =====================
+
+// A player edits several chunks atomically with TX_BEGIN, one TX_APPLY per
+// chunk and TX_COMMIT, all sent to the same server. That server coordinates
+// a two-phase commit: every owner involved, itself included, checks its
+// chunks' edits and locks the chunks (TX_PREPARE), and only if all of them
+// agree are the edits applied (TX_FINISH); otherwise none are. A locked
+// chunk refuses other writes with ERR_CHUNK_LOCKED and does not migrate.
+// A participant that hears nothing for txLockTimeout aborts on its own, so
+// a commit lost for good loses that participant's edits; that is the gap
+// this lightweight scheme leaves. Each chunk's edits are one undo step.
+
+const (
+	maxTxChunks   = 8
+	txTimeout     = 30 * time.Second // open transactions never committed
+	txLockTimeout = 10 * time.Second
+	txFinishTries = 3
+)
+
+// Tx is a transaction opened here and not yet committed.
+type Tx struct {
+	PlayerID string
+	Edits    []types.ChunkEdit // one per chunk, in TX_APPLY order
+	Opened   time.Time
+}
+
+// PreparedTx holds a transaction's edits to owned chunks between TX_PREPARE
+// and TX_FINISH.
+type PreparedTx struct {
+	PlayerID    string
+	Coordinator string
+	Edits       []types.ChunkEdit
+	At          time.Time
+}
+
+var (
+	open_txs     = make(map[string]*Tx)
+	prepared_txs = make(map[string]PreparedTx)
+	tx_locks     = make(map[types.ChunkID]string) // chunk -> transaction holding it
+)
+
+// writes refused on a locked chunk
+var txGuarded = map[types.RequestType]bool{
+	types.ReqAddCube:    true,
+	types.ReqDltCube:    true,
+	types.ReqAddCubes:   true,
+	types.ReqDltCubes:   true,
+	types.ReqUndo:       true,
+	types.ReqUpdateData: true,
+}
+
+var txTotal = metrics.NewCounterVec("game_tx_total",
+	"Transactions coordinated by this server, by outcome.", "outcome")
+
+// chunkLocked reports whether req is a write to a chunk a transaction holds.
+// Must be called with zone_map_Mu held.
+func chunkLocked(req types.Request) bool {
+	_, locked := tx_locks[req.ChunkID]
+	return locked && txGuarded[req.Type]
+}
+
+func handleTxBegin(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	tx_id := netproto.NewTraceID()
+	open_txs[tx_id] = &Tx{PlayerID: req.PlayerID, Opened: time.Now()}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Transaction open", TxID: tx_id})
+	netproto.Tracef(req.TraceID, "🧾 %s opened transaction %s", req.PlayerID, tx_id)
+}
+
+func handleTxApply(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	tx, ok := open_txs[req.TxID]
+	if !ok || tx.PlayerID != req.PlayerID {
+		reply(conn, addr, req, types.Response{Success: false, Message: "No such open transaction", Code: types.CodeNotFound})
+		return
+	}
+	for _, cube := range req.Cubes {
+		if cube.ID == "" {
+			reply(conn, addr, req, types.Response{Success: false, Message: "Cube without cube_id", Code: types.CodeBadRequest})
+			return
+		}
+	}
+
+	i := 0
+	for i < len(tx.Edits) && tx.Edits[i].ChunkID != req.ChunkID {
+		i++
+	}
+	if i == len(tx.Edits) {
+		if i == maxTxChunks {
+			reply(conn, addr, req, types.Response{Success: false, Code: types.CodeBadRequest,
+				Message: fmt.Sprintf("A transaction spans at most %d chunks", maxTxChunks)})
+			return
+		}
+		tx.Edits = append(tx.Edits, types.ChunkEdit{ChunkID: req.ChunkID})
+	}
+	edit := tx.Edits[i]
+	if n := len(edit.Cubes) + len(edit.CubeIDs) + len(req.Cubes) + len(req.CubeIDs); n == 0 || n > maxCubeBatch {
+		reply(conn, addr, req, types.Response{Success: false, Code: types.CodeBadRequest,
+			Message: fmt.Sprintf("A chunk takes 1 to %d edits per transaction", maxCubeBatch)})
+		return
+	}
+	edit.Cubes = append(edit.Cubes, req.Cubes...)
+	edit.CubeIDs = append(edit.CubeIDs, req.CubeIDs...)
+	tx.Edits[i] = edit
+
+	reply(conn, addr, req, types.Response{Success: true, TxID: req.TxID,
+		Message: fmt.Sprintf("%d chunks in transaction", len(tx.Edits))})
+}
+
+func handleTxAbort(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if tx, ok := open_txs[req.TxID]; ok && tx.PlayerID == req.PlayerID {
+		delete(open_txs, req.TxID)
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Transaction dropped", TxID: req.TxID})
+}
+
+// handleTxCommit runs the two-phase commit of an open transaction.
+func handleTxCommit(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	tx, ok := open_txs[req.TxID]
+	if !ok || tx.PlayerID != req.PlayerID {
+		reply(conn, addr, req, types.Response{Success: false, Message: "No such open transaction", Code: types.CodeNotFound})
+		return
+	}
+	delete(open_txs, req.TxID)
+	if len(tx.Edits) == 0 {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Transaction has no edits", Code: types.CodeBadRequest})
+		return
+	}
+
+	by_owner := make(map[string][]types.ChunkEdit)
+	for _, edit := range tx.Edits {
+		owner := chunkOwner(edit.ChunkID)
+		if owner == "" {
+			txTotal.Inc("aborted")
+			reply(conn, addr, req, types.Response{Success: false, Code: types.CodeTxAborted, TxID: req.TxID,
+				Message: fmt.Sprintf("Transaction aborted: owner of chunk [%d,%d] unknown", edit.ChunkID.IDX, edit.ChunkID.IDY)})
+			return
+		}
+		by_owner[owner] = append(by_owner[owner], edit)
+	}
+
+	// phase one: every owner checks and locks its chunks
+	var refusals []string
+	type vote struct {
+		owner string
+		err   error
+	}
+	votes := make(chan vote, len(by_owner))
+	for owner, edits := range by_owner {
+		if owner == serverIP {
+			votes <- vote{owner, prepareTx(req.TxID, tx.PlayerID, serverIP, edits)}
+			continue
+		}
+		prepare := types.Request{Type: types.ReqTxPrepare, TxID: req.TxID, PlayerID: tx.PlayerID, CallerIP: serverIP,
+			Edits: edits, TraceID: req.TraceID}
+		go func(owner string) {
+			start := time.Now()
+			res, err := netproto.RoundTrip(network, owner, prepare, peerTimeout)
+			notePeerCall(ctx, time.Since(start))
+			if err == nil && !res.Success {
+				err = fmt.Errorf("%s", res.Message)
+			}
+			votes <- vote{owner, err}
+		}(owner)
+	}
+	for range by_owner {
+		if v := <-votes; v.err != nil {
+			refusals = append(refusals, fmt.Sprintf("%s: %v", v.owner, v.err))
+		}
+	}
+
+	// phase two: apply everywhere or nowhere
+	commit := len(refusals) == 0
+	finishTx(req.TxID, by_owner, commit, req.TraceID)
+	if !commit {
+		txTotal.Inc("aborted")
+		reply(conn, addr, req, types.Response{Success: false, Code: types.CodeTxAborted, TxID: req.TxID,
+			Message: "Transaction aborted: " + strings.Join(refusals, "; ")})
+		netproto.Tracef(req.TraceID, "🧾 Transaction %s aborted: %s", req.TxID, strings.Join(refusals, "; "))
+		return
+	}
+	txTotal.Inc("committed")
+	reply(conn, addr, req, types.Response{Success: true, TxID: req.TxID,
+		Message: fmt.Sprintf("Committed edits to %d chunks", len(tx.Edits))})
+	netproto.Tracef(req.TraceID, "🧾 Transaction %s committed across %d chunks on %d servers", req.TxID, len(tx.Edits), len(by_owner))
+}
+
+// chunkOwner returns the server this one believes owns chunk_id. Must be
+// called with zone_map_Mu held.
+func chunkOwner(chunk_id types.ChunkID) string {
+	touchChunk(chunk_id)
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP != "" {
+		return chunk.ServerIP
+	}
+	if replica, ok := replicaCopy(chunk_id); ok {
+		return replica.Owner
+	}
+	if owner, ok := gossipOwner(chunk_id); ok {
+		return owner
+	}
+	return ""
+}
+
+// finishTx tells every owner the outcome and waits for them, retrying lost
+// messages. Must be called with zone_map_Mu held.
+func finishTx(tx_id string, by_owner map[string][]types.ChunkEdit, commit bool, trace string) {
+	done := make(chan struct{}, len(by_owner))
+	for owner := range by_owner {
+		if owner == serverIP {
+			finishLocal(tx_id, commit, trace)
+			done <- struct{}{}
+			continue
+		}
+		finish := types.Request{Type: types.ReqTxFinish, TxID: tx_id, Commit: commit, CallerIP: serverIP, TraceID: trace}
+		go func(owner string) {
+			defer func() { done <- struct{}{} }()
+			var err error
+			for try := 0; try < txFinishTries; try++ {
+				var res types.Response
+				if res, err = netproto.RoundTrip(network, owner, finish, peerTimeout); err == nil {
+					if !res.Success {
+						err = fmt.Errorf("%s", res.Message)
+					}
+					break
+				}
+			}
+			if err != nil {
+				log.Printf("⚠️  TX_FINISH %s (commit %t) on %s failed: %v", tx_id, commit, owner, err)
+			}
+		}(owner)
+	}
+	for range by_owner {
+		<-done
+	}
+}
+
+// prepareTx checks a transaction's edits against owned chunks and locks
+// them. Must be called with zone_map_Mu held.
+func prepareTx(tx_id, player_id, coordinator string, edits []types.ChunkEdit) error {
+	for _, edit := range edits {
+		touchChunk(edit.ChunkID)
+		chunk, ok := zone_map[edit.ChunkID]
+		if !ok || chunk.ServerIP != serverIP {
+			return fmt.Errorf("chunk [%d,%d] is not owned by %s", edit.ChunkID.IDX, edit.ChunkID.IDY, serverIP)
+		}
+		if holder, locked := tx_locks[edit.ChunkID]; locked && holder != tx_id {
+			return fmt.Errorf("chunk [%d,%d] is locked by another transaction", edit.ChunkID.IDX, edit.ChunkID.IDY)
+		}
+		if len(edit.Cubes)+len(edit.CubeIDs) > maxCubeBatch {
+			return fmt.Errorf("too many edits for chunk [%d,%d]", edit.ChunkID.IDX, edit.ChunkID.IDY)
+		}
+		for _, cube := range edit.Cubes {
+			if cube.ID == "" {
+				return fmt.Errorf("cube without cube_id")
+			}
+		}
+		ix := cubeIndex(edit.ChunkID, chunk)
+		seen := make(map[string]bool, len(edit.CubeIDs))
+		for _, cube_id := range edit.CubeIDs {
+			if _, ok := ix.Find(chunk.Cells, cube_id); !ok || seen[cube_id] {
+				return fmt.Errorf("cube %s not in chunk [%d,%d]", cube_id, edit.ChunkID.IDX, edit.ChunkID.IDY)
+			}
+			seen[cube_id] = true
+		}
+	}
+	for _, edit := range edits {
+		tx_locks[edit.ChunkID] = tx_id
+	}
+	prepared_txs[tx_id] = PreparedTx{PlayerID: player_id, Coordinator: coordinator, Edits: edits, At: time.Now()}
+	return nil
+}
+
+// finishLocal applies or drops a prepared transaction and unlocks its
+// chunks. Must be called with zone_map_Mu held.
+func finishLocal(tx_id string, commit bool, trace string) bool {
+	tx, ok := prepared_txs[tx_id]
+	if !ok {
+		return false
+	}
+	delete(prepared_txs, tx_id)
+	for _, edit := range tx.Edits {
+		if tx_locks[edit.ChunkID] == tx_id {
+			delete(tx_locks, edit.ChunkID)
+		}
+	}
+	if commit {
+		for _, edit := range tx.Edits {
+			applyTxEdit(tx_id, tx.PlayerID, tx.Coordinator, edit, trace)
+		}
+	}
+	return true
+}
+
+// applyTxEdit applies one chunk's prepared edits, journaled as coming from
+// the coordinator. Must be called with zone_map_Mu held.
+func applyTxEdit(tx_id, player_id, coordinator string, edit types.ChunkEdit, trace string) {
+	chunk_id := edit.ChunkID
+	touchChunk(chunk_id)
+	chunk, ok := zone_map[chunk_id]
+	if !ok || chunk.ServerIP != serverIP {
+		log.Printf("⚠️  Chunk [%d,%d] left this server while locked by transaction %s; its edits are lost", chunk_id.IDX, chunk_id.IDY, tx_id)
+		return
+	}
+
+	batch := newEditBatch()
+	for _, cube_id := range edit.CubeIDs {
+		if cube, ok := removeCube(chunk_id, &chunk, cube_id); ok {
+			publish(CubeRemoved{ChunkID: chunk_id, Cube: cube, PlayerID: player_id, Batch: batch, Addr: coordinator, Detail: "tx " + tx_id, TraceID: trace})
+		}
+	}
+	for _, cube := range edit.Cubes {
+		cube = addCube(chunk_id, &chunk, cube)
+		publish(CubeAdded{ChunkID: chunk_id, Cube: cube, PlayerID: player_id, Batch: batch, Addr: coordinator, Detail: "tx " + tx_id, TraceID: trace})
+	}
+	chunk.IsDirty = true
+	zone_map[chunk_id] = chunk
+	markUnsaved(chunk_id)
+}
+
+func handleTxPrepare(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if err := prepareTx(req.TxID, req.PlayerID, req.CallerIP, req.Edits); err != nil {
+		reply(conn, addr, req, types.Response{Success: false, Message: err.Error(), Code: types.CodeTxAborted, TxID: req.TxID})
+		return
+	}
+	reply(conn, addr, req, types.Response{Success: true, Message: "Prepared", TxID: req.TxID})
+	netproto.Tracef(req.TraceID, "🧾 Prepared transaction %s from %s (%d chunks)", req.TxID, req.CallerIP, len(req.Edits))
+}
+
+func handleTxFinish(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	if !finishLocal(req.TxID, req.Commit, req.TraceID) && req.Commit {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Transaction not prepared here, or expired", Code: types.CodeNotFound, TxID: req.TxID})
+		return
+	}
+	reply(conn, addr, req, types.Response{Success: true, TxID: req.TxID})
+	netproto.Tracef(req.TraceID, "🧾 Transaction %s finished (commit %t)", req.TxID, req.Commit)
+}
+
+// sweepTxs drops transactions left open and aborts prepared ones whose
+// coordinator went quiet. Must be called with zone_map_Mu held.
+func sweepTxs(now time.Time) {
+	for tx_id, tx := range open_txs {
+		if now.Sub(tx.Opened) > txTimeout {
+			delete(open_txs, tx_id)
+		}
+	}
+	for tx_id, tx := range prepared_txs {
+		if now.Sub(tx.At) > txLockTimeout {
+			log.Printf("⌛ Transaction %s from %s not finished in %s, aborting", tx_id, tx.Coordinator, txLockTimeout)
+			finishLocal(tx_id, false, "")
+		}
+	}
+}
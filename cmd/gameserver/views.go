@@ -0,0 +1,414 @@
+package main
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chunk views =====================
+
+// Everything the handlers touch is guarded by zone_map_Mu, so a player
+// polling GET_UPDATES, or a peer or gateway reading with READ_ONLY, would
+// wait behind every write to every chunk. Instead each owned chunk, and
+// each fresh replica copy, is published as a ChunkView: a copy of the chunk
+// taken under zone_map_Mu, never changed after, in chunk_views. Reads are
+// RCU style: each chunk has a slot holding a pointer to its current view,
+// swapped atomically when a new one is published, and the slots are split
+// into viewShards shards hashed by ChunkID, each an immutable map replaced
+// whole when a chunk gains or loses its slot. A reader only loads pointers,
+// so GET_UPDATES from a held player and READ_ONLY are answered from the
+// views without taking any lock (serveView), however many cube edits are
+// going on; a reader holding an old view just answers from it.
+//
+// A view is published again whenever its chunk may have changed: the
+// chunks markUnsaved flags, and those a request moved its player out of or
+// into, once the request is handled, and the rest after each tick. Every
+// reportEvery ticks all of them are published again, and views of chunks no
+// longer here dropped, in case a change went around those hooks.
+//
+// What else a GET_UPDATES needs is published each tick: which players are
+// held here and not kicked, and their party members. Having no view of the
+// chunk or no fresh publication of the player sends the request the locked
+// way, through the middleware, as does anything but a plain GET_UPDATES
+// from a player or a READ_ONLY. Requests served from a view are rate
+// limited and traced as usual; when the player was seen and the chunk used
+// is folded in at the next tick.
+//
+// Only the readers are taken off zone_map_Mu; writes, publishing views
+// included, stay serialized under it. That is deliberate: few writes touch
+// one chunk alone. A move changes the player lists of the chunks it leaves
+// and enters and the players index, a transaction locks and edits several
+// chunks, and the tick (sim, NPCs, projectiles, replicas) walks them all,
+// so per-chunk write locks would need an order across chunks in every
+// handler and the tick. Readers, the polls that make up most requests, were
+// what waited on it. The shard locks here only serialize changes to a
+// shard's slots, which happen when a chunk gains or loses its view.
+
+const viewShards = 64
+
+// ChunkView is a published copy of a chunk. Its fields are never changed
+// once it is in chunk_views; a newer view replaces it.
+type ChunkView struct {
+	Chunk    types.Chunk
+	Version  types.ChunkVersion
+	Versions *chunkstore.Versions
+	Replica  bool
+	SyncedAt time.Time // of a replica copy
+	UpdateMs int64
+}
+
+// A viewSlot holds a chunk's current view.
+type viewSlot struct {
+	view atomic.Pointer[ChunkView]
+}
+
+// A viewShard's map is never changed once stored; writers, one at a time,
+// store a changed copy.
+type viewShard struct {
+	sync.Mutex // serializes writers
+	slots      atomic.Pointer[map[types.ChunkID]*viewSlot]
+}
+
+// ViewStore is a map of chunk views split into shards by ChunkID, read
+// without locks.
+type ViewStore struct {
+	shards [viewShards]viewShard
+}
+
+func newViewStore() *ViewStore {
+	s := &ViewStore{}
+	for i := range s.shards {
+		slots := make(map[types.ChunkID]*viewSlot)
+		s.shards[i].slots.Store(&slots)
+	}
+	return s
+}
+
+// shard picks chunk_id's shard by an FNV-1a hash of its fields.
+func (s *ViewStore) shard(chunk_id types.ChunkID) *viewShard {
+	h := uint64(14695981039346656037)
+	for i := 0; i < len(chunk_id.World); i++ {
+		h = (h ^ uint64(chunk_id.World[i])) * 1099511628211
+	}
+	for _, n := range []int{chunk_id.IDX, chunk_id.IDY, chunk_id.Level} {
+		h = (h ^ uint64(n)) * 1099511628211
+	}
+	return &s.shards[h%viewShards]
+}
+
+func (s *ViewStore) Get(chunk_id types.ChunkID) (*ChunkView, bool) {
+	slot, ok := (*s.shard(chunk_id).slots.Load())[chunk_id]
+	if !ok {
+		return nil, false
+	}
+	view := slot.view.Load()
+	return view, view != nil
+}
+
+// Put swaps in chunk_id's new view, giving the chunk a slot if it has none.
+func (s *ViewStore) Put(chunk_id types.ChunkID, view *ChunkView) {
+	shard := s.shard(chunk_id)
+	if slot, ok := (*shard.slots.Load())[chunk_id]; ok {
+		slot.view.Store(view)
+		return
+	}
+	shard.Lock()
+	defer shard.Unlock()
+	old := *shard.slots.Load()
+	if slot, ok := old[chunk_id]; ok {
+		slot.view.Store(view)
+		return
+	}
+	slots := make(map[types.ChunkID]*viewSlot, len(old)+1)
+	for id, slot := range old {
+		slots[id] = slot
+	}
+	slot := &viewSlot{}
+	slot.view.Store(view)
+	slots[chunk_id] = slot
+	shard.slots.Store(&slots)
+}
+
+func (s *ViewStore) Delete(chunk_id types.ChunkID) {
+	shard := s.shard(chunk_id)
+	shard.Lock()
+	defer shard.Unlock()
+	old := *shard.slots.Load()
+	slot, ok := old[chunk_id]
+	if !ok {
+		return
+	}
+	// a reader that already has the slot sees no view
+	slot.view.Store(nil)
+	slots := make(map[types.ChunkID]*viewSlot, len(old))
+	for id, slot := range old {
+		if id != chunk_id {
+			slots[id] = slot
+		}
+	}
+	shard.slots.Store(&slots)
+}
+
+// IDs lists the chunks with a view.
+func (s *ViewStore) IDs() []types.ChunkID {
+	var ids []types.ChunkID
+	for i := range s.shards {
+		for chunk_id := range *s.shards[i].slots.Load() {
+			ids = append(ids, chunk_id)
+		}
+	}
+	return ids
+}
+
+var chunk_views = newViewStore()
+
+// guarded by zone_map_Mu; chunks whose view is to be published again
+var view_dirty = make(map[types.ChunkID]bool)
+
+// published each tick, read without zone_map_Mu: the players held here and
+// not kicked, and their party members
+var (
+	fast_players atomic.Pointer[map[string]bool]
+	party_views  atomic.Pointer[map[string][]types.Player]
+)
+
+// guarded by viewSeenMu; what serveView saw since the last tick, folded
+// into player_seen, player_addrs and chunk_used by foldViewSeen
+var (
+	viewSeenMu   sync.Mutex
+	view_seen    = make(map[string]string) // player ID to address
+	view_touched = make(map[types.ChunkID]bool)
+)
+
+var (
+	updatesServedTotal = metrics.NewCounterVec("game_updates_served_total",
+		"GET_UPDATES answered, by path: from a chunk view without the server lock, or locked.", "path")
+	readOnlyServedTotal = metrics.NewCounterVec("game_read_only_served_total",
+		"READ_ONLY answered, by path: from a chunk view without the server lock, or locked.", "path")
+	_ = metrics.NewGaugeFunc("game_chunk_views",
+		"Chunks published for reads without the server lock.", "", func() map[string]float64 {
+			return map[string]float64{"": float64(len(chunk_views.IDs()))}
+		})
+)
+
+// publishView publishes chunk_id's view, or drops it if the chunk is neither
+// owned here nor a fresh replica. Must be called with zone_map_Mu held.
+func publishView(chunk_id types.ChunkID, now time.Time) {
+	delete(view_dirty, chunk_id)
+	view := &ChunkView{}
+	if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+		view.Chunk = chunkstore.Clone(chunk)
+	} else if replica, ok := replicaCopy(chunk_id); ok {
+		view.Chunk, view.Replica, view.SyncedAt = chunkstore.Clone(replica.Chunk), true, replica.SyncedAt
+	} else {
+		chunk_views.Delete(chunk_id)
+		return
+	}
+	view.Version, _ = chunkVersion(chunk_id, view.Chunk, nil)
+	view.Versions = chunk_versions[chunk_id]
+	view.UpdateMs = updateMs(chunk_id, now)
+	chunk_views.Put(chunk_id, view)
+}
+
+// publishDirty publishes the views of the chunks flagged since. Must be
+// called with zone_map_Mu held.
+func publishDirty() {
+	if len(view_dirty) == 0 {
+		return
+	}
+	now := time.Now()
+	for chunk_id := range view_dirty {
+		publishView(chunk_id, now)
+	}
+}
+
+// publishViews publishes what changed over a tick, and with all set every
+// view again. Must be called with zone_map_Mu held.
+func publishViews(now time.Time, all bool) {
+	if all {
+		for _, chunk_id := range chunk_views.IDs() {
+			view_dirty[chunk_id] = true
+		}
+		for chunk_id, chunk := range zone_map {
+			if chunk.ServerIP == serverIP {
+				view_dirty[chunk_id] = true
+			}
+		}
+		for chunk_id := range replica_copies {
+			view_dirty[chunk_id] = true
+		}
+	}
+	publishDirty()
+
+	held := make(map[string]bool, len(players))
+	for player_id := range players {
+		if kick, ok := kicked[player_id]; ok && now.Before(kick.Until) {
+			continue
+		}
+		held[player_id] = true
+	}
+	fast_players.Store(&held)
+	parties_now := make(map[string][]types.Player)
+	for player_id := range player_party {
+		if held[player_id] {
+			parties_now[player_id] = partyView(player_id)
+		}
+	}
+	party_views.Store(&parties_now)
+}
+
+// foldViewSeen records the players and chunks serveView saw since the last
+// tick. Must be called with zone_map_Mu held.
+func foldViewSeen(now time.Time) {
+	viewSeenMu.Lock()
+	seen, touched := view_seen, view_touched
+	view_seen, view_touched = make(map[string]string), make(map[types.ChunkID]bool)
+	viewSeenMu.Unlock()
+	for player_id, addr := range seen {
+		player_seen[player_id] = now
+		player_addrs[player_id] = addr
+	}
+	for chunk_id := range touched {
+		if _, hot := zone_map[chunk_id]; hot {
+			chunk_used[chunk_id] = now
+		}
+	}
+}
+
+// serveView answers req from its chunk's view if it can, without
+// zone_map_Mu, and reports whether it did.
+func serveView(req types.Request, conn netproto.Transport, addr string) bool {
+	switch req.Type {
+	case types.ReqGetUpdates:
+		return serveUpdates(req, conn, addr)
+	case types.ReqReadOnly:
+		return serveReadOnly(req, conn, addr)
+	}
+	return false
+}
+
+// freshView returns chunk_id's view, if it has one worth reading.
+func freshView(chunk_id types.ChunkID, now time.Time) (*ChunkView, bool) {
+	view, ok := chunk_views.Get(chunk_id)
+	if !ok || (view.Replica && now.Sub(view.SyncedAt) > replicaTTL) {
+		return nil, false
+	}
+	return view, true
+}
+
+func serveUpdates(req types.Request, conn netproto.Transport, addr string) bool {
+	if req.IsPeerReq || req.InputSeq > 0 || req.Player.ID == "" || invalidRequest(&req) != "" {
+		return false
+	}
+	start := time.Now()
+	if held := fast_players.Load(); held == nil || !(*held)[req.Player.ID] {
+		return false
+	}
+	chunk_id := req.ChunkID
+	view, ok := freshView(chunk_id, start)
+	if !ok {
+		return false
+	}
+
+	if req.TraceID == "" {
+		req.TraceID = netproto.NewTraceID()
+	}
+	netproto.Tracef(req.TraceID, "📩 Received request from %s of type : %s", req.Player.ID, req.Type)
+	if requestRate > 0 {
+		if wait, ok := takeRequest(req.Player.ID, start); !ok {
+			replyLimited(conn, addr, req, wait)
+			countRequest(req, addr, time.Since(start), nil)
+			return true
+		}
+	}
+	viewSeenMu.Lock()
+	view_seen[req.Player.ID] = addr
+	view_touched[chunk_id] = true
+	viewSeenMu.Unlock()
+
+	key := snapshotKey{Client: req.Player.ID, ChunkID: chunk_id}
+	if req.Since != nil {
+		noteAck(key, *req.Since, start)
+	} else {
+		forgetAcks(key)
+	}
+	version, delta := syncVersion(key, view.Chunk, view.Version, view.Versions, start)
+	res := types.Response{Success: true, Version: &version, UpdateMs: view.UpdateMs}
+	if parties := party_views.Load(); parties != nil {
+		res.PartyMembers = (*parties)[req.Player.ID]
+	}
+	if delta != nil {
+		res.Delta, res.Code = delta, types.CodeDelta
+	} else {
+		res.GameData = types.GameData{Chunk: view.Chunk}
+	}
+	reply(conn, addr, req, res)
+	updatesServedTotal.Inc("view")
+	countRequest(req, addr, time.Since(start), nil)
+
+	netproto.Tracef(req.TraceID, "📊 Sent updates for chunk [%d,%d] from its view with %d players",
+		chunk_id.IDX, chunk_id.IDY, len(view.Chunk.PlayerList))
+	return true
+}
+
+// serveReadOnly is handleReadOnly on a view. A delta runs to the newest
+// version the chunk's tracker has observed, which may be past the view's.
+// A request validateRequests would refuse is left to it.
+func serveReadOnly(req types.Request, conn netproto.Transport, addr string) bool {
+	if invalidRequest(&req) != "" {
+		return false
+	}
+	start := time.Now()
+	chunk_id := req.ChunkID
+	view, ok := freshView(chunk_id, start)
+	if !ok {
+		return false
+	}
+	if req.TraceID == "" {
+		req.TraceID = netproto.NewTraceID()
+	}
+	netproto.Tracef(req.TraceID, "📩 Received request from %s of type : %s", req.Player.ID, req.Type)
+	// players and gateways prefetch with READ_ONLY, servers never send it:
+	// limit it per address, as limitRequests would
+	if requestRate > 0 {
+		if wait, ok := takeRequest(addr, start); !ok {
+			replyLimited(conn, addr, req, wait)
+			countRequest(req, addr, time.Since(start), nil)
+			return true
+		}
+	}
+	viewSeenMu.Lock()
+	view_touched[chunk_id] = true
+	viewSeenMu.Unlock()
+
+	version := view.Version
+	var delta *types.ChunkDelta
+	if req.Since != nil {
+		if d, ok := view.Versions.Delta(*req.Since); ok {
+			delta, version = &d, d.To
+		}
+	}
+	chunk := view.Chunk
+	var res types.Response
+	if delta != nil && delta.From == delta.To {
+		res = types.Response{Success: false, Version: &version, Message: "Use your local copy", Code: types.CodeNotModified}
+	} else if delta != nil && !req.IsChunkNew {
+		res = types.Response{Success: true, Delta: delta, Version: &version, Message: "Sending the changes", Code: types.CodeDelta}
+	} else if req.IsChunkNew || req.Since != nil || chunk.IsDirty || len(chunk.PlayerList) > 0 {
+		res = types.Response{Success: true, Chunk: chunk, Version: &view.Version, Message: "Sending the chunk"}
+	} else {
+		res = types.Response{Success: false, Version: &version, Message: "Use your local copy", Code: types.CodeNotModified}
+	}
+	reply(conn, addr, req, res)
+	readOnlyServedTotal.Inc("view")
+	countRequest(req, addr, time.Since(start), nil)
+
+	netproto.Tracef(req.TraceID, "Handled P2P conn from a view")
+	return true
+}
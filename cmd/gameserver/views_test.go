@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+var viewChunk = types.ChunkID{World: "v", IDX: 5, IDY: -1}
+
+// viewServer makes this process a game server owning viewChunk, with its
+// view published, on an in-memory network.
+func viewServer(t *testing.T) (server, player netproto.Transport) {
+	t.Helper()
+	mem := netproto.NewMemNetwork(1)
+	oldNetwork, oldServerIP := network, serverIP
+	network, serverIP = mem, "mem:owner"
+	zone_map_Mu.Lock()
+	oldZone := zone_map
+	zone_map = map[types.ChunkID]types.Chunk{viewChunk: {World: "v", IDX: 5, IDY: -1, ServerIP: serverIP,
+		Cells: []types.Cube{{ID: "c1"}}, IsDirty: true}}
+	publishView(viewChunk, time.Now())
+	zone_map_Mu.Unlock()
+	t.Cleanup(func() {
+		zone_map_Mu.Lock()
+		zone_map = oldZone
+		chunk_views.Delete(viewChunk)
+		zone_map_Mu.Unlock()
+		network, serverIP = oldNetwork, oldServerIP
+	})
+
+	server, _ = mem.Listen(serverIP)
+	player, _ = mem.Listen("mem:player")
+	t.Cleanup(func() { server.Close(); player.Close() })
+	return server, player
+}
+
+func recvReply(t *testing.T, player netproto.Transport) (types.Response, bool) {
+	t.Helper()
+	player.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	_, data, err := player.Recv()
+	if err != nil {
+		return types.Response{}, false
+	}
+	res, err := netproto.DecodeResponse(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res, true
+}
+
+func TestServeReadOnlyValidates(t *testing.T) {
+	tests := []struct {
+		name   string
+		req    types.Request
+		served bool // answered from the view; otherwise validateRequests refuses it
+	}{
+		{"valid", types.Request{Type: types.ReqReadOnly, ChunkID: viewChunk, PlayerID: "p1", IsChunkNew: true}, true},
+		{"player ID too long", types.Request{Type: types.ReqReadOnly, ChunkID: viewChunk, PlayerID: strings.Repeat("p", maxIDLen+1)}, false},
+		{"player ID too long in Player", types.Request{Type: types.ReqReadOnly, ChunkID: viewChunk,
+			Player: types.Player{ID: strings.Repeat("p", maxIDLen+1)}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server, player := viewServer(t)
+			tt.req.TraceID = "t1"
+			if served := serveView(tt.req, server, "mem:player"); served != tt.served {
+				t.Fatalf("serveView = %v, want %v", served, tt.served)
+			}
+			if !tt.served {
+				if res, ok := recvReply(t, player); ok {
+					t.Fatalf("view answered a malformed request: %s %s", res.Code, res.Message)
+				}
+				validateRequests(func(context.Context, types.Request, netproto.Transport, string) {
+					t.Error("malformed request got past validateRequests")
+				})(context.Background(), tt.req, server, "mem:player")
+			}
+
+			res, ok := recvReply(t, player)
+			if !ok {
+				t.Fatal("no reply")
+			}
+			want := types.CodeOK
+			if !tt.served {
+				want = types.CodeBadRequest
+			}
+			if res.Code != want || res.TraceID != "t1" {
+				t.Errorf("reply %s (trace %q): %s, want %s", res.Code, res.TraceID, res.Message, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/metrics"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Whispers =====================
+
+// WHISPER sends Text from a player to the player PlayerID, wherever they
+// are. If they are held here it is pushed to them (PUSH_WHISPER); otherwise
+// this server finds theirs, asking the live gossip members with
+// LOCATE_PLAYER, or central's /locate when it knows of none, and relays the
+// whisper there as a peer request. Where a player was found is remembered
+// for whisperRouteTTL, and forgotten as soon as a relay misses them.
+// Whispers share the sender's chat rate limit and size cap.
+
+const whisperRouteTTL = time.Minute
+
+type WhisperRoute struct {
+	Server string
+	At     time.Time
+}
+
+// guarded by zone_map_Mu
+var whisper_routes = make(map[string]WhisperRoute)
+
+var whispersTotal = metrics.NewCounterVec("game_whispers_total",
+	"WHISPER messages by outcome: local, relayed, not_found or refused.", "result")
+
+func handleWhisper(ctx context.Context, req types.Request, conn netproto.Transport, addr string) {
+	sender_id, target_id := req.Player.ID, req.PlayerID
+	if target_id == "" || req.Text == "" {
+		reply(conn, addr, req, types.Response{Success: false, Message: "Missing player_id or text", Code: types.CodeBadRequest})
+		return
+	}
+	if _, held := players[sender_id]; req.IsPeerReq && !held {
+		// the serve loop noted the sender as seen; they are not ours
+		delete(player_seen, sender_id)
+	}
+	if !req.IsPeerReq {
+		if _, held := players[sender_id]; !held {
+			reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+			return
+		}
+		if len(req.Text) > chatMaxLen {
+			whispersTotal.Inc("refused")
+			reply(conn, addr, req, types.Response{Success: false, Message: "Message too long", Code: types.CodeBadRequest})
+			return
+		}
+		if !allowChat(sender_id, time.Now()) {
+			whispersTotal.Inc("refused")
+			reply(conn, addr, req, types.Response{Success: false, Message: "Too many messages, slow down", Code: types.CodeRateLimited,
+				RetryAfterMs: int64(1000 / chatRate)})
+			return
+		}
+		req.Text = filterChat(req.Text)
+	}
+
+	_, held := players[target_id]
+	if target_addr, ok := player_addrs[target_id]; held && ok {
+		msg := types.ChatMessage{From: sender_id, To: target_id, Text: req.Text, SentMs: time.Now().UnixMilli()}
+		notice := types.Response{Success: true, Code: types.CodeWhisper, Message: "Whisper from " + sender_id, Chat: []types.ChatMessage{msg}}
+		stampClock(&notice)
+		push(conn, target_addr, notice)
+		whispersTotal.Inc("local")
+		reply(conn, addr, req, types.Response{Success: true, Message: "Whisper delivered"})
+		netproto.Tracef(req.TraceID, "🤫 Whisper %s → %s delivered here", sender_id, target_id)
+		return
+	}
+	if req.IsPeerReq {
+		// the sender's server will look again
+		reply(conn, addr, req, types.Response{Success: false, Message: "Player not on this server", Code: types.CodeNotFound})
+		return
+	}
+
+	if server, ok := relayWhisper(ctx, req); ok {
+		whispersTotal.Inc("relayed")
+		reply(conn, addr, req, types.Response{Success: true, Message: "Whisper relayed to " + server})
+		return
+	}
+	whispersTotal.Inc("not_found")
+	reply(conn, addr, req, types.Response{Success: false, Message: "Player " + target_id + " is not online", Code: types.CodeNotFound})
+}
+
+// relayWhisper hands req to the server holding its target, trying the
+// remembered one first. Must be called with zone_map_Mu held.
+func relayWhisper(ctx context.Context, req types.Request) (string, bool) {
+	fwd := req
+	fwd.IsPeerReq = true
+	target_id := req.PlayerID
+
+	if route, ok := whisper_routes[target_id]; ok && time.Since(route.At) < whisperRouteTTL {
+		if res, err := p2p(ctx, fwd, route.Server); err == nil && res.Success {
+			return route.Server, true
+		}
+		delete(whisper_routes, target_id)
+	}
+
+	server, ok := locateRemote(ctx, target_id, req.TraceID)
+	if !ok {
+		return "", false
+	}
+	whisper_routes[target_id] = WhisperRoute{Server: server, At: time.Now()}
+	if res, err := p2p(ctx, fwd, server); err != nil || !res.Success {
+		delete(whisper_routes, target_id)
+		return "", false
+	}
+	return server, true
+}
+
+// locateRemote finds the server holding player_id: one of the live gossip
+// members, asked at once, or central's answer when no member is known. Must
+// be called with zone_map_Mu held.
+func locateRemote(ctx context.Context, player_id, trace string) (string, bool) {
+	now := time.Now()
+	var peers []string
+	for addr, member := range members {
+		if addr != serverIP && member.alive(now) {
+			peers = append(peers, addr)
+		}
+	}
+	if len(peers) == 0 {
+		res, err := callCentral(ctx, "/locate", types.Request{Type: types.ReqLocatePlayer, PlayerID: player_id, TraceID: trace})
+		if err != nil || !res.Success || res.RedirectIP == "" {
+			return "", false
+		}
+		return res.RedirectIP, true
+	}
+
+	req := types.Request{Type: types.ReqLocatePlayer, PlayerID: player_id, TraceID: trace}
+	found := make(chan string, len(peers))
+	ctx, cancel := context.WithTimeout(ctx, peerTimeout)
+	defer cancel()
+	for _, peer := range peers {
+		go func(peer string) {
+			res, err := peerRoundTrip(ctx, peer, req)
+			if err == nil && res.Success {
+				found <- peer
+				return
+			}
+			found <- ""
+		}(peer)
+	}
+	for range peers {
+		if server := <-found; server != "" {
+			return server, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== World export / import =====================
+
+// maxImportBytes caps an uploaded world file.
+const maxImportBytes = 256 << 20
+
+// WorldImportResult is the reply to POST /admin/world/import.
+type WorldImportResult struct {
+	Imported int             `json:"imported"`
+	Skipped  []types.ChunkID `json:"skipped,omitempty"` // already held; pass overwrite=1
+	// Conflicts are chunks central would not let this server claim, as
+	// another live server owns them; their imported copies are dropped.
+	Conflicts []types.ChunkID `json:"conflicts,omitempty"`
+	TraceID   string          `json:"trace_id"`
+}
+
+// parseChunkRange reads ?range=minX,minY,maxX,maxY; no parameter means every
+// chunk.
+func parseChunkRange(r *http.Request) (*chunkstore.ChunkRange, error) {
+	raw := r.URL.Query().Get("range")
+	if raw == "" {
+		return nil, nil
+	}
+	parts := strings.Split(raw, ",")
+	if len(parts) != 4 {
+		return nil, fmt.Errorf("range must be minX,minY,maxX,maxY")
+	}
+	var v [4]int
+	for i, p := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(p))
+		if err != nil {
+			return nil, fmt.Errorf("range must be minX,minY,maxX,maxY")
+		}
+		v[i] = n
+	}
+	return &chunkstore.ChunkRange{MinX: v[0], MinY: v[1], MaxX: v[2], MaxY: v[3]}, nil
+}
+
+// handleAdminWorldExport writes the chunks this server owns, hot or on disk,
+// as a world file.
+func handleAdminWorldExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chunk_range, err := parseChunkRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	file, err := exportWorld(chunk_range)
+	if err != nil {
+		log.Printf("❌ Export: %v", err)
+		http.Error(w, "Failed to read persisted chunk", http.StatusInternalServerError)
+		return
+	}
+	log.Printf("📦 Exported %d chunks to %s", len(file.Chunks), r.RemoteAddr)
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", world.Name+".world.json"))
+	file.Write(w)
+}
+
+// exportWorld builds a world file of the chunks in chunk_range this server
+// owns, hot, persisted or evicted fresh.
+func exportWorld(chunk_range *chunkstore.ChunkRange) (chunkstore.WorldFile, error) {
+	zone_map_Mu.Lock()
+	defer zone_map_Mu.Unlock()
+	var chunks []types.Chunk
+	for chunk_id, chunk := range zone_map {
+		if chunk.ServerIP == serverIP && chunk_range.Contains(chunk_id) {
+			chunks = append(chunks, chunk)
+		}
+	}
+	// cold chunks are read straight from the store so an export doesn't
+	// evict the hot set
+	for chunk_id, path := range cold_chunks {
+		if !chunk_range.Contains(chunk_id) {
+			continue
+		}
+		chunk, err := store.Load(path)
+		if err != nil {
+			return chunkstore.WorldFile{}, fmt.Errorf("loading chunk [%d,%d]: %w", chunk_id.IDX, chunk_id.IDY, err)
+		}
+		chunks = append(chunks, chunk)
+	}
+	for chunk_id := range fresh_chunks {
+		if chunk_range.Contains(chunk_id) {
+			chunks = append(chunks, generatedChunk(chunk_id))
+		}
+	}
+	return chunkstore.NewWorldFile(world, serverIP, chunks), nil
+}
+
+// handleAdminWorldImport takes ownership of the chunks in an uploaded world
+// file and registers them with the central server. Chunks this server
+// already holds are skipped unless ?overwrite=1; players in an overwritten
+// chunk stay in it.
+func handleAdminWorldImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	overwrite := r.URL.Query().Get("overwrite") == "1"
+	chunk_range, err := parseChunkRange(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file, err := chunkstore.ReadWorldFile(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		http.Error(w, "Invalid world file: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	result, err := importWorld(r.Context(), file, chunk_range, overwrite)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	writeAdminJSON(w, result)
+}
+
+// importWorld takes ownership of the chunks of file in chunk_range, as
+// POST /admin/world/import does. It fails only on a chunk size other than
+// this server's.
+func importWorld(ctx context.Context, file chunkstore.WorldFile, chunk_range *chunkstore.ChunkRange, overwrite bool) (WorldImportResult, error) {
+	if file.World.ChunkSize != 0 && file.World.ChunkSize != world.ChunkSize {
+		return WorldImportResult{}, fmt.Errorf("World file uses chunk size %d, this server %d", file.World.ChunkSize, world.ChunkSize)
+	}
+
+	trace := netproto.NewTraceID()
+	result := WorldImportResult{TraceID: trace}
+	var claimed []types.ChunkID
+
+	zone_map_Mu.Lock()
+	for _, chunk := range file.Chunks {
+		chunk_id := chunk.ID()
+		if !chunk_range.Contains(chunk_id) {
+			continue
+		}
+		touchChunk(chunk_id)
+		existing, held := zone_map[chunk_id]
+		if held && !overwrite {
+			result.Skipped = append(result.Skipped, chunk_id)
+			continue
+		}
+		chunk.ServerIP = serverIP
+		chunk.PlayerList = existing.PlayerList
+		chunk.IsDirty = false
+		// the import is a new write of every cube, and replaces whatever
+		// was removed before it
+		at := clock.Now()
+		chunk.Cells = append([]types.Cube(nil), chunk.Cells...)
+		for i := range chunk.Cells {
+			chunk.Cells[i].HLC = at
+		}
+		chunk.Tombstones, chunk.TombstoneFloor = nil, at
+		zone_map[chunk_id] = chunk
+		delete(chunk_history, chunk_id)
+		markUnsaved(chunk_id)
+		claimed = append(claimed, chunk_id)
+	}
+	zone_map_Mu.Unlock()
+	result.Imported = len(claimed)
+
+	for _, chunk_id := range claimed {
+		res, err := callCentral(ctx, "/sentchunk", types.Request{ChunkID: chunk_id, CallerIP: serverIP, TraceID: trace})
+		if err == nil {
+			continue
+		}
+		netproto.Tracef(trace, "⚠️  Central not updated for imported chunk [%d,%d]: %v", chunk_id.IDX, chunk_id.IDY, err)
+		if res.Code == types.CodeNotOwner && res.RedirectIP != "" {
+			// the owner keeps it; the save pass drops the copy saved here
+			zone_map_Mu.Lock()
+			if chunk, ok := zone_map[chunk_id]; ok && chunk.ServerIP == serverIP {
+				chunk.ServerIP = res.RedirectIP
+				zone_map[chunk_id] = chunk
+				markUnsaved(chunk_id)
+			}
+			zone_map_Mu.Unlock()
+			result.Conflicts = append(result.Conflicts, chunk_id)
+			result.Imported--
+		}
+	}
+
+	journal.Record(WorldEvent{Type: "IMPORT", Detail: fmt.Sprintf("%d chunks from %s (%d skipped)", result.Imported, file.Source, len(result.Skipped)), TraceID: trace})
+	netproto.Tracef(trace, "📦 Imported %d chunks from %s, skipped %d, %d owned elsewhere", result.Imported, file.Source, len(result.Skipped), len(result.Conflicts))
+	return result, nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+)
+
+// ===================== Dashboard =====================
+
+// /dashboard is a live map of the cluster for demos and for debugging the
+// ownership protocol: the chunk grid of a world coloured by owner, the
+// players in each chunk, and the latest migrations. The page follows
+// /dashboard/events, a stream of Server-Sent Events carrying central's /map
+// (types.WorldMap) each time it changes, checked every dashboardEvery.
+
+const dashboardEvery = time.Second
+
+// central server the dashboard reads /map from; dashboard disabled if empty
+var centralURL string
+
+//go:embed dashboard.html
+var dashboardPage []byte
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	if centralURL == "" {
+		http.Error(w, "Dashboard not configured on this gateway", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardPage)
+}
+
+func handleDashboardEvents(w http.ResponseWriter, r *http.Request) {
+	if centralURL == "" {
+		http.Error(w, "Dashboard not configured on this gateway", http.StatusServiceUnavailable)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+	trace := requestTrace(w, r)
+	target := centralURL + "/map?world=" + url.QueryEscape(r.URL.Query().Get("world"))
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	netproto.Tracef(trace, "🗺️  Dashboard following %s", target)
+	ticker := time.NewTicker(dashboardEvery)
+	defer ticker.Stop()
+	var last []byte
+	for {
+		data, err := fetchMap(r, target)
+		switch {
+		case err != nil:
+			fmt.Fprintf(w, "event: failure\ndata: %q\n\n", err.Error())
+			flusher.Flush()
+		case !bytes.Equal(data, last):
+			fmt.Fprintf(w, "data: %s\n\n", data)
+			flusher.Flush()
+			last = data
+		}
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// fetchMap returns central's /map at target, as one line of JSON.
+func fetchMap(r *http.Request, target string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("central /map: %s", resp.Status)
+	}
+	return bytes.TrimSpace(data), nil
+}
@@ -0,0 +1,798 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Config =====================
+
+const udpTimeout = 5 * time.Second // per request timeout
+
+var (
+	gameServerUDP = "172.16.118.72:9000" // default game server for players without a session
+	network       = netproto.UDP
+	// requests to game servers share one socket, or gRPC stream, per server
+	calls     *netproto.Mux
+	gatewayID string
+	// HTTP admin API of the game server, for the world export/import proxy
+	serverAdminURL string
+	sessions       SessionStore
+	sessionTTL     = 10 * time.Minute
+)
+
+// ===================== HTTP request structures =====================
+
+type HTTPAddCubeRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	Cube     types.Cube    `json:"cube" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+type HTTPDltCubeRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	CubeID   string        `json:"cube_id" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+type HTTPAddCubesRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	Cubes    []types.Cube  `json:"cubes" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+// HTTPTxRequest edits several chunks as one transaction.
+type HTTPTxRequest struct {
+	PlayerID string            `json:"player_id" validate:"required"`
+	Edits    []types.ChunkEdit `json:"edits" validate:"required"`
+}
+
+type HTTPDltCubesRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	CubeIDs  []string      `json:"cube_ids" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+type HTTPUndoRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+type HTTPMoveRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	X        int           `json:"x" validate:"required"`
+	Y        int           `json:"y" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+}
+
+type HTTPGetDataRequest struct {
+	PlayerID string        `json:"player_id"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+	Player   types.Player  `json:"player" validate:"required"`
+	// version of the chunk already held; an unchanged chunk is not resent
+	Since *types.ChunkVersion `json:"since,omitempty"`
+}
+
+type HTTPGetUpdatesRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+	// version from the last reply; only the changes since are sent back
+	Since *types.ChunkVersion `json:"since,omitempty"`
+}
+
+type HTTPChatRequest struct {
+	PlayerID string        `json:"player_id" validate:"required"`
+	ChunkID  types.ChunkID `json:"chunk_id" validate:"required"`
+	// empty text only fetches the messages after since
+	Text  string `json:"text,omitempty"`
+	Since uint64 `json:"since,omitempty"`
+}
+
+type HTTPDeletePlayerRequest struct {
+	PlayerID string `json:"player_id" validate:"required"`
+}
+
+type HTTPResponse struct {
+	Success    bool                `json:"success"`
+	Code       string              `json:"code,omitempty"`
+	Message    string              `json:"message"`
+	RedirectIP string              `json:"redirect_ip,omitempty"`
+	Data       interface{}         `json:"data,omitempty"`
+	Queue      *QueueStatus        `json:"queue,omitempty"`
+	Version    *types.ChunkVersion `json:"version,omitempty"`
+	UpdateMs   int64               `json:"update_ms,omitempty"` // how often the chunk is worth polling
+	TraceID    string              `json:"trace_id,omitempty"`
+}
+
+// QueueStatus tells a player refused with ERR_CHUNK_FULL where they stand.
+type QueueStatus struct {
+	Position     int            `json:"position"`
+	RetryAfterMs int64          `json:"retry_after_ms"`
+	Alternative  *types.ChunkID `json:"alternative,omitempty"`
+}
+
+// ===================== Session store =====================
+
+// PlayerSession is the routing state a gateway keeps per player. It lives in
+// a SessionStore so any gateway instance behind the load balancer (or one
+// that just restarted) routes the player to the same game server.
+type PlayerSession struct {
+	PlayerID  string    `json:"player_id"`
+	ServerUDP string    `json:"server_udp"`
+	Gateway   string    `json:"gateway"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type SessionStore interface {
+	Get(playerID string) (PlayerSession, bool, error)
+	Put(session PlayerSession) error
+	Delete(playerID string) error
+}
+
+// memorySessionStore is used when no shared store is configured; sessions
+// are lost on restart and not visible to other gateways.
+type memorySessionStore struct {
+	sync.Mutex
+	sessions map[string]PlayerSession
+}
+
+func newMemorySessionStore() *memorySessionStore {
+	return &memorySessionStore{sessions: make(map[string]PlayerSession)}
+}
+
+func (m *memorySessionStore) Get(playerID string) (PlayerSession, bool, error) {
+	m.Lock()
+	defer m.Unlock()
+	session, ok := m.sessions[playerID]
+	if ok && time.Since(session.UpdatedAt) > sessionTTL {
+		delete(m.sessions, playerID)
+		return PlayerSession{}, false, nil
+	}
+	return session, ok, nil
+}
+
+func (m *memorySessionStore) Put(session PlayerSession) error {
+	m.Lock()
+	defer m.Unlock()
+	m.sessions[session.PlayerID] = session
+	return nil
+}
+
+func (m *memorySessionStore) Delete(playerID string) error {
+	m.Lock()
+	defer m.Unlock()
+	delete(m.sessions, playerID)
+	return nil
+}
+
+// redisSessionStore keeps sessions in Redis under gateway:session:<id>
+// with a TTL.
+type redisSessionStore struct {
+	client *redis.Client
+}
+
+// redisTimeout bounds each Redis command.
+const redisTimeout = 2 * time.Second
+
+func newRedisSessionStore(addr string) *redisSessionStore {
+	return &redisSessionStore{client: redis.NewClient(&redis.Options{
+		Addr:         addr,
+		DialTimeout:  redisTimeout,
+		ReadTimeout:  redisTimeout,
+		WriteTimeout: redisTimeout,
+	})}
+}
+
+func sessionKey(playerID string) string {
+	return "gateway:session:" + playerID
+}
+
+func (r *redisSessionStore) Get(playerID string) (PlayerSession, bool, error) {
+	data, err := r.client.Get(context.Background(), sessionKey(playerID)).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return PlayerSession{}, false, nil
+	}
+	if err != nil {
+		return PlayerSession{}, false, err
+	}
+	var session PlayerSession
+	if err := json.Unmarshal(data, &session); err != nil {
+		return PlayerSession{}, false, err
+	}
+	return session, true, nil
+}
+
+func (r *redisSessionStore) Put(session PlayerSession) error {
+	data, err := json.Marshal(session)
+	if err != nil {
+		return err
+	}
+	return r.client.Set(context.Background(), sessionKey(session.PlayerID), data, sessionTTL).Err()
+}
+
+func (r *redisSessionStore) Delete(playerID string) error {
+	return r.client.Del(context.Background(), sessionKey(playerID)).Err()
+}
+
+// routeFor returns the game server a player's requests should go to.
+func routeFor(playerID string) string {
+	if playerID == "" {
+		return gameServerUDP
+	}
+	session, ok, err := sessions.Get(playerID)
+	if err != nil {
+		log.Printf("⚠️  Session lookup for %s failed: %v", playerID, err)
+		return gameServerUDP
+	}
+	if !ok {
+		return gameServerUDP
+	}
+	return session.ServerUDP
+}
+
+// forward sends req to the player's game server and keeps the shared session
+// in step with what the server tells us (new owner on GET_DATA, gone on
+// DLT_PLAYER). Each attempt gets udpTimeout, and all end if ctx does.
+func forward(ctx context.Context, req types.Request) (types.Response, error) {
+	playerID := req.Player.ID
+	if playerID == "" {
+		playerID = req.PlayerID
+	}
+	server := routeFor(playerID)
+
+	resp, err := call(ctx, server, req)
+	if err == nil && resp.Code == types.CodeNotOwner && resp.RedirectIP != "" {
+		// our route is stale; retry once against the owner
+		server = resp.RedirectIP
+		resp, err = call(ctx, server, req)
+	}
+	if err != nil || playerID == "" {
+		return resp, err
+	}
+
+	switch {
+	case resp.Code == types.CodeRedirect && resp.RedirectIP != "":
+		saveSession(req, playerID, resp.RedirectIP)
+	case req.Type == types.ReqGetData && (resp.Code == types.CodeOK || resp.Code == types.CodeNotModified):
+		saveSession(req, playerID, server)
+	case req.Type == types.ReqDltPlayer:
+		if err := sessions.Delete(playerID); err != nil {
+			netproto.Tracef(req.TraceID, "⚠️  Session delete for %s failed: %v", playerID, err)
+		}
+	}
+	return resp, nil
+}
+
+// call makes one attempt at req on server over the shared mux.
+func call(ctx context.Context, server string, req types.Request) (types.Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, udpTimeout)
+	defer cancel()
+	return calls.RoundTrip(ctx, server, req)
+}
+
+func saveSession(req types.Request, playerID, server string) {
+	session := PlayerSession{PlayerID: playerID, ServerUDP: server, Gateway: gatewayID, UpdatedAt: time.Now()}
+	if err := sessions.Put(session); err != nil {
+		netproto.Tracef(req.TraceID, "⚠️  Session save for %s failed: %v", playerID, err)
+	}
+}
+
+// ===================== HTTP handlers =====================
+
+// requestTrace returns the caller's X-Request-ID, or a fresh trace ID, and
+// echoes it back so the client can quote it when reporting a problem.
+func requestTrace(w http.ResponseWriter, r *http.Request) string {
+	trace := r.Header.Get("X-Request-ID")
+	if trace == "" {
+		trace = netproto.NewTraceID()
+	}
+	w.Header().Set("X-Request-ID", trace)
+	return trace
+}
+
+func handleMovePlayerHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var moveReq HTTPMoveRequest
+	if !decodeBody(w, r, &moveReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:    types.ReqMovePlayer,
+		TraceID: trace,
+		Player:  types.Player{ID: moveReq.PlayerID, PosX: moveReq.X, PosY: moveReq.Y, World: moveReq.ChunkID.World},
+		ChunkID: moveReq.ChunkID,
+	}
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP MOVE_PLAYER error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, resp.GameData, trace))
+}
+
+func handleAddCubeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPAddCubeRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:     types.ReqAddCube,
+		TraceID:  trace,
+		ChunkID:  dataReq.ChunkID,
+		Cube:     dataReq.Cube,
+		PlayerID: dataReq.PlayerID,
+	}
+
+	netproto.Tracef(trace, "ADD_CUBE req: %+v", dataReq)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP ADD_CUBE error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+func handleDltCubeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPDltCubeRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:     types.ReqDltCube,
+		TraceID:  trace,
+		ChunkID:  dataReq.ChunkID,
+		CubeID:   dataReq.CubeID,
+		PlayerID: dataReq.PlayerID,
+	}
+
+	netproto.Tracef(trace, "DLT_CUBE req: %+v", dataReq)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP DLT_CUBE error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+func handleAddCubesHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPAddCubesRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:     types.ReqAddCubes,
+		TraceID:  trace,
+		ChunkID:  dataReq.ChunkID,
+		Cubes:    dataReq.Cubes,
+		PlayerID: dataReq.PlayerID,
+	}
+
+	netproto.Tracef(trace, "ADD_CUBES req: %d cubes for player %s", len(dataReq.Cubes), dataReq.PlayerID)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP ADD_CUBES error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+func handleDltCubesHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPDltCubesRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:     types.ReqDltCubes,
+		TraceID:  trace,
+		ChunkID:  dataReq.ChunkID,
+		CubeIDs:  dataReq.CubeIDs,
+		PlayerID: dataReq.PlayerID,
+	}
+
+	netproto.Tracef(trace, "DLT_CUBES req: %d cubes for player %s", len(dataReq.CubeIDs), dataReq.PlayerID)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP DLT_CUBES error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+// handleTxHTTP runs TX_BEGIN, a TX_APPLY per chunk and TX_COMMIT, so the
+// edits land on every chunk or on none.
+func handleTxHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPTxRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	netproto.Tracef(trace, "TX req: %d chunks for player %s", len(dataReq.Edits), dataReq.PlayerID)
+
+	resp, err := forward(r.Context(), types.Request{Type: types.ReqTxBegin, TraceID: trace, PlayerID: dataReq.PlayerID})
+	tx_id := resp.TxID
+	for _, edit := range dataReq.Edits {
+		if err != nil || !resp.Success {
+			break
+		}
+		resp, err = forward(r.Context(), types.Request{Type: types.ReqTxApply, TraceID: trace, PlayerID: dataReq.PlayerID, TxID: tx_id,
+			ChunkID: edit.ChunkID, Cubes: edit.Cubes, CubeIDs: edit.CubeIDs})
+	}
+	if err == nil && resp.Success {
+		resp, err = forward(r.Context(), types.Request{Type: types.ReqTxCommit, TraceID: trace, PlayerID: dataReq.PlayerID, TxID: tx_id})
+	} else if tx_id != "" {
+		forward(context.WithoutCancel(r.Context()), types.Request{Type: types.ReqTxAbort, TraceID: trace, PlayerID: dataReq.PlayerID, TxID: tx_id})
+	}
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP TX error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+func handleUndoHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPUndoRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:     types.ReqUndo,
+		TraceID:  trace,
+		ChunkID:  dataReq.ChunkID,
+		PlayerID: dataReq.PlayerID,
+	}
+
+	netproto.Tracef(trace, "UNDO req: %+v", dataReq)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP UNDO error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+func handleGetDataHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPGetDataRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:    types.ReqGetData,
+		TraceID: trace,
+		Player:  dataReq.Player,
+		ChunkID: dataReq.ChunkID,
+		Since:   dataReq.Since,
+	}
+
+	netproto.Tracef(trace, "GET_DATA req: %+v", dataReq)
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP GET_DATA error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	if resp.Code == types.CodeNotModified {
+		writeJSON(w, toHTTPResponse(resp, nil, trace))
+		return
+	}
+	writeJSON(w, toHTTPResponse(resp, resp.Chunk, trace))
+}
+
+// handleGetUpdatesHTTP polls a chunk for changes. Clients that can keep a
+// request open get them pushed by /api/chunk/{x}/{y}/events instead.
+func handleGetUpdatesHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPGetUpdatesRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:    types.ReqGetUpdates,
+		TraceID: trace,
+		Player:  types.Player{ID: dataReq.PlayerID},
+		ChunkID: dataReq.ChunkID,
+		Since:   dataReq.Since,
+	}
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP GET_UPDATES error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	if resp.Code == types.CodeDelta {
+		writeJSON(w, toHTTPResponse(resp, resp.Delta, trace))
+		return
+	}
+	writeJSON(w, toHTTPResponse(resp, resp.GameData, trace))
+}
+
+// handleChatHTTP sends a chat message and returns the chunk's messages
+// after since. Game servers push chat over UDP to the address a player last
+// sent from, which gateway players do not keep, so they poll this instead.
+func handleChatHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var chatReq HTTPChatRequest
+	if !decodeBody(w, r, &chatReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:      types.ReqChat,
+		TraceID:   trace,
+		Player:    types.Player{ID: chatReq.PlayerID},
+		ChunkID:   chatReq.ChunkID,
+		Text:      chatReq.Text,
+		ChatSince: chatReq.Since,
+	}
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP CHAT error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, resp.Chat, trace))
+}
+
+func handleDeletePlayerHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var dataReq HTTPDeletePlayerRequest
+	if !decodeBody(w, r, &dataReq) {
+		return
+	}
+
+	trace := requestTrace(w, r)
+	udpReq := types.Request{
+		Type:    types.ReqDltPlayer,
+		TraceID: trace,
+		Player:  types.Player{ID: dataReq.PlayerID},
+	}
+
+	resp, err := forward(r.Context(), udpReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP DLT_PLAYER error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+
+	writeJSON(w, toHTTPResponse(resp, nil, trace))
+}
+
+// handleWorldHTTP proxies /api/world/export and /api/world/import to the
+// game server's admin API. The caller's X-Admin-Token is passed through, so
+// the game server still decides who may use them.
+func handleWorldHTTP(w http.ResponseWriter, r *http.Request) {
+	if serverAdminURL == "" {
+		http.Error(w, "World export/import not configured on this gateway", http.StatusServiceUnavailable)
+		return
+	}
+	target := serverAdminURL + "/admin/world/" + strings.TrimPrefix(r.URL.Path, "/api/world/")
+	if r.URL.RawQuery != "" {
+		target += "?" + r.URL.RawQuery
+	}
+
+	trace := requestTrace(w, r)
+	proxyReq, err := http.NewRequestWithContext(r.Context(), r.Method, target, r.Body)
+	if err != nil {
+		http.Error(w, "Invalid request", http.StatusBadRequest)
+		return
+	}
+	proxyReq.Header.Set("Content-Type", r.Header.Get("Content-Type"))
+	proxyReq.Header.Set("X-Admin-Token", r.Header.Get("X-Admin-Token"))
+
+	netproto.Tracef(trace, "🌍 %s %s → %s", r.Method, r.URL.Path, target)
+	resp, err := http.DefaultClient.Do(proxyReq)
+	if err != nil {
+		netproto.Tracef(trace, "❌ World proxy error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusBadGateway)
+		return
+	}
+	defer resp.Body.Close()
+	for _, h := range []string{"Content-Type", "Content-Disposition"} {
+		if v := resp.Header.Get(h); v != "" {
+			w.Header().Set(h, v)
+		}
+	}
+	w.WriteHeader(resp.StatusCode)
+	io.Copy(w, resp.Body)
+}
+
+func handleHealthCheck(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, HTTPResponse{Success: true, Message: "HTTP Gateway is running", Data: map[string]string{"gateway_id": gatewayID}})
+}
+
+// ===================== HTTP bootstrap =====================
+
+func startHTTPServer(listenAddr string, certs netproto.TLSFiles) {
+	registerRoutes()
+
+	scheme := "HTTP"
+	if certs.Enabled() {
+		scheme = "HTTPS"
+	}
+	log.Printf("🌐 %s API Gateway %s starting on %s", scheme, gatewayID, listenAddr)
+	if err := netproto.ListenAndServe(listenAddr, nil, certs); err != nil {
+		log.Fatal("HTTP server failed:", err)
+	}
+}
+
+func main() {
+	hostname, _ := os.Hostname()
+	listenAddr := flag.String("listen", ":8081", "HTTP listen address")
+	redisAddr := flag.String("redis", os.Getenv("REDIS_ADDR"), "Redis address for shared sessions (in-memory if empty)")
+	flag.StringVar(&gameServerUDP, "server", gameServerUDP, "game server for players without a session")
+	flag.StringVar(&serverAdminURL, "server-admin", "", "game server admin API base URL, e.g. http://10.0.0.5:9100 (world export/import disabled if empty)")
+	flag.StringVar(&centralURL, "central", "", "central server URL for /dashboard, /api/players and /api/servers (disabled if empty)")
+	flag.StringVar(&gatewayID, "gateway-id", hostname, "name of this gateway instance")
+	flag.DurationVar(&sessionTTL, "session-ttl", sessionTTL, "how long an idle player session is kept")
+	flag.Float64Var(&gatewayLimits.ipRate, "ip-rate", gatewayLimits.ipRate, "requests per second a client IP may make to each route (0 = unlimited)")
+	flag.Float64Var(&gatewayLimits.ipBurst, "ip-burst", gatewayLimits.ipBurst, "requests a client IP may make to a route in a burst")
+	flag.IntVar(&gatewayLimits.ipInflight, "ip-inflight", gatewayLimits.ipInflight, "requests and streams a client IP may have open at once (0 = unlimited)")
+	flag.IntVar(&gatewayLimits.maxInflight, "max-inflight", gatewayLimits.maxInflight, "requests and streams open at once across clients (0 = unlimited)")
+	flag.Int64Var(&gatewayLimits.maxBody, "max-body", gatewayLimits.maxBody, "largest request body in bytes, unless the route sets its own")
+	flag.BoolVar(&gatewayLimits.trustProxy, "trust-proxy", false, "take client IPs from the last X-Forwarded-For address, as appended by the proxy in front")
+	transport := flag.String("transport", "udp", "how requests reach game servers: udp, or grpc for a stream to each one on the TCP port of its address (the servers need -grpc)")
+	sealSecret := flag.String("seal-secret", os.Getenv("SEAL_SECRET"), "the cluster's seal secret, to seal requests to game servers with (unsealed if empty)")
+	printAPI := flag.Bool("openapi", false, "print the OpenAPI document of the gateway and exit")
+	netproto.CORS.RegisterFlags(flag.CommandLine)
+	var certs netproto.TLSFiles
+	certs.RegisterFlags(flag.CommandLine)
+	flag.Parse()
+	if err := netproto.CORS.Validate(); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	if *printAPI {
+		out, _ := json.MarshalIndent(openAPI(), "", "  ")
+		fmt.Println(string(out))
+		return
+	}
+	serverAdminURL = strings.TrimRight(serverAdminURL, "/")
+	centralURL = strings.TrimRight(centralURL, "/")
+
+	if *redisAddr != "" {
+		sessions = newRedisSessionStore(*redisAddr)
+		log.Printf("🔗 Player sessions shared via Redis at %s", *redisAddr)
+	} else {
+		sessions = newMemorySessionStore()
+		log.Println("⚠️  Player sessions kept in memory; run with -redis to share them between gateways")
+	}
+
+	switch *transport {
+	case "udp":
+	case "grpc":
+		network = netproto.GRPC
+		log.Println("🔌 Requests to game servers go over gRPC streams")
+	default:
+		log.Fatalf("❌ Unknown -transport %q (udp or grpc)", *transport)
+	}
+	if *sealSecret != "" {
+		network = netproto.SealClient(network, netproto.NewSealKeys(*sealSecret))
+		log.Println("🔒 Requests to game servers are sealed")
+	}
+	calls = netproto.NewMux(network)
+	setupLimits()
+	startHTTPServer(*listenAddr, certs)
+}
+
+// ===================== Helpers =====================
+
+func toHTTPResponse(resp types.Response, data interface{}, trace string) HTTPResponse {
+	res := HTTPResponse{Success: resp.Success, Code: resp.Code, Message: resp.Message, RedirectIP: resp.RedirectIP, Data: data, Version: resp.Version, UpdateMs: resp.UpdateMs, TraceID: trace}
+	if resp.Code == types.CodeChunkFull {
+		res.Queue = &QueueStatus{Position: resp.QueuePosition, RetryAfterMs: resp.RetryAfterMs, Alternative: resp.Alternative}
+	}
+	return res
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
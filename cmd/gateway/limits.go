@@ -0,0 +1,189 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Limits =====================
+
+// Every route goes through limit, which keeps a misbehaving web client from
+// flooding the game servers behind the gateway:
+//
+//   - each client IP has a token bucket per route (-ip-rate, -ip-burst)
+//   - a client IP may have -ip-inflight requests open at once, streams
+//     included, and the gateway -max-inflight in all
+//   - bodies are cut off at the route's MaxBody, or -max-body
+//
+// Going over a rate or a cap gets 429 with Retry-After; too big a body gets
+// 413. The client IP is the connection's, or behind a proxy trusted with
+// -trust-proxy the last X-Forwarded-For address: the one the proxy itself
+// appended, where those before it are whatever the client sent.
+//
+// There is no per-player limit: no route authenticates the player a body
+// names, so a client could spread its requests over made-up player ids.
+
+type limits struct {
+	ipRate, ipBurst         float64
+	ipInflight, maxInflight int
+	maxBody                 int64
+	trustProxy              bool
+}
+
+var gatewayLimits = limits{
+	ipRate: 50, ipBurst: 100,
+	ipInflight: 32, maxInflight: 1024,
+	maxBody: 1 << 20,
+}
+
+// bucketIdle is how long an untouched bucket is kept; it is full by then.
+const bucketIdle = time.Minute
+
+type tokenBucket struct {
+	Tokens float64
+	At     time.Time
+}
+
+// rateLimiter is a set of token buckets filling at rate up to burst.
+type rateLimiter struct {
+	rate, burst float64
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+	swept   time.Time
+}
+
+func newRateLimiter(rate, burst float64) *rateLimiter {
+	return &rateLimiter{rate: rate, burst: math.Max(burst, 1), buckets: make(map[string]*tokenBucket)}
+}
+
+// take takes a token from key's bucket, or says how long until there is one.
+// A rate of 0 or less never limits.
+func (l *rateLimiter) take(key string, now time.Time) (bool, time.Duration) {
+	if l.rate <= 0 {
+		return true, 0
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if now.Sub(l.swept) > bucketIdle {
+		for k, b := range l.buckets {
+			if now.Sub(b.At) > bucketIdle {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &tokenBucket{Tokens: l.burst, At: now}
+		l.buckets[key] = b
+	}
+	b.Tokens = math.Min(l.burst, b.Tokens+now.Sub(b.At).Seconds()*l.rate)
+	b.At = now
+	if b.Tokens >= 1 {
+		b.Tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.Tokens) / l.rate * float64(time.Second))
+}
+
+var (
+	ipLimiter *rateLimiter
+
+	inflightMu sync.Mutex
+	inflight   = make(map[string]int) // by client IP
+	inflightN  int
+)
+
+func setupLimits() {
+	ipLimiter = newRateLimiter(gatewayLimits.ipRate, gatewayLimits.ipBurst)
+}
+
+// limit applies the limits to route's handler.
+func limit(route apiRoute, next http.HandlerFunc) http.HandlerFunc {
+	maxBody := route.MaxBody
+	if maxBody == 0 {
+		maxBody = gatewayLimits.maxBody
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		ip := clientIP(r)
+		if ok, wait := ipLimiter.take(ip+" "+route.Pattern, time.Now()); !ok {
+			tooMany(w, wait, "Too many requests from your address, slow down")
+			return
+		}
+		if !enter(ip) {
+			tooMany(w, time.Second, "Too many requests in progress, retry shortly")
+			return
+		}
+		defer leave(ip)
+
+		if maxBody > 0 {
+			if r.ContentLength > maxBody {
+				bodyTooLarge(w, maxBody)
+				return
+			}
+			r.Body = http.MaxBytesReader(w, r.Body, maxBody)
+		}
+		next(w, r)
+	}
+}
+
+// enter counts a request in, unless its IP or the gateway is at the cap.
+func enter(ip string) bool {
+	inflightMu.Lock()
+	defer inflightMu.Unlock()
+	if (gatewayLimits.ipInflight > 0 && inflight[ip] >= gatewayLimits.ipInflight) ||
+		(gatewayLimits.maxInflight > 0 && inflightN >= gatewayLimits.maxInflight) {
+		return false
+	}
+	inflight[ip]++
+	inflightN++
+	return true
+}
+
+func leave(ip string) {
+	inflightMu.Lock()
+	defer inflightMu.Unlock()
+	if inflight[ip]--; inflight[ip] <= 0 {
+		delete(inflight, ip)
+	}
+	inflightN--
+}
+
+func clientIP(r *http.Request) string {
+	if gatewayLimits.trustProxy {
+		if forwarded := r.Header.Get("X-Forwarded-For"); forwarded != "" {
+			return strings.TrimSpace(forwarded[strings.LastIndex(forwarded, ",")+1:])
+		}
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+func tooMany(w http.ResponseWriter, wait time.Duration, message string) {
+	w.Header().Set("Retry-After", fmt.Sprint(max(1, int(math.Ceil(wait.Seconds())))))
+	writeJSONStatus(w, http.StatusTooManyRequests, HTTPResponse{Success: false, Code: types.CodeRateLimited, Message: message})
+}
+
+func bodyTooLarge(w http.ResponseWriter, max int64) {
+	writeJSONStatus(w, http.StatusRequestEntityTooLarge, HTTPResponse{Success: false, Code: types.CodeBadRequest,
+		Message: fmt.Sprintf("Request body over %d bytes", max)})
+}
+
+// isTooLarge reports whether err is a body cut off by limit.
+func isTooLarge(err error) bool {
+	var tooLarge *http.MaxBytesError
+	return errors.As(err, &tooLarge)
+}
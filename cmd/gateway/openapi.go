@@ -0,0 +1,348 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/client"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== API description =====================
+
+// apiRoutes is every endpoint of the gateway. startHTTPServer registers the
+// handlers from it and GET /api/openapi.json describes them from it, so the
+// two cannot drift apart: the request body and the data in the reply are
+// turned into JSON schemas from their Go types. Body fields tagged
+// `validate:"required"` are required, and decodeBody enforces it along with
+// rejecting fields the body type does not have.
+
+type apiRoute struct {
+	Pattern string // for http.HandleFunc
+	Method  string
+	Path    string // as documented, with {params}
+	Summary string
+	Params  []apiParam
+	Body    any    // zero value of the request body type, if any
+	Data    any    // zero value of HTTPResponse.Data, if any
+	Stream  bool   // Data is sent as Server-Sent Events
+	Content string // type of a reply that is not JSON (or not ours)
+	MaxBody int64  // bytes; 0 for -max-body, -1 for no limit
+	Handler http.HandlerFunc
+	NoCORS  bool
+}
+
+type apiParam struct {
+	Name string
+	In   string // "path" or "query"
+	Type string
+	Doc  string
+}
+
+var chunkParams = []apiParam{
+	{"x", "path", "integer", "chunk column"},
+	{"y", "path", "integer", "chunk row"},
+	{"world", "query", "string", "world of the chunk; the shared world if empty"},
+	{"level", "query", "integer", "split level of the chunk (0 for a whole chunk)"},
+}
+
+var apiRoutes = []apiRoute{
+	{Pattern: "/api/player/move", Method: "POST", Summary: "Move a player within its chunk",
+		Body: HTTPMoveRequest{}, Data: types.GameData{}, Handler: handleMovePlayerHTTP},
+	{Pattern: "/api/player/data", Method: "POST", Summary: "Fetch the chunk a player is in, claiming it if unowned",
+		Body: HTTPGetDataRequest{}, Data: types.Chunk{}, Handler: handleGetDataHTTP},
+	{Pattern: "/api/player/updates", Method: "POST", Summary: "Poll a chunk for changes (see /api/chunk/{x}/{y}/events)",
+		Body: HTTPGetUpdatesRequest{}, Data: types.GameData{}, Handler: handleGetUpdatesHTTP},
+	{Pattern: "/api/player/delete", Method: "POST", Summary: "Remove a player who is leaving",
+		Body: HTTPDeletePlayerRequest{}, Handler: handleDeletePlayerHTTP},
+	{Pattern: "/api/player/chat", Method: "POST", Summary: "Send a chat message and read the chunk's recent messages",
+		Body: HTTPChatRequest{}, Data: []types.ChatMessage{}, Handler: handleChatHTTP},
+	{Pattern: "/api/health", Method: "GET", Summary: "Check the gateway is up",
+		Data: map[string]string{}, Handler: handleHealthCheck},
+	{Pattern: "/api/player/addcube", Method: "POST", Summary: "Place a cube in a chunk",
+		Body: HTTPAddCubeRequest{}, Handler: handleAddCubeHTTP},
+	{Pattern: "/api/player/dltcube", Method: "POST", Summary: "Remove a cube from a chunk",
+		Body: HTTPDltCubeRequest{}, Handler: handleDltCubeHTTP},
+	{Pattern: "/api/player/addcubes", Method: "POST", Summary: "Place several cubes in a chunk at once",
+		Body: HTTPAddCubesRequest{}, Handler: handleAddCubesHTTP},
+	{Pattern: "/api/player/dltcubes", Method: "POST", Summary: "Remove several cubes from a chunk at once",
+		Body: HTTPDltCubesRequest{}, Handler: handleDltCubesHTTP},
+	{Pattern: "/api/player/tx", Method: "POST", Summary: "Edit several chunks as one transaction",
+		Body: HTTPTxRequest{}, Handler: handleTxHTTP},
+	{Pattern: "/api/player/undo", Method: "POST", Summary: "Revert the player's latest cube edit in a chunk",
+		Body: HTTPUndoRequest{}, Handler: handleUndoHTTP},
+	{Pattern: "/api/world/export", Method: "GET", Summary: "Export the world (admin, proxied to the game server)",
+		Content: "application/octet-stream", Handler: handleWorldHTTP},
+	{Pattern: "/api/world/import", Method: "POST", Summary: "Import a world (admin, proxied to the game server)",
+		Content: "application/json", MaxBody: -1, Handler: handleWorldHTTP},
+	{Pattern: "/api/spectate", Method: "GET", Summary: "Stream a region of chunks as Server-Sent Events",
+		Params: []apiParam{
+			{"world", "query", "string", "world of the chunks; the shared world if empty"},
+			{"x0", "query", "integer", "first chunk column"}, {"y0", "query", "integer", "first chunk row"},
+			{"x1", "query", "integer", "last chunk column"}, {"y1", "query", "integer", "last chunk row"},
+		},
+		Data: client.SpectateUpdate{}, Stream: true, Handler: handleSpectateHTTP},
+	{Pattern: "/api/chunk/", Method: "GET", Path: "/api/chunk/{x}/{y}/events", Summary: "Stream a chunk's changes as Server-Sent Events",
+		Params: chunkParams, Data: ChunkEvent{}, Stream: true, Handler: handleChunkEventsHTTP},
+	{Pattern: "/api/chunks/", Method: "GET", Path: "/api/chunks/{x}/{y}", Summary: "Read a chunk; ETag is its version",
+		Params: chunkParams, Data: types.Chunk{}, Handler: handleChunkResource},
+	{Pattern: "/api/players/", Method: "GET", Path: "/api/players/{id}", Summary: "Find a player and their state",
+		Params: []apiParam{{"id", "path", "string", "player ID"}}, Data: PlayerInfo{}, Handler: handlePlayerResource},
+	{Pattern: "/api/servers", Method: "GET", Summary: "List the game servers as central sees them",
+		Data: []types.ServerStatus{}, Handler: handleServersResource},
+	{Pattern: "/api/leaderboard", Method: "GET", Summary: "Rank players by a stat, as central totals it",
+		Params: []apiParam{
+			{"stat", "query", "string", "cubes_placed, cubes_removed, distance, chunks_visited, kills (the default) or deaths"},
+			{"limit", "query", "integer", "most players to list; 10 if empty, at most 100"},
+		},
+		Data: types.Leaderboard{}, Handler: handleLeaderboardResource},
+	{Pattern: "/api/openapi.json", Method: "GET", Summary: "This document", Content: "application/json", Handler: handleOpenAPI},
+	{Pattern: "/dashboard", Method: "GET", Summary: "Live world map", Content: "text/html", Handler: handleDashboard, NoCORS: true},
+	{Pattern: "/dashboard/events", Method: "GET", Summary: "Central's /map each time it changes, as Server-Sent Events",
+		Params: []apiParam{{"world", "query", "string", "world to map; the shared world if empty"}},
+		Data:   types.WorldMap{}, Stream: true, Handler: handleDashboardEvents},
+}
+
+// the document served on /api/openapi.json, built by registerRoutes
+var apiDoc map[string]any
+
+func handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, apiDoc)
+}
+
+// openAPI describes apiRoutes as an OpenAPI 3.0 document.
+func openAPI() map[string]any {
+	schemas := make(map[string]any)
+	paths := make(map[string]map[string]any)
+	for _, route := range apiRoutes {
+		path := route.Path
+		if path == "" {
+			path = route.Pattern
+		}
+		op := map[string]any{"summary": route.Summary}
+
+		var params []map[string]any
+		for _, p := range route.Params {
+			params = append(params, map[string]any{"name": p.Name, "in": p.In, "required": p.In == "path",
+				"description": p.Doc, "schema": map[string]any{"type": p.Type}})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if route.Body != nil {
+			op["requestBody"] = map[string]any{"required": true,
+				"content": map[string]any{"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(route.Body), schemas)}}}
+		}
+
+		var content map[string]any
+		switch {
+		case route.Content != "":
+			content = map[string]any{route.Content: map[string]any{}}
+		case route.Stream:
+			content = map[string]any{"text/event-stream": map[string]any{"schema": schemaOf(reflect.TypeOf(route.Data), schemas)}}
+		case route.Data != nil:
+			envelope := schemaOf(reflect.TypeOf(HTTPResponse{}), schemas)
+			content = map[string]any{"application/json": map[string]any{"schema": map[string]any{"allOf": []any{envelope,
+				map[string]any{"properties": map[string]any{"data": schemaOf(reflect.TypeOf(route.Data), schemas)}}}}}}
+		default:
+			content = map[string]any{"application/json": map[string]any{"schema": schemaOf(reflect.TypeOf(HTTPResponse{}), schemas)}}
+		}
+		responses := map[string]any{"200": map[string]any{"description": "OK", "content": content}}
+		if route.Body != nil || route.Params != nil {
+			responses["400"] = map[string]any{"description": "Invalid request"}
+		}
+		responses["429"] = map[string]any{"description": "Rate limited; see Retry-After"}
+		op["responses"] = responses
+
+		if paths[path] == nil {
+			paths[path] = make(map[string]any)
+		}
+		paths[path][strings.ToLower(route.Method)] = op
+	}
+
+	return map[string]any{
+		"openapi":    "3.0.3",
+		"info":       map[string]any{"title": "Game gateway API", "version": "1"},
+		"paths":      paths,
+		"components": map[string]any{"schemas": schemas},
+	}
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// schemaOf returns the JSON schema of t, adding named structs to schemas
+// and referring to them.
+func schemaOf(t reflect.Type, schemas map[string]any) map[string]any {
+	if t == nil {
+		return map[string]any{}
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema := schemaOf(t.Elem(), schemas)
+		if _, ref := schema["$ref"]; ref {
+			return map[string]any{"allOf": []any{schema}, "nullable": true}
+		}
+		schema["nullable"] = true
+		return schema
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemaOf(t.Elem(), schemas)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaOf(t.Elem(), schemas)}
+	case reflect.Interface:
+		return map[string]any{}
+	case reflect.Struct:
+		if t == timeType {
+			return map[string]any{"type": "string", "format": "date-time"}
+		}
+		name := t.Name()
+		if name == "" {
+			return structSchema(t, schemas)
+		}
+		ref := map[string]any{"$ref": "#/components/schemas/" + name}
+		if _, ok := schemas[name]; !ok {
+			schemas[name] = nil // placeholder, for types that contain themselves
+			schemas[name] = structSchema(t, schemas)
+		}
+		return ref
+	}
+	return map[string]any{}
+}
+
+func structSchema(t reflect.Type, schemas map[string]any) map[string]any {
+	props := make(map[string]any)
+	var required []string
+	for _, f := range fields(t) {
+		props[f.name] = schemaOf(f.Type, schemas)
+		if f.required {
+			required = append(required, f.name)
+		}
+	}
+	schema := map[string]any{"type": "object", "properties": props}
+	if required != nil {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+type jsonField struct {
+	reflect.StructField
+	name     string
+	required bool
+}
+
+// fields lists the JSON fields of struct type t, embedded structs included.
+func fields(t reflect.Type) []jsonField {
+	var list []jsonField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			list = append(list, fields(f.Type)...)
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		list = append(list, jsonField{StructField: f, name: name, required: f.Tag.Get("validate") == "required"})
+	}
+	return list
+}
+
+// ===================== Request validation =====================
+
+// decodeBody reads r's JSON body into v, a pointer to a request struct,
+// refusing fields v does not have and requiring those tagged
+// `validate:"required"`. On failure it has already replied with why.
+func decodeBody(w http.ResponseWriter, r *http.Request, v any) bool {
+	if err := validBody(r.Body, v); err != nil {
+		if isTooLarge(err) {
+			bodyTooLarge(w, err.(*http.MaxBytesError).Limit)
+			return false
+		}
+		writeJSONStatus(w, http.StatusBadRequest, HTTPResponse{Success: false, Code: types.CodeBadRequest,
+			Message: "Invalid request body: " + err.Error()})
+		return false
+	}
+	return true
+}
+
+func validBody(body io.Reader, v any) error {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return err
+	}
+	var present map[string]json.RawMessage
+	if err := json.Unmarshal(data, &present); err != nil {
+		return errors.New("must be a JSON object")
+	}
+	for _, f := range fields(reflect.TypeOf(v).Elem()) {
+		if !f.required {
+			continue
+		}
+		found := false
+		for key, value := range present {
+			// encoding/json matches keys regardless of case
+			if strings.EqualFold(key, f.name) && string(value) != "null" {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("missing %s", f.name)
+		}
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errors.New(strings.TrimPrefix(err.Error(), "json: "))
+	}
+	return nil
+}
+
+func writeJSONStatus(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+// registerRoutes puts apiRoutes on the default mux.
+func registerRoutes() {
+	apiDoc = openAPI()
+	for _, route := range apiRoutes {
+		handler := limit(route, route.Handler)
+		if !route.NoCORS {
+			handler = netproto.EnableCORS(handler)
+		}
+		http.HandleFunc(route.Pattern, handler)
+	}
+}
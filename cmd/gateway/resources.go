@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Read-only resources =====================
+
+// Plain GET endpoints for tooling and dashboards, answered in the same
+// HTTPResponse envelope as the player API:
+//
+//	/api/chunks/{x}/{y}?world=&level=  a chunk, read with READ_ONLY from its
+//	                                   owner or a replica; ETag is its version
+//	                                   and If-None-Match gets 304
+//	/api/players/{id}                  where a player is and their state
+//	/api/servers                       the game servers as central sees them
+//	/api/leaderboard?stat=&limit=      players ranked by a stat they have
+//	                                   played up
+//
+// Players, servers and leaderboards are asked of central, so they need
+// -central.
+
+func handleChunkResource(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/chunks/"), "/"), "/")
+	if len(parts) != 2 {
+		http.NotFound(w, r)
+		return
+	}
+	chunk_id, err := types.ParseChunkID(parts[0], parts[1], r.URL.Query().Get("world"), r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	trace := requestTrace(w, r)
+	req := types.Request{Type: types.ReqReadOnly, TraceID: trace, ChunkID: chunk_id, IsChunkNew: true,
+		Since: parseETag(r.Header.Get("If-None-Match"))}
+	resp, err := forward(r.Context(), req)
+	if err != nil {
+		netproto.Tracef(trace, "❌ UDP READ_ONLY error: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusBadGateway)
+		return
+	}
+
+	if resp.Version != nil {
+		w.Header().Set("ETag", fmt.Sprintf(`"%d-%d"`, resp.Version.Epoch, resp.Version.Seq))
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	switch {
+	case resp.Code == types.CodeNotModified:
+		w.WriteHeader(http.StatusNotModified)
+	case resp.Code == types.CodeNotOwner:
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, HTTPResponse{Success: false, Code: resp.Code, Message: "No server has this chunk loaded", TraceID: trace})
+	case !resp.Success:
+		w.WriteHeader(http.StatusBadGateway)
+		writeJSON(w, toHTTPResponse(resp, nil, trace))
+	default:
+		writeJSON(w, toHTTPResponse(resp, resp.Chunk, trace))
+	}
+}
+
+// parseETag reads back a chunk version sent as an ETag by
+// handleChunkResource.
+func parseETag(tag string) *types.ChunkVersion {
+	var version types.ChunkVersion
+	if _, err := fmt.Sscanf(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`), "%d-%d", &version.Epoch, &version.Seq); err != nil {
+		return nil
+	}
+	return &version
+}
+
+// PlayerInfo is the data of /api/players/{id}.
+type PlayerInfo struct {
+	Player types.Player `json:"player"`
+	Server string       `json:"server"`
+}
+
+func handlePlayerResource(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) || !needCentral(w) {
+		return
+	}
+	player_id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/players/"), "/")
+	if player_id == "" || strings.Contains(player_id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+
+	trace := requestTrace(w, r)
+	body, _ := json.Marshal(types.Request{Type: types.ReqLocatePlayer, PlayerID: player_id, TraceID: trace})
+	httpResp, err := http.Post(centralURL+"/locate", "application/json", bytes.NewReader(body))
+	if err != nil {
+		netproto.Tracef(trace, "❌ Central /locate error: %v", err)
+		http.Error(w, "Failed to communicate with central server", http.StatusBadGateway)
+		return
+	}
+	defer httpResp.Body.Close()
+	var resp types.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+		http.Error(w, "Invalid reply from central server", http.StatusBadGateway)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	if !resp.Success || resp.Player == nil {
+		w.WriteHeader(http.StatusNotFound)
+		writeJSON(w, HTTPResponse{Success: false, Code: types.CodeNotFound, Message: "Player is not online", TraceID: trace})
+		return
+	}
+	writeJSON(w, HTTPResponse{Success: true, Message: resp.Message, Data: PlayerInfo{Player: *resp.Player, Server: resp.RedirectIP}, TraceID: trace})
+}
+
+func handleServersResource(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) || !needCentral(w) {
+		return
+	}
+	trace := requestTrace(w, r)
+	httpResp, err := http.Get(centralURL + "/servers")
+	if err != nil {
+		netproto.Tracef(trace, "❌ Central /servers error: %v", err)
+		http.Error(w, "Failed to communicate with central server", http.StatusBadGateway)
+		return
+	}
+	defer httpResp.Body.Close()
+	var list []types.ServerStatus
+	if httpResp.StatusCode != http.StatusOK || json.NewDecoder(httpResp.Body).Decode(&list) != nil {
+		http.Error(w, "Invalid reply from central server", http.StatusBadGateway)
+		return
+	}
+
+	// reports come in every few seconds; a little staleness costs nothing
+	w.Header().Set("Cache-Control", "max-age=2")
+	writeJSON(w, HTTPResponse{Success: true, Message: "Game servers", Data: list, TraceID: trace})
+}
+
+func handleLeaderboardResource(w http.ResponseWriter, r *http.Request) {
+	if !allowGet(w, r) || !needCentral(w) {
+		return
+	}
+	trace := requestTrace(w, r)
+	query := url.Values{}
+	for _, key := range []string{"stat", "limit"} {
+		if v := r.URL.Query().Get(key); v != "" {
+			query.Set(key, v)
+		}
+	}
+	httpResp, err := http.Get(centralURL + "/leaderboard?" + query.Encode())
+	if err != nil {
+		netproto.Tracef(trace, "❌ Central /leaderboard error: %v", err)
+		http.Error(w, "Failed to communicate with central server", http.StatusBadGateway)
+		return
+	}
+	defer httpResp.Body.Close()
+	if httpResp.StatusCode == http.StatusBadRequest {
+		msg, _ := io.ReadAll(httpResp.Body)
+		writeJSONStatus(w, http.StatusBadRequest, HTTPResponse{Success: false, Code: types.CodeBadRequest,
+			Message: strings.TrimSpace(string(msg)), TraceID: trace})
+		return
+	}
+	var board types.Leaderboard
+	if httpResp.StatusCode != http.StatusOK || json.NewDecoder(httpResp.Body).Decode(&board) != nil {
+		http.Error(w, "Invalid reply from central server", http.StatusBadGateway)
+		return
+	}
+
+	// totals only move with the servers' reports
+	w.Header().Set("Cache-Control", "max-age=2")
+	writeJSON(w, HTTPResponse{Success: true, Message: "Leaderboard for " + board.Stat, Data: board, TraceID: trace})
+}
+
+// allowGet refuses anything but GET and HEAD.
+func allowGet(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	return false
+}
+
+func needCentral(w http.ResponseWriter) bool {
+	if centralURL == "" {
+		http.Error(w, "Central server not configured on this gateway", http.StatusServiceUnavailable)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/client"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Spectating =====================
+
+// GET /api/spectate?world=&x0=&y0=&x1=&y1= streams a region of chunks, from
+// chunk (x0,y0) to (x1,y1) inclusive, to a web viewer as Server-Sent Events:
+// one "data:" line per change, each a client.SpectateUpdate with the whole
+// chunk and, when only part of it changed, the delta. The gateway spectates
+// the chunks on the viewer's behalf for as long as the request is open;
+// spectators are not players, so watching never shows up in the chunks.
+// A chunk no server has loaded yet is sent once one does.
+
+const maxSpectateChunks = 25
+
+func handleSpectateHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	chunks, err := spectateRegion(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	streamChunks(w, r, chunks, func(update client.SpectateUpdate) (string, any) {
+		return "", update
+	})
+}
+
+// handleChunkEventsHTTP serves GET /api/chunk/{x}/{y}/events?world=&level=,
+// the changes of one chunk as Server-Sent Events, for clients that would
+// otherwise poll /api/player/updates. The first event, and any after the
+// stream lost track of a change, is a "chunk" event with the whole chunk
+// (a client.SpectateUpdate); the others are "delta" events with only what
+// changed since the version before (a ChunkEvent).
+func handleChunkEventsHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/chunk/"), "/"), "/")
+	if len(parts) != 3 || parts[2] != "events" {
+		http.NotFound(w, r)
+		return
+	}
+	chunk_id, err := types.ParseChunkID(parts[0], parts[1], r.URL.Query().Get("world"), r.URL.Query().Get("level"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	streamChunks(w, r, []types.ChunkID{chunk_id}, func(update client.SpectateUpdate) (string, any) {
+		if update.Delta == nil {
+			return "chunk", update
+		}
+		return "delta", ChunkEvent{ChunkID: update.ChunkID, Delta: update.Delta, Version: update.Version, UpdateMs: update.UpdateMs}
+	})
+}
+
+// ChunkEvent is a "delta" event of /api/chunk/{x}/{y}/events.
+type ChunkEvent struct {
+	ChunkID  types.ChunkID       `json:"chunk_id"`
+	Delta    *types.ChunkDelta   `json:"delta"`
+	Version  *types.ChunkVersion `json:"version,omitempty"`
+	UpdateMs int64               `json:"update_ms,omitempty"`
+}
+
+// streamChunks spectates chunks for as long as r is open, writing each
+// update as the Server-Sent Event event turns it into; an empty event name
+// sends a plain "data:" message.
+func streamChunks(w http.ResponseWriter, r *http.Request, chunks []types.ChunkID, event func(client.SpectateUpdate) (string, any)) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	trace := requestTrace(w, r)
+	spectator, err := client.NewSpectator(network, gameServerUDP)
+	if err != nil {
+		netproto.Tracef(trace, "❌ Spectator: %v", err)
+		http.Error(w, "Failed to communicate with game server", http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	netproto.Tracef(trace, "👀 Spectating %d chunks", len(chunks))
+	err = spectator.Watch(r.Context(), chunks, func(update client.SpectateUpdate) {
+		name, v := event(update)
+		data, err := json.Marshal(v)
+		if err != nil {
+			return
+		}
+		if name != "" {
+			fmt.Fprintf(w, "event: %s\n", name)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+		flusher.Flush()
+	})
+	if err != nil {
+		netproto.Tracef(trace, "❌ Spectating: %v", err)
+	}
+}
+
+// spectateRegion reads the chunks asked for by /api/spectate.
+func spectateRegion(r *http.Request) ([]types.ChunkID, error) {
+	q := r.URL.Query()
+	var bounds [4]int
+	for i, name := range []string{"x0", "y0", "x1", "y1"} {
+		v, err := strconv.Atoi(q.Get(name))
+		if err != nil {
+			return nil, fmt.Errorf("%s must be a chunk coordinate", name)
+		}
+		bounds[i] = v
+	}
+	x0, y0, x1, y1 := bounds[0], bounds[1], bounds[2], bounds[3]
+	if x1 < x0 || y1 < y0 {
+		return nil, fmt.Errorf("region must have x0 <= x1 and y0 <= y1")
+	}
+	if (x1-x0+1)*(y1-y0+1) > maxSpectateChunks {
+		return nil, fmt.Errorf("region is more than %d chunks", maxSpectateChunks)
+	}
+
+	world := q.Get("world")
+	var chunks []types.ChunkID
+	for x := x0; x <= x1; x++ {
+		for y := y0; y <= y1; y++ {
+			chunks = append(chunks, types.ChunkID{World: world, IDX: x, IDY: y})
+		}
+	}
+	return chunks, nil
+}
@@ -0,0 +1,244 @@
+// Command loadtest drives N simulated players against a running cluster and
+// reports per-request latency percentiles, packet loss, redirects and the
+// chunk migrations central recorded while it ran.
+//
+//	go run ./cmd/loadtest -central http://127.0.0.1:8080 -players 200 -pattern hotspot -duration 1m
+package main
+
+import (
+	"bufio"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/player"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// stats collects every round trip, keyed by request type.
+type stats struct {
+	sync.Mutex
+	latencies map[types.RequestType][]time.Duration
+	lost      map[types.RequestType]int
+	codes     map[string]int
+}
+
+func newStats() *stats {
+	return &stats{
+		latencies: make(map[types.RequestType][]time.Duration),
+		lost:      make(map[types.RequestType]int),
+		codes:     make(map[string]int),
+	}
+}
+
+func (s *stats) record(req types.Request, res *types.Response, rtt time.Duration, err error) {
+	s.Lock()
+	defer s.Unlock()
+	if err != nil {
+		s.lost[req.Type]++
+		return
+	}
+	s.latencies[req.Type] = append(s.latencies[req.Type], rtt)
+	s.codes[res.Code]++
+}
+
+// ===================== Movement patterns =====================
+
+// patterns build one mover per player; i lets a pattern spread players out.
+var patterns = map[string]func(i int, rng *rand.Rand) func(*types.Player){
+	"diagonal": func(int, *rand.Rand) func(*types.Player) { return player.MoveDiagonal },
+	"static":   func(int, *rand.Rand) func(*types.Player) { return func(*types.Player) {} },
+	"random": func(_ int, rng *rand.Rand) func(*types.Player) {
+		return func(p *types.Player) {
+			p.PosX += rng.Intn(7) - 3
+			p.PosY += rng.Intn(7) - 3
+			player.ClampToWorld(p)
+		}
+	},
+	// players orbit the world centre, crossing chunk borders steadily
+	"circle": func(i int, rng *rand.Rand) func(*types.Player) {
+		angle := rng.Float64() * 2 * math.Pi
+		radius := 40 + float64(i%5)*30
+		return func(p *types.Player) {
+			angle += 0.1
+			p.PosX = player.WorldSize/2 + int(radius*math.Cos(angle))
+			p.PosY = player.WorldSize/2 + int(radius*math.Sin(angle))
+		}
+	},
+	// everyone converges on one spot, piling load onto a single chunk
+	"hotspot": func(_ int, rng *rand.Rand) func(*types.Player) {
+		return func(p *types.Player) {
+			p.PosX += sign(player.WorldSize/2-p.PosX)*2 + rng.Intn(3) - 1
+			p.PosY += sign(player.WorldSize/2-p.PosY)*2 + rng.Intn(3) - 1
+			player.ClampToWorld(p)
+		}
+	},
+}
+
+func sign(v int) int {
+	switch {
+	case v > 0:
+		return 1
+	case v < 0:
+		return -1
+	}
+	return 0
+}
+
+func main() {
+	centralURL := flag.String("central", "http://127.0.0.1:8080", "base URL of the central server")
+	players := flag.Int("players", 50, "number of simulated players")
+	duration := flag.Duration("duration", 30*time.Second, "how long to run after the ramp")
+	tick := flag.Duration("tick", 500*time.Millisecond, "game loop period of each player")
+	ramp := flag.Duration("ramp", 5*time.Second, "time over which players join")
+	pattern := flag.String("pattern", "random", "movement pattern: diagonal, static, random, circle, hotspot")
+	seed := flag.Int64("seed", time.Now().UnixNano(), "seed for movement patterns")
+	verbose := flag.Bool("v", false, "keep per-player logs")
+	codec := flag.String("codec", netproto.Wire.Name(), "codec requests are encoded with: json or generated")
+	flag.Parse()
+
+	newMover, ok := patterns[*pattern]
+	if !ok {
+		log.Fatalf("unknown pattern %q", *pattern)
+	}
+	c, err := netproto.CodecByName(*codec)
+	if err != nil {
+		log.Fatalf("invalid -codec: %v", err)
+	}
+	netproto.Wire = c
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	migrationsBefore, _ := centralMigrations(*centralURL)
+	st := newStats()
+	ctx, cancel := context.WithTimeout(context.Background(), *ramp+*duration)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	var joinFailures int
+	var joinMu sync.Mutex
+	for i := 0; i < *players; i++ {
+		if *players > 1 {
+			time.Sleep(*ramp / time.Duration(*players))
+		}
+		ps, err := player.NewPlayerState(netproto.UDP, fmt.Sprintf("load-%d", i))
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "player %d: %v\n", i, err)
+			os.Exit(1)
+		}
+		ps.Tick = *tick
+		ps.Move = newMover(i, rand.New(rand.NewSource(*seed+int64(i))))
+		ps.OnResponse = st.record
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			defer ps.Cleanup()
+			if err := ps.Join(*centralURL); err != nil {
+				joinMu.Lock()
+				joinFailures++
+				joinMu.Unlock()
+				return
+			}
+			ps.Initialize()
+			ps.GameLoop(ctx)
+		}()
+	}
+	wg.Wait()
+
+	migrationsAfter, err := centralMigrations(*centralURL)
+	report(st, *players, joinFailures, *pattern)
+	if err != nil {
+		fmt.Printf("\nchunk migrations: unavailable (%v)\n", err)
+	} else {
+		fmt.Printf("\nchunk migrations: %d\n", int64(migrationsAfter-migrationsBefore))
+	}
+}
+
+func report(st *stats, players, joinFailures int, pattern string) {
+	st.Lock()
+	defer st.Unlock()
+
+	fmt.Printf("players: %d (%d failed to join), pattern: %s\n\n", players, joinFailures, pattern)
+	fmt.Printf("%-12s %8s %8s %9s %9s %9s %9s\n", "type", "ok", "lost", "p50", "p90", "p99", "max")
+
+	seen := make(map[types.RequestType]bool)
+	for t := range st.latencies {
+		seen[t] = true
+	}
+	for t := range st.lost {
+		seen[t] = true
+	}
+	var reqTypes []string
+	for t := range seen {
+		reqTypes = append(reqTypes, string(t))
+	}
+	sort.Strings(reqTypes)
+
+	var total, lost int
+	for _, name := range reqTypes {
+		t := types.RequestType(name)
+		lat := st.latencies[t]
+		sort.Slice(lat, func(i, j int) bool { return lat[i] < lat[j] })
+		total += len(lat) + st.lost[t]
+		lost += st.lost[t]
+		fmt.Printf("%-12s %8d %8d %9s %9s %9s %9s\n", name, len(lat), st.lost[t],
+			percentile(lat, 50), percentile(lat, 90), percentile(lat, 99), percentile(lat, 100))
+	}
+	if total > 0 {
+		fmt.Printf("\npacket loss: %.2f%% (%d of %d requests unanswered)\n", 100*float64(lost)/float64(total), lost, total)
+	}
+
+	var codes []string
+	for code, n := range st.codes {
+		codes = append(codes, fmt.Sprintf("%s=%d", code, n))
+	}
+	sort.Strings(codes)
+	fmt.Printf("response codes: %s\n", strings.Join(codes, " "))
+}
+
+func percentile(sorted []time.Duration, p int) string {
+	if len(sorted) == 0 {
+		return "-"
+	}
+	i := (len(sorted)*p + 99) / 100
+	if i > 0 {
+		i--
+	}
+	return sorted[i].Round(10 * time.Microsecond).String()
+}
+
+// centralMigrations reads central_chunk_migrations_total from central's
+// /metrics endpoint.
+func centralMigrations(centralURL string) (float64, error) {
+	res, err := http.Get(centralURL + "/metrics")
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	scanner := bufio.NewScanner(res.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if value, ok := strings.CutPrefix(line, "central_chunk_migrations_total "); ok {
+			return strconv.ParseFloat(value, 64)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, err
+	}
+	return 0, nil
+}
@@ -0,0 +1,42 @@
+// Command simclient runs one simulated player against a live cluster.
+package main
+
+import (
+	"context"
+	"flag"
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/player"
+)
+
+func main() {
+	centralURL := flag.String("central", "http://127.0.0.1:8080", "base URL of the central server")
+	id := flag.String("id", "1", "player ID")
+	statsEvery := flag.Duration("stats", 10*time.Second, "how often to log and report connection stats (0 to disable)")
+	worldID := flag.String("world", "", "world to play in, as created at central's /worlds (the shared world if empty)")
+	seal := flag.Bool("seal", false, "ask /join for a key to encrypt UDP traffic with (a cluster with a seal secret gives one anyway)")
+	flag.Parse()
+
+	// Create player with unique ID
+	//playerID := "player_" + time.Now().Format("150405")
+	ps, err := player.NewPlayerState(netproto.UDP, *id)
+	if err != nil {
+		log.Fatal("Listen failed:", err)
+	}
+	defer ps.Cleanup()
+
+	// Initialize and start game loop
+	ps.SetWorld(*worldID)
+	ps.SetSeal(*seal)
+	if err := ps.Join(*centralURL); err != nil {
+		log.Fatalf("❌ %v", err)
+	}
+	ps.Initialize()
+	ctx := context.Background()
+	if *statsEvery > 0 {
+		ps.ReportStats(ctx, *statsEvery)
+	}
+	ps.GameLoop(ctx)
+}
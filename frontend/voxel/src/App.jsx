@@ -338,7 +338,8 @@ export default function Game() {
   const [currentChunk, setCurrentChunk] = useState({ IDX: 0, IDY: 0 });
   const controlsRef = useRef();
 
-  const chunkSize = 32;
+  // the cluster's chunk size arrives with the /join response
+  const [chunkSize, setChunkSize] = useState(32);
 
 
 
@@ -364,6 +365,9 @@ export default function Game() {
       const result = await response.json();
       console.log(result.message);
       setServerAddr(result.message);
+      if (result.chunk_size) {
+        setChunkSize(result.chunk_size);
+      }
       
     } catch (error) {
       console.error('Failed to connect to central server:', error);
@@ -763,6 +767,7 @@ export default function Game() {
     }
 
     const add_req = {
+        player_id : playerId,
         cube : new_cube, 
         chunk_id : current_chunk_id
    }
@@ -811,6 +816,7 @@ export default function Game() {
       }
 
       const dlt_req = {
+            player_id : playerId,
             cube_id : topCube.cube_id, 
             chunk_id : current_chunk_id
       }
@@ -838,6 +844,7 @@ export default function Game() {
       }
 
       const dlt_req = {
+            player_id : playerId,
             cube_id : cubeId, 
             chunk_id : current_chunk_id
       }
@@ -1,5 +1,6 @@
 // api.js
-const API_BASE_URL = 'http://172.16.118.72:8081/api'; // Your HTTP gateway
+// Your HTTP gateway; set VITE_API_BASE_URL=https://... when it serves HTTPS
+const API_BASE_URL = import.meta.env.VITE_API_BASE_URL || 'http://172.16.118.72:8081/api';
 
 // Helper function to make API calls
 async function apiCall(endpoint, data) {
@@ -41,14 +42,30 @@ export async function movePlayer(playerId, x, y, chunkId) {
   });
 }
 
-// Get updates for a chunk
-export async function getChunkUpdates(playerId, chunkId) {
+// Get updates for a chunk. Pass the version from the last response as since
+// to get only the changes after it (code OK_DELTA, data a delta); without it,
+// or when too far behind, the whole chunk comes back.
+export async function getChunkUpdates(playerId, chunkId, since) {
   return await apiCall('/player/updates', {
     player_id: playerId,
-    chunk_id: chunkId
+    chunk_id: chunkId,
+    ...(since && { since })
   });
 }
 
+// Stream a chunk's changes instead of polling getChunkUpdates: onChunk gets
+// the whole chunk first (and again whenever the stream resyncs), onDelta the
+// changes after it. Returns the EventSource; call close() on it to stop.
+export function watchChunk(chunkId, onChunk, onDelta) {
+  const params = new URLSearchParams();
+  if (chunkId.world) params.set('world', chunkId.world);
+  if (chunkId.level) params.set('level', chunkId.level);
+  const events = new EventSource(`${API_BASE_URL}/chunk/${chunkId.id_x}/${chunkId.id_y}/events?${params}`);
+  events.addEventListener('chunk', (e) => onChunk(JSON.parse(e.data)));
+  events.addEventListener('delta', (e) => onDelta(JSON.parse(e.data)));
+  return events;
+}
+
 // Delete player
 export async function deletePlayer(playerId) {
   return await apiCall('/player/delete', {
@@ -0,0 +1,273 @@
+// Package chunkstore persists chunks to disk, one zstd-compressed file per
+// chunk, or to PostgreSQL, and holds the chunk operations shared by servers
+// and tools.
+package chunkstore
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+type codec struct {
+	ext    string
+	writer func(io.Writer) (io.WriteCloser, error)
+	reader func(io.Reader) (io.ReadCloser, error)
+}
+
+// zstdCodec is what chunks are saved with. Each file gets its own encoder
+// or decoder, so neither starts goroutines of its own.
+var zstdCodec = codec{
+	ext: ".json.zst",
+	writer: func(w io.Writer) (io.WriteCloser, error) {
+		return zstd.NewWriter(w, zstd.WithEncoderLevel(zstd.SpeedDefault), zstd.WithEncoderConcurrency(1))
+	},
+	reader: func(r io.Reader) (io.ReadCloser, error) {
+		d, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, err
+		}
+		return d.IOReadCloser(), nil
+	},
+}
+
+// gzipCodec is what chunks were saved with before zstd. Such files are
+// still read, and replaced by zstd ones the next time the chunk is saved.
+var gzipCodec = codec{
+	ext: ".json.gz",
+	writer: func(w io.Writer) (io.WriteCloser, error) {
+		return gzip.NewWriterLevel(w, gzip.BestSpeed)
+	},
+	reader: func(r io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(r)
+	},
+}
+
+// A Backend persists chunks: a Store on disk, or Postgres.
+type Backend interface {
+	// Path names where a chunk is persisted, as Index lists it and Load
+	// takes it.
+	Path(chunk_id types.ChunkID) string
+	// Index lists the persisted chunks without reading them.
+	Index() (map[types.ChunkID]string, error)
+	Load(path string) (types.Chunk, error)
+	Save(chunk_id types.ChunkID, chunk types.Chunk) error
+	// SaveBatch saves chunks and removes the persisted copies of gone, all
+	// at once where the backend can.
+	SaveBatch(chunks map[types.ChunkID]types.Chunk, gone []types.ChunkID) error
+	// Remove deletes a persisted chunk; a missing one is not an error.
+	Remove(chunk_id types.ChunkID) error
+}
+
+// Store is a directory of persisted chunks.
+type Store struct {
+	dir   string
+	codec codec
+	older []codec // read, never written
+}
+
+// Open creates dir if needed and returns a Store on it.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &Store{dir: dir, codec: zstdCodec, older: []codec{gzipCodec}}, nil
+}
+
+func (s *Store) Dir() string { return s.dir }
+
+// Path is the file a chunk is persisted to; sub-chunks get their level as a
+// third component. Chunks of the shared world sit in the store's directory,
+// those of other worlds in worlds/<world ID> below it.
+func (s *Store) Path(chunk_id types.ChunkID) string {
+	return s.pathWith(chunk_id, s.codec)
+}
+
+func (s *Store) pathWith(chunk_id types.ChunkID, c codec) string {
+	dir := s.worldDir(chunk_id.World)
+	if chunk_id.Level > 0 {
+		return filepath.Join(dir, fmt.Sprintf("chunk_%d_%d_%d%s", chunk_id.IDX, chunk_id.IDY, chunk_id.Level, c.ext))
+	}
+	return filepath.Join(dir, fmt.Sprintf("chunk_%d_%d%s", chunk_id.IDX, chunk_id.IDY, c.ext))
+}
+
+// codecOf is the codec a file was saved with, by its extension.
+func (s *Store) codecOf(path string) (codec, bool) {
+	for _, c := range append([]codec{s.codec}, s.older...) {
+		if strings.HasSuffix(path, c.ext) {
+			return c, true
+		}
+	}
+	return codec{}, false
+}
+
+func (s *Store) worldDir(world string) string {
+	if world == types.DefaultWorld {
+		return s.dir
+	}
+	return filepath.Join(s.dir, "worlds", world)
+}
+
+// Index lists the persisted chunks of every world without reading them. A
+// chunk saved with an older codec is listed only if it was not saved since.
+func (s *Store) Index() (map[types.ChunkID]string, error) {
+	index := make(map[types.ChunkID]string)
+	for _, c := range append(slices.Clone(s.older), s.codec) { // newest last, so it wins
+		paths, err := filepath.Glob(filepath.Join(s.dir, "chunk_*"+c.ext))
+		if err != nil {
+			return nil, err
+		}
+		others, err := filepath.Glob(filepath.Join(s.dir, "worlds", "*", "chunk_*"+c.ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range append(paths, others...) {
+			var chunk_id types.ChunkID
+			if dir := filepath.Dir(path); dir != filepath.Clean(s.dir) {
+				chunk_id.World = filepath.Base(dir)
+			}
+			name := strings.TrimSuffix(filepath.Base(path), c.ext)
+			if _, err := fmt.Sscanf(name, "chunk_%d_%d_%d", &chunk_id.IDX, &chunk_id.IDY, &chunk_id.Level); err != nil {
+				chunk_id = types.ChunkID{World: chunk_id.World}
+				if _, err := fmt.Sscanf(name, "chunk_%d_%d", &chunk_id.IDX, &chunk_id.IDY); err != nil {
+					log.Printf("⚠️  Ignoring unexpected file %s", path)
+					continue
+				}
+			}
+			index[chunk_id] = path
+		}
+	}
+	return index, nil
+}
+
+// Load decompresses the chunk stored at path, with the codec its extension
+// names.
+func (s *Store) Load(path string) (types.Chunk, error) {
+	c, ok := s.codecOf(path)
+	if !ok {
+		return types.Chunk{}, fmt.Errorf("%s: not a chunk file", path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return types.Chunk{}, err
+	}
+	defer f.Close()
+
+	r, err := c.reader(f)
+	if err != nil {
+		return types.Chunk{}, err
+	}
+	defer r.Close()
+
+	var chunk types.Chunk
+	if err := json.NewDecoder(r).Decode(&chunk); err != nil {
+		return types.Chunk{}, err
+	}
+	return chunk, nil
+}
+
+// Save writes a chunk atomically (temp file + rename).
+func (s *Store) Save(chunk_id types.ChunkID, chunk types.Chunk) error {
+	dir := s.worldDir(chunk_id.World)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(dir, ".chunk-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	w, err := s.codec.writer(tmp)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := json.NewEncoder(w).Encode(chunk); err != nil {
+		w.Close()
+		tmp.Close()
+		return err
+	}
+	if err := w.Close(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), s.Path(chunk_id)); err != nil {
+		return err
+	}
+	return s.removeOlder(chunk_id)
+}
+
+// removeOlder deletes the files of chunk_id saved with older codecs.
+func (s *Store) removeOlder(chunk_id types.ChunkID) error {
+	for _, c := range s.older {
+		if err := os.Remove(s.pathWith(chunk_id, c)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// Remove deletes a persisted chunk; a missing file is not an error.
+func (s *Store) Remove(chunk_id types.ChunkID) error {
+	if err := os.Remove(s.Path(chunk_id)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return s.removeOlder(chunk_id)
+}
+
+// SaveBatch saves and removes one file at a time, stopping at the first
+// failure.
+func (s *Store) SaveBatch(chunks map[types.ChunkID]types.Chunk, gone []types.ChunkID) error {
+	for chunk_id, chunk := range chunks {
+		if err := s.Save(chunk_id, chunk); err != nil {
+			return err
+		}
+	}
+	for _, chunk_id := range gone {
+		if err := s.Remove(chunk_id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ===================== Chunk operations =====================
+
+// Clone detaches the slices of chunk so the copy can be read while the
+// original keeps changing.
+func Clone(chunk types.Chunk) types.Chunk {
+	chunk.PlayerList = append([]types.Player(nil), chunk.PlayerList...)
+	chunk.Cells = append([]types.Cube(nil), chunk.Cells...)
+	chunk.NPCs = append([]types.NPC(nil), chunk.NPCs...)
+	chunk.Items = append([]types.Item(nil), chunk.Items...)
+	chunk.Tombstones = append([]types.Tombstone(nil), chunk.Tombstones...)
+	return chunk
+}
+
+// RemoveCube deletes the cube with the given ID from chunk, returning it.
+// Cell order is not preserved.
+func RemoveCube(chunk *types.Chunk, cube_id string) (types.Cube, bool) {
+	for i, cube := range chunk.Cells {
+		if cube.ID == cube_id {
+			last := len(chunk.Cells) - 1
+			chunk.Cells[i] = chunk.Cells[last]
+			chunk.Cells = chunk.Cells[:last]
+			return cube, true
+		}
+	}
+	return types.Cube{}, false
+}
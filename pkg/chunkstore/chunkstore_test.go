@@ -0,0 +1,57 @@
+package chunkstore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+func TestStoreCodecs(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	old := types.ChunkID{World: "w1", IDX: 1, IDY: -2}
+	chunk := types.Chunk{World: "w1", IDX: 1, IDY: -2, Cells: []types.Cube{{ID: "c1", X: 3}}}
+
+	// a chunk saved before zstd
+	gz := &Store{dir: s.dir, codec: gzipCodec}
+	if err := gz.Save(old, chunk); err != nil {
+		t.Fatal(err)
+	}
+	index, err := s.Index()
+	if err != nil || index[old] != s.pathWith(old, gzipCodec) {
+		t.Fatalf("Index = %v, %v; want the gzip file of %v", index, err, old)
+	}
+	if got, err := s.Load(index[old]); err != nil || len(got.Cells) != 1 || got.Cells[0].ID != "c1" {
+		t.Fatalf("Load(%s) = %+v, %v", index[old], got, err)
+	}
+
+	// saving it again moves it to zstd
+	chunk.Cells = append(chunk.Cells, types.Cube{ID: "c2"})
+	if err := s.Save(old, chunk); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(s.pathWith(old, gzipCodec)); !os.IsNotExist(err) {
+		t.Errorf("gzip file left after a save: %v", err)
+	}
+	if filepath.Ext(s.Path(old)) != ".zst" {
+		t.Errorf("saved to %s", s.Path(old))
+	}
+	index, err = s.Index()
+	if err != nil || len(index) != 1 || index[old] != s.Path(old) {
+		t.Fatalf("Index after a save = %v, %v", index, err)
+	}
+	if got, err := s.Load(index[old]); err != nil || len(got.Cells) != 2 {
+		t.Fatalf("Load(%s) = %+v, %v", index[old], got, err)
+	}
+
+	if err := s.Remove(old); err != nil {
+		t.Fatal(err)
+	}
+	if index, err := s.Index(); err != nil || len(index) != 0 {
+		t.Errorf("Index after Remove = %v, %v", index, err)
+	}
+}
@@ -0,0 +1,161 @@
+package chunkstore
+
+import "github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+
+// ===================== Cube index =====================
+
+// DefaultGridSize is the edge length of one CubeIndex grid cell.
+const DefaultGridSize = 4
+
+type gridKey struct{ gx, gz int }
+
+// CubeIndex finds a chunk's cubes by ID in O(1) and by region through a
+// uniform grid, instead of scanning Cells. Cells stays the wire format and
+// the source of truth; the index is built from it and kept in step by Add
+// and Remove.
+//
+// An index remembers the Cells slice it describes. Code that replaces Cells
+// wholesale (a merge, a load from disk, a simulation step) makes Current
+// report false, and the owner rebuilds.
+type CubeIndex struct {
+	gridSize int
+	pos      map[string]int       // cube ID -> position in Cells
+	grid     map[gridKey][]string // cube IDs per grid cell
+	base     *types.Cube
+	n        int
+	dups     bool // Cells holds repeated IDs, which only a rebuild resolves
+	stale    bool
+}
+
+func BuildCubeIndex(cells []types.Cube, gridSize int) *CubeIndex {
+	if gridSize <= 0 {
+		gridSize = DefaultGridSize
+	}
+	ix := &CubeIndex{gridSize: gridSize, pos: make(map[string]int, len(cells)), grid: make(map[gridKey][]string)}
+	for i, cube := range cells {
+		if _, dup := ix.pos[cube.ID]; dup {
+			ix.dups = true
+			continue // the first copy wins, as with a linear scan
+		}
+		ix.pos[cube.ID] = i
+		ix.addToGrid(cube)
+	}
+	ix.track(cells)
+	return ix
+}
+
+// Current reports whether the index still describes cells.
+func (ix *CubeIndex) Current(cells []types.Cube) bool {
+	return !ix.stale && ix.base == sliceBase(cells) && ix.n == len(cells)
+}
+
+func (ix *CubeIndex) Find(cells []types.Cube, cube_id string) (types.Cube, bool) {
+	i, ok := ix.pos[cube_id]
+	if !ok {
+		return types.Cube{}, false
+	}
+	return cells[i], true
+}
+
+// Add appends cube to chunk's Cells and indexes it.
+func (ix *CubeIndex) Add(chunk *types.Chunk, cube types.Cube) {
+	chunk.Cells = append(chunk.Cells, cube)
+	if _, dup := ix.pos[cube.ID]; dup {
+		ix.dups = true
+	} else {
+		ix.pos[cube.ID] = len(chunk.Cells) - 1
+		ix.addToGrid(cube)
+	}
+	ix.track(chunk.Cells)
+}
+
+// Remove deletes the cube with the given ID from chunk, like RemoveCube, in
+// O(1). Cell order is not preserved.
+func (ix *CubeIndex) Remove(chunk *types.Chunk, cube_id string) (types.Cube, bool) {
+	i, ok := ix.pos[cube_id]
+	if !ok {
+		return types.Cube{}, false
+	}
+	cube := chunk.Cells[i]
+	last := len(chunk.Cells) - 1
+	chunk.Cells[i] = chunk.Cells[last]
+	chunk.Cells = chunk.Cells[:last]
+
+	delete(ix.pos, cube_id)
+	ix.removeFromGrid(cube)
+	if i < last {
+		moved := chunk.Cells[i]
+		if ix.pos[moved.ID] == last {
+			ix.pos[moved.ID] = i
+		}
+	}
+	ix.track(chunk.Cells)
+	// another copy of cube_id may now be the one to index
+	ix.stale = ix.dups
+	return cube, true
+}
+
+// InRegion returns the cubes with minX <= X <= maxX and minZ <= Z <= maxZ.
+func (ix *CubeIndex) InRegion(cells []types.Cube, minX, minZ, maxX, maxZ int) []types.Cube {
+	var out []types.Cube
+	for gx := floorDiv(minX, ix.gridSize); gx <= floorDiv(maxX, ix.gridSize); gx++ {
+		for gz := floorDiv(minZ, ix.gridSize); gz <= floorDiv(maxZ, ix.gridSize); gz++ {
+			for _, id := range ix.grid[gridKey{gx, gz}] {
+				cube := cells[ix.pos[id]]
+				if cube.X >= minX && cube.X <= maxX && cube.Z >= minZ && cube.Z <= maxZ {
+					out = append(out, cube)
+				}
+			}
+		}
+	}
+	return out
+}
+
+func (ix *CubeIndex) Len() int { return len(ix.pos) }
+
+func (ix *CubeIndex) keyOf(cube types.Cube) gridKey {
+	return gridKey{floorDiv(cube.X, ix.gridSize), floorDiv(cube.Z, ix.gridSize)}
+}
+
+func (ix *CubeIndex) addToGrid(cube types.Cube) {
+	key := ix.keyOf(cube)
+	ix.grid[key] = append(ix.grid[key], cube.ID)
+}
+
+func (ix *CubeIndex) removeFromGrid(cube types.Cube) {
+	key := ix.keyOf(cube)
+	ids := ix.grid[key]
+	for i, id := range ids {
+		if id == cube.ID {
+			ids[i] = ids[len(ids)-1]
+			ids = ids[:len(ids)-1]
+			break
+		}
+	}
+	if len(ids) == 0 {
+		delete(ix.grid, key)
+	} else {
+		ix.grid[key] = ids
+	}
+}
+
+func (ix *CubeIndex) track(cells []types.Cube) {
+	ix.base, ix.n = sliceBase(cells), len(cells)
+}
+
+// sliceBase identifies the array behind cells, so a Cells slice swapped for
+// a different one is noticed even when the length matches.
+func sliceBase(cells []types.Cube) *types.Cube {
+	if cap(cells) == 0 {
+		return nil
+	}
+	return &cells[:1][0]
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if (a%b != 0) && ((a < 0) != (b < 0)) {
+		q--
+	}
+	return q
+}
@@ -0,0 +1,147 @@
+package chunkstore
+
+import (
+	"sort"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Last-writer-wins merges =====================
+
+// MaxTombstonesPerChunk bounds the cube removals a chunk remembers; see
+// Chunk.TombstoneFloor for what forgetting one means.
+const MaxTombstonesPerChunk = 256
+
+// Bury records that the cube with this ID was removed from chunk at at.
+// The slice is copied, not changed in place, as clones of chunk share it.
+func Bury(chunk *types.Chunk, cube_id string, at types.HLC) {
+	tombstones := make([]types.Tombstone, 0, len(chunk.Tombstones)+1)
+	for _, t := range chunk.Tombstones {
+		if t.ID == cube_id {
+			at = max(at, t.HLC)
+			continue
+		}
+		tombstones = append(tombstones, t)
+	}
+	tombstones = append(tombstones, types.Tombstone{ID: cube_id, HLC: at})
+	if len(tombstones) > MaxTombstonesPerChunk {
+		sort.Slice(tombstones, func(i, j int) bool { return tombstones[i].HLC > tombstones[j].HLC })
+		for _, t := range tombstones[MaxTombstonesPerChunk:] {
+			chunk.TombstoneFloor = max(chunk.TombstoneFloor, t.HLC)
+		}
+		tombstones = tombstones[:MaxTombstonesPerChunk]
+	}
+	chunk.Tombstones = tombstones
+}
+
+// buriedAt is when the cube with this ID was last removed from chunk, as
+// far as chunk remembers.
+func buriedAt(chunk types.Chunk, cube_id string) types.HLC {
+	for _, t := range chunk.Tombstones {
+		if t.ID == cube_id {
+			return max(t.HLC, chunk.TombstoneFloor)
+		}
+	}
+	return chunk.TombstoneFloor
+}
+
+// LastHLC is the latest timestamp in chunk: of a cube, a player or a
+// removal.
+func LastHLC(chunk types.Chunk) types.HLC {
+	last := chunk.TombstoneFloor
+	for _, cube := range chunk.Cells {
+		last = max(last, cube.HLC)
+	}
+	for _, player := range chunk.PlayerList {
+		last = max(last, player.HLC)
+	}
+	for _, t := range chunk.Tombstones {
+		last = max(last, t.HLC)
+	}
+	return last
+}
+
+// MergeLWW merges src into dst, the later stamp winning each cube and
+// player. A cube dst lacks is only taken if it was written after dst last
+// removed it; a cube src removed after dst wrote it is removed. Players
+// dst lacks are added, and a tie goes to src. It returns how many cubes
+// and players of dst a later write changed.
+func MergeLWW(dst *types.Chunk, src types.Chunk) int {
+	changed := 0
+	at := make(map[string]int, len(dst.Cells))
+	for i, cube := range dst.Cells {
+		at[cube.ID] = i
+	}
+	cells := append([]types.Cube(nil), dst.Cells...)
+	for _, cube := range src.Cells {
+		if i, ok := at[cube.ID]; ok {
+			if cube.HLC > cells[i].HLC {
+				cells[i] = cube
+				changed++
+			}
+		} else if cube.HLC > buriedAt(*dst, cube.ID) {
+			at[cube.ID] = len(cells)
+			cells = append(cells, cube)
+			changed++
+		}
+	}
+	for _, t := range src.Tombstones {
+		if i, ok := at[t.ID]; ok && cells[i].HLC < t.HLC {
+			cells[i].ID = "" // dropped below
+			changed++
+		}
+		if t.HLC > buriedAt(*dst, t.ID) {
+			Bury(dst, t.ID, t.HLC)
+		}
+	}
+	kept := cells[:0]
+	for _, cube := range cells {
+		if cube.ID != "" {
+			kept = append(kept, cube)
+		}
+	}
+	dst.Cells = kept
+
+	listed := make(map[string]int, len(dst.PlayerList))
+	for i, player := range dst.PlayerList {
+		listed[player.ID] = i
+	}
+	list := append([]types.Player(nil), dst.PlayerList...)
+	for _, player := range src.PlayerList {
+		if i, ok := listed[player.ID]; ok {
+			if player.HLC > list[i].HLC {
+				changed++
+			}
+			if player.HLC >= list[i].HLC {
+				list[i] = player
+			}
+			continue
+		}
+		listed[player.ID] = len(list)
+		list = append(list, player)
+		changed++
+	}
+	dst.PlayerList = list
+	return changed
+}
+
+// Regresses reports whether storing incoming over have would roll a cube
+// back: replace it with an earlier write, or bring back one have removed
+// later than incoming wrote it. A copy that would comes from a server that
+// no longer owns the chunk, or arrived out of order.
+func Regresses(have, incoming types.Chunk) bool {
+	held := make(map[string]types.HLC, len(have.Cells))
+	for _, cube := range have.Cells {
+		held[cube.ID] = cube.HLC
+	}
+	for _, cube := range incoming.Cells {
+		if at, ok := held[cube.ID]; ok {
+			if cube.HLC < at {
+				return true
+			}
+		} else if removed := buriedAt(have, cube.ID); removed > 0 && cube.HLC < removed {
+			return true
+		}
+	}
+	return false
+}
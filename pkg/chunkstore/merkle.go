@@ -0,0 +1,120 @@
+package chunkstore
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"hash"
+	"hash/fnv"
+	"sort"
+	"strconv"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Merkle digests =====================
+
+// MerkleBuckets is how many leaves a chunk's cubes are hashed into. A copy
+// that differs in one cube is repaired by sending 1/MerkleBuckets of them.
+const MerkleBuckets = 32
+
+// CubeBucket is the leaf of a ChunkDigest the cube with this ID hashes into.
+func CubeBucket(cube_id string) int {
+	h := fnv.New32a()
+	h.Write([]byte(cube_id))
+	return int(h.Sum32() % MerkleBuckets)
+}
+
+// Digest returns the Merkle tree of chunk. Neither the order of its cubes
+// nor its owner, dirty flag and tombstones matter.
+func Digest(chunk types.Chunk) types.ChunkDigest {
+	leaves := make([][]types.Cube, MerkleBuckets)
+	for _, cube := range chunk.Cells {
+		b := CubeBucket(cube.ID)
+		leaves[b] = append(leaves[b], cube)
+	}
+
+	d := types.ChunkDigest{Buckets: make([]uint64, MerkleBuckets)}
+	root := sha256.New()
+	for i, cubes := range leaves {
+		sort.Slice(cubes, func(a, b int) bool { return cubes[a].ID < cubes[b].ID })
+		leaf := sha256.New()
+		for _, cube := range cubes {
+			writeField(leaf, cube.ID)
+			writeField(leaf, strconv.Itoa(cube.X))
+			writeField(leaf, strconv.Itoa(cube.Z))
+			writeField(leaf, strconv.Itoa(cube.Height))
+			writeField(leaf, cube.Color)
+			binary.Write(leaf, binary.BigEndian, uint64(cube.HLC))
+		}
+		d.Buckets[i] = sum64(leaf)
+		binary.Write(root, binary.BigEndian, d.Buckets[i])
+	}
+
+	head := sha256.New()
+	json.NewEncoder(head).Encode(struct {
+		Data    string
+		Players []types.Player
+		NPCs    []types.NPC
+		Items   []types.Item
+	}{chunk.Data, chunk.PlayerList, chunk.NPCs, chunk.Items})
+	d.Head = sum64(head)
+	binary.Write(root, binary.BigEndian, d.Head)
+	d.Root = sum64(root)
+	return d
+}
+
+// writeField writes s length-prefixed, so fields cannot run into each other.
+func writeField(h hash.Hash, s string) {
+	binary.Write(h, binary.BigEndian, uint32(len(s)))
+	h.Write([]byte(s))
+}
+
+func sum64(h hash.Hash) uint64 {
+	return binary.BigEndian.Uint64(h.Sum(nil))
+}
+
+// DivergentBuckets lists the leaves in which have and want differ, all of
+// them if either has no leaves (a replica with no copy at all).
+func DivergentBuckets(have, want types.ChunkDigest) []int {
+	var buckets []int
+	for i := 0; i < MerkleBuckets; i++ {
+		if len(have.Buckets) != MerkleBuckets || len(want.Buckets) != MerkleBuckets || have.Buckets[i] != want.Buckets[i] {
+			buckets = append(buckets, i)
+		}
+	}
+	return buckets
+}
+
+// CubesIn returns the cubes of chunk that hash into buckets.
+func CubesIn(chunk types.Chunk, buckets []int) []types.Cube {
+	in := bucketSet(buckets)
+	cubes := []types.Cube{}
+	for _, cube := range chunk.Cells {
+		if in[CubeBucket(cube.ID)] {
+			cubes = append(cubes, cube)
+		}
+	}
+	return cubes
+}
+
+// PatchCubes replaces the cubes of chunk that hash into buckets with cubes,
+// which CubesIn took from another copy.
+func PatchCubes(chunk *types.Chunk, buckets []int, cubes []types.Cube) {
+	in := bucketSet(buckets)
+	kept := make([]types.Cube, 0, len(chunk.Cells)+len(cubes))
+	for _, cube := range chunk.Cells {
+		if !in[CubeBucket(cube.ID)] {
+			kept = append(kept, cube)
+		}
+	}
+	chunk.Cells = append(kept, cubes...)
+}
+
+func bucketSet(buckets []int) map[int]bool {
+	in := make(map[int]bool, len(buckets))
+	for _, b := range buckets {
+		in[b] = true
+	}
+	return in
+}
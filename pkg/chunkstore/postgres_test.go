@@ -0,0 +1,54 @@
+package chunkstore
+
+import (
+	"testing"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+func TestParsePostgresURL(t *testing.T) {
+	t.Setenv("PGPASSWORD", "")
+	tests := []struct {
+		url  string
+		name string // "" for a URL refused
+	}{
+		{"postgres://game:pw@db:5433/worlds?sslmode=require", "postgres://game@db:5433/worlds"},
+		{"postgresql://game@db/game", "postgres://game@db:5432/game"},
+		{"mysql://game@db/x", ""},
+		{"host=db user=game", ""},
+		{"postgres://game@db/x?sslmode=sometimes", ""},
+	}
+	for _, tt := range tests {
+		cfg, err := parsePostgresURL(tt.url)
+		if tt.name == "" {
+			if err == nil {
+				t.Errorf("parsePostgresURL(%q) took it", tt.url)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePostgresURL(%q): %v", tt.url, err)
+			continue
+		}
+		if got := pgName(cfg); got != tt.name {
+			t.Errorf("parsePostgresURL(%q) names %s, want %s", tt.url, got, tt.name)
+		}
+		if app := cfg.ConnConfig.RuntimeParams["application_name"]; app != "gameserver" {
+			t.Errorf("parsePostgresURL(%q): application_name %q", tt.url, app)
+		}
+	}
+}
+
+func TestPgPath(t *testing.T) {
+	p := &Postgres{}
+	chunk_id := types.ChunkID{World: "w1", Level: 2, IDX: -3, IDY: 4}
+	got, err := parsePgPath(p.Path(chunk_id))
+	if err != nil || got != chunk_id {
+		t.Errorf("parsePgPath(%q) = %+v, %v", p.Path(chunk_id), got, err)
+	}
+	for _, bad := range []string{"w1/2/3", "w1/2/x/4", "w1/2/3/4/5"} {
+		if _, err := parsePgPath(bad); err == nil {
+			t.Errorf("parsePgPath(%q) took it", bad)
+		}
+	}
+}
@@ -0,0 +1,334 @@
+package chunkstore
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Versions =====================
+
+// MaxTombstones bounds the removed cubes, players, NPCs and items a Versions
+// remembers. A reader whose version predates the oldest one forgotten gets
+// the whole chunk again.
+const MaxTombstones = 1024
+
+// Versions tracks when each cube, player, NPC and item of one chunk last
+// changed,
+// so a reader that has an earlier version can be sent just the difference.
+// It compares the chunk with what it saw at the previous Observe instead of
+// hooking every write, so whatever changed the chunk, the next Observe
+// notices. A Versions is safe for concurrent use: Delta may run while
+// another goroutine Observes.
+type Versions struct {
+	mu      sync.RWMutex
+	epoch   uint64
+	seq     uint64
+	floor   uint64 // oldest Seq a delta can start from
+	cubes   map[string]cubeStamp
+	players map[string]playerStamp
+	npcs    map[string]npcStamp
+	items   map[string]itemStamp
+	gone    map[string]uint64 // removed cube, player, NPC or item (prefixed) -> Seq
+}
+
+type cubeStamp struct {
+	cube types.Cube
+	seq  uint64
+}
+
+type playerStamp struct {
+	player types.Player
+	seq    uint64
+}
+
+type npcStamp struct {
+	npc types.NPC
+	seq uint64
+}
+
+type itemStamp struct {
+	item types.Item
+	seq  uint64
+}
+
+// NewVersions starts tracking a chunk under a fresh epoch.
+func NewVersions() *Versions {
+	return &Versions{
+		epoch:   rand.Uint64(),
+		cubes:   make(map[string]cubeStamp),
+		players: make(map[string]playerStamp),
+		npcs:    make(map[string]npcStamp),
+		items:   make(map[string]itemStamp),
+		gone:    make(map[string]uint64),
+	}
+}
+
+// tombstone keys keep cube, player, NPC and item IDs apart
+const (
+	goneCube   = "c:"
+	gonePlayer = "p:"
+	goneNPC    = "n:"
+	goneItem   = "i:"
+)
+
+// Observe records chunk's current state and returns its version, which is
+// only bumped if something changed since the last call.
+func (v *Versions) Observe(chunk types.Chunk) types.ChunkVersion {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	next := v.seq + 1
+	changed := false
+
+	seen := make(map[string]bool, len(chunk.Cells))
+	for _, cube := range chunk.Cells {
+		seen[cube.ID] = true
+		if stamp, ok := v.cubes[cube.ID]; ok && stamp.cube == cube {
+			continue
+		}
+		v.cubes[cube.ID] = cubeStamp{cube: cube, seq: next}
+		delete(v.gone, goneCube+cube.ID)
+		changed = true
+	}
+	if len(seen) != len(v.cubes) {
+		for id := range v.cubes {
+			if !seen[id] {
+				delete(v.cubes, id)
+				v.gone[goneCube+id] = next
+				changed = true
+			}
+		}
+	}
+
+	seen = make(map[string]bool, len(chunk.PlayerList))
+	for _, player := range chunk.PlayerList {
+		seen[player.ID] = true
+		if stamp, ok := v.players[player.ID]; ok && samePlayer(stamp.player, player) {
+			continue
+		}
+		v.players[player.ID] = playerStamp{player: player, seq: next}
+		delete(v.gone, gonePlayer+player.ID)
+		changed = true
+	}
+	if len(seen) != len(v.players) {
+		for id := range v.players {
+			if !seen[id] {
+				delete(v.players, id)
+				v.gone[gonePlayer+id] = next
+				changed = true
+			}
+		}
+	}
+
+	seen = make(map[string]bool, len(chunk.NPCs))
+	for _, npc := range chunk.NPCs {
+		seen[npc.ID] = true
+		if stamp, ok := v.npcs[npc.ID]; ok && stamp.npc == npc {
+			continue
+		}
+		v.npcs[npc.ID] = npcStamp{npc: npc, seq: next}
+		delete(v.gone, goneNPC+npc.ID)
+		changed = true
+	}
+	if len(seen) != len(v.npcs) {
+		for id := range v.npcs {
+			if !seen[id] {
+				delete(v.npcs, id)
+				v.gone[goneNPC+id] = next
+				changed = true
+			}
+		}
+	}
+
+	seen = make(map[string]bool, len(chunk.Items))
+	for _, item := range chunk.Items {
+		seen[item.ID] = true
+		if stamp, ok := v.items[item.ID]; ok && stamp.item == item {
+			continue
+		}
+		v.items[item.ID] = itemStamp{item: item, seq: next}
+		delete(v.gone, goneItem+item.ID)
+		changed = true
+	}
+	if len(seen) != len(v.items) {
+		for id := range v.items {
+			if !seen[id] {
+				delete(v.items, id)
+				v.gone[goneItem+id] = next
+				changed = true
+			}
+		}
+	}
+
+	if changed {
+		v.seq = next
+		v.prune()
+	}
+	return types.ChunkVersion{Epoch: v.epoch, Seq: v.seq}
+}
+
+// Delta returns what changed after since, up to the last Observe, or false
+// if since is not a version this tracker can diff from.
+func (v *Versions) Delta(since types.ChunkVersion) (types.ChunkDelta, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	if since.Epoch != v.epoch || since.Seq < v.floor || since.Seq > v.seq {
+		return types.ChunkDelta{}, false
+	}
+	delta := types.ChunkDelta{From: since, To: types.ChunkVersion{Epoch: v.epoch, Seq: v.seq}}
+	for _, stamp := range v.cubes {
+		if stamp.seq > since.Seq {
+			delta.Cubes = append(delta.Cubes, stamp.cube)
+		}
+	}
+	for _, stamp := range v.players {
+		if stamp.seq > since.Seq {
+			delta.Players = append(delta.Players, stamp.player)
+		}
+	}
+	for _, stamp := range v.npcs {
+		if stamp.seq > since.Seq {
+			delta.NPCs = append(delta.NPCs, stamp.npc)
+		}
+	}
+	for _, stamp := range v.items {
+		if stamp.seq > since.Seq {
+			delta.Items = append(delta.Items, stamp.item)
+		}
+	}
+	for key, seq := range v.gone {
+		if seq <= since.Seq {
+			continue
+		}
+		if id, ok := cutPrefix(key, goneCube); ok {
+			delta.RemovedCubes = append(delta.RemovedCubes, id)
+		} else if id, ok := cutPrefix(key, gonePlayer); ok {
+			delta.RemovedPlayers = append(delta.RemovedPlayers, id)
+		} else if id, ok := cutPrefix(key, goneNPC); ok {
+			delta.RemovedNPCs = append(delta.RemovedNPCs, id)
+		} else if id, ok := cutPrefix(key, goneItem); ok {
+			delta.RemovedItems = append(delta.RemovedItems, id)
+		}
+	}
+	return delta, true
+}
+
+// prune forgets the oldest tombstones past MaxTombstones, raising floor.
+// Must be called with v.mu held.
+func (v *Versions) prune() {
+	if len(v.gone) <= MaxTombstones {
+		return
+	}
+	seqs := make([]uint64, 0, len(v.gone))
+	for _, seq := range v.gone {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+	// keep half, so pruning is not needed again on the next change
+	cut := seqs[len(seqs)-MaxTombstones/2]
+	for key, seq := range v.gone {
+		if seq < cut {
+			delete(v.gone, key)
+		}
+	}
+	v.floor = cut - 1
+}
+
+// samePlayer reports whether a and b are the same state of a player; the
+// inventory keeps players from being compared with ==.
+func samePlayer(a, b types.Player) bool {
+	return reflect.DeepEqual(a, b)
+}
+
+func cutPrefix(s, prefix string) (string, bool) {
+	if len(s) < len(prefix) || s[:len(prefix)] != prefix {
+		return s, false
+	}
+	return s[len(prefix):], true
+}
+
+// ApplyDelta brings a reader's copy of a chunk up to d.To. Cell, player, NPC
+// and item order is not preserved.
+func ApplyDelta(chunk *types.Chunk, d types.ChunkDelta) {
+	cells := make(map[string]int, len(chunk.Cells))
+	for i, cube := range chunk.Cells {
+		cells[cube.ID] = i
+	}
+	for _, cube := range d.Cubes {
+		if i, ok := cells[cube.ID]; ok {
+			chunk.Cells[i] = cube
+		} else {
+			cells[cube.ID] = len(chunk.Cells)
+			chunk.Cells = append(chunk.Cells, cube)
+		}
+	}
+	for _, id := range d.RemovedCubes {
+		RemoveCube(chunk, id)
+	}
+
+	for _, player := range d.Players {
+		found := false
+		for i := range chunk.PlayerList {
+			if chunk.PlayerList[i].ID == player.ID {
+				chunk.PlayerList[i], found = player, true
+				break
+			}
+		}
+		if !found {
+			chunk.PlayerList = append(chunk.PlayerList, player)
+		}
+	}
+	for _, id := range d.RemovedPlayers {
+		for i := range chunk.PlayerList {
+			if chunk.PlayerList[i].ID == id {
+				chunk.PlayerList = append(chunk.PlayerList[:i], chunk.PlayerList[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, npc := range d.NPCs {
+		found := false
+		for i := range chunk.NPCs {
+			if chunk.NPCs[i].ID == npc.ID {
+				chunk.NPCs[i], found = npc, true
+				break
+			}
+		}
+		if !found {
+			chunk.NPCs = append(chunk.NPCs, npc)
+		}
+	}
+	for _, id := range d.RemovedNPCs {
+		for i := range chunk.NPCs {
+			if chunk.NPCs[i].ID == id {
+				chunk.NPCs = append(chunk.NPCs[:i], chunk.NPCs[i+1:]...)
+				break
+			}
+		}
+	}
+
+	for _, item := range d.Items {
+		found := false
+		for i := range chunk.Items {
+			if chunk.Items[i].ID == item.ID {
+				chunk.Items[i], found = item, true
+				break
+			}
+		}
+		if !found {
+			chunk.Items = append(chunk.Items, item)
+		}
+	}
+	for _, id := range d.RemovedItems {
+		for i := range chunk.Items {
+			if chunk.Items[i].ID == id {
+				chunk.Items = append(chunk.Items[:i], chunk.Items[i+1:]...)
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package chunkstore
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== World files =====================
+
+// A world file carries a world, or a range of its chunks, out of a cluster:
+// for backups, for moving a world between clusters and for sharing builds.
+// It is plain JSON so it can be inspected and diffed:
+//
+//	{
+//	  "format": "dgs-world",
+//	  "version": 1,
+//	  "exported_at": "2026-01-02T15:04:05Z",
+//	  "source": "10.0.0.5:9000",
+//	  "world": {"name": "default", "chunk_size": 32, "tick_ms": 50},
+//	  "chunks": [
+//	    {"id_x": 0, "id_y": 0, "data": "...", "cells": [{"cube_id": "...", "x": 1, "z": 2, "height": 3, "color": "#aa0000"}]}
+//	  ]
+//	}
+//
+// Chunks are sorted by (level, id_x, id_y). Live state — server_ip, player_list and
+// is_dirty — is cleared on export; the importing server takes ownership.
+// Readers reject other formats and versions newer than they know.
+const (
+	WorldFileFormat  = "dgs-world"
+	WorldFileVersion = 1
+)
+
+type WorldFile struct {
+	Format     string            `json:"format"`
+	Version    int               `json:"version"`
+	ExportedAt time.Time         `json:"exported_at"`
+	Source     string            `json:"source,omitempty"`
+	World      types.WorldConfig `json:"world"`
+	Chunks     []types.Chunk     `json:"chunks"`
+}
+
+// ChunkRange selects chunks with MinX <= IDX <= MaxX and MinY <= IDY <= MaxY.
+// A nil *ChunkRange selects every chunk.
+type ChunkRange struct {
+	MinX, MinY, MaxX, MaxY int
+}
+
+func (r *ChunkRange) Contains(id types.ChunkID) bool {
+	return r == nil || (id.IDX >= r.MinX && id.IDX <= r.MaxX && id.IDY >= r.MinY && id.IDY <= r.MaxY)
+}
+
+// NewWorldFile builds a world file from chunks, stripping their live state.
+func NewWorldFile(world types.WorldConfig, source string, chunks []types.Chunk) WorldFile {
+	out := make([]types.Chunk, 0, len(chunks))
+	for _, chunk := range chunks {
+		chunk = Clone(chunk)
+		chunk.ServerIP = ""
+		chunk.PlayerList = nil
+		chunk.NPCs = nil
+		chunk.Items = nil
+		chunk.IsDirty = false
+		out = append(out, chunk)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Level != out[j].Level {
+			return out[i].Level < out[j].Level
+		}
+		if out[i].IDX != out[j].IDX {
+			return out[i].IDX < out[j].IDX
+		}
+		return out[i].IDY < out[j].IDY
+	})
+	return WorldFile{
+		Format:     WorldFileFormat,
+		Version:    WorldFileVersion,
+		ExportedAt: time.Now().UTC(),
+		Source:     source,
+		World:      world,
+		Chunks:     out,
+	}
+}
+
+func (f WorldFile) Write(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(f)
+}
+
+// ReadWorldFile decodes and validates a world file.
+func ReadWorldFile(r io.Reader) (WorldFile, error) {
+	var f WorldFile
+	if err := json.NewDecoder(r).Decode(&f); err != nil {
+		return f, err
+	}
+	if f.Format != WorldFileFormat {
+		return f, fmt.Errorf("not a world file (format %q)", f.Format)
+	}
+	if f.Version < 1 || f.Version > WorldFileVersion {
+		return f, fmt.Errorf("unsupported world file version %d (this build reads up to %d)", f.Version, WorldFileVersion)
+	}
+	seen := make(map[types.ChunkID]bool, len(f.Chunks))
+	for _, chunk := range f.Chunks {
+		id := chunk.ID()
+		if seen[id] {
+			return f, fmt.Errorf("chunk [%d,%d] appears twice", id.IDX, id.IDY)
+		}
+		seen[id] = true
+	}
+	return f, nil
+}
@@ -0,0 +1,43 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Achievements =====================
+
+// Central awards achievements as the player's stats reach them, and the
+// server holding the player pushes each one (PUSH_ACHIEVEMENT) shortly
+// after. Achievements hands out those pushed since it was last called; an
+// award pushed while the player was between servers is not repeated, but
+// is kept in their profile (central's GET /achievements?player_id=).
+
+// maxAwards bounds the awards kept until Achievements is called.
+const maxAwards = 32
+
+// awardBox keeps the awards pushed to the player, under a lock of its own.
+type awardBox struct {
+	mu   sync.Mutex
+	list []types.Award
+}
+
+func (b *awardBox) add(awards []types.Award) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.list = append(b.list, awards...)
+	if len(b.list) > maxAwards {
+		b.list = append([]types.Award(nil), b.list[len(b.list)-maxAwards:]...)
+	}
+}
+
+// Achievements returns the achievements the player earned since the last
+// call, oldest first.
+func (c *Client) Achievements() []types.Award {
+	c.awards.mu.Lock()
+	defer c.awards.mu.Unlock()
+	list := c.awards.list
+	c.awards.list = nil
+	return list
+}
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// maxAnnouncements bounds the announcements kept until Announcements is
+// called.
+const maxAnnouncements = 32
+
+// announceBox keeps the announcements central had pushed to the player,
+// under a lock of its own taken after Client.mu, never before.
+type announceBox struct {
+	mu   sync.Mutex
+	list []types.Announcement
+	seen map[string]bool
+}
+
+func (b *announceBox) add(announcement types.Announcement) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	// a player moving between servers may be sent one twice
+	if b.seen[announcement.ID] {
+		return
+	}
+	if b.seen == nil {
+		b.seen = make(map[string]bool)
+	}
+	b.seen[announcement.ID] = true
+	b.list = append(b.list, announcement)
+	if len(b.list) > maxAnnouncements {
+		b.list = append([]types.Announcement(nil), b.list[len(b.list)-maxAnnouncements:]...)
+	}
+}
+
+// Announcements returns the announcements pushed to the player since the
+// last call, oldest first. Like chat, they are picked up whenever the client
+// waits for a reply.
+func (c *Client) Announcements() []types.Announcement {
+	c.announced.mu.Lock()
+	defer c.announced.mu.Unlock()
+	list := c.announced.list
+	c.announced.list = nil
+	return list
+}
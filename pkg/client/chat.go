@@ -0,0 +1,107 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Chat =====================
+
+// Chat sends a message to the players around this one. Their messages are
+// pushed to the player's connection (PUSH_CHAT) and picked up whenever the
+// client waits for a reply, so a client that polls or moves regularly gets
+// them within a request; the replies to Chat also bring any kept messages
+// of the chunk not yet seen, which catches up on pushes that were lost.
+// Whisper sends to one player on any server, and their whispers arrive the
+// same way, with To set. Messages returns what has arrived, oldest first.
+
+// maxInbox bounds the messages kept until Messages is called.
+const maxInbox = 256
+
+// chatBox has a lock of its own so Messages never waits on a round trip; it
+// is taken after Client.mu, never before.
+type chatBox struct {
+	mu    sync.Mutex
+	inbox []types.ChatMessage
+	seen  map[types.ChunkID]uint64 // last message seq per chunk
+}
+
+// add keeps the messages not seen yet.
+func (b *chatBox) add(list []types.ChatMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.seen == nil {
+		b.seen = make(map[types.ChunkID]uint64)
+	}
+	for _, msg := range list {
+		if msg.To == "" {
+			if msg.Seq <= b.seen[msg.ChunkID] {
+				continue
+			}
+			b.seen[msg.ChunkID] = msg.Seq
+		}
+		b.inbox = append(b.inbox, msg)
+	}
+	if len(b.inbox) > maxInbox {
+		b.inbox = append([]types.ChatMessage(nil), b.inbox[len(b.inbox)-maxInbox:]...)
+	}
+}
+
+func (b *chatBox) since(chunk_id types.ChunkID) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.seen[chunk_id]
+}
+
+// reset forgets the numbering, for a new server, which numbers its chunks'
+// messages afresh.
+func (b *chatBox) reset() {
+	b.mu.Lock()
+	b.seen = nil
+	b.mu.Unlock()
+}
+
+// Chat sends text to the players around. An empty text sends nothing and
+// just fetches the chunk's messages not seen yet.
+func (c *Client) Chat(text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := types.Request{Type: types.ReqChat, Player: c.player, ChunkID: c.chunk, Text: text, ChatSince: c.chat.since(c.chunk)}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return &RefusedError{Res: res}
+	}
+	c.chat.add(res.Chat)
+	return nil
+}
+
+// Whisper sends text to the player to alone, on whichever server they are.
+func (c *Client) Whisper(to, text string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := types.Request{Type: types.ReqWhisper, Player: c.player, ChunkID: c.chunk, PlayerID: to, Text: text}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	if !res.Success {
+		return &RefusedError{Res: res}
+	}
+	return nil
+}
+
+// Messages returns the chat messages and whispers that arrived since the last call,
+// oldest first.
+func (c *Client) Messages() []types.ChatMessage {
+	c.chat.mu.Lock()
+	defer c.chat.mu.Unlock()
+	list := c.chat.inbox
+	c.chat.inbox = nil
+	return list
+}
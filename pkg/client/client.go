@@ -0,0 +1,746 @@
+// Package client is what a game frontend links to play on the cluster. A
+// Client joins through the central server and then talks UDP to whichever
+// game server owns the player's chunk: it follows OK_REDIRECT and
+// ERR_NOT_OWNER replies to the new owner, resends requests whose reply was
+// lost, and keeps subscribers fed with the player's chunk as the player
+// crosses chunks and chunks move between servers.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ErrKicked is returned for every request once a server has kicked the
+// player.
+var ErrKicked = errors.New("kicked from the game server")
+
+// RefusedError is a request a server answered but turned down, such as
+// ERR_CHUNK_FULL; Res has the details (queue position, alternative chunk).
+type RefusedError struct {
+	Res *types.Response
+}
+
+func (e *RefusedError) Error() string {
+	return fmt.Sprintf("refused (%s): %s", e.Res.Code, e.Res.Message)
+}
+
+// Update is the player's chunk as of one poll.
+type Update struct {
+	ChunkID types.ChunkID
+	Server  string
+	// Chunk is the whole chunk as the client now knows it.
+	Chunk types.Chunk
+	// Delta is what changed, when the server sent only the changes.
+	Delta *types.ChunkDelta
+	// Party is where the player's party members are, in any chunk.
+	Party []types.Player
+	// UpdateMs is how often the chunk's changes are worth polling for, as
+	// the server judges from its activity (see InterpolationDelay).
+	UpdateMs int64
+}
+
+// migratingBackoff is how long to wait before retrying at the new owner of
+// a chunk that is still moving there.
+const migratingBackoff = 200 * time.Millisecond
+
+// maxViews bounds the chunks a client keeps copies of: the player's, its
+// prefetched neighbours and some it walked through.
+const maxViews = 32
+
+// chunkView is the client's copy of a chunk at Version, as Owner served it
+// at Fetched.
+type chunkView struct {
+	Chunk   types.Chunk
+	Version *types.ChunkVersion
+	Owner   string
+	Fetched time.Time
+}
+
+// A Client plays one player. Its methods may be called from several
+// goroutines; requests go out one at a time.
+type Client struct {
+	mu        sync.Mutex
+	network   netproto.Network
+	conn      netproto.Transport // plain, or sealed once Join got a seal
+	plain     netproto.Transport
+	sealer    *netproto.Sealer // set once Join got a seal
+	player    types.Player
+	chunk     types.ChunkID
+	server    string
+	replicas  []string // read replicas of chunk, if it has any
+	chunkSize int
+	splits    map[types.ChunkID]bool // chunks split into sub-chunks
+	kicked    bool
+	token     string // session token from /join
+	central   string // URL Join was given
+	clock     clockSync
+	// chunks seen lately with their versions, so GET_DATA can skip an
+	// unchanged chunk and GET_UPDATES fetch only the changes
+	views map[types.ChunkID]*chunkView
+
+	// Timeout bounds each attempt at a request.
+	Timeout time.Duration
+	// Retries is how many more times a request that timed out is sent.
+	Retries int
+	// MaxRedirects bounds the owners one request is chased through.
+	MaxRedirects int
+	// PrefetchMargin is how close to a chunk edge, in cells, the player
+	// gets before the neighbouring chunks are prefetched; 0 disables it.
+	PrefetchMargin int
+	// OnResponse, if set, sees every attempt with its round-trip time.
+	OnResponse func(req types.Request, res *types.Response, rtt time.Duration, err error)
+	// LogStats has ReportStats log the stats it sends.
+	LogStats bool
+	// Seal has Join ask for a session key to encrypt the player's UDP
+	// traffic with (see Sealed); a cluster with a seal secret gives every
+	// player one anyway. A key lasts a day, after which requests fail with
+	// netproto.ErrSealExpired until the player Joins (or Resumes) again.
+	Seal bool
+
+	prefetch  prefetcher
+	stats     statsCounter
+	chat      chatBox
+	announced announceBox
+	matched   matchBox
+	hits      hitBox
+	lives     lifeBox
+	awards    awardBox
+	predict   predictor
+}
+
+// New returns a client for playerID at (0, 0), talking to the default game
+// server until Join names one.
+func New(network netproto.Network, playerID string) (*Client, error) {
+	conn, err := network.Listen("")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Client{
+		network:        network,
+		conn:           conn,
+		plain:          conn,
+		player:         types.Player{ID: playerID},
+		server:         "127.0.0.1:9000",
+		chunkSize:      types.DefaultChunkSize,
+		Timeout:        2 * time.Second,
+		Retries:        2,
+		MaxRedirects:   3,
+		PrefetchMargin: 8,
+	}, nil
+}
+
+// Player returns the player's current state.
+func (c *Client) Player() types.Player {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.player
+}
+
+// Server returns the game server the player is talking to.
+func (c *Client) Server() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.server
+}
+
+// Chunk returns the chunk the player is in.
+func (c *Client) Chunk() types.ChunkID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chunk
+}
+
+// Sealed reports whether the player's UDP traffic is encrypted, which it is
+// in a cluster with a seal secret.
+func (c *Client) Sealed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.conn != c.plain
+}
+
+// Kicked reports whether a server has kicked the player.
+func (c *Client) Kicked() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.kicked
+}
+
+// ChunkSize returns the world's chunk edge length.
+func (c *Client) ChunkSize() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.chunkSize
+}
+
+// ChunkAt returns the chunk holding (x, y), down to any split sub-chunk the
+// client has heard of.
+func (c *Client) ChunkAt(x, y int) types.ChunkID {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return types.ResolveChunk(c.player.World, x, y, c.chunkSize, c.splits)
+}
+
+// Place sets the player's position without telling any server, e.g. to
+// choose where to spawn before Join.
+func (c *Client) Place(x, y int) {
+	c.mu.Lock()
+	c.player.PosX, c.player.PosY = x, y
+	c.syncPredicted()
+	c.mu.Unlock()
+}
+
+// SetWorld chooses the world Join enters the player in; it is the shared
+// world unless set.
+func (c *Client) SetWorld(world string) {
+	c.mu.Lock()
+	c.player.World = world
+	c.mu.Unlock()
+}
+
+// Join asks the central server which game server to use and enters the
+// chunk the player spawns in: where their profile says they last left, if
+// central has one. With a session token from an earlier Join (see Resume)
+// the player picks up where they were instead.
+func (c *Client) Join(centralURL string) error {
+	c.mu.Lock()
+	req := types.PlayerJoinRequest{PlayerID: c.player.ID, PosX: c.player.PosX, PosY: c.player.PosY, World: c.player.World, SessionToken: c.token, Seal: c.Seal}
+	c.mu.Unlock()
+
+	b, _ := json.Marshal(req)
+	httpResp, err := http.Post(centralURL+"/join", "application/json", bytes.NewReader(b))
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	var res types.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&res); err != nil {
+		return fmt.Errorf("join: %w", err)
+	}
+	if res.Code != types.CodeRedirect {
+		return fmt.Errorf("join refused (%s): %s", res.Code, res.Message)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	// worlds in experiment mode may use a non-default chunk size
+	if res.ChunkSize > 0 {
+		c.chunkSize = res.ChunkSize
+	}
+	c.token = res.SessionToken
+	c.central = centralURL
+	c.conn, c.sealer = c.plain, nil
+	if res.Seal != nil {
+		sealer, err := netproto.NewSealer(*res.Seal)
+		if err != nil {
+			return fmt.Errorf("join: %w", err)
+		}
+		c.conn, c.sealer = sealer.Transport(c.plain), sealer
+	}
+	c.prefetch.sealed = nil
+	c.learnSplits(res.Splits)
+	c.switchServer(res.RedirectIP)
+	if res.Player != nil {
+		return c.resume(*res.Player)
+	}
+	if res.Profile != nil {
+		// a new session picks up where the last one ended
+		last := res.Profile.Player
+		c.player.PosX, c.player.PosY, c.player.World = last.PosX, last.PosY, last.World
+		c.player.Health, c.player.Inventory = last.Health, last.Inventory
+		c.syncPredicted()
+		log.Printf("📂 %s back at (%d, %d) from their profile", c.player.ID, last.PosX, last.PosY)
+	}
+	return c.enter()
+}
+
+// postCentral posts body, with the player's ID as player_id, to path on
+// the central server Join went through. A refusal comes back as a
+// RefusedError.
+func (c *Client) postCentral(path string, body map[string]string) (*types.Response, error) {
+	c.mu.Lock()
+	central, player_id := c.central, c.player.ID
+	c.mu.Unlock()
+	if central == "" {
+		return nil, fmt.Errorf("%s needs Join first", path)
+	}
+
+	body["player_id"] = player_id
+	b, _ := json.Marshal(body)
+	httpResp, err := http.Post(central+path, "application/json", bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer httpResp.Body.Close()
+	var res types.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&res); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if !res.Success {
+		return &res, &RefusedError{Res: &res}
+	}
+	return &res, nil
+}
+
+// Resume joins as the player of an earlier session, such as one from before
+// the client restarted: same position and chunk, with the server sending to
+// this client's address from now on. An expired session joins afresh.
+func (c *Client) Resume(centralURL, token string) error {
+	c.mu.Lock()
+	c.token = token
+	c.mu.Unlock()
+	return c.Join(centralURL)
+}
+
+// SessionToken returns the token to Resume this player's session with.
+func (c *Client) SessionToken() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.token
+}
+
+// resume sends RESUME for the player central found on c.server, falling
+// back to entering their last chunk afresh. Must be called with mu held.
+func (c *Client) resume(last types.Player) error {
+	c.player.PosX, c.player.PosY, c.player.World = last.PosX, last.PosY, last.World
+	c.syncPredicted()
+	req := types.Request{Type: types.ReqResume, PlayerID: c.player.ID, SessionToken: c.token}
+	res, err := c.send(c.server, req)
+	if err != nil || !res.Success || res.Player == nil {
+		if err == nil {
+			log.Printf("⚠️  Resume of %s refused (%s): %s", c.player.ID, res.Code, res.Message)
+		}
+		return c.enter()
+	}
+
+	c.player.PosX, c.player.PosY = res.Player.PosX, res.Player.PosY
+	c.syncPredicted()
+	if res.ChunkSize > 0 {
+		c.chunkSize = res.ChunkSize
+	}
+	c.chunk = res.Player.ChunkID
+	c.replicas = res.Replicas
+	log.Printf("🔁 %s resumed in chunk [%d,%d] on %s", c.player.ID, c.chunk.IDX, c.chunk.IDY, c.server)
+	return nil
+}
+
+// Enter (re)joins the chunk at the player's position with GET_DATA.
+func (c *Client) Enter() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.enter()
+}
+
+// Move walks the player to (x, y), entering the chunk there first if it is
+// a different one. If the move is refused the player stays where they were.
+func (c *Client) Move(x, y int) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.move(x, y, 0)
+	c.syncPredicted()
+	return err
+}
+
+// move is Move for input seq, 0 if the move is not a numbered input. The
+// response comes back with any refusal. A player who respawned is moved to
+// the spawn point instead. Must be called with mu held.
+func (c *Client) move(x, y int, seq uint64) (*types.Response, error) {
+	res, err := c.moveTo(x, y, seq)
+	if res != nil && res.Code == types.CodeRespawned && res.Player != nil {
+		log.Printf("✨ %s respawned at (%d, %d)", c.player.ID, res.Player.PosX, res.Player.PosY)
+		c.player.Health, c.player.RespawnMs = res.Player.Health, 0
+		return c.moveTo(res.Player.PosX, res.Player.PosY, seq)
+	}
+	return res, err
+}
+
+// moveTo is one attempt at move. Must be called with mu held.
+func (c *Client) moveTo(x, y int, seq uint64) (*types.Response, error) {
+	prev, prev_chunk := c.player, c.chunk
+	c.player.PosX, c.player.PosY = x, y
+	if c.chunkAtLocked() != c.chunk {
+		if err := c.enter(); err != nil {
+			c.player = prev
+			var refused *RefusedError
+			if errors.As(err, &refused) {
+				return refused.Res, err
+			}
+			return nil, err
+		}
+	}
+
+	req := types.Request{Type: types.ReqMovePlayer, Player: c.player, ChunkID: c.chunk, InputSeq: seq}
+	res, err := c.do(req)
+	if err == nil && !res.Success {
+		err = &RefusedError{Res: res}
+	}
+	if err != nil {
+		c.player, c.chunk = prev, prev_chunk
+		return res, err
+	}
+
+	// the server derives the chunk from our position and may move us
+	if res.ChunkID != nil && *res.ChunkID != c.chunk {
+		log.Printf("🔄 Server placed %s in chunk [%d,%d]", c.player.ID, res.ChunkID.IDX, res.ChunkID.IDY)
+		c.chunk = *res.ChunkID
+	}
+	c.replicas = res.Replicas
+	c.prefetchNeighbours()
+	return res, nil
+}
+
+// Leave removes the player from their game server and closes the client.
+func (c *Client) Leave() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := types.Request{Type: types.ReqDltPlayer, Player: c.player, ChunkID: c.chunk}
+	_, err := c.send(c.server, req)
+	c.conn.Close()
+	c.prefetch.close()
+	return err
+}
+
+// Subscribe calls fn with the player's chunk every period until ctx is done
+// or the player is kicked. It keeps following the player: to the chunk they
+// move into, and to the chunk's new owner after a migration.
+func (c *Client) Subscribe(ctx context.Context, every time.Duration, fn func(Update)) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			update, err := c.Poll()
+			if errors.Is(err, ErrKicked) {
+				return
+			} else if err != nil {
+				log.Printf("⚠️  Updates for %s failed: %v", c.Player().ID, err)
+				continue
+			}
+			// a quiet chunk is polled as seldom as the server updates it
+			ticker.Reset(max(every, time.Duration(update.UpdateMs)*time.Millisecond))
+			fn(update)
+		}
+	}()
+}
+
+// Poll fetches what changed in the player's chunk, from one of its read
+// replicas when it has them, and returns the chunk as now known.
+func (c *Client) Poll() (Update, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	view := c.view(c.chunk)
+	req := types.Request{Type: types.ReqGetUpdates, Player: c.player, ChunkID: c.chunk, Since: view.Version}
+
+	server := c.readServer()
+	var res *types.Response
+	var err error
+	if server != c.server {
+		res, err = c.send(server, req)
+		if err != nil || !res.Success {
+			// the replica may have dropped the chunk; the owner always answers
+			c.replicas = nil
+			server = c.server
+		}
+	}
+	if server == c.server {
+		res, err = c.do(req)
+		server = c.server
+	}
+	if err != nil {
+		return Update{}, err
+	}
+	if !res.Success {
+		return Update{}, &RefusedError{Res: res}
+	}
+
+	update := Update{ChunkID: c.chunk, Server: server, Party: res.PartyMembers, UpdateMs: res.UpdateMs}
+	if res.Code == types.CodeDelta && res.Delta != nil {
+		chunkstore.ApplyDelta(&view.Chunk, *res.Delta)
+		update.Delta = res.Delta
+	} else {
+		view.Chunk = res.GameData.Chunk
+	}
+	view.Version, view.Owner, view.Fetched = res.Version, server, time.Now()
+	update.Chunk = view.Chunk
+	return update, nil
+}
+
+// Do sends req to the player's game server, following redirects and
+// resending it when the reply is lost.
+func (c *Client) Do(req types.Request) (*types.Response, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.do(req)
+}
+
+// enter sends GET_DATA for the chunk at the player's position. Must be
+// called with mu held.
+func (c *Client) enter() error {
+	chunk_id := c.chunkAtLocked()
+	req := types.Request{Type: types.ReqGetData, Player: c.player, ChunkID: chunk_id, Since: c.since(chunk_id)}
+	res, err := c.do(req)
+	if err != nil {
+		return err
+	}
+
+	switch res.Code {
+	case types.CodeOK, types.CodeNotModified:
+		if res.ChunkID != nil {
+			chunk_id = *res.ChunkID // a sub-chunk if chunk_id was split
+		}
+		if res.Version != nil {
+			view := c.view(chunk_id)
+			if res.Code != types.CodeNotModified {
+				view.Chunk, view.Version = res.Chunk, res.Version
+			}
+			view.Owner, view.Fetched = c.server, time.Now()
+		}
+		c.chunk = chunk_id
+		c.replicas = res.Replicas
+		log.Printf("✅ %s entered chunk [%d,%d] on %s", c.player.ID, chunk_id.IDX, chunk_id.IDY, c.server)
+		return nil
+	case types.CodeChunkFull:
+		log.Printf("⏳ Chunk [%d,%d] is full: queue position %d, retry in %dms",
+			chunk_id.IDX, chunk_id.IDY, res.QueuePosition, res.RetryAfterMs)
+	}
+	return &RefusedError{Res: res}
+}
+
+// do sends req to the player's game server and chases it to the owner when
+// the server answers with a redirect. Must be called with mu held.
+func (c *Client) do(req types.Request) (*types.Response, error) {
+	if req.TraceID == "" {
+		req.TraceID = netproto.NewTraceID()
+	}
+	for redirects := 0; ; redirects++ {
+		res, err := c.send(c.server, req)
+		if err != nil {
+			return nil, err
+		}
+
+		switch res.Code {
+		case types.CodeRedirect, types.CodeNotOwner, types.CodeChunkMigrating:
+		default:
+			return res, nil
+		}
+		if res.RedirectIP == "" || redirects >= c.MaxRedirects {
+			return res, nil
+		}
+		if res.Code == types.CodeChunkMigrating {
+			time.Sleep(migratingBackoff)
+		}
+		netproto.Tracef(req.TraceID, "↪️  %s for chunk [%d,%d] redirected (%s) to %s", req.Type, req.ChunkID.IDX, req.ChunkID.IDY, res.Code, res.RedirectIP)
+		c.stats.redirect()
+		c.switchServer(res.RedirectIP)
+	}
+}
+
+// send sends req to addr, resending it up to Retries times when no reply
+// comes in time. Must be called with mu held.
+func (c *Client) send(addr string, req types.Request) (*types.Response, error) {
+	if c.kicked {
+		return nil, ErrKicked
+	}
+	if req.TraceID == "" {
+		req.TraceID = netproto.NewTraceID()
+	}
+	for attempt := 0; ; attempt++ {
+		start := time.Now()
+		res, err := c.roundTrip(addr, req)
+		rtt := time.Since(start)
+		c.stats.record(rtt, err)
+		if c.OnResponse != nil {
+			c.OnResponse(req, res, rtt, err)
+		}
+		if err == nil && res.Code == types.CodeKicked {
+			return res, ErrKicked
+		}
+		if !errors.Is(err, netproto.ErrTimeout) || attempt >= c.Retries {
+			return res, err
+		}
+		netproto.Tracef(req.TraceID, "⏱️  %s to %s timed out, retrying (%d/%d)", req.Type, addr, attempt+1, c.Retries)
+	}
+}
+
+// roundTrip sends req once on the player's connection and waits for its
+// reply. Must be called with mu held.
+func (c *Client) roundTrip(addr string, req types.Request) (*types.Response, error) {
+	sent := time.Now()
+	res, err := exchange(c.conn, c.Timeout, addr, req, c.pushed)
+	if err != nil {
+		return nil, err
+	}
+	c.syncClock(res, sent, time.Now())
+	// the server pushes an ERR_KICKED notice instead of the normal response
+	if res.Code == types.CodeKicked {
+		log.Printf("⛔ %s", res.Message)
+		c.kicked = true
+		return res, nil
+	}
+	c.learnSplits(res.Splits)
+	return res, nil
+}
+
+// exchange sends req once on conn and waits up to timeout for its reply,
+// skipping late replies to earlier attempts and handing messages the server
+// pushed meanwhile to push, if set.
+func exchange(conn netproto.Transport, timeout time.Duration, addr string, req types.Request, push func(*types.Response)) (*types.Response, error) {
+	netproto.Tracef(req.TraceID, "→ %s chunk [%d,%d] to %s", req.Type, req.ChunkID.IDX, req.ChunkID.IDY, addr)
+
+	req.AcceptEncoding = netproto.AcceptEncodings
+	data, err := netproto.Wire.Marshal(req)
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Send(addr, data); err != nil {
+		return nil, err
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+	for {
+		_, data, err = conn.Recv()
+		if err != nil {
+			return nil, err
+		}
+		res, err := netproto.DecodeResponse(data)
+		if err != nil {
+			return nil, err
+		}
+		if strings.HasPrefix(res.Code, "PUSH_") {
+			if push != nil {
+				for _, pushed := range netproto.Unbatch(res) {
+					push(&pushed)
+				}
+			}
+			continue
+		}
+		if res.Code != types.CodeKicked && res.TraceID != "" && res.TraceID != req.TraceID {
+			continue
+		}
+		return &res, nil
+	}
+}
+
+// pushed takes a message a server sent unasked.
+func (c *Client) pushed(res *types.Response) {
+	switch res.Code {
+	case types.CodeChat, types.CodeWhisper:
+		c.chat.add(res.Chat)
+	case types.CodeAnnounce:
+		if res.Announcement != nil {
+			c.announced.add(*res.Announcement)
+		}
+	case types.CodeMatch:
+		if res.Match != nil {
+			c.matched.set(*res.Match)
+		}
+	case types.CodeHit:
+		if res.Hit != nil {
+			c.hits.add(*res.Hit)
+		}
+	case types.CodeLife:
+		if res.Life != nil {
+			c.lifeChanged(*res.Life)
+		}
+	case types.CodeAchievement:
+		c.awards.add(res.Awards)
+	}
+}
+
+// switchServer points the client at a new game server. Must be called with
+// mu held.
+func (c *Client) switchServer(server string) {
+	if server == c.server {
+		return
+	}
+	log.Printf("🔀 %s now talks to %s", c.player.ID, server)
+	c.server = server
+	c.replicas = nil
+	c.clock.reset()
+	c.chat.reset()
+}
+
+// readServer picks where to send reads of the current chunk: one of its read
+// replicas when it has them, spread by player ID, else the owner. Must be
+// called with mu held.
+func (c *Client) readServer() string {
+	if len(c.replicas) == 0 {
+		return c.server
+	}
+	servers := append([]string{c.server}, c.replicas...)
+	var h uint32
+	for _, ch := range c.player.ID {
+		h = h*31 + uint32(ch)
+	}
+	return servers[h%uint32(len(servers))]
+}
+
+// chunkAtLocked is ChunkAt for the player's position. Must be called with mu
+// held.
+func (c *Client) chunkAtLocked() types.ChunkID {
+	return types.ResolveChunk(c.player.World, c.player.PosX, c.player.PosY, c.chunkSize, c.splits)
+}
+
+// learnSplits records the split chunks a server or central reported. Must
+// be called with mu held.
+func (c *Client) learnSplits(list []types.ChunkID) {
+	if len(list) == 0 {
+		return
+	}
+	if c.splits == nil {
+		c.splits = make(map[types.ChunkID]bool, len(list))
+	}
+	for _, chunk_id := range list {
+		c.splits[chunk_id] = true
+	}
+}
+
+// since returns the version held for chunk_id, if any. Must be called with
+// mu held.
+func (c *Client) since(chunk_id types.ChunkID) *types.ChunkVersion {
+	if view, ok := c.views[chunk_id]; ok {
+		return view.Version
+	}
+	return nil
+}
+
+// view returns the copy of chunk_id, starting an empty one if needed. Must
+// be called with mu held.
+func (c *Client) view(chunk_id types.ChunkID) *chunkView {
+	if c.views == nil {
+		c.views = make(map[types.ChunkID]*chunkView)
+	}
+	view, ok := c.views[chunk_id]
+	if !ok {
+		for other := range c.views {
+			if len(c.views) < maxViews {
+				break
+			}
+			if other != c.chunk {
+				delete(c.views, other)
+			}
+		}
+		view = &chunkView{}
+		c.views[chunk_id] = view
+	}
+	return view
+}
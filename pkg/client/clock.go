@@ -0,0 +1,136 @@
+package client
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Clock =====================
+
+// Every reply and push message carries the server's clock (ServerTimeMs)
+// and world tick. The client keeps the offset from its own clock, taking
+// each reply as sent halfway through its round trip and smoothing out the
+// jitter, so ServerNow and ServerTick can timestamp events and schedule
+// interpolation on the server's timeline. Sync measures the offset outright
+// NTP-style: a few SYNC round trips, trusting the quickest, since it has the
+// least room for asymmetry. Game servers keep their own clocks and ticks, so
+// the estimate starts over when the client switches server.
+
+// syncSamples is how many SYNC round trips Sync takes by default.
+const syncSamples = 5
+
+// clockSync has a lock of its own so ServerNow and ServerTick never wait on
+// a round trip; it is taken after Client.mu, never before.
+type clockSync struct {
+	mu     sync.Mutex
+	offset int64 // server minus local clock, ms
+	synced bool
+	tick   int64 // server tick as of tickAt, server ms
+	tickAt int64
+	tickMs int // from SYNC; 0 until known
+}
+
+// syncClock folds one reply's clock and tick into the estimate.
+func (c *Client) syncClock(res *types.Response, sent, received time.Time) {
+	if res.ServerTimeMs <= 0 {
+		return
+	}
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	if res.Tick > 0 {
+		c.clock.tick, c.clock.tickAt = res.Tick, res.ServerTimeMs
+	}
+	if res.TickMs > 0 {
+		c.clock.tickMs = res.TickMs
+	}
+	sample := clockSample(res.ServerTimeMs, sent, received)
+	if !c.clock.synced {
+		c.clock.offset, c.clock.synced = sample, true
+		return
+	}
+	c.clock.offset += (sample - c.clock.offset) / 8
+}
+
+// clockSample is the offset one reply implies, assuming it was sent halfway
+// through the round trip.
+func clockSample(server_ms int64, sent, received time.Time) int64 {
+	return server_ms - sent.Add(received.Sub(sent)/2).UnixMilli()
+}
+
+// Sync measures the offset between the local and the server clock with
+// samples SYNC round trips (syncSamples if samples <= 0), keeping the one
+// with the shortest round trip. It returns that offset and round trip.
+func (c *Client) Sync(samples int) (offset, rtt time.Duration, err error) {
+	if samples <= 0 {
+		samples = syncSamples
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.kicked {
+		return 0, 0, ErrKicked
+	}
+
+	best := time.Duration(-1)
+	var best_offset int64
+	for i := 0; i < samples; i++ {
+		req := types.Request{Type: types.ReqSync, PlayerID: c.player.ID, TraceID: netproto.NewTraceID()}
+		sent := time.Now()
+		res, err := exchange(c.conn, c.Timeout, c.server, req, c.pushed)
+		received := time.Now()
+		c.stats.record(received.Sub(sent), err)
+		if c.OnResponse != nil {
+			c.OnResponse(req, res, received.Sub(sent), err)
+		}
+		if errors.Is(err, netproto.ErrTimeout) {
+			continue
+		} else if err != nil {
+			return 0, 0, err
+		}
+		if !res.Success || res.ServerTimeMs <= 0 {
+			return 0, 0, &RefusedError{Res: res}
+		}
+		c.syncClock(res, sent, received)
+		if round := received.Sub(sent); best < 0 || round < best {
+			best, best_offset = round, clockSample(res.ServerTimeMs, sent, received)
+		}
+	}
+	if best < 0 {
+		return 0, 0, netproto.ErrTimeout
+	}
+	c.clock.mu.Lock()
+	c.clock.offset, c.clock.synced = best_offset, true
+	c.clock.mu.Unlock()
+	return time.Duration(best_offset) * time.Millisecond, best, nil
+}
+
+// ServerNow returns the client's estimate of the server clock, in Unix
+// milliseconds.
+func (c *Client) ServerNow() int64 {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	return time.Now().UnixMilli() + c.clock.offset
+}
+
+// ServerTick returns the client's estimate of the server's current world
+// tick: the last one heard of, advanced by the time since if the tick length
+// is known from Sync.
+func (c *Client) ServerTick() int64 {
+	c.clock.mu.Lock()
+	defer c.clock.mu.Unlock()
+	if c.clock.tickMs <= 0 {
+		return c.clock.tick
+	}
+	now := time.Now().UnixMilli() + c.clock.offset
+	return c.clock.tick + (now-c.clock.tickAt)/int64(c.clock.tickMs)
+}
+
+// reset forgets the estimate, for a new server.
+func (s *clockSync) reset() {
+	s.mu.Lock()
+	s.offset, s.synced, s.tick, s.tickAt, s.tickMs = 0, false, 0, 0, 0
+	s.mu.Unlock()
+}
@@ -0,0 +1,63 @@
+package client
+
+import (
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Items =====================
+
+// The player's inventory is held by their server; every PICKUP, DROP and
+// USE reply brings the client's copy up to date. Items lying in the
+// player's chunk are in Chunk.Items of the updates.
+
+// Inventory returns what the player carries, as last reported.
+func (c *Client) Inventory() []types.ItemStack {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]types.ItemStack(nil), c.player.Inventory...)
+}
+
+// Pickup takes the item itemID, lying within reach, into the inventory.
+func (c *Client) Pickup(itemID string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.itemAction(types.Request{Type: types.ReqPickup, ItemID: itemID})
+	return err
+}
+
+// Drop leaves count items of kind at the player's feet and returns the ID of
+// the item they make.
+func (c *Client) Drop(kind string, count int) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	res, err := c.itemAction(types.Request{Type: types.ReqDrop, Item: &types.ItemStack{Kind: kind, Count: count}})
+	if err != nil {
+		return "", err
+	}
+	return res.ItemID, nil
+}
+
+// Use uses up one item of kind.
+func (c *Client) Use(kind string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, err := c.itemAction(types.Request{Type: types.ReqUse, Item: &types.ItemStack{Kind: kind}})
+	return err
+}
+
+// itemAction sends req for the player and takes the inventory and health
+// the reply brings. Must be called with mu held.
+func (c *Client) itemAction(req types.Request) (*types.Response, error) {
+	req.Player, req.ChunkID = c.player, c.chunk
+	res, err := c.do(req)
+	if err != nil {
+		return nil, err
+	}
+	if res.Player != nil {
+		c.player.Inventory, c.player.Health = res.Player.Inventory, res.Player.Health
+	}
+	if !res.Success {
+		return res, &RefusedError{Res: res}
+	}
+	return res, nil
+}
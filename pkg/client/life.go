@@ -0,0 +1,64 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Health =====================
+
+// The server holding the player keeps their health and pushes every change
+// to it and to the players around (PUSH_LIFE); the client keeps the
+// player's own Health and RespawnMs up to date from those and hands them
+// all out with Life. A dead player's moves are refused with ERR_DEAD until
+// they respawn; the first move after that takes them to the spawn point.
+
+// maxLifeEvents bounds the events kept until Life is called.
+const maxLifeEvents = 64
+
+// lifeBox keeps the life events pushed to the player, under a lock of its
+// own taken after Client.mu, never before.
+type lifeBox struct {
+	mu   sync.Mutex
+	list []types.LifeEvent
+}
+
+func (b *lifeBox) add(event types.LifeEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.list = append(b.list, event)
+	if len(b.list) > maxLifeEvents {
+		b.list = append([]types.LifeEvent(nil), b.list[len(b.list)-maxLifeEvents:]...)
+	}
+}
+
+// lifeChanged takes a pushed life event. Must be called with mu held.
+func (c *Client) lifeChanged(event types.LifeEvent) {
+	if event.PlayerID == c.player.ID {
+		c.player.Health = event.Health
+		c.player.RespawnMs = 0
+		if event.Kind == types.LifeDeath {
+			c.player.RespawnMs = event.RespawnMs
+		}
+	}
+	c.lives.add(event)
+}
+
+// Health returns the player's health as the server last reported it (0
+// before any report), and whether they are dead.
+func (c *Client) Health() (int, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.player.Health, c.player.RespawnMs > 0
+}
+
+// Life returns the life events pushed to the player since the last call,
+// oldest first: their own and those of players around them.
+func (c *Client) Life() []types.LifeEvent {
+	c.lives.mu.Lock()
+	defer c.lives.mu.Unlock()
+	list := c.lives.list
+	c.lives.list = nil
+	return list
+}
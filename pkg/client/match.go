@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Matches =====================
+
+// Matchmaking is done by the central server Join went through. A player
+// queues for a mode with QueueMatch; once the match is formed it is pushed
+// to them (see PendingMatch) and also answers MatchStatus. EnterMatch then
+// takes the player off their server and into the match's world.
+
+// matchBox keeps the match last pushed to the player, under a lock of its
+// own taken after Client.mu, never before.
+type matchBox struct {
+	mu    sync.Mutex
+	match *types.Match
+}
+
+func (b *matchBox) set(match types.Match) {
+	b.mu.Lock()
+	b.match = &match
+	b.mu.Unlock()
+}
+
+// QueueMatch queues the player for a match of mode, leaving any other
+// queue. It returns the match if theirs was formed by this call, or nil
+// while they wait.
+func (c *Client) QueueMatch(mode string) (*types.Match, error) {
+	res, err := c.postCentral("/match/queue", map[string]string{"mode": mode})
+	if err != nil {
+		return nil, err
+	}
+	return res.Match, nil
+}
+
+// LeaveMatch takes the player out of the queue, or out of their match.
+func (c *Client) LeaveMatch() error {
+	_, err := c.postCentral("/match/leave", map[string]string{})
+	c.matched.mu.Lock()
+	c.matched.match = nil
+	c.matched.mu.Unlock()
+	return err
+}
+
+// MatchStatus asks central for the player's match, or else their place in
+// the queue. Polling it also keeps that place.
+func (c *Client) MatchStatus() (*types.Match, int, error) {
+	c.mu.Lock()
+	central, player_id := c.central, c.player.ID
+	c.mu.Unlock()
+	if central == "" {
+		return nil, 0, errors.New("/match needs Join first")
+	}
+
+	httpResp, err := http.Get(central + "/match?player_id=" + url.QueryEscape(player_id))
+	if err != nil {
+		return nil, 0, err
+	}
+	defer httpResp.Body.Close()
+	var res types.Response
+	if err := json.NewDecoder(httpResp.Body).Decode(&res); err != nil {
+		return nil, 0, fmt.Errorf("/match: %w", err)
+	}
+	if !res.Success {
+		return nil, 0, &RefusedError{Res: &res}
+	}
+	return res.Match, res.QueuePosition, nil
+}
+
+// PendingMatch returns the match pushed to the player, if one has been
+// since the last call. Like chat, it is picked up whenever the client waits
+// for a reply.
+func (c *Client) PendingMatch() (types.Match, bool) {
+	c.matched.mu.Lock()
+	defer c.matched.mu.Unlock()
+	if c.matched.match == nil {
+		return types.Match{}, false
+	}
+	match := *c.matched.match
+	c.matched.match = nil
+	return match, true
+}
+
+// EnterMatch leaves the player's game server and enters match's world on
+// its server, at their team's spawn.
+func (c *Client) EnterMatch(match types.Match) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	team := match.Team(c.player.ID)
+	if team < 0 || team >= len(match.Spawns) {
+		return fmt.Errorf("%s is not in match %s", c.player.ID, match.ID)
+	}
+
+	if match.Server != c.server {
+		req := types.Request{Type: types.ReqDltPlayer, Player: c.player, ChunkID: c.chunk}
+		if _, err := c.send(c.server, req); err != nil {
+			log.Printf("⚠️  Leaving %s for match %s: %v", c.server, match.ID, err)
+		}
+		c.switchServer(match.Server)
+	}
+	spawn := match.Spawns[team]
+	c.player.PosX, c.player.PosY, c.player.World = spawn.PosX, spawn.PosY, match.World
+	c.syncPredicted()
+	log.Printf("🏟️  %s enters %s match %s on team %d", c.player.ID, match.Mode, match.ID, team)
+	return c.enter()
+}
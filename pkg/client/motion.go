@@ -0,0 +1,57 @@
+package client
+
+import (
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Motion =====================
+
+// Remote players arrive one poll apart, each with the velocity the server
+// measured and the server time of their last move (Player.UpdatedMs). A
+// frontend renders a little in the past and Interpolates between the two
+// latest updates of a player, and Extrapolates from the latest when the next
+// is late, for at most maxExtrapolate so a player who stopped answering does
+// not drift off. ServerNow (see Sync) puts the local clock on the server's
+// timeline. How far in the past to render follows how often the chunk is
+// updated, which the server adapts to its activity (see
+// InterpolationDelay).
+
+const maxExtrapolate = time.Second
+
+// InterpolationDelay is how far behind server time to render a chunk updated
+// every updateMs, so there are usually two updates to interpolate between.
+// An unknown rate gets the delay of the default 200ms updates.
+func InterpolationDelay(updateMs int64) time.Duration {
+	if updateMs <= 0 {
+		updateMs = 200
+	}
+	return 2 * time.Duration(updateMs) * time.Millisecond
+}
+
+// Extrapolate returns where p is at server time at_ms if it kept its last
+// velocity, for at most maxExtrapolate past its last update.
+func Extrapolate(p types.Player, at_ms int64) (x, y float64) {
+	x, y = float64(p.PosX), float64(p.PosY)
+	if p.UpdatedMs <= 0 || at_ms <= p.UpdatedMs {
+		return x, y
+	}
+	dt := min(at_ms-p.UpdatedMs, maxExtrapolate.Milliseconds())
+	secs := float64(dt) / 1000
+	return x + p.VelX*secs, y + p.VelY*secs
+}
+
+// Interpolate returns where a player is at server time at_ms given two of
+// their updates, from before and after at_ms; past the later one it
+// extrapolates.
+func Interpolate(from, to types.Player, at_ms int64) (x, y float64) {
+	if at_ms >= to.UpdatedMs || to.UpdatedMs <= from.UpdatedMs {
+		return Extrapolate(to, at_ms)
+	}
+	if at_ms <= from.UpdatedMs {
+		return float64(from.PosX), float64(from.PosY)
+	}
+	t := float64(at_ms-from.UpdatedMs) / float64(to.UpdatedMs-from.UpdatedMs)
+	return float64(from.PosX) + t*float64(to.PosX-from.PosX), float64(from.PosY) + t*float64(to.PosY-from.PosY)
+}
@@ -0,0 +1,38 @@
+package client
+
+import (
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Parties =====================
+
+// Parties are kept by the central server Join went through. Once the player
+// is in one, every Poll brings the members' positions in Update.Party,
+// wherever they are.
+
+// CreateParty starts a party led by the player, leaving any other one.
+func (c *Client) CreateParty() (types.Party, error) {
+	return c.partyCall("/party/create", "")
+}
+
+// JoinParty joins the party party_id, leaving any other one.
+func (c *Client) JoinParty(party_id string) (types.Party, error) {
+	return c.partyCall("/party/join", party_id)
+}
+
+// LeaveParty leaves the player's party.
+func (c *Client) LeaveParty() error {
+	_, err := c.partyCall("/party/leave", "")
+	return err
+}
+
+func (c *Client) partyCall(path, party_id string) (types.Party, error) {
+	res, err := c.postCentral(path, map[string]string{"party_id": party_id})
+	if err != nil {
+		return types.Party{}, err
+	}
+	if res.Party == nil {
+		return types.Party{}, nil
+	}
+	return *res.Party, nil
+}
@@ -0,0 +1,138 @@
+package client
+
+import (
+	"errors"
+	"log"
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Prediction =====================
+
+// Move only returns once the server has the move, so a frontend drawing the
+// player from it lags a round trip behind their input. Input is the
+// predicting alternative: each step (dx, dy) is numbered, applied to the
+// position Predicted returns straight away, and sent in the background; the
+// steps issued while a move is in flight go together in the next
+// MOVE_PLAYER, numbered as the latest. Every reply acknowledges the last
+// input the server processed (AckSeq) with where the player authoritatively
+// is; the client drops the acknowledged inputs and replays the rest on top
+// of that, so a corrected or refused move snaps the prediction back. A move
+// that gets no reply at all is refused too.
+
+type input struct {
+	seq    uint64
+	dx, dy int
+}
+
+// predictor has a lock of its own so Input and Predicted never wait on a
+// round trip; it is taken after Client.mu, never before.
+type predictor struct {
+	mu      sync.Mutex
+	seq     uint64 // last input issued
+	sent    uint64 // last input sent
+	acked   uint64 // last input the server acknowledged
+	x, y    int    // predicted position
+	pending []input
+	sending bool
+}
+
+// Input moves the player by (dx, dy) at once in the prediction and sends the
+// move to the server in the background. It returns the input's number.
+func (c *Client) Input(dx, dy int) uint64 {
+	p := &c.predict
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.seq++
+	p.x, p.y = p.x+dx, p.y+dy
+	p.pending = append(p.pending, input{seq: p.seq, dx: dx, dy: dy})
+	if !p.sending {
+		p.sending = true
+		go c.sendInputs()
+	}
+	return p.seq
+}
+
+// Predicted returns where the player is with every input applied, including
+// those the server has not acknowledged yet.
+func (c *Client) Predicted() (x, y int) {
+	c.predict.mu.Lock()
+	defer c.predict.mu.Unlock()
+	return c.predict.x, c.predict.y
+}
+
+// Acked returns the number of the last input the server acknowledged.
+func (c *Client) Acked() uint64 {
+	c.predict.mu.Lock()
+	defer c.predict.mu.Unlock()
+	return c.predict.acked
+}
+
+// sendInputs sends the predicted position, numbered as the latest input,
+// until the server has been sent every input.
+func (c *Client) sendInputs() {
+	p := &c.predict
+	for {
+		p.mu.Lock()
+		if p.sent >= p.seq {
+			p.sending = false
+			p.mu.Unlock()
+			return
+		}
+		seq, x, y := p.seq, p.x, p.y
+		p.sent = seq
+		p.mu.Unlock()
+
+		c.mu.Lock()
+		res, err := c.move(x, y, seq)
+		if err != nil && !errors.As(err, new(*RefusedError)) {
+			log.Printf("⚠️  Input %d of %s failed: %v", seq, c.player.ID, err)
+		}
+		c.reconcile(seq, res)
+		c.mu.Unlock()
+	}
+}
+
+// reconcile applies the server's answer to input seq: the player is where
+// the server says after the acknowledged input, plus the inputs after it.
+// Must be called with mu held.
+func (c *Client) reconcile(seq uint64, res *types.Response) {
+	ack := seq
+	if res != nil && res.AckSeq > 0 {
+		ack = res.AckSeq
+	}
+	if res != nil && res.Player != nil {
+		c.player.PosX, c.player.PosY = res.Player.PosX, res.Player.PosY
+	}
+
+	p := &c.predict
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if ack <= p.acked {
+		return
+	}
+	p.acked = ack
+	kept := p.pending[:0]
+	x, y := c.player.PosX, c.player.PosY
+	for _, in := range p.pending {
+		if in.seq > ack {
+			kept = append(kept, in)
+			x, y = x+in.dx, y+in.dy
+		}
+	}
+	p.pending = kept
+	p.x, p.y = x, y
+}
+
+// syncPredicted lines the prediction up with the player's position when no
+// input is outstanding. Must be called with mu held.
+func (c *Client) syncPredicted() {
+	p := &c.predict
+	p.mu.Lock()
+	if len(p.pending) == 0 {
+		p.x, p.y = c.player.PosX, c.player.PosY
+	}
+	p.mu.Unlock()
+}
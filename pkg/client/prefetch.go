@@ -0,0 +1,198 @@
+package client
+
+import (
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Neighbour prefetch =====================
+
+// When the player comes within PrefetchMargin cells of an edge of their
+// chunk, the client fetches the eight chunks around it with READ_ONLY in the
+// background, on a connection of its own so moves are not held up. A chunk
+// already held is asked for with Since, so an unchanged one costs a reply
+// without the chunk, and each is tried at most once per prefetchEvery.
+// Entering one of them then sends its version with GET_DATA, which comes
+// back OK_NOT_MODIFIED instead of the whole chunk.
+
+const prefetchEvery = 2 * time.Second
+
+type prefetcher struct {
+	conn    netproto.Transport // opened on first use
+	sealed  netproto.Transport // conn with the player's seal, if any; reset by Join
+	running bool
+	tried   map[types.ChunkID]time.Time // last attempt per chunk, owned or not
+}
+
+func (p *prefetcher) close() {
+	if p.conn != nil {
+		p.conn.Close()
+	}
+}
+
+// Cached returns the client's copy of chunk_id, prefetched or seen earlier.
+func (c *Client) Cached(chunk_id types.ChunkID) (types.Chunk, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	view, ok := c.views[chunk_id]
+	if !ok || view.Version == nil {
+		return types.Chunk{}, false
+	}
+	return view.Chunk, true
+}
+
+// prefetchNeighbours starts fetching the chunks around the player's if they
+// are near its edge and none is under way. Must be called with mu held.
+func (c *Client) prefetchNeighbours() {
+	if c.PrefetchMargin <= 0 || c.prefetch.running || !c.nearEdge() {
+		return
+	}
+
+	now := time.Now()
+	for chunk_id, at := range c.prefetch.tried {
+		if now.Sub(at) >= prefetchEvery {
+			delete(c.prefetch.tried, chunk_id)
+		}
+	}
+	if c.prefetch.tried == nil {
+		c.prefetch.tried = make(map[types.ChunkID]time.Time)
+	}
+	var todo []types.ChunkID
+	for _, chunk_id := range c.neighbours() {
+		if _, ok := c.prefetch.tried[chunk_id]; ok {
+			continue
+		}
+		if view, ok := c.views[chunk_id]; ok && now.Sub(view.Fetched) < prefetchEvery {
+			continue
+		}
+		c.prefetch.tried[chunk_id] = now
+		todo = append(todo, chunk_id)
+	}
+	if len(todo) == 0 {
+		return
+	}
+
+	if c.prefetch.conn == nil {
+		conn, err := c.network.Listen("")
+		if err != nil {
+			log.Printf("⚠️  Prefetch connection for %s failed: %v", c.player.ID, err)
+			return
+		}
+		c.prefetch.conn = conn
+	}
+	conn := c.prefetch.conn
+	if c.sealer != nil {
+		if c.prefetch.sealed == nil {
+			c.prefetch.sealed = c.sealer.Transport(conn)
+		}
+		conn = c.prefetch.sealed
+	}
+	c.prefetch.running = true
+	go c.prefetchChunks(conn, todo)
+}
+
+// nearEdge reports whether the player is within PrefetchMargin of an edge
+// of their chunk. Must be called with mu held.
+func (c *Client) nearEdge() bool {
+	edge := c.chunk.Edge(c.chunkSize)
+	x := c.player.PosX - c.chunk.IDX*edge
+	y := c.player.PosY - c.chunk.IDY*edge
+	margin := c.PrefetchMargin
+	return x < margin || y < margin || x >= edge-margin || y >= edge-margin
+}
+
+// neighbours returns the chunks around the player's, one chunk edge away in
+// each of the eight directions. Must be called with mu held.
+func (c *Client) neighbours() []types.ChunkID {
+	edge := c.chunk.Edge(c.chunkSize)
+	cx, cy := c.chunk.IDX*edge+edge/2, c.chunk.IDY*edge+edge/2
+
+	var list []types.ChunkID
+	for dy := -1; dy <= 1; dy++ {
+		for dx := -1; dx <= 1; dx++ {
+			x, y := cx+dx*edge, cy+dy*edge
+			if dx == 0 && dy == 0 || x < 0 || y < 0 {
+				continue
+			}
+			chunk_id := types.ResolveChunk(c.player.World, x, y, c.chunkSize, c.splits)
+			if chunk_id != c.chunk {
+				list = append(list, chunk_id)
+			}
+		}
+	}
+	return list
+}
+
+// prefetchChunks fetches list on conn, asking the server each chunk was last
+// served by, else the player's, and following one redirect to the owner.
+func (c *Client) prefetchChunks(conn netproto.Transport, list []types.ChunkID) {
+	defer func() {
+		c.mu.Lock()
+		c.prefetch.running = false
+		c.mu.Unlock()
+	}()
+
+	for _, chunk_id := range list {
+		c.mu.Lock()
+		if c.kicked {
+			c.mu.Unlock()
+			return
+		}
+		since, target, timeout := c.since(chunk_id), c.server, c.Timeout
+		if view, ok := c.views[chunk_id]; ok && view.Owner != "" {
+			target = view.Owner
+		}
+		c.mu.Unlock()
+
+		req := types.Request{Type: types.ReqReadOnly, ChunkID: chunk_id, Since: since, IsChunkNew: since == nil, TraceID: netproto.NewTraceID()}
+		res, err := c.prefetchOne(conn, timeout, target, req)
+		if err == nil && res.Code == types.CodeNotOwner && res.RedirectIP != "" && res.RedirectIP != target {
+			target = res.RedirectIP
+			c.stats.redirect()
+			res, err = c.prefetchOne(conn, timeout, target, req)
+		}
+		if err != nil {
+			netproto.Tracef(req.TraceID, "⚠️  Prefetch of chunk [%d,%d] failed: %v", chunk_id.IDX, chunk_id.IDY, err)
+			continue
+		}
+
+		c.mu.Lock()
+		c.keepPrefetched(chunk_id, target, res)
+		c.mu.Unlock()
+	}
+}
+
+func (c *Client) prefetchOne(conn netproto.Transport, timeout time.Duration, addr string, req types.Request) (*types.Response, error) {
+	start := time.Now()
+	res, err := exchange(conn, timeout, addr, req, nil)
+	rtt := time.Since(start)
+	c.stats.record(rtt, err)
+	if c.OnResponse != nil {
+		c.OnResponse(req, res, rtt, err)
+	}
+	return res, err
+}
+
+// keepPrefetched stores a READ_ONLY reply for chunk_id from server. Chunks
+// nobody owns yet come back without a version and are skipped. Must be
+// called with mu held.
+func (c *Client) keepPrefetched(chunk_id types.ChunkID, server string, res *types.Response) {
+	if res.Version == nil {
+		return
+	}
+	view := c.view(chunk_id)
+	switch {
+	case res.Code == types.CodeNotModified:
+	case res.Code == types.CodeDelta && res.Delta != nil:
+		chunkstore.ApplyDelta(&view.Chunk, *res.Delta)
+	case res.Success:
+		view.Chunk = res.Chunk
+	default:
+		return
+	}
+	view.Version, view.Owner, view.Fetched = res.Version, server, time.Now()
+}
@@ -0,0 +1,62 @@
+package client
+
+import (
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Shooting =====================
+
+// Shoot fires a projectile the server simulates. The hits it and others
+// score near the player are pushed (PUSH_HIT) and picked up whenever the
+// client waits for a reply, like chat; Hits returns them.
+
+// maxHits bounds the hits kept until Hits is called.
+const maxHits = 64
+
+// hitBox keeps the hits pushed to the player, under a lock of its own taken
+// after Client.mu, never before.
+type hitBox struct {
+	mu   sync.Mutex
+	list []types.HitEvent
+}
+
+func (b *hitBox) add(hit types.HitEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.list = append(b.list, hit)
+	if len(b.list) > maxHits {
+		b.list = append([]types.HitEvent(nil), b.list[len(b.list)-maxHits:]...)
+	}
+}
+
+// Shoot fires a projectile along (dirX, dirY) and returns its ID. seenMs is
+// the server time of the world the player aimed at: ServerNow less the
+// interpolation delay remote players are drawn with (see
+// InterpolationDelay), or 0 for the present.
+func (c *Client) Shoot(dirX, dirY float64, seenMs int64) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := types.Request{Type: types.ReqShoot, Player: c.player, ChunkID: c.chunk,
+		Shot: &types.Shot{DirX: dirX, DirY: dirY, SeenMs: seenMs}}
+	res, err := c.do(req)
+	if err != nil {
+		return "", err
+	}
+	if !res.Success {
+		return "", &RefusedError{Res: res}
+	}
+	return res.ProjectileID, nil
+}
+
+// Hits returns the hits pushed to the player since the last call, oldest
+// first.
+func (c *Client) Hits() []types.HitEvent {
+	c.hits.mu.Lock()
+	defer c.hits.mu.Unlock()
+	list := c.hits.list
+	c.hits.list = nil
+	return list
+}
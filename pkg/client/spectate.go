@@ -0,0 +1,233 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/chunkstore"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Spectating =====================
+
+// A Spectator watches chunks without playing in them, for a viewer or a
+// replay tool. It sends SPECTATE for each chunk, following ERR_NOT_OWNER to
+// the server that has it, and then takes the changes the servers push as
+// they happen, keeping a copy of each chunk up to date and acknowledging
+// each version it reaches with ACK, which later pushes are diffed from.
+// Subscriptions are
+// renewed every spectateRenew, and moved to a chunk's new owner when it
+// migrates.
+
+const (
+	// spectateRenew keeps subscriptions alive; servers drop them after 30s
+	spectateRenew = 10 * time.Second
+	// spectateRetry is how soon a SPECTATE that got no answer is resent
+	spectateRetry = time.Second
+)
+
+// SpectateUpdate is a spectated chunk as of one change.
+type SpectateUpdate struct {
+	ChunkID types.ChunkID `json:"chunk_id"`
+	Server  string        `json:"server"`
+	// Chunk is the whole chunk as the spectator now knows it.
+	Chunk types.Chunk `json:"chunk"`
+	// Delta is what changed, when the server sent only the changes.
+	Delta   *types.ChunkDelta   `json:"delta,omitempty"`
+	Version *types.ChunkVersion `json:"version,omitempty"`
+	// UpdateMs is how often the server now pushes this chunk (see
+	// InterpolationDelay).
+	UpdateMs int64 `json:"update_ms,omitempty"`
+}
+
+type watchedChunk struct {
+	server    string
+	chunk     types.Chunk
+	version   *types.ChunkVersion
+	confirmed bool
+	sent      time.Time
+}
+
+type Spectator struct {
+	conn   netproto.Transport
+	server string // asked first about every chunk
+
+	mu      sync.Mutex
+	watched map[types.ChunkID]*watchedChunk
+}
+
+// NewSpectator returns a spectator that starts out asking server, any game
+// server of the cluster, about the chunks it watches.
+func NewSpectator(network netproto.Network, server string) (*Spectator, error) {
+	conn, err := network.Listen("")
+	if err != nil {
+		return nil, err
+	}
+	return &Spectator{conn: conn, server: server, watched: make(map[types.ChunkID]*watchedChunk)}, nil
+}
+
+// Watch spectates chunks, calling fn with each one when it is first
+// received and after every change, until ctx is done. It unsubscribes and
+// closes the spectator on the way out.
+func (s *Spectator) Watch(ctx context.Context, chunks []types.ChunkID, fn func(SpectateUpdate)) error {
+	s.mu.Lock()
+	for _, chunk_id := range chunks {
+		s.watched[chunk_id] = &watchedChunk{server: s.server}
+	}
+	s.mu.Unlock()
+	defer s.close()
+
+	received := make(chan types.Response, 64)
+	go func() {
+		defer close(received)
+		for {
+			_, data, err := s.conn.Recv()
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			if err != nil {
+				continue
+			}
+			res, err := netproto.DecodeResponse(data)
+			if err != nil {
+				log.Printf("⚠️  Bad spectate message: %v", err)
+				continue
+			}
+			for _, pushed := range netproto.Unbatch(res) {
+				received <- pushed
+			}
+		}
+	}()
+
+	ticker := time.NewTicker(spectateRetry)
+	defer ticker.Stop()
+	s.subscribe(time.Now())
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case now := <-ticker.C:
+			s.subscribe(now)
+		case res, ok := <-received:
+			if !ok {
+				return errors.New("spectator connection closed")
+			}
+			if update, ok := s.take(res); ok {
+				fn(update)
+			}
+		}
+	}
+}
+
+// subscribe sends SPECTATE for every chunk due for it: unconfirmed ones
+// after spectateRetry, the others after spectateRenew.
+func (s *Spectator) subscribe(now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for chunk_id, w := range s.watched {
+		due := spectateRetry
+		if w.confirmed {
+			due = spectateRenew
+		}
+		if now.Sub(w.sent) < due {
+			continue
+		}
+		w.sent = now
+		req := types.Request{Type: types.ReqSpectate, ChunkID: chunk_id, TraceID: netproto.NewTraceID()}
+		if w.confirmed {
+			req.Since = w.version
+		}
+		netproto.SendJSON(s.conn, w.server, req)
+	}
+}
+
+// take applies one message from a server and returns the update it makes,
+// if any.
+func (s *Spectator) take(res types.Response) (SpectateUpdate, bool) {
+	if res.ChunkID == nil {
+		if res.Code == types.CodeNotOwner && res.RedirectIP != "" {
+			// not told which chunk; the next retry goes to the owner
+			s.redirectUnconfirmed(res.RedirectIP)
+		}
+		return SpectateUpdate{}, false
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	chunk_id := *res.ChunkID
+	w, ok := s.watched[chunk_id]
+	if !ok {
+		return SpectateUpdate{}, false
+	}
+	if !res.Success {
+		// the chunk moved: subscribe where it went
+		if res.RedirectIP != "" {
+			w.server = res.RedirectIP
+		}
+		w.confirmed, w.sent = false, time.Time{}
+		return SpectateUpdate{}, false
+	}
+
+	if w.version != nil && res.Version != nil && res.Version.Epoch == w.version.Epoch && res.Version.Seq <= w.version.Seq {
+		// a resend, or overtaken by a later update; the ack may have been
+		// lost
+		s.ack(chunk_id, w)
+		return SpectateUpdate{}, false
+	}
+	update := SpectateUpdate{ChunkID: chunk_id, Server: w.server, UpdateMs: res.UpdateMs}
+	switch {
+	case res.Delta != nil:
+		// deltas are from a version acknowledged earlier, so they apply to
+		// any held since
+		if w.version == nil || res.Delta.From.Epoch != w.version.Epoch || res.Delta.From.Seq > w.version.Seq {
+			// missed a change; a fresh SPECTATE brings the whole chunk
+			w.confirmed, w.sent = false, time.Time{}
+			return SpectateUpdate{}, false
+		}
+		chunkstore.ApplyDelta(&w.chunk, *res.Delta)
+		update.Delta = res.Delta
+	default:
+		w.chunk = res.GameData.Chunk
+	}
+	w.version = res.Version
+	w.confirmed = true
+	s.ack(chunk_id, w)
+	update.Version = res.Version
+	update.Chunk = chunkstore.Clone(w.chunk)
+	return update, true
+}
+
+// ack tells w's server the version of chunk_id now held. Must be called
+// with mu held.
+func (s *Spectator) ack(chunk_id types.ChunkID, w *watchedChunk) {
+	if w.version != nil {
+		netproto.SendJSON(s.conn, w.server, types.Request{Type: types.ReqAck, ChunkID: chunk_id, Since: w.version})
+	}
+}
+
+// redirectUnconfirmed points the chunks still waiting for an answer at
+// server.
+func (s *Spectator) redirectUnconfirmed(server string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, w := range s.watched {
+		if !w.confirmed {
+			w.server = server
+		}
+	}
+}
+
+func (s *Spectator) close() {
+	s.mu.Lock()
+	for chunk_id, w := range s.watched {
+		req := types.Request{Type: types.ReqSpectate, ChunkID: chunk_id, Unsubscribe: true, TraceID: netproto.NewTraceID()}
+		netproto.SendJSON(s.conn, w.server, req)
+	}
+	s.mu.Unlock()
+	s.conn.Close()
+}
@@ -0,0 +1,105 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Connection stats =====================
+
+// The client counts every attempt it sends, the replies it gets with their
+// round trips, the attempts that timed out and the redirects it follows.
+// Stats returns them; ReportStats sends them to the player's game server
+// with TELEMETRY every so often, and logs them too if LogStats is set, so
+// servers and central can tell how well players are served.
+
+// statsCounter has a lock of its own so Stats never waits on a round trip;
+// it is taken after Client.mu, never before.
+type statsCounter struct {
+	mu    sync.Mutex
+	stats types.ClientStats
+	rtt   time.Duration // sum over replies
+}
+
+// record counts one attempt that took rtt and ended with err.
+func (s *statsCounter) record(rtt time.Duration, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.Requests++
+	if err != nil {
+		if errors.Is(err, netproto.ErrTimeout) {
+			s.stats.Lost++
+		}
+		return
+	}
+	s.stats.Replies++
+	s.rtt += rtt
+	ms := float64(rtt.Microseconds()) / 1000
+	s.stats.RTTLastMs = ms
+	if s.stats.Replies == 1 || ms < s.stats.RTTMinMs {
+		s.stats.RTTMinMs = ms
+	}
+	if ms > s.stats.RTTMaxMs {
+		s.stats.RTTMaxMs = ms
+	}
+}
+
+func (s *statsCounter) redirect() {
+	s.mu.Lock()
+	s.stats.Redirects++
+	s.mu.Unlock()
+}
+
+// Stats returns what the client has measured of its requests so far.
+func (c *Client) Stats() types.ClientStats {
+	s := &c.stats
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats := s.stats
+	if stats.Requests > 0 {
+		stats.LossRate = float64(stats.Lost) / float64(stats.Requests)
+	}
+	if stats.Replies > 0 {
+		stats.RTTAvgMs = float64(s.rtt.Microseconds()) / 1000 / float64(stats.Replies)
+	}
+	return stats
+}
+
+// ReportStats sends the client's stats to its game server every period
+// until ctx is done or the player is kicked.
+func (c *Client) ReportStats(ctx context.Context, every time.Duration) {
+	go func() {
+		ticker := time.NewTicker(every)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			stats := c.Stats()
+			if c.LogStats {
+				log.Printf("📶 %s: rtt %.1fms avg (%.1f-%.1f), %d/%d lost (%.1f%%), %d redirects",
+					c.Player().ID, stats.RTTAvgMs, stats.RTTMinMs, stats.RTTMaxMs,
+					stats.Lost, stats.Requests, stats.LossRate*100, stats.Redirects)
+			}
+			c.mu.Lock()
+			req := types.Request{Type: types.ReqTelemetry, Player: c.player, ChunkID: c.chunk, Stats: &stats}
+			_, err := c.send(c.server, req)
+			c.mu.Unlock()
+			if errors.Is(err, ErrKicked) {
+				return
+			} else if err != nil {
+				log.Printf("⚠️  Telemetry for %s failed: %v", c.Player().ID, err)
+			}
+		}
+	}()
+}
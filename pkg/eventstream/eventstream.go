@@ -0,0 +1,74 @@
+// Package eventstream publishes messages to a broker, NATS or Kafka, for
+// consumers outside the cluster (analytics, moderation, other services).
+//
+// NATS is published to with nats.go, and Kafka with franz-go, which is
+// only built in with -tags kafka.
+package eventstream
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// A Message is published to Topic, a NATS subject or Kafka topic. Kafka
+// keeps messages with the same Key in order on one partition; NATS ignores
+// Key.
+type Message struct {
+	Topic string
+	Key   []byte
+	Value []byte
+}
+
+// A Publisher sends messages to a broker. Publish returns once the broker
+// has them, or with the error that stopped it; a failed Publish may have
+// delivered some of msgs. After an error the next Publish connects again.
+// A Publisher is not safe for concurrent use.
+type Publisher interface {
+	Name() string
+	Publish(ctx context.Context, msgs []Message) error
+	Close() error
+}
+
+// Open returns a Publisher for rawURL:
+//
+//	nats://[user:pass@]host:4222     (or nats://token@host:4222)
+//	kafka://host:9092[,host:9092...] (bootstrap brokers)
+//
+// It does not connect until the first Publish.
+func Open(rawURL string) (Publisher, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%q has no broker address", rawURL)
+	}
+	switch u.Scheme {
+	case "nats":
+		return newNATS(u), nil
+	case "kafka":
+		return newKafka(strings.Split(u.Host, ","))
+	}
+	return nil, fmt.Errorf("unknown event stream %q (nats:// or kafka://)", u.Scheme)
+}
+
+// Topic joins parts into a name both brokers accept: dot-separated, each
+// part reduced to letters, digits, '_' and '-' ("" becomes "_").
+func Topic(parts ...string) string {
+	clean := make([]string, len(parts))
+	for i, part := range parts {
+		clean[i] = strings.Map(func(r rune) rune {
+			switch {
+			case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_', r == '-':
+				return r
+			}
+			return '_'
+		}, part)
+		if clean[i] == "" {
+			clean[i] = "_"
+		}
+	}
+	return strings.Join(clean, ".")
+}
@@ -0,0 +1,56 @@
+//go:build kafka
+
+package eventstream
+
+import (
+	"context"
+
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+// ===================== Kafka =====================
+
+// kafkaPublisher produces with franz-go, waiting for each partition
+// leader's ack. A message goes to the partition its Key hashes to (as
+// Kafka's own clients hash it), or is spread over partitions without one.
+// franz-go looks topics up, creating them if the cluster allows, and
+// follows leaders as they move, retrying until Publish's context is done.
+//
+// franz-go brings its compression codecs along, so Kafka is only built in
+// with -tags kafka; a plain build refuses kafka:// URLs.
+
+const kafkaClientID = "gameserver"
+
+type kafkaPublisher struct {
+	client *kgo.Client
+}
+
+func newKafka(bootstrap []string) (Publisher, error) {
+	client, err := kgo.NewClient(
+		kgo.SeedBrokers(bootstrap...),
+		kgo.ClientID(kafkaClientID),
+		kgo.AllowAutoTopicCreation(),
+		kgo.RequiredAcks(kgo.LeaderAck()),
+		kgo.DisableIdempotentWrite(), // needs acks from every replica
+		kgo.ProducerBatchCompression(kgo.NoCompression()),
+	)
+	if err != nil {
+		return nil, err
+	}
+	return &kafkaPublisher{client: client}, nil
+}
+
+func (k *kafkaPublisher) Name() string { return "kafka" }
+
+func (k *kafkaPublisher) Close() error {
+	k.client.Close()
+	return nil
+}
+
+func (k *kafkaPublisher) Publish(ctx context.Context, msgs []Message) error {
+	records := make([]*kgo.Record, len(msgs))
+	for i, m := range msgs {
+		records[i] = &kgo.Record{Topic: m.Topic, Key: m.Key, Value: m.Value}
+	}
+	return k.client.ProduceSync(ctx, records...).FirstErr()
+}
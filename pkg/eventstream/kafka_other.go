@@ -0,0 +1,9 @@
+//go:build !kafka
+
+package eventstream
+
+import "errors"
+
+func newKafka(bootstrap []string) (Publisher, error) {
+	return nil, errors.New("this build has no Kafka support; build with -tags kafka")
+}
@@ -0,0 +1,260 @@
+//go:build kafka
+
+package eventstream
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/twmb/franz-go/pkg/kerr"
+	"github.com/twmb/franz-go/pkg/kmsg"
+)
+
+// fakeBroker is a one-node cluster on a local port answering ApiVersions,
+// Metadata and Produce, with fakePartitions partitions per topic. It keeps
+// what it was sent, or answers every produce with refuse.
+type fakeBroker struct {
+	t      *testing.T
+	ln     net.Listener
+	refuse int16
+
+	mu  sync.Mutex
+	got map[string]map[int32][]string // topic, partition: key=value
+}
+
+const fakePartitions = 2
+
+func newFakeBroker(t *testing.T, refuse int16) *fakeBroker {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	b := &fakeBroker{t: t, ln: ln, refuse: refuse, got: make(map[string]map[int32][]string)}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(conn, size[:]); err != nil {
+			return
+		}
+		msg := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(conn, msg); err != nil {
+			return
+		}
+		key, version, correlation := int16(binary.BigEndian.Uint16(msg)), int16(binary.BigEndian.Uint16(msg[2:])), msg[4:8]
+		body := msg[8:]
+		if n := int16(binary.BigEndian.Uint16(body)); n > 0 {
+			body = body[2+n:] // client ID
+		} else {
+			body = body[2:]
+		}
+		req := kmsg.RequestForKey(key)
+		if req == nil {
+			b.t.Errorf("unexpected request key %d", key)
+			return
+		}
+		req.SetVersion(version)
+		if req.IsFlexible() {
+			body = skipTags(body)
+		}
+		if err := req.ReadFrom(body); err != nil {
+			b.t.Errorf("reading %s v%d: %v", kmsg.NameForKey(key), version, err)
+			return
+		}
+
+		res := b.answer(req)
+		out := append([]byte{0, 0, 0, 0}, correlation...)
+		if res.IsFlexible() && key != kmsg.ApiVersions.Int16() {
+			out = append(out, 0) // no header tags
+		}
+		out = res.AppendTo(out)
+		binary.BigEndian.PutUint32(out, uint32(len(out)-4))
+		if _, err := conn.Write(out); err != nil {
+			return
+		}
+	}
+}
+
+func skipTags(b []byte) []byte {
+	n, size := binary.Uvarint(b)
+	b = b[size:]
+	for range n {
+		_, size = binary.Uvarint(b)
+		b = b[size:]
+		length, size := binary.Uvarint(b)
+		b = b[size+int(length):]
+	}
+	return b
+}
+
+func (b *fakeBroker) answer(req kmsg.Request) kmsg.Response {
+	switch req := req.(type) {
+	case *kmsg.ApiVersionsRequest:
+		res := req.ResponseKind().(*kmsg.ApiVersionsResponse)
+		for _, api := range [][3]int16{
+			{kmsg.ApiVersions.Int16(), 0, 3},
+			{kmsg.Metadata.Int16(), 1, 8},
+			{kmsg.Produce.Int16(), 3, 8},
+		} {
+			res.ApiKeys = append(res.ApiKeys, kmsg.ApiVersionsResponseApiKey{ApiKey: api[0], MinVersion: api[1], MaxVersion: api[2]})
+		}
+		return res
+
+	case *kmsg.MetadataRequest:
+		res := req.ResponseKind().(*kmsg.MetadataResponse)
+		host, port, _ := net.SplitHostPort(b.ln.Addr().String())
+		p, _ := strconv.Atoi(port)
+		res.Brokers = []kmsg.MetadataResponseBroker{{NodeID: 0, Host: host, Port: int32(p)}}
+		for _, topic := range req.Topics {
+			rt := kmsg.NewMetadataResponseTopic()
+			rt.Topic = topic.Topic
+			for i := range int32(fakePartitions) {
+				rp := kmsg.NewMetadataResponseTopicPartition()
+				rp.Partition, rp.Leader, rp.Replicas, rp.ISR = i, 0, []int32{0}, []int32{0}
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			res.Topics = append(res.Topics, rt)
+		}
+		return res
+
+	case *kmsg.ProduceRequest:
+		res := req.ResponseKind().(*kmsg.ProduceResponse)
+		for _, topic := range req.Topics {
+			rt := kmsg.NewProduceResponseTopic()
+			rt.Topic = topic.Topic
+			for _, partition := range topic.Partitions {
+				rp := kmsg.NewProduceResponseTopicPartition()
+				rp.Partition, rp.ErrorCode = partition.Partition, b.refuse
+				if b.refuse == 0 {
+					b.keep(topic.Topic, partition.Partition, partition.Records)
+				}
+				rt.Partitions = append(rt.Partitions, rp)
+			}
+			res.Topics = append(res.Topics, rt)
+		}
+		return res
+	}
+	b.t.Errorf("unexpected %s", kmsg.NameForKey(req.Key()))
+	return req.ResponseKind()
+}
+
+// keep records the key=value of each record in an uncompressed v2 batch.
+func (b *fakeBroker) keep(topic string, partition int32, raw []byte) {
+	var batch kmsg.RecordBatch
+	if err := batch.ReadFrom(raw); err != nil {
+		b.t.Errorf("reading a record batch: %v", err)
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.got[topic] == nil {
+		b.got[topic] = make(map[int32][]string)
+	}
+	records := batch.Records
+	for range batch.NumRecords {
+		length, size := binary.Varint(records)
+		var r kmsg.Record
+		if err := r.ReadFrom(records[:size+int(length)]); err != nil {
+			b.t.Errorf("reading a record: %v", err)
+			return
+		}
+		records = records[size+int(length):]
+		b.got[topic][partition] = append(b.got[topic][partition], string(r.Key)+"="+string(r.Value))
+	}
+}
+
+// sent returns the values the broker got for key on topic, in order, and
+// the partitions they were on.
+func (b *fakeBroker) sent(topic, key string) (values []string, partitions map[int32]bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	partitions = make(map[int32]bool)
+	for p := range int32(fakePartitions) {
+		for _, record := range b.got[topic][p] {
+			if k, v, _ := strings.Cut(record, "="); k == key {
+				values = append(values, v)
+				partitions[p] = true
+			}
+		}
+	}
+	return values, partitions
+}
+
+func publishCtx(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+func TestKafkaPublish(t *testing.T) {
+	b := newFakeBroker(t, 0)
+	pub, err := Open("kafka://" + b.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	msgs := []Message{
+		{Topic: "game.w1.spawn", Key: []byte("p1"), Value: []byte("1")},
+		{Topic: "game.w1.spawn", Key: []byte("p2"), Value: []byte("2")},
+		{Topic: "game.w1.spawn", Key: []byte("p1"), Value: []byte("3")},
+		{Topic: "game.w1.chat", Value: []byte("4")},
+	}
+	if err := pub.Publish(publishCtx(t), msgs); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	tests := []struct {
+		topic, key string
+		values     []string
+	}{
+		{"game.w1.spawn", "p1", []string{"1", "3"}},
+		{"game.w1.spawn", "p2", []string{"2"}},
+		{"game.w1.chat", "", []string{"4"}},
+	}
+	for _, tt := range tests {
+		values, partitions := b.sent(tt.topic, tt.key)
+		if !slices.Equal(values, tt.values) {
+			t.Errorf("%s key %q: broker got %v, want %v", tt.topic, tt.key, values, tt.values)
+		}
+		if len(partitions) > 1 {
+			t.Errorf("%s key %q spread over partitions %v", tt.topic, tt.key, partitions)
+		}
+	}
+}
+
+func TestKafkaPublishRefused(t *testing.T) {
+	b := newFakeBroker(t, kerr.TopicAuthorizationFailed.Code)
+	pub, err := Open("kafka://" + b.ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer pub.Close()
+
+	err = pub.Publish(publishCtx(t), []Message{{Topic: "game.w1.spawn", Value: []byte("1")}})
+	if !errors.Is(err, kerr.TopicAuthorizationFailed) {
+		t.Errorf("Publish: %v, want %v", err, kerr.TopicAuthorizationFailed)
+	}
+}
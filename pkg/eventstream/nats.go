@@ -0,0 +1,67 @@
+package eventstream
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// ===================== NATS =====================
+
+// natsPublisher publishes with nats.go, connecting on the first Publish.
+// A flush after each batch returns once the server has read it. nats.go
+// reconnects by itself after a dropped connection; only once it has given
+// up and closed the connection does the next Publish open a new one.
+
+const natsDialTimeout = 5 * time.Second
+
+type natsPublisher struct {
+	url string // with the user and password, or token, to connect as
+	nc  *nats.Conn
+}
+
+func newNATS(u *url.URL) *natsPublisher {
+	return &natsPublisher{url: u.String()}
+}
+
+func (n *natsPublisher) Name() string { return "nats" }
+
+func (n *natsPublisher) Close() error {
+	if n.nc != nil {
+		n.nc.Close()
+		n.nc = nil
+	}
+	return nil
+}
+
+func (n *natsPublisher) Publish(ctx context.Context, msgs []Message) error {
+	if n.nc == nil || n.nc.IsClosed() {
+		nc, err := nats.Connect(n.url, nats.Name("gameserver"), nats.Timeout(natsDialTimeout))
+		if err != nil {
+			return err
+		}
+		n.nc = nc
+	}
+
+	var tooLarge error
+	for _, m := range msgs {
+		err := n.nc.Publish(m.Topic, m.Value)
+		if errors.Is(err, nats.ErrMaxPayload) {
+			if tooLarge == nil {
+				tooLarge = fmt.Errorf("nats: %d-byte message to %s over max_payload %d", len(m.Value), m.Topic, n.nc.MaxPayload())
+			}
+			continue
+		}
+		if err != nil {
+			return errors.Join(err, tooLarge)
+		}
+	}
+	if err := n.nc.FlushWithContext(ctx); err != nil {
+		return errors.Join(err, tooLarge)
+	}
+	return tooLarge
+}
@@ -0,0 +1,92 @@
+// Package hlc is a hybrid logical clock: timestamps that follow wall time
+// but also order causally related events across machines whose clocks
+// disagree. A clock never hands out a timestamp below one it has handed
+// out or seen from a peer, so a write made after seeing another write is
+// stamped later even if this machine's clock runs behind.
+//
+// Timestamps are types.HLC, wall milliseconds and a 16-bit counter packed
+// into a uint64.
+package hlc
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+const logicalBits = 16
+
+// Make packs wall time and a counter into a timestamp.
+func Make(wall time.Time, logical uint16) types.HLC {
+	return types.HLC(uint64(wall.UnixMilli())<<logicalBits | uint64(logical))
+}
+
+// Wall is the wall time part of h.
+func Wall(h types.HLC) time.Time {
+	return time.UnixMilli(int64(h >> logicalBits))
+}
+
+// Logical is the counter part of h.
+func Logical(h types.HLC) uint16 {
+	return uint16(h)
+}
+
+// Clock hands out timestamps. It is safe for concurrent use.
+type Clock struct {
+	mu   sync.Mutex
+	last types.HLC
+	now  func() time.Time
+	// maxOffset is how far ahead of local wall time a peer's timestamp
+	// may be before Update refuses it.
+	maxOffset time.Duration
+}
+
+// New returns a clock that trusts peers' timestamps up to maxOffset ahead
+// of local wall time; 0 trusts any.
+func New(maxOffset time.Duration) *Clock {
+	return &Clock{now: time.Now, maxOffset: maxOffset}
+}
+
+// Now returns a timestamp later than every one the clock has returned or
+// been updated with.
+func (c *Clock) Now() types.HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.tick(0)
+}
+
+// Update moves the clock past remote, a timestamp seen from a peer, and
+// returns a timestamp later than both. A remote timestamp further ahead of
+// wall time than the clock's max offset is refused: the clock is left
+// alone and the error says by how much it was ahead.
+func (c *Clock) Update(remote types.HLC) (types.HLC, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.maxOffset > 0 {
+		if ahead := Wall(remote).Sub(c.now()); ahead > c.maxOffset {
+			return c.tick(0), fmt.Errorf("hlc: remote timestamp %v ahead of local clock (max %v)", ahead.Round(time.Millisecond), c.maxOffset)
+		}
+	}
+	return c.tick(remote), nil
+}
+
+// Last returns the latest timestamp the clock has returned.
+func (c *Clock) Last() types.HLC {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// tick advances the clock past remote. Must be called with mu held.
+func (c *Clock) tick(remote types.HLC) types.HLC {
+	latest := max(c.last, remote)
+	if wall := Make(c.now(), 0); wall > latest {
+		c.last = wall
+	} else {
+		// the counter overflowing borrows from the next millisecond
+		c.last = latest + 1
+	}
+	return c.last
+}
@@ -0,0 +1,152 @@
+package hlc
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+var epoch = time.UnixMilli(1_700_000_000_000)
+
+// ms is the timestamp at epoch plus wall milliseconds, with counter logical.
+func ms(wall int64, logical uint16) types.HLC {
+	return Make(epoch.Add(time.Duration(wall)*time.Millisecond), logical)
+}
+
+// fakeClock returns a clock whose wall time is epoch plus *wall
+// milliseconds.
+func fakeClock(maxOffset time.Duration, wall *int64) *Clock {
+	c := New(maxOffset)
+	c.now = func() time.Time { return epoch.Add(time.Duration(*wall) * time.Millisecond) }
+	return c
+}
+
+func TestMakeRoundTrip(t *testing.T) {
+	for _, logical := range []uint16{0, 1, 0xffff} {
+		h := Make(epoch, logical)
+		if !Wall(h).Equal(epoch) || Logical(h) != logical {
+			t.Errorf("Make(%v, %d) came back as %v, %d", epoch, logical, Wall(h), Logical(h))
+		}
+	}
+	// the wall time orders before the counter
+	if !(ms(0, 0xffff) < ms(1, 0)) {
+		t.Error("a later millisecond does not order after a full counter")
+	}
+}
+
+// A step advances the local wall clock to wall, then either asks for Now
+// (remote 0) or merges remote with Update.
+type step struct {
+	wall   int64
+	remote types.HLC
+	want   types.HLC
+	err    bool
+}
+
+func TestMergeOrdering(t *testing.T) {
+	tests := []struct {
+		name      string
+		maxOffset time.Duration
+		steps     []step
+	}{
+		{"follows wall time", 0, []step{
+			{wall: 0, want: ms(0, 0)},
+			{wall: 5, want: ms(5, 0)},
+			{wall: 9, want: ms(9, 0)},
+		}},
+		{"same millisecond counts up", 0, []step{
+			{wall: 3, want: ms(3, 0)},
+			{wall: 3, want: ms(3, 1)},
+			{wall: 3, want: ms(3, 2)},
+		}},
+		{"wall clock stepping back", 0, []step{
+			{wall: 10, want: ms(10, 0)},
+			{wall: 4, want: ms(10, 1)},
+			{wall: 11, want: ms(11, 0)},
+		}},
+		{"remote ahead", 0, []step{
+			{wall: 1, want: ms(1, 0)},
+			{wall: 2, remote: ms(50, 7), want: ms(50, 8)},
+			// local time has not caught up, so it keeps counting past the remote
+			{wall: 3, want: ms(50, 9)},
+			{wall: 60, want: ms(60, 0)},
+		}},
+		{"remote behind", 0, []step{
+			{wall: 20, want: ms(20, 0)},
+			{wall: 21, remote: ms(5, 3), want: ms(21, 0)},
+		}},
+		{"remote equal to the last", 0, []step{
+			{wall: 20, want: ms(20, 0)},
+			{wall: 20, remote: ms(20, 0), want: ms(20, 1)},
+		}},
+		{"remote in the same millisecond, further on", 0, []step{
+			{wall: 20, want: ms(20, 0)},
+			{wall: 20, remote: ms(20, 5), want: ms(20, 6)},
+		}},
+		{"counter overflow borrows a millisecond", 0, []step{
+			{wall: 7, remote: ms(7, 0xfffe), want: ms(7, 0xffff)},
+			{wall: 7, want: ms(8, 0)},
+		}},
+		{"within the max offset", time.Second, []step{
+			{wall: 0, remote: ms(1000, 0), want: ms(1000, 1)},
+		}},
+		{"past the max offset", time.Second, []step{
+			{wall: 0, want: ms(0, 0)},
+			{wall: 0, remote: ms(1001, 0), want: ms(0, 1), err: true},
+			// the refused remote did not move the clock
+			{wall: 2, want: ms(2, 0)},
+		}},
+		{"max offset 0 trusts any", 0, []step{
+			{wall: 0, remote: ms(3_600_000, 0), want: ms(3_600_000, 1)},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var wall int64
+			c := fakeClock(tt.maxOffset, &wall)
+			for i, s := range tt.steps {
+				wall = s.wall
+				var got types.HLC
+				var err error
+				if s.remote == 0 {
+					got = c.Now()
+				} else {
+					got, err = c.Update(s.remote)
+				}
+				if (err != nil) != s.err {
+					t.Errorf("step %d: err = %v, want error %v", i, err, s.err)
+				}
+				if got != s.want {
+					t.Errorf("step %d: got %v+%d, want %v+%d", i,
+						Wall(got).Sub(epoch), Logical(got), Wall(s.want).Sub(epoch), Logical(s.want))
+				}
+				if c.Last() != got {
+					t.Errorf("step %d: Last = %v, want %v", i, c.Last(), got)
+				}
+			}
+		})
+	}
+}
+
+// TestCausalOrder sends messages back and forth between two clocks whose
+// wall times disagree: each receive must stamp after its send.
+func TestCausalOrder(t *testing.T) {
+	var fastWall, slowWall int64 = 1000, 0
+	fast, slow := fakeClock(0, &fastWall), fakeClock(0, &slowWall)
+
+	sent := fast.Now()
+	for i := 0; i < 10; i++ {
+		got, _ := slow.Update(sent)
+		if got <= sent {
+			t.Fatalf("round %d: slow stamped %v after receiving %v", i, got, sent)
+		}
+		sent = slow.Now()
+		slowWall++
+		got, _ = fast.Update(sent)
+		if got <= sent {
+			t.Fatalf("round %d: fast stamped %v after receiving %v", i, got, sent)
+		}
+		sent = got
+	}
+}
@@ -0,0 +1,222 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/pprof"
+	"runtime/trace"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// ===================== Debug endpoints =====================
+
+// HandleDebug registers the profiling endpoints with handle, which should
+// put them behind the admin token:
+//
+//	/debug/pprof/             index of the profiles
+//	/debug/pprof/profile      CPU profile over ?seconds= (default 30)
+//	/debug/pprof/trace        execution trace over ?seconds= (default 5)
+//	/debug/pprof/NAME         heap, allocs, goroutine, mutex, block, threadcreate (?debug=1 for text, ?gc=1 to collect first)
+//	/debug/goroutines         every goroutine's stack, as text
+//	/debug/heap               a heap profile taken after a GC, as a download
+//	/debug/runtime            goroutines, memory and GC as JSON; POST ?mutex=N&block=N sets the contention sampling rates
+//
+// They mirror net/http/pprof, which is not imported because it registers
+// its handlers on http.DefaultServeMux for anyone to call. Fetch a profile
+// with the token and open the file as usual:
+//
+//	curl -H "X-Admin-Token: $TOKEN" -o cpu.pprof host:9100/debug/pprof/profile?seconds=30
+//	go tool pprof -http=: cpu.pprof
+func HandleDebug(handle func(pattern string, h http.HandlerFunc)) {
+	handle("/debug/pprof/", debugProfile)
+	handle("/debug/pprof/profile", debugCPUProfile)
+	handle("/debug/pprof/trace", debugTrace)
+	handle("/debug/goroutines", debugGoroutines)
+	handle("/debug/heap", debugHeap)
+	handle("/debug/runtime", debugRuntime)
+}
+
+const maxProfileSeconds = 300
+
+// mutexFraction and blockRate are the sampling rates last set, which the
+// runtime does not report back.
+var mutexFraction, blockRate atomic.Int64
+
+// profileSeconds reads ?seconds=, defaulting to def.
+func profileSeconds(r *http.Request, def int) (time.Duration, error) {
+	s := r.URL.Query().Get("seconds")
+	if s == "" {
+		return time.Duration(def) * time.Second, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n <= 0 || n > maxProfileSeconds {
+		return 0, fmt.Errorf("seconds must be 1 to %d", maxProfileSeconds)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// download marks the response as a file named after the host, kind and
+// time, so snapshots from several servers don't overwrite each other.
+func download(w http.ResponseWriter, kind, ext string) {
+	host, _ := os.Hostname()
+	name := fmt.Sprintf("%s-%s-%s.%s", kind, host, time.Now().Format("20060102-150405"), ext)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+}
+
+// sleepRequest waits d, or less if the client goes away.
+func sleepRequest(r *http.Request, d time.Duration) {
+	select {
+	case <-time.After(d):
+	case <-r.Context().Done():
+	}
+}
+
+func debugProfile(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/debug/pprof/")
+	if name == "" {
+		debugIndex(w)
+		return
+	}
+	p := pprof.Lookup(name)
+	if p == nil {
+		http.Error(w, "Unknown profile "+strconv.Quote(name), http.StatusNotFound)
+		return
+	}
+	debug, _ := strconv.Atoi(r.URL.Query().Get("debug"))
+	if name == "heap" && r.URL.Query().Get("gc") != "" {
+		runtime.GC()
+	}
+	if debug > 0 {
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	} else {
+		download(w, name, "pprof")
+	}
+	p.WriteTo(w, debug)
+}
+
+func debugIndex(w http.ResponseWriter) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	fmt.Fprintln(w, "profiles (/debug/pprof/NAME, ?debug=1 for text):")
+	for _, p := range pprof.Profiles() {
+		fmt.Fprintf(w, "  %-14s %d\n", p.Name(), p.Count())
+	}
+	fmt.Fprintln(w, "\nalso: /debug/pprof/profile?seconds=30  /debug/pprof/trace?seconds=5  /debug/goroutines  /debug/heap  /debug/runtime")
+}
+
+func debugCPUProfile(w http.ResponseWriter, r *http.Request) {
+	d, err := profileSeconds(r, 30)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	download(w, "cpu", "pprof")
+	if err := pprof.StartCPUProfile(w); err != nil {
+		// most likely another profile is running
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Could not start the CPU profile: "+err.Error(), http.StatusConflict)
+		return
+	}
+	sleepRequest(r, d)
+	pprof.StopCPUProfile()
+}
+
+func debugTrace(w http.ResponseWriter, r *http.Request) {
+	d, err := profileSeconds(r, 5)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	download(w, "trace", "out")
+	if err := trace.Start(w); err != nil {
+		w.Header().Del("Content-Disposition")
+		http.Error(w, "Could not start the trace: "+err.Error(), http.StatusConflict)
+		return
+	}
+	sleepRequest(r, d)
+	trace.Stop()
+}
+
+func debugGoroutines(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+}
+
+func debugHeap(w http.ResponseWriter, r *http.Request) {
+	runtime.GC()
+	download(w, "heap", "pprof")
+	pprof.Lookup("heap").WriteTo(w, 0)
+}
+
+// RuntimeStats is what /debug/runtime reports.
+type RuntimeStats struct {
+	GoVersion     string  `json:"go_version"`
+	GOMAXPROCS    int     `json:"gomaxprocs"`
+	NumCPU        int     `json:"num_cpu"`
+	Goroutines    int     `json:"goroutines"`
+	HeapAlloc     uint64  `json:"heap_alloc_bytes"`
+	HeapInuse     uint64  `json:"heap_inuse_bytes"`
+	HeapObjects   uint64  `json:"heap_objects"`
+	Sys           uint64  `json:"sys_bytes"`
+	TotalAlloc    uint64  `json:"total_alloc_bytes"`
+	Mallocs       uint64  `json:"mallocs"`
+	NumGC         uint32  `json:"num_gc"`
+	LastGCPauseMs float64 `json:"last_gc_pause_ms"`
+	GCCPUFraction float64 `json:"gc_cpu_fraction"`
+	MutexFraction int64   `json:"mutex_profile_fraction"`
+	BlockRate     int64   `json:"block_profile_rate"`
+}
+
+func debugRuntime(w http.ResponseWriter, r *http.Request) {
+	if r.Method == http.MethodPost {
+		rates := make(map[string]int)
+		for _, param := range []string{"mutex", "block"} {
+			s := r.URL.Query().Get(param)
+			if s == "" {
+				continue
+			}
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				http.Error(w, "Invalid "+param+" rate "+strconv.Quote(s), http.StatusBadRequest)
+				return
+			}
+			rates[param] = n
+		}
+		if n, ok := rates["mutex"]; ok {
+			runtime.SetMutexProfileFraction(n)
+			mutexFraction.Store(int64(n))
+		}
+		if n, ok := rates["block"]; ok {
+			runtime.SetBlockProfileRate(n)
+			blockRate.Store(int64(n))
+		}
+	}
+
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	stats := RuntimeStats{
+		GoVersion:     runtime.Version(),
+		GOMAXPROCS:    runtime.GOMAXPROCS(0),
+		NumCPU:        runtime.NumCPU(),
+		Goroutines:    runtime.NumGoroutine(),
+		HeapAlloc:     ms.HeapAlloc,
+		HeapInuse:     ms.HeapInuse,
+		HeapObjects:   ms.HeapObjects,
+		Sys:           ms.Sys,
+		TotalAlloc:    ms.TotalAlloc,
+		Mallocs:       ms.Mallocs,
+		NumGC:         ms.NumGC,
+		LastGCPauseMs: float64(ms.PauseNs[(ms.NumGC+255)%256]) / 1e6,
+		GCCPUFraction: ms.GCCPUFraction,
+		MutexFraction: mutexFraction.Load(),
+		BlockRate:     blockRate.Load(),
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(stats)
+}
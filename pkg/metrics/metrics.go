@@ -0,0 +1,191 @@
+// Package metrics is a minimal Prometheus text-format registry shared by the
+// game server and the central server. Every metric has at most one label; an
+// empty label name means the metric is unlabelled.
+package metrics
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets suit request latencies from sub-millisecond to seconds.
+var DefaultBuckets = []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5}
+
+type metric interface {
+	write(sb *strings.Builder)
+}
+
+var (
+	registry   []metric
+	registryMu sync.Mutex
+)
+
+func register(m metric) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry = append(registry, m)
+}
+
+type CounterVec struct {
+	sync.Mutex
+	name   string
+	help   string
+	label  string
+	values map[string]float64
+}
+
+func NewCounterVec(name, help, label string) *CounterVec {
+	c := &CounterVec{name: name, help: help, label: label, values: make(map[string]float64)}
+	register(c)
+	return c
+}
+
+func (c *CounterVec) Add(labelValue string, v float64) {
+	c.Lock()
+	defer c.Unlock()
+	c.values[labelValue] += v
+}
+
+func (c *CounterVec) Inc(labelValue string) {
+	c.Add(labelValue, 1)
+}
+
+func (c *CounterVec) write(sb *strings.Builder) {
+	c.Lock()
+	defer c.Unlock()
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s counter\n", c.name, c.help, c.name)
+	for _, lv := range sortedKeys(c.values) {
+		fmt.Fprintf(sb, "%s%s %s\n", c.name, labels(c.label, lv, ""), formatFloat(c.values[lv]))
+	}
+}
+
+// GaugeFunc is evaluated on every scrape, so gauges always reflect live state.
+type GaugeFunc struct {
+	name  string
+	help  string
+	label string
+	fn    func() map[string]float64
+}
+
+func NewGaugeFunc(name, help, label string, fn func() map[string]float64) *GaugeFunc {
+	g := &GaugeFunc{name: name, help: help, label: label, fn: fn}
+	register(g)
+	return g
+}
+
+func (g *GaugeFunc) write(sb *strings.Builder) {
+	values := g.fn()
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s gauge\n", g.name, g.help, g.name)
+	for _, lv := range sortedKeys(values) {
+		fmt.Fprintf(sb, "%s%s %s\n", g.name, labels(g.label, lv, ""), formatFloat(values[lv]))
+	}
+}
+
+type histogram struct {
+	counts []uint64
+	sum    float64
+	count  uint64
+}
+
+type HistogramVec struct {
+	sync.Mutex
+	name    string
+	help    string
+	label   string
+	buckets []float64
+	series  map[string]*histogram
+}
+
+func NewHistogramVec(name, help, label string, buckets []float64) *HistogramVec {
+	h := &HistogramVec{name: name, help: help, label: label, buckets: buckets, series: make(map[string]*histogram)}
+	register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(labelValue string, v float64) {
+	h.Lock()
+	defer h.Unlock()
+	s, ok := h.series[labelValue]
+	if !ok {
+		s = &histogram{counts: make([]uint64, len(h.buckets))}
+		h.series[labelValue] = s
+	}
+	for i, upper := range h.buckets {
+		if v <= upper {
+			s.counts[i]++
+		}
+	}
+	s.sum += v
+	s.count++
+}
+
+func (h *HistogramVec) write(sb *strings.Builder) {
+	h.Lock()
+	defer h.Unlock()
+	fmt.Fprintf(sb, "# HELP %s %s\n# TYPE %s histogram\n", h.name, h.help, h.name)
+	keys := make([]string, 0, len(h.series))
+	for k := range h.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, lv := range keys {
+		s := h.series[lv]
+		for i, upper := range h.buckets {
+			fmt.Fprintf(sb, "%s_bucket%s %d\n", h.name, labels(h.label, lv, formatFloat(upper)), s.counts[i])
+		}
+		fmt.Fprintf(sb, "%s_bucket%s %d\n", h.name, labels(h.label, lv, "+Inf"), s.count)
+		fmt.Fprintf(sb, "%s_sum%s %s\n", h.name, labels(h.label, lv, ""), formatFloat(s.sum))
+		fmt.Fprintf(sb, "%s_count%s %d\n", h.name, labels(h.label, lv, ""), s.count)
+	}
+}
+
+// Handler serves every registered metric.
+func Handler(w http.ResponseWriter, r *http.Request) {
+	registryMu.Lock()
+	metrics := append([]metric(nil), registry...)
+	registryMu.Unlock()
+
+	var sb strings.Builder
+	for _, m := range metrics {
+		m.write(&sb)
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	w.Write([]byte(sb.String()))
+}
+
+// ===================== Helpers =====================
+
+func labels(name, value, le string) string {
+	var parts []string
+	if name != "" {
+		parts = append(parts, fmt.Sprintf("%s=%q", name, value))
+	}
+	if le != "" {
+		parts = append(parts, fmt.Sprintf("le=%q", le))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	if math.IsInf(v, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func sortedKeys(m map[string]float64) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
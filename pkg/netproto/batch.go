@@ -0,0 +1,150 @@
+package netproto
+
+import (
+	"net"
+
+	"golang.org/x/net/ipv4"
+)
+
+// ===================== Batched I/O =====================
+
+// On Linux a UDP socket can move many datagrams per system call
+// (recvmmsg/sendmmsg). Server sockets, the ones bound to a given address,
+// read up to RecvBatch datagrams at a time and hand them out one by one from
+// Recv; SendBatch sends a whole fan-out, such as a tick's pushes, at once.
+// Other platforms read and write one datagram per call, as before.
+
+// RecvBatch is how many datagrams a server socket reads per call; 0 or 1
+// reads one at a time. Each slot holds a MaxDatagram buffer. It must be set
+// before the sockets are opened, as by a -udp-batch flag.
+var RecvBatch = 32
+
+// A Message is one message of a SendBatch.
+type Message struct {
+	To   string
+	Data []byte
+}
+
+// batchSender is implemented by Transports that can send several messages
+// in one go.
+type batchSender interface {
+	sendBatch(msgs []Message) error
+}
+
+// SendBatch sends each message as t.Send would, in as few system calls as t
+// manages. A message that fails does not stop the rest; the first error is
+// returned.
+func SendBatch(t Transport, msgs []Message) error {
+	if b, ok := t.(batchSender); ok {
+		return b.sendBatch(msgs)
+	}
+	return sendEach(t, msgs)
+}
+
+func sendEach(t Transport, msgs []Message) error {
+	var first error
+	for _, m := range msgs {
+		if err := t.Send(m.To, m.Data); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// recvBatch holds the datagrams of the last batched read until Recv has
+// handed them all out.
+type recvBatch struct {
+	msgs  []ipv4.Message
+	next  int
+	count int
+}
+
+func newRecvBatch(size int) *recvBatch {
+	b := &recvBatch{msgs: make([]ipv4.Message, size)}
+	for i := range b.msgs {
+		b.msgs[i].Buffers = [][]byte{make([]byte, MaxDatagram)}
+	}
+	return b
+}
+
+// read returns a copy of the next datagram, reading another batch from pc
+// when the last is used up.
+func (b *recvBatch) read(pc *ipv4.PacketConn) (string, []byte, error) {
+	for b.next == b.count {
+		n, err := pc.ReadBatch(b.msgs, 0)
+		if err != nil {
+			return "", nil, err
+		}
+		b.next, b.count = 0, n
+	}
+	m := &b.msgs[b.next]
+	b.next++
+	return m.Addr.String(), append([]byte(nil), m.Buffers[0][:m.N]...), nil
+}
+
+// batchReads makes u read RecvBatch datagrams per call. Only Linux gains
+// from it.
+func (u *udpTransport) batchReads() {
+	if RecvBatch > 1 && batchedIO {
+		u.batch = newRecvBatch(RecvBatch)
+	}
+}
+
+// sendBatch writes every fragment of msgs with WriteBatch. x/net only
+// writes IPv4 socket addresses, so a dual-stack socket or a connected one
+// sends one at a time.
+func (u *udpTransport) sendBatch(msgs []Message) error {
+	if !batchedIO || u.peer != "" || !u.inet4 {
+		return sendEach(u, msgs)
+	}
+
+	var first error
+	var out []ipv4.Message
+	for _, m := range msgs {
+		frags, err := u.frag.split(m.Data)
+		if err == nil {
+			var addr *net.UDPAddr
+			if addr, err = net.ResolveUDPAddr("udp", m.To); err == nil {
+				for _, frag := range frags {
+					out = append(out, ipv4.Message{Buffers: [][]byte{frag}, Addr: addr})
+				}
+			}
+		}
+		if err != nil && first == nil {
+			first = err
+		}
+	}
+	for len(out) > 0 {
+		n, err := u.pc.WriteBatch(out, 0)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			// sendmmsg only fails on the first datagram it tries: skip it
+			// and go on with the rest
+			n = max(n, 0) + 1
+		}
+		out = out[min(n, len(out)):]
+	}
+	return first
+}
+
+func (t *openingTransport) sendBatch(msgs []Message) error {
+	sealedMsgs := make([]Message, 0, len(msgs))
+	var first error
+	for _, m := range msgs {
+		data, err := t.net.sealFor(m.To, m.Data)
+		if err != nil {
+			if first == nil {
+				first = err
+			}
+			continue
+		}
+		m.Data = data
+		sealedMsgs = append(sealedMsgs, m)
+	}
+	if err := SendBatch(t.Transport, sealedMsgs); first == nil {
+		first = err
+	}
+	return first
+}
@@ -0,0 +1,5 @@
+package netproto
+
+// batchedIO reports whether x/net's ReadBatch and WriteBatch use
+// recvmmsg/sendmmsg here rather than one datagram per call.
+const batchedIO = true
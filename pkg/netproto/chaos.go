@@ -0,0 +1,88 @@
+package netproto
+
+import (
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ===================== Chaos =====================
+
+// ChaosConfig describes the failures a Chaos network injects on every Send.
+type ChaosConfig struct {
+	LossRate float64       // probability a datagram is dropped
+	DupRate  float64       // probability a datagram is sent twice
+	MaxDelay time.Duration // sends are delayed uniformly in [0, MaxDelay)
+}
+
+// Enabled reports whether any failure is configured.
+func (c ChaosConfig) Enabled() bool {
+	return c.LossRate > 0 || c.DupRate > 0 || c.MaxDelay > 0
+}
+
+// Chaos wraps a Network so every Transport it hands out drops, duplicates and
+// delays outgoing datagrams. Delayed datagrams overtake each other, which is
+// how reordering shows up. It is meant for validating the MERGE and
+// FROM_CENTRAL handoffs against a lossy network, never for production.
+type Chaos struct {
+	Network
+	cfg ChaosConfig
+
+	mu  sync.Mutex
+	rng *rand.Rand
+}
+
+func NewChaos(n Network, cfg ChaosConfig, seed int64) *Chaos {
+	return &Chaos{Network: n, cfg: cfg, rng: rand.New(rand.NewSource(seed))}
+}
+
+func (c *Chaos) Listen(addr string) (Transport, error) {
+	t, err := c.Network.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosTransport{Transport: t, chaos: c}, nil
+}
+
+// plan decides the fate of one datagram: how many copies go out and after
+// what delay each.
+func (c *Chaos) plan() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.rng.Float64() < c.cfg.LossRate {
+		return nil
+	}
+	copies := 1
+	if c.rng.Float64() < c.cfg.DupRate {
+		copies = 2
+	}
+	delays := make([]time.Duration, copies)
+	for i := range delays {
+		if c.cfg.MaxDelay > 0 {
+			delays[i] = time.Duration(c.rng.Int63n(int64(c.cfg.MaxDelay)))
+		}
+	}
+	return delays
+}
+
+type chaosTransport struct {
+	Transport
+	chaos *Chaos
+}
+
+// Send reports success for dropped and delayed datagrams, as a real UDP
+// socket would; errors from delayed sends are lost.
+func (t *chaosTransport) Send(to string, data []byte) error {
+	var err error
+	for _, delay := range t.chaos.plan() {
+		if delay == 0 {
+			if sendErr := t.Transport.Send(to, data); sendErr != nil {
+				err = sendErr
+			}
+			continue
+		}
+		pkt := append([]byte(nil), data...)
+		time.AfterFunc(delay, func() { t.Transport.Send(to, pkt) })
+	}
+	return err
+}
@@ -0,0 +1,101 @@
+package netproto
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Codecs =====================
+
+// A Codec turns the messages sent over a Transport into bytes and back.
+// Every codec writes the same JSON; they differ in how fast they do it.
+type Codec interface {
+	Name() string
+	Marshal(v any) ([]byte, error)
+	Unmarshal(data []byte, v any) error
+}
+
+var (
+	// Reflect is encoding/json.
+	Reflect Codec = reflectCodec{}
+	// Generated uses the codecs generated for the wire structs
+	// (types.Request, types.Response, types.Chunk), and encoding/json for
+	// anything else.
+	Generated Codec = generatedCodec{}
+)
+
+// Wire is the codec requests and responses are sent with. It must only be
+// changed before anything is sent, as by a -codec flag.
+var Wire = Generated
+
+// Codecs lists the codecs by name, for flags.
+var Codecs = map[string]Codec{Reflect.Name(): Reflect, Generated.Name(): Generated}
+
+// CodecByName returns the codec called name.
+func CodecByName(name string) (Codec, error) {
+	c, ok := Codecs[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown codec %q (json or generated)", name)
+	}
+	return c, nil
+}
+
+type reflectCodec struct{}
+
+func (reflectCodec) Name() string                       { return "json" }
+func (reflectCodec) Marshal(v any) ([]byte, error)      { return json.Marshal(v) }
+func (reflectCodec) Unmarshal(data []byte, v any) error { return json.Unmarshal(data, v) }
+
+// appender and decoder are what the generated codecs implement.
+type (
+	appender interface {
+		AppendJSON(b []byte) ([]byte, error)
+	}
+	decoder interface {
+		DecodeJSON(data []byte) error
+	}
+)
+
+type generatedCodec struct{}
+
+func (generatedCodec) Name() string { return "generated" }
+
+// marshalBufs holds the buffers generatedCodec.Marshal writes into, so a
+// large chunk is not regrown from scratch every time; what it returns is an
+// exact-size copy.
+var marshalBufs = sync.Pool{New: func() any { b := make([]byte, 0, 1024); return &b }}
+
+func (generatedCodec) Marshal(v any) ([]byte, error) {
+	bp := marshalBufs.Get().(*[]byte)
+	defer marshalBufs.Put(bp)
+	var b []byte
+	var err error
+	switch x := v.(type) {
+	case appender:
+		b, err = x.AppendJSON((*bp)[:0])
+	// the wire structs are usually passed by value
+	case types.Request:
+		b, err = x.AppendJSON((*bp)[:0])
+	case types.Response:
+		b, err = x.AppendJSON((*bp)[:0])
+	case types.Chunk:
+		b, err = x.AppendJSON((*bp)[:0])
+	default:
+		return json.Marshal(v)
+	}
+	if err != nil {
+		return nil, err
+	}
+	*bp = b
+	return append([]byte(nil), b...), nil
+}
+
+func (generatedCodec) Unmarshal(data []byte, v any) error {
+	if d, ok := v.(decoder); ok {
+		return d.DecodeJSON(data)
+	}
+	return json.Unmarshal(data, v)
+}
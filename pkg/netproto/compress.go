@@ -0,0 +1,135 @@
+package netproto
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Compression =====================
+
+// Payload encodings. A client lists those it decodes in
+// Request.AcceptEncoding, comma-separated and best first, and the server
+// answers with the first it knows in Response.Encoding. Clients that list
+// only gzip keep getting gzip.
+const (
+	EncodingZstd = "zstd"
+	EncodingGzip = "gzip"
+	// AcceptEncodings is what DecodeResponse decodes, best first.
+	AcceptEncodings = EncodingZstd + "," + EncodingGzip
+)
+
+// maxDecoded caps a decompressed payload, so a forged one cannot take the
+// memory of whoever decodes it.
+const maxDecoded = 64 << 20
+
+// Both are safe for concurrent use by EncodeAll and DecodeAll.
+var (
+	zstdEncoder, _ = zstd.NewWriter(nil, zstd.WithEncoderLevel(zstd.SpeedDefault))
+	zstdDecoder, _ = zstd.NewReader(nil, zstd.WithDecoderConcurrency(0), zstd.WithDecoderMaxMemory(maxDecoded))
+)
+
+// pickEncoding returns the first encoding of accept this package knows, or "".
+func pickEncoding(accept string) string {
+	for _, name := range strings.Split(accept, ",") {
+		switch name = strings.TrimSpace(name); name {
+		case EncodingZstd, EncodingGzip:
+			return name
+		}
+	}
+	return ""
+}
+
+// compressedBody is the part of a Response that gets compressed: the chunk
+// data, which is what grows with cubes and players.
+type compressedBody struct {
+	Chunk    types.Chunk    `json:"chunk"`
+	GameData types.GameData `json:"game_data"`
+}
+
+// CompressResponse moves res's chunk data into a compressed Payload when the
+// client accepts an encoding (see pickEncoding) and the data is at least
+// minSize bytes. Smaller or incompressible data is left alone.
+func CompressResponse(res *types.Response, accept string, minSize int) error {
+	encoding := pickEncoding(accept)
+	if encoding == "" {
+		return nil
+	}
+	body, err := json.Marshal(compressedBody{Chunk: res.Chunk, GameData: res.GameData})
+	if err != nil {
+		return err
+	}
+	if len(body) < minSize {
+		return nil
+	}
+
+	var payload []byte
+	if encoding == EncodingZstd {
+		payload = zstdEncoder.EncodeAll(body, nil)
+	} else {
+		var buf bytes.Buffer
+		zw := gzip.NewWriter(&buf)
+		if _, err := zw.Write(body); err != nil {
+			return err
+		}
+		if err := zw.Close(); err != nil {
+			return err
+		}
+		payload = buf.Bytes()
+	}
+	if len(payload) >= len(body) {
+		return nil
+	}
+
+	res.Encoding = encoding
+	res.Payload = payload
+	res.Chunk = types.Chunk{}
+	res.GameData = types.GameData{}
+	return nil
+}
+
+// DecodeResponse unmarshals a response and restores a compressed Payload.
+func DecodeResponse(data []byte) (types.Response, error) {
+	var res types.Response
+	if err := Wire.Unmarshal(data, &res); err != nil {
+		return res, err
+	}
+	var body []byte
+	switch res.Encoding {
+	case "":
+		return res, nil
+	case EncodingZstd:
+		var err error
+		if body, err = zstdDecoder.DecodeAll(res.Payload, nil); err != nil {
+			return res, err
+		}
+	case EncodingGzip:
+		zr, err := gzip.NewReader(bytes.NewReader(res.Payload))
+		if err != nil {
+			return res, err
+		}
+		if body, err = io.ReadAll(io.LimitReader(zr, maxDecoded+1)); err != nil {
+			return res, err
+		}
+		if len(body) > maxDecoded {
+			return res, fmt.Errorf("gzip payload over %d bytes", maxDecoded)
+		}
+	default:
+		return res, fmt.Errorf("unknown response encoding %q", res.Encoding)
+	}
+
+	var cb compressedBody
+	if err := json.Unmarshal(body, &cb); err != nil {
+		return res, err
+	}
+	res.Chunk, res.GameData = cb.Chunk, cb.GameData
+	res.Encoding, res.Payload = "", nil
+	return res, nil
+}
@@ -0,0 +1,51 @@
+package netproto
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+func TestCompressResponse(t *testing.T) {
+	var cells []types.Cube
+	for i := range 200 {
+		cells = append(cells, types.Cube{ID: fmt.Sprintf("c%d", i), X: i % 16})
+	}
+	big := types.Chunk{IDX: 1, IDY: 2, Cells: cells}
+	tests := []struct {
+		name    string
+		accept  string
+		chunk   types.Chunk
+		encoded string // Response.Encoding wanted
+	}{
+		{"zstd preferred", AcceptEncodings, big, EncodingZstd},
+		{"gzip-only client", EncodingGzip, big, EncodingGzip},
+		{"client order", "gzip, zstd", big, EncodingGzip},
+		{"unknown encodings", "br,deflate", big, ""},
+		{"nothing accepted", "", big, ""},
+		{"too small", AcceptEncodings, types.Chunk{IDX: 1}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := types.Response{Success: true, Chunk: tt.chunk}
+			if err := CompressResponse(&res, tt.accept, 256); err != nil {
+				t.Fatal(err)
+			}
+			if res.Encoding != tt.encoded {
+				t.Fatalf("encoded with %q, want %q", res.Encoding, tt.encoded)
+			}
+			data, err := Wire.Marshal(res)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := DecodeResponse(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Encoding != "" || got.Chunk.IDX != tt.chunk.IDX || len(got.Chunk.Cells) != len(tt.chunk.Cells) {
+				t.Errorf("decoded %d cells, encoding %q", len(got.Chunk.Cells), got.Encoding)
+			}
+		})
+	}
+}
@@ -0,0 +1,162 @@
+package netproto
+
+import (
+	"errors"
+	"flag"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ===================== CORS =====================
+
+// CORSPolicy says which browser origins may call an HTTP API and how.
+// Origins are exact ("https://play.example.com"), a subdomain wildcard
+// ("https://*.example.com") or "*" for any origin.
+type CORSPolicy struct {
+	Origins     []string
+	Methods     []string
+	Headers     []string      // request headers a page may send
+	Expose      []string      // response headers a page may read
+	Credentials bool          // let pages send cookies and read the reply
+	MaxAge      time.Duration // how long a browser may cache a preflight
+}
+
+// CORS is the policy EnableCORS applies. Every component starts from this
+// open policy; RegisterFlags lets a deployment narrow it.
+var CORS = CORSPolicy{
+	Origins: []string{"*"},
+	Methods: []string{"GET", "POST", "OPTIONS"},
+	Headers: []string{"Content-Type", "X-Request-ID", "X-Admin-Token", "If-None-Match"},
+	Expose:  []string{"X-Request-ID", "ETag"},
+	MaxAge:  10 * time.Minute,
+}
+
+// RegisterFlags adds -cors-* flags for p to fs. Their defaults are taken from
+// CORS_ORIGINS, CORS_METHODS, CORS_HEADERS and CORS_CREDENTIALS when set, so
+// one environment can configure every component. Call Validate after
+// parsing.
+func (p *CORSPolicy) RegisterFlags(fs *flag.FlagSet) {
+	for env, list := range map[string]*[]string{"CORS_ORIGINS": &p.Origins, "CORS_METHODS": &p.Methods, "CORS_HEADERS": &p.Headers} {
+		if v, ok := os.LookupEnv(env); ok {
+			*list = splitList(v)
+		}
+	}
+	if v, err := strconv.ParseBool(os.Getenv("CORS_CREDENTIALS")); err == nil {
+		p.Credentials = v
+	}
+	fs.Var(listValue{&p.Origins}, "cors-origins", `comma-separated origins browsers may call from, e.g. https://play.example.com,https://*.example.com ("*" for any, empty for none)`)
+	fs.Var(listValue{&p.Methods}, "cors-methods", "comma-separated methods allowed cross-origin")
+	fs.Var(listValue{&p.Headers}, "cors-headers", "comma-separated request headers allowed cross-origin")
+	fs.BoolVar(&p.Credentials, "cors-credentials", p.Credentials, "allow cross-origin requests with cookies (needs explicit -cors-origins)")
+	fs.DurationVar(&p.MaxAge, "cors-max-age", p.MaxAge, "how long browsers may cache a CORS preflight")
+}
+
+// Validate refuses a policy browsers would reject or that would be unsafe:
+// credentials are never allowed for any origin.
+func (p *CORSPolicy) Validate() error {
+	if p.Credentials && p.allowsAny() {
+		return errors.New(`cors: credentials need explicit origins, not "*"`)
+	}
+	return nil
+}
+
+func (p *CORSPolicy) allowsAny() bool {
+	for _, o := range p.Origins {
+		if o == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// allows reports whether a page from origin may call us.
+func (p *CORSPolicy) allows(origin string) bool {
+	for _, o := range p.Origins {
+		if o == "*" || strings.EqualFold(o, origin) {
+			return true
+		}
+		// https://*.example.com matches https://a.example.com, not https://example.com
+		if scheme, domain, ok := strings.Cut(o, "*."); ok && strings.HasPrefix(origin, scheme) {
+			host := strings.TrimPrefix(origin, scheme)
+			if strings.HasSuffix(strings.ToLower(host), "."+strings.ToLower(domain)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// EnableCORS applies the CORS policy to next. Requests without an Origin
+// header (other servers, curl) pass untouched. A disallowed origin gets no
+// CORS headers, so its browser keeps the reply from the page, and its
+// preflights are refused with 403. OPTIONS is always answered here.
+func EnableCORS(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		p := &CORS
+		origin := r.Header.Get("Origin")
+		if origin != "" {
+			w.Header().Add("Vary", "Origin")
+		}
+		allowed := origin != "" && p.allows(origin)
+
+		if allowed {
+			if p.allowsAny() && !p.Credentials {
+				w.Header().Set("Access-Control-Allow-Origin", "*")
+			} else {
+				w.Header().Set("Access-Control-Allow-Origin", origin)
+			}
+			if p.Credentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			if len(p.Expose) > 0 {
+				w.Header().Set("Access-Control-Expose-Headers", strings.Join(p.Expose, ", "))
+			}
+		}
+
+		if r.Method == http.MethodOptions {
+			if origin != "" && !allowed {
+				http.Error(w, "Origin not allowed", http.StatusForbidden)
+				return
+			}
+			if allowed {
+				w.Header().Set("Access-Control-Allow-Methods", strings.Join(p.Methods, ", "))
+				w.Header().Set("Access-Control-Allow-Headers", strings.Join(p.Headers, ", "))
+				if p.MaxAge > 0 {
+					w.Header().Set("Access-Control-Max-Age", strconv.Itoa(int(p.MaxAge.Seconds())))
+				}
+			}
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// listValue is a flag holding a comma-separated list.
+type listValue struct{ list *[]string }
+
+func (v listValue) String() string {
+	if v.list == nil {
+		return ""
+	}
+	return strings.Join(*v.list, ",")
+}
+
+func (v listValue) Set(s string) error {
+	*v.list = splitList(s)
+	return nil
+}
+
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
@@ -0,0 +1,60 @@
+package netproto
+
+import (
+	"fmt"
+	"net"
+)
+
+// ===================== Connected transports =====================
+
+// dialNetwork is implemented by Networks that can bind a Transport to one
+// peer, as a connected UDP socket: it skips resolving the address on every
+// Send and only hears from that peer.
+type dialNetwork interface {
+	dial(addr string) (Transport, error)
+}
+
+// Dial opens a Transport for talking to addr alone. Networks that cannot
+// connect one hand out an ordinary Transport, which works the same as long
+// as it is only sent to addr.
+func Dial(n Network, addr string) (Transport, error) {
+	if d, ok := n.(dialNetwork); ok {
+		return d.dial(addr)
+	}
+	return n.Listen("")
+}
+
+func (udpNetwork) dial(addr string) (Transport, error) {
+	raddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := net.DialUDP("udp", nil, raddr)
+	if err != nil {
+		return nil, err
+	}
+	t := newUDPTransport(conn)
+	t.peer = addr
+	return t, nil
+}
+
+func (c *Chaos) dial(addr string) (Transport, error) {
+	t, err := Dial(c.Network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return &chaosTransport{Transport: t, chaos: c}, nil
+}
+
+// sendConnected sends frags on a connected socket, which only reaches peer.
+func (u *udpTransport) sendConnected(to string, frags [][]byte) error {
+	if to != u.peer {
+		return fmt.Errorf("send to %s on a socket connected to %s", to, u.peer)
+	}
+	for _, frag := range frags {
+		if _, err := u.conn.Write(frag); err != nil {
+			return err
+		}
+	}
+	return nil
+}
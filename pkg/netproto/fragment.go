@@ -0,0 +1,135 @@
+package netproto
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/rand"
+	"sync/atomic"
+	"time"
+)
+
+// ===================== Fragmentation =====================
+
+// Messages that fit in one datagram go out unchanged, as they always have.
+// Larger ones (a chunk with thousands of cubes) are split into fragments
+// small enough to dodge IP fragmentation, each carrying
+//
+//	fragMagic | message ID (4) | index (2) | count (2) | payload
+//
+// JSON never starts with fragMagic, so receivers tell the two apart by the
+// first byte and peers that never fragment keep working.
+const (
+	fragMagic       = 0xFF
+	fragHeaderLen   = 9
+	fragPayloadSize = 1200
+	maxFragments    = 4096 // caps a message at ~4.9MB
+	maxPartials     = 64   // messages reassembled at once per Transport
+	fragmentTimeout = 5 * time.Second
+)
+
+var errMessageTooLarge = errors.New("message too large to fragment")
+
+type fragmenter struct {
+	nextID uint32
+}
+
+func newFragmenter() *fragmenter {
+	return &fragmenter{nextID: rand.Uint32()}
+}
+
+// split returns the datagrams that carry data.
+func (f *fragmenter) split(data []byte) ([][]byte, error) {
+	if len(data) <= MaxDatagram {
+		return [][]byte{data}, nil
+	}
+	count := (len(data) + fragPayloadSize - 1) / fragPayloadSize
+	if count > maxFragments {
+		return nil, errMessageTooLarge
+	}
+	id := atomic.AddUint32(&f.nextID, 1)
+	frags := make([][]byte, 0, count)
+	for i := 0; i < count; i++ {
+		end := min((i+1)*fragPayloadSize, len(data))
+		frag := make([]byte, fragHeaderLen, fragHeaderLen+end-i*fragPayloadSize)
+		frag[0] = fragMagic
+		binary.BigEndian.PutUint32(frag[1:], id)
+		binary.BigEndian.PutUint16(frag[5:], uint16(i))
+		binary.BigEndian.PutUint16(frag[7:], uint16(count))
+		frags = append(frags, append(frag, data[i*fragPayloadSize:end]...))
+	}
+	return frags, nil
+}
+
+type partialKey struct {
+	from string
+	id   uint32
+}
+
+type partial struct {
+	frags    [][]byte
+	received int
+	started  time.Time
+}
+
+// reassembler collects fragments until a message is complete. It is only
+// touched by the goroutine reading the Transport.
+type reassembler struct {
+	partials map[partialKey]*partial
+}
+
+func newReassembler() *reassembler {
+	return &reassembler{partials: make(map[partialKey]*partial)}
+}
+
+// add takes one datagram and returns a whole message once one is ready.
+// Datagrams that are not fragments are returned as they are; malformed
+// fragments are dropped.
+func (r *reassembler) add(from string, data []byte) ([]byte, bool) {
+	if len(data) == 0 || data[0] != fragMagic {
+		return data, true
+	}
+	if len(data) < fragHeaderLen {
+		return nil, false
+	}
+	key := partialKey{from: from, id: binary.BigEndian.Uint32(data[1:])}
+	index := int(binary.BigEndian.Uint16(data[5:]))
+	count := int(binary.BigEndian.Uint16(data[7:]))
+	if count == 0 || count > maxFragments || index >= count {
+		return nil, false
+	}
+
+	now := time.Now()
+	r.expire(now)
+	p, ok := r.partials[key]
+	if !ok {
+		if len(r.partials) >= maxPartials {
+			return nil, false
+		}
+		p = &partial{frags: make([][]byte, count), started: now}
+		r.partials[key] = p
+	}
+	if len(p.frags) != count || p.frags[index] != nil {
+		return nil, false // duplicate or inconsistent fragment
+	}
+	p.frags[index] = data[fragHeaderLen:]
+	p.received++
+	if p.received < count {
+		return nil, false
+	}
+
+	delete(r.partials, key)
+	var msg []byte
+	for _, frag := range p.frags {
+		msg = append(msg, frag...)
+	}
+	return msg, true
+}
+
+// expire drops messages whose fragments stopped arriving.
+func (r *reassembler) expire(now time.Time) {
+	for key, p := range r.partials {
+		if now.Sub(p.started) > fragmentTimeout {
+			delete(r.partials, key)
+		}
+	}
+}
@@ -0,0 +1,422 @@
+package netproto
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/backoff"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// ===================== gRPC =====================
+
+// GRPC is the Network of gRPC streams over TCP, for a client such as the
+// gateway that keeps talking to the same servers: one long-lived stream per
+// server replaces a datagram per request, so nothing is lost or fragmented
+// and a server restarting shows up as the stream ending. Each message of a
+// Transport is one message of the stream, carried as is; there is no
+// protobuf schema, as the payloads are the same Wire-encoded requests and
+// responses UDP carries.
+//
+// Listen(addr) serves streams on the TCP port of addr, which a game server
+// pairs with its UDP socket of the same address; each stream is known by the
+// address it comes from, and replies sent there go down it. Listen("") is a
+// client that opens a stream to each server it sends to, and Dial one bound
+// to a single server. Streams are opened on the first Send.
+var GRPC Network = grpcNetwork{}
+
+type grpcNetwork struct{}
+
+const (
+	grpcService     = "netproto.Relay"
+	grpcMethod      = "/" + grpcService + "/Stream"
+	grpcMaxMessage  = maxFragments * fragPayloadSize // what UDP can reassemble
+	grpcDialTimeout = 3 * time.Second
+)
+
+func (grpcNetwork) Listen(addr string) (Transport, error) {
+	if addr == "" {
+		return newGRPCClient(""), nil
+	}
+	return listenGRPC(addr)
+}
+
+func (grpcNetwork) dial(addr string) (Transport, error) {
+	return newGRPCClient(addr), nil
+}
+
+// rawCodec passes messages through untouched: a message is a *[]byte
+// holding what Transport.Send was given.
+type rawCodec struct{}
+
+func (rawCodec) Name() string { return "netproto" }
+
+func (rawCodec) Marshal(v any) ([]byte, error) {
+	data, ok := v.(*[]byte)
+	if !ok {
+		return nil, fmt.Errorf("grpc: cannot send %T", v)
+	}
+	return *data, nil
+}
+
+func (rawCodec) Unmarshal(data []byte, v any) error {
+	dst, ok := v.(*[]byte)
+	if !ok {
+		return fmt.Errorf("grpc: cannot receive into %T", v)
+	}
+	*dst = append([]byte(nil), data...)
+	return nil
+}
+
+func init() {
+	encoding.RegisterCodec(rawCodec{})
+}
+
+// relayDesc describes the one method streams are opened on, as protoc would
+// for service Relay { rpc Stream(stream bytes) returns (stream bytes); }.
+var relayDesc = grpc.ServiceDesc{
+	ServiceName: grpcService,
+	HandlerType: (*relayServer)(nil),
+	Streams: []grpc.StreamDesc{{
+		StreamName:    "Stream",
+		Handler:       func(srv any, stream grpc.ServerStream) error { return srv.(relayServer).relay(stream) },
+		ServerStreams: true,
+		ClientStreams: true,
+	}},
+}
+
+type relayServer interface {
+	relay(stream grpc.ServerStream) error
+}
+
+// grpcInbox queues the messages read from every stream of a Transport for
+// Recv. Readers wait while it is full, which pushes back on the sender
+// through the stream's flow control instead of dropping.
+type grpcInbox struct {
+	ch     chan memPacket
+	closed chan struct{}
+	once   sync.Once
+
+	mu       sync.Mutex
+	deadline time.Time
+}
+
+func newGRPCInbox() *grpcInbox {
+	return &grpcInbox{ch: make(chan memPacket, 1024), closed: make(chan struct{})}
+}
+
+// put reports false once the Transport is closed.
+func (in *grpcInbox) put(pkt memPacket) bool {
+	select {
+	case in.ch <- pkt:
+		return true
+	case <-in.closed:
+		return false
+	}
+}
+
+func (in *grpcInbox) Recv() (string, []byte, error) {
+	in.mu.Lock()
+	deadline := in.deadline
+	in.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case pkt := <-in.ch:
+		return pkt.from, pkt.data, nil
+	case <-timeout:
+		return "", nil, ErrTimeout
+	case <-in.closed:
+		return "", nil, net.ErrClosed
+	}
+}
+
+func (in *grpcInbox) SetReadDeadline(d time.Time) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	in.deadline = d
+	return nil
+}
+
+// close reports whether it was the first call.
+func (in *grpcInbox) close() bool {
+	first := false
+	in.once.Do(func() {
+		close(in.closed)
+		first = true
+	})
+	return first
+}
+
+func (in *grpcInbox) isClosed() bool {
+	select {
+	case <-in.closed:
+		return true
+	default:
+		return false
+	}
+}
+
+// grpcStream serializes the sends on one stream, which gRPC requires.
+type grpcStream struct {
+	mu     sync.Mutex
+	stream interface{ SendMsg(m any) error }
+}
+
+func (s *grpcStream) send(data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.stream.SendMsg(&data)
+}
+
+// ----- client side -----
+
+type grpcClient struct {
+	*grpcInbox
+	peer   string // set by Dial: the only server sent to
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu    sync.Mutex
+	conns map[string]*grpcClientConn
+}
+
+// grpcClientConn is the stream to one server; ready is closed once it is
+// open or err is set.
+type grpcClientConn struct {
+	ready chan struct{}
+	err   error
+	conn  *grpc.ClientConn
+	grpcStream
+}
+
+func newGRPCClient(peer string) *grpcClient {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &grpcClient{grpcInbox: newGRPCInbox(), peer: peer, ctx: ctx, cancel: cancel, conns: make(map[string]*grpcClientConn)}
+}
+
+func (c *grpcClient) Send(to string, data []byte) error {
+	if c.peer != "" && to != c.peer {
+		return fmt.Errorf("send to %s on a stream to %s", to, c.peer)
+	}
+	s, err := c.stream(to)
+	if err != nil {
+		return err
+	}
+	if err := s.send(data); err != nil {
+		c.drop(to, s, err)
+		return err
+	}
+	return nil
+}
+
+// stream returns the stream to addr, opening it if there is none.
+func (c *grpcClient) stream(addr string) (*grpcClientConn, error) {
+	c.mu.Lock()
+	if c.isClosed() {
+		c.mu.Unlock()
+		return nil, net.ErrClosed
+	}
+	s, ok := c.conns[addr]
+	if !ok {
+		s = &grpcClientConn{ready: make(chan struct{})}
+		c.conns[addr] = s
+	}
+	c.mu.Unlock()
+	if ok {
+		<-s.ready
+		return s, s.err
+	}
+
+	s.err = s.open(c.ctx, addr)
+	close(s.ready)
+	if s.err != nil {
+		c.drop(addr, s, nil)
+		return nil, s.err
+	}
+	go c.read(addr, s)
+	return s, nil
+}
+
+func (s *grpcClientConn) open(ctx context.Context, addr string) error {
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithConnectParams(grpc.ConnectParams{Backoff: backoff.DefaultConfig, MinConnectTimeout: grpcDialTimeout}),
+		grpc.WithDefaultCallOptions(
+			grpc.CallContentSubtype(rawCodec{}.Name()),
+			grpc.MaxCallRecvMsgSize(grpcMaxMessage),
+			grpc.MaxCallSendMsgSize(grpcMaxMessage),
+		))
+	if err != nil {
+		return err
+	}
+	stream, err := conn.NewStream(ctx, &relayDesc.Streams[0], grpcMethod)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	s.conn, s.stream = conn, stream
+	return nil
+}
+
+// read queues what the server sends on s until the stream ends.
+func (c *grpcClient) read(addr string, s *grpcClientConn) {
+	stream := s.stream.(grpc.ClientStream)
+	for {
+		var data []byte
+		if err := stream.RecvMsg(&data); err != nil {
+			c.drop(addr, s, err)
+			return
+		}
+		if !c.put(memPacket{from: addr, data: data}) {
+			return
+		}
+	}
+}
+
+// drop forgets the stream to addr after it failed with err, so the next
+// Send opens another. A client bound by Dial closes instead, so its reader
+// sees net.ErrClosed and dials again, as the Mux does.
+func (c *grpcClient) drop(addr string, s *grpcClientConn, err error) {
+	c.mu.Lock()
+	if c.conns[addr] == s {
+		delete(c.conns, addr)
+	}
+	c.mu.Unlock()
+	if s.conn != nil {
+		s.conn.Close()
+	}
+	if c.isClosed() {
+		return
+	}
+	if err != nil && !errors.Is(err, io.EOF) {
+		log.Printf("⚠️  gRPC stream to %s ended: %v", addr, err)
+	}
+	if c.peer != "" {
+		c.Close()
+	}
+}
+
+// LocalAddr is empty: every stream has its own.
+func (c *grpcClient) LocalAddr() string { return "" }
+
+func (c *grpcClient) Close() error {
+	if !c.close() {
+		return nil
+	}
+	c.cancel()
+	c.mu.Lock()
+	conns := c.conns
+	c.conns = make(map[string]*grpcClientConn)
+	c.mu.Unlock()
+	for _, s := range conns {
+		<-s.ready
+		if s.conn != nil {
+			s.conn.Close()
+		}
+	}
+	return nil
+}
+
+// ----- server side -----
+
+type grpcServer struct {
+	*grpcInbox
+	srv *grpc.Server
+	ln  net.Listener
+
+	mu      sync.Mutex
+	streams map[string]*grpcStream // by the address they come from
+}
+
+func listenGRPC(addr string) (*grpcServer, error) {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+	g := &grpcServer{
+		grpcInbox: newGRPCInbox(),
+		srv:       grpc.NewServer(grpc.MaxRecvMsgSize(grpcMaxMessage), grpc.MaxSendMsgSize(grpcMaxMessage)),
+		ln:        ln,
+		streams:   make(map[string]*grpcStream),
+	}
+	g.srv.RegisterService(&relayDesc, g)
+	go g.srv.Serve(ln)
+	return g, nil
+}
+
+// relay reads one client's stream until it ends. A client has one stream
+// per connection, so a second from the same address is refused.
+func (g *grpcServer) relay(stream grpc.ServerStream) error {
+	p, ok := peer.FromContext(stream.Context())
+	if !ok {
+		return status.Error(codes.Internal, "no peer address")
+	}
+	from := p.Addr.String()
+	s := &grpcStream{stream: stream}
+	g.mu.Lock()
+	if _, taken := g.streams[from]; taken {
+		g.mu.Unlock()
+		return status.Errorf(codes.AlreadyExists, "%s already has a stream", from)
+	}
+	g.streams[from] = s
+	g.mu.Unlock()
+	defer func() {
+		g.mu.Lock()
+		delete(g.streams, from)
+		g.mu.Unlock()
+	}()
+
+	for {
+		var data []byte
+		if err := stream.RecvMsg(&data); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if !g.put(memPacket{from: from, data: data}) {
+			return status.Error(codes.Unavailable, "server closed")
+		}
+	}
+}
+
+// Send replies down the stream from to; it fails for an address with no
+// stream open.
+func (g *grpcServer) Send(to string, data []byte) error {
+	if g.isClosed() {
+		return net.ErrClosed
+	}
+	g.mu.Lock()
+	s, ok := g.streams[to]
+	g.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("send to %s: no gRPC stream from it", to)
+	}
+	return s.send(data)
+}
+
+func (g *grpcServer) LocalAddr() string { return g.ln.Addr().String() }
+
+func (g *grpcServer) Close() error {
+	if g.close() {
+		g.srv.Stop()
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+package netproto
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// echoServer answers every request on t with its PlayerID as the message,
+// until t is closed.
+func echoServer(t Transport) {
+	for {
+		from, data, err := t.Recv()
+		if err != nil {
+			return
+		}
+		var req types.Request
+		if Wire.Unmarshal(data, &req) != nil {
+			continue
+		}
+		SendJSON(t, from, types.Response{Success: true, Message: req.PlayerID, CallID: req.CallID})
+	}
+}
+
+func listenEcho(t *testing.T, n Network, addr string) Transport {
+	t.Helper()
+	server, err := n.Listen(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { server.Close() })
+	go echoServer(server)
+	return server
+}
+
+func muxCall(m *Mux, server, player string) (types.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	return m.RoundTrip(ctx, server, types.Request{Type: types.ReqGetUpdates, PlayerID: player})
+}
+
+func TestGRPCMux(t *testing.T) {
+	server := listenEcho(t, GRPC, "127.0.0.1:0")
+	m := NewMux(GRPC)
+	defer m.Close()
+
+	big := strings.Repeat("x", 2*MaxDatagram) // more than one datagram
+	var wg sync.WaitGroup
+	for i := range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			player := fmt.Sprintf("p%d", i)
+			if i == 0 {
+				player = big
+			}
+			res, err := muxCall(m, server.LocalAddr(), player)
+			if err != nil || res.Message != player {
+				t.Errorf("call %d: %.20q, %v", i, res.Message, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+func TestGRPCMuxRedials(t *testing.T) {
+	server := listenEcho(t, GRPC, "127.0.0.1:0")
+	addr := server.LocalAddr()
+	m := NewMux(GRPC)
+	defer m.Close()
+	if _, err := muxCall(m, addr, "p1"); err != nil {
+		t.Fatal(err)
+	}
+
+	server.Close()
+	if _, err := muxCall(m, addr, "p2"); err == nil {
+		t.Fatal("call to a stopped server succeeded")
+	}
+	listenEcho(t, GRPC, addr)
+	var err error
+	for range 50 { // until the Mux has seen the old stream end
+		var res types.Response
+		if res, err = muxCall(m, addr, "p3"); err == nil && res.Message == "p3" {
+			return
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	t.Errorf("no call got through to the restarted server: %v", err)
+}
+
+func TestGRPCSealed(t *testing.T) {
+	keys, _, servers := sealPair(t)
+	inner, err := GRPC.Listen("127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := servers.Wrap(inner)
+	defer server.Close()
+	go echoServer(server)
+
+	sealed := NewMux(SealClient(GRPC, keys))
+	defer sealed.Close()
+	if res, err := muxCall(sealed, inner.LocalAddr(), "p1"); err != nil || res.Message != "p1" {
+		t.Errorf("sealed call: %q, %v", res.Message, err)
+	}
+
+	plain := NewMux(GRPC)
+	defer plain.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if res, err := plain.RoundTrip(ctx, inner.LocalAddr(), types.Request{PlayerID: "p2"}); err == nil {
+		t.Errorf("plain call answered: %q", res.Message)
+	}
+}
@@ -0,0 +1,160 @@
+package netproto
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"net"
+	"sync"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Multiplexing =====================
+
+// A Mux sends requests to any number of game servers for a process such as
+// the gateway that relays many requests at once. It keeps one connected
+// Transport per server (see Dial): a UDP socket, or a stream over GRPC,
+// opened on the first request and reused by every later one. Each request
+// gets a CallID, the server echoes it in the reply, and the reader of the
+// server's Transport hands the reply to the call waiting for it, so
+// concurrent calls share the socket. Calls end at their context's deadline
+// or when it is cancelled. Anything else a server sends (pushes meant for
+// players whose requests were relayed) is dropped.
+type Mux struct {
+	network Network
+
+	mu     sync.Mutex
+	next   uint64
+	conns  map[string]*muxConn
+	closed bool
+}
+
+type muxConn struct {
+	server string
+	t      Transport
+	// guarded by Mux.mu
+	waiting map[uint64]chan []byte
+}
+
+// ErrMuxClosed is returned by calls on a closed Mux, and by calls waiting on
+// a Transport that failed.
+var ErrMuxClosed = errors.New("mux closed")
+
+func NewMux(n Network) *Mux {
+	return &Mux{network: n, conns: make(map[string]*muxConn)}
+}
+
+// RoundTrip sends req to server and waits for the reply, like the RoundTrip
+// function, until ctx is done.
+func (m *Mux) RoundTrip(ctx context.Context, server string, req types.Request) (types.Response, error) {
+	if req.AcceptEncoding == "" {
+		req.AcceptEncoding = AcceptEncodings
+	}
+	replied := make(chan []byte, 1)
+	c, err := m.register(server, &req, replied)
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer func() {
+		m.mu.Lock()
+		delete(c.waiting, req.CallID)
+		m.mu.Unlock()
+	}()
+
+	data, err := Wire.Marshal(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	if err := c.t.Send(server, data); err != nil {
+		return types.Response{}, err
+	}
+	select {
+	case data, ok := <-replied:
+		if !ok {
+			return types.Response{}, ErrMuxClosed
+		}
+		return DecodeResponse(data)
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return types.Response{}, ErrTimeout
+		}
+		return types.Response{}, ctx.Err()
+	}
+}
+
+// register numbers req and files replied under it on server's Transport,
+// dialing server if there is none yet.
+func (m *Mux) register(server string, req *types.Request, replied chan []byte) (*muxConn, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.closed {
+		return nil, ErrMuxClosed
+	}
+	c, ok := m.conns[server]
+	if !ok {
+		t, err := Dial(m.network, server)
+		if err != nil {
+			return nil, err
+		}
+		c = &muxConn{server: server, t: t, waiting: make(map[uint64]chan []byte)}
+		m.conns[server] = c
+		go m.read(c)
+	}
+	m.next++
+	req.CallID = m.next
+	c.waiting[req.CallID] = replied
+	return c, nil
+}
+
+// Close stops the Mux, failing the calls still waiting.
+func (m *Mux) Close() error {
+	m.mu.Lock()
+	m.closed = true
+	conns := m.conns
+	m.conns = make(map[string]*muxConn)
+	m.mu.Unlock()
+	for _, c := range conns {
+		c.t.Close()
+	}
+	return nil
+}
+
+// read hands c's replies to their calls until c's Transport is closed, then
+// fails the calls left and forgets c, so the next call dials again.
+func (m *Mux) read(c *muxConn) {
+	for {
+		_, data, err := c.t.Recv()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				break
+			}
+			// e.g. the server's port refused a datagram; calls time out
+			log.Printf("⚠️  Reading from %s failed: %v", c.server, err)
+			continue
+		}
+		var header struct {
+			CallID uint64 `json:"call_id"`
+		}
+		if json.Unmarshal(data, &header) != nil || header.CallID == 0 {
+			continue
+		}
+		m.mu.Lock()
+		if replied, ok := c.waiting[header.CallID]; ok {
+			replied <- data
+			delete(c.waiting, header.CallID)
+		}
+		m.mu.Unlock()
+	}
+
+	m.mu.Lock()
+	for id, replied := range c.waiting {
+		close(replied)
+		delete(c.waiting, id)
+	}
+	if m.conns[c.server] == c {
+		delete(m.conns, c.server)
+	}
+	m.mu.Unlock()
+}
@@ -0,0 +1,150 @@
+// Package netproto is how the components talk to each other: JSON requests
+// and responses over a Transport (UDP, gRPC or in-memory), trace IDs that
+// follow one action across hops, and the HTTP middleware shared by the
+// central server, gateway and admin APIs.
+package netproto
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// MaxDatagram is the largest UDP payload; longer messages are fragmented.
+const MaxDatagram = 65507
+
+// ===================== Requests =====================
+
+// SendJSON marshals v with the Wire codec and sends it to addr, logging failures.
+func SendJSON(t Transport, addr string, v interface{}) {
+	data, err := Wire.Marshal(v)
+	if err != nil {
+		log.Println("JSON marshal error:", err)
+		return
+	}
+	if err := t.Send(addr, data); err != nil {
+		log.Printf("❌ Error sending to %s: %v", addr, err)
+	}
+}
+
+// RoundTrip sends req to server from a fresh Transport on n and waits up to
+// timeout for the response, so concurrent callers never read each other's
+// replies. It accepts compressed payloads and returns them decompressed.
+func RoundTrip(n Network, server string, req types.Request, timeout time.Duration) (types.Response, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	return RoundTripContext(ctx, n, server, req)
+}
+
+// RoundTripContext is RoundTrip waiting until ctx is done rather than for a
+// timeout; it returns ctx's error if that comes first.
+func RoundTripContext(ctx context.Context, n Network, server string, req types.Request) (types.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return types.Response{}, err
+	}
+	t, err := n.Listen("")
+	if err != nil {
+		return types.Response{}, err
+	}
+	defer t.Close()
+
+	if req.AcceptEncoding == "" {
+		req.AcceptEncoding = AcceptEncodings
+	}
+	data, err := Wire.Marshal(req)
+	if err != nil {
+		return types.Response{}, err
+	}
+	if err := t.Send(server, data); err != nil {
+		return types.Response{}, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		t.SetReadDeadline(deadline)
+	}
+	// cancellation before the deadline ends the wait too
+	stop := context.AfterFunc(ctx, func() { t.Close() })
+	defer stop()
+	_, data, err = t.Recv()
+	if err != nil {
+		if ctx.Err() != nil {
+			return types.Response{}, ctx.Err()
+		}
+		return types.Response{}, err
+	}
+	return DecodeResponse(data)
+}
+
+// Unbatch returns the pushes a PUSH_BATCH carries, or res alone.
+func Unbatch(res types.Response) []types.Response {
+	if res.Code != types.CodeBatch {
+		return []types.Response{res}
+	}
+	return res.Pushes
+}
+
+// ===================== Tracing =====================
+
+// NewTraceID returns a random correlation ID used to follow one player
+// action through the gateway, game servers and central server logs.
+func NewTraceID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "notrace"
+	}
+	return hex.EncodeToString(b)
+}
+
+// Tracef logs with the trace ID prefixed so every hop can be grepped.
+func Tracef(traceID string, format string, args ...interface{}) {
+	log.Printf("[trace=%s] "+format, append([]interface{}{traceID}, args...)...)
+}
+
+// ===================== HTTP =====================
+
+// RequireAdmin rejects requests that do not carry token in X-Admin-Token.
+// An empty token disables the wrapped endpoint.
+func RequireAdmin(token string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token == "" {
+			http.Error(w, "Admin API disabled", http.StatusForbidden)
+			return
+		}
+		got := r.Header.Get("X-Admin-Token")
+		if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// ServerTokenHeader carries SealKeys.ServerToken on game servers' calls to
+// central.
+const ServerTokenHeader = "X-Server-Token"
+
+// RequireServer rejects requests that do not come from a game server: with
+// a token, those that do not carry it in ServerTokenHeader; without one,
+// those from a host known does not report as a game server's.
+func RequireServer(token string, known func(host string) bool, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if token != "" {
+			got := r.Header.Get(ServerTokenHeader)
+			if subtle.ConstantTimeCompare([]byte(got), []byte(token)) != 1 {
+				http.Error(w, "Unauthorized", http.StatusUnauthorized)
+				return
+			}
+		} else if host, _, err := net.SplitHostPort(r.RemoteAddr); err != nil || !known(host) {
+			http.Error(w, "Only game servers may call this", http.StatusForbidden)
+			return
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,65 @@
+package netproto
+
+import (
+	"context"
+	"fmt"
+	"net"
+)
+
+// ===================== SO_REUSEPORT =====================
+
+// reusePortNetwork is implemented by Networks that can bind several
+// Transports to one address, letting the kernel spread datagrams across them.
+type reusePortNetwork interface {
+	listenReusePort(addr string, count int) ([]Transport, error)
+}
+
+// ListenN opens count Transports on addr. With count > 1 the Network must
+// support SO_REUSEPORT; each Transport gets a share of the incoming datagrams
+// (the kernel keeps one sender on one socket), so each can have its own read
+// loop.
+func ListenN(n Network, addr string, count int) ([]Transport, error) {
+	if count <= 1 {
+		t, err := n.Listen(addr)
+		if err != nil {
+			return nil, err
+		}
+		return []Transport{t}, nil
+	}
+	rp, ok := n.(reusePortNetwork)
+	if !ok {
+		return nil, fmt.Errorf("listen %s: %T cannot open several sockets on one address", addr, n)
+	}
+	return rp.listenReusePort(addr, count)
+}
+
+func (udpNetwork) listenReusePort(addr string, count int) ([]Transport, error) {
+	lc := net.ListenConfig{Control: reusePortControl}
+	var ts []Transport
+	for i := 0; i < count; i++ {
+		pc, err := lc.ListenPacket(context.Background(), "udp", addr)
+		if err != nil {
+			for _, t := range ts {
+				t.Close()
+			}
+			return nil, err
+		}
+		// later sockets must bind the port the first one got, if it was ":0"
+		addr = pc.LocalAddr().String()
+		t := newUDPTransport(pc.(*net.UDPConn))
+		t.batchReads()
+		ts = append(ts, t)
+	}
+	return ts, nil
+}
+
+func (c *Chaos) listenReusePort(addr string, count int) ([]Transport, error) {
+	ts, err := ListenN(c.Network, addr, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range ts {
+		ts[i] = &chaosTransport{Transport: t, chaos: c}
+	}
+	return ts, nil
+}
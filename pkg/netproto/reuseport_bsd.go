@@ -0,0 +1,7 @@
+//go:build darwin || freebsd
+
+package netproto
+
+import "syscall"
+
+const soReusePort = syscall.SO_REUSEPORT
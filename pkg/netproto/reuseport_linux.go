@@ -0,0 +1,4 @@
+package netproto
+
+// soReusePort is SO_REUSEPORT, which the frozen syscall package lacks on Linux.
+const soReusePort = 0xf
@@ -0,0 +1,574 @@
+package netproto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Sealing =====================
+
+// In a cluster with a seal secret every message to a game server is sealed,
+// before fragmentation, as
+//
+//	magic | session ID (8) | expiry (4) | sequence (8) | nonce (12) | AES-256-GCM(message)
+//
+// with the header as additional data. Players get a types.SessionSeal from
+// /join; game servers and central seal what they send each other with a
+// peer session of their own, whose ID has peerBit set, which Issue never
+// hands out. Game servers derive a session's key from its ID, its expiry
+// and the secret, so neither can be changed, open what they receive, and
+// drop what is plain. Replies to a player are sealed with the player's key
+// and marked sealDownMagic, so they cannot be sent back to a server as the
+// player's own. JSON never starts with either magic byte, so the envelope
+// is told apart by the first byte, as fragments are.
+//
+// Each sender numbers its messages, and a receiver keeps a window of the
+// latest sequence numbers it accepted (replayWindow): a game server one per
+// session until the session expires, which is when it can forget it, and a
+// player one per server address. A message numbered below the window, or
+// already in it, is a replay and is dropped.
+const (
+	sealMagic     = 0xFE // to a game server (or central), from anyone
+	sealDownMagic = 0xFD // from a game server to a player
+	sealHeaderLen = 1 + 8 + 4 + 8
+	sealNonceLen  = 12
+	sealIdle      = 10 * time.Minute // a player's address is forgotten after this
+	sealTTL       = 24 * time.Hour   // how long a session seal is good for
+
+	peerBit = 1 << 63 // set in the IDs of servers' peer sessions
+)
+
+// ErrSealExpired is returned by sends on a Transport whose player session
+// has expired; the player must Join again for a new one.
+var ErrSealExpired = errors.New("session seal expired")
+
+var (
+	errBadSeal  = errors.New("sealed message failed to open")
+	errUnsealed = errors.New("message is not sealed")
+	errExpired  = errors.New("sealed with an expired session")
+	errReplayed = errors.New("sealed message replayed")
+)
+
+// SealKeys issues and derives session keys from a cluster-wide secret.
+type SealKeys struct {
+	secret []byte
+}
+
+func NewSealKeys(secret string) *SealKeys {
+	return &SealKeys{secret: []byte(secret)}
+}
+
+// ServerToken returns the token game servers show central's HTTP API, to
+// prove they hold the secret (see RequireServer).
+func (k *SealKeys) ServerToken() string {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte("server"))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Issue returns a fresh player session seal, good for sealTTL.
+func (k *SealKeys) Issue() (types.SessionSeal, error) {
+	return k.issue(0)
+}
+
+func (k *SealKeys) issue(bits uint64) (types.SessionSeal, error) {
+	var id [8]byte
+	if _, err := rand.Read(id[:]); err != nil {
+		return types.SessionSeal{}, err
+	}
+	seal := types.SessionSeal{ID: binary.BigEndian.Uint64(id[:])&^peerBit | bits, Expires: time.Now().Add(sealTTL).Unix()}
+	seal.Key = k.key(seal.ID, uint32(seal.Expires))
+	return seal, nil
+}
+
+func (k *SealKeys) key(id uint64, expires uint32) []byte {
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte("seal"))
+	binary.Write(mac, binary.BigEndian, id)
+	binary.Write(mac, binary.BigEndian, expires)
+	return mac.Sum(nil)
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// A sealer seals the messages of one session, numbering them.
+type sealer struct {
+	id      uint64
+	expires uint32
+	aead    cipher.AEAD
+	seq     atomic.Uint64
+}
+
+func newSealer(s types.SessionSeal) (*sealer, error) {
+	aead, err := newAEAD(s.Key)
+	if err != nil {
+		return nil, err
+	}
+	sl := &sealer{id: s.ID, expires: uint32(s.Expires), aead: aead}
+	// from the clock, so a restarted server keeps counting up for the
+	// players it had
+	sl.seq.Store(uint64(time.Now().UnixNano()))
+	return sl, nil
+}
+
+func (s *sealer) seal(magic byte, msg []byte) ([]byte, error) {
+	return seal(s.aead, magic, s.id, s.expires, s.seq.Add(1), msg)
+}
+
+func seal(aead cipher.AEAD, magic byte, id uint64, expires uint32, seq uint64, msg []byte) ([]byte, error) {
+	out := make([]byte, sealHeaderLen+sealNonceLen, sealHeaderLen+sealNonceLen+len(msg)+aead.Overhead())
+	out[0] = magic
+	binary.BigEndian.PutUint64(out[1:], id)
+	binary.BigEndian.PutUint32(out[9:], expires)
+	binary.BigEndian.PutUint64(out[13:], seq)
+	nonce := out[sealHeaderLen:]
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(out, nonce, msg, out[:sealHeaderLen]), nil
+}
+
+// A sealSession is a session as the envelope names it.
+type sealSession struct {
+	id      uint64
+	expires uint32
+}
+
+// A sealHeader is what a sealed message claims before it is opened.
+type sealHeader struct {
+	sealSession
+	seq uint64
+}
+
+// readSealHeader returns the header of a message sealed with magic.
+func readSealHeader(magic byte, data []byte) (sealHeader, bool) {
+	if len(data) < sealHeaderLen+sealNonceLen || data[0] != magic {
+		return sealHeader{}, false
+	}
+	return sealHeader{
+		sealSession: sealSession{id: binary.BigEndian.Uint64(data[1:]), expires: binary.BigEndian.Uint32(data[9:])},
+		seq:         binary.BigEndian.Uint64(data[13:]),
+	}, true
+}
+
+func open(aead cipher.AEAD, data []byte) ([]byte, error) {
+	if len(data) < sealHeaderLen+sealNonceLen+aead.Overhead() {
+		return nil, errBadSeal
+	}
+	nonce := data[sealHeaderLen : sealHeaderLen+sealNonceLen]
+	msg, err := aead.Open(nil, nonce, data[sealHeaderLen+sealNonceLen:], data[:sealHeaderLen])
+	if err != nil {
+		return nil, errBadSeal
+	}
+	return msg, nil
+}
+
+// ----- replays -----
+
+// replayWindowBits is how far behind the latest accepted message another
+// may arrive and still be taken; enough for a burst of small messages to
+// overtake a fragmented one.
+const replayWindowBits = 1024
+
+// A replayWindow remembers which of the replayWindowBits sequence numbers
+// up to top it has accepted.
+type replayWindow struct {
+	top  uint64
+	bits [replayWindowBits / 64]uint64 // bit seq%replayWindowBits
+}
+
+// accept reports whether seq is new, and records it if so.
+func (w *replayWindow) accept(seq uint64) bool {
+	switch {
+	case seq > w.top:
+		if seq-w.top >= replayWindowBits {
+			w.bits = [replayWindowBits / 64]uint64{}
+		} else {
+			for n := w.top + 1; n < seq; n++ {
+				w.clear(n)
+			}
+		}
+		w.top = seq
+	case w.top-seq >= replayWindowBits || w.has(seq):
+		return false
+	}
+	w.set(seq)
+	return true
+}
+
+func (w *replayWindow) has(seq uint64) bool {
+	return w.bits[seq%replayWindowBits/64]&(1<<(seq%64)) != 0
+}
+func (w *replayWindow) set(seq uint64)   { w.bits[seq%replayWindowBits/64] |= 1 << (seq % 64) }
+func (w *replayWindow) clear(seq uint64) { w.bits[seq%replayWindowBits/64] &^= 1 << (seq % 64) }
+
+// ----- client side -----
+
+// A Sealer seals a player's Transports with their session. Its Transports
+// share one sequence, as a server keeps one replay window per session.
+type Sealer struct {
+	src fixedSeal
+}
+
+func NewSealer(s types.SessionSeal) (*Sealer, error) {
+	sl, err := newSealer(s)
+	if err != nil {
+		return nil, err
+	}
+	return &Sealer{fixedSeal{sl}}, nil
+}
+
+// Transport seals everything t sends and opens what comes back sealed with
+// the session; anything else is dropped, plain datagrams included, since
+// the servers of a cluster that seals seal everything they send.
+func (s *Sealer) Transport(t Transport) Transport {
+	return newSealedTransport(t, s.src)
+}
+
+// A sealSource is the session, or sessions, a client's Transports seal with.
+type sealSource interface {
+	// current is the session to seal with.
+	current() (*sealer, error)
+	// lookup is the session a reply sealed with id belongs to, if any.
+	lookup(id uint64) *sealer
+}
+
+type fixedSeal struct{ s *sealer }
+
+func (f fixedSeal) current() (*sealer, error) { return f.s, nil }
+
+func (f fixedSeal) lookup(id uint64) *sealer {
+	if id == f.s.id {
+		return f.s
+	}
+	return nil
+}
+
+func newSealedTransport(t Transport, src sealSource) *sealedTransport {
+	return &sealedTransport{Transport: t, src: src, windows: make(map[string]*replayWindow)}
+}
+
+type sealedTransport struct {
+	Transport
+	src sealSource
+
+	mu      sync.Mutex
+	windows map[string]*replayWindow // by server address
+}
+
+func (t *sealedTransport) Send(to string, data []byte) error {
+	s, err := t.src.current()
+	if err != nil {
+		return err
+	}
+	if int64(s.expires) <= time.Now().Unix() {
+		return ErrSealExpired
+	}
+	msg, err := s.seal(sealMagic, data)
+	if err != nil {
+		return err
+	}
+	return t.Transport.Send(to, msg)
+}
+
+func (t *sealedTransport) Recv() (string, []byte, error) {
+	for {
+		from, data, err := t.Transport.Recv()
+		if err != nil {
+			return from, data, err
+		}
+		h, ok := readSealHeader(sealDownMagic, data)
+		if !ok {
+			continue
+		}
+		s := t.src.lookup(h.id)
+		if s == nil {
+			continue
+		}
+		if msg, err := open(s.aead, data); err == nil && t.accept(from, h.seq) {
+			return from, msg, nil
+		}
+	}
+}
+
+func (t *sealedTransport) accept(from string, seq uint64) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	w := t.windows[from]
+	if w == nil {
+		w = &replayWindow{}
+		t.windows[from] = w
+	}
+	return w.accept(seq)
+}
+
+// SealClient is a Network whose Transports seal as a player would, with a
+// session it issues from keys itself, for a process holding the secret that
+// relays players' requests, as the gateway does: it gets no more trust
+// than they do. The session is shared by every Transport, as a server
+// keeps one replay window per session, and replaced halfway through its
+// life, replies to the one before still being opened.
+func SealClient(n Network, keys *SealKeys) Network {
+	return &sealClient{Network: n, keys: keys}
+}
+
+type sealClient struct {
+	Network
+	keys *SealKeys
+
+	mu        sync.Mutex
+	cur, prev *sealer
+}
+
+func (c *sealClient) current() (*sealer, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.cur != nil && time.Until(time.Unix(int64(c.cur.expires), 0)) > sealTTL/2 {
+		return c.cur, nil
+	}
+	seal, err := c.keys.Issue()
+	if err != nil {
+		return nil, err
+	}
+	next, err := newSealer(seal)
+	if err != nil {
+		return nil, err
+	}
+	c.cur, c.prev = next, c.cur
+	return c.cur, nil
+}
+
+func (c *sealClient) lookup(id uint64) *sealer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, s := range []*sealer{c.cur, c.prev} {
+		if s != nil && s.id == id {
+			return s
+		}
+	}
+	return nil
+}
+
+func (c *sealClient) Listen(addr string) (Transport, error) {
+	t, err := c.Network.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	return newSealedTransport(t, c), nil
+}
+
+func (c *sealClient) dial(addr string) (Transport, error) {
+	t, err := Dial(c.Network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return newSealedTransport(t, c), nil
+}
+
+// ----- server side -----
+
+// SealNetwork wraps a Network so its Transports take only sealed messages,
+// and seal what they send: with a player's key to an address a player
+// sealed with, and with the network's own peer session to anyone else. The
+// peer session is replaced halfway through its life, so it never expires
+// in flight. The peers' seals and replay windows are shared by every
+// Transport it hands out, so a push leaving by another socket of the same
+// server is sealed too.
+type SealNetwork struct {
+	Network
+	keys *SealKeys
+
+	mu      sync.Mutex
+	self    *sealer              // the peer session
+	peers   map[string]*peerSeal // by address
+	windows map[sealSession]*replayWindow
+	swept   time.Time
+}
+
+// A peerSeal is the session an address last sealed a message with: a
+// player's, whose key replies are sealed with, or a server's.
+type peerSeal struct {
+	id      uint64
+	expires uint32
+	aead    cipher.AEAD
+	seen    time.Time
+}
+
+func (p *peerSeal) server() bool { return p.id&peerBit != 0 }
+
+func NewSealNetwork(n Network, keys *SealKeys) *SealNetwork {
+	return &SealNetwork{Network: n, keys: keys, peers: make(map[string]*peerSeal), windows: make(map[sealSession]*replayWindow)}
+}
+
+func (s *SealNetwork) Listen(addr string) (Transport, error) {
+	t, err := s.Network.Listen(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &openingTransport{Transport: t, net: s}, nil
+}
+
+func (s *SealNetwork) listenReusePort(addr string, count int) ([]Transport, error) {
+	ts, err := ListenN(s.Network, addr, count)
+	if err != nil {
+		return nil, err
+	}
+	for i, t := range ts {
+		ts[i] = &openingTransport{Transport: t, net: s}
+	}
+	return ts, nil
+}
+
+// Wrap makes t, listening on another Network (a server's GRPC beside its
+// UDP), take and send only sealed messages, with the seals and replay
+// windows of s.
+func (s *SealNetwork) Wrap(t Transport) Transport {
+	return &openingTransport{Transport: t, net: s}
+}
+
+// FromPeer reports whether the latest message from addr was sealed by a
+// server (a game server or central) rather than a player.
+func (s *SealNetwork) FromPeer(addr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.peers[addr]
+	return p != nil && p.server()
+}
+
+// opened opens a sealed message from addr, drops it if it is plain, forged,
+// expired or replayed, and remembers the seal it came with.
+func (s *SealNetwork) opened(addr string, data []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.swept) > sealIdle {
+		s.sweep(now)
+	}
+
+	h, ok := readSealHeader(sealMagic, data)
+	switch {
+	case !ok && len(data) > 0 && data[0] == sealMagic:
+		return nil, errBadSeal
+	case !ok:
+		return nil, errUnsealed
+	case int64(h.expires) <= now.Unix():
+		return nil, errExpired
+	}
+	p := s.peers[addr]
+	if p == nil || p.id != h.id || p.expires != h.expires {
+		aead, err := newAEAD(s.keys.key(h.id, h.expires))
+		if err != nil {
+			return nil, err
+		}
+		p = &peerSeal{id: h.id, expires: h.expires, aead: aead}
+	}
+	msg, err := open(p.aead, data)
+	if err != nil {
+		return nil, err
+	}
+	// only a message that opened may move the window or claim the address
+	w := s.windows[h.sealSession]
+	if w == nil {
+		w = &replayWindow{}
+		s.windows[h.sealSession] = w
+	}
+	if !w.accept(h.seq) {
+		return nil, errReplayed
+	}
+	p.seen = now
+	s.peers[addr] = p
+	return msg, nil
+}
+
+// sweep forgets the addresses not heard from for sealIdle, and the windows
+// of expired sessions, which opened refuses anyway. Must be called with
+// s.mu held.
+func (s *SealNetwork) sweep(now time.Time) {
+	for addr, p := range s.peers {
+		if now.Sub(p.seen) > sealIdle {
+			delete(s.peers, addr)
+		}
+	}
+	for session := range s.windows {
+		if int64(session.expires) <= now.Unix() {
+			delete(s.windows, session)
+		}
+	}
+	s.swept = now
+}
+
+// sealFor seals data for addr.
+func (s *SealNetwork) sealFor(addr string, data []byte) ([]byte, error) {
+	s.mu.Lock()
+	p := s.peers[addr]
+	self, err := s.peerSession()
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	if p == nil || p.server() {
+		return self.seal(sealMagic, data)
+	}
+	return seal(p.aead, sealDownMagic, p.id, p.expires, self.seq.Add(1), data)
+}
+
+// peerSession returns the network's peer session, issuing a new one when
+// there is none or it is half way to expiring. Must be called with s.mu
+// held.
+func (s *SealNetwork) peerSession() (*sealer, error) {
+	if s.self != nil && time.Until(time.Unix(int64(s.self.expires), 0)) > sealTTL/2 {
+		return s.self, nil
+	}
+	seal, err := s.keys.issue(peerBit)
+	if err != nil {
+		return nil, err
+	}
+	if s.self, err = newSealer(seal); err != nil {
+		return nil, err
+	}
+	return s.self, nil
+}
+
+type openingTransport struct {
+	Transport
+	net *SealNetwork
+}
+
+func (t *openingTransport) Send(to string, data []byte) error {
+	msg, err := t.net.sealFor(to, data)
+	if err != nil {
+		return err
+	}
+	return t.Transport.Send(to, msg)
+}
+
+// Recv drops messages that are plain, fail to open, are expired or are
+// replayed.
+func (t *openingTransport) Recv() (string, []byte, error) {
+	for {
+		from, data, err := t.Transport.Recv()
+		if err != nil {
+			return from, data, err
+		}
+		if msg, err := t.net.opened(from, data); err == nil {
+			return from, msg, nil
+		}
+	}
+}
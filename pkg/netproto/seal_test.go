@@ -0,0 +1,205 @@
+package netproto
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+func TestReplayWindow(t *testing.T) {
+	tests := []struct {
+		name string
+		seqs []uint64
+		want []bool
+	}{
+		{"in order", []uint64{1, 2, 3}, []bool{true, true, true}},
+		{"duplicate", []uint64{5, 5}, []bool{true, false}},
+		{"reordered within the window", []uint64{10, 8, 9, 8}, []bool{true, true, true, false}},
+		{"older than the window", []uint64{5000, 5000 - replayWindowBits}, []bool{true, false}},
+		{"last in the window", []uint64{5000, 5000 - replayWindowBits + 1}, []bool{true, true}},
+		{"jump past the window", []uint64{1, 1 + 2*replayWindowBits, 1}, []bool{true, true, false}},
+		{"gap after a jump", []uint64{100, 101 + replayWindowBits, 100 + replayWindowBits, 100}, []bool{true, true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var w replayWindow
+			for i, seq := range tt.seqs {
+				if got := w.accept(seq); got != tt.want[i] {
+					t.Errorf("accept(%d) = %v, want %v", seq, got, tt.want[i])
+				}
+			}
+		})
+	}
+}
+
+// sealPair returns a cluster's keys, a player session issued from them and
+// the seal network of one of its game servers.
+func sealPair(t *testing.T) (*SealKeys, types.SessionSeal, *SealNetwork) {
+	t.Helper()
+	keys := NewSealKeys("cluster secret")
+	session, err := keys.Issue()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keys, session, NewSealNetwork(NewMemNetwork(1), keys)
+}
+
+func mustSeal(t *testing.T, s types.SessionSeal, magic byte, seq uint64, msg string) []byte {
+	t.Helper()
+	aead, err := newAEAD(s.Key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := seal(aead, magic, s.ID, uint32(s.Expires), seq, []byte(msg))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestSealOpened(t *testing.T) {
+	keys, session, _ := sealPair(t)
+	other := NewSealKeys("another cluster")
+	foreign, _ := other.Issue()
+	expired := session
+	expired.Expires = time.Now().Add(-time.Minute).Unix()
+	expired.Key = keys.key(expired.ID, uint32(expired.Expires))
+
+	tamper := func(data []byte, i int) []byte {
+		data = bytes.Clone(data)
+		data[i] ^= 1
+		return data
+	}
+	good := mustSeal(t, session, sealMagic, 1, `{"type":"GET_DATA"}`)
+
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"sealed", good, nil},
+		{"plain", []byte(`{"type":"GET_DATA"}`), errUnsealed},
+		{"truncated", good[:sealHeaderLen], errBadSeal},
+		{"ciphertext flipped", tamper(good, len(good)-1), errBadSeal},
+		{"session ID flipped", tamper(good, 1), errBadSeal},
+		{"expiry flipped", tamper(good, 12), errBadSeal},
+		{"sequence flipped", tamper(good, 20), errBadSeal},
+		{"other cluster's key", mustSeal(t, foreign, sealMagic, 1, "{}"), errBadSeal},
+		{"expired session", mustSeal(t, expired, sealMagic, 1, "{}"), errExpired},
+		{"reply turned around", mustSeal(t, session, sealDownMagic, 1, "{}"), errUnsealed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewSealNetwork(NewMemNetwork(1), keys)
+			msg, err := s.opened("mem:1", tt.data)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("opened: err = %v, want %v", err, tt.err)
+			}
+			if err == nil && string(msg) != `{"type":"GET_DATA"}` {
+				t.Errorf("opened = %q", msg)
+			}
+		})
+	}
+}
+
+func TestSealReplay(t *testing.T) {
+	_, session, s := sealPair(t)
+	first := mustSeal(t, session, sealMagic, 7, "{}")
+	if _, err := s.opened("mem:1", first); err != nil {
+		t.Fatalf("first: %v", err)
+	}
+	// the same datagram again, from the same address or another
+	for _, addr := range []string{"mem:1", "mem:2"} {
+		if _, err := s.opened(addr, first); !errors.Is(err, errReplayed) {
+			t.Errorf("replay from %s: err = %v, want %v", addr, err, errReplayed)
+		}
+	}
+	// a replay that fails must not move the window or claim the address
+	if _, err := s.opened("mem:1", mustSeal(t, session, sealMagic, 6, "{}")); err != nil {
+		t.Errorf("earlier message within the window: %v", err)
+	}
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	keys, session, _ := sealPair(t)
+	mem := NewMemNetwork(1)
+	servers := NewSealNetwork(mem, keys)
+	server, err := servers.Listen("mem:server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plain, _ := mem.Listen("")
+	sealer, err := NewSealer(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	player := sealer.Transport(plain)
+	deadline := time.Now().Add(time.Second)
+	server.SetReadDeadline(deadline)
+	player.SetReadDeadline(deadline)
+
+	if err := player.Send("mem:server", []byte("ping")); err != nil {
+		t.Fatal(err)
+	}
+	from, msg, err := server.Recv()
+	if err != nil || string(msg) != "ping" {
+		t.Fatalf("server got %q, %v", msg, err)
+	}
+	if servers.FromPeer(from) {
+		t.Errorf("FromPeer(%s) for a player", from)
+	}
+	if err := server.Send(from, []byte("pong")); err != nil {
+		t.Fatal(err)
+	}
+	if _, msg, err := player.Recv(); err != nil || string(msg) != "pong" {
+		t.Fatalf("player got %q, %v", msg, err)
+	}
+
+	// a plain push to the player is dropped
+	plainServer, _ := mem.Listen("")
+	plainServer.Send(from, []byte("spoofed"))
+	player.SetReadDeadline(time.Now().Add(50 * time.Millisecond))
+	if _, msg, err := player.Recv(); !errors.Is(err, ErrTimeout) {
+		t.Errorf("player took a plain push: %q, %v", msg, err)
+	}
+}
+
+func TestSealPeers(t *testing.T) {
+	keys, _, _ := sealPair(t)
+	mem := NewMemNetwork(1)
+	a, b := NewSealNetwork(mem, keys), NewSealNetwork(mem, keys)
+	serverB, err := b.Listen("mem:b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	serverB.SetReadDeadline(time.Now().Add(time.Second))
+
+	fromA, _ := a.Listen("")
+	if err := fromA.Send("mem:b", []byte("gossip")); err != nil {
+		t.Fatal(err)
+	}
+	from, msg, err := serverB.Recv()
+	if err != nil || string(msg) != "gossip" {
+		t.Fatalf("b got %q, %v", msg, err)
+	}
+	if !b.FromPeer(from) {
+		t.Errorf("FromPeer(%s) = false for a server", from)
+	}
+
+	// b replies with its own peer session, which a opens
+	fromA.SetReadDeadline(time.Now().Add(time.Second))
+	serverB.Send(from, []byte("ack"))
+	if _, msg, err := fromA.Recv(); err != nil || string(msg) != "ack" {
+		t.Fatalf("a got %q, %v", msg, err)
+	}
+
+	// a player's session never has the peer bit
+	for i := 0; i < 64; i++ {
+		if s, _ := keys.Issue(); s.ID&peerBit != 0 {
+			t.Fatalf("Issue gave a peer session %x", s.ID)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+package netproto
+
+import (
+	"crypto/tls"
+	"errors"
+	"flag"
+	"log"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ===================== TLS =====================
+
+// TLSFiles is the certificate and key an HTTP API serves HTTPS with. The
+// files are read again whenever they change on disk, so a certificate
+// renewed in place by certbot or another ACME client is picked up without a
+// restart.
+type TLSFiles struct {
+	CertFile string // PEM certificate chain, leaf first
+	KeyFile  string // PEM private key
+}
+
+// RegisterFlags adds -tls-cert and -tls-key to fs, defaulting to TLS_CERT and
+// TLS_KEY.
+func (t *TLSFiles) RegisterFlags(fs *flag.FlagSet) {
+	fs.StringVar(&t.CertFile, "tls-cert", os.Getenv("TLS_CERT"), "PEM certificate file to serve HTTPS with (plain HTTP if empty)")
+	fs.StringVar(&t.KeyFile, "tls-key", os.Getenv("TLS_KEY"), "PEM private key file for -tls-cert")
+}
+
+func (t TLSFiles) Enabled() bool {
+	return t.CertFile != "" || t.KeyFile != ""
+}
+
+// ListenAndServe serves h on addr, over HTTPS when t is enabled. It fails
+// at once if the certificate cannot be loaded.
+func ListenAndServe(addr string, h http.Handler, t TLSFiles) error {
+	if !t.Enabled() {
+		return http.ListenAndServe(addr, h)
+	}
+	if t.CertFile == "" || t.KeyFile == "" {
+		return errors.New("tls: both -tls-cert and -tls-key are needed")
+	}
+	certs := &certReloader{files: t}
+	if _, err := certs.load(); err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Addr:    addr,
+		Handler: h,
+		TLSConfig: &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			GetCertificate: certs.get,
+		},
+	}
+	return srv.ListenAndServeTLS("", "")
+}
+
+// certReloader hands out the certificate in its files, loading it again
+// when either file's modification time changes.
+type certReloader struct {
+	files TLSFiles
+
+	mu      sync.Mutex
+	cert    *tls.Certificate
+	modTime time.Time
+	checked time.Time
+}
+
+// certCheckEvery bounds how often handshakes stat the files.
+const certCheckEvery = 10 * time.Second
+
+func (c *certReloader) get(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if time.Since(c.checked) < certCheckEvery {
+		return c.cert, nil
+	}
+	c.checked = time.Now()
+	cert, err := c.loadLocked()
+	if err != nil {
+		// a renewal caught half-written; keep serving the old one
+		log.Printf("⚠️  Reloading TLS certificate failed, keeping the current one: %v", err)
+		return c.cert, nil
+	}
+	return cert, nil
+}
+
+func (c *certReloader) load() (*tls.Certificate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checked = time.Now()
+	return c.loadLocked()
+}
+
+func (c *certReloader) loadLocked() (*tls.Certificate, error) {
+	var latest time.Time
+	for _, path := range []string{c.files.CertFile, c.files.KeyFile} {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	if c.cert != nil && latest.Equal(c.modTime) {
+		return c.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(c.files.CertFile, c.files.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	if c.cert != nil {
+		log.Printf("🔐 Reloaded TLS certificate from %s", c.files.CertFile)
+	}
+	c.cert, c.modTime = &cert, latest
+	return c.cert, nil
+}
@@ -0,0 +1,273 @@
+package netproto
+
+import (
+	"fmt"
+	"math/rand"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/net/ipv4"
+)
+
+// ===================== Transport =====================
+
+// A Transport sends and receives datagrams addressed by "host:port" strings.
+// Handlers only see a Transport, so the same server code runs over real UDP
+// sockets or over an in-memory Network inside one process.
+type Transport interface {
+	Send(to string, data []byte) error
+	// Recv blocks until a datagram arrives or the read deadline passes.
+	Recv() (from string, data []byte, err error)
+	SetReadDeadline(t time.Time) error
+	LocalAddr() string
+	Close() error
+}
+
+// A Network hands out Transports. Listen("") picks a free address, as a
+// client socket does.
+type Network interface {
+	Listen(addr string) (Transport, error)
+}
+
+// ErrTimeout is returned by Recv when the read deadline passes.
+var ErrTimeout = os.ErrDeadlineExceeded
+
+// ===================== UDP =====================
+
+// UDP is the Network of real UDP sockets.
+var UDP Network = udpNetwork{}
+
+type udpNetwork struct{}
+
+func (udpNetwork) Listen(addr string) (Transport, error) {
+	var laddr *net.UDPAddr
+	if addr != "" {
+		var err error
+		if laddr, err = net.ResolveUDPAddr("udp", addr); err != nil {
+			return nil, err
+		}
+	}
+	conn, err := net.ListenUDP("udp", laddr)
+	if err != nil {
+		return nil, err
+	}
+	t := newUDPTransport(conn)
+	if addr != "" {
+		t.batchReads()
+	}
+	return t, nil
+}
+
+// udpTransport fragments messages larger than one datagram on Send and
+// reassembles them in Recv.
+type udpTransport struct {
+	conn  *net.UDPConn
+	pc    *ipv4.PacketConn // conn, for batched reads and writes
+	inet4 bool             // an IPv4 socket, which WriteBatch can send on
+	buf   []byte
+	batch *recvBatch // set when reads are batched
+	frag  *fragmenter
+	asm   *reassembler
+	peer  string // set for a socket connected by Dial
+}
+
+// socketBuffer is requested for both directions so a fragmented message is
+// not dropped by the kernel before Recv drains it. The OS may cap it lower.
+const socketBuffer = 4 << 20
+
+func newUDPTransport(conn *net.UDPConn) *udpTransport {
+	conn.SetReadBuffer(socketBuffer)
+	conn.SetWriteBuffer(socketBuffer)
+	laddr, _ := conn.LocalAddr().(*net.UDPAddr)
+	return &udpTransport{
+		conn:  conn,
+		pc:    ipv4.NewPacketConn(conn),
+		inet4: laddr != nil && laddr.IP.To4() != nil,
+		buf:   make([]byte, MaxDatagram),
+		frag:  newFragmenter(),
+		asm:   newReassembler(),
+	}
+}
+
+func (u *udpTransport) Send(to string, data []byte) error {
+	frags, err := u.frag.split(data)
+	if err != nil {
+		return err
+	}
+	if u.peer != "" {
+		return u.sendConnected(to, frags)
+	}
+	addr, err := net.ResolveUDPAddr("udp", to)
+	if err != nil {
+		return err
+	}
+	for _, frag := range frags {
+		if _, err := u.conn.WriteToUDP(frag, addr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Recv keeps reading until a whole message is in, so the read deadline
+// covers every fragment of it.
+func (u *udpTransport) Recv() (string, []byte, error) {
+	for {
+		from, data, err := u.read()
+		if err != nil {
+			return "", nil, err
+		}
+		if msg, ok := u.asm.add(from, data); ok {
+			return from, msg, nil
+		}
+	}
+}
+
+// read returns a copy of the next datagram.
+func (u *udpTransport) read() (string, []byte, error) {
+	if u.batch != nil {
+		return u.batch.read(u.pc)
+	}
+	n, addr, err := u.conn.ReadFromUDP(u.buf)
+	if err != nil {
+		return "", nil, err
+	}
+	return addr.String(), append([]byte(nil), u.buf[:n]...), nil
+}
+
+func (u *udpTransport) SetReadDeadline(t time.Time) error { return u.conn.SetReadDeadline(t) }
+func (u *udpTransport) LocalAddr() string                 { return u.conn.LocalAddr().String() }
+func (u *udpTransport) Close() error                      { return u.conn.Close() }
+
+// ===================== In-memory =====================
+
+// MemNetwork delivers datagrams between Transports in the same process. Loss
+// and reordering are driven by a seeded source so a failing run can be
+// replayed exactly.
+type MemNetwork struct {
+	mu        sync.Mutex
+	endpoints map[string]*memTransport
+	rng       *rand.Rand
+	nextPort  int
+
+	LossRate    float64       // probability a datagram is dropped
+	ReorderRate float64       // probability a datagram is held back
+	MaxDelay    time.Duration // upper bound for held-back datagrams
+}
+
+func NewMemNetwork(seed int64) *MemNetwork {
+	return &MemNetwork{endpoints: make(map[string]*memTransport), rng: rand.New(rand.NewSource(seed)), nextPort: 40000}
+}
+
+func (m *MemNetwork) Listen(addr string) (Transport, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if addr == "" {
+		m.nextPort++
+		addr = fmt.Sprintf("mem:%d", m.nextPort)
+	}
+	if _, taken := m.endpoints[addr]; taken {
+		return nil, fmt.Errorf("listen %s: address already in use", addr)
+	}
+	t := &memTransport{net: m, addr: addr, inbox: make(chan memPacket, 1024), closed: make(chan struct{})}
+	m.endpoints[addr] = t
+	return t, nil
+}
+
+// deliver applies loss and reordering, then queues the datagram.
+func (m *MemNetwork) deliver(from, to string, data []byte) {
+	m.mu.Lock()
+	dst, ok := m.endpoints[to]
+	drop := m.rng.Float64() < m.LossRate
+	var delay time.Duration
+	if m.MaxDelay > 0 && m.rng.Float64() < m.ReorderRate {
+		delay = time.Duration(m.rng.Int63n(int64(m.MaxDelay)))
+	}
+	m.mu.Unlock()
+	if !ok || drop {
+		return
+	}
+
+	pkt := memPacket{from: from, data: append([]byte(nil), data...)}
+	if delay > 0 {
+		time.AfterFunc(delay, func() { dst.enqueue(pkt) })
+		return
+	}
+	dst.enqueue(pkt)
+}
+
+type memPacket struct {
+	from string
+	data []byte
+}
+
+type memTransport struct {
+	net   *MemNetwork
+	addr  string
+	inbox chan memPacket
+
+	mu       sync.Mutex
+	deadline time.Time
+	closed   chan struct{}
+	once     sync.Once
+}
+
+func (t *memTransport) enqueue(pkt memPacket) {
+	select {
+	case t.inbox <- pkt:
+	case <-t.closed:
+	default: // full inbox drops, like a full socket buffer
+	}
+}
+
+func (t *memTransport) Send(to string, data []byte) error {
+	select {
+	case <-t.closed:
+		return net.ErrClosed
+	default:
+	}
+	t.net.deliver(t.addr, to, data)
+	return nil
+}
+
+func (t *memTransport) Recv() (string, []byte, error) {
+	t.mu.Lock()
+	deadline := t.deadline
+	t.mu.Unlock()
+
+	var timeout <-chan time.Time
+	if !deadline.IsZero() {
+		timer := time.NewTimer(time.Until(deadline))
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case pkt := <-t.inbox:
+		return pkt.from, pkt.data, nil
+	case <-timeout:
+		return "", nil, ErrTimeout
+	case <-t.closed:
+		return "", nil, net.ErrClosed
+	}
+}
+
+func (t *memTransport) SetReadDeadline(d time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deadline = d
+	return nil
+}
+
+func (t *memTransport) LocalAddr() string { return t.addr }
+
+func (t *memTransport) Close() error {
+	t.once.Do(func() {
+		close(t.closed)
+		t.net.mu.Lock()
+		delete(t.net.endpoints, t.addr)
+		t.net.mu.Unlock()
+	})
+	return nil
+}
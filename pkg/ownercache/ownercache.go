@@ -0,0 +1,138 @@
+// Package ownercache remembers which game server owns each chunk, so a game
+// server can route a player to a chunk's owner without asking central. An
+// entry is a hint: it expires after its TTL, and whoever acts on it must
+// cope with it being stale.
+//
+// The cache is kept in process (LRU), or in Redis to share it between the
+// game servers.
+package ownercache
+
+import (
+	"container/list"
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// A Cache maps chunks to their owners. Get reports a miss for an unknown or
+// expired chunk; errors are the backing store's.
+type Cache interface {
+	Name() string
+	Get(ctx context.Context, id types.ChunkID) (string, bool, error)
+	Set(ctx context.Context, id types.ChunkID, owner string) error
+	Delete(ctx context.Context, id types.ChunkID) error
+}
+
+// Open returns the cache rawURL names, its entries living ttl:
+//
+//	memory                          in process, up to size chunks
+//	redis://[:password@]host:6379[/db]
+//
+// A Redis cache does not connect until it is first used.
+func Open(rawURL string, size int, ttl time.Duration) (Cache, error) {
+	if ttl <= 0 {
+		return nil, fmt.Errorf("cache TTL must be positive")
+	}
+	if rawURL == "memory" {
+		if size <= 0 {
+			return nil, fmt.Errorf("cache size must be positive")
+		}
+		return NewLRU(size, ttl), nil
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "redis" || u.Host == "" {
+		return nil, fmt.Errorf("unknown owner cache %q (memory or redis://host:port)", rawURL)
+	}
+	return newRedis(rawURL, ttl)
+}
+
+// key names id in a shared store.
+func key(id types.ChunkID) string {
+	return "owner:" + id.World + ":" + strconv.Itoa(id.Level) + ":" + strconv.Itoa(id.IDX) + ":" + strconv.Itoa(id.IDY)
+}
+
+// ===================== In-process LRU =====================
+
+// LRU is a Cache in memory holding the size most recently used chunks. It
+// is safe for concurrent use.
+type LRU struct {
+	mu      sync.Mutex
+	size    int
+	ttl     time.Duration
+	order   *list.List // front is most recent
+	entries map[types.ChunkID]*list.Element
+}
+
+type lruEntry struct {
+	id      types.ChunkID
+	owner   string
+	expires time.Time
+}
+
+// NewLRU returns an empty LRU.
+func NewLRU(size int, ttl time.Duration) *LRU {
+	return &LRU{size: size, ttl: ttl, order: list.New(), entries: make(map[types.ChunkID]*list.Element)}
+}
+
+func (c *LRU) Name() string { return "memory" }
+
+func (c *LRU) Get(ctx context.Context, id types.ChunkID) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[id]
+	if !ok {
+		return "", false, nil
+	}
+	entry := el.Value.(*lruEntry)
+	if time.Now().After(entry.expires) {
+		c.order.Remove(el)
+		delete(c.entries, id)
+		return "", false, nil
+	}
+	c.order.MoveToFront(el)
+	return entry.owner, true, nil
+}
+
+func (c *LRU) Set(ctx context.Context, id types.ChunkID, owner string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expires := time.Now().Add(c.ttl)
+	if el, ok := c.entries[id]; ok {
+		entry := el.Value.(*lruEntry)
+		entry.owner, entry.expires = owner, expires
+		c.order.MoveToFront(el)
+		return nil
+	}
+	c.entries[id] = c.order.PushFront(&lruEntry{id: id, owner: owner, expires: expires})
+	for c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*lruEntry).id)
+	}
+	return nil
+}
+
+func (c *LRU) Delete(ctx context.Context, id types.ChunkID) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[id]; ok {
+		c.order.Remove(el)
+		delete(c.entries, id)
+	}
+	return nil
+}
+
+// Len returns how many chunks are cached, expired ones included.
+func (c *LRU) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries)
+}
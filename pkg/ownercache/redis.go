@@ -0,0 +1,54 @@
+package ownercache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// ===================== Redis =====================
+
+// redisCache keeps entries as plain string keys (see key) with an expiry,
+// through a go-redis client, which pools its connections, dials on first
+// use and again after an error.
+
+const redisDialTimeout = time.Second
+
+type redisCache struct {
+	client *redis.Client
+	ttl    time.Duration
+}
+
+func newRedis(rawURL string, ttl time.Duration) (*redisCache, error) {
+	opts, err := redis.ParseURL(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	opts.DialTimeout = redisDialTimeout
+	return &redisCache{client: redis.NewClient(opts), ttl: ttl}, nil
+}
+
+func (c *redisCache) Name() string { return "redis" }
+
+func (c *redisCache) Get(ctx context.Context, id types.ChunkID) (string, bool, error) {
+	owner, err := c.client.Get(ctx, key(id)).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, err
+	}
+	return owner, true, nil
+}
+
+func (c *redisCache) Set(ctx context.Context, id types.ChunkID, owner string) error {
+	return c.client.Set(ctx, key(id), owner, c.ttl).Err()
+}
+
+func (c *redisCache) Delete(ctx context.Context, id types.ChunkID) error {
+	return c.client.Del(ctx, key(id)).Err()
+}
@@ -0,0 +1,194 @@
+// Package player is the simulated player used by cmd/simclient and
+// cmd/loadtest: a pkg/client Client that joins through the central server
+// and walks across chunks in a game loop.
+package player
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/client"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/netproto"
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// WorldSize bounds player coordinates on both axes.
+const WorldSize = 500
+
+type PlayerState struct {
+	c *client.Client
+
+	// Tick is the game loop period.
+	Tick time.Duration
+	// Move advances the player once per frame; MoveDiagonal if nil.
+	Move func(p *types.Player)
+	// OnResponse, if set, sees every request with its round-trip time.
+	OnResponse func(req types.Request, res *types.Response, rtt time.Duration, err error)
+}
+
+func NewPlayerState(network netproto.Network, playerID string) (*PlayerState, error) {
+	c, err := client.New(network, playerID)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &PlayerState{c: c, Tick: 2 * time.Second}
+	c.OnResponse = func(req types.Request, res *types.Response, rtt time.Duration, err error) {
+		if ps.OnResponse != nil {
+			ps.OnResponse(req, res, rtt, err)
+		}
+	}
+	return ps, nil
+}
+
+// Player returns the player's current state.
+func (ps *PlayerState) Player() types.Player { return ps.c.Player() }
+
+// Server returns the game server the player is talking to.
+func (ps *PlayerState) Server() string { return ps.c.Server() }
+
+// ReportStats sends the player's connection stats to their game server,
+// and logs them, every period until ctx is done.
+func (ps *PlayerState) ReportStats(ctx context.Context, every time.Duration) {
+	ps.c.LogStats = true
+	ps.c.ReportStats(ctx, every)
+}
+
+// SetWorld chooses the world Join enters the player in.
+func (ps *PlayerState) SetWorld(world string) { ps.c.SetWorld(world) }
+
+// SetSeal has Join ask for a key to encrypt the player's UDP traffic with.
+func (ps *PlayerState) SetSeal(seal bool) { ps.c.Seal = seal }
+
+// Join asks the central server which game server to use.
+func (ps *PlayerState) Join(centralURL string) error {
+	if err := ps.c.Join(centralURL); err != nil {
+		return err
+	}
+	if ps.c.Seal && !ps.c.Sealed() {
+		log.Println("⚠️  Central has no seal to give; playing unsealed")
+	}
+	return nil
+}
+
+// Initialize enters the chunk at the player's position, spawning in the
+// alternative a full chunk suggests.
+func (ps *PlayerState) Initialize() {
+	log.Printf("🎮 Player %s initializing...", ps.c.Player().ID)
+
+	err := ps.c.Enter()
+	var refused *client.RefusedError
+	if errors.As(err, &refused) && refused.Res.Code == types.CodeChunkFull && refused.Res.Alternative != nil {
+		ps.moveInto(*refused.Res.Alternative)
+		log.Printf("↪️  Spawning in chunk [%d,%d] instead", refused.Res.Alternative.IDX, refused.Res.Alternative.IDY)
+		return
+	}
+	if err != nil {
+		log.Printf("⚠️  Initialization failed: %v", err)
+	}
+}
+
+// moveInto puts the player at the centre of chunk_id.
+func (ps *PlayerState) moveInto(chunk_id types.ChunkID) {
+	edge := chunk_id.Edge(ps.c.ChunkSize())
+	ps.c.Place(chunk_id.IDX*edge+edge/2, chunk_id.IDY*edge+edge/2)
+}
+
+// MoveDiagonal walks one step towards +x,+y, the original sim client path.
+func MoveDiagonal(p *types.Player) {
+	p.PosX += 1
+	p.PosY += 1
+	ClampToWorld(p)
+}
+
+// ClampToWorld keeps a player inside the simulated 500x500 area.
+func ClampToWorld(p *types.Player) {
+	if p.PosX < 0 {
+		p.PosX = 0
+	}
+	if p.PosY < 0 {
+		p.PosY = 0
+	}
+	if p.PosX > WorldSize {
+		p.PosX = WorldSize
+	}
+	if p.PosY > WorldSize {
+		p.PosY = WorldSize
+	}
+}
+
+func (ps *PlayerState) GetNearbyPlayers() {
+	update, err := ps.c.Poll()
+	if err != nil {
+		log.Printf("❌ Failed to get updates: %v", err)
+		return
+	}
+
+	if d := update.Delta; d != nil {
+		log.Printf("👥 Received chunk changes: %d cubes, %d players changed, %d cubes, %d players removed",
+			len(d.Cubes), len(d.Players), len(d.RemovedCubes), len(d.RemovedPlayers))
+	} else {
+		log.Printf("👥 Received chunk updates")
+		log.Printf("Chunk is : %+v", update.Chunk)
+	}
+}
+
+// GameLoop plays until ctx is done or the player is kicked.
+func (ps *PlayerState) GameLoop(ctx context.Context) {
+	player := ps.c.Player()
+	log.Printf("🎯 Starting game loop for player %s", player.ID)
+
+	ticker := time.NewTicker(ps.Tick)
+	defer ticker.Stop()
+
+	move := ps.Move
+	if move == nil {
+		move = MoveDiagonal
+	}
+
+	frame := 0
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		if ps.c.Kicked() {
+			log.Printf("🛑 Player %s was kicked, leaving game loop", player.ID)
+			return
+		}
+		frame++
+		log.Printf("\n--- Frame %d ---", frame)
+
+		// 1. Move player; the client enters the new chunk when it changes
+		player = ps.c.Player()
+		move(&player)
+		if err := ps.c.Move(player.PosX, player.PosY); err != nil {
+			log.Printf("⚠️  Move to (%d, %d) failed: %v", player.PosX, player.PosY, err)
+			continue // Skip this frame if the move failed
+		}
+
+		// 2. Get nearby players and updates (every 3 frames)
+		if frame%3 == 0 {
+			ps.GetNearbyPlayers()
+		}
+
+		for _, announcement := range ps.c.Announcements() {
+			log.Printf("📢 [%s] %s", announcement.Kind, announcement.Text)
+		}
+
+		// 3. Log current state
+		chunk_id := ps.c.Chunk()
+		log.Printf("🎮 Player %s at (%d, %d) in chunk [%d,%d]",
+			player.ID, player.PosX, player.PosY, chunk_id.IDX, chunk_id.IDY)
+	}
+}
+
+func (ps *PlayerState) Cleanup() {
+	log.Printf("🧹 Cleaning up player %s", ps.c.Player().ID)
+
+	// Notify server about player departure
+	ps.c.Leave() // Best effort cleanup
+}
@@ -0,0 +1,122 @@
+// Package s3 keeps objects in one bucket of S3-compatible object storage
+// (AWS S3, MinIO, Ceph, R2, ...) through minio-go: putting, getting,
+// listing and deleting them. Buckets are addressed by path
+// (ENDPOINT/BUCKET/KEY), which every implementation accepts.
+package s3
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// Config says where a bucket is and how to sign for it.
+type Config struct {
+	Endpoint     string // e.g. https://s3.us-east-1.amazonaws.com or http://minio:9000
+	Region       string
+	Bucket       string
+	AccessKey    string
+	SecretKey    string
+	SessionToken string // for temporary credentials
+}
+
+// Client talks to one bucket.
+type Client struct {
+	bucket string
+	mc     *minio.Client
+}
+
+// Object is one entry of a listing.
+type Object struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// New returns a client for cfg.Bucket. It does not connect until first
+// used.
+func New(cfg Config) (*Client, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("s3: endpoint must be an http:// or https:// URL, got %q", cfg.Endpoint)
+	}
+	if strings.Trim(u.Path, "/") != "" {
+		return nil, fmt.Errorf("s3: endpoint %q must not have a path", cfg.Endpoint)
+	}
+	if cfg.Bucket == "" || cfg.Region == "" {
+		return nil, fmt.Errorf("s3: bucket and region are required")
+	}
+	if cfg.AccessKey == "" || cfg.SecretKey == "" {
+		return nil, fmt.Errorf("s3: no credentials (AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY)")
+	}
+	mc, err := minio.New(u.Host, &minio.Options{
+		Creds:        credentials.NewStaticV4(cfg.AccessKey, cfg.SecretKey, cfg.SessionToken),
+		Secure:       u.Scheme == "https",
+		Region:       cfg.Region,
+		BucketLookup: minio.BucketLookupPath,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("s3: %w", err)
+	}
+	return &Client{bucket: cfg.Bucket, mc: mc}, nil
+}
+
+// Put stores body at key with the given user metadata (x-amz-meta-*). The
+// store checks the upload against a checksum sent along with it.
+func (c *Client) Put(ctx context.Context, key string, body []byte, contentType string, meta map[string]string) error {
+	_, err := c.mc.PutObject(ctx, c.bucket, key, bytes.NewReader(body), int64(len(body)),
+		minio.PutObjectOptions{ContentType: contentType, UserMetadata: meta})
+	return err
+}
+
+// Get returns the object at key and its user metadata, keys lower case.
+func (c *Client) Get(ctx context.Context, key string) ([]byte, map[string]string, error) {
+	obj, err := c.mc.GetObject(ctx, c.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	defer obj.Close()
+	body, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, nil, err
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		return nil, nil, err
+	}
+	meta := make(map[string]string, len(info.UserMetadata))
+	for k, v := range info.UserMetadata {
+		meta[strings.ToLower(k)] = v
+	}
+	return body, meta, nil
+}
+
+// Delete removes the object at key; a missing one is not an error.
+func (c *Client) Delete(ctx context.Context, key string) error {
+	return c.mc.RemoveObject(ctx, c.bucket, key, minio.RemoveObjectOptions{})
+}
+
+// List returns every object whose key starts with prefix, in key order.
+func (c *Client) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	for o := range c.mc.ListObjects(ctx, c.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if o.Err != nil {
+			return nil, o.Err
+		}
+		objects = append(objects, Object{Key: o.Key, Size: o.Size, LastModified: o.LastModified})
+	}
+	return objects, nil
+}
+
+// IsNotFound reports whether err says there is no object at the key asked
+// for.
+func IsNotFound(err error) bool {
+	return err != nil && minio.ToErrorResponse(err).Code == "NoSuchKey"
+}
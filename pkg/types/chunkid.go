@@ -0,0 +1,80 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// ===================== Chunk IDs =====================
+
+// A ChunkID names a chunk, never a position: IDX and IDY count chunks of
+// the ID's level, whole chunks at level 0 and sub-chunks of Edge at level
+// n, so position (x, y) is in chunk floor(x/edge), floor(y/edge). Positions
+// become IDs only through ChunkOf and ResolveChunk, and every component
+// keys its maps, files and messages by the ID exactly as it got it, without
+// rescaling. Whatever takes chunk IDs from outside (UDP requests, central's
+// endpoints, HTTP paths) checks them with Validate, or reads them with
+// ParseChunkID, and refuses the request if they are out of range.
+
+// MaxChunkCoord bounds the level-0 chunk coordinates along each axis to
+// -MaxChunkCoord..MaxChunkCoord-1, far beyond any world a cluster can hold
+// but small enough that no position or sub-chunk coordinate overflows.
+const MaxChunkCoord = 1 << 20
+
+// Validate reports what is wrong with id, if anything: a world that cannot
+// be named, a level outside 0 to MaxChunkLevel, or coordinates beyond
+// MaxChunkCoord (scaled to the level).
+func (id ChunkID) Validate() error {
+	if id.World != DefaultWorld && !ValidWorldID(id.World) {
+		return fmt.Errorf("invalid world %q", id.World)
+	}
+	if id.Level < 0 || id.Level > MaxChunkLevel {
+		return fmt.Errorf("chunk level %d outside 0 to %d", id.Level, MaxChunkLevel)
+	}
+	limit := MaxChunkCoord << id.Level
+	if id.IDX < -limit || id.IDX >= limit || id.IDY < -limit || id.IDY >= limit {
+		return fmt.Errorf("chunk [%d,%d] at level %d out of range", id.IDX, id.IDY, id.Level)
+	}
+	return nil
+}
+
+// NormalizeChunk makes the chunk a request carries agree with the request's
+// ChunkID. A bare chunk (see Request.BareChunk), sent without an ID of its
+// own, takes id. Any other chunk must already carry id: a zero ID is the
+// origin chunk's, never a missing one, so a whole chunk sent with the
+// wrong ID is refused rather than filed under id.
+func NormalizeChunk(id ChunkID, chunk *Chunk, bare bool) error {
+	got := chunk.ID()
+	if bare && got == (ChunkID{}) {
+		chunk.World, chunk.IDX, chunk.IDY, chunk.Level = id.World, id.IDX, id.IDY, id.Level
+		return nil
+	}
+	if got == id {
+		return nil
+	}
+	return fmt.Errorf("chunk [%d,%d] level %d sent for chunk [%d,%d] level %d", got.IDX, got.IDY, got.Level, id.IDX, id.IDY, id.Level)
+}
+
+// ParseChunkID reads a chunk ID the way HTTP endpoints take one: x and y
+// from the path, world and level from the query (either may be empty), and
+// validates it.
+func ParseChunkID(x, y, world, level string) (ChunkID, error) {
+	idx, errX := strconv.Atoi(x)
+	idy, errY := strconv.Atoi(y)
+	if errX != nil || errY != nil {
+		return ChunkID{}, errors.New("Chunk coordinates must be integers")
+	}
+	id := ChunkID{World: world, IDX: idx, IDY: idy}
+	if level != "" {
+		n, err := strconv.Atoi(level)
+		if err != nil {
+			return ChunkID{}, errors.New("Invalid level")
+		}
+		id.Level = n
+	}
+	if err := id.Validate(); err != nil {
+		return ChunkID{}, err
+	}
+	return id, nil
+}
@@ -0,0 +1,114 @@
+package types
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChunkIDValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		id   ChunkID
+		ok   bool
+	}{
+		{"origin", ChunkID{}, true},
+		{"negative", ChunkID{IDX: -3, IDY: -7}, true},
+		{"named world", ChunkID{World: "arena-2", IDX: 1}, true},
+		{"world with capitals", ChunkID{World: "Arena"}, false},
+		{"world with a slash", ChunkID{World: "../etc"}, false},
+		{"world too long", ChunkID{World: strings.Repeat("a", maxWorldID+1)}, false},
+		{"longest world", ChunkID{World: strings.Repeat("a", maxWorldID)}, true},
+		{"last level", ChunkID{Level: MaxChunkLevel}, true},
+		{"level too deep", ChunkID{Level: MaxChunkLevel + 1}, false},
+		{"negative level", ChunkID{Level: -1}, false},
+		{"lowest x", ChunkID{IDX: -MaxChunkCoord}, true},
+		{"highest x", ChunkID{IDX: MaxChunkCoord - 1}, true},
+		{"x too high", ChunkID{IDX: MaxChunkCoord}, false},
+		{"x too low", ChunkID{IDX: -MaxChunkCoord - 1}, false},
+		{"y too high", ChunkID{IDY: MaxChunkCoord}, false},
+		{"y too low", ChunkID{IDY: -MaxChunkCoord - 1}, false},
+		{"scaled to the level", ChunkID{IDX: MaxChunkCoord<<2 - 1, IDY: -MaxChunkCoord << 2, Level: 2}, true},
+		{"past the level's range", ChunkID{IDX: MaxChunkCoord << 2, Level: 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.id.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate(%+v) = %v, want ok %v", tt.id, err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestParseChunkID(t *testing.T) {
+	tests := []struct {
+		name               string
+		x, y, world, level string
+		want               ChunkID
+		err                string // part of the error wanted, "" for none
+	}{
+		{"plain", "3", "-4", "", "", ChunkID{IDX: 3, IDY: -4}, ""},
+		{"world and level", "10", "11", "lobby", "1", ChunkID{World: "lobby", IDX: 10, IDY: 11, Level: 1}, ""},
+		{"explicit level 0", "0", "0", "", "0", ChunkID{}, ""},
+		{"plus sign", "+5", "6", "", "", ChunkID{IDX: 5, IDY: 6}, ""},
+		{"x not a number", "a", "1", "", "", ChunkID{}, "integers"},
+		{"y empty", "1", "", "", "", ChunkID{}, "integers"},
+		{"fraction", "1.5", "1", "", "", ChunkID{}, "integers"},
+		{"overflowing int", "99999999999999999999", "0", "", "", ChunkID{}, "integers"},
+		{"level not a number", "1", "1", "", "deep", ChunkID{}, "Invalid level"},
+		{"level out of range", "1", "1", "", "9", ChunkID{}, "level 9"},
+		{"out of range", "1048576", "0", "", "", ChunkID{}, "out of range"},
+		{"bad world", "1", "1", "No Such World", "", ChunkID{}, "invalid world"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseChunkID(tt.x, tt.y, tt.world, tt.level)
+			if tt.err == "" {
+				if err != nil || got != tt.want {
+					t.Errorf("ParseChunkID = %+v, %v, want %+v", got, err, tt.want)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("ParseChunkID = %+v, %v, want an error with %q", got, err, tt.err)
+			}
+			if got != (ChunkID{}) {
+				t.Errorf("ParseChunkID returned %+v with its error", got)
+			}
+		})
+	}
+}
+
+func TestNormalizeChunk(t *testing.T) {
+	origin, other := ChunkID{}, ChunkID{World: "w1", IDX: 3, IDY: -4, Level: 1}
+	tests := []struct {
+		name  string
+		id    ChunkID
+		chunk ChunkID // the ID the chunk is sent with
+		bare  bool
+		want  ChunkID // the chunk's ID afterwards; ignored when refused
+		ok    bool
+	}{
+		{"matching", other, other, false, other, true},
+		{"origin chunk for the origin", origin, origin, false, origin, true},
+		{"origin chunk for another chunk", other, origin, false, origin, false},
+		{"other chunk for the origin", origin, other, false, origin, false},
+		{"bare chunk takes the ID", other, origin, true, other, true},
+		{"bare chunk for the origin", origin, origin, true, origin, true},
+		{"bare chunk with another ID", other, ChunkID{IDX: 1}, true, origin, false},
+		{"bare chunk already with the ID", other, other, true, other, true},
+		{"another world", other, ChunkID{World: "w2", IDX: 3, IDY: -4, Level: 1}, false, origin, false},
+		{"another level", other, ChunkID{World: "w1", IDX: 3, IDY: -4}, false, origin, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			chunk := Chunk{World: tt.chunk.World, IDX: tt.chunk.IDX, IDY: tt.chunk.IDY, Level: tt.chunk.Level}
+			err := NormalizeChunk(tt.id, &chunk, tt.bare)
+			if (err == nil) != tt.ok {
+				t.Fatalf("NormalizeChunk(%+v, %+v, bare %v) = %v, want ok %v", tt.id, tt.chunk, tt.bare, err, tt.ok)
+			}
+			if tt.ok && chunk.ID() != tt.want {
+				t.Errorf("chunk afterwards %+v, want %+v", chunk.ID(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,3435 @@
+// Code generated by gen_codecs.go; DO NOT EDIT.
+
+package types
+
+func (x *Request) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"type":`)
+	w.string(string(x.Type))
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	w.raw(`,"caller_ip":`)
+	w.string(x.CallerIP)
+	w.raw(`,"player":`)
+	x.Player.writeJSON(w)
+	w.raw(`,"is_peer_req":`)
+	w.bool(x.IsPeerReq)
+	w.raw(`,"chunk":`)
+	x.Chunk.writeJSON(w)
+	w.raw(`,"is_chunk_new":`)
+	w.bool(x.IsChunkNew)
+	w.raw(`,"player_count":`)
+	w.int(int64(x.PlayerCount))
+	if x.MinLead != 0 {
+		w.raw(`,"min_lead":`)
+		w.int(int64(x.MinLead))
+	}
+	w.raw(`,"player_id":`)
+	w.string(x.PlayerID)
+	w.raw(`,"cube":`)
+	x.Cube.writeJSON(w)
+	w.raw(`,"cube_id":`)
+	w.string(x.CubeID)
+	if len(x.Cubes) != 0 {
+		w.raw(`,"cubes":`)
+		w.raw("[")
+		for i0 := range x.Cubes {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Cubes[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.CubeIDs) != 0 {
+		w.raw(`,"cube_ids":`)
+		w.raw("[")
+		for i0 := range x.CubeIDs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.CubeIDs[i0])
+		}
+		w.raw("]")
+	}
+	if x.TraceID != "" {
+		w.raw(`,"trace_id":`)
+		w.string(x.TraceID)
+	}
+	if x.Reason != "" {
+		w.raw(`,"reason":`)
+		w.string(x.Reason)
+	}
+	if len(x.Handoffs) != 0 {
+		w.raw(`,"handoffs":`)
+		w.raw("[")
+		for i0 := range x.Handoffs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Handoffs[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.Replicas) != 0 {
+		w.raw(`,"replicas":`)
+		w.raw("[")
+		for i0 := range x.Replicas {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.Replicas[i0])
+		}
+		w.raw("]")
+	}
+	if len(x.Members) != 0 {
+		w.raw(`,"members":`)
+		w.raw("[")
+		for i0 := range x.Members {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Members[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.TxID != "" {
+		w.raw(`,"tx_id":`)
+		w.string(x.TxID)
+	}
+	if len(x.Edits) != 0 {
+		w.raw(`,"edits":`)
+		w.raw("[")
+		for i0 := range x.Edits {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Edits[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.Commit {
+		w.raw(`,"commit":`)
+		w.bool(x.Commit)
+	}
+	if x.Since != nil {
+		w.raw(`,"since":`)
+		x.Since.writeJSON(w)
+	}
+	if x.OwnerHint {
+		w.raw(`,"owner_hint":`)
+		w.bool(x.OwnerHint)
+	}
+	if x.BareChunk {
+		w.raw(`,"bare_chunk":`)
+		w.bool(x.BareChunk)
+	}
+	if x.AcceptEncoding != "" {
+		w.raw(`,"accept_encoding":`)
+		w.string(x.AcceptEncoding)
+	}
+	if x.SessionToken != "" {
+		w.raw(`,"session_token":`)
+		w.string(x.SessionToken)
+	}
+	if x.InputSeq != 0 {
+		w.raw(`,"input_seq":`)
+		w.uint(x.InputSeq)
+	}
+	if x.Stats != nil {
+		w.raw(`,"stats":`)
+		x.Stats.writeJSON(w)
+	}
+	if x.Text != "" {
+		w.raw(`,"text":`)
+		w.string(x.Text)
+	}
+	if x.ChatSince != 0 {
+		w.raw(`,"chat_since":`)
+		w.uint(x.ChatSince)
+	}
+	if x.Announcement != nil {
+		w.raw(`,"announcement":`)
+		x.Announcement.writeJSON(w)
+	}
+	if x.Party != nil {
+		w.raw(`,"party":`)
+		x.Party.writeJSON(w)
+	}
+	if x.Match != nil {
+		w.raw(`,"match":`)
+		x.Match.writeJSON(w)
+	}
+	if x.Unsubscribe {
+		w.raw(`,"unsubscribe":`)
+		w.bool(x.Unsubscribe)
+	}
+	if x.Shot != nil {
+		w.raw(`,"shot":`)
+		x.Shot.writeJSON(w)
+	}
+	if x.ItemID != "" {
+		w.raw(`,"item_id":`)
+		w.string(x.ItemID)
+	}
+	if x.Item != nil {
+		w.raw(`,"item":`)
+		x.Item.writeJSON(w)
+	}
+	if x.Amount != 0 {
+		w.raw(`,"amount":`)
+		w.int(int64(x.Amount))
+	}
+	if x.Owner != "" {
+		w.raw(`,"owner":`)
+		w.string(x.Owner)
+	}
+	if x.Digest != nil {
+		w.raw(`,"digest":`)
+		x.Digest.writeJSON(w)
+	}
+	if len(x.Buckets) != 0 {
+		w.raw(`,"buckets":`)
+		w.raw("[")
+		for i0 := range x.Buckets {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.int(int64(x.Buckets[i0]))
+		}
+		w.raw("]")
+	}
+	if x.CallID != 0 {
+		w.raw(`,"call_id":`)
+		w.uint(x.CallID)
+	}
+	if x.Epoch != 0 {
+		w.raw(`,"epoch":`)
+		w.uint(x.Epoch)
+	}
+	w.objectEnd(start)
+}
+
+var requestKeys = []string{"type", "chunk_id", "caller_ip", "player", "is_peer_req", "chunk", "is_chunk_new", "player_count", "min_lead", "player_id", "cube", "cube_id", "cubes", "cube_ids", "trace_id", "reason", "handoffs", "replicas", "members", "tx_id", "edits", "commit", "since", "owner_hint", "bare_chunk", "accept_encoding", "session_token", "input_seq", "stats", "text", "chat_since", "announcement", "party", "match", "unsubscribe", "shot", "item_id", "item", "amount", "owner", "digest", "buckets", "call_id", "epoch"}
+
+func (x *Request) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "type":
+			if !r.null() {
+				x.Type = RequestType(r.string())
+			}
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "caller_ip":
+			if !r.null() {
+				x.CallerIP = r.string()
+			}
+		case "player":
+			x.Player.readJSON(r)
+		case "is_peer_req":
+			if !r.null() {
+				x.IsPeerReq = r.bool()
+			}
+		case "chunk":
+			x.Chunk.readJSON(r)
+		case "is_chunk_new":
+			if !r.null() {
+				x.IsChunkNew = r.bool()
+			}
+		case "player_count":
+			if !r.null() {
+				x.PlayerCount = int(r.int(0))
+			}
+		case "min_lead":
+			if !r.null() {
+				x.MinLead = int(r.int(0))
+			}
+		case "player_id":
+			if !r.null() {
+				x.PlayerID = r.string()
+			}
+		case "cube":
+			x.Cube.readJSON(r)
+		case "cube_id":
+			if !r.null() {
+				x.CubeID = r.string()
+			}
+		case "cubes":
+			if r.null() {
+				x.Cubes = nil
+			} else if r.array() {
+				s0 := x.Cubes[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Cube))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Cube{}
+				}
+				x.Cubes = s0
+			}
+		case "cube_ids":
+			if r.null() {
+				x.CubeIDs = nil
+			} else if r.array() {
+				s0 := x.CubeIDs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.CubeIDs = s0
+			}
+		case "trace_id":
+			if !r.null() {
+				x.TraceID = r.string()
+			}
+		case "reason":
+			if !r.null() {
+				x.Reason = r.string()
+			}
+		case "handoffs":
+			if r.null() {
+				x.Handoffs = nil
+			} else if r.array() {
+				s0 := x.Handoffs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(PlayerHandoff))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []PlayerHandoff{}
+				}
+				x.Handoffs = s0
+			}
+		case "replicas":
+			if r.null() {
+				x.Replicas = nil
+			} else if r.array() {
+				s0 := x.Replicas[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.Replicas = s0
+			}
+		case "members":
+			if r.null() {
+				x.Members = nil
+			} else if r.array() {
+				s0 := x.Members[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(MemberState))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []MemberState{}
+				}
+				x.Members = s0
+			}
+		case "tx_id":
+			if !r.null() {
+				x.TxID = r.string()
+			}
+		case "edits":
+			if r.null() {
+				x.Edits = nil
+			} else if r.array() {
+				s0 := x.Edits[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ChunkEdit))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ChunkEdit{}
+				}
+				x.Edits = s0
+			}
+		case "commit":
+			if !r.null() {
+				x.Commit = r.bool()
+			}
+		case "since":
+			if r.null() {
+				x.Since = nil
+			} else {
+				if x.Since == nil {
+					x.Since = new(ChunkVersion)
+				}
+				x.Since.readJSON(r)
+			}
+		case "owner_hint":
+			if !r.null() {
+				x.OwnerHint = r.bool()
+			}
+		case "bare_chunk":
+			if !r.null() {
+				x.BareChunk = r.bool()
+			}
+		case "accept_encoding":
+			if !r.null() {
+				x.AcceptEncoding = r.string()
+			}
+		case "session_token":
+			if !r.null() {
+				x.SessionToken = r.string()
+			}
+		case "input_seq":
+			if !r.null() {
+				x.InputSeq = r.uint(64)
+			}
+		case "stats":
+			if r.null() {
+				x.Stats = nil
+			} else {
+				if x.Stats == nil {
+					x.Stats = new(ClientStats)
+				}
+				x.Stats.readJSON(r)
+			}
+		case "text":
+			if !r.null() {
+				x.Text = r.string()
+			}
+		case "chat_since":
+			if !r.null() {
+				x.ChatSince = r.uint(64)
+			}
+		case "announcement":
+			if r.null() {
+				x.Announcement = nil
+			} else {
+				if x.Announcement == nil {
+					x.Announcement = new(Announcement)
+				}
+				x.Announcement.readJSON(r)
+			}
+		case "party":
+			if r.null() {
+				x.Party = nil
+			} else {
+				if x.Party == nil {
+					x.Party = new(Party)
+				}
+				x.Party.readJSON(r)
+			}
+		case "match":
+			if r.null() {
+				x.Match = nil
+			} else {
+				if x.Match == nil {
+					x.Match = new(Match)
+				}
+				x.Match.readJSON(r)
+			}
+		case "unsubscribe":
+			if !r.null() {
+				x.Unsubscribe = r.bool()
+			}
+		case "shot":
+			if r.null() {
+				x.Shot = nil
+			} else {
+				if x.Shot == nil {
+					x.Shot = new(Shot)
+				}
+				x.Shot.readJSON(r)
+			}
+		case "item_id":
+			if !r.null() {
+				x.ItemID = r.string()
+			}
+		case "item":
+			if r.null() {
+				x.Item = nil
+			} else {
+				if x.Item == nil {
+					x.Item = new(ItemStack)
+				}
+				x.Item.readJSON(r)
+			}
+		case "amount":
+			if !r.null() {
+				x.Amount = int(r.int(0))
+			}
+		case "owner":
+			if !r.null() {
+				x.Owner = r.string()
+			}
+		case "digest":
+			if r.null() {
+				x.Digest = nil
+			} else {
+				if x.Digest == nil {
+					x.Digest = new(ChunkDigest)
+				}
+				x.Digest.readJSON(r)
+			}
+		case "buckets":
+			if r.null() {
+				x.Buckets = nil
+			} else if r.array() {
+				s0 := x.Buckets[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(int))
+					if !r.null() {
+						s0[len(s0)-1] = int(r.int(0))
+					}
+				}
+				if s0 == nil {
+					s0 = []int{}
+				}
+				x.Buckets = s0
+			}
+		case "call_id":
+			if !r.null() {
+				x.CallID = r.uint(64)
+			}
+		case "epoch":
+			if !r.null() {
+				x.Epoch = r.uint(64)
+			}
+		default:
+			if k, ok := foldKey(key, requestKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Response) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"success":`)
+	w.bool(x.Success)
+	w.raw(`,"chunk":`)
+	x.Chunk.writeJSON(w)
+	w.raw(`,"message":`)
+	w.string(x.Message)
+	w.raw(`,"game_data":`)
+	x.GameData.writeJSON(w)
+	w.raw(`,"new_ip":`)
+	w.string(x.NewIP)
+	w.raw(`,"player_count":`)
+	w.int(int64(x.PlayerCount))
+	if x.ChunkSize != 0 {
+		w.raw(`,"chunk_size":`)
+		w.int(int64(x.ChunkSize))
+	}
+	if x.TraceID != "" {
+		w.raw(`,"trace_id":`)
+		w.string(x.TraceID)
+	}
+	if x.Code != "" {
+		w.raw(`,"code":`)
+		w.string(x.Code)
+	}
+	if x.RedirectIP != "" {
+		w.raw(`,"redirect_ip":`)
+		w.string(x.RedirectIP)
+	}
+	if len(x.Supported) != 0 {
+		w.raw(`,"supported":`)
+		w.raw("[")
+		for i0 := range x.Supported {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(string(x.Supported[i0]))
+		}
+		w.raw("]")
+	}
+	if x.ChunkID != nil {
+		w.raw(`,"chunk_id":`)
+		x.ChunkID.writeJSON(w)
+	}
+	if len(x.Splits) != 0 {
+		w.raw(`,"splits":`)
+		w.raw("[")
+		for i0 := range x.Splits {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Splits[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.QueuePosition != 0 {
+		w.raw(`,"queue_position":`)
+		w.int(int64(x.QueuePosition))
+	}
+	if x.RetryAfterMs != 0 {
+		w.raw(`,"retry_after_ms":`)
+		w.int(x.RetryAfterMs)
+	}
+	if x.Alternative != nil {
+		w.raw(`,"alternative":`)
+		x.Alternative.writeJSON(w)
+	}
+	if len(x.Replicas) != 0 {
+		w.raw(`,"replicas":`)
+		w.raw("[")
+		for i0 := range x.Replicas {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.Replicas[i0])
+		}
+		w.raw("]")
+	}
+	if len(x.Members) != 0 {
+		w.raw(`,"members":`)
+		w.raw("[")
+		for i0 := range x.Members {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Members[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.TxID != "" {
+		w.raw(`,"tx_id":`)
+		w.string(x.TxID)
+	}
+	if x.SessionToken != "" {
+		w.raw(`,"session_token":`)
+		w.string(x.SessionToken)
+	}
+	if x.Seal != nil {
+		w.raw(`,"seal":`)
+		x.Seal.writeJSON(w)
+	}
+	if x.Player != nil {
+		w.raw(`,"player":`)
+		x.Player.writeJSON(w)
+	}
+	if x.Digest != nil {
+		w.raw(`,"digest":`)
+		x.Digest.writeJSON(w)
+	}
+	if x.AckSeq != 0 {
+		w.raw(`,"ack_seq":`)
+		w.uint(x.AckSeq)
+	}
+	if len(x.Chat) != 0 {
+		w.raw(`,"chat":`)
+		w.raw("[")
+		for i0 := range x.Chat {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Chat[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.Pushes) != 0 {
+		w.raw(`,"pushes":`)
+		w.raw("[")
+		for i0 := range x.Pushes {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Pushes[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.Announcement != nil {
+		w.raw(`,"announcement":`)
+		x.Announcement.writeJSON(w)
+	}
+	if x.Profile != nil {
+		w.raw(`,"profile":`)
+		x.Profile.writeJSON(w)
+	}
+	if x.Party != nil {
+		w.raw(`,"party":`)
+		x.Party.writeJSON(w)
+	}
+	if len(x.PartyMembers) != 0 {
+		w.raw(`,"party_members":`)
+		w.raw("[")
+		for i0 := range x.PartyMembers {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.PartyMembers[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.Match != nil {
+		w.raw(`,"match":`)
+		x.Match.writeJSON(w)
+	}
+	if x.ProjectileID != "" {
+		w.raw(`,"projectile_id":`)
+		w.string(x.ProjectileID)
+	}
+	if x.Hit != nil {
+		w.raw(`,"hit":`)
+		x.Hit.writeJSON(w)
+	}
+	if x.Life != nil {
+		w.raw(`,"life":`)
+		x.Life.writeJSON(w)
+	}
+	if len(x.Awards) != 0 {
+		w.raw(`,"awards":`)
+		w.raw("[")
+		for i0 := range x.Awards {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Awards[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.Worlds) != 0 {
+		w.raw(`,"worlds":`)
+		w.raw("[")
+		for i0 := range x.Worlds {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Worlds[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.ItemID != "" {
+		w.raw(`,"item_id":`)
+		w.string(x.ItemID)
+	}
+	if x.Version != nil {
+		w.raw(`,"version":`)
+		x.Version.writeJSON(w)
+	}
+	if x.Delta != nil {
+		w.raw(`,"delta":`)
+		x.Delta.writeJSON(w)
+	}
+	if x.UpdateMs != 0 {
+		w.raw(`,"update_ms":`)
+		w.int(x.UpdateMs)
+	}
+	if x.ServerTimeMs != 0 {
+		w.raw(`,"server_time_ms":`)
+		w.int(x.ServerTimeMs)
+	}
+	if x.Tick != 0 {
+		w.raw(`,"tick":`)
+		w.int(x.Tick)
+	}
+	if x.TickMs != 0 {
+		w.raw(`,"tick_ms":`)
+		w.int(int64(x.TickMs))
+	}
+	if x.Encoding != "" {
+		w.raw(`,"encoding":`)
+		w.string(x.Encoding)
+	}
+	if len(x.Payload) != 0 {
+		w.raw(`,"payload":`)
+		w.bytes([]byte(x.Payload))
+	}
+	if x.CallID != 0 {
+		w.raw(`,"call_id":`)
+		w.uint(x.CallID)
+	}
+	if x.Epoch != 0 {
+		w.raw(`,"epoch":`)
+		w.uint(x.Epoch)
+	}
+	if x.LeaseMs != 0 {
+		w.raw(`,"lease_ms":`)
+		w.int(x.LeaseMs)
+	}
+	w.objectEnd(start)
+}
+
+var responseKeys = []string{"success", "chunk", "message", "game_data", "new_ip", "player_count", "chunk_size", "trace_id", "code", "redirect_ip", "supported", "chunk_id", "splits", "queue_position", "retry_after_ms", "alternative", "replicas", "members", "tx_id", "session_token", "seal", "player", "digest", "ack_seq", "chat", "pushes", "announcement", "profile", "party", "party_members", "match", "projectile_id", "hit", "life", "awards", "worlds", "item_id", "version", "delta", "update_ms", "server_time_ms", "tick", "tick_ms", "encoding", "payload", "call_id", "epoch", "lease_ms"}
+
+func (x *Response) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "success":
+			if !r.null() {
+				x.Success = r.bool()
+			}
+		case "chunk":
+			x.Chunk.readJSON(r)
+		case "message":
+			if !r.null() {
+				x.Message = r.string()
+			}
+		case "game_data":
+			x.GameData.readJSON(r)
+		case "new_ip":
+			if !r.null() {
+				x.NewIP = r.string()
+			}
+		case "player_count":
+			if !r.null() {
+				x.PlayerCount = int(r.int(0))
+			}
+		case "chunk_size":
+			if !r.null() {
+				x.ChunkSize = int(r.int(0))
+			}
+		case "trace_id":
+			if !r.null() {
+				x.TraceID = r.string()
+			}
+		case "code":
+			if !r.null() {
+				x.Code = r.string()
+			}
+		case "redirect_ip":
+			if !r.null() {
+				x.RedirectIP = r.string()
+			}
+		case "supported":
+			if r.null() {
+				x.Supported = nil
+			} else if r.array() {
+				s0 := x.Supported[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(RequestType))
+					if !r.null() {
+						s0[len(s0)-1] = RequestType(r.string())
+					}
+				}
+				if s0 == nil {
+					s0 = []RequestType{}
+				}
+				x.Supported = s0
+			}
+		case "chunk_id":
+			if r.null() {
+				x.ChunkID = nil
+			} else {
+				if x.ChunkID == nil {
+					x.ChunkID = new(ChunkID)
+				}
+				x.ChunkID.readJSON(r)
+			}
+		case "splits":
+			if r.null() {
+				x.Splits = nil
+			} else if r.array() {
+				s0 := x.Splits[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ChunkID))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ChunkID{}
+				}
+				x.Splits = s0
+			}
+		case "queue_position":
+			if !r.null() {
+				x.QueuePosition = int(r.int(0))
+			}
+		case "retry_after_ms":
+			if !r.null() {
+				x.RetryAfterMs = r.int(64)
+			}
+		case "alternative":
+			if r.null() {
+				x.Alternative = nil
+			} else {
+				if x.Alternative == nil {
+					x.Alternative = new(ChunkID)
+				}
+				x.Alternative.readJSON(r)
+			}
+		case "replicas":
+			if r.null() {
+				x.Replicas = nil
+			} else if r.array() {
+				s0 := x.Replicas[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.Replicas = s0
+			}
+		case "members":
+			if r.null() {
+				x.Members = nil
+			} else if r.array() {
+				s0 := x.Members[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(MemberState))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []MemberState{}
+				}
+				x.Members = s0
+			}
+		case "tx_id":
+			if !r.null() {
+				x.TxID = r.string()
+			}
+		case "session_token":
+			if !r.null() {
+				x.SessionToken = r.string()
+			}
+		case "seal":
+			if r.null() {
+				x.Seal = nil
+			} else {
+				if x.Seal == nil {
+					x.Seal = new(SessionSeal)
+				}
+				x.Seal.readJSON(r)
+			}
+		case "player":
+			if r.null() {
+				x.Player = nil
+			} else {
+				if x.Player == nil {
+					x.Player = new(Player)
+				}
+				x.Player.readJSON(r)
+			}
+		case "digest":
+			if r.null() {
+				x.Digest = nil
+			} else {
+				if x.Digest == nil {
+					x.Digest = new(ChunkDigest)
+				}
+				x.Digest.readJSON(r)
+			}
+		case "ack_seq":
+			if !r.null() {
+				x.AckSeq = r.uint(64)
+			}
+		case "chat":
+			if r.null() {
+				x.Chat = nil
+			} else if r.array() {
+				s0 := x.Chat[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ChatMessage))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ChatMessage{}
+				}
+				x.Chat = s0
+			}
+		case "pushes":
+			if r.null() {
+				x.Pushes = nil
+			} else if r.array() {
+				s0 := x.Pushes[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Response))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Response{}
+				}
+				x.Pushes = s0
+			}
+		case "announcement":
+			if r.null() {
+				x.Announcement = nil
+			} else {
+				if x.Announcement == nil {
+					x.Announcement = new(Announcement)
+				}
+				x.Announcement.readJSON(r)
+			}
+		case "profile":
+			if r.null() {
+				x.Profile = nil
+			} else {
+				if x.Profile == nil {
+					x.Profile = new(PlayerProfile)
+				}
+				x.Profile.readJSON(r)
+			}
+		case "party":
+			if r.null() {
+				x.Party = nil
+			} else {
+				if x.Party == nil {
+					x.Party = new(Party)
+				}
+				x.Party.readJSON(r)
+			}
+		case "party_members":
+			if r.null() {
+				x.PartyMembers = nil
+			} else if r.array() {
+				s0 := x.PartyMembers[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Player))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Player{}
+				}
+				x.PartyMembers = s0
+			}
+		case "match":
+			if r.null() {
+				x.Match = nil
+			} else {
+				if x.Match == nil {
+					x.Match = new(Match)
+				}
+				x.Match.readJSON(r)
+			}
+		case "projectile_id":
+			if !r.null() {
+				x.ProjectileID = r.string()
+			}
+		case "hit":
+			if r.null() {
+				x.Hit = nil
+			} else {
+				if x.Hit == nil {
+					x.Hit = new(HitEvent)
+				}
+				x.Hit.readJSON(r)
+			}
+		case "life":
+			if r.null() {
+				x.Life = nil
+			} else {
+				if x.Life == nil {
+					x.Life = new(LifeEvent)
+				}
+				x.Life.readJSON(r)
+			}
+		case "awards":
+			if r.null() {
+				x.Awards = nil
+			} else if r.array() {
+				s0 := x.Awards[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Award))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Award{}
+				}
+				x.Awards = s0
+			}
+		case "worlds":
+			if r.null() {
+				x.Worlds = nil
+			} else if r.array() {
+				s0 := x.Worlds[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(World))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []World{}
+				}
+				x.Worlds = s0
+			}
+		case "item_id":
+			if !r.null() {
+				x.ItemID = r.string()
+			}
+		case "version":
+			if r.null() {
+				x.Version = nil
+			} else {
+				if x.Version == nil {
+					x.Version = new(ChunkVersion)
+				}
+				x.Version.readJSON(r)
+			}
+		case "delta":
+			if r.null() {
+				x.Delta = nil
+			} else {
+				if x.Delta == nil {
+					x.Delta = new(ChunkDelta)
+				}
+				x.Delta.readJSON(r)
+			}
+		case "update_ms":
+			if !r.null() {
+				x.UpdateMs = r.int(64)
+			}
+		case "server_time_ms":
+			if !r.null() {
+				x.ServerTimeMs = r.int(64)
+			}
+		case "tick":
+			if !r.null() {
+				x.Tick = r.int(64)
+			}
+		case "tick_ms":
+			if !r.null() {
+				x.TickMs = int(r.int(0))
+			}
+		case "encoding":
+			if !r.null() {
+				x.Encoding = r.string()
+			}
+		case "payload":
+			if r.null() {
+				x.Payload = nil
+			} else {
+				x.Payload = []uint8(r.bytes())
+			}
+		case "call_id":
+			if !r.null() {
+				x.CallID = r.uint(64)
+			}
+		case "epoch":
+			if !r.null() {
+				x.Epoch = r.uint(64)
+			}
+		case "lease_ms":
+			if !r.null() {
+				x.LeaseMs = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, responseKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Chunk) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	if x.World != "" {
+		w.raw(`,"world":`)
+		w.string(x.World)
+	}
+	w.raw(`,"id_x":`)
+	w.int(int64(x.IDX))
+	w.raw(`,"id_y":`)
+	w.int(int64(x.IDY))
+	if x.Level != 0 {
+		w.raw(`,"level":`)
+		w.int(int64(x.Level))
+	}
+	w.raw(`,"server_ip":`)
+	w.string(x.ServerIP)
+	w.raw(`,"data":`)
+	w.string(x.Data)
+	w.raw(`,"player_list":`)
+	if x.PlayerList == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.PlayerList {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.PlayerList[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	w.raw(`,"is_dirty":`)
+	w.bool(x.IsDirty)
+	w.raw(`,"cells":`)
+	if x.Cells == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.Cells {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Cells[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.NPCs) != 0 {
+		w.raw(`,"npcs":`)
+		w.raw("[")
+		for i0 := range x.NPCs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.NPCs[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.Items) != 0 {
+		w.raw(`,"items":`)
+		w.raw("[")
+		for i0 := range x.Items {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Items[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.Tombstones) != 0 {
+		w.raw(`,"tombstones":`)
+		w.raw("[")
+		for i0 := range x.Tombstones {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Tombstones[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.TombstoneFloor != 0 {
+		w.raw(`,"tombstone_floor":`)
+		w.uint(uint64(x.TombstoneFloor))
+	}
+	w.objectEnd(start)
+}
+
+var chunkKeys = []string{"world", "id_x", "id_y", "level", "server_ip", "data", "player_list", "is_dirty", "cells", "npcs", "items", "tombstones", "tombstone_floor"}
+
+func (x *Chunk) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "world":
+			if !r.null() {
+				x.World = r.string()
+			}
+		case "id_x":
+			if !r.null() {
+				x.IDX = int(r.int(0))
+			}
+		case "id_y":
+			if !r.null() {
+				x.IDY = int(r.int(0))
+			}
+		case "level":
+			if !r.null() {
+				x.Level = int(r.int(0))
+			}
+		case "server_ip":
+			if !r.null() {
+				x.ServerIP = r.string()
+			}
+		case "data":
+			if !r.null() {
+				x.Data = r.string()
+			}
+		case "player_list":
+			if r.null() {
+				x.PlayerList = nil
+			} else if r.array() {
+				s0 := x.PlayerList[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Player))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Player{}
+				}
+				x.PlayerList = s0
+			}
+		case "is_dirty":
+			if !r.null() {
+				x.IsDirty = r.bool()
+			}
+		case "cells":
+			if r.null() {
+				x.Cells = nil
+			} else if r.array() {
+				s0 := x.Cells[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Cube))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Cube{}
+				}
+				x.Cells = s0
+			}
+		case "npcs":
+			if r.null() {
+				x.NPCs = nil
+			} else if r.array() {
+				s0 := x.NPCs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(NPC))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []NPC{}
+				}
+				x.NPCs = s0
+			}
+		case "items":
+			if r.null() {
+				x.Items = nil
+			} else if r.array() {
+				s0 := x.Items[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Item))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Item{}
+				}
+				x.Items = s0
+			}
+		case "tombstones":
+			if r.null() {
+				x.Tombstones = nil
+			} else if r.array() {
+				s0 := x.Tombstones[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Tombstone))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Tombstone{}
+				}
+				x.Tombstones = s0
+			}
+		case "tombstone_floor":
+			if !r.null() {
+				x.TombstoneFloor = HLC(r.uint(64))
+			}
+		default:
+			if k, ok := foldKey(key, chunkKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChunkID) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	if x.World != "" {
+		w.raw(`,"world":`)
+		w.string(x.World)
+	}
+	w.raw(`,"id_x":`)
+	w.int(int64(x.IDX))
+	w.raw(`,"id_y":`)
+	w.int(int64(x.IDY))
+	if x.Level != 0 {
+		w.raw(`,"level":`)
+		w.int(int64(x.Level))
+	}
+	w.objectEnd(start)
+}
+
+var chunkIDKeys = []string{"world", "id_x", "id_y", "level"}
+
+func (x *ChunkID) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "world":
+			if !r.null() {
+				x.World = r.string()
+			}
+		case "id_x":
+			if !r.null() {
+				x.IDX = int(r.int(0))
+			}
+		case "id_y":
+			if !r.null() {
+				x.IDY = int(r.int(0))
+			}
+		case "level":
+			if !r.null() {
+				x.Level = int(r.int(0))
+			}
+		default:
+			if k, ok := foldKey(key, chunkIDKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Player) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"posx":`)
+	w.int(int64(x.PosX))
+	w.raw(`,"posy":`)
+	w.int(int64(x.PosY))
+	w.raw(`,"server_ip":`)
+	w.string(x.ServerIP)
+	w.raw(`,"aoi_radius":`)
+	w.int(int64(x.AOIRadius))
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	if x.World != "" {
+		w.raw(`,"world":`)
+		w.string(x.World)
+	}
+	if x.VelX != 0 {
+		w.raw(`,"vel_x":`)
+		w.float(x.VelX, 64)
+	}
+	if x.VelY != 0 {
+		w.raw(`,"vel_y":`)
+		w.float(x.VelY, 64)
+	}
+	if x.Heading != 0 {
+		w.raw(`,"heading":`)
+		w.float(x.Heading, 64)
+	}
+	if x.UpdatedMs != 0 {
+		w.raw(`,"updated_ms":`)
+		w.int(x.UpdatedMs)
+	}
+	if x.Health != 0 {
+		w.raw(`,"health":`)
+		w.int(int64(x.Health))
+	}
+	if x.RespawnMs != 0 {
+		w.raw(`,"respawn_ms":`)
+		w.int(x.RespawnMs)
+	}
+	if len(x.Inventory) != 0 {
+		w.raw(`,"inventory":`)
+		w.raw("[")
+		for i0 := range x.Inventory {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Inventory[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if x.HLC != 0 {
+		w.raw(`,"hlc":`)
+		w.uint(uint64(x.HLC))
+	}
+	w.objectEnd(start)
+}
+
+var playerKeys = []string{"id", "posx", "posy", "server_ip", "aoi_radius", "chunk_id", "world", "vel_x", "vel_y", "heading", "updated_ms", "health", "respawn_ms", "inventory", "hlc"}
+
+func (x *Player) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "posx":
+			if !r.null() {
+				x.PosX = int(r.int(0))
+			}
+		case "posy":
+			if !r.null() {
+				x.PosY = int(r.int(0))
+			}
+		case "server_ip":
+			if !r.null() {
+				x.ServerIP = r.string()
+			}
+		case "aoi_radius":
+			if !r.null() {
+				x.AOIRadius = int(r.int(0))
+			}
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "world":
+			if !r.null() {
+				x.World = r.string()
+			}
+		case "vel_x":
+			if !r.null() {
+				x.VelX = r.float(64)
+			}
+		case "vel_y":
+			if !r.null() {
+				x.VelY = r.float(64)
+			}
+		case "heading":
+			if !r.null() {
+				x.Heading = r.float(64)
+			}
+		case "updated_ms":
+			if !r.null() {
+				x.UpdatedMs = r.int(64)
+			}
+		case "health":
+			if !r.null() {
+				x.Health = int(r.int(0))
+			}
+		case "respawn_ms":
+			if !r.null() {
+				x.RespawnMs = r.int(64)
+			}
+		case "inventory":
+			if r.null() {
+				x.Inventory = nil
+			} else if r.array() {
+				s0 := x.Inventory[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ItemStack))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ItemStack{}
+				}
+				x.Inventory = s0
+			}
+		case "hlc":
+			if !r.null() {
+				x.HLC = HLC(r.uint(64))
+			}
+		default:
+			if k, ok := foldKey(key, playerKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Cube) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"cube_id":`)
+	w.string(x.ID)
+	w.raw(`,"x":`)
+	w.int(int64(x.X))
+	w.raw(`,"z":`)
+	w.int(int64(x.Z))
+	w.raw(`,"height":`)
+	w.int(int64(x.Height))
+	w.raw(`,"color":`)
+	w.string(x.Color)
+	if x.HLC != 0 {
+		w.raw(`,"hlc":`)
+		w.uint(uint64(x.HLC))
+	}
+	w.objectEnd(start)
+}
+
+var cubeKeys = []string{"cube_id", "x", "z", "height", "color", "hlc"}
+
+func (x *Cube) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "cube_id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "x":
+			if !r.null() {
+				x.X = int(r.int(0))
+			}
+		case "z":
+			if !r.null() {
+				x.Z = int(r.int(0))
+			}
+		case "height":
+			if !r.null() {
+				x.Height = int(r.int(0))
+			}
+		case "color":
+			if !r.null() {
+				x.Color = r.string()
+			}
+		case "hlc":
+			if !r.null() {
+				x.HLC = HLC(r.uint(64))
+			}
+		default:
+			if k, ok := foldKey(key, cubeKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *PlayerHandoff) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"player":`)
+	x.Player.writeJSON(w)
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	w.raw(`,"last_seen":`)
+	w.value(x.LastSeen)
+	if x.Addr != "" {
+		w.raw(`,"addr":`)
+		w.string(x.Addr)
+	}
+	w.objectEnd(start)
+}
+
+var playerHandoffKeys = []string{"player", "chunk_id", "last_seen", "addr"}
+
+func (x *PlayerHandoff) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "player":
+			x.Player.readJSON(r)
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "last_seen":
+			r.value(&x.LastSeen)
+		case "addr":
+			if !r.null() {
+				x.Addr = r.string()
+			}
+		default:
+			if k, ok := foldKey(key, playerHandoffKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *MemberState) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"addr":`)
+	w.string(x.Addr)
+	w.raw(`,"heartbeat":`)
+	w.uint(x.Heartbeat)
+	w.raw(`,"players":`)
+	w.int(int64(x.Players))
+	if len(x.Chunks) != 0 {
+		w.raw(`,"chunks":`)
+		w.raw("[")
+		for i0 := range x.Chunks {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Chunks[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.PartyPlayers) != 0 {
+		w.raw(`,"party_players":`)
+		w.raw("[")
+		for i0 := range x.PartyPlayers {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.PartyPlayers[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	w.objectEnd(start)
+}
+
+var memberStateKeys = []string{"addr", "heartbeat", "players", "chunks", "party_players"}
+
+func (x *MemberState) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "addr":
+			if !r.null() {
+				x.Addr = r.string()
+			}
+		case "heartbeat":
+			if !r.null() {
+				x.Heartbeat = r.uint(64)
+			}
+		case "players":
+			if !r.null() {
+				x.Players = int(r.int(0))
+			}
+		case "chunks":
+			if r.null() {
+				x.Chunks = nil
+			} else if r.array() {
+				s0 := x.Chunks[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ChunkID))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ChunkID{}
+				}
+				x.Chunks = s0
+			}
+		case "party_players":
+			if r.null() {
+				x.PartyPlayers = nil
+			} else if r.array() {
+				s0 := x.PartyPlayers[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Player))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Player{}
+				}
+				x.PartyPlayers = s0
+			}
+		default:
+			if k, ok := foldKey(key, memberStateKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChunkEdit) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	if len(x.Cubes) != 0 {
+		w.raw(`,"cubes":`)
+		w.raw("[")
+		for i0 := range x.Cubes {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Cubes[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.CubeIDs) != 0 {
+		w.raw(`,"cube_ids":`)
+		w.raw("[")
+		for i0 := range x.CubeIDs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.CubeIDs[i0])
+		}
+		w.raw("]")
+	}
+	w.objectEnd(start)
+}
+
+var chunkEditKeys = []string{"chunk_id", "cubes", "cube_ids"}
+
+func (x *ChunkEdit) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "cubes":
+			if r.null() {
+				x.Cubes = nil
+			} else if r.array() {
+				s0 := x.Cubes[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Cube))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Cube{}
+				}
+				x.Cubes = s0
+			}
+		case "cube_ids":
+			if r.null() {
+				x.CubeIDs = nil
+			} else if r.array() {
+				s0 := x.CubeIDs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.CubeIDs = s0
+			}
+		default:
+			if k, ok := foldKey(key, chunkEditKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChunkVersion) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"epoch":`)
+	w.uint(x.Epoch)
+	w.raw(`,"seq":`)
+	w.uint(x.Seq)
+	w.objectEnd(start)
+}
+
+var chunkVersionKeys = []string{"epoch", "seq"}
+
+func (x *ChunkVersion) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "epoch":
+			if !r.null() {
+				x.Epoch = r.uint(64)
+			}
+		case "seq":
+			if !r.null() {
+				x.Seq = r.uint(64)
+			}
+		default:
+			if k, ok := foldKey(key, chunkVersionKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ClientStats) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"requests":`)
+	w.int(x.Requests)
+	w.raw(`,"replies":`)
+	w.int(x.Replies)
+	w.raw(`,"lost":`)
+	w.int(x.Lost)
+	w.raw(`,"redirects":`)
+	w.int(x.Redirects)
+	w.raw(`,"loss_rate":`)
+	w.float(x.LossRate, 64)
+	w.raw(`,"rtt_last_ms":`)
+	w.float(x.RTTLastMs, 64)
+	w.raw(`,"rtt_avg_ms":`)
+	w.float(x.RTTAvgMs, 64)
+	w.raw(`,"rtt_min_ms":`)
+	w.float(x.RTTMinMs, 64)
+	w.raw(`,"rtt_max_ms":`)
+	w.float(x.RTTMaxMs, 64)
+	w.objectEnd(start)
+}
+
+var clientStatsKeys = []string{"requests", "replies", "lost", "redirects", "loss_rate", "rtt_last_ms", "rtt_avg_ms", "rtt_min_ms", "rtt_max_ms"}
+
+func (x *ClientStats) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "requests":
+			if !r.null() {
+				x.Requests = r.int(64)
+			}
+		case "replies":
+			if !r.null() {
+				x.Replies = r.int(64)
+			}
+		case "lost":
+			if !r.null() {
+				x.Lost = r.int(64)
+			}
+		case "redirects":
+			if !r.null() {
+				x.Redirects = r.int(64)
+			}
+		case "loss_rate":
+			if !r.null() {
+				x.LossRate = r.float(64)
+			}
+		case "rtt_last_ms":
+			if !r.null() {
+				x.RTTLastMs = r.float(64)
+			}
+		case "rtt_avg_ms":
+			if !r.null() {
+				x.RTTAvgMs = r.float(64)
+			}
+		case "rtt_min_ms":
+			if !r.null() {
+				x.RTTMinMs = r.float(64)
+			}
+		case "rtt_max_ms":
+			if !r.null() {
+				x.RTTMaxMs = r.float(64)
+			}
+		default:
+			if k, ok := foldKey(key, clientStatsKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Announcement) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"text":`)
+	w.string(x.Text)
+	w.raw(`,"sent_ms":`)
+	w.int(x.SentMs)
+	w.objectEnd(start)
+}
+
+var announcementKeys = []string{"id", "kind", "text", "sent_ms"}
+
+func (x *Announcement) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "text":
+			if !r.null() {
+				x.Text = r.string()
+			}
+		case "sent_ms":
+			if !r.null() {
+				x.SentMs = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, announcementKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Party) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"leader":`)
+	w.string(x.Leader)
+	w.raw(`,"members":`)
+	if x.Members == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.Members {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.Members[i0])
+		}
+		w.raw("]")
+	}
+	w.objectEnd(start)
+}
+
+var partyKeys = []string{"id", "leader", "members"}
+
+func (x *Party) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "leader":
+			if !r.null() {
+				x.Leader = r.string()
+			}
+		case "members":
+			if r.null() {
+				x.Members = nil
+			} else if r.array() {
+				s0 := x.Members[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.Members = s0
+			}
+		default:
+			if k, ok := foldKey(key, partyKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Match) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"mode":`)
+	w.string(x.Mode)
+	w.raw(`,"teams":`)
+	if x.Teams == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.Teams {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			if x.Teams[i0] == nil {
+				w.raw("null")
+			} else {
+				w.raw("[")
+				for i1 := range x.Teams[i0] {
+					if i1 > 0 {
+						w.raw(",")
+					}
+					w.string(x.Teams[i0][i1])
+				}
+				w.raw("]")
+			}
+		}
+		w.raw("]")
+	}
+	w.raw(`,"server":`)
+	w.string(x.Server)
+	w.raw(`,"world":`)
+	w.string(x.World)
+	w.raw(`,"chunks":`)
+	if x.Chunks == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.Chunks {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Chunks[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	w.raw(`,"spawns":`)
+	if x.Spawns == nil {
+		w.raw("null")
+	} else {
+		w.raw("[")
+		for i0 := range x.Spawns {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Spawns[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	w.raw(`,"formed_ms":`)
+	w.int(x.FormedMs)
+	w.objectEnd(start)
+}
+
+var matchKeys = []string{"id", "mode", "teams", "server", "world", "chunks", "spawns", "formed_ms"}
+
+func (x *Match) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "mode":
+			if !r.null() {
+				x.Mode = r.string()
+			}
+		case "teams":
+			if r.null() {
+				x.Teams = nil
+			} else if r.array() {
+				s0 := x.Teams[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new([]string))
+					if r.null() {
+						s0[len(s0)-1] = nil
+					} else if r.array() {
+						s1 := s0[len(s0)-1][:0]
+						for i1 := 0; r.more(']', i1); i1++ {
+							s1 = append(s1, *new(string))
+							if !r.null() {
+								s1[len(s1)-1] = r.string()
+							}
+						}
+						if s1 == nil {
+							s1 = []string{}
+						}
+						s0[len(s0)-1] = s1
+					}
+				}
+				if s0 == nil {
+					s0 = [][]string{}
+				}
+				x.Teams = s0
+			}
+		case "server":
+			if !r.null() {
+				x.Server = r.string()
+			}
+		case "world":
+			if !r.null() {
+				x.World = r.string()
+			}
+		case "chunks":
+			if r.null() {
+				x.Chunks = nil
+			} else if r.array() {
+				s0 := x.Chunks[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(ChunkID))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []ChunkID{}
+				}
+				x.Chunks = s0
+			}
+		case "spawns":
+			if r.null() {
+				x.Spawns = nil
+			} else if r.array() {
+				s0 := x.Spawns[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(SpawnPoint))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []SpawnPoint{}
+				}
+				x.Spawns = s0
+			}
+		case "formed_ms":
+			if !r.null() {
+				x.FormedMs = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, matchKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Shot) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"dir_x":`)
+	w.float(x.DirX, 64)
+	w.raw(`,"dir_y":`)
+	w.float(x.DirY, 64)
+	if x.SeenMs != 0 {
+		w.raw(`,"seen_ms":`)
+		w.int(x.SeenMs)
+	}
+	w.objectEnd(start)
+}
+
+var shotKeys = []string{"dir_x", "dir_y", "seen_ms"}
+
+func (x *Shot) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "dir_x":
+			if !r.null() {
+				x.DirX = r.float(64)
+			}
+		case "dir_y":
+			if !r.null() {
+				x.DirY = r.float(64)
+			}
+		case "seen_ms":
+			if !r.null() {
+				x.SeenMs = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, shotKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ItemStack) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"count":`)
+	w.int(int64(x.Count))
+	w.objectEnd(start)
+}
+
+var itemStackKeys = []string{"kind", "count"}
+
+func (x *ItemStack) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "count":
+			if !r.null() {
+				x.Count = int(r.int(0))
+			}
+		default:
+			if k, ok := foldKey(key, itemStackKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChunkDigest) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"root":`)
+	w.uint(x.Root)
+	if x.Head != 0 {
+		w.raw(`,"head":`)
+		w.uint(x.Head)
+	}
+	if len(x.Buckets) != 0 {
+		w.raw(`,"buckets":`)
+		w.raw("[")
+		for i0 := range x.Buckets {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.uint(x.Buckets[i0])
+		}
+		w.raw("]")
+	}
+	w.objectEnd(start)
+}
+
+var chunkDigestKeys = []string{"root", "head", "buckets"}
+
+func (x *ChunkDigest) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "root":
+			if !r.null() {
+				x.Root = r.uint(64)
+			}
+		case "head":
+			if !r.null() {
+				x.Head = r.uint(64)
+			}
+		case "buckets":
+			if r.null() {
+				x.Buckets = nil
+			} else if r.array() {
+				s0 := x.Buckets[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(uint64))
+					if !r.null() {
+						s0[len(s0)-1] = r.uint(64)
+					}
+				}
+				if s0 == nil {
+					s0 = []uint64{}
+				}
+				x.Buckets = s0
+			}
+		default:
+			if k, ok := foldKey(key, chunkDigestKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *GameData) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"chunk":`)
+	x.Chunk.writeJSON(w)
+	w.objectEnd(start)
+}
+
+var gameDataKeys = []string{"chunk"}
+
+func (x *GameData) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "chunk":
+			x.Chunk.readJSON(r)
+		default:
+			if k, ok := foldKey(key, gameDataKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *SessionSeal) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.uint(x.ID)
+	w.raw(`,"expires":`)
+	w.int(x.Expires)
+	w.raw(`,"key":`)
+	w.bytes([]byte(x.Key))
+	w.objectEnd(start)
+}
+
+var sessionSealKeys = []string{"id", "expires", "key"}
+
+func (x *SessionSeal) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.uint(64)
+			}
+		case "expires":
+			if !r.null() {
+				x.Expires = r.int(64)
+			}
+		case "key":
+			if r.null() {
+				x.Key = nil
+			} else {
+				x.Key = []uint8(r.bytes())
+			}
+		default:
+			if k, ok := foldKey(key, sessionSealKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChatMessage) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"seq":`)
+	w.uint(x.Seq)
+	w.raw(`,"from":`)
+	w.string(x.From)
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	w.raw(`,"text":`)
+	w.string(x.Text)
+	w.raw(`,"sent_ms":`)
+	w.int(x.SentMs)
+	if x.To != "" {
+		w.raw(`,"to":`)
+		w.string(x.To)
+	}
+	w.objectEnd(start)
+}
+
+var chatMessageKeys = []string{"seq", "from", "chunk_id", "text", "sent_ms", "to"}
+
+func (x *ChatMessage) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "seq":
+			if !r.null() {
+				x.Seq = r.uint(64)
+			}
+		case "from":
+			if !r.null() {
+				x.From = r.string()
+			}
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "text":
+			if !r.null() {
+				x.Text = r.string()
+			}
+		case "sent_ms":
+			if !r.null() {
+				x.SentMs = r.int(64)
+			}
+		case "to":
+			if !r.null() {
+				x.To = r.string()
+			}
+		default:
+			if k, ok := foldKey(key, chatMessageKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *PlayerProfile) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"player_id":`)
+	w.string(x.PlayerID)
+	w.raw(`,"player":`)
+	x.Player.writeJSON(w)
+	w.raw(`,"sessions":`)
+	w.int(int64(x.Sessions))
+	w.raw(`,"play_seconds":`)
+	w.int(x.PlaySeconds)
+	if x.LastServer != "" {
+		w.raw(`,"last_server":`)
+		w.string(x.LastServer)
+	}
+	w.raw(`,"saved_at":`)
+	w.value(x.SavedAt)
+	w.raw(`,"joined_at":`)
+	w.value(x.JoinedAt)
+	w.raw(`,"stats":`)
+	x.Stats.writeJSON(w)
+	if len(x.Achievements) != 0 {
+		w.raw(`,"achievements":`)
+		w.value(x.Achievements)
+	}
+	w.objectEnd(start)
+}
+
+var playerProfileKeys = []string{"player_id", "player", "sessions", "play_seconds", "last_server", "saved_at", "joined_at", "stats", "achievements"}
+
+func (x *PlayerProfile) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "player_id":
+			if !r.null() {
+				x.PlayerID = r.string()
+			}
+		case "player":
+			x.Player.readJSON(r)
+		case "sessions":
+			if !r.null() {
+				x.Sessions = int(r.int(0))
+			}
+		case "play_seconds":
+			if !r.null() {
+				x.PlaySeconds = r.int(64)
+			}
+		case "last_server":
+			if !r.null() {
+				x.LastServer = r.string()
+			}
+		case "saved_at":
+			r.value(&x.SavedAt)
+		case "joined_at":
+			r.value(&x.JoinedAt)
+		case "stats":
+			x.Stats.readJSON(r)
+		case "achievements":
+			r.value(&x.Achievements)
+		default:
+			if k, ok := foldKey(key, playerProfileKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *HitEvent) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"projectile_id":`)
+	w.string(x.ProjectileID)
+	w.raw(`,"shooter_id":`)
+	w.string(x.ShooterID)
+	w.raw(`,"target_id":`)
+	w.string(x.TargetID)
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	w.raw(`,"x":`)
+	w.float(x.X, 64)
+	w.raw(`,"y":`)
+	w.float(x.Y, 64)
+	w.raw(`,"damage":`)
+	w.int(int64(x.Damage))
+	w.raw(`,"health":`)
+	w.int(int64(x.Health))
+	w.objectEnd(start)
+}
+
+var hitEventKeys = []string{"projectile_id", "shooter_id", "target_id", "chunk_id", "x", "y", "damage", "health"}
+
+func (x *HitEvent) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "projectile_id":
+			if !r.null() {
+				x.ProjectileID = r.string()
+			}
+		case "shooter_id":
+			if !r.null() {
+				x.ShooterID = r.string()
+			}
+		case "target_id":
+			if !r.null() {
+				x.TargetID = r.string()
+			}
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		case "x":
+			if !r.null() {
+				x.X = r.float(64)
+			}
+		case "y":
+			if !r.null() {
+				x.Y = r.float(64)
+			}
+		case "damage":
+			if !r.null() {
+				x.Damage = int(r.int(0))
+			}
+		case "health":
+			if !r.null() {
+				x.Health = int(r.int(0))
+			}
+		default:
+			if k, ok := foldKey(key, hitEventKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *LifeEvent) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"player_id":`)
+	w.string(x.PlayerID)
+	if x.SourceID != "" {
+		w.raw(`,"source_id":`)
+		w.string(x.SourceID)
+	}
+	if x.Reason != "" {
+		w.raw(`,"reason":`)
+		w.string(x.Reason)
+	}
+	if x.Amount != 0 {
+		w.raw(`,"amount":`)
+		w.int(int64(x.Amount))
+	}
+	w.raw(`,"health":`)
+	w.int(int64(x.Health))
+	if x.RespawnMs != 0 {
+		w.raw(`,"respawn_ms":`)
+		w.int(x.RespawnMs)
+	}
+	w.raw(`,"posx":`)
+	w.int(int64(x.PosX))
+	w.raw(`,"posy":`)
+	w.int(int64(x.PosY))
+	w.raw(`,"chunk_id":`)
+	x.ChunkID.writeJSON(w)
+	w.objectEnd(start)
+}
+
+var lifeEventKeys = []string{"kind", "player_id", "source_id", "reason", "amount", "health", "respawn_ms", "posx", "posy", "chunk_id"}
+
+func (x *LifeEvent) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "player_id":
+			if !r.null() {
+				x.PlayerID = r.string()
+			}
+		case "source_id":
+			if !r.null() {
+				x.SourceID = r.string()
+			}
+		case "reason":
+			if !r.null() {
+				x.Reason = r.string()
+			}
+		case "amount":
+			if !r.null() {
+				x.Amount = int(r.int(0))
+			}
+		case "health":
+			if !r.null() {
+				x.Health = int(r.int(0))
+			}
+		case "respawn_ms":
+			if !r.null() {
+				x.RespawnMs = r.int(64)
+			}
+		case "posx":
+			if !r.null() {
+				x.PosX = int(r.int(0))
+			}
+		case "posy":
+			if !r.null() {
+				x.PosY = int(r.int(0))
+			}
+		case "chunk_id":
+			x.ChunkID.readJSON(r)
+		default:
+			if k, ok := foldKey(key, lifeEventKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Award) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"player_id":`)
+	w.string(x.PlayerID)
+	w.raw(`,"achievement":`)
+	x.Achievement.writeJSON(w)
+	w.raw(`,"earned_ms":`)
+	w.int(x.EarnedMs)
+	w.objectEnd(start)
+}
+
+var awardKeys = []string{"player_id", "achievement", "earned_ms"}
+
+func (x *Award) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "player_id":
+			if !r.null() {
+				x.PlayerID = r.string()
+			}
+		case "achievement":
+			x.Achievement.readJSON(r)
+		case "earned_ms":
+			if !r.null() {
+				x.EarnedMs = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, awardKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *World) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"created_ms":`)
+	w.int(x.CreatedMs)
+	if x.Consistency != "" {
+		w.raw(`,"consistency":`)
+		w.string(x.Consistency)
+	}
+	w.objectEnd(start)
+}
+
+var worldKeys = []string{"id", "kind", "created_ms", "consistency"}
+
+func (x *World) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "created_ms":
+			if !r.null() {
+				x.CreatedMs = r.int(64)
+			}
+		case "consistency":
+			if !r.null() {
+				x.Consistency = r.string()
+			}
+		default:
+			if k, ok := foldKey(key, worldKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *ChunkDelta) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"from":`)
+	x.From.writeJSON(w)
+	w.raw(`,"to":`)
+	x.To.writeJSON(w)
+	if len(x.Cubes) != 0 {
+		w.raw(`,"cubes":`)
+		w.raw("[")
+		for i0 := range x.Cubes {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Cubes[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.RemovedCubes) != 0 {
+		w.raw(`,"removed_cubes":`)
+		w.raw("[")
+		for i0 := range x.RemovedCubes {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.RemovedCubes[i0])
+		}
+		w.raw("]")
+	}
+	if len(x.Players) != 0 {
+		w.raw(`,"players":`)
+		w.raw("[")
+		for i0 := range x.Players {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Players[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.RemovedPlayers) != 0 {
+		w.raw(`,"removed_players":`)
+		w.raw("[")
+		for i0 := range x.RemovedPlayers {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.RemovedPlayers[i0])
+		}
+		w.raw("]")
+	}
+	if len(x.NPCs) != 0 {
+		w.raw(`,"npcs":`)
+		w.raw("[")
+		for i0 := range x.NPCs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.NPCs[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.RemovedNPCs) != 0 {
+		w.raw(`,"removed_npcs":`)
+		w.raw("[")
+		for i0 := range x.RemovedNPCs {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.RemovedNPCs[i0])
+		}
+		w.raw("]")
+	}
+	if len(x.Items) != 0 {
+		w.raw(`,"items":`)
+		w.raw("[")
+		for i0 := range x.Items {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			x.Items[i0].writeJSON(w)
+		}
+		w.raw("]")
+	}
+	if len(x.RemovedItems) != 0 {
+		w.raw(`,"removed_items":`)
+		w.raw("[")
+		for i0 := range x.RemovedItems {
+			if i0 > 0 {
+				w.raw(",")
+			}
+			w.string(x.RemovedItems[i0])
+		}
+		w.raw("]")
+	}
+	w.objectEnd(start)
+}
+
+var chunkDeltaKeys = []string{"from", "to", "cubes", "removed_cubes", "players", "removed_players", "npcs", "removed_npcs", "items", "removed_items"}
+
+func (x *ChunkDelta) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "from":
+			x.From.readJSON(r)
+		case "to":
+			x.To.readJSON(r)
+		case "cubes":
+			if r.null() {
+				x.Cubes = nil
+			} else if r.array() {
+				s0 := x.Cubes[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Cube))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Cube{}
+				}
+				x.Cubes = s0
+			}
+		case "removed_cubes":
+			if r.null() {
+				x.RemovedCubes = nil
+			} else if r.array() {
+				s0 := x.RemovedCubes[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.RemovedCubes = s0
+			}
+		case "players":
+			if r.null() {
+				x.Players = nil
+			} else if r.array() {
+				s0 := x.Players[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Player))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Player{}
+				}
+				x.Players = s0
+			}
+		case "removed_players":
+			if r.null() {
+				x.RemovedPlayers = nil
+			} else if r.array() {
+				s0 := x.RemovedPlayers[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.RemovedPlayers = s0
+			}
+		case "npcs":
+			if r.null() {
+				x.NPCs = nil
+			} else if r.array() {
+				s0 := x.NPCs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(NPC))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []NPC{}
+				}
+				x.NPCs = s0
+			}
+		case "removed_npcs":
+			if r.null() {
+				x.RemovedNPCs = nil
+			} else if r.array() {
+				s0 := x.RemovedNPCs[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.RemovedNPCs = s0
+			}
+		case "items":
+			if r.null() {
+				x.Items = nil
+			} else if r.array() {
+				s0 := x.Items[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(Item))
+					s0[len(s0)-1].readJSON(r)
+				}
+				if s0 == nil {
+					s0 = []Item{}
+				}
+				x.Items = s0
+			}
+		case "removed_items":
+			if r.null() {
+				x.RemovedItems = nil
+			} else if r.array() {
+				s0 := x.RemovedItems[:0]
+				for i0 := 0; r.more(']', i0); i0++ {
+					s0 = append(s0, *new(string))
+					if !r.null() {
+						s0[len(s0)-1] = r.string()
+					}
+				}
+				if s0 == nil {
+					s0 = []string{}
+				}
+				x.RemovedItems = s0
+			}
+		default:
+			if k, ok := foldKey(key, chunkDeltaKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *NPC) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"posx":`)
+	w.int(int64(x.PosX))
+	w.raw(`,"posy":`)
+	w.int(int64(x.PosY))
+	if x.Target != "" {
+		w.raw(`,"target":`)
+		w.string(x.Target)
+	}
+	w.objectEnd(start)
+}
+
+var nPCKeys = []string{"id", "kind", "posx", "posy", "target"}
+
+func (x *NPC) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "posx":
+			if !r.null() {
+				x.PosX = int(r.int(0))
+			}
+		case "posy":
+			if !r.null() {
+				x.PosY = int(r.int(0))
+			}
+		case "target":
+			if !r.null() {
+				x.Target = r.string()
+			}
+		default:
+			if k, ok := foldKey(key, nPCKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Item) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"kind":`)
+	w.string(x.Kind)
+	w.raw(`,"count":`)
+	w.int(int64(x.Count))
+	w.raw(`,"posx":`)
+	w.int(int64(x.PosX))
+	w.raw(`,"posy":`)
+	w.int(int64(x.PosY))
+	w.objectEnd(start)
+}
+
+var itemKeys = []string{"id", "kind", "count", "posx", "posy"}
+
+func (x *Item) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "kind":
+			if !r.null() {
+				x.Kind = r.string()
+			}
+		case "count":
+			if !r.null() {
+				x.Count = int(r.int(0))
+			}
+		case "posx":
+			if !r.null() {
+				x.PosX = int(r.int(0))
+			}
+		case "posy":
+			if !r.null() {
+				x.PosY = int(r.int(0))
+			}
+		default:
+			if k, ok := foldKey(key, itemKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Tombstone) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"cube_id":`)
+	w.string(x.ID)
+	w.raw(`,"hlc":`)
+	w.uint(uint64(x.HLC))
+	w.objectEnd(start)
+}
+
+var tombstoneKeys = []string{"cube_id", "hlc"}
+
+func (x *Tombstone) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "cube_id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "hlc":
+			if !r.null() {
+				x.HLC = HLC(r.uint(64))
+			}
+		default:
+			if k, ok := foldKey(key, tombstoneKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *SpawnPoint) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"pos_x":`)
+	w.int(int64(x.PosX))
+	w.raw(`,"pos_y":`)
+	w.int(int64(x.PosY))
+	w.objectEnd(start)
+}
+
+var spawnPointKeys = []string{"pos_x", "pos_y"}
+
+func (x *SpawnPoint) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "pos_x":
+			if !r.null() {
+				x.PosX = int(r.int(0))
+			}
+		case "pos_y":
+			if !r.null() {
+				x.PosY = int(r.int(0))
+			}
+		default:
+			if k, ok := foldKey(key, spawnPointKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *PlayerStats) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	if x.PlayerID != "" {
+		w.raw(`,"player_id":`)
+		w.string(x.PlayerID)
+	}
+	if x.CubesPlaced != 0 {
+		w.raw(`,"cubes_placed":`)
+		w.int(x.CubesPlaced)
+	}
+	if x.CubesRemoved != 0 {
+		w.raw(`,"cubes_removed":`)
+		w.int(x.CubesRemoved)
+	}
+	if x.Distance != 0 {
+		w.raw(`,"distance":`)
+		w.float(x.Distance, 64)
+	}
+	if x.ChunksVisited != 0 {
+		w.raw(`,"chunks_visited":`)
+		w.int(x.ChunksVisited)
+	}
+	if x.Kills != 0 {
+		w.raw(`,"kills":`)
+		w.int(x.Kills)
+	}
+	if x.Deaths != 0 {
+		w.raw(`,"deaths":`)
+		w.int(x.Deaths)
+	}
+	w.objectEnd(start)
+}
+
+var playerStatsKeys = []string{"player_id", "cubes_placed", "cubes_removed", "distance", "chunks_visited", "kills", "deaths"}
+
+func (x *PlayerStats) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "player_id":
+			if !r.null() {
+				x.PlayerID = r.string()
+			}
+		case "cubes_placed":
+			if !r.null() {
+				x.CubesPlaced = r.int(64)
+			}
+		case "cubes_removed":
+			if !r.null() {
+				x.CubesRemoved = r.int(64)
+			}
+		case "distance":
+			if !r.null() {
+				x.Distance = r.float(64)
+			}
+		case "chunks_visited":
+			if !r.null() {
+				x.ChunksVisited = r.int(64)
+			}
+		case "kills":
+			if !r.null() {
+				x.Kills = r.int(64)
+			}
+		case "deaths":
+			if !r.null() {
+				x.Deaths = r.int(64)
+			}
+		default:
+			if k, ok := foldKey(key, playerStatsKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+func (x *Achievement) writeJSON(w *jsonWriter) {
+	start := len(w.b)
+	w.raw(`,"id":`)
+	w.string(x.ID)
+	w.raw(`,"name":`)
+	w.string(x.Name)
+	if x.Description != "" {
+		w.raw(`,"description":`)
+		w.string(x.Description)
+	}
+	w.raw(`,"stat":`)
+	w.string(x.Stat)
+	w.raw(`,"goal":`)
+	w.float(x.Goal, 64)
+	w.objectEnd(start)
+}
+
+var achievementKeys = []string{"id", "name", "description", "stat", "goal"}
+
+func (x *Achievement) readJSON(r *jsonReader) {
+	if !r.object() {
+		return
+	}
+	for i := 0; r.more('}', i); i++ {
+		key := r.key()
+	field:
+		switch string(key) {
+		case "id":
+			if !r.null() {
+				x.ID = r.string()
+			}
+		case "name":
+			if !r.null() {
+				x.Name = r.string()
+			}
+		case "description":
+			if !r.null() {
+				x.Description = r.string()
+			}
+		case "stat":
+			if !r.null() {
+				x.Stat = r.string()
+			}
+		case "goal":
+			if !r.null() {
+				x.Goal = r.float(64)
+			}
+		default:
+			if k, ok := foldKey(key, achievementKeys); ok {
+				key = k
+				goto field
+			}
+			r.skip()
+		}
+	}
+}
+
+// AppendJSON appends x to b as encoding/json would encode it.
+func (x *Request) AppendJSON(b []byte) ([]byte, error) {
+	w := jsonWriter{b: b}
+	x.writeJSON(&w)
+	return w.b, w.err
+}
+
+// DecodeJSON decodes data into x as encoding/json would.
+func (x *Request) DecodeJSON(data []byte) error {
+	r := jsonReader{data: data}
+	x.readJSON(&r)
+	r.end()
+	return r.err
+}
+
+// AppendJSON appends x to b as encoding/json would encode it.
+func (x *Response) AppendJSON(b []byte) ([]byte, error) {
+	w := jsonWriter{b: b}
+	x.writeJSON(&w)
+	return w.b, w.err
+}
+
+// DecodeJSON decodes data into x as encoding/json would.
+func (x *Response) DecodeJSON(data []byte) error {
+	r := jsonReader{data: data}
+	x.readJSON(&r)
+	r.end()
+	return r.err
+}
+
+// AppendJSON appends x to b as encoding/json would encode it.
+func (x *Chunk) AppendJSON(b []byte) ([]byte, error) {
+	w := jsonWriter{b: b}
+	x.writeJSON(&w)
+	return w.b, w.err
+}
+
+// DecodeJSON decodes data into x as encoding/json would.
+func (x *Chunk) DecodeJSON(data []byte) error {
+	r := jsonReader{data: data}
+	x.readJSON(&r)
+	r.end()
+	return r.err
+}
@@ -0,0 +1,409 @@
+//go:build ignore
+
+// gen_codecs writes codecs.go: JSON codecs for the wire structs in roots and
+// every struct of this package they contain, read off the structs with
+// reflection, so they always follow the json tags. Run `go run
+// gen_codecs.go` after changing a wire struct; cmd/codecbench then checks
+// the codecs still agree with encoding/json. As it builds against this
+// package, a codecs.go broken by a change must be deleted first.
+package main
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// roots get exported AppendJSON and DecodeJSON methods.
+var roots = []reflect.Type{
+	reflect.TypeOf(types.Request{}),
+	reflect.TypeOf(types.Response{}),
+	reflect.TypeOf(types.Chunk{}),
+}
+
+var typesPkg = reflect.TypeOf(types.Request{}).PkgPath()
+
+var (
+	marshaler       = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	unmarshaler     = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+	textMarshaler   = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	textUnmarshaler = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+type field struct {
+	Go        string // Go field name
+	JSON      string // key on the wire
+	OmitEmpty bool
+	Type      reflect.Type
+}
+
+type gen struct {
+	b       bytes.Buffer
+	imports map[string]bool
+	done    map[reflect.Type]bool
+	queue   []reflect.Type
+}
+
+func main() {
+	g := &gen{imports: map[string]bool{}, done: map[reflect.Type]bool{}}
+	for _, t := range roots {
+		g.want(t)
+	}
+	var body bytes.Buffer
+	for len(g.queue) > 0 {
+		t := g.queue[0]
+		g.queue = g.queue[1:]
+		g.b.Reset()
+		g.structCodec(t)
+		body.Write(g.b.Bytes())
+	}
+	for _, t := range roots {
+		fmt.Fprintf(&body, "// AppendJSON appends x to b as encoding/json would encode it.\n")
+		fmt.Fprintf(&body, "func (x *%s) AppendJSON(b []byte) ([]byte, error) {\n", t.Name())
+		fmt.Fprintf(&body, "\tw := jsonWriter{b: b}\n\tx.writeJSON(&w)\n\treturn w.b, w.err\n}\n\n")
+		fmt.Fprintf(&body, "// DecodeJSON decodes data into x as encoding/json would.\n")
+		fmt.Fprintf(&body, "func (x *%s) DecodeJSON(data []byte) error {\n", t.Name())
+		fmt.Fprintf(&body, "\tr := jsonReader{data: data}\n\tx.readJSON(&r)\n\tr.end()\n\treturn r.err\n}\n\n")
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintln(&out, "// Code generated by gen_codecs.go; DO NOT EDIT.")
+	fmt.Fprintln(&out)
+	fmt.Fprintln(&out, "package types")
+	fmt.Fprintln(&out)
+	var imports []string
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	if len(imports) > 0 {
+		fmt.Fprintln(&out, "import (")
+		for _, path := range imports {
+			fmt.Fprintf(&out, "\t%q\n", path)
+		}
+		fmt.Fprintln(&out, ")")
+		fmt.Fprintln(&out)
+	}
+	out.Write(body.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		os.WriteFile("codecs.go.broken", out.Bytes(), 0o644)
+		log.Fatalf("gofmt: %v (source in codecs.go.broken)", err)
+	}
+	if err := os.WriteFile("codecs.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// want queues a struct of this package for a codec.
+func (g *gen) want(t reflect.Type) {
+	if !g.done[t] {
+		g.done[t] = true
+		g.queue = append(g.queue, t)
+	}
+}
+
+// generated reports whether t gets a codec of its own.
+func generated(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == typesPkg && !custom(t)
+}
+
+// custom reports whether encoding/json would call methods of t's rather
+// than look at its fields, in which case the codecs hand it to encoding/json.
+func custom(t reflect.Type) bool {
+	p := reflect.PointerTo(t)
+	return t.Implements(marshaler) || t.Implements(textMarshaler) ||
+		p.Implements(marshaler) || p.Implements(textMarshaler) ||
+		p.Implements(unmarshaler) || p.Implements(textUnmarshaler)
+}
+
+func fields(t reflect.Type) []field {
+	var list []field
+	seen := map[string]bool{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous {
+			log.Fatalf("%s.%s: embedded fields are not supported", t.Name(), f.Name)
+		}
+		if !f.IsExported() {
+			continue
+		}
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			name = f.Name
+		}
+		if strings.ContainsAny(name, "\"\\<>&") {
+			log.Fatalf("%s.%s: key %q needs escaping", t.Name(), f.Name, name)
+		}
+		omit := false
+		for _, opt := range strings.Split(opts, ",") {
+			switch opt {
+			case "":
+			case "omitempty":
+				omit = true
+			default:
+				log.Fatalf("%s.%s: json option %q is not supported", t.Name(), f.Name, opt)
+			}
+		}
+		if seen[name] {
+			log.Fatalf("%s.%s: key %q used twice", t.Name(), f.Name, name)
+		}
+		seen[name] = true
+		list = append(list, field{Go: f.Name, JSON: name, OmitEmpty: omit, Type: f.Type})
+	}
+	return list
+}
+
+func (g *gen) printf(format string, args ...any) {
+	fmt.Fprintf(&g.b, format, args...)
+}
+
+func (g *gen) structCodec(t reflect.Type) {
+	list := fields(t)
+
+	g.printf("func (x *%s) writeJSON(w *jsonWriter) {\n", t.Name())
+	g.printf("start := len(w.b)\n")
+	for _, f := range list {
+		v := "x." + f.Go
+		if f.OmitEmpty {
+			if cond := nonEmpty(v, f.Type); cond != "" {
+				g.printf("if %s {\n", cond)
+				g.printf("w.raw(`,%q:`)\n", f.JSON)
+				g.encode(v, f.Type, 0, true)
+				g.printf("}\n")
+				continue
+			}
+		}
+		g.printf("w.raw(`,%q:`)\n", f.JSON)
+		g.encode(v, f.Type, 0, false)
+	}
+	g.printf("w.objectEnd(start)\n")
+	g.printf("}\n\n")
+
+	names := make([]string, len(list))
+	for i, f := range list {
+		names[i] = fmt.Sprintf("%q", f.JSON)
+	}
+	g.printf("var %sKeys = []string{%s}\n\n", lowerFirst(t.Name()), strings.Join(names, ", "))
+
+	g.printf("func (x *%s) readJSON(r *jsonReader) {\n", t.Name())
+	g.printf("if !r.object() {\nreturn\n}\n")
+	g.printf("for i := 0; r.more('}', i); i++ {\n")
+	g.printf("key := r.key()\n")
+	g.printf("field:\n")
+	g.printf("switch string(key) {\n")
+	for _, f := range list {
+		g.printf("case %q:\n", f.JSON)
+		g.decode("x."+f.Go, f.Type, 0)
+	}
+	g.printf("default:\n")
+	g.printf("if k, ok := foldKey(key, %sKeys); ok {\nkey = k\ngoto field\n}\n", lowerFirst(t.Name()))
+	g.printf("r.skip()\n")
+	g.printf("}\n}\n}\n\n")
+}
+
+// nonEmpty is the condition under which omitempty keeps v, or "" if it
+// always does.
+func nonEmpty(v string, t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return v
+	case reflect.String:
+		return v + ` != ""`
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return v + " != 0"
+	case reflect.Pointer, reflect.Interface:
+		return v + " != nil"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return "len(" + v + ") != 0"
+	}
+	return ""
+}
+
+// typeName is t as written in package types.
+func (g *gen) typeName(t reflect.Type) string {
+	if t.Name() != "" {
+		switch t.PkgPath() {
+		case "", typesPkg:
+			return t.Name()
+		}
+		g.imports[t.PkgPath()] = true
+		return t.String()
+	}
+	switch t.Kind() {
+	case reflect.Pointer:
+		return "*" + g.typeName(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.typeName(t.Elem())
+	case reflect.Array:
+		return fmt.Sprintf("[%d]%s", t.Len(), g.typeName(t.Elem()))
+	case reflect.Map:
+		return fmt.Sprintf("map[%s]%s", g.typeName(t.Key()), g.typeName(t.Elem()))
+	}
+	log.Fatalf("cannot name type %s", t)
+	return ""
+}
+
+// conv is v, of type t, converted to the predeclared type to, if it is not
+// of that type already.
+func conv(to, v string, t reflect.Type) string {
+	if t.PkgPath() == "" && t.Name() == to {
+		return v
+	}
+	return to + "(" + v + ")"
+}
+
+// encode writes the code appending v, of type t, to w; nonNil says v is
+// known not to be nil.
+func (g *gen) encode(v string, t reflect.Type, depth int, nonNil bool) {
+	if custom(t) {
+		g.printf("w.value(%s)\n", v)
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		g.printf("w.string(%s)\n", conv("string", v, t))
+	case reflect.Bool:
+		g.printf("w.bool(%s)\n", conv("bool", v, t))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		g.printf("w.int(%s)\n", conv("int64", v, t))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		g.printf("w.uint(%s)\n", conv("uint64", v, t))
+	case reflect.Float32, reflect.Float64:
+		g.printf("w.float(%s, %d)\n", conv("float64", v, t), t.Bits())
+	case reflect.Struct:
+		if !generated(t) {
+			g.printf("w.value(%s)\n", v)
+			return
+		}
+		g.want(t)
+		g.printf("%s.writeJSON(w)\n", v)
+	case reflect.Pointer:
+		if !nonNil {
+			g.printf("if %s == nil {\nw.raw(\"null\")\n} else {\n", v)
+		}
+		if generated(t.Elem()) {
+			g.want(t.Elem())
+			g.printf("%s.writeJSON(w)\n", v)
+		} else {
+			g.encode("(*"+v+")", t.Elem(), depth, false)
+		}
+		if !nonNil {
+			g.printf("}\n")
+		}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !custom(t.Elem()) {
+			g.printf("w.bytes(%s)\n", conv("[]byte", v, t))
+			return
+		}
+		i := fmt.Sprintf("i%d", depth)
+		if !nonNil {
+			g.printf("if %s == nil {\nw.raw(\"null\")\n} else {\n", v)
+		}
+		g.printf("w.raw(\"[\")\n")
+		g.printf("for %s := range %s {\n", i, v)
+		g.printf("if %s > 0 {\nw.raw(\",\")\n}\n", i)
+		g.encode(v+"["+i+"]", t.Elem(), depth+1, false)
+		g.printf("}\n")
+		g.printf("w.raw(\"]\")\n")
+		if !nonNil {
+			g.printf("}\n")
+		}
+	default:
+		g.printf("w.value(%s)\n", v)
+	}
+}
+
+// decode writes the code reading v, of type t, from r.
+func (g *gen) decode(v string, t reflect.Type, depth int) {
+	if custom(t) {
+		g.printf("r.value(&%s)\n", v)
+		return
+	}
+	switch t.Kind() {
+	case reflect.String:
+		g.printf("if !r.null() {\n%s = %s\n}\n", v, g.convFrom("string", "r.string()", t))
+	case reflect.Bool:
+		g.printf("if !r.null() {\n%s = %s\n}\n", v, g.convFrom("bool", "r.bool()", t))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		g.printf("if !r.null() {\n%s = %s\n}\n", v, g.convFrom("int64", fmt.Sprintf("r.int(%d)", bits(t)), t))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		g.printf("if !r.null() {\n%s = %s\n}\n", v, g.convFrom("uint64", fmt.Sprintf("r.uint(%d)", bits(t)), t))
+	case reflect.Float32, reflect.Float64:
+		g.printf("if !r.null() {\n%s = %s\n}\n", v, g.convFrom("float64", fmt.Sprintf("r.float(%d)", t.Bits()), t))
+	case reflect.Struct:
+		if !generated(t) {
+			g.printf("r.value(&%s)\n", v)
+			return
+		}
+		g.want(t)
+		g.printf("%s.readJSON(r)\n", v)
+	case reflect.Pointer:
+		g.printf("if r.null() {\n%s = nil\n} else {\n", v)
+		g.printf("if %s == nil {\n%s = new(%s)\n}\n", v, v, g.typeName(t.Elem()))
+		if generated(t.Elem()) {
+			g.want(t.Elem())
+			g.printf("%s.readJSON(r)\n", v)
+		} else {
+			g.decode("(*"+v+")", t.Elem(), depth)
+		}
+		g.printf("}\n")
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 && !custom(t.Elem()) {
+			g.printf("if r.null() {\n%s = nil\n} else {\n%s = %s\n}\n", v, v, g.convFrom("[]byte", "r.bytes()", t))
+			return
+		}
+		s, i := fmt.Sprintf("s%d", depth), fmt.Sprintf("i%d", depth)
+		elem := g.typeName(t.Elem())
+		g.printf("if r.null() {\n%s = nil\n} else if r.array() {\n", v)
+		g.printf("%s := %s[:0]\n", s, v)
+		g.printf("for %s := 0; r.more(']', %s); %s++ {\n", i, i, i)
+		g.printf("%s = append(%s, *new(%s))\n", s, s, elem)
+		g.decode(s+"[len("+s+")-1]", t.Elem(), depth+1)
+		g.printf("}\n")
+		g.printf("if %s == nil {\n%s = %s{}\n}\n", s, s, g.typeName(t))
+		g.printf("%s = %s\n", v, s)
+		g.printf("}\n")
+	default:
+		g.printf("r.value(&%s)\n", v)
+	}
+}
+
+// bits is the bit size to parse t's values with: 0, for int and uint,
+// means whatever size they are where the code is built.
+func bits(t reflect.Type) int {
+	switch t.Kind() {
+	case reflect.Int, reflect.Uint:
+		return 0
+	}
+	return t.Bits()
+}
+
+// convFrom is v, of the predeclared type from, converted to t if it is not
+// of that type already.
+func (g *gen) convFrom(from, v string, t reflect.Type) string {
+	if name := g.typeName(t); name != from {
+		return name + "(" + v + ")"
+	}
+	return v
+}
+
+func lowerFirst(s string) string {
+	return strings.ToLower(s[:1]) + s[1:]
+}
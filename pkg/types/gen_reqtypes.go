@@ -0,0 +1,154 @@
+//go:build ignore
+
+// gen_reqtypes writes reqtypes.go from the table below. Add a message type
+// here, run `go run gen_reqtypes.go`, and every binary picks it up; the game
+// server refuses to start until its dispatch table covers the new type.
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"strings"
+)
+
+type requestType struct {
+	Wire    string // value of Request.Type on the wire
+	Handler string // "server" (game server UDP) or "central" (central HTTP)
+	Peer    bool   // sent server-to-server rather than by a player
+	Doc     string
+}
+
+var requestTypes = []requestType{
+	{"GET_DATA", "server", false, "fetch (and claim, if unowned) the chunk a player is in"},
+	{"MOVE_PLAYER", "server", false, "update a player's position within its chunk"},
+	{"GET_UPDATES", "server", false, "poll the state of a player's chunk"},
+	{"DLT_PLAYER", "server", false, "remove a player who is leaving"},
+	{"RESUME", "server", false, "reattach a restarted client to its player with the session token from /join"},
+	{"SYNC", "server", false, "sample the server clock and world tick for the client's clock offset estimate"},
+	{"TELEMETRY", "server", false, "report the client's RTT, loss and redirect statistics for QoS decisions"},
+	{"CHAT", "server", false, "send a text message to the players around, and read the chunk's recent messages"},
+	{"WHISPER", "server", false, "send a text message to one player, relayed to whichever server holds them"},
+	{"SPECTATE", "server", false, "watch a chunk's changes as they happen without being a player in it"},
+	{"ACK", "server", false, "acknowledge a pushed chunk version, so later pushes are diffed from it"},
+	{"SHOOT", "server", false, "fire a projectile the server simulates and hit-tests against players"},
+	{"PICKUP", "server", false, "take an item lying near the player into their inventory"},
+	{"DROP", "server", false, "leave items from the player's inventory in their chunk"},
+	{"USE", "server", false, "use up one item from the player's inventory"},
+	{"ADD_CUBE", "server", false, "place a cube in a chunk"},
+	{"DLT_CUBE", "server", false, "remove a cube from a chunk"},
+	{"ADD_CUBES", "server", false, "place several cubes in a chunk at once"},
+	{"DLT_CUBES", "server", false, "remove several cubes from a chunk at once"},
+	{"UNDO", "server", false, "revert the player's latest cube edit in a chunk"},
+	{"UPDATE_DATA", "server", false, "overwrite a chunk with a newer copy"},
+	{"TX_BEGIN", "server", false, "open a transaction of cube edits across chunks"},
+	{"TX_APPLY", "server", false, "add one chunk's cube edits to an open transaction"},
+	{"TX_COMMIT", "server", false, "apply a transaction's edits to every chunk, or to none"},
+	{"TX_ABORT", "server", false, "drop an open transaction"},
+	{"FROM_CENTRAL", "server", true, "central asks the owner to hand a chunk over"},
+	{"READ_ONLY", "server", true, "read a chunk without taking ownership"},
+	{"MERGE", "server", true, "merge a migrating chunk into the new owner"},
+	{"PLAYER_TRANSFER", "server", true, "hand players' state to the new owner of their chunk"},
+	{"SET_REPLICAS", "server", true, "central names the read replicas of an owned chunk"},
+	{"REPLICA_SYNC", "server", true, "stream an owned chunk, or the cubes a replica's copy differs in, to one of its read replicas"},
+	{"REPLICA_DIGEST", "server", true, "compare a read replica's copy of a chunk with the owner's by Merkle digest"},
+	{"SPLIT_CHUNK", "server", true, "central tells a server a chunk was split into four sub-chunks"},
+	{"KICK_PLAYER", "server", true, "central disconnects a player from this server"},
+	{"GOSSIP", "server", true, "exchange membership, load and chunk ownership hints with a peer"},
+	{"TX_PREPARE", "server", true, "check a transaction's edits on owned chunks and lock them"},
+	{"TX_FINISH", "server", true, "commit or abort a prepared transaction"},
+	{"ADOPT_CHUNK", "server", true, "central hands a server a chunk: a dead owner's, or a new one in its region"},
+	{"LOCATE_PLAYER", "server", true, "central asks which server holds a resuming player, arming their session token there"},
+	{"ANNOUNCE", "server", true, "central has a server push an announcement to every player connected to it"},
+	{"PARTY_UPDATE", "server", true, "central tells every server a party's new member list (empty when disbanded)"},
+	{"DAMAGE", "server", true, "take Amount off a player's health for Reason, killing them at 0"},
+	{"HEAL", "server", true, "give a living player back Amount of health, up to full"},
+	{"MATCH", "server", true, "central has the server hosting a match seed its chunks, and every server tell the players in it"},
+	{"OWNER_CHANGED", "server", true, "central tells every server a chunk has a new owner, for their ownership caches"},
+	{"GET_CHUNK", "central", false, "ask central who owns a chunk"},
+	{"JOIN", "central", false, "ask central which server a new player should use"},
+}
+
+func main() {
+	var b bytes.Buffer
+	fmt.Fprintln(&b, "// Code generated by gen_reqtypes.go; DO NOT EDIT.")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "package types")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "// RequestType is the value of Request.Type.")
+	fmt.Fprintln(&b, "type RequestType string")
+	fmt.Fprintln(&b)
+	fmt.Fprintln(&b, "const (")
+	for _, t := range requestTypes {
+		fmt.Fprintf(&b, "\t%s RequestType = %q // %s\n", constName(t.Wire), t.Wire, t.Doc)
+	}
+	fmt.Fprintln(&b, ")")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "// AllRequestTypes lists every request type in declaration order.")
+	fmt.Fprintln(&b, "var AllRequestTypes = []RequestType{")
+	for _, t := range requestTypes {
+		fmt.Fprintf(&b, "\t%s,\n", constName(t.Wire))
+	}
+	fmt.Fprintln(&b, "}")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "// GameServerRequestTypes are the types the game server dispatches over UDP.")
+	fmt.Fprintln(&b, "var GameServerRequestTypes = []RequestType{")
+	for _, t := range requestTypes {
+		if t.Handler == "server" {
+			fmt.Fprintf(&b, "\t%s,\n", constName(t.Wire))
+		}
+	}
+	fmt.Fprintln(&b, "}")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "// Valid reports whether t is a known request type.")
+	fmt.Fprintln(&b, "func (t RequestType) Valid() bool {")
+	fmt.Fprintln(&b, "\tswitch t {")
+	fmt.Fprintf(&b, "\tcase %s:\n", joinConsts(requestTypes, func(requestType) bool { return true }))
+	fmt.Fprintln(&b, "\t\treturn true")
+	fmt.Fprintln(&b, "\t}")
+	fmt.Fprintln(&b, "\treturn false")
+	fmt.Fprintln(&b, "}")
+	fmt.Fprintln(&b)
+
+	fmt.Fprintln(&b, "// IsPeer reports whether t is sent between servers rather than by a player.")
+	fmt.Fprintln(&b, "func (t RequestType) IsPeer() bool {")
+	fmt.Fprintln(&b, "\tswitch t {")
+	fmt.Fprintf(&b, "\tcase %s:\n", joinConsts(requestTypes, func(t requestType) bool { return t.Peer }))
+	fmt.Fprintln(&b, "\t\treturn true")
+	fmt.Fprintln(&b, "\t}")
+	fmt.Fprintln(&b, "\treturn false")
+	fmt.Fprintln(&b, "}")
+
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		log.Fatalf("gofmt: %v", err)
+	}
+	if err := os.WriteFile("reqtypes.go", src, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// constName turns GET_DATA into ReqGetData.
+func constName(wire string) string {
+	var sb strings.Builder
+	sb.WriteString("Req")
+	for _, part := range strings.Split(wire, "_") {
+		sb.WriteString(part[:1] + strings.ToLower(part[1:]))
+	}
+	return sb.String()
+}
+
+func joinConsts(types []requestType, keep func(requestType) bool) string {
+	var names []string
+	for _, t := range types {
+		if keep(t) {
+			names = append(names, constName(t.Wire))
+		}
+	}
+	return strings.Join(names, ", ")
+}
@@ -0,0 +1,562 @@
+package types
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// ===================== Generated JSON codecs =====================
+
+// The wire structs decoded and encoded on every datagram (Request,
+// Response, Chunk) have JSON codecs written by gen_codecs.go into
+// codecs.go: AppendJSON and DecodeJSON, which read and write the same JSON
+// encoding/json does without its reflection. Fields of types the generator
+// does not know (time.Time, maps) go through encoding/json. netproto picks
+// the codec with netproto.Wire; cmd/codecbench checks the two agree and
+// compares their speed.
+//
+// What follows is what the generated code is made of: a writer and a
+// reader of JSON values.
+
+// jsonWriter appends JSON to b; the first error sticks.
+type jsonWriter struct {
+	b   []byte
+	err error
+}
+
+func (w *jsonWriter) raw(s string) {
+	w.b = append(w.b, s...)
+}
+
+func (w *jsonWriter) bool(v bool) {
+	w.b = strconv.AppendBool(w.b, v)
+}
+
+func (w *jsonWriter) int(v int64) {
+	w.b = strconv.AppendInt(w.b, v, 10)
+}
+
+func (w *jsonWriter) uint(v uint64) {
+	w.b = strconv.AppendUint(w.b, v, 10)
+}
+
+// float writes v as encoding/json does: no exponent unless v is tiny or
+// huge, and an error for NaN and infinities.
+func (w *jsonWriter) float(v float64, bits int) {
+	if math.IsInf(v, 0) || math.IsNaN(v) {
+		if w.err == nil {
+			w.err = &json.UnsupportedValueError{Str: strconv.FormatFloat(v, 'g', -1, bits)}
+		}
+		w.b = append(w.b, '0')
+		return
+	}
+	format := byte('f')
+	if abs := math.Abs(v); abs != 0 {
+		if bits == 64 && (abs < 1e-6 || abs >= 1e21) || bits == 32 && (float32(abs) < 1e-6 || float32(abs) >= 1e21) {
+			format = 'e'
+		}
+	}
+	w.b = strconv.AppendFloat(w.b, v, format, -1, bits)
+	if format == 'e' {
+		// e-09 to e-9
+		if n := len(w.b); n >= 4 && w.b[n-4] == 'e' && w.b[n-3] == '-' && w.b[n-2] == '0' {
+			w.b[n-2] = w.b[n-1]
+			w.b = w.b[:n-1]
+		}
+	}
+}
+
+const hexDigits = "0123456789abcdef"
+
+// string writes s quoted, escaped as encoding/json does, HTML characters
+// included.
+func (w *jsonWriter) string(s string) {
+	b := append(w.b, '"')
+	start := 0
+	for i := 0; i < len(s); {
+		if c := s[i]; c < utf8.RuneSelf {
+			if c >= 0x20 && c != '"' && c != '\\' && c != '<' && c != '>' && c != '&' {
+				i++
+				continue
+			}
+			b = append(b, s[start:i]...)
+			switch c {
+			case '"', '\\':
+				b = append(b, '\\', c)
+			case '\b':
+				b = append(b, '\\', 'b')
+			case '\f':
+				b = append(b, '\\', 'f')
+			case '\n':
+				b = append(b, '\\', 'n')
+			case '\r':
+				b = append(b, '\\', 'r')
+			case '\t':
+				b = append(b, '\\', 't')
+			default:
+				b = append(b, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xF])
+			}
+			i++
+			start = i
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			b = append(b, s[start:i]...)
+			b = utf8.AppendRune(b, utf8.RuneError)
+			i += size
+			start = i
+			continue
+		}
+		if r == '\u2028' || r == '\u2029' {
+			b = append(b, s[start:i]...)
+			b = append(b, '\\', 'u', '2', '0', '2', hexDigits[r&0xF])
+			i += size
+			start = i
+			continue
+		}
+		i += size
+	}
+	b = append(b, s[start:]...)
+	w.b = append(b, '"')
+}
+
+func (w *jsonWriter) bytes(v []byte) {
+	if v == nil {
+		w.b = append(w.b, "null"...)
+		return
+	}
+	w.b = append(w.b, '"')
+	w.b = base64.StdEncoding.AppendEncode(w.b, v)
+	w.b = append(w.b, '"')
+}
+
+// value writes v with encoding/json, for the types the generator leaves to
+// it.
+func (w *jsonWriter) value(v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		if w.err == nil {
+			w.err = err
+		}
+		w.b = append(w.b, "null"...)
+		return
+	}
+	w.b = append(w.b, data...)
+}
+
+// objectEnd closes an object opened at start. Every field was written with
+// a leading comma, so the first one becomes the opening brace.
+func (w *jsonWriter) objectEnd(start int) {
+	if len(w.b) == start {
+		w.b = append(w.b, '{', '}')
+		return
+	}
+	w.b[start] = '{'
+	w.b = append(w.b, '}')
+}
+
+// jsonReader reads JSON values from data; the first error sticks, and
+// everything read after it is zero. Reading a null is left to the caller
+// (null), as what it does depends on the type read into: nothing for a
+// string, number, bool or struct, nil for a pointer or slice.
+type jsonReader struct {
+	data []byte
+	pos  int
+	err  error
+}
+
+var errJSONEnd = errors.New("unexpected end of JSON input")
+
+func (r *jsonReader) fail(format string, args ...any) {
+	if r.err == nil {
+		r.err = fmt.Errorf("json: "+format+" at offset %d", append(args, r.pos)...)
+	}
+}
+
+func (r *jsonReader) ws() {
+	for r.pos < len(r.data) {
+		switch r.data[r.pos] {
+		case ' ', '\t', '\n', '\r':
+			r.pos++
+		default:
+			return
+		}
+	}
+}
+
+// peek returns the next byte past whitespace, or 0 at the end or after an
+// error.
+func (r *jsonReader) peek() byte {
+	r.ws()
+	if r.err != nil || r.pos >= len(r.data) {
+		return 0
+	}
+	return r.data[r.pos]
+}
+
+func (r *jsonReader) expect(c byte) {
+	switch r.peek() {
+	case c:
+		r.pos++
+	case 0:
+		if r.err == nil {
+			r.err = errJSONEnd
+		}
+	default:
+		r.fail("expected %q, found %q", c, r.data[r.pos])
+	}
+}
+
+// end checks that nothing but whitespace is left.
+func (r *jsonReader) end() {
+	if c := r.peek(); c != 0 {
+		r.fail("invalid character %q after top-level value", c)
+	}
+}
+
+func (r *jsonReader) literal(word string) {
+	if !bytes.HasPrefix(r.data[r.pos:], []byte(word)) {
+		r.fail("invalid literal, expected %s", word)
+		return
+	}
+	r.pos += len(word)
+}
+
+// null consumes a null and reports whether there was one.
+func (r *jsonReader) null() bool {
+	if r.peek() != 'n' {
+		return false
+	}
+	r.literal("null")
+	return r.err == nil
+}
+
+// object opens an object, reporting false (and reading nothing) for null.
+func (r *jsonReader) object() bool {
+	if r.null() {
+		return false
+	}
+	r.expect('{')
+	return r.err == nil
+}
+
+// array opens an array, reporting false (and reading nothing) for null.
+func (r *jsonReader) array() bool {
+	if r.null() {
+		return false
+	}
+	r.expect('[')
+	return r.err == nil
+}
+
+// more reports whether the object or array closed by end has an i-th
+// member, consuming the comma before it or the closing end.
+func (r *jsonReader) more(end byte, i int) bool {
+	c := r.peek()
+	if r.err != nil {
+		return false
+	}
+	if c == end {
+		r.pos++
+		return false
+	}
+	if i > 0 {
+		r.expect(',')
+	}
+	return r.err == nil
+}
+
+// key reads an object key and its colon. The key may alias data.
+func (r *jsonReader) key() []byte {
+	k := r.stringBytes()
+	r.expect(':')
+	return k
+}
+
+func (r *jsonReader) string() string {
+	return string(r.stringBytes())
+}
+
+// stringBytes reads a string, unescaped; it aliases data when there was
+// nothing to unescape.
+func (r *jsonReader) stringBytes() []byte {
+	r.expect('"')
+	if r.err != nil {
+		return nil
+	}
+	start := r.pos
+	for r.pos < len(r.data) {
+		c := r.data[r.pos]
+		if c == '"' {
+			s := r.data[start:r.pos]
+			r.pos++
+			if !utf8.Valid(s) {
+				return validUTF8(s)
+			}
+			return s
+		}
+		if c == '\\' {
+			return r.unescape(start)
+		}
+		if c < 0x20 {
+			r.fail("invalid character %q in string literal", c)
+			return nil
+		}
+		r.pos++
+	}
+	r.err = errJSONEnd
+	return nil
+}
+
+// unescape reads the rest of a string that has escapes, from start.
+func (r *jsonReader) unescape(start int) []byte {
+	out := append([]byte(nil), r.data[start:r.pos]...)
+	for r.pos < len(r.data) {
+		c := r.data[r.pos]
+		switch {
+		case c == '"':
+			r.pos++
+			if !utf8.Valid(out) {
+				out = validUTF8(out)
+			}
+			return out
+		case c < 0x20:
+			r.fail("invalid character %q in string literal", c)
+			return nil
+		case c != '\\':
+			out = append(out, c)
+			r.pos++
+			continue
+		}
+		if r.pos+1 >= len(r.data) {
+			break
+		}
+		e := r.data[r.pos+1]
+		r.pos += 2
+		switch e {
+		case '"', '\\', '/':
+			out = append(out, e)
+		case 'b':
+			out = append(out, '\b')
+		case 'f':
+			out = append(out, '\f')
+		case 'n':
+			out = append(out, '\n')
+		case 'r':
+			out = append(out, '\r')
+		case 't':
+			out = append(out, '\t')
+		case 'u':
+			c1 := r.hex4()
+			if r.err != nil {
+				return nil
+			}
+			if utf16.IsSurrogate(c1) {
+				c2 := rune(-1)
+				if r.pos+1 < len(r.data) && r.data[r.pos] == '\\' && r.data[r.pos+1] == 'u' {
+					save := r.pos
+					r.pos += 2
+					if c2 = r.hex4(); r.err != nil {
+						return nil
+					}
+					if dec := utf16.DecodeRune(c1, c2); dec != utf8.RuneError {
+						out = utf8.AppendRune(out, dec)
+						continue
+					}
+					r.pos = save
+				}
+				c1 = utf8.RuneError
+			}
+			out = utf8.AppendRune(out, c1)
+		default:
+			r.fail("invalid escape %q in string literal", e)
+			return nil
+		}
+	}
+	r.err = errJSONEnd
+	return nil
+}
+
+// validUTF8 replaces each byte of s that is not UTF-8 with U+FFFD, in a
+// copy.
+func validUTF8(s []byte) []byte {
+	out := make([]byte, 0, len(s)+8)
+	for len(s) > 0 {
+		c, size := utf8.DecodeRune(s)
+		out = utf8.AppendRune(out, c)
+		s = s[size:]
+	}
+	return out
+}
+
+func (r *jsonReader) hex4() rune {
+	if r.pos+4 > len(r.data) {
+		r.err = errJSONEnd
+		return 0
+	}
+	v, err := strconv.ParseUint(string(r.data[r.pos:r.pos+4]), 16, 32)
+	if err != nil {
+		r.fail("invalid \\u escape")
+		return 0
+	}
+	r.pos += 4
+	return rune(v)
+}
+
+func (r *jsonReader) bool() bool {
+	switch r.peek() {
+	case 't':
+		r.literal("true")
+		return r.err == nil
+	case 'f':
+		r.literal("false")
+	case 0:
+		if r.err == nil {
+			r.err = errJSONEnd
+		}
+	default:
+		r.fail("cannot read %q as a bool", r.data[r.pos])
+	}
+	return false
+}
+
+// number reads a number's text.
+func (r *jsonReader) number() []byte {
+	r.ws()
+	start := r.pos
+	for r.pos < len(r.data) {
+		c := r.data[r.pos]
+		if (c < '0' || c > '9') && c != '-' && c != '+' && c != '.' && c != 'e' && c != 'E' {
+			break
+		}
+		r.pos++
+	}
+	if r.pos == start {
+		if r.pos == len(r.data) {
+			if r.err == nil {
+				r.err = errJSONEnd
+			}
+		} else {
+			r.fail("cannot read %q as a number", r.data[r.pos])
+		}
+		return nil
+	}
+	return r.data[start:r.pos]
+}
+
+func (r *jsonReader) int(bits int) int64 {
+	n := r.number()
+	if n == nil || r.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseInt(string(n), 10, bits)
+	if err != nil {
+		r.fail("cannot read number %s as an integer", n)
+		return 0
+	}
+	return v
+}
+
+func (r *jsonReader) uint(bits int) uint64 {
+	n := r.number()
+	if n == nil || r.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseUint(string(n), 10, bits)
+	if err != nil {
+		r.fail("cannot read number %s as an unsigned integer", n)
+		return 0
+	}
+	return v
+}
+
+func (r *jsonReader) float(bits int) float64 {
+	n := r.number()
+	if n == nil || r.err != nil {
+		return 0
+	}
+	v, err := strconv.ParseFloat(string(n), bits)
+	if err != nil {
+		r.fail("cannot read number %s as a float", n)
+		return 0
+	}
+	return v
+}
+
+func (r *jsonReader) bytes() []byte {
+	s := r.stringBytes()
+	if r.err != nil {
+		return nil
+	}
+	out := make([]byte, base64.StdEncoding.DecodedLen(len(s)))
+	n, err := base64.StdEncoding.Decode(out, s)
+	if err != nil {
+		r.fail("invalid base64: %v", err)
+		return nil
+	}
+	return out[:n]
+}
+
+// skip reads past one value of any kind.
+func (r *jsonReader) skip() {
+	switch r.peek() {
+	case '{':
+		r.pos++
+		for i := 0; r.more('}', i); i++ {
+			r.key()
+			r.skip()
+		}
+	case '[':
+		r.pos++
+		for i := 0; r.more(']', i); i++ {
+			r.skip()
+		}
+	case '"':
+		r.stringBytes()
+	case 't':
+		r.literal("true")
+	case 'f':
+		r.literal("false")
+	case 'n':
+		r.literal("null")
+	case 0:
+		if r.err == nil {
+			r.err = errJSONEnd
+		}
+	default:
+		r.number()
+	}
+}
+
+// value reads one value into v with encoding/json, for the types the
+// generator leaves to it.
+func (r *jsonReader) value(v any) {
+	r.ws()
+	start := r.pos
+	r.skip()
+	if r.err != nil {
+		return
+	}
+	if err := json.Unmarshal(r.data[start:r.pos], v); err != nil && r.err == nil {
+		r.err = err
+	}
+}
+
+// foldKey returns the field name of names key matches case-insensitively,
+// as encoding/json matches keys without an exact match.
+func foldKey(key []byte, names []string) ([]byte, bool) {
+	for _, name := range names {
+		if bytes.EqualFold(key, []byte(name)) {
+			return []byte(name), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,174 @@
+// Code generated by gen_reqtypes.go; DO NOT EDIT.
+
+package types
+
+// RequestType is the value of Request.Type.
+type RequestType string
+
+const (
+	ReqGetData        RequestType = "GET_DATA"        // fetch (and claim, if unowned) the chunk a player is in
+	ReqMovePlayer     RequestType = "MOVE_PLAYER"     // update a player's position within its chunk
+	ReqGetUpdates     RequestType = "GET_UPDATES"     // poll the state of a player's chunk
+	ReqDltPlayer      RequestType = "DLT_PLAYER"      // remove a player who is leaving
+	ReqResume         RequestType = "RESUME"          // reattach a restarted client to its player with the session token from /join
+	ReqSync           RequestType = "SYNC"            // sample the server clock and world tick for the client's clock offset estimate
+	ReqTelemetry      RequestType = "TELEMETRY"       // report the client's RTT, loss and redirect statistics for QoS decisions
+	ReqChat           RequestType = "CHAT"            // send a text message to the players around, and read the chunk's recent messages
+	ReqWhisper        RequestType = "WHISPER"         // send a text message to one player, relayed to whichever server holds them
+	ReqSpectate       RequestType = "SPECTATE"        // watch a chunk's changes as they happen without being a player in it
+	ReqAck            RequestType = "ACK"             // acknowledge a pushed chunk version, so later pushes are diffed from it
+	ReqShoot          RequestType = "SHOOT"           // fire a projectile the server simulates and hit-tests against players
+	ReqPickup         RequestType = "PICKUP"          // take an item lying near the player into their inventory
+	ReqDrop           RequestType = "DROP"            // leave items from the player's inventory in their chunk
+	ReqUse            RequestType = "USE"             // use up one item from the player's inventory
+	ReqAddCube        RequestType = "ADD_CUBE"        // place a cube in a chunk
+	ReqDltCube        RequestType = "DLT_CUBE"        // remove a cube from a chunk
+	ReqAddCubes       RequestType = "ADD_CUBES"       // place several cubes in a chunk at once
+	ReqDltCubes       RequestType = "DLT_CUBES"       // remove several cubes from a chunk at once
+	ReqUndo           RequestType = "UNDO"            // revert the player's latest cube edit in a chunk
+	ReqUpdateData     RequestType = "UPDATE_DATA"     // overwrite a chunk with a newer copy
+	ReqTxBegin        RequestType = "TX_BEGIN"        // open a transaction of cube edits across chunks
+	ReqTxApply        RequestType = "TX_APPLY"        // add one chunk's cube edits to an open transaction
+	ReqTxCommit       RequestType = "TX_COMMIT"       // apply a transaction's edits to every chunk, or to none
+	ReqTxAbort        RequestType = "TX_ABORT"        // drop an open transaction
+	ReqFromCentral    RequestType = "FROM_CENTRAL"    // central asks the owner to hand a chunk over
+	ReqReadOnly       RequestType = "READ_ONLY"       // read a chunk without taking ownership
+	ReqMerge          RequestType = "MERGE"           // merge a migrating chunk into the new owner
+	ReqPlayerTransfer RequestType = "PLAYER_TRANSFER" // hand players' state to the new owner of their chunk
+	ReqSetReplicas    RequestType = "SET_REPLICAS"    // central names the read replicas of an owned chunk
+	ReqReplicaSync    RequestType = "REPLICA_SYNC"    // stream an owned chunk, or the cubes a replica's copy differs in, to one of its read replicas
+	ReqReplicaDigest  RequestType = "REPLICA_DIGEST"  // compare a read replica's copy of a chunk with the owner's by Merkle digest
+	ReqSplitChunk     RequestType = "SPLIT_CHUNK"     // central tells a server a chunk was split into four sub-chunks
+	ReqKickPlayer     RequestType = "KICK_PLAYER"     // central disconnects a player from this server
+	ReqGossip         RequestType = "GOSSIP"          // exchange membership, load and chunk ownership hints with a peer
+	ReqTxPrepare      RequestType = "TX_PREPARE"      // check a transaction's edits on owned chunks and lock them
+	ReqTxFinish       RequestType = "TX_FINISH"       // commit or abort a prepared transaction
+	ReqAdoptChunk     RequestType = "ADOPT_CHUNK"     // central hands a server a chunk: a dead owner's, or a new one in its region
+	ReqLocatePlayer   RequestType = "LOCATE_PLAYER"   // central asks which server holds a resuming player, arming their session token there
+	ReqAnnounce       RequestType = "ANNOUNCE"        // central has a server push an announcement to every player connected to it
+	ReqPartyUpdate    RequestType = "PARTY_UPDATE"    // central tells every server a party's new member list (empty when disbanded)
+	ReqDamage         RequestType = "DAMAGE"          // take Amount off a player's health for Reason, killing them at 0
+	ReqHeal           RequestType = "HEAL"            // give a living player back Amount of health, up to full
+	ReqMatch          RequestType = "MATCH"           // central has the server hosting a match seed its chunks, and every server tell the players in it
+	ReqOwnerChanged   RequestType = "OWNER_CHANGED"   // central tells every server a chunk has a new owner, for their ownership caches
+	ReqGetChunk       RequestType = "GET_CHUNK"       // ask central who owns a chunk
+	ReqJoin           RequestType = "JOIN"            // ask central which server a new player should use
+)
+
+// AllRequestTypes lists every request type in declaration order.
+var AllRequestTypes = []RequestType{
+	ReqGetData,
+	ReqMovePlayer,
+	ReqGetUpdates,
+	ReqDltPlayer,
+	ReqResume,
+	ReqSync,
+	ReqTelemetry,
+	ReqChat,
+	ReqWhisper,
+	ReqSpectate,
+	ReqAck,
+	ReqShoot,
+	ReqPickup,
+	ReqDrop,
+	ReqUse,
+	ReqAddCube,
+	ReqDltCube,
+	ReqAddCubes,
+	ReqDltCubes,
+	ReqUndo,
+	ReqUpdateData,
+	ReqTxBegin,
+	ReqTxApply,
+	ReqTxCommit,
+	ReqTxAbort,
+	ReqFromCentral,
+	ReqReadOnly,
+	ReqMerge,
+	ReqPlayerTransfer,
+	ReqSetReplicas,
+	ReqReplicaSync,
+	ReqReplicaDigest,
+	ReqSplitChunk,
+	ReqKickPlayer,
+	ReqGossip,
+	ReqTxPrepare,
+	ReqTxFinish,
+	ReqAdoptChunk,
+	ReqLocatePlayer,
+	ReqAnnounce,
+	ReqPartyUpdate,
+	ReqDamage,
+	ReqHeal,
+	ReqMatch,
+	ReqOwnerChanged,
+	ReqGetChunk,
+	ReqJoin,
+}
+
+// GameServerRequestTypes are the types the game server dispatches over UDP.
+var GameServerRequestTypes = []RequestType{
+	ReqGetData,
+	ReqMovePlayer,
+	ReqGetUpdates,
+	ReqDltPlayer,
+	ReqResume,
+	ReqSync,
+	ReqTelemetry,
+	ReqChat,
+	ReqWhisper,
+	ReqSpectate,
+	ReqAck,
+	ReqShoot,
+	ReqPickup,
+	ReqDrop,
+	ReqUse,
+	ReqAddCube,
+	ReqDltCube,
+	ReqAddCubes,
+	ReqDltCubes,
+	ReqUndo,
+	ReqUpdateData,
+	ReqTxBegin,
+	ReqTxApply,
+	ReqTxCommit,
+	ReqTxAbort,
+	ReqFromCentral,
+	ReqReadOnly,
+	ReqMerge,
+	ReqPlayerTransfer,
+	ReqSetReplicas,
+	ReqReplicaSync,
+	ReqReplicaDigest,
+	ReqSplitChunk,
+	ReqKickPlayer,
+	ReqGossip,
+	ReqTxPrepare,
+	ReqTxFinish,
+	ReqAdoptChunk,
+	ReqLocatePlayer,
+	ReqAnnounce,
+	ReqPartyUpdate,
+	ReqDamage,
+	ReqHeal,
+	ReqMatch,
+	ReqOwnerChanged,
+}
+
+// Valid reports whether t is a known request type.
+func (t RequestType) Valid() bool {
+	switch t {
+	case ReqGetData, ReqMovePlayer, ReqGetUpdates, ReqDltPlayer, ReqResume, ReqSync, ReqTelemetry, ReqChat, ReqWhisper, ReqSpectate, ReqAck, ReqShoot, ReqPickup, ReqDrop, ReqUse, ReqAddCube, ReqDltCube, ReqAddCubes, ReqDltCubes, ReqUndo, ReqUpdateData, ReqTxBegin, ReqTxApply, ReqTxCommit, ReqTxAbort, ReqFromCentral, ReqReadOnly, ReqMerge, ReqPlayerTransfer, ReqSetReplicas, ReqReplicaSync, ReqReplicaDigest, ReqSplitChunk, ReqKickPlayer, ReqGossip, ReqTxPrepare, ReqTxFinish, ReqAdoptChunk, ReqLocatePlayer, ReqAnnounce, ReqPartyUpdate, ReqDamage, ReqHeal, ReqMatch, ReqOwnerChanged, ReqGetChunk, ReqJoin:
+		return true
+	}
+	return false
+}
+
+// IsPeer reports whether t is sent between servers rather than by a player.
+func (t RequestType) IsPeer() bool {
+	switch t {
+	case ReqFromCentral, ReqReadOnly, ReqMerge, ReqPlayerTransfer, ReqSetReplicas, ReqReplicaSync, ReqReplicaDigest, ReqSplitChunk, ReqKickPlayer, ReqGossip, ReqTxPrepare, ReqTxFinish, ReqAdoptChunk, ReqLocatePlayer, ReqAnnounce, ReqPartyUpdate, ReqDamage, ReqHeal, ReqMatch, ReqOwnerChanged:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package types
+
+// ===================== Chunk splits =====================
+
+// An overcrowded chunk is split quadtree style into four sub-chunks of half
+// the edge, which can then be owned by different servers like any chunk. A
+// sub-chunk can be split again, down to MaxChunkLevel. The central server
+// keeps the set of split chunks; everyone else learns it from /join, GET_DATA
+// replies and ERR_CHUNK_SPLIT, and turns positions into chunk IDs with
+// ResolveChunk.
+
+// MaxChunkLevel bounds how deep a chunk can be split.
+const MaxChunkLevel = 3
+
+// ID returns the chunk's ID.
+func (c Chunk) ID() ChunkID {
+	return ChunkID{World: c.World, IDX: c.IDX, IDY: c.IDY, Level: c.Level}
+}
+
+// Edge is the edge length of the chunk in a world with the given chunk size.
+func (id ChunkID) Edge(chunkSize int) int {
+	return chunkSize >> id.Level
+}
+
+// CanSplit reports whether id may be split further.
+func (id ChunkID) CanSplit(chunkSize int) bool {
+	return id.Level < MaxChunkLevel && id.Edge(chunkSize) >= 2
+}
+
+// Children returns the four sub-chunks id splits into.
+func (id ChunkID) Children() [4]ChunkID {
+	w, x, y, level := id.World, 2*id.IDX, 2*id.IDY, id.Level+1
+	return [4]ChunkID{{w, x, y, level}, {w, x + 1, y, level}, {w, x, y + 1, level}, {w, x + 1, y + 1, level}}
+}
+
+// Child returns the sub-chunk of id containing (x, y), clamping the position
+// into id first so a neighbouring position picks the nearest sub-chunk.
+func (id ChunkID) Child(x, y, chunkSize int) ChunkID {
+	edge := id.Edge(chunkSize)
+	x = clamp(x, id.IDX*edge, (id.IDX+1)*edge-1)
+	y = clamp(y, id.IDY*edge, (id.IDY+1)*edge-1)
+	half := edge / 2
+	return ChunkID{World: id.World, IDX: floorDiv(x, half), IDY: floorDiv(y, half), Level: id.Level + 1}
+}
+
+// ResolveChunk returns the chunk containing position (x, y) of world,
+// descending through the chunks in split.
+func ResolveChunk(world string, x, y, chunkSize int, split map[ChunkID]bool) ChunkID {
+	id := ChunkOf(world, x, y, chunkSize)
+	for split[id] && id.CanSplit(chunkSize) {
+		id = id.Child(x, y, chunkSize)
+	}
+	return id
+}
+
+func clamp(v, lo, hi int) int {
+	if v < lo {
+		return lo
+	}
+	if v > hi {
+		return hi
+	}
+	return v
+}
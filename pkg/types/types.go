@@ -0,0 +1,783 @@
+// Package types holds the wire structs shared by the game server, central
+// server, gateway and clients.
+package types
+
+//go:generate go run gen_reqtypes.go
+//go:generate go run gen_codecs.go
+
+import "time"
+
+// DefaultChunkSize is the chunk edge length used when a world does not
+// configure its own (see WorldConfig).
+const DefaultChunkSize = 32
+
+// ChunkOf returns the chunk containing position (x, y) of world. Every
+// component derives chunk IDs with it so a negative coordinate lands in chunk
+// -1, not 0.
+func ChunkOf(world string, x, y, chunkSize int) ChunkID {
+	return ChunkID{World: world, IDX: floorDiv(x, chunkSize), IDY: floorDiv(y, chunkSize)}
+}
+
+func floorDiv(a, b int) int {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+type GameData struct {
+	Chunk Chunk `json:"chunk"`
+}
+type Player struct {
+	ID        string  `json:"id"`
+	PosX      int     `json:"posx"`
+	PosY      int     `json:"posy"`
+	ServerIP  string  `json:"server_ip"`
+	AOIRadius int     `json:"aoi_radius"`
+	ChunkID   ChunkID `json:"chunk_id"`
+	// World is the world the player is in (see ChunkID.World).
+	World string `json:"world,omitempty"`
+	// Motion as the server measured it between the player's last two moves,
+	// for clients to interpolate and extrapolate remote players: velocity
+	// in cells per second, heading in radians (0 is +x, counter-clockwise),
+	// and the server time of the last move in Unix milliseconds.
+	VelX      float64 `json:"vel_x,omitempty"`
+	VelY      float64 `json:"vel_y,omitempty"`
+	Heading   float64 `json:"heading,omitempty"`
+	UpdatedMs int64   `json:"updated_ms,omitempty"`
+	// Health is what the server holds the player to have, full when they
+	// join; 0 only while dead. A dead player respawns at RespawnMs (Unix
+	// milliseconds, server time), which is 0 while alive.
+	Health    int   `json:"health,omitempty"`
+	RespawnMs int64 `json:"respawn_ms,omitempty"`
+	// Inventory is what the player carries, one stack per item kind, in
+	// kind order. Like Health it is held by the player's server.
+	Inventory []ItemStack `json:"inventory,omitempty"`
+	// HLC is when the player's server last updated them.
+	HLC HLC `json:"hlc,omitempty"`
+}
+
+// ItemStack is Count items of one Kind.
+type ItemStack struct {
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+}
+
+type Cube struct {
+	ID     string `json:"cube_id"`
+	X      int    `json:"x"`
+	Z      int    `json:"z"`
+	Height int    `json:"height"`
+	Color  string `json:"color"`
+	// HLC is when the owner placed or last changed the cube.
+	HLC HLC `json:"hlc,omitempty"`
+}
+
+// HLC is a hybrid logical clock timestamp (see pkg/hlc): wall time in Unix
+// milliseconds in the upper 48 bits and a counter ordering events within
+// one millisecond in the lower 16, so timestamps compare as integers. The
+// server stamps cube edits and player updates with it; where two copies of
+// a chunk meet, the later stamp wins. 0 is never stamped, and loses.
+type HLC uint64
+
+// Tombstone remembers when a cube was removed, so a copy of the chunk from
+// before that cannot bring it back.
+type Tombstone struct {
+	ID  string `json:"cube_id"`
+	HLC HLC    `json:"hlc"`
+}
+
+type Chunk struct {
+	World      string   `json:"world,omitempty"` // see ChunkID.World
+	IDX        int      `json:"id_x"`
+	IDY        int      `json:"id_y"`
+	Level      int      `json:"level,omitempty"` // see ChunkID.Level
+	ServerIP   string   `json:"server_ip"`
+	Data       string   `json:"data"`
+	PlayerList []Player `json:"player_list"`
+	IsDirty    bool     `json:"is_dirty"`
+	Cells      []Cube   `json:"cells"`
+	NPCs       []NPC    `json:"npcs,omitempty"`
+	Items      []Item   `json:"items,omitempty"`
+	// Tombstones are the chunk's latest cube removals. Older ones are
+	// forgotten, raising TombstoneFloor: a cube stamped no later than it
+	// that a copy lacks may have been removed.
+	Tombstones     []Tombstone `json:"tombstones,omitempty"`
+	TombstoneFloor HLC         `json:"tombstone_floor,omitempty"`
+}
+
+// NPC is a server-owned entity living in a chunk, moved each step by the
+// behavior its Kind names. It travels with its chunk.
+type NPC struct {
+	ID   string `json:"id"`
+	Kind string `json:"kind"` // wanderer, mob, or a behavior a server registered
+	PosX int    `json:"posx"`
+	PosY int    `json:"posy"`
+	// Target is the player the NPC is after, for behaviors that chase.
+	Target string `json:"target,omitempty"`
+}
+
+// Item is a stack of items lying in a chunk, dropped by a player and
+// waiting to be picked up. It travels with its chunk.
+type Item struct {
+	ID    string `json:"id"`
+	Kind  string `json:"kind"`
+	Count int    `json:"count"`
+	PosX  int    `json:"posx"`
+	PosY  int    `json:"posy"`
+}
+
+type ChunkID struct {
+	// World names the world the chunk belongs to; chunks of different
+	// worlds never meet. DefaultWorld, "", is the shared world every player
+	// starts in.
+	World string `json:"world,omitempty"`
+	IDX   int    `json:"id_x"`
+	IDY   int    `json:"id_y"`
+	// Level is 0 for a whole chunk and n for a sub-chunk left by n splits;
+	// IDX and IDY count in units of the sub-chunk's edge (see Edge).
+	Level int `json:"level,omitempty"`
+}
+
+type Request struct {
+	Type        RequestType     `json:"type"`
+	ChunkID     ChunkID         `json:"chunk_id"`
+	CallerIP    string          `json:"caller_ip"`
+	Player      Player          `json:"player"`
+	IsPeerReq   bool            `json:"is_peer_req"`
+	Chunk       Chunk           `json:"chunk"`
+	IsChunkNew  bool            `json:"is_chunk_new"`
+	PlayerCount int             `json:"player_count"`
+	MinLead     int             `json:"min_lead,omitempty"` // FROM_CENTRAL: players the caller must lead the owner by
+	PlayerID    string          `json:"player_id"`
+	Cube        Cube            `json:"cube"`
+	CubeID      string          `json:"cube_id"`
+	Cubes       []Cube          `json:"cubes,omitempty"`    // ADD_CUBES
+	CubeIDs     []string        `json:"cube_ids,omitempty"` // DLT_CUBES
+	TraceID     string          `json:"trace_id,omitempty"`
+	Reason      string          `json:"reason,omitempty"`
+	Handoffs    []PlayerHandoff `json:"handoffs,omitempty"` // PLAYER_TRANSFER
+	Replicas    []string        `json:"replicas,omitempty"` // SET_REPLICAS
+	Members     []MemberState   `json:"members,omitempty"`  // GOSSIP
+	TxID        string          `json:"tx_id,omitempty"`    // TX_*
+	Edits       []ChunkEdit     `json:"edits,omitempty"`    // TX_PREPARE
+	Commit      bool            `json:"commit,omitempty"`   // TX_FINISH
+	// Since is the version of the chunk the sender already has; READ_ONLY
+	// and GET_UPDATES then answer with only what changed (OK_DELTA), and
+	// GET_DATA and READ_ONLY with no chunk at all if it is unchanged
+	// (OK_NOT_MODIFIED). With GET_UPDATES, SPECTATE and ACK it also
+	// acknowledges that version, which later updates are diffed from.
+	Since *ChunkVersion `json:"since,omitempty"`
+	// OwnerHint marks a MERGE sent on a gossiped ownership hint rather than
+	// central's word; a server that does not own the chunk refuses it.
+	OwnerHint bool `json:"owner_hint,omitempty"`
+	// BareChunk marks a Chunk that is no whole chunk but players to merge
+	// into the chunk of ChunkID, whose ID it takes (see NormalizeChunk).
+	BareChunk bool `json:"bare_chunk,omitempty"`
+	// AcceptEncoding lists the payload encodings the sender can decode,
+	// comma-separated and best first (zstd,gzip).
+	AcceptEncoding string `json:"accept_encoding,omitempty"`
+	// SessionToken is the token /join issued the player (RESUME,
+	// LOCATE_PLAYER).
+	SessionToken string `json:"session_token,omitempty"`
+	// InputSeq numbers a client's movement inputs in the order issued
+	// (MOVE_PLAYER); see Response.AckSeq.
+	InputSeq uint64 `json:"input_seq,omitempty"`
+	// Stats is the client's view of its connection (TELEMETRY).
+	Stats *ClientStats `json:"stats,omitempty"`
+	// Text is a chat message to send, to PlayerID for WHISPER; ChatSince
+	// asks for the chunk's messages after that sequence number (CHAT).
+	Text      string `json:"text,omitempty"`
+	ChatSince uint64 `json:"chat_since,omitempty"`
+	// Announcement is what central broadcasts (ANNOUNCE).
+	Announcement *Announcement `json:"announcement,omitempty"`
+	// Party is a party's new member list (PARTY_UPDATE).
+	Party *Party `json:"party,omitempty"`
+	// Match is a match central has formed (MATCH).
+	Match *Match `json:"match,omitempty"`
+	// Unsubscribe stops watching ChunkID (SPECTATE).
+	Unsubscribe bool `json:"unsubscribe,omitempty"`
+	// Shot is the projectile to fire (SHOOT).
+	Shot *Shot `json:"shot,omitempty"`
+	// ItemID is the item to pick up (PICKUP); Item the kind and count to
+	// drop (DROP) or the kind to use (USE).
+	ItemID string     `json:"item_id,omitempty"`
+	Item   *ItemStack `json:"item,omitempty"`
+	// Amount is the damage or healing to apply to PlayerID (DAMAGE, HEAL),
+	// for the cause in Reason.
+	Amount int `json:"amount,omitempty"`
+	// Owner is ChunkID's new owner (OWNER_CHANGED, and central's
+	// /sentchunk, where it defaults to CallerIP).
+	Owner string `json:"owner,omitempty"`
+	// Digest is the owner's digest of ChunkID: just its root to ask a
+	// replica whether its copy matches (REPLICA_DIGEST), or in full with a
+	// REPLICA_SYNC that only carries the cubes of Buckets, the leaves the
+	// replica's copy differs in.
+	Digest  *ChunkDigest `json:"digest,omitempty"`
+	Buckets []int        `json:"buckets,omitempty"`
+	// CallID tells apart requests sent over one shared socket; the reply
+	// carries it back (see netproto.Mux).
+	CallID uint64 `json:"call_id,omitempty"`
+	// Epoch is the sender's incarnation at central, from its last /rejoin
+	// (central's /heartbeat), or that of the server a chunk is handed to
+	// (ADOPT_CHUNK).
+	Epoch uint64 `json:"epoch,omitempty"`
+}
+
+type Response struct {
+	Success     bool          `json:"success"`
+	Chunk       Chunk         `json:"chunk"`
+	Message     string        `json:"message"`
+	GameData    GameData      `json:"game_data"`
+	NewIP       string        `json:"new_ip"`
+	PlayerCount int           `json:"player_count"`
+	ChunkSize   int           `json:"chunk_size,omitempty"`
+	TraceID     string        `json:"trace_id,omitempty"`
+	Code        string        `json:"code,omitempty"`
+	RedirectIP  string        `json:"redirect_ip,omitempty"`
+	Supported   []RequestType `json:"supported,omitempty"`
+	// ChunkID is the chunk the server placed the player in, derived from
+	// its position (GET_DATA, MOVE_PLAYER).
+	ChunkID *ChunkID `json:"chunk_id,omitempty"`
+	// Splits lists every chunk the cluster has split into sub-chunks (/join,
+	// GET_DATA); clients resolve positions with ResolveChunk.
+	Splits []ChunkID `json:"splits,omitempty"`
+	// With ERR_CHUNK_FULL: the player's place in the chunk's queue, when to
+	// ask again, and a nearby chunk with room, if one is known. RetryAfterMs
+	// also comes with ERR_CENTRAL_UNAVAILABLE, ERR_RATE_LIMITED and ERR_DEAD.
+	QueuePosition int      `json:"queue_position,omitempty"`
+	RetryAfterMs  int64    `json:"retry_after_ms,omitempty"`
+	Alternative   *ChunkID `json:"alternative,omitempty"`
+	// Replicas are servers that also answer GET_UPDATES and READ_ONLY for
+	// the chunk; writes still go to the owner (GET_DATA, MOVE_PLAYER).
+	Replicas []string `json:"replicas,omitempty"`
+	// Members is the responder's view of the cluster (GOSSIP).
+	Members []MemberState `json:"members,omitempty"`
+	// TxID names the transaction TX_BEGIN opened.
+	TxID string `json:"tx_id,omitempty"`
+	// SessionToken lets the player resume after a restart (/join).
+	SessionToken string `json:"session_token,omitempty"`
+	// Seal is the key the player's UDP traffic must be sealed with, when the
+	// cluster has a seal secret.
+	Seal *SessionSeal `json:"seal,omitempty"`
+	// Player is the state a resumed player picks up from (/join,
+	// LOCATE_PLAYER, RESUME), or where the player authoritatively is after
+	// the input AckSeq.
+	Player *Player `json:"player,omitempty"`
+	// Digest is a replica's digest of its copy, when it does not match the
+	// root a REPLICA_DIGEST asked about.
+	Digest *ChunkDigest `json:"digest,omitempty"`
+	// AckSeq is the last of the player's numbered inputs the server has
+	// processed, applied or refused (Request.InputSeq).
+	AckSeq uint64 `json:"ack_seq,omitempty"`
+	// Chat carries chat messages, oldest first: those a CHAT asked for, or
+	// the one a CodeChat or CodeWhisper push delivers.
+	Chat []ChatMessage `json:"chat,omitempty"`
+	// Pushes are the messages a CodeBatch push carries, most urgent first.
+	Pushes []Response `json:"pushes,omitempty"`
+	// Announcement is what a CodeAnnounce push delivers.
+	Announcement *Announcement `json:"announcement,omitempty"`
+	// Profile is the saved profile a player starting a new session at /join
+	// picks up from, if they have one.
+	Profile *PlayerProfile `json:"profile,omitempty"`
+	// Party is the player's party (central's /party endpoints).
+	Party *Party `json:"party,omitempty"`
+	// PartyMembers are the player's party members wherever they are, sent
+	// with GET_UPDATES whatever chunk they are in.
+	PartyMembers []Player `json:"party_members,omitempty"`
+	// Match is the player's match, from central's /match endpoints or
+	// pushed with CodeMatch once it is formed.
+	Match *Match `json:"match,omitempty"`
+	// ProjectileID names the projectile a SHOOT fired.
+	ProjectileID string `json:"projectile_id,omitempty"`
+	// Hit is what a CodeHit push reports.
+	Hit *HitEvent `json:"hit,omitempty"`
+	// Life is what a CodeLife push reports.
+	Life *LifeEvent `json:"life,omitempty"`
+	// Awards are achievements central's reply to a metrics report says the
+	// server's players earned; a CodeAchievement push carries the player's.
+	Awards []Award `json:"awards,omitempty"`
+	// Worlds is central's world registry, sent back with every metrics
+	// report so servers learn each world's Consistency.
+	Worlds []World `json:"worlds,omitempty"`
+	// ItemID names the item a DROP left in the chunk.
+	ItemID string `json:"item_id,omitempty"`
+	// Version is the chunk version a GET_DATA, READ_ONLY or GET_UPDATES
+	// reply brings the reader to, whole (Chunk, GameData) or as a Delta.
+	Version *ChunkVersion `json:"version,omitempty"`
+	Delta   *ChunkDelta   `json:"delta,omitempty"`
+	// UpdateMs is how often the chunk's changes are currently pushed
+	// (SPECTATE, PUSH_SPECTATE) or worth polling for (GET_UPDATES); it
+	// follows the chunk's activity, and clients size their interpolation
+	// windows to it.
+	UpdateMs int64 `json:"update_ms,omitempty"`
+	// ServerTimeMs is the responder's clock when it replied, in Unix
+	// milliseconds, to line Player.UpdatedMs up with the client's clock.
+	ServerTimeMs int64 `json:"server_time_ms,omitempty"`
+	// Tick is the responder's world tick when it replied; TickMs, sent
+	// with SYNC, is how long a tick lasts.
+	Tick   int64 `json:"tick,omitempty"`
+	TickMs int   `json:"tick_ms,omitempty"`
+	// Encoding is set when Chunk and GameData travel compressed in Payload.
+	Encoding string `json:"encoding,omitempty"`
+	Payload  []byte `json:"payload,omitempty"`
+	// CallID is the Request.CallID this replies to.
+	CallID uint64 `json:"call_id,omitempty"`
+	// Epoch is the server's new incarnation (/rejoin); LeaseMs how long
+	// after a heartbeat it may go on writing to its chunks without another
+	// (/rejoin, /heartbeat), or 0 if central never fails them over.
+	Epoch   uint64 `json:"epoch,omitempty"`
+	LeaseMs int64  `json:"lease_ms,omitempty"`
+}
+
+// Response codes. OK_* codes accompany Success: true (or a benign false, as
+// with OK_NOT_MODIFIED); ERR_* codes explain a failure; PUSH_* codes mark
+// messages a server sends unasked. Clients branch on
+// Code and read the server to talk to from RedirectIP, never from Message.
+const (
+	CodeOK                 = "OK"
+	CodeRedirect           = "OK_REDIRECT"
+	CodeNotModified        = "OK_NOT_MODIFIED"
+	CodeDelta              = "OK_DELTA" // only the changes since Request.Since, in Delta
+	CodeNotOwner           = "ERR_NOT_OWNER"
+	CodeChunkMigrating     = "ERR_CHUNK_MIGRATING"
+	CodeRateLimited        = "ERR_RATE_LIMITED"
+	CodeBadRequest         = "ERR_BAD_REQUEST"
+	CodeForbidden          = "ERR_FORBIDDEN" // a request only servers may send, from someone else
+	CodeFenced             = "ERR_FENCED"    // central declared the server dead, or it is a stale incarnation; it must rejoin
+	CodeUnsupported        = "ERR_UNSUPPORTED"
+	CodeCentralUnavailable = "ERR_CENTRAL_UNAVAILABLE" // ask again after RetryAfterMs
+	CodeKicked             = "ERR_KICKED"
+	CodeBanned             = "ERR_BANNED"
+	CodeNotFound           = "ERR_NOT_FOUND"
+	CodeInternal           = "ERR_INTERNAL"
+	CodeChunkSplit         = "ERR_CHUNK_SPLIT"
+	CodeChunkFull          = "ERR_CHUNK_FULL"
+	CodeChunkLocked        = "ERR_CHUNK_LOCKED" // held by a transaction being committed; retry shortly
+	CodeTxAborted          = "ERR_TX_ABORTED"
+	CodeBadSession         = "ERR_BAD_SESSION"  // RESUME with a token that is not (or no longer) armed
+	CodeChat               = "PUSH_CHAT"        // a chat message pushed unasked, not a reply
+	CodeAnnounce           = "PUSH_ANNOUNCE"    // an announcement from central, pushed unasked
+	CodeWhisper            = "PUSH_WHISPER"     // a whisper to the player, pushed unasked
+	CodeMatch              = "PUSH_MATCH"       // the player's match is ready, pushed unasked
+	CodeSpectate           = "PUSH_SPECTATE"    // a change to a chunk being spectated, pushed unasked
+	CodeBatch              = "PUSH_BATCH"       // several pushes to one client in one datagram, in Pushes
+	CodeHit                = "PUSH_HIT"         // a projectile hit a player nearby, in Hit
+	CodeLife               = "PUSH_LIFE"        // a player nearby was damaged, healed, died or respawned, in Life
+	CodeAchievement        = "PUSH_ACHIEVEMENT" // the player earned achievements, in Awards
+	CodeDead               = "ERR_DEAD"         // the player is dead until RetryAfterMs has passed
+	CodeRespawned          = "ERR_RESPAWNED"    // the player respawned elsewhere, at Player; move from there
+	CodeUnreplicated       = "ERR_UNREPLICATED" // applied by the owner, but no replica acknowledged it in time
+)
+
+// ChatMessage is one line of chat, numbered per chunk by the server that
+// owns it, or a whisper to one player.
+type ChatMessage struct {
+	Seq     uint64  `json:"seq"`
+	From    string  `json:"from"`
+	ChunkID ChunkID `json:"chunk_id"`
+	Text    string  `json:"text"`
+	SentMs  int64   `json:"sent_ms"`
+	// To is set on a whisper, which is not numbered (Seq 0).
+	To string `json:"to,omitempty"`
+}
+
+// Announcement is a message central sends to every player, such as a
+// maintenance warning or a world reset.
+type Announcement struct {
+	ID     string `json:"id"`
+	Kind   string `json:"kind"` // "info", "maintenance", "event", "reset", ...
+	Text   string `json:"text"`
+	SentMs int64  `json:"sent_ms"`
+}
+
+// Party is a group of players who see each other wherever they are. Central
+// keeps parties; the Leader is whoever created it, or the longest-standing
+// member once they leave.
+type Party struct {
+	ID      string   `json:"id"`
+	Leader  string   `json:"leader"`
+	Members []string `json:"members"`
+}
+
+// Match is a game central's matchmaking formed from its queue for Mode:
+// Teams of player IDs, played on Server in a World of its own made of
+// Chunks, with team i spawning at Spawns[i].
+type Match struct {
+	ID       string       `json:"id"`
+	Mode     string       `json:"mode"`
+	Teams    [][]string   `json:"teams"`
+	Server   string       `json:"server"`
+	World    string       `json:"world"`
+	Chunks   []ChunkID    `json:"chunks"`
+	Spawns   []SpawnPoint `json:"spawns"`
+	FormedMs int64        `json:"formed_ms"`
+}
+
+type SpawnPoint struct {
+	PosX int `json:"pos_x"`
+	PosY int `json:"pos_y"`
+}
+
+// Team returns the team player_id plays in, or -1.
+func (m Match) Team(player_id string) int {
+	for i, team := range m.Teams {
+		for _, member := range team {
+			if member == player_id {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// WorldConfig describes the experiment arm a game server is running: which
+// world it belongs to and the chunk size / tick rate that world uses.
+type WorldConfig struct {
+	Name      string `json:"name"`
+	ChunkSize int    `json:"chunk_size"`
+	TickMs    int    `json:"tick_ms"`
+}
+
+// WorldMetrics is the periodic report a game server sends to the central
+// server so worlds with different configs can be compared side by side.
+type WorldMetrics struct {
+	World       WorldConfig `json:"world"`
+	ServerIP    string      `json:"server_ip"`
+	Ticks       int64       `json:"ticks"`
+	Requests    int64       `json:"requests"`
+	AvgHandleUs int64       `json:"avg_handle_us"`
+	MaxHandleUs int64       `json:"max_handle_us"`
+	ChunksOwned int         `json:"chunks_owned"`
+	Players     int         `json:"players"`
+	Migrations  int64       `json:"migrations"`
+	ReportedAt  time.Time   `json:"reported_at"`
+	// HotChunks are the server's most crowded owned chunks, busiest first;
+	// central gives read replicas to the ones over its threshold.
+	HotChunks []ChunkLoad `json:"hot_chunks,omitempty"`
+	// ChunkPlayers counts the players in every owned chunk that has any,
+	// for central's world map.
+	ChunkPlayers []ChunkLoad `json:"chunk_players,omitempty"`
+	// ClientRTTMs and ClientLoss average what the server's players last
+	// reported with TELEMETRY.
+	ClientRTTMs float64 `json:"client_rtt_ms,omitempty"`
+	ClientLoss  float64 `json:"client_loss,omitempty"`
+	// PlayerStats is what each player did on the server since its last
+	// report; central adds it to their profile's totals.
+	PlayerStats []PlayerStats `json:"player_stats,omitempty"`
+}
+
+// ClientStats is what a client measured of its requests: attempts sent,
+// replies received and attempts that timed out, redirects followed, and
+// round-trip times of the replies.
+type ClientStats struct {
+	Requests  int64   `json:"requests"`
+	Replies   int64   `json:"replies"`
+	Lost      int64   `json:"lost"`
+	Redirects int64   `json:"redirects"`
+	LossRate  float64 `json:"loss_rate"`
+	RTTLastMs float64 `json:"rtt_last_ms"`
+	RTTAvgMs  float64 `json:"rtt_avg_ms"`
+	RTTMinMs  float64 `json:"rtt_min_ms"`
+	RTTMaxMs  float64 `json:"rtt_max_ms"`
+}
+
+type ChunkLoad struct {
+	ChunkID ChunkID `json:"chunk_id"`
+	Players int     `json:"players"`
+}
+
+// WorldMap is central's view of who owns what, served on /map: every
+// assigned chunk of a world with its owner and the players its owner last
+// reported in it, and the latest changes of owner.
+type WorldMap struct {
+	World      string      `json:"world"`
+	ChunkSize  int         `json:"chunk_size"`
+	Servers    []string    `json:"servers"`
+	Chunks     []MapChunk  `json:"chunks"`
+	Migrations []Migration `json:"migrations"`
+}
+
+type MapChunk struct {
+	ChunkID ChunkID `json:"chunk_id"`
+	Owner   string  `json:"owner"`
+	Players int     `json:"players"`
+}
+
+// ChunkAssignment is central's record of one assigned chunk, served on
+// /chunks.
+type ChunkAssignment struct {
+	ChunkID  ChunkID  `json:"chunk_id"`
+	Owner    string   `json:"owner"`
+	Replicas []string `json:"replicas,omitempty"`
+	Players  int      `json:"players"` // as the owner last reported
+	// LeaseExpiresMs is when central declares the owner dead and fails the
+	// chunk over unless the owner reports again first; 0 if failover is
+	// off or the owner has not reported yet.
+	LeaseExpiresMs int64 `json:"lease_expires_ms,omitempty"`
+	// MigratedMs is when the chunk last changed owner, its first
+	// assignment included, as far as the central node answering knows.
+	MigratedMs int64 `json:"migrated_ms,omitempty"`
+}
+
+// ChunkPage is one page of /chunks.
+type ChunkPage struct {
+	Chunks     []ChunkAssignment `json:"chunks"`
+	Total      int               `json:"total"`                 // assignments matching the filter, on all pages
+	NextOffset int               `json:"next_offset,omitempty"` // 0 on the last page
+}
+
+// ServerStatus is central's view of one game server, served on /servers.
+type ServerStatus struct {
+	Server     string    `json:"server"`
+	Alive      bool      `json:"alive"`
+	Chunks     int       `json:"chunks"`
+	Players    int       `json:"players"`
+	ReportedAt time.Time `json:"reported_at"`
+}
+
+// Migration is one change of a chunk's owner; From is empty for a chunk's
+// first owner.
+type Migration struct {
+	ChunkID ChunkID `json:"chunk_id"`
+	From    string  `json:"from,omitempty"`
+	To      string  `json:"to"`
+	AtMs    int64   `json:"at_ms"`
+}
+
+// Ban is one entry of the central server's banlist. A zero Until means the
+// ban never expires.
+type Ban struct {
+	PlayerID string    `json:"player_id"`
+	Reason   string    `json:"reason"`
+	BannedAt time.Time `json:"banned_at"`
+	Until    time.Time `json:"until,omitempty"`
+}
+
+func (b Ban) Active(now time.Time) bool {
+	return b.Until.IsZero() || now.Before(b.Until)
+}
+
+// PlayerProfile is what central keeps of a player between sessions. Player
+// is them as they last left a game server: position, world, health and
+// inventory. Sessions counts their joins and PlaySeconds the time from each
+// join to the next save.
+type PlayerProfile struct {
+	PlayerID    string    `json:"player_id"`
+	Player      Player    `json:"player"`
+	Sessions    int       `json:"sessions"`
+	PlaySeconds int64     `json:"play_seconds"`
+	LastServer  string    `json:"last_server,omitempty"`
+	SavedAt     time.Time `json:"saved_at"`
+	// JoinedAt is when the current session started, zero between sessions.
+	JoinedAt time.Time `json:"joined_at,omitempty"`
+	// Stats are the player's totals over every session.
+	Stats PlayerStats `json:"stats"`
+	// Achievements maps the ID of each achievement earned to when.
+	Achievements map[string]time.Time `json:"achievements,omitempty"`
+}
+
+// PlayerStats counts what a player did: cubes placed and removed, cells
+// walked, chunks entered, and players they killed and were killed by.
+type PlayerStats struct {
+	PlayerID      string  `json:"player_id,omitempty"`
+	CubesPlaced   int64   `json:"cubes_placed,omitempty"`
+	CubesRemoved  int64   `json:"cubes_removed,omitempty"`
+	Distance      float64 `json:"distance,omitempty"`
+	ChunksVisited int64   `json:"chunks_visited,omitempty"`
+	Kills         int64   `json:"kills,omitempty"`
+	Deaths        int64   `json:"deaths,omitempty"`
+}
+
+// Add adds other's counts to s.
+func (s *PlayerStats) Add(other PlayerStats) {
+	s.CubesPlaced += other.CubesPlaced
+	s.CubesRemoved += other.CubesRemoved
+	s.Distance += other.Distance
+	s.ChunksVisited += other.ChunksVisited
+	s.Kills += other.Kills
+	s.Deaths += other.Deaths
+}
+
+// Achievement is earned by a player the first time their total of Stat (a
+// leaderboard stat) reaches Goal.
+type Achievement struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	Description string  `json:"description,omitempty"`
+	Stat        string  `json:"stat"`
+	Goal        float64 `json:"goal"`
+}
+
+// Award is an achievement a player earned at EarnedMs.
+type Award struct {
+	PlayerID    string      `json:"player_id"`
+	Achievement Achievement `json:"achievement"`
+	EarnedMs    int64       `json:"earned_ms"`
+}
+
+// AchievementProgress is how far a player is towards an achievement:
+// Value of its Goal, and when they earned it if they have.
+type AchievementProgress struct {
+	Achievement Achievement `json:"achievement"`
+	Value       float64     `json:"value"`
+	EarnedAt    *time.Time  `json:"earned_at,omitempty"`
+}
+
+// LeaderboardEntry is one player's place on a Leaderboard.
+type LeaderboardEntry struct {
+	Rank     int     `json:"rank"`
+	PlayerID string  `json:"player_id"`
+	Value    float64 `json:"value"`
+}
+
+// Leaderboard ranks players by one of their PlayerStats, highest first.
+type Leaderboard struct {
+	Stat    string             `json:"stat"`
+	Entries []LeaderboardEntry `json:"entries"`
+}
+
+// PlayerHandoff is what a game server knows about a connected player. It is
+// sent ahead to the new owner of the player's chunk (PLAYER_TRANSFER) so the
+// player arrives warm rather than reconnecting from scratch.
+type PlayerHandoff struct {
+	Player   Player    `json:"player"`
+	ChunkID  ChunkID   `json:"chunk_id"`
+	LastSeen time.Time `json:"last_seen"`
+	Addr     string    `json:"addr,omitempty"` // last UDP address, for pushed notices
+}
+
+// Shot is a projectile a player fires with SHOOT, from their position along
+// (DirX, DirY). SeenMs is the server time of the world the shooter aimed at,
+// which is behind the present by what they render remote players with (see
+// client.InterpolationDelay); players are hit where they were then. 0 is
+// the present.
+type Shot struct {
+	DirX   float64 `json:"dir_x"`
+	DirY   float64 `json:"dir_y"`
+	SeenMs int64   `json:"seen_ms,omitempty"`
+}
+
+// HitEvent is a projectile hitting a player, pushed to the players and
+// spectators of the chunk it happened in and to the shooter.
+type HitEvent struct {
+	ProjectileID string  `json:"projectile_id"`
+	ShooterID    string  `json:"shooter_id"`
+	TargetID     string  `json:"target_id"`
+	ChunkID      ChunkID `json:"chunk_id"`
+	X            float64 `json:"x"`
+	Y            float64 `json:"y"`
+	Damage       int     `json:"damage"`
+	// Health is what the target has left; at 0 they died (see LifeEvent).
+	Health int `json:"health"`
+}
+
+// Life event kinds.
+const (
+	LifeDamage  = "damage"
+	LifeHeal    = "heal"
+	LifeDeath   = "death"
+	LifeRespawn = "respawn"
+)
+
+// LifeEvent is a change to a player's health, pushed to the players and
+// spectators of their chunk and to whoever caused it: damage or healing by
+// Amount, their death, and their respawn at (PosX, PosY) in ChunkID with
+// full health. SourceID is the player who caused it, if one did, and Reason
+// what did.
+type LifeEvent struct {
+	Kind      string  `json:"kind"`
+	PlayerID  string  `json:"player_id"`
+	SourceID  string  `json:"source_id,omitempty"`
+	Reason    string  `json:"reason,omitempty"`
+	Amount    int     `json:"amount,omitempty"`
+	Health    int     `json:"health"`
+	RespawnMs int64   `json:"respawn_ms,omitempty"` // with death: when they respawn
+	PosX      int     `json:"posx"`
+	PosY      int     `json:"posy"`
+	ChunkID   ChunkID `json:"chunk_id"`
+}
+
+// ChunkEdit is one chunk's share of a transaction: cubes to add and cube
+// IDs to remove. Removals are applied first.
+type ChunkEdit struct {
+	ChunkID ChunkID  `json:"chunk_id"`
+	Cubes   []Cube   `json:"cubes,omitempty"`
+	CubeIDs []string `json:"cube_ids,omitempty"`
+}
+
+// ChunkVersion names a state of a chunk as one server saw it. Epoch changes
+// whenever a server starts tracking the chunk afresh, so a version from
+// another server, or from before a restart, never matches.
+type ChunkVersion struct {
+	Epoch uint64 `json:"epoch"`
+	Seq   uint64 `json:"seq"`
+}
+
+// ChunkDelta is what changed in a chunk between two versions: cubes,
+// players, NPCs and items added or changed, and the IDs of those gone.
+type ChunkDelta struct {
+	From           ChunkVersion `json:"from"`
+	To             ChunkVersion `json:"to"`
+	Cubes          []Cube       `json:"cubes,omitempty"`
+	RemovedCubes   []string     `json:"removed_cubes,omitempty"`
+	Players        []Player     `json:"players,omitempty"`
+	RemovedPlayers []string     `json:"removed_players,omitempty"`
+	NPCs           []NPC        `json:"npcs,omitempty"`
+	RemovedNPCs    []string     `json:"removed_npcs,omitempty"`
+	Items          []Item       `json:"items,omitempty"`
+	RemovedItems   []string     `json:"removed_items,omitempty"`
+}
+
+// ChunkDigest is a Merkle tree of a chunk (see chunkstore.Digest): Buckets
+// hash its cubes, split by ID into chunkstore.MerkleBuckets leaves, Head
+// hashes the rest (data, players, NPCs and items), and Root hashes them
+// all. Two copies with the same Root are the same; otherwise the leaves
+// tell which cubes to send.
+type ChunkDigest struct {
+	Root    uint64   `json:"root"`
+	Head    uint64   `json:"head,omitempty"`
+	Buckets []uint64 `json:"buckets,omitempty"`
+}
+
+// MemberState is what game servers gossip about each other. Each server
+// bumps its own Heartbeat every round; peers keep the entry with the highest
+// Heartbeat they have seen.
+type MemberState struct {
+	Addr      string    `json:"addr"`
+	Heartbeat uint64    `json:"heartbeat"`
+	Players   int       `json:"players"`
+	Chunks    []ChunkID `json:"chunks,omitempty"` // owned chunks, as ownership hints
+	// PartyPlayers are the member's players who are in a party, so other
+	// servers can show them to their party members.
+	PartyPlayers []Player `json:"party_players,omitempty"`
+}
+
+type PlayerJoinRequest struct {
+	PlayerID string `json:"player_id"`
+	PosX     int    `json:"pos_x"`
+	PosY     int    `json:"pos_y"`
+	// World is the world to join in; the shared one if empty.
+	World string `json:"world,omitempty"`
+	// SessionToken, from an earlier /join, resumes that player's session.
+	SessionToken string `json:"session_token,omitempty"`
+	// Seal asks for a SessionSeal to encrypt UDP traffic with. A cluster
+	// with a seal secret gives every player one, asked or not.
+	Seal bool `json:"seal,omitempty"`
+}
+
+// SessionSeal is a per-session key for the encrypted UDP envelope. Every game
+// server derives Key from ID, Expires and the cluster's seal secret, so the
+// player keeps it across servers without central telling them. Game servers
+// refuse it once Expires (Unix seconds) has passed.
+type SessionSeal struct {
+	ID      uint64 `json:"id"`
+	Expires int64  `json:"expires"`
+	Key     []byte `json:"key"`
+}
+
+type PlayerJoinResponse struct {
+	AssignedServer string `json:"assigned_server"`
+	Message        string `json:"message"`
+}
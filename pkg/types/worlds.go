@@ -0,0 +1,55 @@
+package types
+
+// ===================== Worlds =====================
+
+// A cluster can run several isolated worlds side by side: the shared one,
+// and lobbies, creative worlds or match instances created at central. Every
+// chunk ID names its world, so the same coordinates in two worlds are two
+// different chunks, owned, split and persisted independently. Players are
+// in one world at a time (Player.World) and pick it at /join.
+
+// DefaultWorld is the shared world, which always exists.
+const DefaultWorld = ""
+
+// maxWorldID bounds the length of a world ID.
+const maxWorldID = 64
+
+// World is one world in central's registry.
+type World struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"` // "shared", "lobby", "creative", "match", ...
+	CreatedMs int64  `json:"created_ms"`
+	// Consistency is when a cube edit in the world is acknowledged; see
+	// ConsistencyQuorum.
+	Consistency string `json:"consistency,omitempty"`
+}
+
+// Consistency modes of a world. Edits are normally acknowledged once the
+// chunk's owner has applied them, so an owner that dies before its next
+// save or replica sync loses them. In a quorum world an edit is
+// acknowledged only once a read replica of the chunk has applied it too:
+// slower, but an acknowledged edit survives the owner. Every owned chunk
+// of a quorum world has a replica.
+const (
+	ConsistencyOwner  = ""
+	ConsistencyQuorum = "quorum"
+)
+
+// ValidConsistency reports whether mode is a consistency mode.
+func ValidConsistency(mode string) bool {
+	return mode == ConsistencyOwner || mode == ConsistencyQuorum
+}
+
+// ValidWorldID reports whether id can name a created world: 1 to 64 lower
+// case letters, digits and dashes, so it is safe in file names and URLs.
+func ValidWorldID(id string) bool {
+	if id == "" || len(id) > maxWorldID {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r == '-') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,94 @@
+package worldgen
+
+import (
+	"math"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// Terrain raises hills from seeded value noise. Most of the ground stays flat
+// and empty, so chunks stay small on the wire; where the noise climbs above
+// the waterline a column of cubes is stacked, grass on top of dirt on top of
+// stone.
+type Terrain struct {
+	Seed      int64
+	Scale     int     // world units between noise lattice points
+	MaxHeight int     // tallest column
+	Waterline float64 // noise below this leaves a column empty
+}
+
+func NewTerrain(seed int64) Terrain {
+	return Terrain{Seed: seed, Scale: 16, MaxHeight: 4, Waterline: 0.7}
+}
+
+func (t Terrain) Name() string { return "terrain" }
+
+func (t Terrain) Generate(id types.ChunkID, chunkSize int) []types.Cube {
+	cells := make([]types.Cube, 0)
+	for x := id.IDX * chunkSize; x < (id.IDX+1)*chunkSize; x++ {
+		for z := id.IDY * chunkSize; z < (id.IDY+1)*chunkSize; z++ {
+			h := t.height(x, z)
+			for level := 0; level < h; level++ {
+				cells = append(cells, types.Cube{ID: CubeID(x, z, level), X: x, Z: z, Height: level, Color: colorFor(level, h)})
+			}
+		}
+	}
+	return cells
+}
+
+// height maps the noise above the waterline onto 0..MaxHeight.
+func (t Terrain) height(x, z int) int {
+	n := t.noise(x, z)
+	if n <= t.Waterline {
+		return 0
+	}
+	return int(math.Ceil((n - t.Waterline) / (1 - t.Waterline) * float64(t.MaxHeight)))
+}
+
+// noise is bilinearly interpolated lattice noise in [0, 1). It only depends
+// on world coordinates, so hills continue across chunk borders.
+func (t Terrain) noise(x, z int) float64 {
+	gx, fx := divmod(x, t.Scale)
+	gz, fz := divmod(z, t.Scale)
+	sx := smooth(float64(fx) / float64(t.Scale))
+	sz := smooth(float64(fz) / float64(t.Scale))
+
+	top := lerp(t.lattice(gx, gz), t.lattice(gx+1, gz), sx)
+	bottom := lerp(t.lattice(gx, gz+1), t.lattice(gx+1, gz+1), sx)
+	return lerp(top, bottom, sz)
+}
+
+func (t Terrain) lattice(gx, gz int) float64 {
+	h := splitmix64(uint64(t.Seed) ^ splitmix64(uint64(int64(gx))^splitmix64(uint64(int64(gz)))))
+	return float64(h>>11) / (1 << 53)
+}
+
+func colorFor(level, height int) string {
+	switch {
+	case level == height-1:
+		return "#4caf50" // grass
+	case level >= height-3:
+		return "#8d6e63" // dirt
+	}
+	return "#9e9e9e" // stone
+}
+
+func splitmix64(v uint64) uint64 {
+	v += 0x9e3779b97f4a7c15
+	v = (v ^ (v >> 30)) * 0xbf58476d1ce4e5b9
+	v = (v ^ (v >> 27)) * 0x94d049bb133111eb
+	return v ^ (v >> 31)
+}
+
+func divmod(a, b int) (int, int) {
+	q, r := a/b, a%b
+	if r < 0 {
+		q--
+		r += b
+	}
+	return q, r
+}
+
+func smooth(f float64) float64 { return f * f * (3 - 2*f) }
+
+func lerp(a, b, f float64) float64 { return a + (b-a)*f }
@@ -0,0 +1,56 @@
+// Package worldgen fills new chunks with terrain. Generators are pure
+// functions of (seed, chunk ID, chunk size), so every server running with the
+// same seed produces the same cubes for a chunk nobody has touched yet.
+package worldgen
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Bharghava-Oruganti/distributed_game_server/pkg/types"
+)
+
+// A ChunkGenerator produces the initial Cells of a chunk. Cube X/Z are world
+// coordinates, so chunk (IDX, IDY) covers X in [IDX*size, (IDX+1)*size) and
+// Z in [IDY*size, (IDY+1)*size), matching the client. For a sub-chunk, size
+// is its own edge (types.ChunkID.Edge).
+type ChunkGenerator interface {
+	Name() string
+	Generate(id types.ChunkID, chunkSize int) []types.Cube
+}
+
+// generators maps -generator names to constructors.
+var generators = map[string]func(seed int64) ChunkGenerator{
+	"flat":    func(int64) ChunkGenerator { return Flat{} },
+	"terrain": func(seed int64) ChunkGenerator { return NewTerrain(seed) },
+}
+
+// New returns the generator registered under name.
+func New(name string, seed int64) (ChunkGenerator, error) {
+	newGen, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown generator %q (have %v)", name, Names())
+	}
+	return newGen(seed), nil
+}
+
+func Names() []string {
+	var names []string
+	for name := range generators {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Flat leaves chunks empty, as servers did before generators existed.
+type Flat struct{}
+
+func (Flat) Name() string                             { return "flat" }
+func (Flat) Generate(types.ChunkID, int) []types.Cube { return make([]types.Cube, 0) }
+
+// CubeID names a generated cube the way the client names placed ones, so a
+// player can dig a generated cube like any other.
+func CubeID(x, z, height int) string {
+	return fmt.Sprintf("cube_%d_%d_%d", x, z, height)
+}